@@ -3,8 +3,13 @@ package entsoe
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/devskill-org/ems/utils"
@@ -14,6 +19,7 @@ import (
 type APIClient struct {
 	httpClient *http.Client
 	userAgent  string
+	cache      *DiskCache
 }
 
 // NewAPIClient creates a new ENTSO-E API client with default settings
@@ -29,13 +35,70 @@ func (c *APIClient) SetUserAgent(userAgent string) {
 	c.userAgent = userAgent
 }
 
-// DownloadPublicationMarketData downloads and decodes a PublicationMarketData from the given API URL
+// SetCache sets the on-disk cache checked before downloading (nil = no cache)
+func (c *APIClient) SetCache(cache *DiskCache) {
+	c.cache = cache
+}
+
+// DownloadPublicationMarketData downloads and decodes a PublicationMarketData from the given API URL.
+// With a cache set, a fresh cached document is returned without an HTTP request and downloads are stored in it.
+// When the download fails a stale cached document is returned instead of the error, and a warning is printed.
 func (c *APIClient) DownloadPublicationMarketData(ctx context.Context, apiURL string) (*PublicationMarketData, error) {
+	var cached *PublicationMarketData
+	if c.cache != nil {
+		// A cache failure does not fail the download
+		doc, fresh, err := c.cache.Get(apiURL)
+		if err == nil && doc != nil {
+			if fresh {
+				return doc, nil
+			}
+			cached = doc
+		}
+	}
+
 	opts := &DownloadOptions{
 		UserAgent: c.userAgent,
 	}
 
-	return DownloadPublicationMarketDataWithOptions(ctx, apiURL, opts)
+	doc, err := DownloadPublicationMarketDataWithOptions(ctx, apiURL, opts)
+	if err != nil {
+		// Stale prices are better than none while the API is unavailable
+		if cached != nil {
+			fmt.Printf("Warning: %v, using the stale cached document\n", err)
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	if c.cache != nil {
+		_ = c.cache.Put(apiURL, doc)
+		// A stale entry with a higher revision is still the latest data
+		if cached != nil && cached.RevisionNumber > doc.RevisionNumber {
+			return cached, nil
+		}
+	}
+	return doc, nil
+}
+
+// maxErrorBodySize limits how much of an error response body is kept in an APIError
+const maxErrorBodySize = 4096
+
+// APIError is returned for non-2xx responses of the ENTSO-E API
+type APIError struct {
+	StatusCode int    // HTTP status code
+	Body       string // Response body, truncated to maxErrorBodySize bytes
+	URL        string // Request URL with the security token redacted
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("HTTP request failed with status %d: %d %s", e.StatusCode, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Retryable reports whether the request may succeed when repeated later: rate limiting and server errors are
+// temporary, other client errors such as a 401 for a bad security token are not
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
 }
 
 // DownloadOptions contains options for downloading publication market data with additional options.
@@ -46,12 +109,19 @@ type DownloadOptions struct {
 
 // DownloadPublicationMarketData downloads and decodes publication market data for the current and next day if needed.
 func DownloadPublicationMarketData(ctx context.Context, securityToken string, urlFormat string, location *time.Location) (*PublicationMarketData, error) {
+	return DownloadPublicationMarketDataWithCache(ctx, securityToken, urlFormat, location, nil)
+}
+
+// DownloadPublicationMarketDataWithCache downloads publication market data like DownloadPublicationMarketData,
+// checking the cache for each day window before downloading it (nil = no cache).
+func DownloadPublicationMarketDataWithCache(ctx context.Context, securityToken string, urlFormat string, location *time.Location, cache *DiskCache) (*PublicationMarketData, error) {
 
 	now := time.Now().In(location)
 	url := buildPublicationMarketDataURL(securityToken, urlFormat, now)
-	fmt.Println(url)
+	fmt.Println(RedactURL(url))
 
 	client := NewAPIClient()
+	client.SetCache(cache)
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 	marketDocument, err := client.DownloadPublicationMarketData(ctx, url)
@@ -109,6 +179,154 @@ func mergePublicationMarketData(first *PublicationMarketData, second *Publicatio
 	return &merged
 }
 
+// MergePublicationMarketData merges any number of PublicationMarketData objects into one.
+// The period time interval spans all documents with TimeSeries. TimeSeries of the same domains, business type,
+// contract type and resolution whose periods overlap are deduplicated: the one from the document with the highest
+// revision number wins, or from the later document on equal revisions. A TimeSeries that only partly overlaps a
+// winning one is trimmed to the whole slots outside it, so its other points are kept. TimeSeries are ordered by
+// period start. Nil documents are skipped, nil is returned when all are nil. The input documents are not modified.
+func MergePublicationMarketData(docs ...*PublicationMarketData) *PublicationMarketData {
+	type candidate struct {
+		series   TimeSeries
+		revision int
+		order    int
+	}
+
+	var merged *PublicationMarketData
+	var candidates []candidate
+	for i, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		if merged == nil {
+			copied := *doc
+			merged = &copied
+		}
+		if len(doc.TimeSeries) == 0 {
+			continue
+		}
+		if len(candidates) == 0 || doc.PeriodTimeInterval.Start.Before(merged.PeriodTimeInterval.Start) {
+			merged.PeriodTimeInterval.Start = doc.PeriodTimeInterval.Start
+		}
+		if len(candidates) == 0 || doc.PeriodTimeInterval.End.After(merged.PeriodTimeInterval.End) {
+			merged.PeriodTimeInterval.End = doc.PeriodTimeInterval.End
+		}
+		for _, series := range doc.TimeSeries {
+			candidates = append(candidates, candidate{series: series, revision: doc.RevisionNumber, order: i})
+		}
+	}
+	if merged == nil {
+		return nil
+	}
+
+	// Series are taken in priority order, each keeps the parts of its period not covered by a series taken before
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].revision != candidates[j].revision {
+			return candidates[i].revision > candidates[j].revision
+		}
+		return candidates[i].order > candidates[j].order
+	})
+	type seriesKey struct {
+		inDomain, outDomain, businessType, contractType string
+		resolution                                      time.Duration
+	}
+	covered := make(map[seriesKey][]TimeInterval)
+	merged.TimeSeries = nil
+	for _, c := range candidates {
+		key := seriesKey{
+			inDomain:     c.series.InDomainMRID.Value,
+			outDomain:    c.series.OutDomainMRID.Value,
+			businessType: c.series.BusinessType,
+			contractType: c.series.ContractMarketAgreementType,
+			resolution:   c.series.Period.Resolution,
+		}
+		for _, part := range subtractIntervals(c.series.Period.TimeInterval, covered[key]) {
+			if trimmed, ok := trimSeries(c.series, part); ok {
+				merged.TimeSeries = append(merged.TimeSeries, trimmed)
+			}
+		}
+		covered[key] = append(covered[key], c.series.Period.TimeInterval)
+	}
+
+	sort.SliceStable(merged.TimeSeries, func(i, j int) bool {
+		return merged.TimeSeries[i].Period.TimeInterval.Start.Before(merged.TimeSeries[j].Period.TimeInterval.Start)
+	})
+	return merged
+}
+
+// subtractIntervals returns the parts of interval not covered by any of the covered intervals, in order
+func subtractIntervals(interval TimeInterval, covered []TimeInterval) []TimeInterval {
+	parts := []TimeInterval{interval}
+	for _, c := range covered {
+		var remaining []TimeInterval
+		for _, part := range parts {
+			if !c.Start.Before(part.End) || !part.Start.Before(c.End) {
+				remaining = append(remaining, part)
+				continue
+			}
+			if part.Start.Before(c.Start) {
+				remaining = append(remaining, TimeInterval{Start: part.Start, End: c.Start})
+			}
+			if c.End.Before(part.End) {
+				remaining = append(remaining, TimeInterval{Start: c.End, End: part.End})
+			}
+		}
+		parts = remaining
+	}
+	return parts
+}
+
+// trimSeries returns a copy of the series limited to the whole slots of its period within part, with the points
+// renumbered from 1. It returns false when no point is left.
+func trimSeries(series TimeSeries, part TimeInterval) (TimeSeries, bool) {
+	period := series.Period
+	if part.Start.Equal(period.TimeInterval.Start) && part.End.Equal(period.TimeInterval.End) {
+		return series, true
+	}
+	if period.Resolution <= 0 {
+		return TimeSeries{}, false
+	}
+
+	periodStart := period.TimeInterval.Start
+	first := int((part.Start.Sub(periodStart) + period.Resolution - 1) / period.Resolution) // zero-based
+	start := periodStart.Add(time.Duration(first) * period.Resolution)
+	end := part.End
+	if !end.Equal(period.TimeInterval.End) {
+		end = periodStart.Add(part.End.Sub(periodStart) / period.Resolution * period.Resolution)
+	}
+	if !start.Before(end) {
+		return TimeSeries{}, false
+	}
+
+	var points []Point
+	var carried *Point
+	for _, point := range period.Points {
+		slot := point.Position - 1
+		if slot < first {
+			if carried == nil || point.Position > carried.Position {
+				carried = &point
+			}
+			continue
+		}
+		if !periodStart.Add(time.Duration(slot) * period.Resolution).Before(end) {
+			continue
+		}
+		points = append(points, Point{Position: slot - first + 1, PriceAmount: point.PriceAmount})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Position < points[j].Position })
+	// A curve omits points that repeat the previous price, the first kept slot takes the price before it
+	if carried != nil && (len(points) == 0 || points[0].Position != 1) {
+		points = append([]Point{{Position: 1, PriceAmount: carried.PriceAmount}}, points...)
+	}
+	if len(points) == 0 {
+		return TimeSeries{}, false
+	}
+
+	trimmed := series
+	trimmed.Period = Period{TimeInterval: TimeInterval{Start: start, End: end}, Resolution: period.Resolution, Points: points}
+	return trimmed, true
+}
+
 // DownloadPublicationMarketDataWithOptions downloads and decodes a PublicationMarketData with custom options
 func DownloadPublicationMarketDataWithOptions(ctx context.Context, apiURL string, opts *DownloadOptions) (*PublicationMarketData, error) {
 	if apiURL == "" {
@@ -120,7 +338,7 @@ func DownloadPublicationMarketDataWithOptions(ctx context.Context, apiURL string
 	// Create HTTP request with context
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, fmt.Errorf("failed to create HTTP request: %w", redactURLError(err))
 	}
 
 	// Set default headers
@@ -139,13 +357,14 @@ func DownloadPublicationMarketDataWithOptions(ctx context.Context, apiURL string
 	// Execute the request
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute HTTP request: %w", err)
+		return nil, fmt.Errorf("failed to execute HTTP request: %w", redactURLError(err))
 	}
 	defer resp.Body.Close()
 
 	// Check HTTP status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP request failed with status %d: %s", resp.StatusCode, resp.Status)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body), URL: RedactURL(apiURL)}
 	}
 
 	// Decode the XML response using the existing decoder
@@ -157,6 +376,52 @@ func DownloadPublicationMarketDataWithOptions(ctx context.Context, apiURL string
 	return doc, nil
 }
 
+// redactedToken replaces the security token in URLs of errors and logs
+const redactedToken = "REDACTED"
+
+// RedactURL returns the API URL with the value of the securityToken query parameter replaced,
+// so the URL can be logged or put into errors. It works on URLs that fail to parse as well.
+func RedactURL(apiURL string) string {
+	const param = "securityToken="
+	var b strings.Builder
+	rest := apiURL
+	for {
+		index := strings.Index(rest, param)
+		if index < 0 {
+			b.WriteString(rest)
+			return b.String()
+		}
+		// Only a query parameter name, not the end of another parameter's name
+		start := index + len(param)
+		if index > 0 && rest[index-1] != '?' && rest[index-1] != '&' {
+			b.WriteString(rest[:start])
+			rest = rest[start:]
+			continue
+		}
+		b.WriteString(rest[:start])
+		rest = rest[start:]
+		end := strings.IndexAny(rest, "&#")
+		if end < 0 {
+			end = len(rest)
+		}
+		if end > 0 {
+			b.WriteString(redactedToken)
+		}
+		rest = rest[end:]
+	}
+}
+
+// redactURLError redacts the URL of a *url.Error, which the HTTP client returns with the full request URL
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		redacted := *urlErr
+		redacted.URL = RedactURL(urlErr.URL)
+		return &redacted
+	}
+	return err
+}
+
 // ValidateAPIURL performs basic validation on the API URL
 func ValidateAPIURL(apiURL string) error {
 	if apiURL == "" {
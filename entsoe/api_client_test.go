@@ -2,9 +2,11 @@ package entsoe
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -593,6 +595,290 @@ func TestMergePublicationMarketData_EndTimeNotExtended(t *testing.T) {
 	}
 }
 
+// dailyPriceDocument returns a document with hourly prices of price for the day starting at start
+func dailyPriceDocument(start time.Time, revision int, price float64) *PublicationMarketData {
+	return priceDocument(start, 24, revision, price)
+}
+
+// priceDocument returns a document of hourly prices over the given number of hours
+func priceDocument(start time.Time, hours, revision int, price float64) *PublicationMarketData {
+	interval := TimeInterval{Start: start, End: start.Add(time.Duration(hours) * time.Hour)}
+	points := make([]Point, hours)
+	for i := range points {
+		points[i] = Point{Position: i + 1, PriceAmount: price}
+	}
+	return &PublicationMarketData{
+		MRID:               fmt.Sprintf("doc-%s", start.Format("20060102")),
+		RevisionNumber:     revision,
+		PeriodTimeInterval: interval,
+		TimeSeries: []TimeSeries{
+			{
+				MRID:         "1",
+				BusinessType: "A62",
+				InDomainMRID: MarketParticipantMRID{CodingScheme: "A01", Value: "10YLV-1001A00074"},
+				Period:       Period{TimeInterval: interval, Resolution: time.Hour, Points: points},
+			},
+		},
+	}
+}
+
+func TestMergePublicationMarketData_FiveDays(t *testing.T) {
+	start := time.Date(2025, 9, 1, 22, 0, 0, 0, time.UTC)
+	var docs []*PublicationMarketData
+	for day := 0; day < 5; day++ {
+		docs = append(docs, dailyPriceDocument(start.AddDate(0, 0, day), 1, float64(10*(day+1))))
+	}
+	// Documents arrive out of order, with a nil in between
+	shuffled := []*PublicationMarketData{docs[3], docs[0], nil, docs[4], docs[1], docs[2]}
+
+	merged := MergePublicationMarketData(shuffled...)
+	if merged == nil {
+		t.Fatal("Expected a merged document")
+	}
+
+	end := start.AddDate(0, 0, 5)
+	if !merged.PeriodTimeInterval.Start.Equal(start) || !merged.PeriodTimeInterval.End.Equal(end) {
+		t.Errorf("Expected span %v - %v, got %v - %v", start, end, merged.PeriodTimeInterval.Start, merged.PeriodTimeInterval.End)
+	}
+	if len(merged.TimeSeries) != 5 {
+		t.Fatalf("Expected 5 TimeSeries, got %d", len(merged.TimeSeries))
+	}
+
+	// Series are ordered and contiguous
+	for i, series := range merged.TimeSeries {
+		if !series.Period.TimeInterval.Start.Equal(start.AddDate(0, 0, i)) {
+			t.Errorf("Expected TimeSeries %d to start at %v, got %v", i, start.AddDate(0, 0, i), series.Period.TimeInterval.Start)
+		}
+		if i > 0 && !series.Period.TimeInterval.Start.Equal(merged.TimeSeries[i-1].Period.TimeInterval.End) {
+			t.Errorf("Expected TimeSeries %d to start where the previous one ends", i)
+		}
+	}
+
+	// Every hour of the span has the price of its day
+	for hour := start; hour.Before(end); hour = hour.Add(time.Hour) {
+		price, found := merged.LookupPriceByTime(hour)
+		expected := float64(10 * (int(hour.Sub(start)/(24*time.Hour)) + 1))
+		if !found || price != expected {
+			t.Errorf("Expected price %.2f at %v, got %.2f (found %v)", expected, hour, price, found)
+		}
+	}
+	if _, found := merged.LookupPriceByTime(end); found {
+		t.Errorf("Expected no price at the end of the span %v", end)
+	}
+
+	// The input documents are not modified
+	for _, doc := range docs {
+		if len(doc.TimeSeries) != 1 {
+			t.Errorf("Original document %s should still have 1 TimeSeries, got %d", doc.MRID, len(doc.TimeSeries))
+		}
+	}
+}
+
+func TestMergePublicationMarketData_DedupesByRevision(t *testing.T) {
+	start := time.Date(2025, 9, 1, 22, 0, 0, 0, time.UTC)
+	day1 := dailyPriceDocument(start, 1, 10)
+	day2 := dailyPriceDocument(start.AddDate(0, 0, 1), 2, 20)
+	day2Old := dailyPriceDocument(start.AddDate(0, 0, 1), 1, 99)
+	day2Repeat := dailyPriceDocument(start.AddDate(0, 0, 1), 2, 25)
+	// Day 2 and the first half of day 3
+	day2And3Old := priceDocument(start.AddDate(0, 0, 1), 36, 1, 99)
+
+	tests := []struct {
+		name           string
+		docs           []*PublicationMarketData
+		expected       float64
+		expectedSeries int
+	}{
+		{name: "higher revision first", docs: []*PublicationMarketData{day1, day2, day2Old}, expected: 20, expectedSeries: 2},
+		{name: "higher revision last", docs: []*PublicationMarketData{day1, day2Old, day2}, expected: 20, expectedSeries: 2},
+		{name: "equal revision keeps the later document", docs: []*PublicationMarketData{day1, day2, day2Repeat}, expected: 25, expectedSeries: 2},
+		// The lower revision keeps the part of day 3 no other series covers
+		{name: "partial overlap trimmed", docs: []*PublicationMarketData{day1, day2And3Old, day2}, expected: 20, expectedSeries: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := MergePublicationMarketData(tt.docs...)
+			if len(merged.TimeSeries) != tt.expectedSeries {
+				t.Fatalf("Expected %d TimeSeries after deduplication, got %d", tt.expectedSeries, len(merged.TimeSeries))
+			}
+			price, found := merged.LookupPriceByTime(start.AddDate(0, 0, 1).Add(5 * time.Hour))
+			if !found || price != tt.expected {
+				t.Errorf("Expected price %.2f on the second day, got %.2f (found %v)", tt.expected, price, found)
+			}
+		})
+	}
+
+	merged := MergePublicationMarketData(day1, day2And3Old, day2)
+	day3 := start.AddDate(0, 0, 2)
+	if price, found := merged.LookupPriceByTime(day3.Add(11 * time.Hour)); !found || price != 99 {
+		t.Errorf("Expected the trimmed lower revision price 99 on the third day, got %.2f (found %v)", price, found)
+	}
+	if _, found := merged.LookupPriceByTime(day3.Add(12 * time.Hour)); found {
+		t.Error("Expected no price past the trimmed series")
+	}
+	trimmed := merged.TimeSeries[2].Period
+	if !trimmed.TimeInterval.Start.Equal(day3) || len(trimmed.Points) != 12 || trimmed.Points[0].Position != 1 {
+		t.Errorf("Expected 12 points renumbered from day 3, got %+v", trimmed)
+	}
+	if len(day2And3Old.TimeSeries[0].Period.Points) != 36 {
+		t.Error("Expected the input document unchanged")
+	}
+}
+
+func TestTrimSeries_CarriesOmittedPoint(t *testing.T) {
+	start := time.Date(2025, 9, 1, 22, 0, 0, 0, time.UTC)
+	// A curve with the price of position 1 repeated until position 4
+	series := TimeSeries{Period: Period{
+		TimeInterval: TimeInterval{Start: start, End: start.Add(6 * time.Hour)},
+		Resolution:   time.Hour,
+		Points:       []Point{{Position: 1, PriceAmount: 10}, {Position: 4, PriceAmount: 40}},
+	}}
+
+	trimmed, ok := trimSeries(series, TimeInterval{Start: start.Add(90 * time.Minute), End: start.Add(6 * time.Hour)})
+	if !ok {
+		t.Fatal("Expected the trimmed series")
+	}
+	// Whole slots only: the part starts at the slot of 02:00
+	if !trimmed.Period.TimeInterval.Start.Equal(start.Add(2 * time.Hour)) {
+		t.Errorf("Expected the trimmed period to start at %v, got %v", start.Add(2*time.Hour), trimmed.Period.TimeInterval.Start)
+	}
+	expected := []Point{{Position: 1, PriceAmount: 10}, {Position: 2, PriceAmount: 40}}
+	if !slices.Equal(trimmed.Period.Points, expected) {
+		t.Errorf("Expected points %+v, got %+v", expected, trimmed.Period.Points)
+	}
+
+	if _, ok := trimSeries(series, TimeInterval{Start: start.Add(10 * time.Minute), End: start.Add(50 * time.Minute)}); ok {
+		t.Error("Expected no series without a whole slot")
+	}
+}
+
+func TestMergePublicationMarketData_Variadic(t *testing.T) {
+	if merged := MergePublicationMarketData(); merged != nil {
+		t.Error("Expected merging no documents to return nil")
+	}
+	if merged := MergePublicationMarketData(nil, nil); merged != nil {
+		t.Error("Expected merging nil documents to return nil")
+	}
+
+	empty := &PublicationMarketData{MRID: "empty"}
+	if merged := MergePublicationMarketData(nil, empty); merged == nil || merged.MRID != "empty" {
+		t.Errorf("Expected a copy of the only document, got %+v", merged)
+	}
+}
+
+func TestDownloadPublicationMarketData_APIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		retryable bool
+	}{
+		{name: "unauthorized", status: http.StatusUnauthorized, body: "Invalid security token", retryable: false},
+		{name: "service unavailable", status: http.StatusServiceUnavailable, body: "Maintenance", retryable: true},
+		{name: "too many requests", status: http.StatusTooManyRequests, body: "Slow down", retryable: true},
+		{name: "bad request", status: http.StatusBadRequest, body: "Invalid domain", retryable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := NewAPIClient().DownloadPublicationMarketData(context.Background(), server.URL)
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected an *APIError, got %T: %v", err, err)
+			}
+			if apiErr.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, apiErr.StatusCode)
+			}
+			if apiErr.Body != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, apiErr.Body)
+			}
+			if apiErr.URL != server.URL {
+				t.Errorf("Expected URL %q, got %q", server.URL, apiErr.URL)
+			}
+			if apiErr.Retryable() != tt.retryable {
+				t.Errorf("Expected retryable %v, got %v", tt.retryable, apiErr.Retryable())
+			}
+		})
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    "https://web-api.tp.entsoe.eu/api?documentType=A44&periodStart=202509042200&periodEnd=202509052200&securityToken=secret-token",
+			expected: "https://web-api.tp.entsoe.eu/api?documentType=A44&periodStart=202509042200&periodEnd=202509052200&securityToken=REDACTED",
+		},
+		{
+			input:    "https://example.com/api?securityToken=secret-token&periodStart=1#top",
+			expected: "https://example.com/api?securityToken=REDACTED&periodStart=1#top",
+		},
+		{
+			input:    "https://example.com/api?notsecurityToken=value&securityToken=",
+			expected: "https://example.com/api?notsecurityToken=value&securityToken=",
+		},
+		{
+			input:    "https://example.com/api?periodStart=1",
+			expected: "https://example.com/api?periodStart=1",
+		},
+		{
+			input:    "://bad url with spaces?securityToken=secret-token",
+			expected: "://bad url with spaces?securityToken=REDACTED",
+		},
+	}
+
+	for _, tt := range tests {
+		if result := RedactURL(tt.input); result != tt.expected {
+			t.Errorf("RedactURL(%q) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestDownloadPublicationMarketData_ErrorRedactsToken(t *testing.T) {
+	const token = "secret-token-123"
+
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer unauthorized.Close()
+
+	// A closed server fails the request itself, the HTTP client puts the URL into the error
+	closed := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	closed.Close()
+
+	for name, server := range map[string]*httptest.Server{"status error": unauthorized, "request error": closed} {
+		t.Run(name, func(t *testing.T) {
+			urlFormat := server.URL + "/api?documentType=A44&periodStart=%s&periodEnd=%s&securityToken=%s"
+			_, err := DownloadPublicationMarketData(context.Background(), token, urlFormat, time.UTC)
+			if err == nil {
+				t.Fatal("Expected an error from the failing request")
+			}
+			if strings.Contains(err.Error(), token) {
+				t.Errorf("Expected the token to be redacted, got %q", err.Error())
+			}
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && strings.Contains(apiErr.URL, token) {
+				t.Errorf("Expected the token to be redacted from the API error URL, got %q", apiErr.URL)
+			}
+		})
+	}
+
+	_, err := DownloadPublicationMarketDataWithOptions(context.Background(), "http://bad host/api?securityToken="+token, &DownloadOptions{})
+	if err == nil || strings.Contains(err.Error(), token) {
+		t.Errorf("Expected an error without the token for an invalid URL, got %v", err)
+	}
+}
+
 // Example test showing how to use the API client
 func ExampleAPIClient_DownloadPublicationMarketData() {
 	client := NewAPIClient()
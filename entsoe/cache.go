@@ -0,0 +1,138 @@
+package entsoe
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiskCache stores downloaded price documents as XML files keyed by the domain and day window of the request
+type DiskCache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+	mu  sync.Mutex
+}
+
+// NewDiskCache creates a cache storing documents in dir. Entries older than ttl are stale (0 = never stale).
+func NewDiskCache(dir string, ttl time.Duration) *DiskCache {
+	return &DiskCache{
+		dir: dir,
+		ttl: ttl,
+		now: time.Now,
+	}
+}
+
+// cacheKey returns the file name for an API URL from its domains and period, the security token is not part of it
+func cacheKey(apiURL string) (string, error) {
+	parsed, err := url.Parse(apiURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse API URL: %w", err)
+	}
+	query := parsed.Query()
+	periodStart, periodEnd := query.Get("periodStart"), query.Get("periodEnd")
+	if periodStart == "" || periodEnd == "" {
+		return "", fmt.Errorf("API URL has no period")
+	}
+
+	parts := []string{query.Get("documentType"), query.Get("in_Domain"), query.Get("out_Domain"), periodStart, periodEnd}
+	for i, part := range parts {
+		parts[i] = strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+				return r
+			}
+			return '_'
+		}, part)
+	}
+	return strings.Join(parts, "_") + ".xml", nil
+}
+
+// Get returns the cached document for an API URL. Stale entries are returned with fresh set to false,
+// so a failed refetch can still fall back to them.
+func (c *DiskCache) Get(apiURL string) (doc *PublicationMarketData, fresh bool, err error) {
+	key, err := cacheKey(apiURL)
+	if err != nil {
+		return nil, false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.read(filepath.Join(c.dir, key))
+}
+
+// read decodes a cache file, a missing file is not an error
+func (c *DiskCache) read(path string) (*PublicationMarketData, bool, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to stat cache file: %w", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open cache file: %w", err)
+	}
+	defer file.Close()
+
+	doc, err := DecodeEnergyPricesXML(file)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode cache file: %w", err)
+	}
+
+	fresh := c.ttl <= 0 || c.now().Sub(info.ModTime()) < c.ttl
+	return doc, fresh, nil
+}
+
+// Put stores the document downloaded from an API URL. Documents without TimeSeries are not cached,
+// and a document with a lower revision number than the cached one does not replace it.
+func (c *DiskCache) Put(apiURL string, doc *PublicationMarketData) error {
+	if doc == nil || len(doc.TimeSeries) == 0 {
+		return nil
+	}
+	key, err := cacheKey(apiURL)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path := filepath.Join(c.dir, key)
+	now := c.now()
+	if cached, _, err := c.read(path); err == nil && cached != nil && cached.RevisionNumber > doc.RevisionNumber {
+		// The cached revision is still the latest, it is fresh again
+		if err := os.Chtimes(path, now, now); err != nil {
+			return fmt.Errorf("failed to touch cache file: %w", err)
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeEnergyPricesXML(&buf, doc); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated entry
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	// The modification time marks when the entry was stored
+	if err := os.Chtimes(path, now, now); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	return nil
+}
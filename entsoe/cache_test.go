@@ -0,0 +1,214 @@
+package entsoe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// serveCountingPrices returns a server answering with sampleXMLResponse using the given revision and a request counter
+func serveCountingPrices(t *testing.T, revision *atomic.Int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/xml")
+		body := strings.Replace(sampleXMLResponse, "<revisionNumber>1</revisionNumber>",
+			fmt.Sprintf("<revisionNumber>%d</revisionNumber>", revision.Load()), 1)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestCacheKey(t *testing.T) {
+	key, err := cacheKey("https://example.com/api?documentType=A44&out_Domain=10YLV-1001A00074&in_Domain=10YLV-1001A00074&periodStart=202509042200&periodEnd=202509052200&securityToken=secret")
+	if err != nil {
+		t.Fatalf("cacheKey() unexpected error = %v", err)
+	}
+	if expected := "A44_10YLV-1001A00074_10YLV-1001A00074_202509042200_202509052200.xml"; key != expected {
+		t.Errorf("cacheKey() = %q, want %q", key, expected)
+	}
+
+	if _, err := cacheKey("https://example.com/api?documentType=A44"); err == nil {
+		t.Error("cacheKey() expected an error without a period")
+	}
+}
+
+func TestDiskCache_HitAvoidsHTTPCall(t *testing.T) {
+	var revision atomic.Int32
+	revision.Store(1)
+	server, requests := serveCountingPrices(t, &revision)
+	apiURL := server.URL + "?in_Domain=10YLV-1001A00074&periodStart=202509052200&periodEnd=202509062200&securityToken=secret"
+
+	client := NewAPIClient()
+	client.SetCache(NewDiskCache(t.TempDir(), time.Hour))
+
+	first, err := client.DownloadPublicationMarketData(context.Background(), apiURL)
+	if err != nil {
+		t.Fatalf("DownloadPublicationMarketData() unexpected error = %v", err)
+	}
+	second, err := client.DownloadPublicationMarketData(context.Background(), apiURL)
+	if err != nil {
+		t.Fatalf("DownloadPublicationMarketData() unexpected error = %v", err)
+	}
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("Expected 1 HTTP request with a cache hit, got %d", got)
+	}
+	ts := time.Date(2025, 9, 5, 23, 0, 0, 0, time.UTC)
+	firstPrice, _ := first.LookupPriceByTime(ts)
+	secondPrice, found := second.LookupPriceByTime(ts)
+	if !found || secondPrice != firstPrice {
+		t.Errorf("Expected the cached price %.2f, got %.2f (found %v)", firstPrice, secondPrice, found)
+	}
+
+	// Another day window is not served from the cache
+	otherDay := strings.Replace(apiURL, "202509062200", "202509072200", 1)
+	if _, err := client.DownloadPublicationMarketData(context.Background(), otherDay); err != nil {
+		t.Fatalf("DownloadPublicationMarketData() unexpected error = %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("Expected 2 HTTP requests for another day window, got %d", got)
+	}
+}
+
+func TestDiskCache_StaleEntryRefetched(t *testing.T) {
+	var revision atomic.Int32
+	revision.Store(1)
+	server, requests := serveCountingPrices(t, &revision)
+	apiURL := server.URL + "?in_Domain=10YLV-1001A00074&periodStart=202509052200&periodEnd=202509062200"
+
+	now := time.Date(2025, 9, 5, 12, 0, 0, 0, time.UTC)
+	cache := NewDiskCache(t.TempDir(), time.Hour)
+	cache.now = func() time.Time { return now }
+	client := NewAPIClient()
+	client.SetCache(cache)
+
+	if _, err := client.DownloadPublicationMarketData(context.Background(), apiURL); err != nil {
+		t.Fatalf("DownloadPublicationMarketData() unexpected error = %v", err)
+	}
+
+	// Past the TTL the entry is stale and a revised document is downloaded
+	now = now.Add(2 * time.Hour)
+	revision.Store(2)
+	doc, err := client.DownloadPublicationMarketData(context.Background(), apiURL)
+	if err != nil {
+		t.Fatalf("DownloadPublicationMarketData() unexpected error = %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("Expected 2 HTTP requests for a stale entry, got %d", got)
+	}
+	if doc.RevisionNumber != 2 {
+		t.Errorf("Expected revision 2, got %d", doc.RevisionNumber)
+	}
+
+	cached, fresh, err := cache.Get(apiURL)
+	if err != nil || cached == nil || !fresh || cached.RevisionNumber != 2 {
+		t.Errorf("Expected a fresh cached revision 2, got %+v (fresh %v, err %v)", cached, fresh, err)
+	}
+}
+
+func TestDiskCache_StaleEntryOnDownloadFailure(t *testing.T) {
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(sampleXMLResponse))
+	}))
+	t.Cleanup(server.Close)
+	apiURL := server.URL + "?in_Domain=10YLV-1001A00074&periodStart=202509052200&periodEnd=202509062200"
+
+	now := time.Date(2025, 9, 5, 12, 0, 0, 0, time.UTC)
+	cache := NewDiskCache(t.TempDir(), time.Hour)
+	cache.now = func() time.Time { return now }
+	client := NewAPIClient()
+	client.SetCache(cache)
+
+	primed, err := client.DownloadPublicationMarketData(context.Background(), apiURL)
+	if err != nil {
+		t.Fatalf("DownloadPublicationMarketData() unexpected error = %v", err)
+	}
+
+	// Past the TTL the API fails, the stale entry is used
+	now = now.Add(2 * time.Hour)
+	failing.Store(true)
+	doc, err := client.DownloadPublicationMarketData(context.Background(), apiURL)
+	if err != nil {
+		t.Fatalf("Expected the stale cached document, got error = %v", err)
+	}
+	if doc == nil || doc.MRID != primed.MRID || len(doc.TimeSeries) != len(primed.TimeSeries) {
+		t.Errorf("Expected the cached document %q, got %+v", primed.MRID, doc)
+	}
+
+	// Without a cached document the error is returned
+	otherDay := server.URL + "?in_Domain=10YLV-1001A00074&periodStart=202509062200&periodEnd=202509072200"
+	if _, err := client.DownloadPublicationMarketData(context.Background(), otherDay); err == nil {
+		t.Error("Expected an error without a cached document")
+	}
+}
+
+func TestDiskCache_LowerRevisionDoesNotReplace(t *testing.T) {
+	var revision atomic.Int32
+	revision.Store(3)
+	server, requests := serveCountingPrices(t, &revision)
+	apiURL := server.URL + "?in_Domain=10YLV-1001A00074&periodStart=202509052200&periodEnd=202509062200"
+
+	now := time.Date(2025, 9, 5, 12, 0, 0, 0, time.UTC)
+	cache := NewDiskCache(t.TempDir(), time.Hour)
+	cache.now = func() time.Time { return now }
+	client := NewAPIClient()
+	client.SetCache(cache)
+
+	if _, err := client.DownloadPublicationMarketData(context.Background(), apiURL); err != nil {
+		t.Fatalf("DownloadPublicationMarketData() unexpected error = %v", err)
+	}
+
+	// A lagging mirror answers with an older revision after the TTL
+	now = now.Add(2 * time.Hour)
+	revision.Store(2)
+	doc, err := client.DownloadPublicationMarketData(context.Background(), apiURL)
+	if err != nil {
+		t.Fatalf("DownloadPublicationMarketData() unexpected error = %v", err)
+	}
+	if doc.RevisionNumber != 3 {
+		t.Errorf("Expected the cached revision 3 to be kept, got %d", doc.RevisionNumber)
+	}
+
+	// The kept entry is fresh again
+	if _, err := client.DownloadPublicationMarketData(context.Background(), apiURL); err != nil {
+		t.Fatalf("DownloadPublicationMarketData() unexpected error = %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("Expected 2 HTTP requests, got %d", got)
+	}
+}
+
+func TestDiskCache_EmptyDocumentNotCached(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewDiskCache(dir, time.Hour)
+	apiURL := "https://example.com/api?periodStart=202509052200&periodEnd=202509062200"
+
+	if err := cache.Put(apiURL, &PublicationMarketData{MRID: "empty"}); err != nil {
+		t.Fatalf("Put() unexpected error = %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no cache file for a document without TimeSeries, got %d", len(entries))
+	}
+	if doc, _, err := cache.Get(apiURL); err != nil || doc != nil {
+		t.Errorf("Expected a cache miss, got %+v (err %v)", doc, err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -23,6 +25,22 @@ type PublicationMarketData struct {
 	TimeSeries                        []TimeSeries          `xml:"TimeSeries"`
 }
 
+// publicationDocumentNamespace is the XML namespace of ENTSO-E publication market documents
+const publicationDocumentNamespace = "urn:iec62325.351:tc57wg16:451-3:publicationdocument:7:3"
+
+// MarshalXML implements custom XML marshaling for PublicationMarketData.
+// The namespace is only written as the xmlns attribute, the decoded XMLName would repeat it.
+func (pmd PublicationMarketData) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type document PublicationMarketData
+	doc := document(pmd)
+	doc.XMLName = xml.Name{}
+	if doc.Xmlns == "" {
+		doc.Xmlns = publicationDocumentNamespace
+	}
+	start.Name = xml.Name{Local: "Publication_MarketDocument"}
+	return e.EncodeElement(doc, start)
+}
+
 // MarketParticipantMRID represents market participant with coding scheme
 type MarketParticipantMRID struct {
 	CodingScheme string `xml:"codingScheme,attr"`
@@ -60,6 +78,30 @@ func (ti *TimeInterval) UnmarshalXML(d *xml.Decoder, start xml.StartElement) err
 	return nil
 }
 
+// timeIntervalLayout is the UTC minute precision time format of ENTSO-E time intervals
+const timeIntervalLayout = "2006-01-02T15:04Z"
+
+// MarshalXML implements custom XML marshaling for TimeInterval
+func (ti TimeInterval) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	aux := struct {
+		Start string `xml:"start"`
+		End   string `xml:"end"`
+	}{
+		Start: formatTimeString(ti.Start),
+		End:   formatTimeString(ti.End),
+	}
+	return e.EncodeElement(aux, start)
+}
+
+// formatTimeString formats a time in the ENTSO-E XML format, seconds are only written when present
+func formatTimeString(t time.Time) string {
+	t = t.UTC()
+	if t.Second() != 0 || t.Nanosecond() != 0 {
+		return t.Format(time.RFC3339Nano)
+	}
+	return t.Format(timeIntervalLayout)
+}
+
 // parseTimeString parses time strings in the format used by ENTSO-E XML
 func parseTimeString(timeStr string) (time.Time, error) {
 	// Try RFC3339 format first (2006-01-02T15:04:05Z07:00)
@@ -125,6 +167,20 @@ func (p *Period) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	return nil
 }
 
+// MarshalXML implements custom XML marshaling for Period
+func (p Period) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	aux := struct {
+		TimeInterval TimeInterval `xml:"timeInterval"`
+		Resolution   string       `xml:"resolution"`
+		Points       []Point      `xml:"Point"`
+	}{
+		TimeInterval: p.TimeInterval,
+		Resolution:   formatISO8601Duration(p.Resolution),
+		Points:       p.Points,
+	}
+	return e.EncodeElement(aux, start)
+}
+
 // parseISO8601Duration parses ISO 8601 duration format to time.Duration
 func parseISO8601Duration(duration string) (time.Duration, error) {
 	// Handle common ISO 8601 duration formats used in ENTSO-E
@@ -181,6 +237,46 @@ func parseISO8601Duration(duration string) (time.Duration, error) {
 	return totalDuration + timeDuration, nil
 }
 
+// formatISO8601Duration formats a time.Duration in the canonical ISO 8601 form accepted by parseISO8601Duration.
+// Whole days are written as P#D and the remainder as T#H#M#S, omitting zero units (e.g. PT15M, P1D, P1DT2H30M).
+// Only days are used for the date part, as years and months have no exact length. A zero duration is PT0S.
+func formatISO8601Duration(d time.Duration) string {
+	if d == 0 {
+		return "PT0S"
+	}
+
+	var b strings.Builder
+	if d < 0 {
+		// Negative durations are not part of ISO 8601, the sign follows the ISO 8601-2 extension
+		b.WriteByte('-')
+		d = -d
+	}
+	b.WriteByte('P')
+
+	day := 24 * time.Hour
+	if days := d / day; days > 0 {
+		b.WriteString(strconv.FormatInt(int64(days), 10) + "D")
+		d -= days * day
+	}
+	if d == 0 {
+		return b.String()
+	}
+
+	b.WriteByte('T')
+	if hours := d / time.Hour; hours > 0 {
+		b.WriteString(strconv.FormatInt(int64(hours), 10) + "H")
+		d -= hours * time.Hour
+	}
+	if minutes := d / time.Minute; minutes > 0 {
+		b.WriteString(strconv.FormatInt(int64(minutes), 10) + "M")
+		d -= minutes * time.Minute
+	}
+	if d > 0 {
+		b.WriteString(strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S")
+	}
+	return b.String()
+}
+
 // parseDatePart parses the date portion of ISO 8601 duration (years, months, days)
 func parseDatePart(datePart string) (time.Duration, error) {
 	var duration time.Duration
@@ -317,7 +413,16 @@ func ParseDateTime(dateStr string) (time.Time, error) {
 // Returns the first matching price found and true, or 0 and false if no price is found.
 // The time lookup checks if the given time falls within any interval in any TimeSeries.
 func (pmd *PublicationMarketData) LookupPriceByTime(t time.Time) (float64, bool) {
+	return pmd.LookupPriceByTimeAndBusinessType(t, "")
+}
+
+// LookupPriceByTimeAndBusinessType searches the TimeSeries of a business type (e.g. "A62" for day-ahead prices)
+// for a price at the given time like LookupPriceByTime. An empty business type matches all TimeSeries.
+func (pmd *PublicationMarketData) LookupPriceByTimeAndBusinessType(t time.Time, businessType string) (float64, bool) {
 	for _, timeSeries := range pmd.TimeSeries {
+		if businessType != "" && timeSeries.BusinessType != businessType {
+			continue
+		}
 		if price, found := timeSeries.Period.GetPriceByTime(t); found {
 			return price, true
 		}
@@ -325,6 +430,35 @@ func (pmd *PublicationMarketData) LookupPriceByTime(t time.Time) (float64, bool)
 	return 0, false
 }
 
+// MissingHours returns the hours of the range [start, start+hours) that have no price at their start.
+// start is used as given, truncate it to a full hour to check clock hours.
+func (pmd *PublicationMarketData) MissingHours(start time.Time, hours int) []time.Time {
+	var missing []time.Time
+	for i := range hours {
+		hour := start.Add(time.Duration(i) * time.Hour)
+		if _, found := pmd.LookupPriceByTime(hour); !found {
+			missing = append(missing, hour)
+		}
+	}
+	return missing
+}
+
+// FilterByBusinessType returns a copy of the market data with only the TimeSeries of a business type,
+// so every lookup reads that type. An empty business type returns the market data unchanged.
+func (pmd *PublicationMarketData) FilterByBusinessType(businessType string) *PublicationMarketData {
+	if businessType == "" {
+		return pmd
+	}
+	filtered := *pmd
+	filtered.TimeSeries = nil
+	for _, timeSeries := range pmd.TimeSeries {
+		if timeSeries.BusinessType == businessType {
+			filtered.TimeSeries = append(filtered.TimeSeries, timeSeries)
+		}
+	}
+	return &filtered
+}
+
 // GetPriceByTime returns the price for a specific time.
 // The price corresponds to the interval that contains the given time.
 // For example, if the period starts at 22:00 with hourly resolution:
@@ -412,5 +546,89 @@ func DecodeEnergyPricesXML(file io.Reader) (*PublicationMarketData, error) {
 		return nil, fmt.Errorf("error parsing XML: %v", err)
 	}
 
+	if err := doc.NormalizeUnits(); err != nil {
+		return nil, err
+	}
+
 	return &doc, nil
 }
+
+// CanonicalPriceMeasureUnit is the measure unit of all prices after NormalizeUnits
+const CanonicalPriceMeasureUnit = "MWH"
+
+// CanonicalCurrency is the currency of all prices after NormalizeUnits, price limits are configured in it
+const CanonicalCurrency = "EUR"
+
+// currencyFactors converts a price in a currency or its subunit into EUR. Names are compared upper case
+// without spaces, underscores and dashes, so "EUR cents" and "EUR_CENT" match. Other currencies are not
+// converted, exchange rates change.
+var currencyFactors = map[string]float64{
+	"EUR":      1,
+	"EURCENT":  0.01,
+	"EURCENTS": 0.01,
+	"CENT":     0.01,
+	"CENTS":    0.01,
+	"CT":       0.01,
+}
+
+// priceMeasureUnitFactors converts a price per measure unit into a price per MWh
+var priceMeasureUnitFactors = map[string]float64{
+	"WH":  1e6,
+	"KWH": 1e3,
+	"MWH": 1,
+	"GWH": 1e-3,
+}
+
+// NormalizeUnits converts the prices of all TimeSeries to the canonical EUR per MWh based on their declared
+// price_Measure_Unit.name and currency_Unit.name, so lookups always return EUR per MWh. TimeSeries without a
+// declared unit are taken as MWh, without a declared currency as EUR. Euro cents are converted to EUR. It returns
+// an error for an unknown measure unit or a currency other than EUR.
+func (pmd *PublicationMarketData) NormalizeUnits() error {
+	for i := range pmd.TimeSeries {
+		series := &pmd.TimeSeries[i]
+		factor := 1.0
+		if unit := strings.ToUpper(strings.TrimSpace(series.PriceMeasureUnitName)); unit != "" {
+			unitFactor, ok := priceMeasureUnitFactors[unit]
+			if !ok {
+				return fmt.Errorf("unsupported price measure unit: %s", series.PriceMeasureUnitName)
+			}
+			factor *= unitFactor
+			series.PriceMeasureUnitName = CanonicalPriceMeasureUnit
+		}
+		if currency := strings.NewReplacer(" ", "", "_", "", "-", "").Replace(
+			strings.ToUpper(strings.TrimSpace(series.CurrencyUnitName))); currency != "" {
+			currencyFactor, ok := currencyFactors[currency]
+			if !ok {
+				return fmt.Errorf("unsupported price currency: %s, prices must be in %s", series.CurrencyUnitName, CanonicalCurrency)
+			}
+			factor *= currencyFactor
+			series.CurrencyUnitName = CanonicalCurrency
+		}
+		if factor != 1 {
+			// Copy the points, they may share a backing array with another document
+			points := make([]Point, len(series.Period.Points))
+			for j, point := range series.Period.Points {
+				points[j] = Point{Position: point.Position, PriceAmount: point.PriceAmount * factor}
+			}
+			series.Period.Points = points
+		}
+	}
+	return nil
+}
+
+// EncodeEnergyPricesXML writes the market data as an indented ENTSO-E XML document
+func EncodeEnergyPricesXML(w io.Writer, doc *PublicationMarketData) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("error writing XML: %v", err)
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("error encoding XML: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("error encoding XML: %v", err)
+	}
+	return nil
+}
@@ -1,7 +1,11 @@
 package entsoe
 
 import (
+	"bytes"
+	"math"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -170,6 +174,65 @@ func TestParseISO8601Duration_CommonENTSOEFormats(t *testing.T) {
 	}
 }
 
+func TestFormatISO8601Duration(t *testing.T) {
+	tests := []struct {
+		input    time.Duration
+		expected string
+	}{
+		{input: 0, expected: "PT0S"},
+		{input: 15 * time.Minute, expected: "PT15M"},
+		{input: 30 * time.Minute, expected: "PT30M"},
+		{input: time.Hour, expected: "PT1H"},
+		{input: 24 * time.Hour, expected: "P1D"},
+		{input: 7 * 24 * time.Hour, expected: "P7D"},
+		{input: 26*time.Hour + 30*time.Minute, expected: "P1DT2H30M"},
+		{input: time.Hour + 5*time.Second, expected: "PT1H5S"},
+		{input: 90 * time.Second, expected: "PT1M30S"},
+		{input: 1500 * time.Millisecond, expected: "PT1.5S"},
+		{input: -15 * time.Minute, expected: "-PT15M"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if result := formatISO8601Duration(tt.input); result != tt.expected {
+				t.Errorf("formatISO8601Duration(%v) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatISO8601Duration_RoundTrip(t *testing.T) {
+	durations := []time.Duration{
+		0,
+		time.Second,
+		45 * time.Second,
+		250 * time.Millisecond,
+		1500 * time.Millisecond,
+		time.Minute,
+		15 * time.Minute,
+		30 * time.Minute,
+		time.Hour,
+		90 * time.Minute,
+		23*time.Hour + 59*time.Minute + 59*time.Second,
+		24 * time.Hour,
+		25 * time.Hour,
+		48*time.Hour + 15*time.Minute,
+		365 * 24 * time.Hour,
+	}
+
+	for _, d := range durations {
+		formatted := formatISO8601Duration(d)
+		parsed, err := parseISO8601Duration(formatted)
+		if err != nil {
+			t.Errorf("parseISO8601Duration(%q) unexpected error = %v", formatted, err)
+			continue
+		}
+		if parsed != d {
+			t.Errorf("parseISO8601Duration(formatISO8601Duration(%v)) = %v via %q", d, parsed, formatted)
+		}
+	}
+}
+
 func TestParseDatePart(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -628,3 +691,242 @@ func TestDocumentDecode(t *testing.T) {
 		t.Errorf("Returned price: %f, want %f", price, 57.73)
 	}
 }
+
+func TestEncodeEnergyPricesXML_RoundTrip(t *testing.T) {
+	for _, name := range []string{
+		"../test_data/Energy_Prices_202509112200-202509122200.xml",
+		"../test_data/Energy_Prices_202601032300-202601042300.xml",
+	} {
+		t.Run(name, func(t *testing.T) {
+			file, err := os.Open(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			original, err := DecodeEnergyPricesXML(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			if err := EncodeEnergyPricesXML(&buf, original); err != nil {
+				t.Fatalf("EncodeEnergyPricesXML() unexpected error = %v", err)
+			}
+			encoded := buf.String()
+			if !strings.HasPrefix(encoded, "<?xml") || strings.Count(encoded, "xmlns=") != 1 {
+				t.Errorf("Expected an XML header and a single namespace declaration, got:\n%s", encoded)
+			}
+
+			decoded, err := DecodeEnergyPricesXML(&buf)
+			if err != nil {
+				t.Fatalf("DecodeEnergyPricesXML() of the encoded document unexpected error = %v", err)
+			}
+
+			if !decoded.PeriodTimeInterval.Start.Equal(original.PeriodTimeInterval.Start) ||
+				!decoded.PeriodTimeInterval.End.Equal(original.PeriodTimeInterval.End) {
+				t.Errorf("Period interval = %+v, want %+v", decoded.PeriodTimeInterval, original.PeriodTimeInterval)
+			}
+			if len(decoded.TimeSeries) != len(original.TimeSeries) {
+				t.Fatalf("Decoded %d time series, want %d", len(decoded.TimeSeries), len(original.TimeSeries))
+			}
+			for i, series := range decoded.TimeSeries {
+				want := original.TimeSeries[i].Period
+				if !series.Period.TimeInterval.Start.Equal(want.TimeInterval.Start) ||
+					!series.Period.TimeInterval.End.Equal(want.TimeInterval.End) {
+					t.Errorf("Time series %d interval = %+v, want %+v", i, series.Period.TimeInterval, want.TimeInterval)
+				}
+				if series.Period.Resolution != want.Resolution {
+					t.Errorf("Time series %d resolution = %v, want %v", i, series.Period.Resolution, want.Resolution)
+				}
+				if !reflect.DeepEqual(series.Period.Points, want.Points) {
+					t.Errorf("Time series %d points = %v, want %v", i, series.Period.Points, want.Points)
+				}
+			}
+			if !reflect.DeepEqual(decoded, original) {
+				t.Errorf("Round-tripped document = %+v, want %+v", decoded, original)
+			}
+		})
+	}
+}
+
+func TestLookupPriceByTimeAndBusinessType(t *testing.T) {
+	interval := TimeInterval{
+		Start: time.Date(2025, 9, 4, 22, 0, 0, 0, time.UTC),
+		End:   time.Date(2025, 9, 5, 22, 0, 0, 0, time.UTC),
+	}
+	series := func(businessType string, price float64) TimeSeries {
+		return TimeSeries{
+			BusinessType: businessType,
+			Period: Period{
+				TimeInterval: interval,
+				Resolution:   time.Hour,
+				Points:       []Point{{Position: 1, PriceAmount: price}},
+			},
+		}
+	}
+	// Another business type comes first in the document
+	doc := &PublicationMarketData{
+		PeriodTimeInterval: interval,
+		TimeSeries:         []TimeSeries{series("A64", 99), series("A62", 50)},
+	}
+	ts := interval.Start.Add(30 * time.Minute)
+
+	tests := []struct {
+		name          string
+		businessType  string
+		expected      float64
+		expectedFound bool
+	}{
+		{name: "day-ahead", businessType: "A62", expected: 50, expectedFound: true},
+		{name: "other type", businessType: "A64", expected: 99, expectedFound: true},
+		{name: "any type returns the first match", businessType: "", expected: 99, expectedFound: true},
+		{name: "missing type", businessType: "A25", expectedFound: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, found := doc.LookupPriceByTimeAndBusinessType(ts, tt.businessType)
+			if found != tt.expectedFound || price != tt.expected {
+				t.Errorf("LookupPriceByTimeAndBusinessType() = %v, %v, want %v, %v", price, found, tt.expected, tt.expectedFound)
+			}
+
+			// The filtered document returns the same price for every lookup
+			price, found = doc.FilterByBusinessType(tt.businessType).LookupPriceByTime(ts)
+			if found != tt.expectedFound || price != tt.expected {
+				t.Errorf("FilterByBusinessType().LookupPriceByTime() = %v, %v, want %v, %v", price, found, tt.expected, tt.expectedFound)
+			}
+		})
+	}
+
+	if len(doc.TimeSeries) != 2 {
+		t.Errorf("Expected the original document to keep 2 TimeSeries, got %d", len(doc.TimeSeries))
+	}
+}
+
+func TestMissingHours(t *testing.T) {
+	start := time.Date(2025, 9, 4, 22, 0, 0, 0, time.UTC)
+	series := func(from, to int, price float64) TimeSeries {
+		points := make([]Point, to-from)
+		for i := range points {
+			points[i] = Point{Position: i + 1, PriceAmount: price}
+		}
+		return TimeSeries{
+			Period: Period{
+				TimeInterval: TimeInterval{Start: start.Add(time.Duration(from) * time.Hour), End: start.Add(time.Duration(to) * time.Hour)},
+				Resolution:   time.Hour,
+				Points:       points,
+			},
+		}
+	}
+	// The hour 04:00-05:00 is not covered by any TimeSeries
+	doc := &PublicationMarketData{
+		PeriodTimeInterval: TimeInterval{Start: start, End: start.Add(24 * time.Hour)},
+		TimeSeries:         []TimeSeries{series(0, 6, 40), series(7, 24, 50)},
+	}
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		hours    int
+		expected []time.Time
+	}{
+		{name: "whole day", start: start, hours: 24, expected: []time.Time{start.Add(6 * time.Hour)}},
+		{name: "before the gap", start: start, hours: 6, expected: nil},
+		{name: "past the end", start: start.Add(22 * time.Hour), hours: 4, expected: []time.Time{start.Add(24 * time.Hour), start.Add(25 * time.Hour)}},
+		{name: "before the start", start: start.Add(-time.Hour), hours: 2, expected: []time.Time{start.Add(-time.Hour)}},
+		{name: "no hours", start: start, hours: 0, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missing := doc.MissingHours(tt.start, tt.hours)
+			if len(missing) != len(tt.expected) {
+				t.Fatalf("MissingHours() = %v, want %v", missing, tt.expected)
+			}
+			for i := range missing {
+				if !missing[i].Equal(tt.expected[i]) {
+					t.Errorf("MissingHours()[%d] = %v, want %v", i, missing[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeEnergyPricesXML_NormalizesUnits(t *testing.T) {
+	document := func(unit, currency, price string) string {
+		return `<?xml version="1.0" encoding="UTF-8"?>
+<Publication_MarketDocument xmlns="urn:iec62325.351:tc57wg16:451-3:publicationdocument:7:3">
+    <period.timeInterval>
+        <start>2025-09-04T22:00Z</start>
+        <end>2025-09-05T00:00Z</end>
+    </period.timeInterval>
+    <TimeSeries>
+        <businessType>A62</businessType>
+        <currency_Unit.name>` + currency + `</currency_Unit.name>
+        <price_Measure_Unit.name>` + unit + `</price_Measure_Unit.name>
+        <Period>
+            <timeInterval>
+                <start>2025-09-04T22:00Z</start>
+                <end>2025-09-05T00:00Z</end>
+            </timeInterval>
+            <resolution>PT60M</resolution>
+            <Point>
+                <position>1</position>
+                <price.amount>` + price + `</price.amount>
+            </Point>
+            <Point>
+                <position>2</position>
+                <price.amount>-0.005</price.amount>
+            </Point>
+        </Period>
+    </TimeSeries>
+</Publication_MarketDocument>`
+	}
+
+	tests := []struct {
+		name          string
+		unit          string
+		currency      string
+		price         string
+		expectedFirst float64
+		expectedNext  float64
+	}{
+		{name: "MWh unchanged", unit: "MWH", currency: "EUR", price: "85.5", expectedFirst: 85.5, expectedNext: -0.005},
+		{name: "kWh to MWh", unit: "KWH", currency: "EUR", price: "0.0855", expectedFirst: 85.5, expectedNext: -5},
+		{name: "lower case kWh to MWh", unit: "kWh", currency: "EUR", price: "0.0855", expectedFirst: 85.5, expectedNext: -5},
+		{name: "no unit taken as MWh", unit: "", currency: "EUR", price: "85.5", expectedFirst: 85.5, expectedNext: -0.005},
+		{name: "cents per kWh to EUR per MWh", unit: "KWH", currency: "EUR cents", price: "8.55", expectedFirst: 85.5, expectedNext: -0.05},
+		{name: "no currency taken as EUR", unit: "MWH", currency: "", price: "85.5", expectedFirst: 85.5, expectedNext: -0.005},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := DecodeEnergyPricesXML(strings.NewReader(document(tt.unit, tt.currency, tt.price)))
+			if err != nil {
+				t.Fatalf("DecodeEnergyPricesXML() unexpected error = %v", err)
+			}
+
+			first, found := doc.LookupPriceByTime(time.Date(2025, 9, 4, 22, 30, 0, 0, time.UTC))
+			if !found || math.Abs(first-tt.expectedFirst) > 1e-9 {
+				t.Errorf("First price = %v (found %v), want %v", first, found, tt.expectedFirst)
+			}
+			next, found := doc.LookupPriceByTime(time.Date(2025, 9, 4, 23, 30, 0, 0, time.UTC))
+			if !found || math.Abs(next-tt.expectedNext) > 1e-9 {
+				t.Errorf("Second price = %v (found %v), want %v", next, found, tt.expectedNext)
+			}
+			if tt.unit != "" && doc.TimeSeries[0].PriceMeasureUnitName != CanonicalPriceMeasureUnit {
+				t.Errorf("Expected the canonical unit %s, got %s", CanonicalPriceMeasureUnit, doc.TimeSeries[0].PriceMeasureUnitName)
+			}
+			if tt.currency != "" && doc.TimeSeries[0].CurrencyUnitName != CanonicalCurrency {
+				t.Errorf("Expected the canonical currency %s, got %s", CanonicalCurrency, doc.TimeSeries[0].CurrencyUnitName)
+			}
+		})
+	}
+
+	if _, err := DecodeEnergyPricesXML(strings.NewReader(document("BARREL", "EUR", "1"))); err == nil {
+		t.Error("DecodeEnergyPricesXML() expected an error for an unknown measure unit")
+	}
+	if _, err := DecodeEnergyPricesXML(strings.NewReader(document("MWH", "PLN", "1"))); err == nil {
+		t.Error("DecodeEnergyPricesXML() expected an error for a currency other than EUR")
+	}
+}
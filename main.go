@@ -23,6 +23,7 @@ func main() {
 		help       = flag.Bool("help", false, "Show help message")
 		serverOnly = flag.Bool("serverOnly", false, "Run only web server without periodic checks")
 		mpc        = flag.Bool("mpc", false, "Run MPC optimization once and log all decisions")
+		selfTest   = flag.Bool("selftest", false, "Check that all configured integrations are reachable and exit")
 	)
 	flag.Parse()
 
@@ -50,6 +51,13 @@ func main() {
 		return
 	}
 
+	if *selfTest {
+		if !runSelfTest(config) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	fmt.Printf("Starting Energy Management System with the following configuration:\n")
 	fmt.Printf("  Price Limit: %.2f EUR/MWh\n", config.PriceLimit)
 	fmt.Printf("  Network: %s\n", config.Network)
@@ -100,6 +108,32 @@ func main() {
 	logger.Printf("Scheduler stopped successfully")
 }
 
+// runSelfTest checks every configured integration once and prints the results.
+// It returns false if any integration failed.
+func runSelfTest(config *scheduler.Config) bool {
+	logger := log.New(os.Stdout, "[SELFTEST] ", log.LstdFlags)
+	minerScheduler := scheduler.NewMinerScheduler(config, logger)
+
+	results := minerScheduler.SelfTest(context.Background())
+
+	fmt.Println()
+	ok := true
+	for _, result := range results {
+		line := fmt.Sprintf("  %-10s %-8s", result.Name, result.Status)
+		if result.Status != scheduler.SelfTestSkipped {
+			line += fmt.Sprintf(" %8s", result.Duration.Round(time.Millisecond))
+		}
+		if result.Error != "" {
+			line += "  " + result.Error
+		}
+		fmt.Println(line)
+		if result.Status == scheduler.SelfTestFailed {
+			ok = false
+		}
+	}
+	return ok
+}
+
 func runMPCOptimize(config *scheduler.Config) {
 	logger := log.New(os.Stdout, "[MPC] ", log.LstdFlags)
 
@@ -126,22 +160,29 @@ func runMPCOptimize(config *scheduler.Config) {
 		return
 	}
 
+	// Show slot times on the market clock, the hour index restarts at the current slot and hides midnight
+	location, err := time.LoadLocation(config.Location)
+	if err != nil {
+		logger.Printf("Invalid location %q, showing times in local time: %v", config.Location, err)
+		location = time.Local
+	}
+
 	fmt.Println("\n========================================")
 	fmt.Println("MPC OPTIMIZATION RESULTS")
 	fmt.Println("========================================")
 	fmt.Printf("Total decisions: %d\n\n", len(decisions))
 
 	// Print table header
-	fmt.Println("┌──────┬─────────────────────┬──────────┬──────────┬──────────┬───────────┬────────────┬────────────┬────────────┬──────────┬────────────┬────────────┬──────────┬──────────┐")
-	fmt.Println("│ Hour │     Timestamp       │ Batt SOC │ Chr (PV) │ Chr (Grd)│ Bat Disch │ Grid Imprt │ Grid Exprt │ Solar Fcst │ Load Fst │ Imprt Prce │ Exprt Prce │ Bat Temp │  Profit  │")
-	fmt.Println("│      │                     │    (%)   │   (kW)   │   (kW)   │   (kW)    │    (kW)    │    (kW)    │    (kW)    │   (kW)   │ (EUR/MWh)  │ (EUR/MWh)  │   (°C)   │   (EUR)  │")
-	fmt.Println("├──────┼─────────────────────┼──────────┼──────────┼──────────┼───────────┼────────────┼────────────┼────────────┼──────────┼────────────┼────────────┼──────────┼──────────┤")
+	fmt.Println("┌───────┬─────────────────────┬──────────┬──────────┬──────────┬───────────┬────────────┬────────────┬────────────┬──────────┬────────────┬────────────┬──────────┬──────────┐")
+	fmt.Println("│ Time  │     Timestamp       │ Batt SOC │ Chr (PV) │ Chr (Grd)│ Bat Disch │ Grid Imprt │ Grid Exprt │ Solar Fcst │ Load Fst │ Imprt Prce │ Exprt Prce │ Bat Temp │  Profit  │")
+	fmt.Println("│       │                     │    (%)   │   (kW)   │   (kW)   │   (kW)    │    (kW)    │    (kW)    │    (kW)    │   (kW)   │ (EUR/MWh)  │ (EUR/MWh)  │   (°C)   │   (EUR)  │")
+	fmt.Println("├───────┼─────────────────────┼──────────┼──────────┼──────────┼───────────┼────────────┼────────────┼────────────┼──────────┼────────────┼────────────┼──────────┼──────────┤")
 
 	totalProfit := 0.0
 	for _, dec := range decisions {
-		timestamp := time.Unix(dec.Timestamp, 0).Format("2006-01-02 15:04")
-		fmt.Printf("│ %4d │ %19s │  %6.1f  │  %6.2f  │  %6.2f  │   %6.2f  │   %6.2f   │   %6.2f   │   %6.2f   │  %6.2f  │   %6.2f   │   %6.2f   │  %6.1f  │  %6.4f  │\n",
-			dec.Hour,
+		timestamp := time.Unix(dec.Timestamp, 0).In(location).Format("2006-01-02 15:04")
+		fmt.Printf("│ %5s │ %19s │  %6.1f  │  %6.2f  │  %6.2f  │   %6.2f  │   %6.2f   │   %6.2f   │   %6.2f   │  %6.2f  │   %6.2f   │   %6.2f   │  %6.1f  │  %6.4f  │\n",
+			formatDecisionTime(dec.Timestamp, location),
 			timestamp,
 			dec.BatterySOC*100,
 			dec.BatteryChargeFromPV,
@@ -159,7 +200,7 @@ func runMPCOptimize(config *scheduler.Config) {
 		totalProfit += dec.Profit
 	}
 
-	fmt.Println("└──────┴─────────────────────┴──────────┴──────────┴──────────┴───────────┴────────────┴────────────┴────────────┴──────────┴────────────┴────────────┴──────────┴──────────┘")
+	fmt.Println("└───────┴─────────────────────┴──────────┴──────────┴──────────┴───────────┴────────────┴────────────┴────────────┴──────────┴────────────┴────────────┴──────────┴──────────┘")
 	fmt.Println("\n========================================")
 	fmt.Println("SUMMARY")
 	fmt.Println("========================================")
@@ -168,6 +209,11 @@ func runMPCOptimize(config *scheduler.Config) {
 	fmt.Println("========================================")
 }
 
+// formatDecisionTime returns the clock time at which a decision's time slot begins in location
+func formatDecisionTime(timestamp int64, location *time.Location) string {
+	return time.Unix(timestamp, 0).In(location).Format("15:04")
+}
+
 func showHelp() {
 	fmt.Println("Energy Management System (EMS) - Optimize energy consumption, production, and storage")
 	fmt.Println()
@@ -206,6 +252,9 @@ func showHelp() {
 	fmt.Println("  # Run MPC optimization once and log all decisions")
 	fmt.Println("  ems -mpc")
 	fmt.Println()
+	fmt.Println("  # Check that prices, weather, plant and database are reachable")
+	fmt.Println("  ems -selftest")
+	fmt.Println()
 	fmt.Println("  # Show this help")
 	fmt.Println("  ems -help")
 }
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatDecisionTime(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Riga")
+	if err != nil {
+		t.Skipf("Time zone data not available: %v", err)
+	}
+
+	// 15 minute slots from 23:30 local time, past midnight into the next day
+	start := time.Date(2024, 6, 15, 23, 30, 0, 0, location).Unix()
+	expected := []string{"23:30", "23:45", "00:00", "00:15", "00:30"}
+	for i, want := range expected {
+		if got := formatDecisionTime(start+int64(i*900), location); got != want {
+			t.Errorf("Slot %d: expected %s, got %s", i, want, got)
+		}
+	}
+
+	// The same instant is shown on the clock of the configured location, not UTC
+	if got := formatDecisionTime(start, time.UTC); got != "20:30" {
+		t.Errorf("Expected 20:30 UTC, got %s", got)
+	}
+}
+
+// TestInternalImportsUseModulePath checks that every package of the repository imports its siblings
+// under the module path of go.mod, so a stale import path of a renamed module cannot slip in
+func TestInternalImportsUseModulePath(t *testing.T) {
+	data, err := os.ReadFile("go.mod")
+	if err != nil {
+		t.Fatalf("Failed to read go.mod: %v", err)
+	}
+	var modulePath string
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			modulePath = strings.TrimSpace(path)
+			break
+		}
+	}
+	if modulePath == "" {
+		t.Fatal("No module path in go.mod")
+	}
+	// Other module paths of the same owner, e.g. github.com/devskill-org/miners-scheduler
+	ownerPrefix := modulePath[:strings.LastIndex(modulePath, "/")+1]
+
+	fset := token.NewFileSet()
+	err = filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Directories ignored by the go tool
+			name := d.Name()
+			if path != "." && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		for _, spec := range file.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(importPath, ownerPrefix) && importPath != modulePath && !strings.HasPrefix(importPath, modulePath+"/") {
+				t.Errorf("%s imports %s, internal packages must be imported under %s", fset.Position(spec.Pos()), importPath, modulePath)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk the repository: %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package meteo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ClassicForecast represents the root of a forecast in the classic XML format
+type ClassicForecast struct {
+	XMLName xml.Name       `xml:"weatherdata"`
+	Created time.Time      `xml:"created,attr"`
+	Product ClassicProduct `xml:"product"`
+}
+
+// ClassicProduct contains the forecast entries of a classic forecast
+type ClassicProduct struct {
+	Class string        `xml:"class,attr"`
+	Times []ClassicTime `xml:"time"`
+}
+
+// ClassicTime is one forecast entry. Instant parameters have the same from and to time,
+// period parameters such as precipitation and the symbol are valid from from to to.
+type ClassicTime struct {
+	DataType string          `xml:"datatype,attr"`
+	From     time.Time       `xml:"from,attr"`
+	To       time.Time       `xml:"to,attr"`
+	Location ClassicLocation `xml:"location"`
+}
+
+// ClassicLocation contains the parameters of a forecast entry
+type ClassicLocation struct {
+	Altitude            float64               `xml:"altitude,attr"`
+	Latitude            float64               `xml:"latitude,attr"`
+	Longitude           float64               `xml:"longitude,attr"`
+	Temperature         *ClassicValue         `xml:"temperature"`
+	WindDirection       *ClassicDirection     `xml:"windDirection"`
+	WindSpeed           *ClassicSpeed         `xml:"windSpeed"`
+	WindGust            *ClassicSpeed         `xml:"windGust"`
+	Humidity            *ClassicValue         `xml:"humidity"`
+	Pressure            *ClassicValue         `xml:"pressure"`
+	Cloudiness          *ClassicPercent       `xml:"cloudiness"`
+	Fog                 *ClassicPercent       `xml:"fog"`
+	LowClouds           *ClassicPercent       `xml:"lowClouds"`
+	MediumClouds        *ClassicPercent       `xml:"mediumClouds"`
+	HighClouds          *ClassicPercent       `xml:"highClouds"`
+	DewpointTemperature *ClassicValue         `xml:"dewpointTemperature"`
+	Precipitation       *ClassicPrecipitation `xml:"precipitation"`
+	MinTemperature      *ClassicValue         `xml:"minTemperature"`
+	MaxTemperature      *ClassicValue         `xml:"maxTemperature"`
+	Symbol              *ClassicSymbol        `xml:"symbol"`
+}
+
+// ClassicValue is a parameter given as a value with its unit
+type ClassicValue struct {
+	Unit  string  `xml:"unit,attr"`
+	Value float64 `xml:"value,attr"`
+}
+
+// ClassicDirection is a wind direction in degrees
+type ClassicDirection struct {
+	Deg  float64 `xml:"deg,attr"`
+	Name string  `xml:"name,attr"`
+}
+
+// ClassicSpeed is a wind speed in m/s
+type ClassicSpeed struct {
+	MPS float64 `xml:"mps,attr"`
+}
+
+// ClassicPercent is an area fraction in %
+type ClassicPercent struct {
+	Percent float64 `xml:"percent,attr"`
+}
+
+// ClassicPrecipitation is the precipitation over the period of an entry
+type ClassicPrecipitation struct {
+	Unit        string   `xml:"unit,attr"`
+	Value       float64  `xml:"value,attr"`
+	MinValue    *float64 `xml:"minvalue,attr"`
+	MaxValue    *float64 `xml:"maxvalue,attr"`
+	Probability *float64 `xml:"probability,attr"`
+}
+
+// ClassicSymbol is the weather symbol over the period of an entry
+type ClassicSymbol struct {
+	Code WeatherSymbol `xml:"code,attr"`
+}
+
+// DecodeClassic decodes a forecast in the classic XML format
+func DecodeClassic(data []byte) (*ClassicForecast, error) {
+	var forecast ClassicForecast
+	if err := xml.Unmarshal(data, &forecast); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal classic forecast: %w", err)
+	}
+	return &forecast, nil
+}
+
+// Normalize converts a classic forecast into the structure of the JSON endpoints, so the same accessors
+// such as GetTemperature and GetCloudCoverage work for it. Instant entries become the instant details of
+// the time step at their time, 1, 6 and 12 hour period entries the next_1_hours, next_6_hours and
+// next_12_hours data of the time step they start at. Periods of other lengths are dropped.
+func (cf *ClassicForecast) Normalize() *METJSONForecast {
+	if cf == nil {
+		return nil
+	}
+
+	steps := make(map[time.Time]*ForecastTimeStepData)
+	step := func(t time.Time) *ForecastTimeStepData {
+		data, ok := steps[t]
+		if !ok {
+			data = &ForecastTimeStepData{}
+			steps[t] = data
+		}
+		return data
+	}
+
+	var geometry *PointGeometry
+	for _, entry := range cf.Product.Times {
+		location := entry.Location
+		if geometry == nil {
+			geometry = &PointGeometry{
+				Type:        "Point",
+				Coordinates: []float64{location.Longitude, location.Latitude, location.Altitude},
+			}
+		}
+
+		if entry.To.Equal(entry.From) {
+			step(entry.From).Instant = &ForecastInstantData{Details: location.instantDetails()}
+			continue
+		}
+
+		period := &ForecastPeriodData{Details: location.periodDetails()}
+		if location.Symbol != nil {
+			period.Summary = &ForecastSummary{SymbolCode: location.Symbol.Code}
+		}
+		switch entry.To.Sub(entry.From) {
+		case time.Hour:
+			step(entry.From).Next1Hours = period
+		case 6 * time.Hour:
+			step(entry.From).Next6Hours = period
+		case 12 * time.Hour:
+			step(entry.From).Next12Hours = period
+		}
+	}
+
+	timeseries := make([]ForecastTimeStep, 0, len(steps))
+	for t, data := range steps {
+		timeseries = append(timeseries, ForecastTimeStep{Time: t, Data: data})
+	}
+	slices.SortFunc(timeseries, func(a, b ForecastTimeStep) int { return a.Time.Compare(b.Time) })
+
+	return &METJSONForecast{
+		Type:     "Feature",
+		Geometry: geometry,
+		Properties: &Forecast{
+			Meta:       ForecastMeta{UpdatedAt: cf.Created},
+			Timeseries: timeseries,
+		},
+	}
+}
+
+// instantDetails returns the instant parameters of an entry
+func (l ClassicLocation) instantDetails() *ForecastTimeInstant {
+	details := &ForecastTimeInstant{}
+	if l.Temperature != nil {
+		details.AirTemperature = Float64Ptr(l.Temperature.Value)
+	}
+	if l.WindDirection != nil {
+		details.WindFromDirection = Float64Ptr(l.WindDirection.Deg)
+	}
+	if l.WindSpeed != nil {
+		details.WindSpeed = Float64Ptr(l.WindSpeed.MPS)
+	}
+	if l.WindGust != nil {
+		details.WindSpeedOfGust = Float64Ptr(l.WindGust.MPS)
+	}
+	if l.Humidity != nil {
+		details.RelativeHumidity = Float64Ptr(l.Humidity.Value)
+	}
+	if l.Pressure != nil {
+		details.AirPressureAtSeaLevel = Float64Ptr(l.Pressure.Value)
+	}
+	if l.Cloudiness != nil {
+		details.CloudAreaFraction = Float64Ptr(l.Cloudiness.Percent)
+	}
+	if l.Fog != nil {
+		details.FogAreaFraction = Float64Ptr(l.Fog.Percent)
+	}
+	if l.LowClouds != nil {
+		details.CloudAreaFractionLow = Float64Ptr(l.LowClouds.Percent)
+	}
+	if l.MediumClouds != nil {
+		details.CloudAreaFractionMedium = Float64Ptr(l.MediumClouds.Percent)
+	}
+	if l.HighClouds != nil {
+		details.CloudAreaFractionHigh = Float64Ptr(l.HighClouds.Percent)
+	}
+	if l.DewpointTemperature != nil {
+		details.DewPointTemperature = Float64Ptr(l.DewpointTemperature.Value)
+	}
+	return details
+}
+
+// periodDetails returns the period parameters of an entry
+func (l ClassicLocation) periodDetails() *ForecastTimePeriod {
+	details := &ForecastTimePeriod{}
+	if l.Precipitation != nil {
+		details.PrecipitationAmount = Float64Ptr(l.Precipitation.Value)
+		details.PrecipitationAmountMin = l.Precipitation.MinValue
+		details.PrecipitationAmountMax = l.Precipitation.MaxValue
+		details.ProbabilityOfPrecipitation = l.Precipitation.Probability
+	}
+	if l.MinTemperature != nil {
+		details.AirTemperatureMin = Float64Ptr(l.MinTemperature.Value)
+	}
+	if l.MaxTemperature != nil {
+		details.AirTemperatureMax = Float64Ptr(l.MaxTemperature.Value)
+	}
+	return details
+}
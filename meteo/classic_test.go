@@ -0,0 +1,177 @@
+package meteo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// classicTestForecast is a classic forecast with an instant entry at 12:00 and its 1 and 6 hour periods,
+// and an instant entry at 13:00
+const classicTestForecast = `<?xml version="1.0" encoding="UTF-8"?>
+<weatherdata created="2024-06-15T10:05:00Z">
+  <meta>
+    <model name="met_public_forecast" termin="2024-06-15T09:00:00Z" runended="2024-06-15T09:45:00Z" nextrun="2024-06-15T11:00:00Z" from="2024-06-15T12:00:00Z" to="2024-06-24T12:00:00Z"/>
+  </meta>
+  <product class="pointData">
+    <time datatype="forecast" from="2024-06-15T12:00:00Z" to="2024-06-15T12:00:00Z">
+      <location altitude="10" latitude="56.9496" longitude="24.1052">
+        <temperature id="TTT" unit="celsius" value="18.3"/>
+        <windDirection id="dd" deg="202.5" name="SSW"/>
+        <windSpeed id="ff" mps="4.2" beaufort="3" name="Lett bris"/>
+        <windGust id="ff_gust" mps="7.1"/>
+        <humidity value="65.2" unit="percent"/>
+        <pressure id="pr" unit="hPa" value="1012.3"/>
+        <cloudiness id="NN" percent="45.3"/>
+        <fog id="FOG" percent="0.0"/>
+        <lowClouds id="LOW" percent="20.1"/>
+        <mediumClouds id="MEDIUM" percent="10.0"/>
+        <highClouds id="HIGH" percent="30.0"/>
+        <dewpointTemperature id="TD" unit="celsius" value="11.7"/>
+      </location>
+    </time>
+    <time datatype="forecast" from="2024-06-15T12:00:00Z" to="2024-06-15T13:00:00Z">
+      <location altitude="10" latitude="56.9496" longitude="24.1052">
+        <precipitation unit="mm" value="0.4" minvalue="0.1" maxvalue="0.9" probability="60.0"/>
+        <symbol id="LightRain" number="46" code="lightrain"/>
+      </location>
+    </time>
+    <time datatype="forecast" from="2024-06-15T12:00:00Z" to="2024-06-15T18:00:00Z">
+      <location altitude="10" latitude="56.9496" longitude="24.1052">
+        <precipitation unit="mm" value="1.2"/>
+        <minTemperature id="TTT" unit="celsius" value="16.0"/>
+        <maxTemperature id="TTT" unit="celsius" value="19.5"/>
+        <symbol id="Rain" number="9" code="rain"/>
+      </location>
+    </time>
+    <time datatype="forecast" from="2024-06-15T13:00:00Z" to="2024-06-15T13:00:00Z">
+      <location altitude="10" latitude="56.9496" longitude="24.1052">
+        <temperature id="TTT" unit="celsius" value="19.1"/>
+        <cloudiness id="NN" percent="80.0"/>
+      </location>
+    </time>
+  </product>
+</weatherdata>`
+
+func TestClassicForecast_Normalize(t *testing.T) {
+	classic, err := DecodeClassic([]byte(classicTestForecast))
+	if err != nil {
+		t.Fatalf("DecodeClassic failed: %v", err)
+	}
+
+	forecast := classic.Normalize()
+	if forecast.Properties == nil || len(forecast.Properties.Timeseries) != 2 {
+		t.Fatalf("Expected 2 time steps, got %+v", forecast.Properties)
+	}
+	if updatedAt := time.Date(2024, 6, 15, 10, 5, 0, 0, time.UTC); !forecast.Properties.Meta.UpdatedAt.Equal(updatedAt) {
+		t.Errorf("Expected updated at %v, got %v", updatedAt, forecast.Properties.Meta.UpdatedAt)
+	}
+	if forecast.Geometry == nil || len(forecast.Geometry.Coordinates) != 3 ||
+		forecast.Geometry.Coordinates[0] != 24.1052 || forecast.Geometry.Coordinates[1] != 56.9496 {
+		t.Errorf("Expected the location as [lon, lat, altitude], got %+v", forecast.Geometry)
+	}
+
+	noon := forecast.GetWeatherAtTime(time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC))
+	checks := []struct {
+		name     string
+		value    *float64
+		expected float64
+	}{
+		{name: "temperature", value: noon.GetTemperature(), expected: 18.3},
+		{name: "cloud coverage", value: noon.GetCloudCoverage(), expected: 45.3},
+		{name: "wind speed", value: noon.GetWindSpeed(), expected: 4.2},
+		{name: "wind direction", value: noon.GetWindDirection(), expected: 202.5},
+		{name: "humidity", value: noon.GetHumidity(), expected: 65.2},
+		{name: "precipitation", value: noon.Data.Next1Hours.Details.PrecipitationAmount, expected: 0.4},
+		{name: "precipitation max", value: noon.Data.Next1Hours.Details.PrecipitationAmountMax, expected: 0.9},
+		{name: "6 hour max temperature", value: noon.Data.Next6Hours.Details.AirTemperatureMax, expected: 19.5},
+	}
+	for _, check := range checks {
+		if check.value == nil || *check.value != check.expected {
+			t.Errorf("Expected %s %.1f, got %v", check.name, check.expected, check.value)
+		}
+	}
+	if cardinal := noon.WindCardinal(); cardinal != "SSW" {
+		t.Errorf("Expected wind from SSW, got %q", cardinal)
+	}
+	if symbol := noon.GetSymbolCode(); symbol == nil || *symbol != LightRain {
+		t.Errorf("Expected the 1 hour symbol %s, got %v", LightRain, symbol)
+	}
+	if !noon.HasPrecipitation() {
+		t.Error("Expected precipitation at 12:00")
+	}
+
+	// A step with only instant parameters has no symbol or precipitation
+	afternoon := forecast.GetWeatherAtTime(time.Date(2024, 6, 15, 13, 0, 0, 0, time.UTC))
+	if temp := afternoon.GetTemperature(); temp == nil || *temp != 19.1 {
+		t.Errorf("Expected temperature 19.1 at 13:00, got %v", temp)
+	}
+	if symbol := afternoon.GetSymbolCode(); symbol != nil {
+		t.Errorf("Expected no symbol at 13:00, got %s", *symbol)
+	}
+	if afternoon.HasPrecipitation() {
+		t.Error("Expected no precipitation at 13:00")
+	}
+}
+
+func TestGetClassic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/classic" {
+			t.Errorf("Expected path /classic, got %s", r.URL.Path)
+		}
+		if accept := r.Header.Get("Accept"); accept != "application/xml" {
+			t.Errorf("Expected Accept 'application/xml', got '%s'", accept)
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(classicTestForecast))
+	}))
+	defer server.Close()
+
+	client := NewClient("TestApp/1.0")
+	client.SetBaseURL(server.URL)
+	params := QueryParams{Location: Location{Latitude: 56.9496, Longitude: 24.1052}}
+
+	forecast, err := client.GetClassic(params)
+	if err != nil {
+		t.Fatalf("GetClassic returned error: %v", err)
+	}
+	noon := forecast.GetWeatherAtTime(time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC))
+	if temp := noon.GetTemperature(); temp == nil || *temp != 18.3 {
+		t.Errorf("Expected temperature 18.3, got %v", temp)
+	}
+	if symbol := noon.GetSymbolCode(); symbol == nil || *symbol != LightRain {
+		t.Errorf("Expected symbol %s, got %v", LightRain, symbol)
+	}
+}
+
+func TestGetClassic_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("lat") == "0" {
+			w.Write([]byte(`{"type": "Feature"}`))
+			return
+		}
+		http.Error(w, "Unprocessable Entity", http.StatusUnprocessableEntity)
+	}))
+	defer server.Close()
+
+	client := NewClient("TestApp/1.0")
+	client.SetBaseURL(server.URL)
+
+	var apiErr *APIError
+	if _, err := client.GetClassic(QueryParams{Location: Location{Latitude: 56.9496, Longitude: 24.1052}}); !errors.As(err, &apiErr) ||
+		apiErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected an APIError with status 422, got %v", err)
+	}
+
+	if _, err := client.GetClassic(QueryParams{}); err == nil {
+		t.Error("Expected an error for a response that is not classic XML")
+	}
+}
+
+func TestDecodeClassic_Invalid(t *testing.T) {
+	if _, err := DecodeClassic([]byte(`{"type": "Feature"}`)); err == nil {
+		t.Error("Expected an error for a JSON document")
+	}
+}
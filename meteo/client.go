@@ -1,12 +1,17 @@
 package meteo
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,26 +20,70 @@ type Client struct {
 	httpClient *http.Client
 	baseURL    string
 	userAgent  string
+
+	// Rate limiting, requests are spaced at least minInterval apart
+	rateMu      sync.Mutex
+	minInterval time.Duration
+	nextRequest time.Time
+
+	// Retries of temporary API errors, see WithRetryPolicy
+	retry RetryPolicy
+
+	// Request options, see SetAcceptLanguage, SetExtraQuery, SetFields and SetUnits
+	acceptLanguage string
+	extraQuery     url.Values
+	fields         *fieldDecoder
+	units          Units
+
+	// Forecast responses by request URL, reused until they expire and revalidated with If-Modified-Since
+	cacheMu sync.Mutex
+	cache   map[string]*cachedResponse
 }
 
-// NewClient creates a new client for the MET Norway Location Forecast API
-func NewClient(userAgent string) *Client {
-	return &Client{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		baseURL:   "https://api.met.no/weatherapi/locationforecast/2.0",
-		userAgent: userAgent,
+// cachedResponse is a forecast response body with the Expires and Last-Modified headers it was sent with
+type cachedResponse struct {
+	body         []byte
+	expires      time.Time // zero when the response had no valid Expires header
+	lastModified string
+}
+
+// response is a successful API response. notModified is set for a 304 reply to a conditional request,
+// it has no body.
+type response struct {
+	body        []byte
+	header      http.Header
+	notModified bool
+}
+
+// ClientOption configures a client when it is created
+type ClientOption func(*Client)
+
+// WithRetryPolicy sets how requests failing with 429 Too Many Requests or a temporary server error are retried,
+// DefaultRetryPolicy by default. A policy with MaxAttempts of 1 disables retries. The policy is fixed for the
+// lifetime of the client, so a client shared between goroutines keeps retrying the same way.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retry = policy
 	}
 }
 
+// NewClient creates a new client for the MET Norway Location Forecast API
+func NewClient(userAgent string, opts ...ClientOption) *Client {
+	return NewClientWithHTTPClient(&http.Client{Timeout: 30 * time.Second}, userAgent, opts...)
+}
+
 // NewClientWithHTTPClient creates a new client with a custom HTTP client
-func NewClientWithHTTPClient(httpClient *http.Client, userAgent string) *Client {
-	return &Client{
+func NewClientWithHTTPClient(httpClient *http.Client, userAgent string, opts ...ClientOption) *Client {
+	c := &Client{
 		httpClient: httpClient,
 		baseURL:    "https://api.met.no/weatherapi/locationforecast/2.0",
 		userAgent:  userAgent,
+		retry:      DefaultRetryPolicy(),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
 // SetBaseURL sets the base URL for the API (useful for testing)
@@ -42,36 +91,232 @@ func (c *Client) SetBaseURL(baseURL string) {
 	c.baseURL = baseURL
 }
 
+// ClearCache drops all cached forecast responses, the next request for each location goes to the network
+// without If-Modified-Since
+func (c *Client) ClearCache() {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	c.cache = nil
+}
+
+// SetRateLimit spaces all requests made by the client at least minInterval apart (0 disables rate limiting)
+func (c *Client) SetRateLimit(minInterval time.Duration) {
+	c.rateMu.Lock()
+	defer c.rateMu.Unlock()
+	c.minInterval = minInterval
+}
+
+// SetUnits selects the units returned by the accessor helpers of the forecasts fetched by the client,
+// Metric by default. The forecast data keeps the SI units of the API, see Units.
+func (c *Client) SetUnits(units Units) {
+	c.units = units
+}
+
+// SetAcceptLanguage sets the Accept-Language header sent with all requests ("" = not sent)
+func (c *Client) SetAcceptLanguage(language string) {
+	c.acceptLanguage = language
+}
+
+// SetExtraQuery sets query parameters passed through with every forecast request, e.g. for parameters the
+// client does not wrap. The location parameters of the request take precedence over the same names here.
+func (c *Client) SetExtraQuery(query url.Values) {
+	c.extraQuery = query
+}
+
+// SetFields limits the parsed detail parameters of forecasts to the given names, e.g. "air_temperature" or
+// "cloud_area_fraction". Other parameters are left nil and skipped while decoding, which saves allocations
+// for high-frequency polling. Symbol codes are always parsed, no names parse all parameters.
+// The fields do not apply to GetClassic. An unknown name is returned as a *ValidationError.
+func (c *Client) SetFields(fields ...string) error {
+	if len(fields) == 0 {
+		c.fields = nil
+		return nil
+	}
+	decoder, err := newFieldDecoder(fields)
+	if err != nil {
+		return err
+	}
+	c.fields = decoder
+	return nil
+}
+
+// FetchRaw performs a GET request for an endpoint that is not wrapped by the client, e.g. nowcast or
+// air quality, and returns the raw response body for the caller to decode. The User-Agent, base URL
+// and rate limit of the client are applied. A path starting with "/" replaces the path of the base URL,
+// any other path is appended to it.
+func (c *Client) FetchRaw(ctx context.Context, path string, query url.Values) ([]byte, error) {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build URL: %w", err)
+	}
+	if strings.HasPrefix(path, "/") {
+		u.Path = path
+	} else {
+		u.Path = fmt.Sprintf("%s/%s", strings.TrimSuffix(u.Path, "/"), path)
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.body, nil
+}
+
 // GetCompact retrieves compact forecast data for the specified location
 func (c *Client) GetCompact(params QueryParams) (*METJSONForecast, error) {
-	return c.getForecast("compact", params)
+	return c.GetCompactWithContext(context.Background(), params)
+}
+
+// GetCompactWithContext retrieves compact forecast data for the specified location.
+// The request is cancelled when ctx is done.
+func (c *Client) GetCompactWithContext(ctx context.Context, params QueryParams) (*METJSONForecast, error) {
+	return c.getForecast(ctx, "compact", params)
 }
 
 // GetComplete retrieves complete forecast data for the specified location
 func (c *Client) GetComplete(params QueryParams) (*METJSONForecast, error) {
-	return c.getForecast("complete", params)
+	return c.GetCompleteWithContext(context.Background(), params)
 }
 
-// GetClassic retrieves classic forecast data for the specified location
+// GetCompleteWithContext retrieves complete forecast data for the specified location.
+// The request is cancelled when ctx is done.
+func (c *Client) GetCompleteWithContext(ctx context.Context, params QueryParams) (*METJSONForecast, error) {
+	return c.getForecast(ctx, "complete", params)
+}
+
+// GetClassic retrieves classic forecast data for the specified location.
+// The classic XML response is normalized into the structure of the JSON endpoints, see ClassicForecast.Normalize.
 func (c *Client) GetClassic(params QueryParams) (*METJSONForecast, error) {
-	return c.getForecast("classic", params)
+	return c.GetClassicWithContext(context.Background(), params)
 }
 
-// getForecast is the internal method that performs the actual API request
-func (c *Client) getForecast(endpoint string, params QueryParams) (*METJSONForecast, error) {
+// GetClassicWithContext retrieves classic forecast data for the specified location.
+// The request is cancelled when ctx is done.
+func (c *Client) GetClassicWithContext(ctx context.Context, params QueryParams) (*METJSONForecast, error) {
+	body, err := c.fetchForecast(ctx, "classic", "application/xml", params)
+	if err != nil {
+		return nil, err
+	}
+
+	classic, err := DecodeClassic(body)
+	if err != nil {
+		return nil, err
+	}
+	forecast := classic.Normalize()
+	forecast.SetUnits(c.units)
+	return forecast, nil
+}
+
+// getForecast is the internal method that performs the actual API request for the JSON endpoints
+func (c *Client) getForecast(ctx context.Context, endpoint string, params QueryParams) (*METJSONForecast, error) {
+	body, err := c.fetchForecast(ctx, endpoint, "application/json", params)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.fields != nil {
+		forecast, err := c.fields.decode(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		forecast.SetUnits(c.units)
+		return forecast, nil
+	}
+
+	var forecast METJSONForecast
+	if err := json.Unmarshal(body, &forecast); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	forecast.SetUnits(c.units)
+
+	return &forecast, nil
+}
+
+// fetchForecast requests a forecast endpoint accepting the given content type and returns the response body.
+// As required by the MET terms of service, a cached response is returned without a request until its Expires
+// time has passed, and is then revalidated with If-Modified-Since: a 304 reply returns the cached body.
+func (c *Client) fetchForecast(ctx context.Context, endpoint, accept string, params QueryParams) ([]byte, error) {
 	reqURL, err := c.buildURL(endpoint, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build URL: %w", err)
 	}
 
-	req, err := http.NewRequest("GET", reqURL, nil)
+	c.cacheMu.Lock()
+	cached := c.cache[reqURL]
+	c.cacheMu.Unlock()
+	if cached != nil && time.Now().Before(cached.expires) {
+		return cached.body, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Header.Set("Accept", accept)
+	if cached != nil && cached.lastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.lastModified)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &cachedResponse{body: resp.body, lastModified: resp.header.Get("Last-Modified")}
+	if resp.notModified {
+		entry.body = cached.body
+		if entry.lastModified == "" {
+			entry.lastModified = cached.lastModified
+		}
+	}
+	if expires, err := http.ParseTime(resp.header.Get("Expires")); err == nil {
+		entry.expires = expires
+	}
 
-	// Set required headers
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	if entry.expires.IsZero() && entry.lastModified == "" {
+		delete(c.cache, reqURL)
+	} else {
+		if c.cache == nil {
+			c.cache = make(map[string]*cachedResponse)
+		}
+		c.cache[reqURL] = entry
+	}
+	return entry.body, nil
+}
+
+// do sets the required headers and performs the request, retrying temporary API errors as set by the retry
+// policy. It returns the response, or an APIError for a status other than 200 and 304 to a conditional request.
+// Waiting for a retry ends when the request context is done.
+func (c *Client) do(req *http.Request) (*response, error) {
 	req.Header.Set("User-Agent", c.userAgent)
-	req.Header.Set("Accept", "application/json")
+	if c.acceptLanguage != "" {
+		req.Header.Set("Accept-Language", c.acceptLanguage)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.doOnce(req)
+		var apiErr *APIError
+		if err == nil || attempt >= c.retry.MaxAttempts || !errors.As(err, &apiErr) || !apiErr.Retryable() {
+			return resp, err
+		}
+		if err := sleep(req.Context(), c.retry.delay(attempt, apiErr.RetryAfter)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// doOnce waits for the rate limit and performs a single attempt of the request
+func (c *Client) doOnce(req *http.Request) (*response, error) {
+	if err := c.waitRateLimit(req.Context()); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -79,11 +324,15 @@ func (c *Client) getForecast(endpoint string, params QueryParams) (*METJSONForec
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && req.Header.Get("If-Modified-Since") != "" {
+		return &response{header: resp.Header, notModified: true}, nil
+	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		return nil, &APIError{
 			StatusCode: resp.StatusCode,
 			Message:    string(body),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
 		}
 	}
 
@@ -91,13 +340,29 @@ func (c *Client) getForecast(endpoint string, params QueryParams) (*METJSONForec
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
+	return &response{body: body, header: resp.Header}, nil
+}
 
-	var forecast METJSONForecast
-	if err := json.Unmarshal(body, &forecast); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+// waitRateLimit reserves the next request slot and waits until it is reached or ctx is done
+func (c *Client) waitRateLimit(ctx context.Context) error {
+	c.rateMu.Lock()
+	if c.minInterval <= 0 {
+		c.rateMu.Unlock()
+		return nil
 	}
+	now := time.Now()
+	slot := now
+	if c.nextRequest.After(now) {
+		slot = c.nextRequest
+	}
+	c.nextRequest = slot.Add(c.minInterval)
+	c.rateMu.Unlock()
 
-	return &forecast, nil
+	delay := slot.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	return sleep(ctx, delay)
 }
 
 // buildURL constructs the API URL with query parameters
@@ -110,6 +375,9 @@ func (c *Client) buildURL(endpoint string, params QueryParams) (string, error) {
 	u.Path = fmt.Sprintf("%s/%s", u.Path, endpoint)
 
 	query := u.Query()
+	for name, values := range c.extraQuery {
+		query[name] = values
+	}
 	query.Set("lat", formatFloat(params.Location.Latitude))
 	query.Set("lon", formatFloat(params.Location.Longitude))
 
@@ -126,16 +394,37 @@ func formatFloat(f float64) string {
 	return strconv.FormatFloat(f, 'f', -1, 64)
 }
 
-// ValidateLocation validates that the location parameters are within acceptable ranges
+// ValidateLocation validates that the location parameters are within acceptable ranges.
+// Invalid parameters are returned as a *ValidationError. Locations outside of MET's high-resolution
+// domain are valid, see LocationCoverageWarning.
 func ValidateLocation(loc Location) error {
-	if loc.Latitude < -90 || loc.Latitude > 90 {
-		return fmt.Errorf("latitude must be between -90 and 90, got %f", loc.Latitude)
+	if math.IsNaN(loc.Latitude) || loc.Latitude < -90 || loc.Latitude > 90 {
+		return &ValidationError{Field: "latitude", Message: fmt.Sprintf("must be between -90 and 90, got %f", loc.Latitude)}
 	}
-	if loc.Longitude < -180 || loc.Longitude > 180 {
-		return fmt.Errorf("longitude must be between -180 and 180, got %f", loc.Longitude)
+	if math.IsNaN(loc.Longitude) || loc.Longitude < -180 || loc.Longitude > 180 {
+		return &ValidationError{Field: "longitude", Message: fmt.Sprintf("must be between -180 and 180, got %f", loc.Longitude)}
 	}
 	if loc.Altitude != nil && *loc.Altitude < 0 {
-		return fmt.Errorf("altitude must be non-negative, got %d", *loc.Altitude)
+		return &ValidationError{Field: "altitude", Message: fmt.Sprintf("must be non-negative, got %d", *loc.Altitude)}
 	}
 	return nil
 }
+
+// Approximate bounding box of the MET Nordic high-resolution domain covering the Nordic and Baltic countries
+const (
+	nordicMinLatitude  = 52.0
+	nordicMaxLatitude  = 73.0
+	nordicMinLongitude = -5.0
+	nordicMaxLongitude = 37.0
+)
+
+// LocationCoverageWarning returns a warning if a valid location is outside of MET's high-resolution Nordic domain.
+// Forecasts there come from the coarser global model and are less accurate. Returns "" inside the domain.
+func LocationCoverageWarning(loc Location) string {
+	if loc.Latitude >= nordicMinLatitude && loc.Latitude <= nordicMaxLatitude &&
+		loc.Longitude >= nordicMinLongitude && loc.Longitude <= nordicMaxLongitude {
+		return ""
+	}
+	return fmt.Sprintf("location (%.4f, %.4f) is outside of MET's high-resolution Nordic domain, forecasts are less accurate",
+		loc.Latitude, loc.Longitude)
+}
@@ -1,9 +1,14 @@
 package meteo
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sync"
 	"testing"
 	"time"
 )
@@ -177,6 +182,48 @@ func TestValidateLocation(t *testing.T) {
 	}
 }
 
+func TestValidateLocation_Coverage(t *testing.T) {
+	tests := []struct {
+		name        string
+		location    Location
+		expectWarn  bool
+		expectField string
+	}{
+		{name: "Oslo", location: Location{Latitude: 59.9139, Longitude: 10.7522}},
+		{name: "Riga", location: Location{Latitude: 56.9496, Longitude: 24.1052}},
+		{name: "Tromsø", location: Location{Latitude: 69.649208, Longitude: 18.955324}},
+		{name: "Sydney", location: Location{Latitude: -33.868820, Longitude: 151.209290}, expectWarn: true},
+		{name: "Madrid", location: Location{Latitude: 40.4168, Longitude: -3.7038}, expectWarn: true},
+		{name: "latitude off the globe", location: Location{Latitude: 95, Longitude: 10}, expectField: "latitude"},
+		{name: "longitude off the globe", location: Location{Latitude: 60, Longitude: -200}, expectField: "longitude"},
+		{name: "latitude not a number", location: Location{Latitude: math.NaN(), Longitude: 10}, expectField: "latitude"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLocation(tt.location)
+			if tt.expectField != "" {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("Expected a *ValidationError, got %v", err)
+				}
+				if validationErr.Field != tt.expectField {
+					t.Errorf("Expected field %q, got %q", tt.expectField, validationErr.Field)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			warning := LocationCoverageWarning(tt.location)
+			if (warning != "") != tt.expectWarn {
+				t.Errorf("Expected warning %v, got %q", tt.expectWarn, warning)
+			}
+		})
+	}
+}
+
 func TestGetCompact(t *testing.T) {
 	// Create test forecast data
 	testForecast := METJSONForecast{
@@ -277,6 +324,201 @@ func TestGetCompact(t *testing.T) {
 	}
 }
 
+func TestFetchRaw(t *testing.T) {
+	var mu sync.Mutex
+	var requestTimes []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requestTimes = append(requestTimes, time.Now())
+		mu.Unlock()
+
+		if r.Header.Get("User-Agent") != "TestApp/1.0" {
+			t.Errorf("Expected User-Agent 'TestApp/1.0', got '%s'", r.Header.Get("User-Agent"))
+		}
+		if r.URL.Query().Get("lat") != "59.9139" {
+			t.Errorf("Expected lat parameter '59.9139', got '%s'", r.URL.Query().Get("lat"))
+		}
+
+		switch r.URL.Path {
+		case "/weatherapi/locationforecast/2.0/status":
+			w.Write([]byte(`{"last_update":"2024-06-15T12:00:00Z"}`))
+		case "/weatherapi/nowcast/2.0/complete":
+			w.Write([]byte(`{"type":"Feature"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("TestApp/1.0")
+	client.SetBaseURL(server.URL + "/weatherapi/locationforecast/2.0")
+	interval := 50 * time.Millisecond
+	client.SetRateLimit(interval)
+
+	query := url.Values{"lat": {"59.9139"}, "lon": {"10.7522"}}
+	ctx := context.Background()
+	start := time.Now()
+
+	// A relative path is appended to the base URL
+	body, err := client.FetchRaw(ctx, "status", query)
+	if err != nil {
+		t.Fatalf("FetchRaw returned error: %v", err)
+	}
+	if string(body) != `{"last_update":"2024-06-15T12:00:00Z"}` {
+		t.Errorf("Unexpected body %q", body)
+	}
+
+	// An absolute path reaches other MET products on the same host
+	body, err = client.FetchRaw(ctx, "/weatherapi/nowcast/2.0/complete", query)
+	if err != nil {
+		t.Fatalf("FetchRaw returned error: %v", err)
+	}
+	if string(body) != `{"type":"Feature"}` {
+		t.Errorf("Unexpected body %q", body)
+	}
+
+	// Errors are reported like for the wrapped endpoints
+	_, err = client.FetchRaw(ctx, "missing", query)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected APIError with status 404, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requestTimes) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(requestTimes))
+	}
+	for i := 1; i < len(requestTimes); i++ {
+		// Measured from the first call, a late arrival of an earlier request cannot shrink the spacing
+		if elapsed, expected := requestTimes[i].Sub(start), time.Duration(i)*interval; elapsed < expected {
+			t.Errorf("Request %d came %v after the first call, expected at least %v", i, elapsed, expected)
+		}
+	}
+}
+
+func TestFetchRaw_RateLimitHonoursContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	client := NewClient("TestApp/1.0")
+	client.SetBaseURL(server.URL)
+	client.SetRateLimit(time.Hour)
+
+	if _, err := client.FetchRaw(context.Background(), "status", nil); err != nil {
+		t.Fatalf("First request should not wait, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.FetchRaw(ctx, "status", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded while waiting for the rate limit, got %v", err)
+	}
+}
+
+func TestGetCompactWithContext_Cancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient("TestApp/1.0")
+	client.SetBaseURL(server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.GetCompactWithContext(ctx, QueryParams{Location: Location{Latitude: 59.9139, Longitude: 10.7522}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the request to be aborted with deadline exceeded, got %v", err)
+	}
+}
+
+func TestGetCompact_Expires(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.Header().Set("Expires", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		w.Write([]byte(`{"type": "Feature"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("TestApp/1.0")
+	client.SetBaseURL(server.URL)
+	params := QueryParams{Location: Location{Latitude: 59.9139, Longitude: 10.7522}}
+
+	for range 3 {
+		forecast, err := client.GetCompact(params)
+		if err != nil || forecast.Type != "Feature" {
+			t.Fatalf("Expected the forecast, got %+v (err %v)", forecast, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected a single request before the forecast expires, got %d", requests)
+	}
+
+	// Another location is not cached
+	if _, err := client.GetCompact(QueryParams{Location: Location{Latitude: 60, Longitude: 10}}); err != nil {
+		t.Fatalf("GetCompact returned error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected a request for another location, got %d requests", requests)
+	}
+
+	client.ClearCache()
+	if _, err := client.GetCompact(params); err != nil {
+		t.Fatalf("GetCompact returned error: %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("Expected a request after clearing the cache, got %d requests", requests)
+	}
+}
+
+func TestGetCompact_IfModifiedSince(t *testing.T) {
+	const lastModified = "Sat, 15 Jun 2024 10:05:00 GMT"
+	var conditional []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		since := r.Header.Get("If-Modified-Since")
+		conditional = append(conditional, since)
+		// Already expired, so every call revalidates
+		w.Header().Set("Expires", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+		w.Header().Set("Last-Modified", lastModified)
+		if since == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`{"type": "Feature", "properties": {"meta": {"updated_at": "2024-06-15T10:05:00Z"}, "timeseries": []}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("TestApp/1.0")
+	client.SetBaseURL(server.URL)
+	params := QueryParams{Location: Location{Latitude: 59.9139, Longitude: 10.7522}}
+
+	for i := range 2 {
+		forecast, err := client.GetCompact(params)
+		if err != nil {
+			t.Fatalf("Request %d returned error: %v", i, err)
+		}
+		if forecast.Properties == nil || forecast.Properties.Meta.UpdatedAt.IsZero() {
+			t.Errorf("Request %d: expected the forecast, got %+v", i, forecast)
+		}
+	}
+
+	if len(conditional) != 2 || conditional[0] != "" || conditional[1] != lastModified {
+		t.Errorf("Expected a plain request and then If-Modified-Since %q, got %q", lastModified, conditional)
+	}
+}
+
 func TestAPIError(t *testing.T) {
 	// Create test server that returns an error
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -333,3 +575,144 @@ func TestFormatFloat(t *testing.T) {
 		}
 	}
 }
+
+// completeTestForecast returns a forecast with the instant and next hour parameters set for the given hours
+func completeTestForecast(hours int) METJSONForecast {
+	start := time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC)
+	forecast := METJSONForecast{Type: "Feature", Properties: &Forecast{Meta: ForecastMeta{UpdatedAt: start}}}
+	for i := range hours {
+		forecast.Properties.Timeseries = append(forecast.Properties.Timeseries, ForecastTimeStep{
+			Time: start.Add(time.Duration(i) * time.Hour),
+			Data: &ForecastTimeStepData{
+				Instant: &ForecastInstantData{Details: &ForecastTimeInstant{
+					AirPressureAtSeaLevel: Float64Ptr(1013),
+					AirTemperature:        Float64Ptr(15.5),
+					CloudAreaFraction:     Float64Ptr(40),
+					CloudAreaFractionHigh: Float64Ptr(10),
+					CloudAreaFractionLow:  Float64Ptr(20),
+					DewPointTemperature:   Float64Ptr(8),
+					RelativeHumidity:      Float64Ptr(70),
+					WindFromDirection:     Float64Ptr(180),
+					WindSpeed:             Float64Ptr(3.2),
+					WindSpeedOfGust:       Float64Ptr(7.5),
+				}},
+				Next1Hours: &ForecastPeriodData{
+					Summary: &ForecastSummary{SymbolCode: PartlyCloudyDay},
+					Details: &ForecastTimePeriod{
+						PrecipitationAmount:        Float64Ptr(0.2),
+						ProbabilityOfPrecipitation: Float64Ptr(30),
+						ProbabilityOfThunder:       Float64Ptr(1),
+					},
+				},
+			},
+		})
+	}
+	return forecast
+}
+
+func TestBuildURL_ExtraQuery(t *testing.T) {
+	client := NewClient("TestApp/1.0")
+	client.SetBaseURL("https://api.example.com")
+	client.SetExtraQuery(url.Values{"variables": {"air_temperature"}, "lat": {"0"}})
+
+	reqURL, err := client.buildURL("complete", QueryParams{Location: Location{Latitude: 59.9139, Longitude: 10.7522}})
+	if err != nil {
+		t.Fatalf("buildURL returned error: %v", err)
+	}
+	// The location of the request takes precedence over the extra parameters
+	if expected := "https://api.example.com/complete?lat=59.9139&lon=10.7522&variables=air_temperature"; reqURL != expected {
+		t.Errorf("Expected URL %q, got %q", expected, reqURL)
+	}
+}
+
+func TestGetComplete_RequestOptions(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept-Language"); got != "nb-NO" {
+			t.Errorf("Expected Accept-Language 'nb-NO', got '%s'", got)
+		}
+		if got := r.URL.Query().Get("variables"); got != "air_temperature" {
+			t.Errorf("Expected the variables parameter 'air_temperature', got '%s'", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	client := NewClient("TestApp/1.0")
+	client.SetBaseURL(server.URL)
+	client.SetAcceptLanguage("nb-NO")
+	client.SetExtraQuery(url.Values{"variables": {"air_temperature"}})
+	if err := client.SetFields("air_temperature", "cloud_area_fraction", "precipitation_amount"); err != nil {
+		t.Fatalf("SetFields returned error: %v", err)
+	}
+	params := QueryParams{Location: Location{Latitude: 59.9139, Longitude: 10.7522}}
+
+	// Parameters that are not selected are left nil
+	body, _ = json.Marshal(completeTestForecast(2))
+	forecast, err := client.GetComplete(params)
+	if err != nil {
+		t.Fatalf("GetComplete returned error: %v", err)
+	}
+	if len(forecast.Properties.Timeseries) != 2 || !forecast.Properties.Meta.UpdatedAt.Equal(time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Unexpected forecast %+v", forecast.Properties)
+	}
+	step := forecast.Properties.Timeseries[1]
+	instant := step.Data.Instant.Details
+	if instant.AirTemperature == nil || *instant.AirTemperature != 15.5 || instant.CloudAreaFraction == nil || *instant.CloudAreaFraction != 40 {
+		t.Errorf("Expected the selected instant parameters, got %+v", instant)
+	}
+	if instant.WindSpeed != nil || instant.RelativeHumidity != nil {
+		t.Errorf("Expected parameters that are not selected to be nil, got %+v", instant)
+	}
+	period := step.Data.Next1Hours
+	if period.Details.PrecipitationAmount == nil || *period.Details.PrecipitationAmount != 0.2 || period.Details.ProbabilityOfThunder != nil {
+		t.Errorf("Expected only the selected period parameters, got %+v", period.Details)
+	}
+	if symbol := step.GetSymbolCode(); symbol == nil || *symbol != PartlyCloudyDay {
+		t.Errorf("Expected the symbol code to be kept, got %v", symbol)
+	}
+
+	// A reduced payload with only some of the selected parameters parses too
+	body = []byte(`{"type":"Feature","properties":{"meta":{"updated_at":"2024-06-21T00:00:00Z"},"timeseries":[` +
+		`{"time":"2024-06-21T00:00:00Z","data":{"instant":{"details":{"air_temperature":12.5}}}}]}}`)
+	forecast, err = client.GetComplete(params)
+	if err != nil {
+		t.Fatalf("GetComplete returned error: %v", err)
+	}
+	step = forecast.Properties.Timeseries[0]
+	if temp := step.GetTemperature(); temp == nil || *temp != 12.5 {
+		t.Errorf("Expected temperature 12.5, got %v", temp)
+	}
+	if step.Data.Instant.Details.CloudAreaFraction != nil || step.Data.Next1Hours != nil {
+		t.Errorf("Expected missing parameters to be nil, got %+v", step.Data)
+	}
+
+	var validationErr *ValidationError
+	if err := client.SetFields("air_temperature", "sunshine"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for an unknown parameter, got %v", err)
+	}
+}
+
+func TestFieldDecoder_Allocations(t *testing.T) {
+	body, err := json.Marshal(completeTestForecast(48))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := newFieldDecoder([]string{"air_temperature", "cloud_area_fraction"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	full := testing.AllocsPerRun(10, func() {
+		var forecast METJSONForecast
+		_ = json.Unmarshal(body, &forecast)
+	})
+	reduced := testing.AllocsPerRun(10, func() {
+		_, _ = decoder.decode(body)
+	})
+	if reduced >= full {
+		t.Errorf("Expected fewer allocations with selected fields, got %.0f with and %.0f without", reduced, full)
+	}
+	t.Logf("Allocations: %.0f parsing all parameters, %.0f parsing two", full, reduced)
+}
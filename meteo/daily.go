@@ -0,0 +1,121 @@
+package meteo
+
+import (
+	"strings"
+	"time"
+)
+
+// DailySummary summarizes the forecast of one calendar day
+type DailySummary struct {
+	Date               time.Time     // midnight starting the day
+	MinTemp            float64       // °C (°F in Imperial units), lowest instant air temperature
+	MaxTemp            float64       // °C (°F in Imperial units), highest instant air temperature
+	TotalPrecipitation float64       // mm (inches in Imperial units) over the periods starting on the day
+	DominantSymbol     WeatherSymbol // most frequent symbol, the more severe one on a tie (empty without symbols)
+}
+
+// GetDailySummaries returns a summary of each calendar day of the forecast in the time zone of its time steps,
+// UTC for a forecast decoded from the API. See GetDailySummariesIn.
+func (f *METJSONForecast) GetDailySummaries() []DailySummary {
+	return f.GetDailySummariesIn(nil)
+}
+
+// GetDailySummariesIn returns a summary of each calendar day of the forecast in location, oldest first.
+// A nil location keeps the time zone of the time steps. Days without an instant air temperature are skipped.
+// The precipitation of the next hour is preferred, a step without it counts its next 6 hours unless they overlap
+// a period already counted, so the hourly and 6-hourly parts of the forecast are not counted twice.
+func (f *METJSONForecast) GetDailySummariesIn(location *time.Location) []DailySummary {
+	if f == nil || f.Properties == nil {
+		return nil
+	}
+
+	var summaries []DailySummary
+	var current *DailySummary
+	var counts map[WeatherSymbol]int
+	hasTemp := false
+	var countedUntil time.Time
+
+	flush := func() {
+		if current != nil && hasTemp {
+			summaries = append(summaries, *current)
+		}
+	}
+
+	for i := range f.Properties.Timeseries {
+		step := &f.Properties.Timeseries[i]
+		t := step.Time
+		if location != nil {
+			t = t.In(location)
+		}
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		if current == nil || !day.Equal(current.Date) {
+			flush()
+			current = &DailySummary{Date: day}
+			counts = make(map[WeatherSymbol]int)
+			hasTemp = false
+		}
+
+		if temp := step.GetTemperature(); temp != nil {
+			if !hasTemp || *temp < current.MinTemp {
+				current.MinTemp = *temp
+			}
+			if !hasTemp || *temp > current.MaxTemp {
+				current.MaxTemp = *temp
+			}
+			hasTemp = true
+		}
+
+		if amount, period := stepPrecipitation(step); amount != nil && !step.Time.Before(countedUntil) {
+			current.TotalPrecipitation += *amount
+			countedUntil = step.Time.Add(period)
+		}
+
+		if symbol := step.GetSymbolCode(); symbol != nil && *symbol != "" {
+			counts[*symbol]++
+			dominant := current.DominantSymbol
+			if dominant == "" || counts[*symbol] > counts[dominant] ||
+				(counts[*symbol] == counts[dominant] && symbol.severity() > dominant.severity()) {
+				current.DominantSymbol = *symbol
+			}
+		}
+	}
+	flush()
+
+	return summaries
+}
+
+// stepPrecipitation returns the precipitation amount of the next hour of a time step, or of the next 6 hours
+// without it, in the units of the step and the length of the period
+func stepPrecipitation(step *ForecastTimeStep) (*float64, time.Duration) {
+	if step.Data == nil {
+		return nil, 0
+	}
+	if period := step.Data.Next1Hours; period != nil && period.Details != nil && period.Details.PrecipitationAmount != nil {
+		return step.units.precipitation(period.Details.PrecipitationAmount), time.Hour
+	}
+	if period := step.Data.Next6Hours; period != nil && period.Details != nil && period.Details.PrecipitationAmount != nil {
+		return step.units.precipitation(period.Details.PrecipitationAmount), 6 * time.Hour
+	}
+	return nil, 0
+}
+
+// severity ranks how severe the weather of a symbol is: thunder, heavy precipitation, precipitation,
+// light precipitation, cloud or fog and fair weather
+func (ws WeatherSymbol) severity() int {
+	s := string(ws)
+	switch {
+	case ws.HasThunder():
+		return 5
+	case strings.HasPrefix(s, "heavy"):
+		return 4
+	case strings.Contains(s, "rain") || strings.Contains(s, "sleet") || ws.HasSnow():
+		if strings.HasPrefix(s, "light") {
+			return 2
+		}
+		return 3
+	case ws == Cloudy || ws == Fog:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,122 @@
+package meteo
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// hourlyStep returns a time step with an instant temperature and the symbol and precipitation of the next hour
+// and of the next 6 hours
+func hourlyStep(at time.Time, temp, amount1h, amount6h float64, symbol WeatherSymbol) ForecastTimeStep {
+	return ForecastTimeStep{
+		Time: at,
+		Data: &ForecastTimeStepData{
+			Instant: &ForecastInstantData{Details: &ForecastTimeInstant{AirTemperature: Float64Ptr(temp)}},
+			Next1Hours: &ForecastPeriodData{
+				Summary: &ForecastSummary{SymbolCode: symbol},
+				Details: &ForecastTimePeriod{PrecipitationAmount: Float64Ptr(amount1h)},
+			},
+			Next6Hours: &ForecastPeriodData{
+				Summary: &ForecastSummary{SymbolCode: symbol},
+				Details: &ForecastTimePeriod{PrecipitationAmount: Float64Ptr(amount6h)},
+			},
+		},
+	}
+}
+
+// sixHourlyStep returns a time step of the long range part of the forecast, without the next hour
+func sixHourlyStep(at time.Time, temp *float64, amount6h float64, symbol WeatherSymbol) ForecastTimeStep {
+	step := ForecastTimeStep{
+		Time: at,
+		Data: &ForecastTimeStepData{
+			Next6Hours: &ForecastPeriodData{
+				Summary: &ForecastSummary{SymbolCode: symbol},
+				Details: &ForecastTimePeriod{PrecipitationAmount: Float64Ptr(amount6h)},
+			},
+		},
+	}
+	if temp != nil {
+		step.Data.Instant = &ForecastInstantData{Details: &ForecastTimeInstant{AirTemperature: temp}}
+	}
+	return step
+}
+
+func TestMETJSONForecast_GetDailySummaries(t *testing.T) {
+	day1 := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	day3 := day1.AddDate(0, 0, 2)
+
+	var timeseries []ForecastTimeStep
+	// Day 1 evening: hourly steps, a light shower among clear hours
+	for hour := 20; hour < 24; hour++ {
+		symbol, amount := ClearSkyNight, 0.0
+		if hour == 21 {
+			symbol, amount = LightRain, 0.3
+		}
+		timeseries = append(timeseries, hourlyStep(day1.Add(time.Duration(hour)*time.Hour), float64(30-hour), amount, 5, symbol))
+	}
+	// Day 2: hourly until 02:00, then 6-hourly from the end of the hourly part
+	timeseries = append(timeseries,
+		hourlyStep(day2, 8, 0.5, 9, Cloudy),
+		hourlyStep(day2.Add(time.Hour), 7, 0.5, 9, RainAndThunder),
+		sixHourlyStep(day2.Add(2*time.Hour), Float64Ptr(9), 2, Cloudy),
+		sixHourlyStep(day2.Add(8*time.Hour), Float64Ptr(17), 4, RainAndThunder),
+		sixHourlyStep(day2.Add(14*time.Hour), Float64Ptr(15), 0, PartlyCloudyDay),
+	)
+	// Day 3: no instant data
+	timeseries = append(timeseries, sixHourlyStep(day3.Add(6*time.Hour), nil, 1, Cloudy))
+
+	forecast := &METJSONForecast{Properties: &Forecast{Timeseries: timeseries}}
+	summaries := forecast.GetDailySummaries()
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 days, got %+v", summaries)
+	}
+
+	expected := []DailySummary{
+		{Date: day1, MinTemp: 7, MaxTemp: 10, TotalPrecipitation: 0.3, DominantSymbol: ClearSkyNight},
+		// Cloudy and RainAndThunder occur twice each, thunder is more severe
+		{Date: day2, MinTemp: 7, MaxTemp: 17, TotalPrecipitation: 0.5 + 0.5 + 2 + 4, DominantSymbol: RainAndThunder},
+	}
+	for i, want := range expected {
+		got := summaries[i]
+		if !got.Date.Equal(want.Date) || got.MinTemp != want.MinTemp || got.MaxTemp != want.MaxTemp ||
+			math.Abs(got.TotalPrecipitation-want.TotalPrecipitation) > 1e-9 || got.DominantSymbol != want.DominantSymbol {
+			t.Errorf("Day %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+func TestMETJSONForecast_GetDailySummariesIn(t *testing.T) {
+	location := time.FixedZone("UTC+3", 3*60*60)
+	start := time.Date(2024, 6, 10, 20, 0, 0, 0, time.UTC) // 23:00 local
+	forecast := &METJSONForecast{Properties: &Forecast{Timeseries: []ForecastTimeStep{
+		hourlyStep(start, 12, 0, 0, ClearSkyNight),
+		hourlyStep(start.Add(time.Hour), 10, 0, 0, ClearSkyNight), // 00:00 local
+		hourlyStep(start.Add(2*time.Hour), 9, 0, 0, ClearSkyNight),
+	}}}
+
+	if summaries := forecast.GetDailySummaries(); len(summaries) != 1 {
+		t.Errorf("Expected a single UTC day, got %+v", summaries)
+	}
+	summaries := forecast.GetDailySummariesIn(location)
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 local days, got %+v", summaries)
+	}
+	if !summaries[1].Date.Equal(time.Date(2024, 6, 11, 0, 0, 0, 0, location)) || summaries[1].MaxTemp != 10 {
+		t.Errorf("Expected the second local day to start at 21:00 UTC with max 10 °C, got %+v", summaries[1])
+	}
+
+	if summaries := (*METJSONForecast)(nil).GetDailySummaries(); summaries != nil {
+		t.Errorf("Expected nil for a nil forecast, got %+v", summaries)
+	}
+}
+
+func TestWeatherSymbol_Severity(t *testing.T) {
+	ordered := []WeatherSymbol{ClearSkyDay, Cloudy, LightRain, Rain, HeavyRain, RainAndThunder}
+	for i := 1; i < len(ordered); i++ {
+		if ordered[i].severity() <= ordered[i-1].severity() {
+			t.Errorf("Expected %s more severe than %s", ordered[i], ordered[i-1])
+		}
+	}
+}
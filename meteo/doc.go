@@ -33,7 +33,16 @@
 //
 // - GetCompact(): Returns a compact forecast with essential weather parameters
 // - GetComplete(): Returns the complete forecast with all available parameters
-// - GetClassic(): Returns forecast data in the classic format
+// - GetClassic(): Returns forecast data from the classic XML format, normalized like the JSON endpoints
+//
+// GetCompactWithContext, GetCompleteWithContext and GetClassicWithContext take a
+// context.Context that cancels the request, including the wait for the rate limit.
+//
+// Forecast responses are cached per endpoint and location until their Expires time and then
+// revalidated with If-Modified-Since, as required by the MET terms of service. ClearCache drops the cache.
+//
+// Rate limiting (429) and temporary server errors are retried with an exponential backoff that honors
+// Retry-After, see RetryPolicy and WithRetryPolicy.
 //
 // The client automatically handles JSON deserialization according to the MET API
 // specification and includes proper error handling for HTTP and validation errors.
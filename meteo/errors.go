@@ -1,17 +1,33 @@
 package meteo
 
-import "fmt"
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
 
 // APIError represents an error returned by the MET API
 type APIError struct {
 	StatusCode int
 	Message    string
+	RetryAfter time.Duration // wait requested by the Retry-After header, 0 when not sent
 }
 
 func (e *APIError) Error() string {
 	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Message)
 }
 
+// Retryable reports whether the request may succeed when repeated later: rate limiting and temporary
+// server errors are retried, other errors such as a 400 for an invalid location are not
+func (e *APIError) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
 // ValidationError represents a validation error for input parameters
 type ValidationError struct {
 	Field   string
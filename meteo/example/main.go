@@ -24,6 +24,9 @@ func main() {
 	if err := meteo.ValidateLocation(location); err != nil {
 		log.Fatalf("Invalid location: %v", err)
 	}
+	if warning := meteo.LocationCoverageWarning(location); warning != "" {
+		log.Printf("Warning: %s", warning)
+	}
 
 	fmt.Printf("Getting weather forecast for Oslo (%.4f, %.4f)\n\n",
 		location.Latitude, location.Longitude)
@@ -0,0 +1,114 @@
+package meteo
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// detailTypes are the forecast types whose parameters can be selected with SetFields
+var detailTypes = map[reflect.Type]bool{
+	reflect.TypeFor[ForecastTimeInstant](): true,
+	reflect.TypeFor[ForecastTimePeriod]():  true,
+}
+
+// fieldDecoder decodes forecasts keeping only a subset of the detail parameters. The response is decoded into
+// mirror types without the other parameters, so they are skipped by the JSON decoder instead of being allocated.
+type fieldDecoder struct {
+	mirror reflect.Type // mirror of METJSONForecast
+}
+
+// newFieldDecoder returns a decoder for the given parameter names, e.g. "air_temperature".
+// Unknown names are returned as a *ValidationError.
+func newFieldDecoder(fields []string) (*fieldDecoder, error) {
+	known := make(map[string]bool)
+	for t := range detailTypes {
+		for i := range t.NumField() {
+			known[jsonName(t.Field(i))] = true
+		}
+	}
+	selected := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if !known[field] {
+			return nil, &ValidationError{Field: "fields", Message: fmt.Sprintf("unknown forecast parameter %q", field)}
+		}
+		selected[field] = true
+	}
+	return &fieldDecoder{mirror: mirrorType(reflect.TypeFor[METJSONForecast](), selected)}, nil
+}
+
+// jsonName returns the JSON name of a struct field
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+// mirrorType returns t with the parameters of the detail types reduced to the selected ones.
+// Types outside of this package are kept as they are.
+func mirrorType(t reflect.Type, selected map[string]bool) reflect.Type {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return reflect.PointerTo(mirrorType(t.Elem(), selected))
+	case reflect.Slice:
+		return reflect.SliceOf(mirrorType(t.Elem(), selected))
+	case reflect.Struct:
+		if t.PkgPath() != reflect.TypeFor[METJSONForecast]().PkgPath() {
+			return t
+		}
+	default:
+		return t
+	}
+
+	fields := make([]reflect.StructField, 0, t.NumField())
+	for i := range t.NumField() {
+		field := t.Field(i)
+		// Unexported fields are not decoded, e.g. the units of a time step set after decoding
+		if !field.IsExported() || detailTypes[t] && !selected[jsonName(field)] {
+			continue
+		}
+		field.Type = mirrorType(field.Type, selected)
+		fields = append(fields, field)
+	}
+	return reflect.StructOf(fields)
+}
+
+// decode unmarshals a forecast response through the mirror types
+func (d *fieldDecoder) decode(body []byte) (*METJSONForecast, error) {
+	mirror := reflect.New(d.mirror)
+	if err := json.Unmarshal(body, mirror.Interface()); err != nil {
+		return nil, err
+	}
+	var forecast METJSONForecast
+	copyMirror(reflect.ValueOf(&forecast).Elem(), mirror.Elem())
+	return &forecast, nil
+}
+
+// copyMirror copies a value of a mirror type into dst of the original type. Values of the same type,
+// e.g. the selected parameters, are shared instead of copied.
+func copyMirror(dst, src reflect.Value) {
+	if dst.Type() == src.Type() {
+		dst.Set(src)
+		return
+	}
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.New(dst.Type().Elem()))
+		copyMirror(dst.Elem(), src.Elem())
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeSlice(dst.Type(), src.Len(), src.Len()))
+		for i := range src.Len() {
+			copyMirror(dst.Index(i), src.Index(i))
+		}
+	case reflect.Struct:
+		for i := range src.NumField() {
+			copyMirror(dst.FieldByName(src.Type().Field(i).Name), src.Field(i))
+		}
+	}
+}
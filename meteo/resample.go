@@ -0,0 +1,124 @@
+package meteo
+
+import (
+	"math"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// GetHourlyForecast returns the forecast resampled to a uniform hourly series on the whole hours within the
+// specified time period. The complete and compact endpoints switch from hourly to 6-hourly steps a few days
+// ahead, hours between native steps are filled in:
+//   - instant parameters are interpolated linearly between the surrounding steps, the wind direction along
+//     the shorter arc. A parameter missing at either step is left out.
+//   - next_1_hours is derived from the period of the preceding step that covers the hour, with its symbol and
+//     probabilities and the precipitation spread evenly over the period.
+//
+// Hours on a native step return it unchanged. Hours before the first or after the last step are left out.
+// The timeseries must be sorted by time, as returned by the API.
+func (f *METJSONForecast) GetHourlyForecast(start, end time.Time) []ForecastTimeStep {
+	if f == nil || f.Properties == nil || len(f.Properties.Timeseries) == 0 {
+		return nil
+	}
+	timeseries := f.Properties.Timeseries
+
+	hour := start.Truncate(time.Hour)
+	if hour.Before(start) {
+		hour = hour.Add(time.Hour)
+	}
+
+	var hourly []ForecastTimeStep
+	for ; !hour.After(end); hour = hour.Add(time.Hour) {
+		i, found := slices.BinarySearchFunc(timeseries, hour, func(step ForecastTimeStep, t time.Time) int {
+			return step.Time.Compare(t)
+		})
+		if found {
+			hourly = append(hourly, timeseries[i])
+			continue
+		}
+		if i == 0 || i == len(timeseries) {
+			continue
+		}
+		hourly = append(hourly, interpolateStep(timeseries[i-1], timeseries[i], hour))
+	}
+	return hourly
+}
+
+// interpolateStep returns the time step at t between the native steps prev and next
+func interpolateStep(prev, next ForecastTimeStep, t time.Time) ForecastTimeStep {
+	fraction := t.Sub(prev.Time).Hours() / next.Time.Sub(prev.Time).Hours()
+	data := &ForecastTimeStepData{}
+
+	var prevInstant, nextInstant *ForecastTimeInstant
+	if prev.Data != nil && prev.Data.Instant != nil {
+		prevInstant = prev.Data.Instant.Details
+	}
+	if next.Data != nil && next.Data.Instant != nil {
+		nextInstant = next.Data.Instant.Details
+	}
+	if prevInstant != nil && nextInstant != nil {
+		data.Instant = &ForecastInstantData{Details: interpolateInstant(prevInstant, nextInstant, fraction)}
+	}
+
+	if prev.Data != nil {
+		data.Next1Hours = hourOfPeriod(prev.Data, t.Sub(prev.Time))
+	}
+	return ForecastTimeStep{Time: t, Data: data, units: prev.units}
+}
+
+// interpolateInstant interpolates each instant parameter available at both steps
+func interpolateInstant(prev, next *ForecastTimeInstant, fraction float64) *ForecastTimeInstant {
+	details := &ForecastTimeInstant{}
+	prevValue, nextValue, value := reflect.ValueOf(prev).Elem(), reflect.ValueOf(next).Elem(), reflect.ValueOf(details).Elem()
+	for i := range value.NumField() {
+		a, b := prevValue.Field(i).Interface().(*float64), nextValue.Field(i).Interface().(*float64)
+		if a == nil || b == nil {
+			continue
+		}
+		value.Field(i).Set(reflect.ValueOf(Float64Ptr(*a + (*b-*a)*fraction)))
+	}
+
+	if prev.WindFromDirection != nil && next.WindFromDirection != nil {
+		delta := math.Mod(*next.WindFromDirection-*prev.WindFromDirection+540, 360) - 180
+		details.WindFromDirection = Float64Ptr(math.Mod(*prev.WindFromDirection+delta*fraction+360, 360))
+	}
+	return details
+}
+
+// hourOfPeriod returns the next_1_hours data for the hour starting offset after a native step, derived from the
+// shortest period of the step covering it. Returns nil if no period covers the hour.
+func hourOfPeriod(data *ForecastTimeStepData, offset time.Duration) *ForecastPeriodData {
+	periods := []struct {
+		data   *ForecastPeriodData
+		length time.Duration
+	}{
+		{data: data.Next1Hours, length: time.Hour},
+		{data: data.Next6Hours, length: 6 * time.Hour},
+		{data: data.Next12Hours, length: 12 * time.Hour},
+	}
+	for _, period := range periods {
+		if period.data == nil || offset+time.Hour > period.length {
+			continue
+		}
+
+		hour := &ForecastPeriodData{Summary: period.data.Summary}
+		if details := period.data.Details; details != nil {
+			share := func(amount *float64) *float64 {
+				if amount == nil {
+					return nil
+				}
+				return Float64Ptr(*amount / period.length.Hours())
+			}
+			hour.Details = &ForecastTimePeriod{
+				PrecipitationAmount:        share(details.PrecipitationAmount),
+				PrecipitationAmountMax:     share(details.PrecipitationAmountMax),
+				PrecipitationAmountMin:     share(details.PrecipitationAmountMin),
+				ProbabilityOfPrecipitation: details.ProbabilityOfPrecipitation,
+				ProbabilityOfThunder:       details.ProbabilityOfThunder,
+			}
+		}
+		return hour
+	}
+	return nil
+}
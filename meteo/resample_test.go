@@ -0,0 +1,113 @@
+package meteo
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// mixedResolutionForecast has hourly steps from 00:00 to 02:00 followed by 6-hourly steps at 06:00 and 12:00
+func mixedResolutionForecast(base time.Time) *METJSONForecast {
+	step := func(hours int, temperature, wind float64, next1, next6 *ForecastPeriodData) ForecastTimeStep {
+		return ForecastTimeStep{
+			Time: base.Add(time.Duration(hours) * time.Hour),
+			Data: &ForecastTimeStepData{
+				Instant: &ForecastInstantData{Details: &ForecastTimeInstant{
+					AirTemperature:    Float64Ptr(temperature),
+					WindFromDirection: Float64Ptr(wind),
+				}},
+				Next1Hours: next1,
+				Next6Hours: next6,
+			},
+		}
+	}
+	rain := &ForecastPeriodData{
+		Summary: &ForecastSummary{SymbolCode: Rain},
+		Details: &ForecastTimePeriod{PrecipitationAmount: Float64Ptr(3), ProbabilityOfPrecipitation: Float64Ptr(80)},
+	}
+	cloudy := &ForecastPeriodData{Summary: &ForecastSummary{SymbolCode: Cloudy}}
+
+	return &METJSONForecast{
+		Properties: &Forecast{
+			Timeseries: []ForecastTimeStep{
+				step(0, 10, 350, cloudy, nil),
+				step(1, 11, 350, cloudy, nil),
+				step(2, 12, 350, cloudy, rain),
+				step(6, 16, 30, nil, rain),
+				step(12, 4, 30, nil, nil),
+			},
+		},
+	}
+}
+
+func TestMETJSONForecast_GetHourlyForecast(t *testing.T) {
+	base := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	forecast := mixedResolutionForecast(base)
+
+	hourly := forecast.GetHourlyForecast(base.Add(30*time.Minute), base.Add(12*time.Hour))
+	if len(hourly) != 12 {
+		t.Fatalf("Expected 12 hourly steps from 01:00 to 12:00, got %d", len(hourly))
+	}
+	for i, step := range hourly {
+		if expected := base.Add(time.Duration(i+1) * time.Hour); !step.Time.Equal(expected) {
+			t.Errorf("Expected step %d at %v, got %v", i, expected, step.Time)
+		}
+	}
+
+	// Native steps are returned unchanged
+	if hourly[0].Data != forecast.Properties.Timeseries[1].Data {
+		t.Error("Expected the native step at 01:00 to be returned unchanged")
+	}
+
+	// 04:00 is halfway between 02:00 and 06:00
+	at4 := hourly[3]
+	if temp := at4.GetTemperature(); temp == nil || math.Abs(*temp-14) > 1e-9 {
+		t.Errorf("Expected 14.0 °C at 04:00, got %v", temp)
+	}
+	// The wind turns from 350° to 30° through north, not through south
+	if wind := at4.GetWindDirection(); wind == nil || math.Abs(*wind-10) > 1e-9 {
+		t.Errorf("Expected wind from 10° at 04:00, got %v", wind)
+	}
+	// The 6 hour rain period of 02:00 covers 04:00, its precipitation is spread over the hours
+	if symbol := at4.GetSymbolCode(); symbol == nil || *symbol != Rain {
+		t.Errorf("Expected the rain symbol at 04:00, got %v", symbol)
+	}
+	details := at4.Data.Next1Hours.Details
+	if details.PrecipitationAmount == nil || math.Abs(*details.PrecipitationAmount-0.5) > 1e-9 {
+		t.Errorf("Expected 0.5 mm at 04:00, got %v", details.PrecipitationAmount)
+	}
+	if details.ProbabilityOfPrecipitation == nil || *details.ProbabilityOfPrecipitation != 80 {
+		t.Errorf("Expected the 80%% probability of the period at 04:00, got %v", details.ProbabilityOfPrecipitation)
+	}
+
+	// 09:00 is halfway between 06:00 and 12:00 with the 06:00 rain period
+	at9 := hourly[8]
+	if temp := at9.GetTemperature(); temp == nil || math.Abs(*temp-10) > 1e-9 {
+		t.Errorf("Expected 10.0 °C at 09:00, got %v", temp)
+	}
+	if !at9.HasPrecipitation() {
+		t.Error("Expected precipitation at 09:00")
+	}
+}
+
+func TestMETJSONForecast_GetHourlyForecast_OutsideCoverage(t *testing.T) {
+	base := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	forecast := mixedResolutionForecast(base)
+
+	hourly := forecast.GetHourlyForecast(base.Add(-3*time.Hour), base.Add(15*time.Hour))
+	if len(hourly) != 13 {
+		t.Fatalf("Expected the 13 hours covered by the forecast, got %d", len(hourly))
+	}
+	if !hourly[0].Time.Equal(base) || !hourly[12].Time.Equal(base.Add(12*time.Hour)) {
+		t.Errorf("Expected hours from 00:00 to 12:00, got %v to %v", hourly[0].Time, hourly[12].Time)
+	}
+	// The 6 hour period of 06:00 ends at 12:00
+	if hourly[11].Data.Next1Hours == nil {
+		t.Error("Expected the 6 hour period of 06:00 to cover 11:00")
+	}
+
+	var nilForecast *METJSONForecast
+	if steps := nilForecast.GetHourlyForecast(base, base.Add(time.Hour)); steps != nil {
+		t.Errorf("Expected nil for a nil forecast, got %v", steps)
+	}
+}
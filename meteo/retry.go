@@ -0,0 +1,73 @@
+package meteo
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how requests failing with a temporary API error (429, 500, 502, 503 or 504) are retried
+type RetryPolicy struct {
+	MaxAttempts int           // attempts including the first request, 1 or less disables retries
+	BaseDelay   time.Duration // delay before the first retry, doubled for every further retry
+	MaxDelay    time.Duration // upper bound of a single delay, including one from Retry-After (0 = no bound)
+	Jitter      float64       // fraction (0-1) of each delay added at random, so clients do not retry in lockstep
+}
+
+// DefaultRetryPolicy returns the retry policy of a new client: 3 attempts, 1s and 2s apart plus up to 20% jitter,
+// never waiting longer than a minute
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Second,
+		MaxDelay:    time.Minute,
+		Jitter:      0.2,
+	}
+}
+
+// delay returns the wait before the retry following the given failed attempt (1 = the first request).
+// A Retry-After duration sent by the server replaces the exponential backoff.
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if retryAfter > 0 {
+		delay = retryAfter
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration(float64(delay) * p.Jitter * rand.Float64())
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// parseRetryAfter returns the wait requested by a Retry-After header given in seconds or as an HTTP date,
+// 0 when it is missing, invalid or already passed
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(0, time.Duration(seconds)*time.Second)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(0, date.Sub(now))
+	}
+	return 0
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,167 @@
+package meteo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyServer fails the first failures requests with status and answers the others with an empty forecast.
+// It returns the server and a function reporting the number of requests received.
+func flakyServer(t *testing.T, failures, status int, header http.Header) (*httptest.Server, func() int) {
+	t.Helper()
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+		if n <= failures {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		w.Write([]byte(`{"type": "Feature"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func TestClient_RetriesTemporaryErrors(t *testing.T) {
+	params := QueryParams{Location: Location{Latitude: 59.9139, Longitude: 10.7522}}
+	fastRetry := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	tests := []struct {
+		name             string
+		failures         int
+		status           int
+		expectedRequests int
+		expectedStatus   int // 0 = success
+	}{
+		{name: "503 then success", failures: 2, status: http.StatusServiceUnavailable, expectedRequests: 3},
+		{name: "429 then success", failures: 1, status: http.StatusTooManyRequests, expectedRequests: 2},
+		{name: "gives up after max attempts", failures: 5, status: http.StatusBadGateway, expectedRequests: 3, expectedStatus: http.StatusBadGateway},
+		{name: "client error not retried", failures: 5, status: http.StatusNotFound, expectedRequests: 1, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := flakyServer(t, tt.failures, tt.status, nil)
+			client := NewClient("TestApp/1.0", WithRetryPolicy(fastRetry))
+			client.SetBaseURL(server.URL)
+
+			_, err := client.GetCompact(params)
+			if tt.expectedStatus == 0 && err != nil {
+				t.Errorf("Expected success after retrying, got %v", err)
+			}
+			var apiErr *APIError
+			if tt.expectedStatus != 0 && (!errors.As(err, &apiErr) || apiErr.StatusCode != tt.expectedStatus) {
+				t.Errorf("Expected an APIError with status %d, got %v", tt.expectedStatus, err)
+			}
+			if n := requests(); n != tt.expectedRequests {
+				t.Errorf("Expected %d requests, got %d", tt.expectedRequests, n)
+			}
+		})
+	}
+}
+
+func TestClient_RetryHonoursContext(t *testing.T) {
+	server, requests := flakyServer(t, 5, http.StatusServiceUnavailable, nil)
+	client := NewClient("TestApp/1.0", WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}))
+	client.SetBaseURL(server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.GetCompactWithContext(ctx, QueryParams{Location: Location{Latitude: 59.9139, Longitude: 10.7522}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded while waiting to retry, got %v", err)
+	}
+	if n := requests(); n != 1 {
+		t.Errorf("Expected no retry after the context is done, got %d requests", n)
+	}
+}
+
+func TestClient_RetryAfterHeader(t *testing.T) {
+	server, _ := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"7"}})
+	client := NewClient("TestApp/1.0", WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	client.SetBaseURL(server.URL)
+
+	_, err := client.GetCompact(QueryParams{Location: Location{Latitude: 59.9139, Longitude: 10.7522}})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != 7*time.Second {
+		t.Errorf("Expected an APIError asking to retry after 7s, got %#v", err)
+	}
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	if client := NewClient("TestApp/1.0"); client.retry != DefaultRetryPolicy() {
+		t.Errorf("Expected the default retry policy, got %+v", client.retry)
+	}
+
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: 2 * time.Second}
+	client := NewClientWithHTTPClient(&http.Client{}, "TestApp/1.0", WithRetryPolicy(policy))
+	if client.retry != policy {
+		t.Errorf("Expected retry policy %+v, got %+v", policy, client.retry)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{value: "", expected: 0},
+		{value: "120", expected: 2 * time.Minute},
+		{value: "-5", expected: 0},
+		{value: "Sat, 15 Jun 2024 12:00:30 GMT", expected: 30 * time.Second},
+		{value: "Sat, 15 Jun 2024 11:00:00 GMT", expected: 0},
+		{value: "soon", expected: 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.expected {
+			t.Errorf("parseRetryAfter(%q) = %v, expected %v", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	tests := []struct {
+		name       string
+		attempt    int
+		retryAfter time.Duration
+		expected   time.Duration
+	}{
+		{name: "first retry", attempt: 1, expected: time.Second},
+		{name: "doubled", attempt: 3, expected: 4 * time.Second},
+		{name: "capped", attempt: 5, expected: 10 * time.Second},
+		{name: "retry after", attempt: 1, retryAfter: 3 * time.Second, expected: 3 * time.Second},
+		{name: "retry after capped", attempt: 1, retryAfter: time.Hour, expected: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.delay(tt.attempt, tt.retryAfter); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	policy.Jitter = 0.5
+	for range 100 {
+		if got := policy.delay(1, 0); got < time.Second || got > 1500*time.Millisecond {
+			t.Fatalf("Expected the jittered delay between 1s and 1.5s, got %v", got)
+		}
+	}
+}
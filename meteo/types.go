@@ -184,6 +184,8 @@ type ForecastTimeStepData struct {
 type ForecastTimeStep struct {
 	Time time.Time             `json:"time"`
 	Data *ForecastTimeStepData `json:"data,omitempty"`
+
+	units Units // units returned by the accessor helpers, see METJSONForecast.SetUnits
 }
 
 // Forecast contains the main forecast data
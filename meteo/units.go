@@ -0,0 +1,61 @@
+package meteo
+
+// Units selects the units returned by the accessor helpers of a forecast. The forecast data itself always keeps
+// the SI units sent by the API, only GetTemperature, GetWindSpeed, GetWindGust and the precipitation accessors
+// convert. Converted values are not rounded, round them for display: the API sends one decimal, so 10.0 m/s
+// is returned as 22.369... mph.
+type Units int
+
+// Unit systems
+const (
+	Metric   Units = iota // °C, m/s and mm as sent by the API
+	Imperial              // °F, mph and inches
+)
+
+// Conversion factors from the SI units of the API
+const (
+	mphPerMS    = 3600 / 1609.344 // 1 mile = 1609.344 m
+	inchesPerMM = 1 / 25.4
+)
+
+// String returns the name of the unit system
+func (u Units) String() string {
+	if u == Imperial {
+		return "imperial"
+	}
+	return "metric"
+}
+
+// temperature converts an air temperature in °C, nil stays nil
+func (u Units) temperature(celsius *float64) *float64 {
+	if u != Imperial || celsius == nil {
+		return celsius
+	}
+	return Float64Ptr(*celsius*9/5 + 32)
+}
+
+// speed converts a wind speed in m/s, nil stays nil
+func (u Units) speed(ms *float64) *float64 {
+	if u != Imperial || ms == nil {
+		return ms
+	}
+	return Float64Ptr(*ms * mphPerMS)
+}
+
+// precipitation converts a precipitation amount in mm, nil stays nil
+func (u Units) precipitation(mm *float64) *float64 {
+	if u != Imperial || mm == nil {
+		return mm
+	}
+	return Float64Ptr(*mm * inchesPerMM)
+}
+
+// SetUnits selects the units returned by the accessor helpers of every time step of the forecast
+func (f *METJSONForecast) SetUnits(units Units) {
+	if f == nil || f.Properties == nil {
+		return
+	}
+	for i := range f.Properties.Timeseries {
+		f.Properties.Timeseries[i].units = units
+	}
+}
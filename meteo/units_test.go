@@ -0,0 +1,126 @@
+package meteo
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUnits_Conversions(t *testing.T) {
+	tests := []struct {
+		name     string
+		convert  func(Units, *float64) *float64
+		value    float64
+		expected float64
+	}{
+		{name: "freezing point", convert: Units.temperature, value: 0, expected: 32},
+		{name: "body temperature", convert: Units.temperature, value: 37, expected: 98.6},
+		{name: "below zero", convert: Units.temperature, value: -40, expected: -40},
+		{name: "wind speed", convert: Units.speed, value: 10, expected: 22.37},
+		{name: "precipitation", convert: Units.precipitation, value: 25.4, expected: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if metric := tt.convert(Metric, Float64Ptr(tt.value)); *metric != tt.value {
+				t.Errorf("Expected metric units unchanged, got %.4f", *metric)
+			}
+			if imperial := tt.convert(Imperial, Float64Ptr(tt.value)); math.Abs(*imperial-tt.expected) > 0.005 {
+				t.Errorf("Expected %.2f, got %.4f", tt.expected, *imperial)
+			}
+			if tt.convert(Imperial, nil) != nil {
+				t.Error("Expected nil to stay nil")
+			}
+		})
+	}
+}
+
+func TestForecastTimeStep_ImperialAccessors(t *testing.T) {
+	forecast := &METJSONForecast{Properties: &Forecast{Timeseries: []ForecastTimeStep{{
+		Time: time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC),
+		Data: &ForecastTimeStepData{
+			Instant: &ForecastInstantData{Details: &ForecastTimeInstant{
+				AirTemperature:  Float64Ptr(0),
+				WindSpeed:       Float64Ptr(10),
+				WindSpeedOfGust: Float64Ptr(20),
+			}},
+			Next1Hours: &ForecastPeriodData{Details: &ForecastTimePeriod{
+				PrecipitationAmount:    Float64Ptr(2.54),
+				PrecipitationAmountMin: Float64Ptr(0),
+				PrecipitationAmountMax: Float64Ptr(5.08),
+			}},
+			Next6Hours: &ForecastPeriodData{Details: &ForecastTimePeriod{PrecipitationAmount: Float64Ptr(12.7)}},
+		},
+	}}}}
+	forecast.SetUnits(Imperial)
+	step := &forecast.Properties.Timeseries[0]
+	minAmount, maxAmount, _ := step.GetPrecipitationRange()
+
+	checks := []struct {
+		name     string
+		value    *float64
+		expected float64
+	}{
+		{name: "temperature", value: step.GetTemperature(), expected: 32},
+		{name: "wind speed", value: step.GetWindSpeed(), expected: 22.37},
+		{name: "wind gust", value: step.GetWindGust(), expected: 44.74},
+		{name: "precipitation", value: step.GetPrecipitationAmount(), expected: 0.1},
+		{name: "precipitation 6h", value: step.GetPrecipitationAmountNext6h(), expected: 0.5},
+		{name: "precipitation min", value: minAmount, expected: 0},
+		{name: "precipitation max", value: maxAmount, expected: 0.2},
+	}
+	for _, check := range checks {
+		if check.value == nil || math.Abs(*check.value-check.expected) > 0.005 {
+			t.Errorf("Expected %s %.2f, got %v", check.name, check.expected, check.value)
+		}
+	}
+
+	// The data itself stays in SI units
+	if *step.Data.Instant.Details.AirTemperature != 0 || *step.Data.Instant.Details.WindSpeed != 10 {
+		t.Errorf("Expected the forecast data unchanged, got %+v", step.Data.Instant.Details)
+	}
+	// The high wind threshold is in m/s whatever the units
+	if step.IsHighWind(25) || !step.IsHighWind(15) {
+		t.Error("Expected the high wind threshold compared in m/s")
+	}
+}
+
+func TestClient_SetUnits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"type": "Feature", "properties": {"timeseries": [
+			{"time": "2024-06-10T12:00:00Z", "data": {"instant": {"details": {"air_temperature": 10.0, "wind_speed": 10.0}}}}
+		]}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("TestApp/1.0")
+	client.SetBaseURL(server.URL)
+	params := QueryParams{Location: Location{Latitude: 59.9139, Longitude: 10.7522}}
+
+	forecast, err := client.GetCompact(params)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if temp := forecast.Properties.Timeseries[0].GetTemperature(); temp == nil || *temp != 10 {
+		t.Errorf("Expected 10 °C by default, got %v", temp)
+	}
+
+	client.SetUnits(Imperial)
+	forecast, err = client.GetCompact(params)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	step := forecast.Properties.Timeseries[0]
+	if temp := step.GetTemperature(); temp == nil || math.Abs(*temp-50) > 1e-9 {
+		t.Errorf("Expected 50 °F, got %v", temp)
+	}
+	if speed := step.GetWindSpeed(); speed == nil || math.Abs(*speed-22.37) > 0.005 {
+		t.Errorf("Expected 22.37 mph, got %v", speed)
+	}
+	// Resampled steps keep the units
+	hourly := forecast.GetHourlyForecast(step.Time, step.Time)
+	if len(hourly) != 1 || *hourly[0].GetTemperature() != *step.GetTemperature() {
+		t.Errorf("Expected the hourly forecast in the same units, got %+v", hourly)
+	}
+}
@@ -1,6 +1,7 @@
 package meteo
 
 import (
+	"math"
 	"strings"
 	"time"
 )
@@ -30,6 +31,52 @@ func (f *METJSONForecast) GetCurrentWeather() *ForecastTimeStep {
 	return closest
 }
 
+// Age returns how long ago MET generated the forecast, 0 if the forecast has no update time
+func (f *METJSONForecast) Age() time.Duration {
+	return f.AgeAt(time.Now())
+}
+
+// AgeAt returns the age of the forecast at the given time, 0 if the forecast has no update time
+func (f *METJSONForecast) AgeAt(now time.Time) time.Duration {
+	if f == nil || f.Properties == nil || f.Properties.Meta.UpdatedAt.IsZero() {
+		return 0
+	}
+	return now.Sub(f.Properties.Meta.UpdatedAt)
+}
+
+// GetAltitude returns the altitude of the forecast location in meters above sea level, taken from the
+// third coordinate of the geometry, if available
+func (f *METJSONForecast) GetAltitude() *float64 {
+	if f == nil || f.Geometry == nil || len(f.Geometry.Coordinates) < 3 {
+		return nil
+	}
+	return &f.Geometry.Coordinates[2]
+}
+
+// GetStationPressure returns the air pressure in hPa at the altitude of the forecast location for a time step,
+// derived from its sea level pressure. It is nil when the altitude or the sea level pressure is not available.
+func (f *METJSONForecast) GetStationPressure(ts *ForecastTimeStep) *float64 {
+	altitude := f.GetAltitude()
+	seaLevel := ts.GetAirPressureAtSeaLevel()
+	if altitude == nil || seaLevel == nil {
+		return nil
+	}
+	return Float64Ptr(StationPressure(*seaLevel, *altitude))
+}
+
+// Standard atmosphere constants of the barometric formula
+const (
+	standardTemperature = 288.15   // K at sea level
+	temperatureLapse    = 0.0065   // K per meter in the troposphere
+	barometricExponent  = 5.255877 // g·M / (R·L)
+)
+
+// StationPressure converts a sea level air pressure to the pressure at altitude meters with the barometric
+// formula of the standard atmosphere, in the unit of seaLevelPressure
+func StationPressure(seaLevelPressure, altitude float64) float64 {
+	return seaLevelPressure * math.Pow(1-temperatureLapse*altitude/standardTemperature, barometricExponent)
+}
+
 // GetWeatherAtTime returns the weather data closest to the specified time
 func (f *METJSONForecast) GetWeatherAtTime(targetTime time.Time) *ForecastTimeStep {
 	if f == nil || f.Properties == nil || len(f.Properties.Timeseries) == 0 {
@@ -90,6 +137,63 @@ func (f *METJSONForecast) GetForecastForPeriod(start, end time.Time) []ForecastT
 	return periodForecast
 }
 
+// DominantWindDirection returns the most frequent cardinal wind direction within the specified time period
+// Ties are resolved in favor of the direction that occurs first. Returns an empty string if no wind data is available
+func (f *METJSONForecast) DominantWindDirection(start, end time.Time) string {
+	counts := make(map[string]int)
+	var order []string // directions in the order of their first occurrence
+	for _, step := range f.GetForecastForPeriod(start, end) {
+		cardinal := step.WindCardinal()
+		if cardinal == "" {
+			continue
+		}
+		if counts[cardinal] == 0 {
+			order = append(order, cardinal)
+		}
+		counts[cardinal]++
+	}
+
+	dominant := ""
+	for _, cardinal := range order {
+		if counts[cardinal] > counts[dominant] {
+			dominant = cardinal
+		}
+	}
+	return dominant
+}
+
+// CloudTrend returns the expected change in cloud area fraction (percentage points) over the window starting now.
+// Positive values mean clouds are building up (solar ramp down), negative values mean the sky is clearing (solar ramp up).
+// Returns 0 if fewer than two time steps with cloud data fall within the window
+func (f *METJSONForecast) CloudTrend(window time.Duration) float64 {
+	return f.cloudTrendFrom(time.Now(), window)
+}
+
+// cloudTrendFrom fits a least-squares line through the cloud coverage of the time steps within [start, start+window]
+// and returns its change over the window, so a single noisy time step does not dominate the trend
+func (f *METJSONForecast) cloudTrendFrom(start time.Time, window time.Duration) float64 {
+	var n, sumX, sumY, sumXY, sumXX float64
+	for _, step := range f.GetForecastForPeriod(start, start.Add(window)) {
+		cloud := step.GetCloudCoverage()
+		if cloud == nil {
+			continue
+		}
+		x := step.Time.Sub(start).Hours()
+		n++
+		sumX += x
+		sumY += *cloud
+		sumXY += x * *cloud
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if n < 2 || denominator == 0 {
+		return 0
+	}
+	slope := (n*sumXY - sumX*sumY) / denominator // percentage points per hour
+	return slope * window.Hours()
+}
+
 // HasPrecipitation checks if there's any precipitation in the given time step
 func (ts *ForecastTimeStep) HasPrecipitation() bool {
 	if ts == nil || ts.Data == nil {
@@ -115,20 +219,105 @@ func (ts *ForecastTimeStep) HasPrecipitation() bool {
 	return false
 }
 
-// GetTemperature returns the air temperature if available
+// GetPrecipitationAmount returns the precipitation amount in mm (inches in Imperial units) for the next hour
+// if available, falling back to the next 6 and 12 hours
+func (ts *ForecastTimeStep) GetPrecipitationAmount() *float64 {
+	if ts == nil || ts.Data == nil {
+		return nil
+	}
+	for _, period := range []*ForecastPeriodData{ts.Data.Next1Hours, ts.Data.Next6Hours, ts.Data.Next12Hours} {
+		if period != nil && period.Details != nil && period.Details.PrecipitationAmount != nil {
+			return ts.units.precipitation(period.Details.PrecipitationAmount)
+		}
+	}
+	return nil
+}
+
+// GetPrecipitationAmountNext6h returns the precipitation amount in mm (inches in Imperial units) for the next
+// 6 hours if available
+func (ts *ForecastTimeStep) GetPrecipitationAmountNext6h() *float64 {
+	if ts == nil || ts.Data == nil || ts.Data.Next6Hours == nil || ts.Data.Next6Hours.Details == nil {
+		return nil
+	}
+	return ts.units.precipitation(ts.Data.Next6Hours.Details.PrecipitationAmount)
+}
+
+// GetProbabilityOfPrecipitation returns the probability of precipitation in % for the next hour if available,
+// falling back to the next 6 and 12 hours. The value is only provided by the complete endpoint.
+func (ts *ForecastTimeStep) GetProbabilityOfPrecipitation() *float64 {
+	if ts == nil || ts.Data == nil {
+		return nil
+	}
+	for _, period := range []*ForecastPeriodData{ts.Data.Next1Hours, ts.Data.Next6Hours, ts.Data.Next12Hours} {
+		if period != nil && period.Details != nil && period.Details.ProbabilityOfPrecipitation != nil {
+			return period.Details.ProbabilityOfPrecipitation
+		}
+	}
+	return nil
+}
+
+// GetProbabilityOfThunder returns the probability of thunder in % for the next hour if available,
+// falling back to the next 6 hours. The value is only provided by the complete endpoint.
+func (ts *ForecastTimeStep) GetProbabilityOfThunder() *float64 {
+	if ts == nil || ts.Data == nil {
+		return nil
+	}
+	if ts.Data.Next1Hours != nil && ts.Data.Next1Hours.Details != nil && ts.Data.Next1Hours.Details.ProbabilityOfThunder != nil {
+		return ts.Data.Next1Hours.Details.ProbabilityOfThunder
+	}
+	if ts.Data.Next6Hours != nil && ts.Data.Next6Hours.Details != nil {
+		return ts.Data.Next6Hours.Details.ProbabilityOfThunder
+	}
+	return nil
+}
+
+// GetTemperature returns the air temperature in °C (°F in Imperial units) if available
 func (ts *ForecastTimeStep) GetTemperature() *float64 {
 	if ts == nil || ts.Data == nil || ts.Data.Instant == nil || ts.Data.Instant.Details == nil {
 		return nil
 	}
-	return ts.Data.Instant.Details.AirTemperature
+	return ts.units.temperature(ts.Data.Instant.Details.AirTemperature)
+}
+
+// GetAirPressureAtSeaLevel returns the air pressure at sea level in hPa if available
+func (ts *ForecastTimeStep) GetAirPressureAtSeaLevel() *float64 {
+	if ts == nil || ts.Data == nil || ts.Data.Instant == nil || ts.Data.Instant.Details == nil {
+		return nil
+	}
+	return ts.Data.Instant.Details.AirPressureAtSeaLevel
 }
 
-// GetWindSpeed returns the wind speed if available
+// GetWindSpeed returns the wind speed in m/s (mph in Imperial units) if available
 func (ts *ForecastTimeStep) GetWindSpeed() *float64 {
 	if ts == nil || ts.Data == nil || ts.Data.Instant == nil || ts.Data.Instant.Details == nil {
 		return nil
 	}
-	return ts.Data.Instant.Details.WindSpeed
+	return ts.units.speed(ts.Data.Instant.Details.WindSpeed)
+}
+
+// GetWindGust returns the wind speed of gusts in m/s (mph in Imperial units) if available.
+// The value is only provided by the complete endpoint.
+func (ts *ForecastTimeStep) GetWindGust() *float64 {
+	if ts == nil || ts.Data == nil || ts.Data.Instant == nil || ts.Data.Instant.Details == nil {
+		return nil
+	}
+	return ts.units.speed(ts.Data.Instant.Details.WindSpeedOfGust)
+}
+
+// IsHighWind returns true if the sustained wind speed or the gusts exceed thresholdMS in m/s, whatever the units
+// of the forecast
+func (ts *ForecastTimeStep) IsHighWind(thresholdMS float64) bool {
+	if ts == nil || ts.Data == nil || ts.Data.Instant == nil || ts.Data.Instant.Details == nil {
+		return false
+	}
+	details := ts.Data.Instant.Details
+	if details.WindSpeed != nil && *details.WindSpeed > thresholdMS {
+		return true
+	}
+	if details.WindSpeedOfGust != nil && *details.WindSpeedOfGust > thresholdMS {
+		return true
+	}
+	return false
 }
 
 // GetWindDirection returns the wind direction if available
@@ -139,6 +328,29 @@ func (ts *ForecastTimeStep) GetWindDirection() *float64 {
 	return ts.Data.Instant.Details.WindFromDirection
 }
 
+// windCardinals lists the 16 compass points clockwise from north
+var windCardinals = [...]string{
+	"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE",
+	"S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW",
+}
+
+// WindCardinal returns the wind direction as a 16-point compass direction (N, NNE, ..., NNW)
+// Returns an empty string if the wind direction is not available
+func (ts *ForecastTimeStep) WindCardinal() string {
+	direction := ts.GetWindDirection()
+	if direction == nil {
+		return ""
+	}
+
+	sector := 360.0 / float64(len(windCardinals))
+	degrees := math.Mod(*direction, 360)
+	if degrees < 0 {
+		degrees += 360
+	}
+	index := int(math.Floor((degrees+sector/2)/sector)) % len(windCardinals)
+	return windCardinals[index]
+}
+
 // GetHumidity returns the relative humidity if available
 func (ts *ForecastTimeStep) GetHumidity() *float64 {
 	if ts == nil || ts.Data == nil || ts.Data.Instant == nil || ts.Data.Instant.Details == nil {
@@ -155,6 +367,25 @@ func (ts *ForecastTimeStep) GetCloudCoverage() *float64 {
 	return ts.Data.Instant.Details.CloudAreaFraction
 }
 
+// GetPrecipitationRange returns the minimum, maximum and expected precipitation amount in mm (inches in Imperial
+// units) from the shortest period with a precipitation amount, the next hour before the next 6 and 12 hours.
+// The minimum and maximum are nil when the period only has the expected amount, all three are nil without
+// precipitation data.
+func (ts *ForecastTimeStep) GetPrecipitationRange() (minAmount, maxAmount, expected *float64) {
+	if ts == nil || ts.Data == nil {
+		return nil, nil, nil
+	}
+	for _, period := range []*ForecastPeriodData{ts.Data.Next1Hours, ts.Data.Next6Hours, ts.Data.Next12Hours} {
+		if period == nil || period.Details == nil || period.Details.PrecipitationAmount == nil {
+			continue
+		}
+		details := period.Details
+		return ts.units.precipitation(details.PrecipitationAmountMin), ts.units.precipitation(details.PrecipitationAmountMax),
+			ts.units.precipitation(details.PrecipitationAmount)
+	}
+	return nil, nil, nil
+}
+
 // GetSymbolCode returns the weather symbol code for the next hour if available
 func (ts *ForecastTimeStep) GetSymbolCode() *WeatherSymbol {
 	if ts == nil || ts.Data == nil {
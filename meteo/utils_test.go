@@ -1,6 +1,7 @@
 package meteo
 
 import (
+	"math"
 	"testing"
 	"time"
 )
@@ -53,6 +54,30 @@ func TestMETJSONForecast_GetCurrentWeather_EmptyTimeseries(t *testing.T) {
 	}
 }
 
+func TestMETJSONForecast_Age(t *testing.T) {
+	updatedAt := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	forecast := &METJSONForecast{
+		Properties: &Forecast{
+			Meta: ForecastMeta{UpdatedAt: updatedAt},
+		},
+	}
+
+	if age := forecast.AgeAt(updatedAt.Add(90 * time.Minute)); age != 90*time.Minute {
+		t.Errorf("Expected age 1h30m0s, got %v", age)
+	}
+	if age := forecast.Age(); age < time.Since(updatedAt)-time.Minute {
+		t.Errorf("Expected the age relative to now, got %v", age)
+	}
+
+	var missing *METJSONForecast
+	if age := missing.AgeAt(updatedAt); age != 0 {
+		t.Errorf("Expected age 0 for a nil forecast, got %v", age)
+	}
+	if age := (&METJSONForecast{Properties: &Forecast{}}).AgeAt(updatedAt); age != 0 {
+		t.Errorf("Expected age 0 without an update time, got %v", age)
+	}
+}
+
 func TestMETJSONForecast_GetWeatherAtTime(t *testing.T) {
 	target := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
 	closest := time.Date(2023, 1, 1, 12, 30, 0, 0, time.UTC) // 30 minutes after target
@@ -216,6 +241,152 @@ func TestForecastTimeStep_HasPrecipitation(t *testing.T) {
 	}
 }
 
+func TestForecastTimeStep_GetProbabilityOfThunder(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeStep *ForecastTimeStep
+		expected *float64
+	}{
+		{
+			name:     "nil time step",
+			timeStep: nil,
+			expected: nil,
+		},
+		{
+			name: "compact data without probabilities",
+			timeStep: &ForecastTimeStep{
+				Data: &ForecastTimeStepData{
+					Next1Hours: &ForecastPeriodData{
+						Details: &ForecastTimePeriod{PrecipitationAmount: Float64Ptr(0.4)},
+					},
+				},
+			},
+			expected: nil,
+		},
+		{
+			name: "next 1 hour preferred",
+			timeStep: &ForecastTimeStep{
+				Data: &ForecastTimeStepData{
+					Next1Hours: &ForecastPeriodData{
+						Details: &ForecastTimePeriod{ProbabilityOfThunder: Float64Ptr(35.0)},
+					},
+					Next6Hours: &ForecastPeriodData{
+						Details: &ForecastTimePeriod{ProbabilityOfThunder: Float64Ptr(80.0)},
+					},
+				},
+			},
+			expected: Float64Ptr(35.0),
+		},
+		{
+			name: "fallback to next 6 hours",
+			timeStep: &ForecastTimeStep{
+				Data: &ForecastTimeStepData{
+					Next6Hours: &ForecastPeriodData{
+						Details: &ForecastTimePeriod{ProbabilityOfThunder: Float64Ptr(12.5)},
+					},
+				},
+			},
+			expected: Float64Ptr(12.5),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.timeStep.GetProbabilityOfThunder()
+			if (result == nil) != (tt.expected == nil) {
+				t.Fatalf("Expected nil status %v, got %v", tt.expected == nil, result == nil)
+			}
+			if result != nil && *result != *tt.expected {
+				t.Errorf("Expected probability %.1f, got %.1f", *tt.expected, *result)
+			}
+		})
+	}
+}
+
+func TestForecastTimeStep_PrecipitationAccessors(t *testing.T) {
+	tests := []struct {
+		name                string
+		timeStep            *ForecastTimeStep
+		expectedAmount      *float64
+		expectedAmount6h    *float64
+		expectedProbability *float64
+	}{
+		{
+			name:     "nil time step",
+			timeStep: nil,
+		},
+		{
+			name: "no precipitation data",
+			timeStep: &ForecastTimeStep{
+				Data: &ForecastTimeStepData{
+					Next1Hours: &ForecastPeriodData{Summary: &ForecastSummary{SymbolCode: Cloudy}},
+				},
+			},
+		},
+		{
+			name: "next 1 hour preferred",
+			timeStep: &ForecastTimeStep{
+				Data: &ForecastTimeStepData{
+					Next1Hours: &ForecastPeriodData{
+						Details: &ForecastTimePeriod{PrecipitationAmount: Float64Ptr(0.4), ProbabilityOfPrecipitation: Float64Ptr(40.0)},
+					},
+					Next6Hours: &ForecastPeriodData{
+						Details: &ForecastTimePeriod{PrecipitationAmount: Float64Ptr(2.5), ProbabilityOfPrecipitation: Float64Ptr(70.0)},
+					},
+				},
+			},
+			expectedAmount:      Float64Ptr(0.4),
+			expectedAmount6h:    Float64Ptr(2.5),
+			expectedProbability: Float64Ptr(40.0),
+		},
+		{
+			name: "fallback to next 6 hours",
+			timeStep: &ForecastTimeStep{
+				Data: &ForecastTimeStepData{
+					Next1Hours: &ForecastPeriodData{Summary: &ForecastSummary{SymbolCode: Rain}},
+					Next6Hours: &ForecastPeriodData{
+						Details: &ForecastTimePeriod{PrecipitationAmount: Float64Ptr(2.5), ProbabilityOfPrecipitation: Float64Ptr(70.0)},
+					},
+				},
+			},
+			expectedAmount:      Float64Ptr(2.5),
+			expectedAmount6h:    Float64Ptr(2.5),
+			expectedProbability: Float64Ptr(70.0),
+		},
+		{
+			name: "fallback to next 12 hours",
+			timeStep: &ForecastTimeStep{
+				Data: &ForecastTimeStepData{
+					Next12Hours: &ForecastPeriodData{
+						Details: &ForecastTimePeriod{PrecipitationAmount: Float64Ptr(6.0), ProbabilityOfPrecipitation: Float64Ptr(90.0)},
+					},
+				},
+			},
+			expectedAmount:      Float64Ptr(6.0),
+			expectedProbability: Float64Ptr(90.0),
+		},
+	}
+
+	check := func(t *testing.T, what string, result, expected *float64) {
+		t.Helper()
+		if (result == nil) != (expected == nil) {
+			t.Errorf("Expected %s nil status %v, got %v", what, expected == nil, result == nil)
+			return
+		}
+		if result != nil && *result != *expected {
+			t.Errorf("Expected %s %.1f, got %.1f", what, *expected, *result)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check(t, "amount", tt.timeStep.GetPrecipitationAmount(), tt.expectedAmount)
+			check(t, "6 hour amount", tt.timeStep.GetPrecipitationAmountNext6h(), tt.expectedAmount6h)
+			check(t, "probability", tt.timeStep.GetProbabilityOfPrecipitation(), tt.expectedProbability)
+		})
+	}
+}
+
 func TestForecastTimeStep_GetTemperature(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -262,6 +433,188 @@ func TestForecastTimeStep_GetTemperature(t *testing.T) {
 	}
 }
 
+func TestForecastTimeStep_WindCardinal(t *testing.T) {
+	tests := []struct {
+		name      string
+		direction *float64
+		expected  string
+	}{
+		{name: "missing direction", direction: nil, expected: ""},
+		{name: "north", direction: Float64Ptr(0), expected: "N"},
+		{name: "north upper boundary", direction: Float64Ptr(11.24), expected: "N"},
+		{name: "north-northeast", direction: Float64Ptr(11.25), expected: "NNE"},
+		{name: "northeast", direction: Float64Ptr(45), expected: "NE"},
+		{name: "east", direction: Float64Ptr(90), expected: "E"},
+		{name: "south-southeast", direction: Float64Ptr(157.5), expected: "SSE"},
+		{name: "south", direction: Float64Ptr(180), expected: "S"},
+		{name: "southwest", direction: Float64Ptr(225), expected: "SW"},
+		{name: "west", direction: Float64Ptr(270), expected: "W"},
+		{name: "north-northwest", direction: Float64Ptr(340), expected: "NNW"},
+		{name: "north wraps around", direction: Float64Ptr(355), expected: "N"},
+		{name: "full circle", direction: Float64Ptr(360), expected: "N"},
+		{name: "negative degrees", direction: Float64Ptr(-90), expected: "W"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := &ForecastTimeStep{
+				Data: &ForecastTimeStepData{
+					Instant: &ForecastInstantData{
+						Details: &ForecastTimeInstant{
+							WindFromDirection: tt.direction,
+						},
+					},
+				},
+			}
+			if result := step.WindCardinal(); result != tt.expected {
+				t.Errorf("Expected cardinal %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMETJSONForecast_DominantWindDirection(t *testing.T) {
+	base := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	stepWithDirection := func(hour int, direction *float64) ForecastTimeStep {
+		return ForecastTimeStep{
+			Time: base.Add(time.Duration(hour) * time.Hour),
+			Data: &ForecastTimeStepData{
+				Instant: &ForecastInstantData{
+					Details: &ForecastTimeInstant{
+						WindFromDirection: direction,
+					},
+				},
+			},
+		}
+	}
+
+	forecast := &METJSONForecast{
+		Properties: &Forecast{
+			Timeseries: []ForecastTimeStep{
+				stepWithDirection(0, Float64Ptr(180)), // S
+				stepWithDirection(1, Float64Ptr(250)), // WSW
+				stepWithDirection(2, Float64Ptr(268)), // W
+				stepWithDirection(3, nil),
+				stepWithDirection(4, Float64Ptr(275)), // W
+				stepWithDirection(5, Float64Ptr(248)), // WSW
+				stepWithDirection(6, Float64Ptr(272)), // W
+				stepWithDirection(7, Float64Ptr(0)),   // N
+				stepWithDirection(8, Float64Ptr(5)),   // N
+				stepWithDirection(9, Float64Ptr(355)), // N
+				stepWithDirection(10, Float64Ptr(10)), // N
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		end      time.Time
+		expected string
+	}{
+		{name: "morning window", start: base, end: base.Add(6 * time.Hour), expected: "W"},
+		{name: "whole forecast", start: base, end: base.Add(10 * time.Hour), expected: "N"},
+		{name: "tie resolved by first occurrence", start: base.Add(time.Hour), end: base.Add(2 * time.Hour), expected: "WSW"},
+		// W reaches two occurrences first, WSW still occurs first
+		{name: "tie resolved by first occurrence, not first to the count", start: base.Add(time.Hour), end: base.Add(5 * time.Hour), expected: "WSW"},
+		{name: "only missing direction", start: base.Add(3 * time.Hour), end: base.Add(3 * time.Hour), expected: ""},
+		{name: "window without data", start: base.Add(24 * time.Hour), end: base.Add(48 * time.Hour), expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := forecast.DominantWindDirection(tt.start, tt.end); result != tt.expected {
+				t.Errorf("Expected dominant direction %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMETJSONForecast_CloudTrend(t *testing.T) {
+	base := time.Date(2023, 6, 1, 8, 0, 0, 0, time.UTC)
+	forecastWithClouds := func(clouds ...*float64) *METJSONForecast {
+		timeseries := make([]ForecastTimeStep, len(clouds))
+		for i, cloud := range clouds {
+			timeseries[i] = ForecastTimeStep{
+				Time: base.Add(time.Duration(i) * time.Hour),
+				Data: &ForecastTimeStepData{
+					Instant: &ForecastInstantData{
+						Details: &ForecastTimeInstant{
+							CloudAreaFraction: cloud,
+						},
+					},
+				},
+			}
+		}
+		return &METJSONForecast{Properties: &Forecast{Timeseries: timeseries}}
+	}
+
+	tests := []struct {
+		name     string
+		forecast *METJSONForecast
+		window   time.Duration
+		expected float64
+	}{
+		{
+			name:     "increasing clouds",
+			forecast: forecastWithClouds(Float64Ptr(10), Float64Ptr(30), Float64Ptr(50), Float64Ptr(70)),
+			window:   3 * time.Hour,
+			expected: 60,
+		},
+		{
+			name:     "decreasing clouds",
+			forecast: forecastWithClouds(Float64Ptr(100), Float64Ptr(75), Float64Ptr(50), Float64Ptr(25), Float64Ptr(0)),
+			window:   4 * time.Hour,
+			expected: -100,
+		},
+		{
+			name:     "steady clouds",
+			forecast: forecastWithClouds(Float64Ptr(40), Float64Ptr(40), Float64Ptr(40)),
+			window:   2 * time.Hour,
+			expected: 0,
+		},
+		{
+			name:     "window shorter than forecast",
+			forecast: forecastWithClouds(Float64Ptr(0), Float64Ptr(20), Float64Ptr(100), Float64Ptr(100)),
+			window:   time.Hour,
+			expected: 20,
+		},
+		{
+			name:     "noisy step is smoothed",
+			forecast: forecastWithClouds(Float64Ptr(0), Float64Ptr(60), Float64Ptr(20), Float64Ptr(30)),
+			window:   3 * time.Hour,
+			expected: 15, // endpoints alone would give 30
+		},
+		{
+			name:     "missing cloud data is skipped",
+			forecast: forecastWithClouds(Float64Ptr(80), nil, Float64Ptr(40)),
+			window:   2 * time.Hour,
+			expected: -40,
+		},
+		{
+			name:     "single step",
+			forecast: forecastWithClouds(Float64Ptr(50)),
+			window:   3 * time.Hour,
+			expected: 0,
+		},
+		{
+			name:     "nil forecast",
+			forecast: nil,
+			window:   3 * time.Hour,
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.forecast.cloudTrendFrom(base, tt.window)
+			if math.Abs(result-tt.expected) > 1e-9 {
+				t.Errorf("Expected cloud trend %.2f, got %.2f", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestForecastTimeStep_GetSymbolCode(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -336,6 +689,88 @@ func TestForecastTimeStep_GetSymbolCode(t *testing.T) {
 	}
 }
 
+func TestForecastTimeStep_GetPrecipitationRange(t *testing.T) {
+	tests := []struct {
+		name             string
+		timeStep         *ForecastTimeStep
+		expectedMin      *float64
+		expectedMax      *float64
+		expectedExpected *float64
+	}{
+		{
+			name:     "nil time step",
+			timeStep: nil,
+		},
+		{
+			name: "no precipitation data",
+			timeStep: &ForecastTimeStep{
+				Data: &ForecastTimeStepData{
+					Next1Hours: &ForecastPeriodData{Summary: &ForecastSummary{SymbolCode: Cloudy}},
+				},
+			},
+		},
+		{
+			name: "only the expected amount",
+			timeStep: &ForecastTimeStep{
+				Data: &ForecastTimeStepData{
+					Next1Hours: &ForecastPeriodData{
+						Details: &ForecastTimePeriod{PrecipitationAmount: Float64Ptr(0.4)},
+					},
+				},
+			},
+			expectedExpected: Float64Ptr(0.4),
+		},
+		{
+			name: "min, max and expected amount",
+			timeStep: &ForecastTimeStep{
+				Data: &ForecastTimeStepData{
+					Next1Hours: &ForecastPeriodData{
+						Details: &ForecastTimePeriod{
+							PrecipitationAmount:    Float64Ptr(0.4),
+							PrecipitationAmountMin: Float64Ptr(0.1),
+							PrecipitationAmountMax: Float64Ptr(1.2),
+						},
+					},
+				},
+			},
+			expectedMin:      Float64Ptr(0.1),
+			expectedMax:      Float64Ptr(1.2),
+			expectedExpected: Float64Ptr(0.4),
+		},
+		{
+			name: "next 6 hours when the next hour has no amount",
+			timeStep: &ForecastTimeStep{
+				Data: &ForecastTimeStepData{
+					Next1Hours: &ForecastPeriodData{Details: &ForecastTimePeriod{}},
+					Next6Hours: &ForecastPeriodData{
+						Details: &ForecastTimePeriod{
+							PrecipitationAmount:    Float64Ptr(3.0),
+							PrecipitationAmountMin: Float64Ptr(1.0),
+							PrecipitationAmountMax: Float64Ptr(6.5),
+						},
+					},
+				},
+			},
+			expectedMin:      Float64Ptr(1.0),
+			expectedMax:      Float64Ptr(6.5),
+			expectedExpected: Float64Ptr(3.0),
+		},
+	}
+
+	equal := func(a, b *float64) bool {
+		return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minAmount, maxAmount, expected := tt.timeStep.GetPrecipitationRange()
+			if !equal(minAmount, tt.expectedMin) || !equal(maxAmount, tt.expectedMax) || !equal(expected, tt.expectedExpected) {
+				t.Errorf("Expected range (%v, %v, %v), got (%v, %v, %v)",
+					tt.expectedMin, tt.expectedMax, tt.expectedExpected, minAmount, maxAmount, expected)
+			}
+		})
+	}
+}
+
 func TestWeatherSymbol_IsDay(t *testing.T) {
 	tests := []struct {
 		symbol   WeatherSymbol
@@ -476,3 +911,93 @@ func TestHelperFunctions(t *testing.T) {
 		t.Errorf("StringPtr failed: expected %s, got %v", stringVal, stringPtr)
 	}
 }
+
+func TestStationPressure(t *testing.T) {
+	tests := []struct {
+		name     string
+		altitude float64
+		expected float64
+	}{
+		{name: "sea level", altitude: 0, expected: 1013.25},
+		// Standard atmosphere pressures
+		{name: "500 m", altitude: 500, expected: 954.61},
+		{name: "1000 m", altitude: 1000, expected: 898.75},
+		{name: "below sea level", altitude: -400, expected: 1062.23},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StationPressure(1013.25, tt.altitude); math.Abs(got-tt.expected) > 0.01 {
+				t.Errorf("Expected %.2f hPa, got %.2f hPa", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMETJSONForecast_GetStationPressure(t *testing.T) {
+	step := &ForecastTimeStep{
+		Data: &ForecastTimeStepData{
+			Instant: &ForecastInstantData{
+				Details: &ForecastTimeInstant{AirPressureAtSeaLevel: Float64Ptr(1020.0)},
+			},
+		},
+	}
+	forecast := &METJSONForecast{
+		Geometry: &PointGeometry{Type: "Point", Coordinates: []float64{10.7522, 59.9139, 1000}},
+	}
+
+	if altitude := forecast.GetAltitude(); altitude == nil || *altitude != 1000 {
+		t.Fatalf("Expected altitude 1000 m, got %v", altitude)
+	}
+	pressure := forecast.GetStationPressure(step)
+	if pressure == nil {
+		t.Fatal("Expected a station pressure")
+	}
+	if *pressure >= 1020 || math.Abs(*pressure-StationPressure(1020, 1000)) > 1e-9 {
+		t.Errorf("Expected the station pressure below the sea level pressure, got %.2f hPa", *pressure)
+	}
+
+	// Without altitude or sea level pressure there is nothing to convert
+	if p := (&METJSONForecast{Geometry: &PointGeometry{Coordinates: []float64{10.7522, 59.9139}}}).GetStationPressure(step); p != nil {
+		t.Errorf("Expected nil without altitude, got %.2f", *p)
+	}
+	if p := forecast.GetStationPressure(&ForecastTimeStep{}); p != nil {
+		t.Errorf("Expected nil without sea level pressure, got %.2f", *p)
+	}
+}
+
+func TestForecastTimeStep_WindGust(t *testing.T) {
+	instant := func(speed, gust *float64) *ForecastTimeStep {
+		return &ForecastTimeStep{
+			Data: &ForecastTimeStepData{
+				Instant: &ForecastInstantData{
+					Details: &ForecastTimeInstant{WindSpeed: speed, WindSpeedOfGust: gust},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		timeStep     *ForecastTimeStep
+		expectedGust *float64
+		expectedHigh bool
+	}{
+		{name: "nil time step", timeStep: nil},
+		{name: "compact data without gusts", timeStep: instant(Float64Ptr(5), nil)},
+		{name: "sustained wind above threshold", timeStep: instant(Float64Ptr(16), nil), expectedHigh: true},
+		{name: "gust above threshold", timeStep: instant(Float64Ptr(8), Float64Ptr(18.5)), expectedGust: Float64Ptr(18.5), expectedHigh: true},
+		{name: "gust at threshold", timeStep: instant(Float64Ptr(8), Float64Ptr(15)), expectedGust: Float64Ptr(15)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gust := tt.timeStep.GetWindGust()
+			if (gust == nil) != (tt.expectedGust == nil) || (gust != nil && *gust != *tt.expectedGust) {
+				t.Errorf("Expected gust %v, got %v", tt.expectedGust, gust)
+			}
+			if high := tt.timeStep.IsHighWind(15); high != tt.expectedHigh {
+				t.Errorf("Expected IsHighWind %v, got %v", tt.expectedHigh, high)
+			}
+		})
+	}
+}
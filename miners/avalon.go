@@ -320,43 +320,73 @@ func (s *StatsItem) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// DefaultPort is the default Avalon cgminer API port.
+const DefaultPort = 4028
+
+// NewHost creates a host from an "address" or "address:port" string without contacting the miner.
+// The port defaults to DefaultPort.
+func NewHost(hostport string) (*AvalonQHost, error) {
+	if addr, err := netip.ParseAddr(hostport); err == nil {
+		return &AvalonQHost{Address: addr.String(), Port: DefaultPort}, nil
+	}
+	addrPort, err := netip.ParseAddrPort(hostport)
+	if err != nil {
+		return nil, fmt.Errorf("invalid miner host %q: %w", hostport, err)
+	}
+	return &AvalonQHost{Address: addrPort.Addr().String(), Port: int(addrPort.Port())}, nil
+}
+
 // Discover searches for Avalon miners on the specified network and returns a list of discovered hosts.
 func Discover(ctx context.Context, network string) []*AvalonQHost {
-	results := make(chan *AvalonQHost)
-	var wg sync.WaitGroup
-	queue := make(chan string, 25)
+	return DiscoverFiltered(ctx, network, nil)
+}
+
+// DiscoverFiltered searches for Avalon miners on the specified network, probing only the addresses
+// for which allowed returns true. A nil filter probes every address in the network.
+func DiscoverFiltered(ctx context.Context, network string, allowed func(netip.Addr) bool) []*AvalonQHost {
+	return discover(ctx, network, DefaultPort, allowed, version)
+}
 
-	// Collector goroutine - single writer to hosts slice
+// discoveryWorkers is the number of addresses probed at the same time during discovery
+const discoveryWorkers = 25
+
+// discover streams the addresses of network to a fixed pool of workers probing them on port. The address list
+// is never materialized, so memory and goroutines stay bounded whatever the size of the network.
+func discover(ctx context.Context, network string, port int, allowed func(netip.Addr) bool,
+	probe func(ctx context.Context, address string, port int) (*AvalonQVersion, error)) []*AvalonQHost {
+	addresses := make(chan string)
+	var mu sync.Mutex
 	hosts := make([]*AvalonQHost, 0)
-	done := make(chan struct{})
-	go func() {
-		for host := range results {
-			hosts = append(hosts, host)
-		}
-		close(done)
-	}()
 
-	for a := range getAddresses(ctx, network) {
-		address := a.String()
-		queue <- address
+	var wg sync.WaitGroup
+	for range discoveryWorkers {
 		wg.Go(func() {
-			if v, err := version(ctx, address, 4028); err == nil {
-				results <- &AvalonQHost{
-					Address: address,
-					Port:    4028,
-					Version: v,
+			for address := range addresses {
+				v, err := probe(ctx, address, port)
+				if err != nil {
+					continue
 				}
+				mu.Lock()
+				hosts = append(hosts, &AvalonQHost{
+					Address:  address,
+					Port:     port,
+					Version:  v,
+					LastSeen: time.Now(),
+				})
+				mu.Unlock()
 			}
-			<-queue
 		})
 	}
+
+	for a := range getAddresses(ctx, network, allowed) {
+		addresses <- a.String()
+	}
+	close(addresses)
 	wg.Wait()
-	close(results)
-	<-done
 	return hosts
 }
 
-func getAddresses(ctx context.Context, network string) iter.Seq[netip.Addr] {
+func getAddresses(ctx context.Context, network string, allowed func(netip.Addr) bool) iter.Seq[netip.Addr] {
 	return func(yield func(netip.Addr) bool) {
 		prefix, _ := netip.ParsePrefix(network)
 		next := prefix.Addr().Next()
@@ -368,8 +398,10 @@ func getAddresses(ctx context.Context, network string) iter.Seq[netip.Addr] {
 			default:
 			}
 
-			if !yield(next) {
-				return
+			if allowed == nil || allowed(next) {
+				if !yield(next) {
+					return
+				}
 			}
 			next = next.Next()
 		}
@@ -377,6 +409,7 @@ func getAddresses(ctx context.Context, network string) iter.Seq[netip.Addr] {
 }
 
 // Standby puts the Avalon miner into standby mode.
+// The control methods return the raw response of the miner, or a *CommandError if it rejects the command.
 func (h *AvalonQHost) Standby(ctx context.Context) (string, error) {
 	if _, err := h.SetWorkMode(ctx, AvalonEcoMode, true); err != nil {
 		return "", err
@@ -386,7 +419,7 @@ func (h *AvalonQHost) Standby(ctx context.Context) (string, error) {
 			_, err := fmt.Fprintf(conn, "ascset|0,softoff,1: %d", time.Now().Unix())
 			return err
 		},
-		readStringResponse,
+		readCommandResponse,
 	)
 }
 
@@ -400,7 +433,7 @@ func (h *AvalonQHost) SetWorkMode(ctx context.Context, mode AvalonWorkMode, rese
 			_, err := fmt.Fprintf(conn, "ascset|0,workmode,set,%d", mode)
 			return err
 		},
-		readStringResponse,
+		readCommandResponse,
 	)
 }
 
@@ -412,10 +445,44 @@ func (h *AvalonQHost) WakeUp(ctx context.Context) (string, error) {
 			_, err := fmt.Fprintf(conn, "ascset|0,softon,1: %d", time.Now().Unix())
 			return err
 		},
-		readStringResponse,
+		readCommandResponse,
+	)
+}
+
+// Reboot restarts the Avalon miner, it stops responding until it has booted again.
+func (h *AvalonQHost) Reboot(ctx context.Context) (string, error) {
+	h.ResetLiteStats()
+	return send(ctx, h.Address, h.Port,
+		func(conn net.Conn) error {
+			_, err := fmt.Fprint(conn, "ascset|0,reboot,0")
+			return err
+		},
+		readCommandResponse,
 	)
 }
 
+// GetPools returns the configured pools of the Avalon miner with their connection status.
+func (h *AvalonQHost) GetPools(ctx context.Context) ([]PoolStatus, error) {
+	pools, err := send(ctx, h.Address, h.Port,
+		func(conn net.Conn) error {
+			return writeCommand("pools", conn)
+		},
+		func(conn net.Conn) (*AvalonQPools, error) {
+			p := &AvalonQPools{}
+			if err := readJSONResponse(conn, p); err != nil {
+				return nil, err
+			}
+			return p, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	if len(pools.Status) > 0 && pools.Status[0].Status != "S" {
+		return nil, fmt.Errorf("pools request failed: %s", pools.Status[0].Msg)
+	}
+	return pools.Pools, nil
+}
+
 // RefreshLiteStats refreshes the lite statistics for the Avalon miner.
 func (h *AvalonQHost) RefreshLiteStats(ctx context.Context) {
 	stats, err := send(ctx, h.Address, h.Port,
@@ -502,6 +569,58 @@ func readStringResponse(conn net.Conn) (string, error) {
 	return string(r), nil
 }
 
+// ParseCommandResult parses the response of an ascset command. Only the first section of the response
+// is used, a message containing commas is kept whole.
+func ParseCommandResult(response string) (CommandResult, error) {
+	section, _, _ := strings.Cut(strings.TrimRight(response, "\x00\r\n"), "|")
+	var result CommandResult
+	var last *string
+	hasStatus := false
+	for _, field := range strings.Split(section, ",") {
+		key, value, found := strings.Cut(field, "=")
+		switch {
+		case found && key == "STATUS":
+			result.Status = value
+			hasStatus = true
+			last = &result.Status
+		case found && key == "When":
+			result.When, _ = strconv.ParseInt(value, 10, 64)
+			last = nil
+		case found && key == "Code":
+			result.Code, _ = strconv.Atoi(value)
+			last = nil
+		case found && key == "Msg":
+			result.Msg = value
+			last = &result.Msg
+		case found && key == "Description":
+			result.Description = value
+			last = &result.Description
+		case last != nil:
+			*last += "," + field
+		}
+	}
+	if !hasStatus {
+		return CommandResult{}, fmt.Errorf("unexpected command response: %q", response)
+	}
+	return result, nil
+}
+
+// readCommandResponse reads the raw response of an ascset command, a rejected command is a *CommandError
+func readCommandResponse(conn net.Conn) (string, error) {
+	response, err := readStringResponse(conn)
+	if err != nil {
+		return "", err
+	}
+	result, err := ParseCommandResult(response)
+	if err != nil {
+		return "", err
+	}
+	if !result.Success() {
+		return "", &CommandError{Result: result}
+	}
+	return response, nil
+}
+
 func readJSONResponse(conn net.Conn, response any) error {
 	dec := json.NewDecoder(conn)
 	return dec.Decode(response)
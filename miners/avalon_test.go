@@ -1,9 +1,19 @@
 package miners
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
 	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestAvalonQLiteStatParsing(t *testing.T) {
@@ -325,3 +335,458 @@ func TestAvalonQLiteStatParsing(t *testing.T) {
 		t.Errorf("Expected ID 1, got %d", liteStat.ID)
 	}
 }
+
+func TestGetAddresses_Filter(t *testing.T) {
+	denied := netip.MustParseAddr("192.168.1.3")
+	tests := []struct {
+		name     string
+		allowed  func(netip.Addr) bool
+		expected []string
+	}{
+		{
+			name:     "no filter",
+			allowed:  nil,
+			expected: []string{"192.168.1.1", "192.168.1.2", "192.168.1.3", "192.168.1.4", "192.168.1.5", "192.168.1.6"},
+		},
+		{
+			name:     "denied address is skipped",
+			allowed:  func(addr netip.Addr) bool { return addr != denied },
+			expected: []string{"192.168.1.1", "192.168.1.2", "192.168.1.4", "192.168.1.5", "192.168.1.6"},
+		},
+		{
+			name:     "nothing allowed",
+			allowed:  func(netip.Addr) bool { return false },
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var addresses []string
+			for addr := range getAddresses(context.Background(), "192.168.1.0/29", tt.allowed) {
+				addresses = append(addresses, addr.String())
+			}
+			if !slices.Equal(addresses, tt.expected) {
+				t.Errorf("Expected addresses %v, got %v", tt.expected, addresses)
+			}
+		})
+	}
+}
+
+func TestNewHost(t *testing.T) {
+	tests := []struct {
+		name         string
+		hostport     string
+		expectedIP   string
+		expectedPort int
+		expectError  bool
+	}{
+		{name: "address only uses default port", hostport: "192.168.1.10", expectedIP: "192.168.1.10", expectedPort: DefaultPort},
+		{name: "address with port", hostport: "192.168.1.11:4029", expectedIP: "192.168.1.11", expectedPort: 4029},
+		{name: "hostname is rejected", hostport: "miner.local", expectError: true},
+		{name: "invalid port", hostport: "192.168.1.10:abc", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, err := NewHost(tt.hostport)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %q, got host %s:%d", tt.hostport, host.Address, host.Port)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if host.Address != tt.expectedIP || host.Port != tt.expectedPort {
+				t.Errorf("Expected %s:%d, got %s:%d", tt.expectedIP, tt.expectedPort, host.Address, host.Port)
+			}
+		})
+	}
+}
+
+// serveLiteStats starts a local miner API that answers every connection with the given response
+func serveLiteStats(t *testing.T, response []byte) *net.TCPAddr {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			var cmd AvalonQCommand
+			_ = json.NewDecoder(conn).Decode(&cmd)
+			_, _ = conn.Write(response)
+			conn.Close()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr)
+}
+
+func TestRefreshLiteStats_LastSeen(t *testing.T) {
+	data, err := os.ReadFile("../test_data/avalon_litestat.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data file: %v", err)
+	}
+
+	ctx := context.Background()
+	okAddr := serveLiteStats(t, data)
+	badAddr := serveLiteStats(t, []byte(`{"STATUS":[],"STATS":[]}`))
+
+	host := &AvalonQHost{Address: okAddr.IP.String(), Port: okAddr.Port}
+	if !host.LastSeen.IsZero() {
+		t.Fatal("Expected LastSeen to be zero before the first contact")
+	}
+
+	before := time.Now()
+	host.RefreshLiteStats(ctx)
+	if host.LastStatsError != nil {
+		t.Fatalf("Unexpected refresh error: %v", host.LastStatsError)
+	}
+	if host.LastSeen.Before(before) {
+		t.Fatalf("Expected LastSeen to advance on success, got %v (before %v)", host.LastSeen, before)
+	}
+	lastSeen := host.LastSeen
+
+	// Invalid response does not advance LastSeen
+	host.Port = badAddr.Port
+	host.RefreshLiteStats(ctx)
+	if host.LastStatsError == nil {
+		t.Fatal("Expected error for invalid stats response")
+	}
+	if !host.LastSeen.Equal(lastSeen) {
+		t.Errorf("Expected LastSeen to stay at %v after invalid response, got %v", lastSeen, host.LastSeen)
+	}
+
+	// Unreachable miner does not advance LastSeen
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	host.Port = listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	host.RefreshLiteStats(ctx)
+	if host.LastStatsError == nil {
+		t.Fatal("Expected error for unreachable miner")
+	}
+	if !host.LastSeen.Equal(lastSeen) {
+		t.Errorf("Expected LastSeen to stay at %v after connection error, got %v", lastSeen, host.LastSeen)
+	}
+}
+
+// serveCommand starts a mock miner that records the raw command of each connection and answers with response
+func serveCommand(t *testing.T, response string) (*net.TCPAddr, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	commands := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 256)
+			n, _ := conn.Read(buf)
+			commands <- string(buf[:n])
+			_, _ = conn.Write([]byte(response))
+			conn.Close()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr), commands
+}
+
+func TestReboot(t *testing.T) {
+	addr, commands := serveCommand(t, "STATUS=I,When=1700000000,Code=118,Msg=ASC 0 set OK,Description=cgminer 4.11.1|")
+	host := &AvalonQHost{
+		Address:          addr.IP.String(),
+		Port:             addr.Port,
+		LiteStatsHistory: []*AvalonLiteStats{{FanR: 40}, {FanR: 50}},
+	}
+
+	response, err := host.Reboot(context.Background())
+	if err != nil {
+		t.Fatalf("Reboot failed: %v", err)
+	}
+	if !strings.Contains(response, "ASC 0 set OK") {
+		t.Errorf("Expected the miner response, got %q", response)
+	}
+	if command := <-commands; command != "ascset|0,reboot,0" {
+		t.Errorf("Expected command %q, got %q", "ascset|0,reboot,0", command)
+	}
+	if len(host.LiteStatsHistory) != 1 {
+		t.Errorf("Expected the stats history to be reset to the latest entry, got %d", len(host.LiteStatsHistory))
+	}
+}
+
+func TestParseCommandResult(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    string
+		expected    CommandResult
+		success     bool
+		expectError bool
+	}{
+		{
+			name:     "success",
+			response: "STATUS=S,When=1757139337,Code=118,Msg=ASC 0 set OK,Description=cgminer 4.11.1|",
+			expected: CommandResult{Status: "S", When: 1757139337, Code: 118, Msg: "ASC 0 set OK", Description: "cgminer 4.11.1"},
+			success:  true,
+		},
+		{
+			name:     "info",
+			response: "STATUS=I,When=1757139337,Code=118,Msg=ASC 0 set info: softoff,Description=cgminer 4.11.1|\x00",
+			expected: CommandResult{Status: "I", When: 1757139337, Code: 118, Msg: "ASC 0 set info: softoff", Description: "cgminer 4.11.1"},
+			success:  true,
+		},
+		{
+			name:     "error",
+			response: "STATUS=E,When=1757139337,Code=14,Msg=Invalid command,Description=cgminer 4.11.1|",
+			expected: CommandResult{Status: "E", When: 1757139337, Code: 14, Msg: "Invalid command", Description: "cgminer 4.11.1"},
+			success:  false,
+		},
+		{
+			name:     "error message with commas",
+			response: "STATUS=E,When=1757139337,Code=120,Msg=ASC 0 set failed: workmode, invalid value 7,Description=cgminer 4.11.1|",
+			expected: CommandResult{Status: "E", When: 1757139337, Code: 120, Msg: "ASC 0 set failed: workmode, invalid value 7", Description: "cgminer 4.11.1"},
+			success:  false,
+		},
+		{
+			name:     "fatal",
+			response: "STATUS=F,When=1757139337,Code=0,Msg=Access denied,Description=cgminer 4.11.1|",
+			expected: CommandResult{Status: "F", When: 1757139337, Code: 0, Msg: "Access denied", Description: "cgminer 4.11.1"},
+			success:  false,
+		},
+		{name: "empty", response: "", expectError: true},
+		{name: "not a command response", response: `{"STATUS":[]}`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseCommandResult(tt.response)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ParseCommandResult() expected an error, got %+v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCommandResult() unexpected error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("ParseCommandResult() = %+v, want %+v", result, tt.expected)
+			}
+			if result.Success() != tt.success {
+				t.Errorf("Success() = %v, want %v", result.Success(), tt.success)
+			}
+		})
+	}
+}
+
+func TestSetWorkMode_RejectedCommand(t *testing.T) {
+	addr, commands := serveCommand(t, "STATUS=E,When=1757139337,Code=14,Msg=Invalid command,Description=cgminer 4.11.1|")
+	host := &AvalonQHost{Address: addr.IP.String(), Port: addr.Port}
+
+	_, err := host.SetWorkMode(context.Background(), AvalonSuperMode, false)
+	var commandErr *CommandError
+	if !errors.As(err, &commandErr) {
+		t.Fatalf("Expected a CommandError, got %v", err)
+	}
+	if commandErr.Result.Code != 14 || commandErr.Result.Msg != "Invalid command" {
+		t.Errorf("Expected code 14 and the miner message, got %+v", commandErr.Result)
+	}
+	if command := <-commands; command != "ascset|0,workmode,set,2" {
+		t.Errorf("Expected command %q, got %q", "ascset|0,workmode,set,2", command)
+	}
+
+	// Standby stops after the rejected work mode command
+	if _, err := host.Standby(context.Background()); !errors.As(err, &commandErr) {
+		t.Errorf("Expected a CommandError from Standby, got %v", err)
+	}
+	<-commands
+	select {
+	case command := <-commands:
+		t.Errorf("Expected no softoff after the rejected work mode, got %q", command)
+	default:
+	}
+}
+
+func TestGetPools(t *testing.T) {
+	data, err := os.ReadFile("../test_data/avalon_pools.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data file: %v", err)
+	}
+	addr, commands := serveCommand(t, string(data))
+	host := &AvalonQHost{Address: addr.IP.String(), Port: addr.Port}
+
+	pools, err := host.GetPools(context.Background())
+	if err != nil {
+		t.Fatalf("GetPools failed: %v", err)
+	}
+	if command := <-commands; !strings.Contains(command, `"command":"pools"`) {
+		t.Errorf("Expected the pools command, got %q", command)
+	}
+	if len(pools) != 3 {
+		t.Fatalf("Expected 3 pools, got %d", len(pools))
+	}
+
+	expected := []PoolStatus{
+		{Pool: 0, URL: "stratum+tcp://btc.viabtc.io:3333", Status: "Alive", Priority: 0, User: "devskill.avalon1",
+			Accepted: 18211, Rejected: 37, Stale: 2, GetFailures: 1, LastShareTime: 1757157186, StratumActive: true, RejectedPct: 0.2032},
+		{Pool: 1, URL: "stratum+tcp://btc-eu.f2pool.com:1314", Status: "Dead", Priority: 1, User: "devskill.avalon1", GetFailures: 3},
+		{Pool: 2, Status: "Disabled", Priority: 2},
+	}
+	for i, pool := range pools {
+		if pool != expected[i] {
+			t.Errorf("Pool %d = %+v, want %+v", i, pool, expected[i])
+		}
+	}
+	if !pools[0].Alive() || pools[1].Alive() {
+		t.Errorf("Expected only the first pool to be alive")
+	}
+}
+
+func TestGetPools_ErrorStatus(t *testing.T) {
+	addr, _ := serveCommand(t, `{"STATUS":[{"STATUS":"E","When":1757157201,"Code":14,"Msg":"Invalid command","Description":"cgminer 4.11.1"}],"id":1}`)
+	host := &AvalonQHost{Address: addr.IP.String(), Port: addr.Port}
+
+	if _, err := host.GetPools(context.Background()); err == nil || !strings.Contains(err.Error(), "Invalid command") {
+		t.Errorf("Expected the miner error, got %v", err)
+	}
+}
+
+func TestNetworkHealth(t *testing.T) {
+	now := time.Unix(1757138400, 0)
+	ago := func(d time.Duration) int64 { return now.Add(-d).Unix() }
+
+	tests := []struct {
+		name     string
+		netFail  []int64
+		rssi     int
+		expected NetworkHealth
+	}{
+		{name: "wired without failures", expected: NetworkHealthGood},
+		{name: "old failures only", netFail: []int64{ago(3 * time.Hour), ago(2 * time.Hour)}, expected: NetworkHealthGood},
+		{name: "one recent failure", netFail: []int64{ago(2 * time.Hour), ago(10 * time.Minute)}, expected: NetworkHealthDegraded},
+		{name: "repeated recent failures", netFail: []int64{ago(50 * time.Minute), ago(40 * time.Minute), ago(20 * time.Minute), ago(time.Minute)}, expected: NetworkHealthPoor},
+		{name: "strong signal", rssi: -55, expected: NetworkHealthGood},
+		{name: "weak signal", rssi: -75, expected: NetworkHealthDegraded},
+		{name: "very weak signal", rssi: -85, expected: NetworkHealthPoor},
+		{name: "weak signal with repeated failures", netFail: []int64{ago(5 * time.Minute), ago(4 * time.Minute), ago(3 * time.Minute), ago(2 * time.Minute)}, rssi: -75, expected: NetworkHealthPoor},
+		{name: "sample litestats", netFail: []int64{1757135953, 1757135954, 1757137163, 1757137168, 1757138369, 1757138374, 1757134806, 1757134811}, expected: NetworkHealthPoor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := &AvalonLiteStats{NetFail: tt.netFail, RSSI: tt.rssi}
+			if got := stats.NetworkHealth(now); got != tt.expected {
+				t.Errorf("NetworkHealth() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUptime(t *testing.T) {
+	stats := &AvalonLiteStats{Elapsed: 769980}
+	if expected := 213*time.Hour + 53*time.Minute; stats.Uptime() != expected {
+		t.Errorf("Uptime() = %v, want %v", stats.Uptime(), expected)
+	}
+	if uptime := (&AvalonLiteStats{}).Uptime(); uptime != 0 {
+		t.Errorf("Uptime() = %v, want 0", uptime)
+	}
+}
+
+func TestRecentlyRestarted(t *testing.T) {
+	tests := []struct {
+		name     string
+		elapsed  int64
+		bootBy   string
+		within   time.Duration
+		expected bool
+	}{
+		{name: "just booted", elapsed: 0, bootBy: "0x01.00000000", within: 10 * time.Minute, expected: true},
+		{name: "booted minutes ago", elapsed: 300, bootBy: "0x01.00000000", within: 10 * time.Minute, expected: true},
+		{name: "at the threshold", elapsed: 600, bootBy: "0x01.00000000", within: 10 * time.Minute, expected: false},
+		{name: "running for days", elapsed: 769980, bootBy: "0x01.00000000", within: 10 * time.Minute, expected: false},
+		{name: "no boot information", within: 10 * time.Minute, expected: false},
+		{name: "disabled", elapsed: 60, bootBy: "0x01.00000000", within: 0, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := &AvalonLiteStats{Elapsed: tt.elapsed, BootBy: tt.bootBy}
+			if got := stats.RecentlyRestarted(tt.within); got != tt.expected {
+				t.Errorf("RecentlyRestarted(%v) = %v, want %v", tt.within, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	// Miners listen on two addresses of 127.0.0.0/29 with the same port, the other addresses refuse connections
+	first, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	port := first.Addr().(*net.TCPAddr).Port
+	second, err := net.Listen("tcp", fmt.Sprintf("127.0.0.5:%d", port))
+	if err != nil {
+		t.Skipf("Cannot listen on a second loopback address: %v", err)
+	}
+	for _, listener := range []net.Listener{first, second} {
+		t.Cleanup(func() { listener.Close() })
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				var cmd AvalonQCommand
+				_ = json.NewDecoder(conn).Decode(&cmd)
+				_, _ = conn.Write([]byte(`{"STATUS":[{"STATUS":"S"}],"VERSION":[{"PROD":"AvalonMiner Q"}],"id":1}`))
+				conn.Close()
+			}
+		}()
+	}
+
+	hosts := discover(context.Background(), "127.0.0.0/29", port, nil, version)
+	var addresses []string
+	for _, host := range hosts {
+		if host.Port != port || host.Version == nil || host.LastSeen.IsZero() {
+			t.Errorf("Unexpected discovered host %+v", host)
+		}
+		addresses = append(addresses, host.Address)
+	}
+	slices.Sort(addresses)
+	if expected := []string{"127.0.0.2", "127.0.0.5"}; !slices.Equal(addresses, expected) {
+		t.Errorf("Expected miners %v, got %v", expected, addresses)
+	}
+}
+
+func BenchmarkDiscover_Slash16(b *testing.B) {
+	// Probing fails at once, the benchmark measures the cost of walking the network
+	var peak atomic.Int64
+	probe := func(ctx context.Context, address string, port int) (*AvalonQVersion, error) {
+		if n := int64(runtime.NumGoroutine()); n > peak.Load() {
+			peak.Store(n)
+		}
+		return nil, errors.New("connection refused")
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		discover(context.Background(), "10.1.0.0/16", DefaultPort, nil, probe)
+	}
+	b.ReportMetric(float64(peak.Load()), "peak-goroutines")
+}
@@ -1,5 +1,10 @@
 package miners
 
+import (
+	"fmt"
+	"time"
+)
+
 // AvalonState represents the state of an Avalon miner
 type AvalonState int
 
@@ -44,6 +49,39 @@ func (s AvalonState) IsValid() bool {
 	}
 }
 
+// NetworkHealth summarizes the network connection quality of an Avalon miner
+type NetworkHealth int
+
+// Network health constants
+const (
+	NetworkHealthGood     NetworkHealth = 0 // No recent failures and a usable signal
+	NetworkHealthDegraded NetworkHealth = 1 // Occasional failures or a weak signal
+	NetworkHealthPoor     NetworkHealth = 2 // Repeated failures or a very weak signal
+)
+
+// Network health thresholds
+const (
+	netFailWindow         = time.Hour // Net failures older than this are ignored
+	netFailDegradedCount  = 1         // Recent net failures for Degraded
+	netFailPoorCount      = 4         // Recent net failures for Poor
+	rssiDegradedThreshold = -70       // Wi-Fi signal below this dBm is Degraded
+	rssiPoorThreshold     = -80       // Wi-Fi signal below this dBm is Poor
+)
+
+// String returns the string representation of the NetworkHealth
+func (n NetworkHealth) String() string {
+	switch n {
+	case NetworkHealthGood:
+		return "Good"
+	case NetworkHealthDegraded:
+		return "Degraded"
+	case NetworkHealthPoor:
+		return "Poor"
+	default:
+		return "Unknown"
+	}
+}
+
 // String returns the string representation of the AvalonWorkMode
 func (w AvalonWorkMode) String() string {
 	switch w {
@@ -71,15 +109,18 @@ type AvalonQHost struct {
 	LiteStatsHistory []*AvalonLiteStats
 	LastStatsError   error
 	LastStats        *AvalonLiteStats
+	LastSeen         time.Time // Last time the miner responded successfully, zero if never
 }
 
 // AddLiteStats appends a new AvalonLiteStats to the history and keeps only the last 5 entries.
+// A successful result also updates LastSeen.
 func (h *AvalonQHost) AddLiteStats(stats *AvalonLiteStats, err error) {
 	h.LastStats = stats
 	h.LastStatsError = err
 	if err != nil {
 		return
 	}
+	h.LastSeen = time.Now()
 	h.LiteStatsHistory = append(h.LiteStatsHistory, stats)
 	if len(h.LiteStatsHistory) > 5 {
 		h.LiteStatsHistory = h.LiteStatsHistory[len(h.LiteStatsHistory)-5:]
@@ -109,6 +150,64 @@ type StatusItem struct {
 	Description string `json:"Description"`
 }
 
+// CommandResult represents the parsed response of an ascset command, e.g.
+// "STATUS=S,When=1757139337,Code=118,Msg=ASC 0 set OK,Description=cgminer 4.11.1|"
+type CommandResult struct {
+	Status      string // S = success, I = info, W = warning, E = error, F = fatal
+	When        int64
+	Code        int
+	Msg         string
+	Description string
+}
+
+// Success returns true if the miner accepted the command
+func (r CommandResult) Success() bool {
+	switch r.Status {
+	case "S", "I", "W":
+		return true
+	default:
+		return false
+	}
+}
+
+// CommandError is returned by the control methods when the miner rejects a command
+type CommandError struct {
+	Result CommandResult
+}
+
+// Error implements the error interface
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("miner rejected command: status %s, code %d: %s", e.Result.Status, e.Result.Code, e.Result.Msg)
+}
+
+// AvalonQPools represents the pools response from an Avalon miner.
+type AvalonQPools struct {
+	Status []StatusItem `json:"STATUS"`
+	Pools  []PoolStatus `json:"POOLS"`
+	ID     int          `json:"id"`
+}
+
+// PoolStatus represents the connection status and share counters of a mining pool.
+type PoolStatus struct {
+	Pool          int     `json:"POOL"`
+	URL           string  `json:"URL"`
+	Status        string  `json:"Status"` // Alive, Dead, Rejecting or Disabled
+	Priority      int     `json:"Priority"`
+	User          string  `json:"User"`
+	Accepted      int64   `json:"Accepted"`
+	Rejected      int64   `json:"Rejected"`
+	Stale         int64   `json:"Stale"`
+	GetFailures   int64   `json:"Get Failures"`
+	LastShareTime int64   `json:"Last Share Time"`
+	StratumActive bool    `json:"Stratum Active"`
+	RejectedPct   float64 `json:"Pool Rejected%"`
+}
+
+// Alive returns true if the miner is connected to the pool
+func (p PoolStatus) Alive() bool {
+	return p.Status == "Alive"
+}
+
 // VersionItem represents version details from an Avalon miner.
 type VersionItem struct {
 	CGMiner       string `json:"CGMiner"`
@@ -208,3 +307,45 @@ type AvalonLiteStats struct {
 	ADJ          int            `json:"adj"`
 	NonceMask    int            `json:"nonce_mask"`
 }
+
+// NetworkHealth rates the network connection from the net failures within the hour before now and the
+// Wi-Fi signal strength. An RSSI of 0 means a wired connection or no reading and is not rated.
+func (s *AvalonLiteStats) NetworkHealth(now time.Time) NetworkHealth {
+	recent := 0
+	cutoff := now.Add(-netFailWindow).Unix()
+	for _, ts := range s.NetFail {
+		if ts > cutoff && ts <= now.Unix() {
+			recent++
+		}
+	}
+
+	health := NetworkHealthGood
+	switch {
+	case recent >= netFailPoorCount:
+		health = NetworkHealthPoor
+	case recent >= netFailDegradedCount:
+		health = NetworkHealthDegraded
+	}
+
+	switch {
+	case s.RSSI < rssiPoorThreshold:
+		health = NetworkHealthPoor
+	case s.RSSI < rssiDegradedThreshold:
+		health = max(health, NetworkHealthDegraded)
+	}
+	return health
+}
+
+// Uptime returns the time since the miner booted, from the Elapsed seconds
+func (s *AvalonLiteStats) Uptime() time.Duration {
+	return time.Duration(s.Elapsed) * time.Second
+}
+
+// RecentlyRestarted returns true if the miner booted less than within ago. Stats without
+// a boot reason and uptime carry no boot information and never count as a restart.
+func (s *AvalonLiteStats) RecentlyRestarted(within time.Duration) bool {
+	if s.BootBy == "" && s.Elapsed <= 0 {
+		return false
+	}
+	return s.Uptime() < within
+}
@@ -2,23 +2,38 @@
 package mpc
 
 import (
+	"fmt"
 	"math"
+	"time"
 )
 
 // SystemConfig holds the inverter system configuration
 type SystemConfig struct {
-	BatteryCapacity             float64 // kWh
-	BatteryMaxCharge            float64 // kW
-	BatteryMaxDischarge         float64 // kW
-	BatteryMinSOC               float64 // percentage (0-1)
-	BatteryMaxSOC               float64 // percentage (0-1)
-	BatteryEfficiency           float64 // round-trip efficiency (0-1)
-	BatteryDegradationCost      float64 // $/kWh cycled
-	MaxGridImport               float64 // kW
-	MaxGridExport               float64 // kW
-	BatteryPreHeatPower         float64 // kW - power consumption of battery preheating when active
-	BatteryPreHeatTempThreshold float64 // °C - temperature threshold below which battery preheating activates
-	BatteryThermalTimeConstant  float64 // fraction per time slot - rate at which battery temperature approaches air temperature (0-1)
+	BatteryCapacity             float64        // kWh
+	BatteryMaxCharge            float64        // kW
+	BatteryMaxDischarge         float64        // kW
+	BatteryMinSOC               float64        // percentage (0-1)
+	BatteryMaxSOC               float64        // percentage (0-1)
+	BatteryEfficiency           float64        // round-trip efficiency (0-1)
+	BatteryDegradationCost      float64        // $/kWh cycled
+	MaxGridImport               float64        // kW
+	MaxGridExport               float64        // kW
+	BatteryPreHeatPower         float64        // kW - power consumption of battery preheating when active
+	BatteryPreHeatTempThreshold float64        // °C - temperature threshold below which battery preheating activates
+	BatteryThermalTimeConstant  float64        // fraction per time slot - rate at which battery temperature approaches air temperature (0-1)
+	GridSwitchPenalty           float64        // $ per change between grid import and export, idle slots in between included (0 = disabled)
+	TargetSOC                   float64        // percentage (0-1) - desired SOC at the start of TargetHour
+	TargetHour                  int            // hour of day (0-23, in Location) at which TargetSOC should be reached
+	TargetSOCPenalty            float64        // $ per kWh of deviation from TargetSOC at TargetHour (0 = disabled)
+	MinActionDurationHours      float64        // hours a battery charge or discharge must last once started (0 = disabled)
+	ForbidGridCharge            bool           // charge the battery only from solar surplus, never from the grid
+	HighSOCExportThreshold      float64        // percentage (0-1) - above this SOC charging is penalized so surplus is exported instead (0 = disabled)
+	HighSOCChargePenalty        float64        // $ per kWh stored at BatteryMaxSOC, rising linearly from 0 at HighSOCExportThreshold
+	MaxThroughputKWh            float64        // kWh of battery charge plus discharge allowed over the horizon (0 = unlimited)
+	ThroughputSplit             int64          // Unix timestamp, e.g. the next local midnight, ending the slots limited by MaxThroughputBeforeSplitKWh (0 = disabled)
+	MaxThroughputBeforeSplitKWh float64        // kWh of battery charge plus discharge allowed in the slots starting before ThroughputSplit
+	ImportPriceCeiling          float64        // $/kWh - above this import price the battery never charges from the grid (0 = disabled)
+	Location                    *time.Location // time zone of TargetHour (nil = UTC)
 }
 
 // TimeSlot represents one time period of operation (typically 15 minutes, configurable via check_price_interval)
@@ -44,6 +59,7 @@ type ControlDecision struct {
 	BatteryDischarge      float64 // kW (positive = discharging)
 	GridImport            float64 // kW (positive = importing)
 	GridExport            float64 // kW (positive = exporting)
+	SolarCurtailment      float64 // kW of solar forecast not produced (positive = curtailing PV)
 	BatterySOC            float64 // percentage (0-1)
 	Profit                float64 // $ for this time period
 	BatteryPreHeatActive  bool    // true if battery preheating is active during this time slot
@@ -64,6 +80,7 @@ type Controller struct {
 	Horizon               int     // number of time periods to look ahead
 	CurrentSOC            float64
 	CurrentBatteryTemp    float64 // °C current battery temperature
+	slotHours             float64 // length of the planned time slots in hours, 0 = hourly
 }
 
 // NewController creates a new MPC controller
@@ -83,12 +100,13 @@ func (mpc *Controller) Optimize(forecast []TimeSlot) []ControlDecision {
 	if len(forecast) == 0 {
 		return nil
 	}
+	mpc.slotHours = forecastSlotHours(forecast)
 
-	// Run optimization with full solar forecast
-	decisionsWithSolar := mpc.optimizeWithForecast(forecast, true)
+	// Run optimization with full solar forecast, within the throughput limit
+	decisionsWithSolar, throughputCost := mpc.optimizeWithinThroughput(forecast)
 
 	// Run optimization without solar (grid-only scenario)
-	decisionsWithoutSolar := mpc.optimizeWithForecast(forecast, false)
+	decisionsWithoutSolar := mpc.optimizeWithForecast(forecast, false, throughputCost)
 
 	// Combine results: split BatteryCharge into PV and Grid components
 	finalDecisions := make([]ControlDecision, len(decisionsWithSolar))
@@ -108,8 +126,210 @@ func (mpc *Controller) Optimize(forecast []TimeSlot) []ControlDecision {
 	return finalDecisions
 }
 
-// optimizeWithForecast performs the actual optimization with optional solar forecast
-func (mpc *Controller) optimizeWithForecast(forecast []TimeSlot, includeSolar bool) []ControlDecision {
+// throughputRefinements is the number of times the cost that keeps a plan within the throughput limits is refined
+const throughputRefinements = 5
+
+// optimizeWithinThroughput optimizes with the solar forecast and, when the plan exceeds the throughput limits, adds a
+// cost per kWh of battery throughput that keeps it within them. The cost only steers the optimizer, so the least
+// profitable cycles are dropped first. It applies to every slot, so keeping the slots before ThroughputSplit within
+// their limit may also hold back the slots after it. The cost is bisected a few times between no cost and the
+// price spread of the horizon, above which no cycle pays, so the optimization is rerun a bounded number of times.
+// It returns the plan and the cost used.
+func (mpc *Controller) optimizeWithinThroughput(forecast []TimeSlot) ([]ControlDecision, float64) {
+	decisions := mpc.optimizeWithForecast(forecast, true, 0)
+	slotHours := forecastSlotHours(forecast)
+	if mpc.throughputExcess(decisions, slotHours) <= 0 {
+		return decisions, 0
+	}
+
+	// A kWh stored from the grid or from solar that could be curtailed for free never earns more than the spread
+	// between the highest price and the lowest price or 0
+	highest, lowest := math.Inf(-1), 0.0
+	for _, slot := range forecast {
+		highest = max(highest, slot.ImportPrice, slot.ExportPrice)
+		lowest = min(lowest, slot.ImportPrice, slot.ExportPrice)
+	}
+
+	var best []ControlDecision
+	low, high := 0.0, highest-lowest
+	for range throughputRefinements {
+		cost := (low + high) / 2
+		plan := mpc.optimizeWithForecast(forecast, true, cost)
+		if mpc.throughputExcess(plan, slotHours) <= 0 {
+			best, high = plan, cost
+		} else {
+			low = cost
+		}
+	}
+	if best != nil {
+		return best, high
+	}
+
+	// Targets and penalties may still force some throughput, a cost above the sum of the highest prices normally
+	// leaves the battery idle
+	high = 2*highest + 1
+	return mpc.optimizeWithForecast(forecast, true, high), high
+}
+
+// throughputExcess returns by how many kWh a plan exceeds MaxThroughputKWh or, when ThroughputSplit is set,
+// MaxThroughputBeforeSplitKWh over the slots starting before the split, whichever is exceeded more. It is 0 or
+// negative when the plan keeps within both.
+func (mpc *Controller) throughputExcess(decisions []ControlDecision, slotHours float64) float64 {
+	excess := math.Inf(-1)
+	if limit := mpc.Config.MaxThroughputKWh; limit > 0 {
+		excess = Throughput(decisions, slotHours) - limit
+	}
+	if split := mpc.Config.ThroughputSplit; split > 0 {
+		before := 0
+		for before < len(decisions) && decisions[before].Timestamp < split {
+			before++
+		}
+		excess = max(excess, Throughput(decisions[:before], slotHours)-mpc.Config.MaxThroughputBeforeSplitKWh)
+	}
+	return excess
+}
+
+// Throughput returns the battery charge plus discharge energy of a plan in kWh for time slots of slotHours
+func Throughput(decisions []ControlDecision, slotHours float64) float64 {
+	total := 0.0
+	for _, dec := range decisions {
+		total += (dec.BatteryCharge + dec.BatteryDischarge) * slotHours
+	}
+	return total
+}
+
+// PlanSummary summarizes an optimized plan, totals are summed over all time slots of the plan
+type PlanSummary struct {
+	Delta            float64 // relative price shift the plan was optimized for (0.1 = +10%)
+	Profit           float64 // $ total profit of the plan
+	GridImport       float64 // kW summed over time slots
+	GridExport       float64 // kW summed over time slots
+	BatteryCharge    float64 // kW summed over time slots
+	BatteryDischarge float64 // kW summed over time slots
+	FinalSOC         float64 // percentage (0-1) at the end of the plan
+}
+
+// Summarize returns the summary of a plan optimized for the given price delta
+func Summarize(decisions []ControlDecision, delta float64) PlanSummary {
+	summary := PlanSummary{Delta: delta}
+	for _, dec := range decisions {
+		summary.Profit += dec.Profit
+		summary.GridImport += dec.GridImport
+		summary.GridExport += dec.GridExport
+		summary.BatteryCharge += dec.BatteryCharge
+		summary.BatteryDischarge += dec.BatteryDischarge
+	}
+	if len(decisions) > 0 {
+		summary.FinalSOC = decisions[len(decisions)-1].BatterySOC
+	}
+	return summary
+}
+
+// SensitivityAnalysis re-runs Optimize with import and export prices scaled by (1 + delta) for each delta
+// and returns one plan summary per delta in the same order. The forecast is not modified.
+func (mpc *Controller) SensitivityAnalysis(forecast []TimeSlot, deltas []float64) []PlanSummary {
+	summaries := make([]PlanSummary, 0, len(deltas))
+	scaled := make([]TimeSlot, len(forecast))
+	for _, delta := range deltas {
+		for i, slot := range forecast {
+			slot.ImportPrice *= 1 + delta
+			slot.ExportPrice *= 1 + delta
+			scaled[i] = slot
+		}
+		summaries = append(summaries, Summarize(mpc.Optimize(scaled), delta))
+	}
+	return summaries
+}
+
+// Violation describes a planned decision that breaks the power balance or the SOC bounds
+type Violation struct {
+	Index     int    // position of the decision in the plan
+	Timestamp int64  // Unix timestamp of the decision's time slot
+	Reason    string // human readable description of the violated constraint
+}
+
+// String returns a log friendly description of the violation
+func (v Violation) String() string {
+	return fmt.Sprintf("slot %d (%s): %s", v.Index, time.Unix(v.Timestamp, 0).Format(time.RFC3339), v.Reason)
+}
+
+// CheckDecisions verifies that every decision of a plan keeps the power balance
+// Solar + GridImport + BatteryDischarge*eff = Load + GridExport + SolarCurtailment + BatteryCharge/eff + BatteryPreHeat
+// within tolerance kW, unless a grid flow is at its limit, and that its SOC stays within the configured bounds
+// (tolerance applied as a fraction).
+// The optimizer only produces balanced decisions, so any violation indicates a solver bug.
+func (mpc *Controller) CheckDecisions(decisions []ControlDecision, tolerance float64) []Violation {
+	var violations []Violation
+	for i, dec := range decisions {
+		preHeat := 0.0
+		if dec.BatteryPreHeatActive {
+			preHeat = mpc.Config.BatteryPreHeatPower
+		}
+		supply := dec.SolarForecast + dec.GridImport + dec.BatteryDischarge*mpc.Config.BatteryEfficiency
+		demand := dec.LoadForecast + dec.GridExport + dec.SolarCurtailment + dec.BatteryCharge/mpc.Config.BatteryEfficiency + preHeat
+		diff := supply - demand
+		// The optimizer clamps grid flows to their limits, surplus that PV curtailment cannot absorb is lost and unmet load shed
+		if diff > 0 && dec.GridExport >= mpc.Config.MaxGridExport-tolerance {
+			diff = 0
+		}
+		if diff < 0 && dec.GridImport >= mpc.Config.MaxGridImport-tolerance {
+			diff = 0
+		}
+		if math.Abs(diff) > tolerance {
+			violations = append(violations, Violation{
+				Index:     i,
+				Timestamp: dec.Timestamp,
+				Reason:    fmt.Sprintf("power balance off by %.3f kW (supply %.3f kW, demand %.3f kW)", diff, supply, demand),
+			})
+		}
+
+		if dec.BatterySOC < mpc.Config.BatteryMinSOC-tolerance || dec.BatterySOC > mpc.Config.BatteryMaxSOC+tolerance {
+			violations = append(violations, Violation{
+				Index:     i,
+				Timestamp: dec.Timestamp,
+				Reason: fmt.Sprintf("SOC %.1f%% outside %.1f%%-%.1f%%",
+					dec.BatterySOC*100, mpc.Config.BatteryMinSOC*100, mpc.Config.BatteryMaxSOC*100),
+			})
+		}
+	}
+	return violations
+}
+
+// StateSnapshot is the simulated battery state at the end of a time slot of a plan
+type StateSnapshot struct {
+	Timestamp   int64   // Unix timestamp when the time slot begins
+	SOC         float64 // percentage (0-1) at the end of the time slot
+	EnergyKWh   float64 // kWh stored at the end of the time slot
+	BatteryTemp float64 // °C average cell temperature at the end of the time slot
+}
+
+// SimulateTrajectory runs a plan forward from initialSOC and CurrentBatteryTemp with the battery model of the
+// optimizer: the charge efficiency, the SOC limits and the thermal model with preheating. It returns one
+// snapshot per decision. The slot length is taken from the decision timestamps. The optimizer plans on a discrete
+// SOC grid, so its reported BatterySOC may differ from the trajectory by a fraction of a percent.
+func (mpc *Controller) SimulateTrajectory(initialSOC float64, decisions []ControlDecision) []StateSnapshot {
+	if len(decisions) > 1 && decisions[1].Timestamp > decisions[0].Timestamp {
+		mpc.slotHours = float64(decisions[1].Timestamp-decisions[0].Timestamp) / 3600
+	}
+	trajectory := make([]StateSnapshot, len(decisions))
+	soc := initialSOC
+	temp := mpc.CurrentBatteryTemp
+	for i, dec := range decisions {
+		soc = mpc.calculateNewSOC(soc, dec.BatteryCharge, dec.BatteryDischarge)
+		temp = mpc.calculateNextBatteryTemp(temp, dec.AirTemperature, dec.BatteryCharge > 0, dec.BatteryPreHeatActive)
+		trajectory[i] = StateSnapshot{
+			Timestamp:   dec.Timestamp,
+			SOC:         soc,
+			EnergyKWh:   soc * mpc.Config.BatteryCapacity,
+			BatteryTemp: temp,
+		}
+	}
+	return trajectory
+}
+
+// optimizeWithForecast performs the actual optimization with optional solar forecast.
+// throughputCost is charged per kWh of battery charge and discharge to keep the plan within MaxThroughputKWh.
+func (mpc *Controller) optimizeWithForecast(forecast []TimeSlot, includeSolar bool, throughputCost float64) []ControlDecision {
 	// Use dynamic programming for optimization
 	// State: SOC level, Time: hour
 	// We'll discretize SOC into steps for tractability
@@ -117,75 +337,139 @@ func (mpc *Controller) optimizeWithForecast(forecast []TimeSlot, includeSolar bo
 	socSteps := 500
 	socStep := (mpc.Config.BatteryMaxSOC - mpc.Config.BatteryMinSOC) / float64(socSteps)
 
-	// DP table: [time][soc_index] -> (best_profit, best_decision, battery_temp)
+	// The last non-idle grid flow direction is tracked as an extra DP dimension only when switching is penalized,
+	// otherwise a single direction state keeps the table as small as before
+	dirStates := 1
+	if mpc.Config.GridSwitchPenalty > 0 {
+		dirStates = 3
+	}
+
+	// Likewise the length of the current battery action run is only tracked when a minimum duration is set
+	minRun := mpc.minActionSlots(forecast)
+	actStates := 1
+	if minRun > 1 {
+		actStates = 1 + 2*minRun
+	}
+	modeStates := dirStates * actStates
+
+	// DP table: [time][soc_index][grid_direction*actStates+action_run] -> (best_profit, battery_temp, back-pointer).
+	// A state keeps only the back-pointer and the index of the decision taken into it, the decisions of the optimal
+	// path are rebuilt on the traceback, so the extra direction and action run dimensions stay cheap in memory.
 	type dpState struct {
 		profit      float64
-		decision    ControlDecision
-		prevSOC     int
 		batteryTemp float64 // °C battery temperature at this state
+		prevSOC     int32
+		prevMode    int16
+		action      int16 // index of the decision among the feasible decisions of the previous state
 	}
 
-	dp := make([][]dpState, len(forecast)+1)
+	dp := make([][][]dpState, len(forecast)+1)
 	for i := range dp {
-		dp[i] = make([]dpState, socSteps+1)
+		dp[i] = make([][]dpState, socSteps+1)
+		cells := make([]dpState, (socSteps+1)*modeStates)
+		for k := range cells {
+			cells[k].profit = math.Inf(-1)
+		}
 		for j := range dp[i] {
-			dp[i][j].profit = math.Inf(-1)
+			dp[i][j] = cells[j*modeStates : (j+1)*modeStates]
 		}
 	}
 
+	// Time indices at which the SOC is softly pulled toward the target
+	targetSlots := mpc.targetSOCSlots(forecast)
+	throughputCost *= forecastSlotHours(forecast)
+
 	// Initialize with current SOC and battery temperature
+	// The grid direction and battery action before the horizon are unknown, so the first slot is never constrained
 	startSOCIndex := mpc.socToIndex(mpc.CurrentSOC, socStep)
-	dp[0][startSOCIndex].profit = 0
-	dp[0][startSOCIndex].batteryTemp = mpc.CurrentBatteryTemp
+	startMode := gridIdle*actStates + actionIdle
+	dp[0][startSOCIndex][startMode].profit = 0
+	dp[0][startSOCIndex][startMode].batteryTemp = mpc.CurrentBatteryTemp
 
-	// Forward pass - build DP table
-	for t := range forecast {
+	slotAt := func(t int) TimeSlot {
 		slot := forecast[t]
 		if !includeSolar {
 			slot.SolarForecast = 0
 		}
+		return slot
+	}
 
-		for socIdx := 0; socIdx <= socSteps; socIdx++ {
-			if math.IsInf(dp[t][socIdx].profit, -1) {
-				continue
-			}
-
-			currentSOC := mpc.indexToSOC(socIdx, socStep)
-			currentBatteryTemp := dp[t][socIdx].batteryTemp
+	// transition is the outcome of a decision from a SOC and battery temperature, the same for every mode
+	type transition struct {
+		newSOCIdx int // -1 when the decision leaves the SOC grid
+		newTemp   float64
+		value     float64 // profit less the penalties that do not depend on the mode
+		flow      int
+		charge    float64
+		discharge float64
+	}
+	var transitions []transition
 
-			// Try different control decisions
-			decisions := mpc.generateFeasibleDecisions(currentSOC, currentBatteryTemp, slot)
+	// Forward pass - build DP table
+	for t := range forecast {
+		slot := slotAt(t)
 
-			for _, dec := range decisions {
-				newSOC := mpc.calculateNewSOC(currentSOC, dec.BatteryCharge, dec.BatteryDischarge)
-				newSOCIdx := mpc.socToIndex(newSOC, socStep)
+		for socIdx := 0; socIdx <= socSteps; socIdx++ {
+			currentSOC := mpc.indexToSOC(socIdx, socStep)
+			// The transitions only depend on the battery temperature, which is mostly the same for all modes
+			transitions = transitions[:0]
+			transitionsTemp := math.NaN()
 
-				if newSOCIdx < 0 || newSOCIdx > socSteps {
+			for mode := 0; mode < modeStates; mode++ {
+				current := dp[t][socIdx][mode]
+				if math.IsInf(current.profit, -1) {
 					continue
 				}
+				dir, act := mode/actStates, mode%actStates
+
+				if current.batteryTemp != transitionsTemp {
+					transitions = transitions[:0]
+					transitionsTemp = current.batteryTemp
+					for _, dec := range mpc.generateFeasibleDecisions(currentSOC, current.batteryTemp, slot) {
+						newSOC := mpc.calculateNewSOC(currentSOC, dec.BatteryCharge, dec.BatteryDischarge)
+						tr := transition{newSOCIdx: mpc.socToIndex(newSOC, socStep), flow: gridIdle, charge: dec.BatteryCharge, discharge: dec.BatteryDischarge}
+						if tr.newSOCIdx < 0 || tr.newSOCIdx > socSteps {
+							tr.newSOCIdx = -1
+						}
+						if dirStates > 1 {
+							tr.flow = gridDirection(dec)
+						}
+						// Calculate next battery temperature based on this decision
+						tr.newTemp = mpc.calculateNextBatteryTemp(current.batteryTemp, slot.AirTemperature, dec.BatteryCharge > 0, dec.BatteryPreHeatActive)
+						// The switching, high SOC and throughput penalties only steer the optimizer, they are not part of the reported profit
+						tr.value = mpc.calculateProfit(dec, slot) - mpc.highSOCChargeCost(currentSOC, newSOC) -
+							throughputCost*(dec.BatteryCharge+dec.BatteryDischarge)
+						if targetSlots[t+1] {
+							tr.value -= mpc.targetSOCCost(newSOC)
+						}
+						transitions = append(transitions, tr)
+					}
+				}
 
-				// Calculate next battery temperature based on this decision
-				newBatteryTemp := mpc.calculateNextBatteryTemp(currentBatteryTemp, slot.AirTemperature, dec.BatteryCharge > 0, dec.BatteryPreHeatActive)
-
-				profit := mpc.calculateProfit(dec, slot)
-				totalProfit := dp[t][socIdx].profit + profit
-
-				if totalProfit > dp[t+1][newSOCIdx].profit {
-					dp[t+1][newSOCIdx].profit = totalProfit
-					dp[t+1][newSOCIdx].decision = dec
-					dp[t+1][newSOCIdx].decision.BatterySOC = newSOC
-					dp[t+1][newSOCIdx].decision.Profit = profit
-					dp[t+1][newSOCIdx].decision.Timestamp = slot.Timestamp
-					dp[t+1][newSOCIdx].decision.ImportPrice = slot.ImportPrice
-					dp[t+1][newSOCIdx].decision.ExportPrice = slot.ExportPrice
-					dp[t+1][newSOCIdx].decision.SolarForecast = slot.SolarForecast
-					dp[t+1][newSOCIdx].decision.LoadForecast = slot.LoadForecast
-					dp[t+1][newSOCIdx].decision.CloudCoverage = slot.CloudCoverage
-					dp[t+1][newSOCIdx].decision.WeatherSymbol = slot.WeatherSymbol
-					dp[t+1][newSOCIdx].decision.AirTemperature = slot.AirTemperature
-					dp[t+1][newSOCIdx].decision.BatteryAvgCellTemp = currentBatteryTemp
-					dp[t+1][newSOCIdx].prevSOC = socIdx
-					dp[t+1][newSOCIdx].batteryTemp = newBatteryTemp
+				for i := range transitions {
+					tr := &transitions[i]
+					if tr.newSOCIdx < 0 {
+						continue
+					}
+					newDir := dir
+					if tr.flow != gridIdle {
+						newDir = tr.flow
+					}
+					newAct, ok := nextActionRun(act, minRun, tr.charge, tr.discharge)
+					if !ok {
+						continue
+					}
+					newMode := newDir*actStates + newAct
+
+					totalProfit := current.profit + tr.value - mpc.gridSwitchCost(dir, tr.flow)
+					next := &dp[t+1][tr.newSOCIdx][newMode]
+					if totalProfit > next.profit {
+						next.profit = totalProfit
+						next.batteryTemp = tr.newTemp
+						next.prevSOC = int32(socIdx)
+						next.prevMode = int16(mode)
+						next.action = int16(i)
+					}
 				}
 			}
 		}
@@ -193,26 +477,195 @@ func (mpc *Controller) optimizeWithForecast(forecast []TimeSlot, includeSolar bo
 
 	// Backward pass - reconstruct optimal path
 	// Prefer paths that end with lower SOC (use more battery for arbitrage)
+	// A run that is still shorter than the minimum at the end of the horizon is allowed, it continues in the next plan
 	bestFinalSOC := 0
+	bestFinalMode := startMode
 	bestFinalProfit := math.Inf(-1)
 	for socIdx := 0; socIdx <= socSteps; socIdx++ {
-		if dp[len(forecast)][socIdx].profit > bestFinalProfit {
-			bestFinalProfit = dp[len(forecast)][socIdx].profit
-			bestFinalSOC = socIdx
+		for mode := 0; mode < modeStates; mode++ {
+			if dp[len(forecast)][socIdx][mode].profit > bestFinalProfit {
+				bestFinalProfit = dp[len(forecast)][socIdx][mode].profit
+				bestFinalSOC = socIdx
+				bestFinalMode = mode
+			}
 		}
 	}
 
-	// Trace back the path
 	path := make([]ControlDecision, len(forecast))
-	currentIdx := bestFinalSOC
-	for t := len(forecast) - 1; t >= 0; t-- {
-		path[t] = dp[t+1][currentIdx].decision
-		currentIdx = dp[t+1][currentIdx].prevSOC
+	if math.IsInf(bestFinalProfit, -1) {
+		return path
+	}
+
+	// Trace back the states of the path
+	socPath := make([]int, len(forecast)+1)
+	modePath := make([]int, len(forecast)+1)
+	socPath[len(forecast)], modePath[len(forecast)] = bestFinalSOC, bestFinalMode
+	for t := len(forecast); t > 0; t-- {
+		state := dp[t][socPath[t]][modePath[t]]
+		socPath[t-1], modePath[t-1] = int(state.prevSOC), int(state.prevMode)
+	}
+
+	// Rebuild the decisions taken between the states
+	for t := range forecast {
+		slot := slotAt(t)
+		from := dp[t][socPath[t]][modePath[t]]
+		currentSOC := mpc.indexToSOC(socPath[t], socStep)
+		dec := mpc.generateFeasibleDecisions(currentSOC, from.batteryTemp, slot)[dp[t+1][socPath[t+1]][modePath[t+1]].action]
+		dec.BatterySOC = mpc.calculateNewSOC(currentSOC, dec.BatteryCharge, dec.BatteryDischarge)
+		dec.Profit = mpc.calculateProfit(dec, slot)
+		dec.Timestamp = slot.Timestamp
+		dec.ImportPrice = slot.ImportPrice
+		dec.ExportPrice = slot.ExportPrice
+		dec.SolarForecast = slot.SolarForecast
+		dec.LoadForecast = slot.LoadForecast
+		dec.CloudCoverage = slot.CloudCoverage
+		dec.WeatherSymbol = slot.WeatherSymbol
+		dec.AirTemperature = slot.AirTemperature
+		dec.BatteryAvgCellTemp = from.batteryTemp
+		path[t] = dec
 	}
 
 	return path
 }
 
+// Grid flow directions used to penalize switching between import and export
+const (
+	gridIdle = iota
+	gridImporting
+	gridExporting
+)
+
+// gridDirection returns the direction of the net grid flow for a decision
+func gridDirection(dec ControlDecision) int {
+	if dec.GridImport > 0.01 {
+		return gridImporting
+	}
+	if dec.GridExport > 0.01 {
+		return gridExporting
+	}
+	return gridIdle
+}
+
+// gridSwitchCost returns the penalty for moving between two grid flow directions
+// prevDir is the last non-idle direction, so a flip between importing and exporting is penalized
+// even with idle slots in between
+func (mpc *Controller) gridSwitchCost(prevDir, newDir int) float64 {
+	if (prevDir == gridImporting && newDir == gridExporting) || (prevDir == gridExporting && newDir == gridImporting) {
+		return mpc.Config.GridSwitchPenalty
+	}
+	return 0
+}
+
+// Battery action run states: idle, then charging runs of 1..minRun slots, then discharging runs of 1..minRun slots.
+// A run of minRun slots has lasted long enough and may end or continue freely.
+const actionIdle = 0
+
+// forecastSlotHours returns the length of the forecast time slots in hours, taken from the forecast timestamps.
+// Hourly slots are assumed for a single slot.
+func forecastSlotHours(forecast []TimeSlot) float64 {
+	if len(forecast) > 1 && forecast[1].Timestamp > forecast[0].Timestamp {
+		return float64(forecast[1].Timestamp-forecast[0].Timestamp) / 3600
+	}
+	return 1.0
+}
+
+// aboveImportCeiling returns true if the import price of the slot is above the import price ceiling
+func (mpc *Controller) aboveImportCeiling(slot TimeSlot) bool {
+	return mpc.Config.ImportPriceCeiling > 0 && slot.ImportPrice > mpc.Config.ImportPriceCeiling
+}
+
+// minActionSlots returns the number of consecutive time slots a battery charge or discharge must last
+func (mpc *Controller) minActionSlots(forecast []TimeSlot) int {
+	if mpc.Config.MinActionDurationHours <= 0 {
+		return 1
+	}
+	return max(1, int(math.Ceil(mpc.Config.MinActionDurationHours/forecastSlotHours(forecast)-1e-9)))
+}
+
+// nextActionRun returns the action run state after a decision charging or discharging the battery is taken in
+// run state act. It reports false when the decision would end or reverse a charge or discharge run shorter than
+// minRun slots.
+func nextActionRun(act, minRun int, charge, discharge float64) (int, bool) {
+	if minRun <= 1 {
+		return actionIdle, true
+	}
+
+	// Current run: 1 = charging, 2 = discharging, with its length in slots
+	kind, length := 0, 0
+	if act > actionIdle && act <= minRun {
+		kind, length = 1, act
+	} else if act > minRun {
+		kind, length = 2, act-minRun
+	}
+
+	newKind := 0
+	if charge > 0 {
+		newKind = 1
+	} else if discharge > 0 {
+		newKind = 2
+	}
+
+	if newKind != kind && length > 0 && length < minRun {
+		return 0, false
+	}
+	if newKind == 0 {
+		return actionIdle, true
+	}
+	newLength := 1
+	if newKind == kind {
+		newLength = min(length+1, minRun)
+	}
+	return (newKind-1)*minRun + newLength, true
+}
+
+// targetSOCSlots returns, for each DP time index, whether the SOC at that index is compared to TargetSOC.
+// Index t is the SOC at the start of forecast slot t, so the first slot of every TargetHour in the horizon is marked.
+// Hours are taken in the configured Location.
+// The current SOC (index 0) cannot be changed and is never marked.
+func (mpc *Controller) targetSOCSlots(forecast []TimeSlot) []bool {
+	slots := make([]bool, len(forecast)+1)
+	if mpc.Config.TargetSOCPenalty <= 0 {
+		return slots
+	}
+
+	location := mpc.Config.Location
+	if location == nil {
+		location = time.UTC
+	}
+	prevHour := -1
+	for t, slot := range forecast {
+		hour := time.Unix(slot.Timestamp, 0).In(location).Hour()
+		if t > 0 && hour == mpc.Config.TargetHour && prevHour != hour {
+			slots[t] = true
+		}
+		prevHour = hour
+	}
+	return slots
+}
+
+// targetSOCCost returns the soft penalty for ending up at soc when TargetSOC should be reached
+func (mpc *Controller) targetSOCCost(soc float64) float64 {
+	return mpc.Config.TargetSOCPenalty * math.Abs(soc-mpc.Config.TargetSOC) * mpc.Config.BatteryCapacity
+}
+
+// highSOCChargeCost returns the soft penalty for charging from fromSOC to toSOC above HighSOCExportThreshold.
+// The penalty per kWh rises linearly from 0 at the threshold to HighSOCChargePenalty at BatteryMaxSOC, so charging
+// tapers off as the SOC rises and surplus is exported instead, leaving headroom for later solar.
+func (mpc *Controller) highSOCChargeCost(fromSOC, toSOC float64) float64 {
+	threshold := mpc.Config.HighSOCExportThreshold
+	if threshold <= 0 || mpc.Config.HighSOCChargePenalty <= 0 || threshold >= mpc.Config.BatteryMaxSOC {
+		return 0
+	}
+	from := math.Max(fromSOC, threshold) - threshold
+	to := toSOC - threshold
+	if to <= from {
+		return 0
+	}
+	// Integral of the linearly rising penalty over the SOC charged above the threshold
+	slope := mpc.Config.HighSOCChargePenalty / (mpc.Config.BatteryMaxSOC - threshold)
+	return slope * (to*to - from*from) / 2 * mpc.Config.BatteryCapacity
+}
+
 // calculateNextBatteryTemp calculates the battery temperature for the next time slot
 // based on current temperature, air temperature, and whether the battery is charging
 func (mpc *Controller) calculateNextBatteryTemp(currentTemp, airTemp float64, isCharging, isPreHeating bool) float64 {
@@ -281,6 +734,18 @@ func (mpc *Controller) generateFeasibleDecisions(currentSOC float64, currentBatt
 	for _, action := range batteryActions {
 		// Battery preheating is only active when we're actually charging and temp is below threshold
 		preHeatActive := needsPreHeat && action.charge > 0
+
+		// In green-only mode and above the import price ceiling the power drawn for charging, including preheating,
+		// must be covered by the solar surplus
+		if (mpc.Config.ForbidGridCharge || mpc.aboveImportCeiling(slot)) && action.charge > 0 {
+			chargeDraw := action.charge / mpc.Config.BatteryEfficiency
+			if preHeatActive {
+				chargeDraw += preHeatPower
+			}
+			if chargeDraw > slot.SolarForecast-slot.LoadForecast+1e-9 {
+				continue
+			}
+		}
 		
 		dec := ControlDecision{
 			Hour:                 slot.Hour,
@@ -290,7 +755,7 @@ func (mpc *Controller) generateFeasibleDecisions(currentSOC float64, currentBatt
 			BatteryPreHeatActive: preHeatActive,
 		}
 
-		// Power balance: Solar + GridImport + BatteryDischarge = Load + GridExport + BatteryCharge + BatteryPreHeat
+		// Power balance: Solar + GridImport + BatteryDischarge = Load + GridExport + SolarCurtailment + BatteryCharge + BatteryPreHeat
 		// When battery preheating is active (battery is charging at low temp), it consumes extra power from the grid
 		netSolar := slot.SolarForecast
 		extraLoad := 0.0
@@ -306,8 +771,14 @@ func (mpc *Controller) generateFeasibleDecisions(currentSOC float64, currentBatt
 		balance := netSupply - netLoad
 
 		if balance > 0 {
-			// Excess power - can export
-			dec.GridExport = math.Min(balance, mpc.Config.MaxGridExport)
+			// Excess power - can export, PV is curtailed at no cost when exporting would cost money
+			// or the surplus exceeds the export limit
+			if slot.ExportPrice < 0 {
+				dec.SolarCurtailment = math.Min(balance, netSolar)
+			} else if balance > mpc.Config.MaxGridExport {
+				dec.SolarCurtailment = math.Min(balance-mpc.Config.MaxGridExport, netSolar)
+			}
+			dec.GridExport = math.Min(balance-dec.SolarCurtailment, mpc.Config.MaxGridExport)
 			dec.GridImport = 0
 		} else {
 			// Deficit - need to import
@@ -327,7 +798,8 @@ func (mpc *Controller) generateFeasibleDecisions(currentSOC float64, currentBatt
 // calculateProfit computes the profit for a decision
 // The power balance equation ensures: Solar + GridImport + BatteryDischarge*eff = Load + GridExport + BatteryCharge/eff + BatteryPreHeat
 // Therefore, GridImport and GridExport already reflect the effect of battery operations and battery preheating.
-// Profit is simply: revenue from exports - cost of imports - degradation cost
+// Profit is simply: revenue from exports - cost of imports - degradation cost, over the energy of the time slot
+// Curtailed solar is not part of the grid flows, so curtailing PV has no cost
 // Note: The battery preheating cost is already included in GridImport when battery is charging at low temperatures
 func (mpc *Controller) calculateProfit(dec ControlDecision, slot TimeSlot) float64 {
 	// Revenue from exporting to grid
@@ -340,7 +812,7 @@ func (mpc *Controller) calculateProfit(dec ControlDecision, slot TimeSlot) float
 	batteryThroughput := dec.BatteryCharge + dec.BatteryDischarge
 	degradationCost := batteryThroughput * mpc.Config.BatteryDegradationCost
 
-	// Net profit:
+	// Net profit over the energy of the time slot:
 	// + Revenue from exports (GridExport already accounts for battery discharge to grid)
 	// - Cost of imports (GridImport already accounts for battery charging, battery preheating, and reduced imports from discharge)
 	// - Battery degradation (wear and tear cost)
@@ -351,25 +823,68 @@ func (mpc *Controller) calculateProfit(dec ControlDecision, slot TimeSlot) float
 	// - When battery temp is low (<10°C), charging incurs additional battery preheating cost (700W)
 	// - The DP optimizer will naturally prefer charge-low/discharge-high strategies
 	// - The optimizer will avoid charging at low temperatures unless prices are very favorable
-	profit := revenue - importCost - degradationCost
+	profit := mpc.slotEnergy(revenue - importCost - degradationCost)
 
 	return profit
 }
 
+// BreakEvenSpread returns the minimum spread ($/kWh) between the export price and the effective charge cost
+// for battery arbitrage to profit. In the optimizer's model charging c kW draws c/eff from the grid and stores
+// c*eff, discharging d kW delivers d*eff, and both are charged the degradation cost per kWh. A kWh exported
+// from the battery thus has an effective charge cost of importPrice/eff³ and wears the battery by
+// (1+eff)/eff² times the degradation cost, which is the spread returned.
+// Without a positive efficiency arbitrage never profits and +Inf is returned.
+func BreakEvenSpread(cfg SystemConfig) float64 {
+	eff := cfg.BatteryEfficiency
+	if eff <= 0 {
+		return math.Inf(1)
+	}
+	return (1 + eff) / (eff * eff) * cfg.BatteryDegradationCost
+}
+
+// MarginalLoadCost returns for each decision the cost in $/kWh of serving one more kW of load during its time slot
+// with the planned battery setpoint kept. Curtailed solar covers extra load for free, while exporting the extra
+// load only reduces the export, and solar charging the battery without grid flow could have been exported.
+// In all other slots the extra load is imported.
+func MarginalLoadCost(decisions []ControlDecision) []float64 {
+	costs := make([]float64, len(decisions))
+	for i, dec := range decisions {
+		switch {
+		case dec.SolarCurtailment > 0:
+			costs[i] = 0
+		case dec.GridExport > 0:
+			costs[i] = dec.ExportPrice
+		case dec.GridImport <= 0 && dec.BatteryChargeFromPV > 0:
+			costs[i] = dec.ExportPrice
+		default:
+			costs[i] = dec.ImportPrice
+		}
+	}
+	return costs
+}
+
+// slotEnergy returns the energy in kWh of a power in kW held for one time slot
+func (mpc *Controller) slotEnergy(power float64) float64 {
+	if mpc.slotHours <= 0 {
+		return power
+	}
+	return power * mpc.slotHours
+}
+
 // Helper functions
 func (mpc *Controller) canCharge(soc, charge float64) bool {
-	newSOC := soc + (charge / mpc.Config.BatteryCapacity)
+	newSOC := soc + (mpc.slotEnergy(charge) / mpc.Config.BatteryCapacity)
 	return newSOC <= mpc.Config.BatteryMaxSOC
 }
 
 func (mpc *Controller) canDischarge(soc, discharge float64) bool {
-	newSOC := soc - (discharge / mpc.Config.BatteryCapacity)
+	newSOC := soc - (mpc.slotEnergy(discharge) / mpc.Config.BatteryCapacity)
 	return newSOC >= mpc.Config.BatteryMinSOC
 }
 
 func (mpc *Controller) calculateNewSOC(currentSOC, charge, discharge float64) float64 {
-	chargeEnergy := charge * mpc.Config.BatteryEfficiency
-	socChange := (chargeEnergy - discharge) / mpc.Config.BatteryCapacity
+	chargeEnergy := mpc.slotEnergy(charge) * mpc.Config.BatteryEfficiency
+	socChange := (chargeEnergy - mpc.slotEnergy(discharge)) / mpc.Config.BatteryCapacity
 	newSOC := currentSOC + socChange
 	return math.Max(mpc.Config.BatteryMinSOC, math.Min(mpc.Config.BatteryMaxSOC, newSOC))
 }
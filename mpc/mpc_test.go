@@ -3,9 +3,18 @@ package mpc
 import (
 	"fmt"
 	"math"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
+// adjustedTestPrices mirrors scheduler.AdjustedPrices with the default fees (8.5 + 40 EUR/MWh import, 17 EUR/MWh export).
+// The scheduler package cannot be imported here because it depends on mpc, TestAdjustedPrices there pins the same values.
+func adjustedTestPrices(spotMWh float64) (importKWh, exportKWh float64) {
+	return (spotMWh + 8.5 + 40.0) / 1000.0, (spotMWh - 17.0) / 1000.0
+}
+
 func TestCalculateProfit(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -429,12 +438,11 @@ func TestOptimize(t *testing.T) {
 	for i := range len(hourlyPrices) {
 		forecast[i] = TimeSlot{
 			Hour:          i,
-			Timestamp:     int64(1704326400 + i*3600),             // Starting from 2024-01-04 00:00:00 UTC
-			ImportPrice:   hourlyPrices[i]/1000.0 + 0.04 + 0.0085, // Convert EUR/MWh to EUR/kWh
-			ExportPrice:   hourlyPrices[i]/1000.0 - 0.017,         // Convert EUR/MWh to EUR/kWh
-			SolarForecast: 0.0,                                    // Zero solar as specified
-			LoadForecast:  0.38,                                   // 0.38 kW load as specified
+			Timestamp:     int64(1704326400 + i*3600), // Starting from 2024-01-04 00:00:00 UTC
+			SolarForecast: 0.0,                        // Zero solar as specified
+			LoadForecast:  0.38,                       // 0.38 kW load as specified
 		}
+		forecast[i].ImportPrice, forecast[i].ExportPrice = adjustedTestPrices(hourlyPrices[i])
 	}
 
 	// forecast[15].SolarForecast = 0.5
@@ -802,6 +810,68 @@ func TestOptimizeHighSolar(t *testing.T) {
 	t.Logf("High solar scenario: Charge=%.3f, Export=%.3f", decisions[0].BatteryCharge, decisions[0].GridExport)
 }
 
+func TestOptimizeSolarCurtailment(t *testing.T) {
+	config := SystemConfig{
+		BatteryCapacity:        10.0,
+		BatteryMaxCharge:       5.0,
+		BatteryMaxDischarge:    5.0,
+		BatteryMinSOC:          0.1,
+		BatteryMaxSOC:          0.9,
+		BatteryEfficiency:      0.9,
+		BatteryDegradationCost: 0.01,
+		MaxGridImport:          10.0,
+		MaxGridExport:          10.0,
+	}
+
+	// Full battery and a negative export price: exporting the surplus costs money, storing it is impossible
+	forecast := []TimeSlot{
+		{
+			Hour:           0,
+			Timestamp:      1704326400,
+			ImportPrice:    0.10,
+			ExportPrice:    -0.05,
+			SolarForecast:  8.0,
+			LoadForecast:   2.0,
+			AirTemperature: 20.0,
+		},
+	}
+
+	mpc := NewController(config, 1, 0.9)
+	decisions := mpc.Optimize(forecast)
+	if len(decisions) != 1 {
+		t.Fatalf("Expected 1 decision, got %d", len(decisions))
+	}
+
+	dec := decisions[0]
+	if dec.GridExport > 0.01 {
+		t.Errorf("Expected no export at a negative price, got %.3f kW", dec.GridExport)
+	}
+	if math.Abs(dec.SolarCurtailment-6.0) > 0.01 {
+		t.Errorf("Expected the 6 kW surplus to be curtailed, got %.3f kW", dec.SolarCurtailment)
+	}
+	if dec.Profit < 0 {
+		t.Errorf("Expected curtailment to avoid paying for export, got profit %.4f", dec.Profit)
+	}
+	if violations := mpc.CheckDecisions(decisions, 0.01); len(violations) != 0 {
+		t.Errorf("Expected curtailed plan to pass the self-check, got %v", violations)
+	}
+
+	// At a positive export price the surplus is exported instead, only PV above the export limit is curtailed
+	forecast[0].ExportPrice = 0.05
+	decisions = mpc.Optimize(forecast)
+	dec = decisions[0]
+	if dec.GridExport < 6.0-0.01 {
+		t.Errorf("Expected the 6 kW surplus to be exported, got %.3f kW", dec.GridExport)
+	}
+	if dec.SolarCurtailment > 0.01 && dec.GridExport < config.MaxGridExport-0.01 {
+		t.Errorf("Expected curtailment only at the export limit, got %.3f kW curtailed with %.3f kW export",
+			dec.SolarCurtailment, dec.GridExport)
+	}
+	if violations := mpc.CheckDecisions(decisions, 0.01); len(violations) != 0 {
+		t.Errorf("Expected exporting plan to pass the self-check, got %v", violations)
+	}
+}
+
 func TestOptimizeWithBatteryPreHeat(t *testing.T) {
 	config := SystemConfig{
 		BatteryCapacity:             10.0,
@@ -1282,3 +1352,1100 @@ func TestBatteryTemperatureThermalDynamics(t *testing.T) {
 		decisions3[2].BatteryAvgCellTemp, forecast3[2].AirTemperature, decisions3[2].BatteryCharge, decisions3[2].BatteryPreHeatActive)
 	t.Logf("  Note: Optimizer accounts for temperature forecasts and preheating costs in all periods")
 }
+
+func TestOptimizeGridSwitchPenalty(t *testing.T) {
+	config := SystemConfig{
+		BatteryCapacity:        10.0,
+		BatteryMaxCharge:       5.0,
+		BatteryMaxDischarge:    5.0,
+		BatteryMinSOC:          0.1,
+		BatteryMaxSOC:          0.9,
+		BatteryEfficiency:      0.9,
+		BatteryDegradationCost: 0.01,
+		MaxGridImport:          10.0,
+		MaxGridExport:          10.0,
+	}
+
+	// Alternating cheap and expensive hours invite charging from grid and exporting back every other hour
+	forecast := make([]TimeSlot, 12)
+	for i := range forecast {
+		importPrice, exportPrice := 0.05, 0.02
+		if i%2 == 1 {
+			importPrice, exportPrice = 0.40, 0.30
+		}
+		forecast[i] = TimeSlot{
+			Hour:           i,
+			Timestamp:      1704326400 + int64(i*3600),
+			ImportPrice:    importPrice,
+			ExportPrice:    exportPrice,
+			SolarForecast:  0.0,
+			LoadForecast:   0.5,
+			AirTemperature: 20.0,
+		}
+	}
+
+	countSwitches := func(decisions []ControlDecision) int {
+		switches := 0
+		prevDir := gridIdle
+		for _, d := range decisions {
+			dir := gridDirection(d)
+			if dir == gridIdle {
+				continue
+			}
+			if prevDir != gridIdle && dir != prevDir {
+				switches++
+			}
+			prevDir = dir
+		}
+		return switches
+	}
+
+	withoutPenalty := NewController(config, len(forecast), 0.5)
+	withoutPenalty.CurrentBatteryTemp = 20.0
+	switchesOff := countSwitches(withoutPenalty.Optimize(forecast))
+
+	config.GridSwitchPenalty = 1.0
+	withPenalty := NewController(config, len(forecast), 0.5)
+	withPenalty.CurrentBatteryTemp = 20.0
+	switchesOn := countSwitches(withPenalty.Optimize(forecast))
+
+	t.Logf("Import/export switches: penalty off=%d, penalty on=%d", switchesOff, switchesOn)
+
+	if switchesOff == 0 {
+		t.Fatalf("Expected the unpenalized plan to switch between import and export at least once")
+	}
+	if switchesOn >= switchesOff {
+		t.Errorf("Expected fewer import/export switches with penalty, got %d (off) vs %d (on)", switchesOff, switchesOn)
+	}
+}
+
+func TestOptimizeTargetSOC(t *testing.T) {
+	config := SystemConfig{
+		BatteryCapacity:        10.0,
+		BatteryMaxCharge:       5.0,
+		BatteryMaxDischarge:    5.0,
+		BatteryMinSOC:          0.1,
+		BatteryMaxSOC:          0.9,
+		BatteryEfficiency:      0.9,
+		BatteryDegradationCost: 0.01,
+		MaxGridImport:          10.0,
+		MaxGridExport:          10.0,
+		TargetSOC:              0.8,
+		TargetHour:             6,
+		Location:               time.FixedZone("UTC+2", 2*60*60),
+	}
+
+	// Flat prices give no reason to charge, so only the target can move the SOC
+	start := time.Date(2024, 1, 4, 0, 0, 0, 0, config.Location)
+	forecast := make([]TimeSlot, 12)
+	for i := range forecast {
+		forecast[i] = TimeSlot{
+			Hour:           i,
+			Timestamp:      start.Add(time.Duration(i) * time.Hour).Unix(),
+			ImportPrice:    0.20,
+			ExportPrice:    0.05,
+			SolarForecast:  0.0,
+			LoadForecast:   0.5,
+			AirTemperature: 20.0,
+		}
+	}
+
+	// The SOC at the start of 06:00 is the SOC after the 05:00 slot
+	socAtTarget := func(decisions []ControlDecision) float64 {
+		return decisions[config.TargetHour-1].BatterySOC
+	}
+
+	withoutTarget := NewController(config, len(forecast), 0.2)
+	withoutTarget.CurrentBatteryTemp = 20.0
+	socOff := socAtTarget(withoutTarget.Optimize(forecast))
+
+	config.TargetSOCPenalty = 1.0
+	withTarget := NewController(config, len(forecast), 0.2)
+	withTarget.CurrentBatteryTemp = 20.0
+	socOn := socAtTarget(withTarget.Optimize(forecast))
+
+	t.Logf("SOC at %02d:00: target off=%.3f, target on=%.3f (target %.2f)", config.TargetHour, socOff, socOn, config.TargetSOC)
+
+	if math.Abs(socOn-config.TargetSOC) >= math.Abs(socOff-config.TargetSOC) {
+		t.Errorf("Expected SOC closer to target with penalty, got %.3f (off) vs %.3f (on)", socOff, socOn)
+	}
+	if math.Abs(socOn-config.TargetSOC) > 0.05 {
+		t.Errorf("Expected SOC at target hour to converge to %.2f, got %.3f", config.TargetSOC, socOn)
+	}
+}
+
+func TestOptimizeMinActionDuration(t *testing.T) {
+	config := SystemConfig{
+		BatteryCapacity:        10.0,
+		BatteryMaxCharge:       5.0,
+		BatteryMaxDischarge:    5.0,
+		BatteryMinSOC:          0.1,
+		BatteryMaxSOC:          0.9,
+		BatteryEfficiency:      0.9,
+		BatteryDegradationCost: 0.01,
+		MaxGridImport:          10.0,
+		MaxGridExport:          10.0,
+	}
+
+	// A single cheap and a single expensive hour invite isolated one-slot charge and discharge bursts
+	start := time.Date(2024, 1, 4, 0, 0, 0, 0, time.Local)
+	forecast := make([]TimeSlot, 8)
+	for i := range forecast {
+		forecast[i] = TimeSlot{
+			Hour:           i,
+			Timestamp:      start.Add(time.Duration(i) * time.Hour).Unix(),
+			ImportPrice:    0.10,
+			ExportPrice:    0.05,
+			LoadForecast:   0.5,
+			AirTemperature: 20.0,
+		}
+	}
+	forecast[2].ImportPrice = 0.01
+	forecast[5].ExportPrice = 0.40
+
+	// runLengths returns the length of every charge and discharge run that ends before the last slot
+	runLengths := func(decisions []ControlDecision) []int {
+		var runs []int
+		kind, length := 0, 0
+		for _, dec := range decisions {
+			newKind := 0
+			if dec.BatteryCharge > 0 {
+				newKind = 1
+			} else if dec.BatteryDischarge > 0 {
+				newKind = 2
+			}
+			if newKind != kind && length > 0 {
+				runs = append(runs, length)
+				length = 0
+			}
+			kind = newKind
+			if kind != 0 {
+				length++
+			}
+		}
+		return runs
+	}
+
+	bursty := NewController(config, len(forecast), 0.5)
+	burstyRuns := runLengths(bursty.Optimize(forecast))
+	if !slices.Contains(burstyRuns, 1) {
+		t.Fatalf("Expected isolated single-slot actions without a minimum duration, got runs %v", burstyRuns)
+	}
+
+	config.MinActionDurationHours = 2
+	sustained := NewController(config, len(forecast), 0.5)
+	decisions := sustained.Optimize(forecast)
+	sustainedRuns := runLengths(decisions)
+	t.Logf("Action runs: without minimum %v, with 2 h minimum %v", burstyRuns, sustainedRuns)
+
+	for _, length := range sustainedRuns {
+		if length < 2 {
+			t.Errorf("Expected every action to last at least 2 slots, got runs %v", sustainedRuns)
+			break
+		}
+	}
+	if violations := sustained.CheckDecisions(decisions, 0.01); len(violations) != 0 {
+		t.Errorf("Expected plan to pass the self-check, got %v", violations)
+	}
+
+	// With the grid direction tracked as well the decisions are still rebuilt along the path
+	config.GridSwitchPenalty = 0.05
+	combined := NewController(config, len(forecast), 0.5)
+	decisions = combined.Optimize(forecast)
+	trajectory := combined.SimulateTrajectory(0.5, decisions)
+	for i, dec := range decisions {
+		if dec.Timestamp != forecast[i].Timestamp || dec.ImportPrice != forecast[i].ImportPrice || dec.ExportPrice != forecast[i].ExportPrice {
+			t.Errorf("Slot %d: expected the forecast of the slot in the decision, got %+v", i, dec)
+		}
+		if math.Abs(dec.BatterySOC-trajectory[i].SOC) > 0.01 {
+			t.Errorf("Slot %d: expected SOC %.3f along the path, got %.3f", i, trajectory[i].SOC, dec.BatterySOC)
+		}
+	}
+	if violations := combined.CheckDecisions(decisions, 0.01); len(violations) != 0 {
+		t.Errorf("Expected plan to pass the self-check, got %v", violations)
+	}
+}
+
+func TestOptimizeForbidGridCharge(t *testing.T) {
+	config := SystemConfig{
+		BatteryCapacity:        10.0,
+		BatteryMaxCharge:       5.0,
+		BatteryMaxDischarge:    5.0,
+		BatteryMinSOC:          0.1,
+		BatteryMaxSOC:          0.9,
+		BatteryEfficiency:      0.9,
+		BatteryDegradationCost: 0.01,
+		MaxGridImport:          10.0,
+		MaxGridExport:          10.0,
+	}
+
+	// Cheap overnight grid prices followed by an expensive evening invite charging from the grid
+	start := time.Date(2024, 1, 4, 0, 0, 0, 0, time.Local)
+	forecast := make([]TimeSlot, 12)
+	for i := range forecast {
+		forecast[i] = TimeSlot{
+			Hour:           i,
+			Timestamp:      start.Add(time.Duration(i) * time.Hour).Unix(),
+			ImportPrice:    0.02,
+			ExportPrice:    0.01,
+			LoadForecast:   0.5,
+			AirTemperature: 20.0,
+		}
+		if i >= 6 {
+			forecast[i].ImportPrice = 0.40
+			forecast[i].ExportPrice = 0.30
+		}
+	}
+
+	unconstrained := NewController(config, len(forecast), 0.1).Optimize(forecast)
+	gridCharged := 0.0
+	for _, dec := range unconstrained[:6] {
+		gridCharged += dec.BatteryChargeFromGrid
+	}
+	if gridCharged == 0 {
+		t.Fatal("Expected the battery to charge from the grid overnight without the constraint")
+	}
+
+	config.ForbidGridCharge = true
+	controller := NewController(config, len(forecast), 0.1)
+	decisions := controller.Optimize(forecast)
+	for i, dec := range decisions {
+		if dec.BatteryCharge != 0 || dec.BatteryChargeFromGrid != 0 {
+			t.Errorf("Slot %d: expected no charging without solar, got %.2f kW (%.2f kW from grid)",
+				i, dec.BatteryCharge, dec.BatteryChargeFromGrid)
+		}
+	}
+	if violations := controller.CheckDecisions(decisions, 0.01); len(violations) != 0 {
+		t.Errorf("Expected plan to pass the self-check, got %v", violations)
+	}
+
+	// Solar surplus may still be stored, but never more than the surplus
+	forecast[3].SolarForecast = 3.0
+	decisions = NewController(config, len(forecast), 0.1).Optimize(forecast)
+	dec := decisions[3]
+	if dec.BatteryCharge == 0 {
+		t.Error("Expected the solar surplus to be stored")
+	}
+	if draw := dec.BatteryCharge / config.BatteryEfficiency; draw > 2.5+1e-9 {
+		t.Errorf("Expected charging to draw at most the 2.50 kW solar surplus, got %.2f kW", draw)
+	}
+	if dec.GridImport > 1e-9 {
+		t.Errorf("Expected no grid import while charging from solar, got %.2f kW", dec.GridImport)
+	}
+}
+
+func TestOptimizeImportPriceCeiling(t *testing.T) {
+	config := SystemConfig{
+		BatteryCapacity:        10.0,
+		BatteryMaxCharge:       5.0,
+		BatteryMaxDischarge:    5.0,
+		BatteryMinSOC:          0.1,
+		BatteryMaxSOC:          0.9,
+		BatteryEfficiency:      0.9,
+		BatteryDegradationCost: 0.01,
+		MaxGridImport:          10.0,
+		MaxGridExport:          10.0,
+	}
+
+	// Expensive hours ahead of an extreme price spike still make charging from the grid pay
+	start := time.Date(2024, 1, 4, 0, 0, 0, 0, time.Local)
+	forecast := make([]TimeSlot, 8)
+	for i := range forecast {
+		forecast[i] = TimeSlot{
+			Hour:           i,
+			Timestamp:      start.Add(time.Duration(i) * time.Hour).Unix(),
+			ImportPrice:    0.60,
+			ExportPrice:    0.50,
+			LoadForecast:   0.5,
+			AirTemperature: 20.0,
+		}
+		if i >= 5 {
+			forecast[i].ImportPrice = 3.00
+			forecast[i].ExportPrice = 2.80
+		}
+	}
+
+	unconstrained := NewController(config, len(forecast), 0.1).Optimize(forecast)
+	gridCharged := 0.0
+	for _, dec := range unconstrained {
+		gridCharged += dec.BatteryChargeFromGrid
+	}
+	if gridCharged == 0 {
+		t.Fatal("Expected the battery to charge from the grid ahead of the spike without a ceiling")
+	}
+
+	config.ImportPriceCeiling = 0.50
+	controller := NewController(config, len(forecast), 0.1)
+	decisions := controller.Optimize(forecast)
+	for i, dec := range decisions {
+		if dec.BatteryChargeFromGrid != 0 {
+			t.Errorf("Slot %d: expected no grid charging above the ceiling, got %.2f kW", i, dec.BatteryChargeFromGrid)
+		}
+		if dec.GridImport > forecast[i].LoadForecast+1e-9 {
+			t.Errorf("Slot %d: expected no import beyond the %.2f kW base load, got %.2f kW", i, forecast[i].LoadForecast, dec.GridImport)
+		}
+	}
+	if violations := controller.CheckDecisions(decisions, 0.01); len(violations) != 0 {
+		t.Errorf("Expected plan to pass the self-check, got %v", violations)
+	}
+
+	// Below the ceiling the battery charges from the grid as before
+	config.ImportPriceCeiling = 1.00
+	gridCharged = 0
+	for _, dec := range NewController(config, len(forecast), 0.1).Optimize(forecast) {
+		gridCharged += dec.BatteryChargeFromGrid
+	}
+	if gridCharged == 0 {
+		t.Error("Expected grid charging at prices below the ceiling")
+	}
+}
+
+func TestOptimizeHighSOCExportThreshold(t *testing.T) {
+	config := SystemConfig{
+		BatteryCapacity:        10.0,
+		BatteryMaxCharge:       5.0,
+		BatteryMaxDischarge:    5.0,
+		BatteryMinSOC:          0.1,
+		BatteryMaxSOC:          0.9,
+		BatteryEfficiency:      0.9,
+		BatteryDegradationCost: 0.01,
+		MaxGridImport:          10.0,
+		MaxGridExport:          10.0,
+	}
+
+	// A sunny morning with a modest export price, followed by an expensive evening that makes storing the surplus pay
+	start := time.Date(2024, 6, 4, 8, 0, 0, 0, time.Local)
+	forecast := make([]TimeSlot, 10)
+	for i := range forecast {
+		forecast[i] = TimeSlot{
+			Hour:           8 + i,
+			Timestamp:      start.Add(time.Duration(i) * time.Hour).Unix(),
+			ImportPrice:    0.20,
+			ExportPrice:    0.05,
+			SolarForecast:  4.0,
+			LoadForecast:   0.5,
+			AirTemperature: 20.0,
+		}
+		if i >= 6 {
+			forecast[i].SolarForecast = 0
+			forecast[i].LoadForecast = 2.0
+			forecast[i].ImportPrice = 0.40
+		}
+	}
+
+	// summarize returns the peak SOC, the solar export and the charge above the threshold over the sunny slots
+	const threshold = 0.6
+	summarize := func(decisions []ControlDecision) (peakSOC, export, chargeAbove float64) {
+		soc := 0.5
+		for _, dec := range decisions[:6] {
+			if soc >= threshold {
+				chargeAbove += dec.BatteryCharge
+			}
+			soc = dec.BatterySOC
+			peakSOC = max(peakSOC, soc)
+			export += dec.GridExport
+		}
+		return peakSOC, export, chargeAbove
+	}
+
+	peakOff, exportOff, chargeAboveOff := summarize(NewController(config, len(forecast), 0.5).Optimize(forecast))
+
+	config.HighSOCExportThreshold = threshold
+	config.HighSOCChargePenalty = 1.0
+	controller := NewController(config, len(forecast), 0.5)
+	decisions := controller.Optimize(forecast)
+	peakOn, exportOn, chargeAboveOn := summarize(decisions)
+
+	t.Logf("Threshold off: peak SOC %.3f, export %.2f kW, charge above %.2f kW; on: peak SOC %.3f, export %.2f kW, charge above %.2f kW",
+		peakOff, exportOff, chargeAboveOff, peakOn, exportOn, chargeAboveOn)
+
+	if peakOff < config.BatteryMaxSOC-0.01 {
+		t.Fatalf("Expected the battery to charge to %.2f without the threshold, got %.3f", config.BatteryMaxSOC, peakOff)
+	}
+	if chargeAboveOn >= chargeAboveOff {
+		t.Errorf("Expected charging above the threshold to taper, got %.2f kW with vs %.2f kW without", chargeAboveOn, chargeAboveOff)
+	}
+	if peakOn >= peakOff {
+		t.Errorf("Expected a lower peak SOC with the threshold, got %.3f vs %.3f", peakOn, peakOff)
+	}
+	if exportOn <= exportOff {
+		t.Errorf("Expected more export with the threshold, got %.2f kW vs %.2f kW", exportOn, exportOff)
+	}
+	if violations := controller.CheckDecisions(decisions, 0.01); len(violations) != 0 {
+		t.Errorf("Expected plan to pass the self-check, got %v", violations)
+	}
+}
+
+func TestHighSOCChargeCost(t *testing.T) {
+	controller := NewController(SystemConfig{
+		BatteryCapacity:        10.0,
+		BatteryMaxSOC:          0.9,
+		HighSOCExportThreshold: 0.5,
+		HighSOCChargePenalty:   0.4,
+	}, 1, 0.5)
+
+	tests := []struct {
+		name     string
+		from, to float64
+		expected float64
+	}{
+		{name: "below threshold", from: 0.2, to: 0.5, expected: 0},
+		{name: "discharging", from: 0.8, to: 0.7, expected: 0},
+		// 2 kWh above the threshold at an average of 0.1 $/kWh
+		{name: "crossing threshold", from: 0.3, to: 0.7, expected: 0.2},
+		// 2 kWh at an average of 0.3 $/kWh, charging higher costs more
+		{name: "near maximum", from: 0.7, to: 0.9, expected: 0.6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cost := controller.highSOCChargeCost(tt.from, tt.to); math.Abs(cost-tt.expected) > 1e-9 {
+				t.Errorf("Expected cost %.3f, got %.3f", tt.expected, cost)
+			}
+		})
+	}
+
+	controller.Config.HighSOCExportThreshold = 0
+	if cost := controller.highSOCChargeCost(0.7, 0.9); cost != 0 {
+		t.Errorf("Expected no cost without a threshold, got %.3f", cost)
+	}
+}
+
+func TestOptimizeMaxThroughput(t *testing.T) {
+	config := SystemConfig{
+		BatteryCapacity:        10.0,
+		BatteryMaxCharge:       5.0,
+		BatteryMaxDischarge:    5.0,
+		BatteryMinSOC:          0.1,
+		BatteryMaxSOC:          0.9,
+		BatteryEfficiency:      0.95,
+		BatteryDegradationCost: 0.01,
+		MaxGridImport:          10.0,
+		MaxGridExport:          10.0,
+	}
+
+	// Two cheap and two expensive periods a day invite two full cycles, the evening peak pays the most
+	start := time.Date(2024, 1, 4, 0, 0, 0, 0, time.Local)
+	forecast := make([]TimeSlot, 24)
+	for i := range forecast {
+		forecast[i] = TimeSlot{
+			Hour:           i,
+			Timestamp:      start.Add(time.Duration(i) * time.Hour).Unix(),
+			ImportPrice:    0.05,
+			ExportPrice:    0.02,
+			LoadForecast:   0.5,
+			AirTemperature: 20.0,
+		}
+		switch {
+		case i >= 7 && i < 10:
+			forecast[i].ImportPrice, forecast[i].ExportPrice = 0.25, 0.20
+		case i >= 18 && i < 21:
+			forecast[i].ImportPrice, forecast[i].ExportPrice = 0.40, 0.35
+		}
+	}
+
+	unlimited := NewController(config, len(forecast), 0.1).Optimize(forecast)
+	unlimitedThroughput := Throughput(unlimited, 1)
+	unlimitedProfit := Summarize(unlimited, 0).Profit
+
+	config.MaxThroughputKWh = 12.0
+	controller := NewController(config, len(forecast), 0.1)
+	limited := controller.Optimize(forecast)
+	limitedThroughput := Throughput(limited, 1)
+	limitedProfit := Summarize(limited, 0).Profit
+
+	t.Logf("Throughput: unlimited %.2f kWh (profit %.3f), limited %.2f kWh (profit %.3f)",
+		unlimitedThroughput, unlimitedProfit, limitedThroughput, limitedProfit)
+
+	if unlimitedThroughput <= config.MaxThroughputKWh {
+		t.Fatalf("Expected the unlimited plan to cycle more than %.1f kWh, got %.2f kWh", config.MaxThroughputKWh, unlimitedThroughput)
+	}
+	if limitedThroughput > config.MaxThroughputKWh+1e-9 {
+		t.Errorf("Expected throughput within %.1f kWh, got %.2f kWh", config.MaxThroughputKWh, limitedThroughput)
+	}
+	if limitedProfit > unlimitedProfit {
+		t.Errorf("Expected the throughput limit to cost profit, got %.3f vs %.3f", limitedProfit, unlimitedProfit)
+	}
+
+	// The remaining cycle serves the evening peak rather than the smaller morning spread
+	morning, evening := 0.0, 0.0
+	for i, dec := range limited {
+		if i >= 7 && i < 10 {
+			morning += dec.BatteryDischarge
+		}
+		if i >= 18 && i < 21 {
+			evening += dec.BatteryDischarge
+		}
+	}
+	if evening <= morning {
+		t.Errorf("Expected the limited plan to discharge mostly at the evening peak, got %.2f kW morning vs %.2f kW evening", morning, evening)
+	}
+	if violations := controller.CheckDecisions(limited, 0.01); len(violations) != 0 {
+		t.Errorf("Expected plan to pass the self-check, got %v", violations)
+	}
+
+	// Split at noon: only 4 kWh may be used in the morning, the total leaves room for the evening cycle
+	config.ThroughputSplit = start.Add(12 * time.Hour).Unix()
+	config.MaxThroughputBeforeSplitKWh = 4.0
+	config.MaxThroughputKWh = 20.0
+	split := NewController(config, len(forecast), 0.1).Optimize(forecast)
+	beforeSplit := Throughput(split[:12], 1)
+	t.Logf("Throughput with split: %.2f kWh before noon, %.2f kWh in total", beforeSplit, Throughput(split, 1))
+	if Throughput(unlimited[:12], 1) <= config.MaxThroughputBeforeSplitKWh {
+		t.Fatalf("Expected the unlimited plan to cycle more than %.1f kWh before noon", config.MaxThroughputBeforeSplitKWh)
+	}
+	if beforeSplit > config.MaxThroughputBeforeSplitKWh+1e-9 {
+		t.Errorf("Expected throughput before the split within %.1f kWh, got %.2f kWh", config.MaxThroughputBeforeSplitKWh, beforeSplit)
+	}
+	if total := Throughput(split, 1); total > config.MaxThroughputKWh+1e-9 {
+		t.Errorf("Expected throughput within %.1f kWh, got %.2f kWh", config.MaxThroughputKWh, total)
+	}
+}
+
+func TestOptimizeQuarterHourSlots(t *testing.T) {
+	config := SystemConfig{
+		BatteryCapacity:     10.0,
+		BatteryMaxCharge:    4.0,
+		BatteryMaxDischarge: 4.0,
+		BatteryMinSOC:       0.1,
+		BatteryMaxSOC:       0.9,
+		BatteryEfficiency:   0.9,
+		MaxGridImport:       10.0,
+		MaxGridExport:       10.0,
+	}
+
+	// Two cheap hours followed by two expensive ones in 15 minute slots
+	start := time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC).Unix()
+	forecast := make([]TimeSlot, 16)
+	for i := range forecast {
+		forecast[i] = TimeSlot{
+			Hour:         i / 4,
+			Timestamp:    start + int64(i)*900,
+			ImportPrice:  0.05,
+			ExportPrice:  0.02,
+			LoadForecast: 0.5,
+		}
+		if i >= 8 {
+			forecast[i].ImportPrice, forecast[i].ExportPrice = 0.40, 0.30
+		}
+	}
+
+	initialSOC := 0.1
+	decisions := NewController(config, len(forecast), initialSOC).Optimize(forecast)
+
+	// A slot moves a quarter of its power as energy, both in the SOC and in the throughput
+	const tolerance = 0.01
+	soc := initialSOC
+	charged, discharged := 0.0, 0.0
+	for i, dec := range decisions {
+		soc += (dec.BatteryCharge*config.BatteryEfficiency - dec.BatteryDischarge) * 0.25 / config.BatteryCapacity
+		if math.Abs(soc-dec.BatterySOC) > tolerance {
+			t.Errorf("Slot %d: expected SOC %.3f for 15 minutes of %.2f kW charge and %.2f kW discharge, got %.3f",
+				i, soc, dec.BatteryCharge, dec.BatteryDischarge, dec.BatterySOC)
+		}
+		soc = dec.BatterySOC
+		charged += dec.BatteryCharge * 0.25
+		discharged += dec.BatteryDischarge * 0.25
+	}
+	if charged == 0 || discharged == 0 {
+		t.Fatalf("Expected a plan that charges and discharges, got %.2f kWh and %.2f kWh", charged, discharged)
+	}
+	if throughput := Throughput(decisions, 0.25); math.Abs(throughput-(charged+discharged)) > 1e-9 {
+		t.Errorf("Expected %.2f kWh of throughput, got %.2f kWh", charged+discharged, throughput)
+	}
+}
+
+func TestMinActionSlots(t *testing.T) {
+	start := time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC).Unix()
+	quarterHours := []TimeSlot{{Timestamp: start}, {Timestamp: start + 900}}
+
+	tests := []struct {
+		name     string
+		hours    float64
+		forecast []TimeSlot
+		expected int
+	}{
+		{name: "disabled", hours: 0, forecast: quarterHours, expected: 1},
+		{name: "one hour of 15 minute slots", hours: 1, forecast: quarterHours, expected: 4},
+		{name: "partial slot rounds up", hours: 0.6, forecast: quarterHours, expected: 3},
+		{name: "single slot is hourly", hours: 2, forecast: quarterHours[:1], expected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mpc := NewController(SystemConfig{MinActionDurationHours: tt.hours}, len(tt.forecast), 0.5)
+			if result := mpc.minActionSlots(tt.forecast); result != tt.expected {
+				t.Errorf("Expected %d slots, got %d", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestTargetSOCSlots(t *testing.T) {
+	location := time.FixedZone("UTC-5", -5*60*60)
+	config := SystemConfig{TargetHour: 2, TargetSOCPenalty: 1.0, Location: location}
+	controller := NewController(config, 0, 0.5)
+
+	// 15-minute slots starting at 01:30 local time
+	start := time.Date(2024, 1, 4, 1, 30, 0, 0, location)
+	forecast := make([]TimeSlot, 8)
+	for i := range forecast {
+		forecast[i].Timestamp = start.Add(time.Duration(i) * 15 * time.Minute).Unix()
+	}
+
+	slots := controller.targetSOCSlots(forecast)
+	for i, marked := range slots {
+		expected := i == 2 // 02:00 is the third slot
+		if marked != expected {
+			t.Errorf("Index %d: expected marked=%v, got %v", i, expected, marked)
+		}
+	}
+
+	// The hour is taken in the configured location, not the time zone of the process
+	controller.Config.Location = time.UTC
+	for i, marked := range controller.targetSOCSlots(forecast) {
+		if marked {
+			t.Errorf("Index %d: expected no 02:00 UTC in the horizon", i)
+		}
+	}
+
+	controller.Config.Location = location
+	controller.Config.TargetSOCPenalty = 0
+	for i, marked := range controller.targetSOCSlots(forecast) {
+		if marked {
+			t.Errorf("Index %d: expected no slots marked when penalty is disabled", i)
+		}
+	}
+}
+
+func TestSensitivityAnalysis(t *testing.T) {
+	config := SystemConfig{
+		BatteryCapacity:        10.0,
+		BatteryMaxCharge:       5.0,
+		BatteryMaxDischarge:    5.0,
+		BatteryMinSOC:          0.1,
+		BatteryMaxSOC:          0.9,
+		BatteryEfficiency:      0.9,
+		BatteryDegradationCost: 0.01,
+		MaxGridImport:          10.0,
+		MaxGridExport:          10.0,
+	}
+	deltas := []float64{-0.2, -0.1, 0, 0.1, 0.2}
+
+	buildForecast := func(solar, load float64) []TimeSlot {
+		forecast := make([]TimeSlot, 8)
+		for i := range forecast {
+			forecast[i] = TimeSlot{
+				Hour:           i,
+				Timestamp:      1704326400 + int64(i*3600),
+				ImportPrice:    0.10 + 0.05*float64(i%4),
+				ExportPrice:    0.05 + 0.03*float64(i%4),
+				SolarForecast:  solar,
+				LoadForecast:   load,
+				AirTemperature: 20.0,
+			}
+		}
+		return forecast
+	}
+
+	tests := []struct {
+		name       string
+		forecast   []TimeSlot
+		increasing bool
+	}{
+		// A plant that must import to cover its load loses more money as prices rise
+		{name: "net importer", forecast: buildForecast(0.0, 2.0), increasing: false},
+		// A plant that exports its solar surplus earns more as prices rise
+		{name: "net exporter", forecast: buildForecast(6.0, 0.5), increasing: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := make([]TimeSlot, len(tt.forecast))
+			copy(original, tt.forecast)
+
+			controller := NewController(config, len(tt.forecast), 0.5)
+			controller.CurrentBatteryTemp = 20.0
+			summaries := controller.SensitivityAnalysis(tt.forecast, deltas)
+
+			if len(summaries) != len(deltas) {
+				t.Fatalf("Expected %d summaries, got %d", len(deltas), len(summaries))
+			}
+			for i, summary := range summaries {
+				t.Logf("Delta %+.0f%%: profit %.4f, import %.2f, export %.2f, final SOC %.2f",
+					summary.Delta*100, summary.Profit, summary.GridImport, summary.GridExport, summary.FinalSOC)
+				if summary.Delta != deltas[i] {
+					t.Errorf("Summary %d: expected delta %.2f, got %.2f", i, deltas[i], summary.Delta)
+				}
+				if i == 0 {
+					continue
+				}
+				prev := summaries[i-1].Profit
+				if tt.increasing && summary.Profit <= prev {
+					t.Errorf("Expected profit to increase with prices, got %.4f after %.4f", summary.Profit, prev)
+				}
+				if !tt.increasing && summary.Profit >= prev {
+					t.Errorf("Expected profit to decrease with prices, got %.4f after %.4f", summary.Profit, prev)
+				}
+			}
+
+			// The unshifted summary matches a plain optimization
+			base := Summarize(controller.Optimize(tt.forecast), 0)
+			if math.Abs(base.Profit-summaries[2].Profit) > 1e-9 {
+				t.Errorf("Expected zero delta profit %.4f to match Optimize, got %.4f", base.Profit, summaries[2].Profit)
+			}
+
+			for i := range original {
+				if original[i] != tt.forecast[i] {
+					t.Fatalf("Expected forecast to be left unmodified, slot %d changed", i)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckDecisions(t *testing.T) {
+	config := SystemConfig{
+		BatteryCapacity:        10.0,
+		BatteryMaxCharge:       5.0,
+		BatteryMaxDischarge:    5.0,
+		BatteryMinSOC:          0.1,
+		BatteryMaxSOC:          0.9,
+		BatteryEfficiency:      0.9,
+		BatteryDegradationCost: 0.01,
+		MaxGridImport:          10.0,
+		MaxGridExport:          10.0,
+	}
+
+	forecast := make([]TimeSlot, 6)
+	for i := range forecast {
+		forecast[i] = TimeSlot{
+			Hour:           i,
+			Timestamp:      1704326400 + int64(i*3600),
+			ImportPrice:    0.10 + 0.10*float64(i%3),
+			ExportPrice:    0.05 + 0.08*float64(i%3),
+			SolarForecast:  float64(i % 4),
+			LoadForecast:   1.5,
+			AirTemperature: 20.0,
+		}
+	}
+
+	controller := NewController(config, len(forecast), 0.5)
+	decisions := controller.Optimize(forecast)
+	if violations := controller.CheckDecisions(decisions, 0.01); len(violations) != 0 {
+		t.Fatalf("Expected optimized plan to pass the self-check, got %v", violations)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(dec *ControlDecision)
+		reason string
+	}{
+		{
+			name:   "grid import without matching load",
+			tamper: func(dec *ControlDecision) { dec.GridImport += 1.0 },
+			reason: "power balance",
+		},
+		{
+			name:   "discharge without grid or load change",
+			tamper: func(dec *ControlDecision) { dec.BatteryDischarge += 2.0 },
+			reason: "power balance",
+		},
+		{
+			name:   "SOC above maximum",
+			tamper: func(dec *ControlDecision) { dec.BatterySOC = 0.95 },
+			reason: "SOC",
+		},
+		{
+			name:   "SOC below minimum",
+			tamper: func(dec *ControlDecision) { dec.BatterySOC = 0.05 },
+			reason: "SOC",
+		},
+	}
+
+	// Surplus beyond the export limit is curtailed by the optimizer and is not a violation
+	curtailed := NewController(config, 1, 0.5)
+	curtailedDecisions := curtailed.Optimize([]TimeSlot{{Timestamp: 1704326400, ImportPrice: 0.1, ExportPrice: 0.05, SolarForecast: 20.0, AirTemperature: 20.0}})
+	if violations := curtailed.CheckDecisions(curtailedDecisions, 0.01); len(violations) != 0 {
+		t.Errorf("Expected curtailed plan to pass the self-check, got %v", violations)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := make([]ControlDecision, len(decisions))
+			copy(tampered, decisions)
+			tt.tamper(&tampered[2])
+
+			violations := controller.CheckDecisions(tampered, 0.01)
+			if len(violations) != 1 {
+				t.Fatalf("Expected 1 violation, got %d: %v", len(violations), violations)
+			}
+			if violations[0].Index != 2 || violations[0].Timestamp != forecast[2].Timestamp {
+				t.Errorf("Expected violation at slot 2, got slot %d (timestamp %d)", violations[0].Index, violations[0].Timestamp)
+			}
+			if !strings.HasPrefix(violations[0].Reason, tt.reason) {
+				t.Errorf("Expected %s violation, got %q", tt.reason, violations[0].Reason)
+			}
+		})
+	}
+}
+
+func TestSimulateTrajectory(t *testing.T) {
+	config := SystemConfig{
+		BatteryCapacity:             10.0,
+		BatteryMaxCharge:            5.0,
+		BatteryMaxDischarge:         5.0,
+		BatteryMinSOC:               0.1,
+		BatteryMaxSOC:               0.9,
+		BatteryEfficiency:           0.9,
+		BatteryDegradationCost:      0.01,
+		MaxGridImport:               10.0,
+		MaxGridExport:               10.0,
+		BatteryPreHeatPower:         0.7,
+		BatteryPreHeatTempThreshold: 10.0,
+		BatteryThermalTimeConstant:  0.1,
+	}
+
+	// A cold day with two price swings and some solar, so the plan charges, preheats and discharges
+	start := time.Date(2024, 1, 4, 0, 0, 0, 0, time.Local)
+	forecast := make([]TimeSlot, 24)
+	for i := range forecast {
+		forecast[i] = TimeSlot{
+			Hour:           i,
+			Timestamp:      start.Add(time.Duration(i) * time.Hour).Unix(),
+			ImportPrice:    0.05,
+			ExportPrice:    0.02,
+			LoadForecast:   0.8,
+			AirTemperature: 2.0,
+		}
+		switch {
+		case i >= 7 && i < 10:
+			forecast[i].ImportPrice, forecast[i].ExportPrice = 0.30, 0.20
+		case i >= 11 && i < 14:
+			forecast[i].SolarForecast = 3.0
+		case i >= 17 && i < 21:
+			forecast[i].ImportPrice, forecast[i].ExportPrice = 0.40, 0.30
+		}
+	}
+
+	initialSOC := 0.3
+	controller := NewController(config, len(forecast), initialSOC)
+	controller.CurrentBatteryTemp = 5.0
+	decisions := controller.Optimize(forecast)
+	trajectory := controller.SimulateTrajectory(initialSOC, decisions)
+	if len(trajectory) != len(decisions) {
+		t.Fatalf("Expected %d snapshots, got %d", len(decisions), len(trajectory))
+	}
+
+	// The optimizer rounds the SOC to a grid of 0.16% steps between slots
+	const tolerance = 0.01
+	cycled, preHeated := false, false
+	for i, snapshot := range trajectory {
+		dec := decisions[i]
+		cycled = cycled || dec.BatteryDischarge > 0
+		preHeated = preHeated || dec.BatteryPreHeatActive
+		if snapshot.Timestamp != dec.Timestamp {
+			t.Errorf("Slot %d: expected timestamp %d, got %d", i, dec.Timestamp, snapshot.Timestamp)
+		}
+		if math.Abs(snapshot.SOC-dec.BatterySOC) > tolerance {
+			t.Errorf("Slot %d: simulated SOC %.4f differs from the planned %.4f", i, snapshot.SOC, dec.BatterySOC)
+		}
+		if math.Abs(snapshot.EnergyKWh-snapshot.SOC*config.BatteryCapacity) > 1e-9 {
+			t.Errorf("Slot %d: expected %.3f kWh stored, got %.3f kWh", i, snapshot.SOC*config.BatteryCapacity, snapshot.EnergyKWh)
+		}
+		// The temperature at the end of a slot is the one the next decision starts from
+		if i+1 < len(decisions) && math.Abs(snapshot.BatteryTemp-decisions[i+1].BatteryAvgCellTemp) > 1e-9 {
+			t.Errorf("Slot %d: simulated battery temperature %.3f °C differs from the planned %.3f °C",
+				i, snapshot.BatteryTemp, decisions[i+1].BatteryAvgCellTemp)
+		}
+	}
+	if !cycled || !preHeated {
+		t.Errorf("Expected a plan that discharges (%v) and preheats (%v)", cycled, preHeated)
+	}
+
+	if trajectory := controller.SimulateTrajectory(initialSOC, nil); len(trajectory) != 0 {
+		t.Errorf("Expected no snapshots without decisions, got %d", len(trajectory))
+	}
+}
+
+func TestBreakEvenSpread(t *testing.T) {
+	tests := []struct {
+		name        string
+		efficiency  float64
+		degradation float64
+		expected    float64
+	}{
+		// (1 + 0.9) / 0.81 * 0.01
+		{name: "default test config", efficiency: 0.9, degradation: 0.01, expected: 0.0234567901},
+		// (1 + 0.95) / 0.9025 * 0.005
+		{name: "higher efficiency, lower degradation", efficiency: 0.95, degradation: 0.005, expected: 0.0108033241},
+		{name: "no degradation", efficiency: 0.9, degradation: 0, expected: 0},
+		{name: "ideal battery", efficiency: 1.0, degradation: 0.01, expected: 0.02},
+		{name: "no efficiency", efficiency: 0, degradation: 0.01, expected: math.Inf(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spread := BreakEvenSpread(SystemConfig{BatteryEfficiency: tt.efficiency, BatteryDegradationCost: tt.degradation})
+			if math.IsInf(tt.expected, 1) {
+				if !math.IsInf(spread, 1) {
+					t.Errorf("Expected +Inf, got %.10f", spread)
+				}
+				return
+			}
+			if math.Abs(spread-tt.expected) > 1e-9 {
+				t.Errorf("Expected spread %.10f, got %.10f", tt.expected, spread)
+			}
+		})
+	}
+}
+
+func TestBreakEvenSpread_CycleProfit(t *testing.T) {
+	config := SystemConfig{
+		BatteryCapacity:        10.0,
+		BatteryMaxCharge:       5.0,
+		BatteryMaxDischarge:    5.0,
+		BatteryMinSOC:          0.1,
+		BatteryMaxSOC:          0.9,
+		BatteryEfficiency:      0.9,
+		BatteryDegradationCost: 0.01,
+		MaxGridImport:          10.0,
+		MaxGridExport:          10.0,
+	}
+	mpc := NewController(config, 2, 0.5)
+	eff := config.BatteryEfficiency
+
+	// Charge 2 kW from the grid, then discharge everything stored to the grid
+	charge := ControlDecision{BatteryCharge: 2.0, GridImport: 2.0 / eff}
+	discharge := ControlDecision{BatteryDischarge: 2.0 * eff, GridExport: 2.0 * eff * eff}
+	importPrice := 0.10
+	breakEvenExport := importPrice/(eff*eff*eff) + BreakEvenSpread(config)
+
+	cycleProfit := func(exportPrice float64) float64 {
+		return mpc.calculateProfit(charge, TimeSlot{ImportPrice: importPrice}) +
+			mpc.calculateProfit(discharge, TimeSlot{ExportPrice: exportPrice})
+	}
+	if profit := cycleProfit(breakEvenExport); math.Abs(profit) > 1e-9 {
+		t.Errorf("Expected zero profit at the break-even export price %.4f, got %.6f", breakEvenExport, profit)
+	}
+	if profit := cycleProfit(breakEvenExport + 0.01); profit <= 0 {
+		t.Errorf("Expected profit above the break-even export price, got %.6f", profit)
+	}
+	if profit := cycleProfit(breakEvenExport - 0.01); profit >= 0 {
+		t.Errorf("Expected loss below the break-even export price, got %.6f", profit)
+	}
+}
+
+func TestMarginalLoadCost(t *testing.T) {
+	config := SystemConfig{
+		BatteryCapacity:        10.0,
+		BatteryMaxCharge:       5.0,
+		BatteryMaxDischarge:    5.0,
+		BatteryMinSOC:          0.1,
+		BatteryMaxSOC:          0.9,
+		BatteryEfficiency:      0.9,
+		BatteryDegradationCost: 0.01,
+		MaxGridImport:          10.0,
+		MaxGridExport:          10.0,
+	}
+
+	// Night hours with an empty battery and then hours with a large solar surplus at the same spot price
+	importPrice, exportPrice := adjustedTestPrices(80)
+	solar := []float64{0, 0, 8, 8, 8}
+	forecast := make([]TimeSlot, len(solar))
+	for i := range forecast {
+		forecast[i] = TimeSlot{
+			Hour:           i,
+			Timestamp:      1704326400 + int64(i*3600),
+			ImportPrice:    importPrice,
+			ExportPrice:    exportPrice,
+			SolarForecast:  solar[i],
+			LoadForecast:   2.0,
+			AirTemperature: 20.0,
+		}
+	}
+
+	mpc := NewController(config, len(forecast), 0.1)
+	decisions := mpc.Optimize(forecast)
+	costs := MarginalLoadCost(decisions)
+	if len(costs) != len(decisions) {
+		t.Fatalf("Expected %d marginal costs, got %d", len(decisions), len(costs))
+	}
+	for i, cost := range costs {
+		if solar[i] > 0 {
+			// More load only takes solar from the battery or the export
+			if cost >= importPrice {
+				t.Errorf("Slot %d: expected a marginal cost below the import price %.4f with solar surplus, got %.4f", i, importPrice, cost)
+			}
+		} else if cost != importPrice {
+			t.Errorf("Slot %d: expected the import price %.4f without solar, got %.4f", i, importPrice, cost)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		decision ControlDecision
+		expected float64
+	}{
+		{name: "importing", decision: ControlDecision{GridImport: 2}, expected: 0.13},
+		{name: "exporting", decision: ControlDecision{GridExport: 2}, expected: 0.06},
+		{name: "curtailing", decision: ControlDecision{SolarCurtailment: 3}, expected: 0},
+		{name: "solar charging", decision: ControlDecision{BatteryChargeFromPV: 2}, expected: 0.06},
+		{name: "battery covers the load", decision: ControlDecision{BatteryDischarge: 2}, expected: 0.13},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.decision.ImportPrice = 0.13
+			tt.decision.ExportPrice = 0.06
+			if got := MarginalLoadCost([]ControlDecision{tt.decision})[0]; got != tt.expected {
+				t.Errorf("Expected marginal cost %.2f, got %.2f", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestOptimizeGridSwitchPenalty_ThroughIdle(t *testing.T) {
+	config := SystemConfig{
+		BatteryCapacity:        10.0,
+		BatteryMaxCharge:       5.0,
+		BatteryMaxDischarge:    5.0,
+		BatteryMinSOC:          0.1,
+		BatteryMaxSOC:          0.9,
+		BatteryEfficiency:      0.9,
+		BatteryDegradationCost: 0.01,
+		MaxGridImport:          10.0,
+		MaxGridExport:          10.0,
+		GridSwitchPenalty:      100.0,
+	}
+
+	// Charging in the cheap first hour and exporting in the last one pays off only without the penalty,
+	// in the hour in between the solar covers the load so the grid can stay idle
+	slots := []struct{ importPrice, exportPrice, solar float64 }{{0.05, 0.02, 0}, {0.40, 0.01, 0.5}, {0.80, 0.70, 0}}
+	forecast := make([]TimeSlot, len(slots))
+	for i, s := range slots {
+		forecast[i] = TimeSlot{
+			Hour:           i,
+			Timestamp:      1704326400 + int64(i*3600),
+			ImportPrice:    s.importPrice,
+			ExportPrice:    s.exportPrice,
+			SolarForecast:  s.solar,
+			LoadForecast:   0.5,
+			AirTemperature: 20.0,
+		}
+	}
+
+	mpc := NewController(config, len(forecast), 0.1)
+	mpc.CurrentBatteryTemp = 20.0
+	decisions := mpc.Optimize(forecast)
+
+	lastDir := gridIdle
+	for i, d := range decisions {
+		dir := gridDirection(d)
+		if dir == gridIdle {
+			continue
+		}
+		if lastDir != gridIdle && dir != lastDir {
+			t.Errorf("Slot %d: expected no flip between import and export through idle slots, got %+v", i, decisions)
+		}
+		lastDir = dir
+	}
+}
@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/devskill-org/ems/miners"
+	"github.com/devskill-org/ems/sigenergy"
+)
+
+// Critical event types posted to the alert webhook
+const (
+	AlertSafeMode     = "safe_mode"     // Safe mode engaged, all miners put into standby
+	AlertMinerOffline = "miner_offline" // A miner that answered before stopped answering
+	AlertPlantAlarm   = "plant_alarm"   // A general alarm bit of the plant was set
+	AlertPlantStopped = "plant_stopped" // The plant left the running state
+)
+
+// AlertEvent is the JSON document posted to the alert webhook
+type AlertEvent struct {
+	Type    string         `json:"type"`
+	Message string         `json:"message"`
+	Time    time.Time      `json:"time"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// alertState is the last seen state of the sources of critical events, so an alert is only sent
+// when a state changes and not on every check
+type alertState struct {
+	mu            sync.Mutex
+	minersOnline  map[string]bool
+	plantAlarms   [4]uint16
+	plantState    sigenergy.RunningState
+	plantObserved bool
+}
+
+// alertRetries is how many times a failed webhook delivery is retried, with the delay doubling each time
+const alertRetries = 3
+
+// sendAlert posts an event to the alert webhook in the background, so the control loop never waits
+// for the receiver. Failed deliveries are retried with an exponential backoff starting at alertRetryDelay.
+func (s *MinerScheduler) sendAlert(eventType, message string, details map[string]any) {
+	config := s.GetConfig()
+	if config.AlertWebhookURL == "" {
+		return
+	}
+	event := AlertEvent{Type: eventType, Message: message, Time: s.now().UTC(), Details: details}
+	go s.deliverAlert(config.AlertWebhookURL, config.APITimeout, event)
+}
+
+// deliverAlert posts an event to the webhook, retrying on network errors and non-2xx responses
+func (s *MinerScheduler) deliverAlert(url string, timeout time.Duration, event AlertEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		s.logger.Printf("Warning: failed to encode %s alert: %v", event.Type, err)
+		return
+	}
+
+	client := &http.Client{Timeout: timeout}
+	delay := s.alertRetryDelay
+	for attempt := 0; ; attempt++ {
+		err = postAlert(client, url, body)
+		if err == nil {
+			return
+		}
+		if attempt == alertRetries {
+			break
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+	s.logger.Printf("Warning: failed to deliver %s alert after %d attempts: %v", event.Type, alertRetries+1, err)
+}
+
+// postAlert posts an encoded event once
+func postAlert(client *http.Client, url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// alertOfflineMiners sends an alert for each miner that answered at its previous state check and does not now.
+// Miners never seen answering are not alerted, they may simply be gone from the network.
+func (s *MinerScheduler) alertOfflineMiners(minersList []*miners.AvalonQHost) {
+	var offline []*miners.AvalonQHost
+	s.alerts.mu.Lock()
+	if s.alerts.minersOnline == nil {
+		s.alerts.minersOnline = make(map[string]bool)
+	}
+	for _, m := range minersList {
+		key := minerKey(m)
+		online := m.LastStatsError == nil && m.LastStats != nil
+		if s.alerts.minersOnline[key] && !online {
+			offline = append(offline, m)
+		}
+		s.alerts.minersOnline[key] = online
+	}
+	s.alerts.mu.Unlock()
+
+	for _, m := range offline {
+		s.logger.Printf("ALERT: miner %s:%d stopped answering: %v", m.Address, m.Port, m.LastStatsError)
+		s.sendAlert(AlertMinerOffline, fmt.Sprintf("Miner %s:%d stopped answering: %v", m.Address, m.Port, m.LastStatsError),
+			map[string]any{"address": m.Address, "port": m.Port})
+	}
+}
+
+// alertPlantState sends an alert when a general alarm bit of the plant is set or the plant leaves the running state
+func (s *MinerScheduler) alertPlantState(info *sigenergy.PlantRunningInfo) {
+	alarms := [4]uint16{info.GeneralAlarm1, info.GeneralAlarm2, info.GeneralAlarm3, info.GeneralAlarm4}
+
+	s.alerts.mu.Lock()
+	var raised [4]uint16
+	anyRaised := false
+	for i := range alarms {
+		raised[i] = alarms[i] &^ s.alerts.plantAlarms[i]
+		anyRaised = anyRaised || raised[i] != 0
+	}
+	stopped := s.alerts.plantObserved && s.alerts.plantState == sigenergy.RunningStateRunning &&
+		info.PlantRunningState != sigenergy.RunningStateRunning
+	s.alerts.plantAlarms = alarms
+	s.alerts.plantState = info.PlantRunningState
+	s.alerts.plantObserved = true
+	s.alerts.mu.Unlock()
+
+	if anyRaised {
+		s.logger.Printf("ALERT: plant alarm raised: general alarms 0x%04X 0x%04X 0x%04X 0x%04X",
+			alarms[0], alarms[1], alarms[2], alarms[3])
+		s.sendAlert(AlertPlantAlarm, "Plant alarm raised", map[string]any{
+			"general_alarms": alarms,
+			"raised":         raised,
+		})
+	}
+	if stopped {
+		s.logger.Printf("ALERT: plant stopped running, running state %s", info.PlantRunningState)
+		s.sendAlert(AlertPlantStopped, fmt.Sprintf("Plant stopped running, running state %s", info.PlantRunningState),
+			map[string]any{"running_state": uint16(info.PlantRunningState), "fault": info.PlantRunningState.IsFault()})
+	}
+}
@@ -0,0 +1,167 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/miners"
+	"github.com/devskill-org/ems/sigenergy"
+)
+
+// serveAlertWebhook returns a webhook receiving alerts into the returned channel. The first failures
+// requests are answered with 500.
+func serveAlertWebhook(t *testing.T, failures int32) (*httptest.Server, <-chan AlertEvent, *atomic.Int32) {
+	t.Helper()
+	events := make(chan AlertEvent, 10)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := requests.Add(1); n <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var event AlertEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("Failed to decode alert: %v", err)
+		}
+		if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("Expected a JSON alert, got content type %q", contentType)
+		}
+		events <- event
+	}))
+	t.Cleanup(server.Close)
+	return server, events, &requests
+}
+
+// newAlertTestScheduler returns a scheduler posting alerts to url with short retry delays
+func newAlertTestScheduler(url string) *MinerScheduler {
+	config := testConfig()
+	config.AlertWebhookURL = url
+	config.APITimeout = time.Second
+	scheduler := newTestScheduler(config)
+	scheduler.alertRetryDelay = time.Millisecond
+	return scheduler
+}
+
+// awaitAlert returns the next alert received by the webhook
+func awaitAlert(t *testing.T, events <-chan AlertEvent) AlertEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected an alert to be posted")
+		return AlertEvent{}
+	}
+}
+
+// expectNoAlert fails if the webhook receives an alert shortly
+func expectNoAlert(t *testing.T, events <-chan AlertEvent) {
+	t.Helper()
+	select {
+	case event := <-events:
+		t.Errorf("Expected no alert, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestAlertPlantState_Alarm(t *testing.T) {
+	server, events, _ := serveAlertWebhook(t, 0)
+	scheduler := newAlertTestScheduler(server.URL)
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	scheduler.setClock(&simulatedClock{now: now})
+
+	running := &sigenergy.PlantRunningInfo{PlantRunningState: sigenergy.RunningStateRunning}
+	scheduler.alertPlantState(running)
+	expectNoAlert(t, events)
+
+	alarm := &sigenergy.PlantRunningInfo{PlantRunningState: sigenergy.RunningStateRunning, GeneralAlarm2: 0x0004}
+	scheduler.alertPlantState(alarm)
+	event := awaitAlert(t, events)
+	if event.Type != AlertPlantAlarm {
+		t.Errorf("Expected a %s alert, got %s", AlertPlantAlarm, event.Type)
+	}
+	if !event.Time.Equal(now) {
+		t.Errorf("Expected the alert at %v, got %v", now, event.Time)
+	}
+	raised, ok := event.Details["raised"].([]any)
+	if !ok || len(raised) != 4 || raised[1] != float64(4) {
+		t.Errorf("Expected alarm bit 0x0004 of general alarm 2 raised, got %v", event.Details)
+	}
+
+	// The alarm staying set is not alerted again
+	scheduler.alertPlantState(alarm)
+	expectNoAlert(t, events)
+
+	// A fault stops the plant
+	scheduler.alertPlantState(&sigenergy.PlantRunningInfo{PlantRunningState: sigenergy.RunningStateFault, GeneralAlarm2: 0x0004})
+	if event := awaitAlert(t, events); event.Type != AlertPlantStopped || event.Details["running_state"] != float64(2) {
+		t.Errorf("Expected a %s alert for running state 2, got %+v", AlertPlantStopped, event)
+	}
+}
+
+func TestAlertOfflineMiners(t *testing.T) {
+	server, events, _ := serveAlertWebhook(t, 0)
+	scheduler := newAlertTestScheduler(server.URL)
+
+	miner := newTestMiner(50, miners.AvalonEcoMode, miners.AvalonStateMining, nil)
+	unreachable := newTestMiner(50, miners.AvalonEcoMode, miners.AvalonStateMining, nil)
+	unreachable.Address = "192.168.1.101"
+	unreachable.LastStatsError = errors.New("connection refused")
+
+	// A miner never seen answering is not alerted
+	scheduler.alertOfflineMiners([]*miners.AvalonQHost{miner, unreachable})
+	expectNoAlert(t, events)
+
+	miner.LastStatsError = errors.New("i/o timeout")
+	scheduler.alertOfflineMiners([]*miners.AvalonQHost{miner, unreachable})
+	event := awaitAlert(t, events)
+	if event.Type != AlertMinerOffline || event.Details["address"] != "192.168.1.100" || event.Details["port"] != float64(4028) {
+		t.Errorf("Expected a %s alert for 192.168.1.100:4028, got %+v", AlertMinerOffline, event)
+	}
+
+	scheduler.alertOfflineMiners([]*miners.AvalonQHost{miner, unreachable})
+	expectNoAlert(t, events)
+}
+
+func TestUpdateSafeMode_Alert(t *testing.T) {
+	server, events, _ := serveAlertWebhook(t, 0)
+	scheduler := newAlertTestScheduler(server.URL)
+	scheduler.config.SafeModeFailureThreshold = 2
+
+	scheduler.updateSafeMode(t.Context(), true)
+	expectNoAlert(t, events)
+
+	scheduler.updateSafeMode(t.Context(), true)
+	if event := awaitAlert(t, events); event.Type != AlertSafeMode || event.Details["failures"] != float64(2) {
+		t.Errorf("Expected a %s alert after 2 failures, got %+v", AlertSafeMode, event)
+	}
+}
+
+func TestSendAlert_Retries(t *testing.T) {
+	server, events, requests := serveAlertWebhook(t, 2)
+	scheduler := newAlertTestScheduler(server.URL)
+
+	scheduler.sendAlert(AlertPlantStopped, "Plant stopped running", nil)
+	if event := awaitAlert(t, events); event.Type != AlertPlantStopped {
+		t.Errorf("Expected the %s alert after the retries, got %+v", AlertPlantStopped, event)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("Expected 2 failed deliveries and 1 successful, got %d requests", n)
+	}
+}
+
+func TestSendAlert_Disabled(t *testing.T) {
+	_, events, requests := serveAlertWebhook(t, 0)
+	scheduler := newAlertTestScheduler("")
+
+	scheduler.sendAlert(AlertSafeMode, "Safe mode entered", nil)
+	expectNoAlert(t, events)
+	if n := requests.Load(); n != 0 {
+		t.Errorf("Expected no requests without a webhook URL, got %d", n)
+	}
+}
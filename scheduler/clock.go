@@ -0,0 +1,32 @@
+package scheduler
+
+import "time"
+
+// Clock is the time source of the scheduler
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the wall clock
+type realClock struct{}
+
+// Now returns the current wall clock time
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// setClock replaces the time source of the scheduler and of its weather forecast cache
+func (s *MinerScheduler) setClock(clock Clock) {
+	s.clock = clock
+	s.weatherCache.mu.Lock()
+	s.weatherCache.clock = clock
+	s.weatherCache.mu.Unlock()
+}
+
+// now returns the current time of the scheduler's clock, the wall clock if none is set
+func (s *MinerScheduler) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
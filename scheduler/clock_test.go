@@ -0,0 +1,126 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/entsoe"
+	"github.com/devskill-org/ems/meteo"
+)
+
+func TestStart_InitialDelaysFromClock(t *testing.T) {
+	config := testConfig()
+	config.DryRun = true
+	config.CheckPriceInterval = 15 * time.Minute
+	config.MPCExecutionInterval = 15 * time.Minute
+
+	scheduler := newTestScheduler(config)
+	scheduler.minerDiscoveryFunc = mockMinerDiscovery
+	scheduler.setClock(&simulatedClock{now: time.Date(2024, 1, 15, 10, 5, 30, 0, time.UTC)})
+	var buf bytes.Buffer
+	scheduler.logger = log.New(&buf, "", 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- scheduler.Start(ctx, false)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// The delays align the tasks to the interval boundaries after the clock's time, not the wall clock
+	expected := []string{
+		"[PriceCheck] Waiting for initial delay: 9m31s",
+		"[DataIntegration] Waiting for initial delay: 9m30s",
+		"[StateCheck] Waiting for initial delay: 30s",
+		"[MPCExecution] Waiting for initial delay: 9m32s",
+	}
+	for _, line := range expected {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("Expected log %q, got:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestBuildMPCForecast_WindowFromClock(t *testing.T) {
+	marketData, dayStart := spikeDay(t, 50, 300, 18)
+	now := dayStart.Add(10*time.Hour + 7*time.Minute)
+
+	config := testConfig()
+	config.CheckPriceInterval = 15 * time.Minute
+	scheduler := newTestScheduler(config)
+	scheduler.setClock(&simulatedClock{now: now})
+	scheduler.logger = log.New(&bytes.Buffer{}, "", 0)
+	scheduler.mu.Lock()
+	scheduler.pricesMarketData = marketData
+	scheduler.pricesMarketDataExpiry = dayStart.AddDate(0, 0, 1)
+	scheduler.mu.Unlock()
+	// The forecast is fresh at the clock's time, no weather is fetched
+	scheduler.weatherCache.Set(&meteo.METJSONForecast{})
+
+	forecast, err := scheduler.buildMPCForecast(context.Background(), config, nil, 0)
+	if err != nil {
+		t.Fatalf("buildMPCForecast failed: %v", err)
+	}
+
+	// Slots start at the clock's time and end with the last price of the day at 23:52
+	if expected := 56; len(forecast) != expected {
+		t.Fatalf("Expected %d slots, got %d", expected, len(forecast))
+	}
+	if forecast[0].Timestamp != now.Unix() {
+		t.Errorf("Expected the first slot at %s, got %s", now, time.Unix(forecast[0].Timestamp, 0).In(now.Location()))
+	}
+	last := now.Add(55 * 15 * time.Minute)
+	if forecast[len(forecast)-1].Timestamp != last.Unix() {
+		t.Errorf("Expected the last slot at %s, got %s", last, time.Unix(forecast[len(forecast)-1].Timestamp, 0).In(now.Location()))
+	}
+}
+
+func TestBuildMPCForecast_HorizonEndsAtMissingHour(t *testing.T) {
+	marketData, dayStart := spikeDay(t, 50, 300, 18)
+	// The hour from 20:00 is missing: the day is split into two TimeSeries around it
+	series := marketData.TimeSeries[0]
+	before, after := series, series
+	before.Period.TimeInterval.End = dayStart.Add(20 * time.Hour).UTC()
+	before.Period.Points = series.Period.Points[:20]
+	after.Period.TimeInterval.Start = dayStart.Add(21 * time.Hour).UTC()
+	after.Period.Points = []entsoe.Point{{Position: 1, PriceAmount: 50}, {Position: 2, PriceAmount: 50}, {Position: 3, PriceAmount: 50}}
+	marketData.TimeSeries = []entsoe.TimeSeries{before, after}
+
+	now := dayStart.Add(10 * time.Hour)
+	config := testConfig()
+	config.CheckPriceInterval = time.Hour
+	scheduler := newTestScheduler(config)
+	scheduler.setClock(&simulatedClock{now: now})
+	var buf bytes.Buffer
+	scheduler.logger = log.New(&buf, "", 0)
+	scheduler.mu.Lock()
+	scheduler.pricesMarketData = marketData
+	scheduler.pricesMarketDataExpiry = dayStart.AddDate(0, 0, 1)
+	scheduler.mu.Unlock()
+	scheduler.weatherCache.Set(&meteo.METJSONForecast{})
+
+	forecast, err := scheduler.buildMPCForecast(context.Background(), config, nil, 0)
+	if err != nil {
+		t.Fatalf("buildMPCForecast failed: %v", err)
+	}
+
+	// Slots from 10:00 to 19:00, the prices after the hole are not used
+	if expected := 10; len(forecast) != expected {
+		t.Fatalf("Expected %d slots, got %d", expected, len(forecast))
+	}
+	last := dayStart.Add(19 * time.Hour)
+	if forecast[len(forecast)-1].Timestamp != last.Unix() {
+		t.Errorf("Expected the last slot at %s, got %s", last, time.Unix(forecast[len(forecast)-1].Timestamp, 0).In(now.Location()))
+	}
+	if !strings.Contains(buf.String(), "Warning: 1 hour(s) without price in the price document") {
+		t.Errorf("Expected a warning about the missing hour, got:\n%s", buf.String())
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/devskill-org/ems/miners"
 )
 
 // Config represents the configuration for the miner scheduler
@@ -19,11 +21,17 @@ type Config struct {
 	MinersStateCheckInterval time.Duration `json:"miners_state_check_interval"` // How often to check miners state
 	MinerDiscoveryInterval   time.Duration `json:"miner_discovery_interval"`    // How often to discover miners
 	DryRun                   bool          `json:"dry_run"`                     // Run in dry-run mode (simulate actions without executing)
+	PriceSpikeFactor         float64       `json:"price_spike_factor"`          // Miners go to standby when a price exceeds the daily median by this factor (0 = disabled)
+	PriceSpikeLeadTime       time.Duration `json:"price_spike_lead_time"`       // How long before a price spike miners go to standby
+	MarginalPriceLimit       bool          `json:"marginal_price_limit"`        // Compare price_limit with the marginal cost of load in the current MPC slot instead of the spot price
 
 	// API settings
-	SecurityToken string        `json:"security_token"` // ENTSO-E API token
-	APITimeout    time.Duration `json:"api_timeout"`    // Timeout for API calls
-	URLFormat     string        `json:"url_format"`     // ENTSO-E API URL format string
+	SecurityToken string        `json:"security_token"`  // ENTSO-E API token
+	APITimeout    time.Duration `json:"api_timeout"`     // Timeout for API calls
+	URLFormat     string        `json:"url_format"`      // ENTSO-E API URL format string
+	BusinessType  string        `json:"business_type"`   // Only prices of TimeSeries with this business type are used, e.g. "A62" day-ahead ("" = any)
+	PriceCacheDir string        `json:"price_cache_dir"` // Directory caching downloaded price documents ("" = disabled)
+	PriceCacheTTL time.Duration `json:"price_cache_ttl"` // How long a cached price document is used before it is downloaded again
 
 	// Logging settings
 	LogLevel  string `json:"log_level"`  // Log level: debug, info, warn, error
@@ -33,15 +41,40 @@ type Config struct {
 	Location string `json:"location"` // Timezone location string (e.g., "CET"), when the market data published at 00:00
 
 	// Miner settings
-	MinerTimeout time.Duration `json:"miner_timeout"` // Timeout for miner operations
+	MinerTimeout            time.Duration `json:"miner_timeout"`             // Timeout for miner operations
+	MinerControlConcurrency int           `json:"miner_control_concurrency"` // Maximum number of miners contacted at the same time during a control cycle (0 = unlimited)
+	MinerHosts              []string      `json:"miner_hosts"`               // Explicit miner addresses ("ip" or "ip:port"), used instead of scanning the network
+	MinerAllowlist          []string      `json:"miner_allowlist"`           // IPs or CIDRs probed during discovery (empty = whole network)
+	MinerDenylist           []string      `json:"miner_denylist"`            // IPs or CIDRs never probed during discovery
+	MinersFile              string        `json:"miners_file"`               // JSON file the discovered miners are persisted to and restored from at startup ("" = disabled)
+	MinerRebootAfter        time.Duration `json:"miner_reboot_after"`        // Reboot a reachable miner whose stats keep failing for this long (0 = disabled)
+	MinerSettleTime         time.Duration `json:"miner_settle_time"`         // Work mode is not increased within this time after a miner boots (0 = disabled)
+	MinerCommandDelay       time.Duration `json:"miner_command_delay"`       // Minimum time between commands sent to the same miner (0 = disabled)
+	MinerCooldownTime       time.Duration `json:"miner_cooldown_time"`       // A hot miner runs in eco mode this long before it is put into standby (0 = disabled)
+	MinerCooldownTemp       int           `json:"miner_cooldown_temp"`       // °C - hashboard outlet temperature at or above which a miner cools down before standby
+	MinerCostAccounting     bool          `json:"miner_cost_accounting"`     // Attribute energy and import cost to each miner from its mode between state checks
 
 	// Advanced settings
-	HealthCheckPort int `json:"health_check_port"` // Port for health check endpoint (0 = disabled)
+	HealthCheckPort          int           `json:"health_check_port"`           // Port for health check endpoint (0 = disabled)
+	SafeModeFailureThreshold int           `json:"safe_mode_failure_threshold"` // Consecutive cycles with all data sources failing before miners are forced to standby (0 = disabled)
+	TaskWatchdogMultiplier   float64       `json:"task_watchdog_multiplier"`    // Multiple of its interval after which a still running task is reported as stuck and its run cancelled (0 = disabled)
+	HashrateDropThreshold    float64       `json:"hashrate_drop_threshold"`     // Fraction of the baseline fleet hashrate below which a drop is alerted (0 = disabled)
+	HashrateBaselineWindow   time.Duration `json:"hashrate_baseline_window"`    // How far back state checks are averaged into the fleet hashrate baseline
+	AlertWebhookURL          string        `json:"alert_webhook_url"`           // URL critical events are posted to as JSON, see AlertEvent ("" = disabled)
 
 	// FanR thresholds for work mode switching
 	FanRHighThreshold int `json:"fanr_high_threshold"` // FanR threshold to decrease work mode
 	FanRLowThreshold  int `json:"fanr_low_threshold"`  // FanR threshold to increase work mode
 
+	// FanR thermal model for predictive work mode selection
+	FanRTarget   int     `json:"fanr_target"`    // Desired FanR % used to pick the work mode from the learned model (0 = disabled, step one mode at a time)
+	FanRModeStep float64 `json:"fanr_mode_step"` // Default FanR % change per work mode step until the model has learned from history
+
+	// Thunderstorm protection based on the MET forecast probability of thunder
+	ThunderThrottleProbability float64       `json:"thunder_throttle_probability"` // Probability of thunder % at which miners are limited to eco mode (0 = disabled)
+	ThunderStandbyProbability  float64       `json:"thunder_standby_probability"`  // Probability of thunder % at which miners are put into standby (0 = disabled)
+	ThunderLookahead           time.Duration `json:"thunder_lookahead"`            // How far ahead the forecast is checked for thunder
+
 	// Power consumption settings (in kilowatts)
 	MinersPowerLimit   float64 `json:"miners_power_limit"`   // Maximum total power limit for miners in kW
 	MinerPowerStandby  float64 `json:"miner_power_standby"`  // Power consumption in standby mode (kW)
@@ -49,9 +82,25 @@ type Config struct {
 	MinerPowerStandard float64 `json:"miner_power_standard"` // Power consumption in standard mode (kW)
 	MinerPowerSuper    float64 `json:"miner_power_super"`    // Power consumption in super mode (kW)
 	UsePVPowerControl  bool    `json:"use_pv_power_control"` // Enable PV power-based control
+	SolarSurplusMining bool    `json:"solar_surplus_mining"` // Run miners on the measured solar surplus while its export price is at or below price_limit
+
+	// Limits on the measured plant state, miners are throttled whatever the plan
+	GridImportFailSafeMargin float64 `json:"grid_import_failsafe_margin"` // kW below max_grid_import from which miners are throttled (0 = disabled)
+	MinerBatteryMinSOC       float64 `json:"miner_battery_min_soc"`       // SOC (0-1) below which miners may only run from solar and grid, not the battery (0 = disabled)
+
+	// Mining revenue model, miners only run while the revenue covers the energy cost (disabled when no revenue is set)
+	MiningRevenuePerTHDay float64 `json:"mining_revenue_per_th_day"` // Revenue per TH/s per day in EUR, overrides the BTC model (0 = use the BTC model)
+	BTCPrice              float64 `json:"btc_price"`                 // BTC price in EUR for the BTC model (0 = disabled)
+	NetworkDifficulty     float64 `json:"network_difficulty"`        // Bitcoin network difficulty for the BTC model
+	BlockReward           float64 `json:"block_reward"`              // Block reward in BTC for the BTC model
+	MinerHashrate         float64 `json:"miner_hashrate"`            // Hashrate of one miner in standard mode (TH/s)
+
+	// Load estimate error tracking
+	LoadForecastBiasCorrection bool `json:"load_forecast_bias_correction"` // Add the measured bias of the load estimate to future MPC load forecasts
 
 	// Plant Modbus server
-	PlantModbusAddress string `json:"plant_modbus_address"` // Plant Modbus server address (format: IP:PORT, e.g., "192.168.1.100:502")
+	PlantModbusAddress string        `json:"plant_modbus_address"` // Plant Modbus server address (format: IP:PORT, e.g., "192.168.1.100:502")
+	PlantModbusTimeout time.Duration `json:"plant_modbus_timeout"` // Connect and response timeout of Modbus requests to the plant
 
 	// PV metrics integration
 	DeviceID            int           `json:"device_id"`             // Device ID for metrics table
@@ -59,11 +108,19 @@ type Config struct {
 	PVIntegrationPeriod time.Duration `json:"pv_integration_period"` // Integration period for PV power (duration)
 	PostgresConnString  string        `json:"postgres_conn_string"`  // PostgreSQL connection string
 
+	// Metrics retention
+	MetricsDownsampleAfter   time.Duration `json:"metrics_downsample_after"`   // Age after which energy flow rows are aggregated into daily rows (0 = disabled)
+	MetricsRetention         time.Duration `json:"metrics_retention"`          // Age after which raw metrics rows are deleted (0 = keep forever)
+	MetricsRetentionInterval time.Duration `json:"metrics_retention_interval"` // How often to run the metrics retention job
+	StoreDailyKPI            bool          `json:"store_daily_kpi"`            // Store the self-sufficiency KPIs of each day in kpi_daily
+
 	// Weather API settings
 	WeatherUpdateInterval time.Duration `json:"weather_update_interval"` // How often to update weather
 	Latitude              float64       `json:"latitude"`                // Latitude for weather data
 	Longitude             float64       `json:"longitude"`               // Longitude for weather data
 	UserAgent             string        `json:"user_agent"`              // User agent for weather API client
+	WeatherEndpoint       string        `json:"weather_endpoint"`        // MET forecast endpoint: "complete" (cloud layers, thunder probability) or "compact"
+	WeatherMaxAge         time.Duration `json:"weather_max_age"`         // Forecasts generated longer ago are not used for the solar forecast (0 = no limit)
 
 	// Battery/Inverter system configuration (MPC)
 	BatteryCapacity        float64       `json:"battery_capacity"`         // kWh
@@ -73,20 +130,58 @@ type Config struct {
 	BatteryMaxSOC          float64       `json:"battery_max_soc"`          // percentage (0-1)
 	BatteryEfficiency      float64       `json:"battery_efficiency"`       // round-trip efficiency (0-1)
 	BatteryDegradationCost float64       `json:"battery_degradation_cost"` // $/kWh cycled
+	BatterySOHThreshold    float64       `json:"battery_soh_threshold"`    // % state of health below which the SOC window is narrowed (0 = disabled)
+	BatterySOCShrinkPerSOH float64       `json:"battery_soc_shrink_per_soh"` // SOC fraction removed from each end of the window per SOH % below the threshold
 	MaxGridImport          float64       `json:"max_grid_import"`          // kW
 	MaxGridExport                 float64       `json:"max_grid_export"`                   // kW
+	GridSwitchPenalty             float64       `json:"grid_switch_penalty"`               // EUR per switch between grid import and export, idle slots in between included (0 = disabled)
 	MaxSolarPower                 float64       `json:"max_solar_power"`                   // kW - peak solar power capacity
+	SolarSmoothingAlpha           float64       `json:"solar_smoothing_alpha"`             // EMA weight per hour of the solar estimates fed to MPC (0-1, 0 = disabled)
+	SolarFallbackProfile          [][]float64   `json:"solar_fallback_profile"`            // typical fraction of max_solar_power per month (12 rows) and local hour (24 values), used without live weather (empty = zero solar)
 	MPCExecutionInterval          time.Duration `json:"mpc_execution_interval"`            // How often to re-execute current MPC decision
+	MPCRerunOnUpdate              bool          `json:"mpc_rerun_on_update"`               // re-run MPC right away when a revised or extended price document or an updated weather forecast is fetched
 	BatteryPreHeatPower           float64       `json:"battery_preheat_power"`             // kW - power consumption of battery preheating when active
 	BatteryPreHeatTempThreshold   float64       `json:"battery_preheat_temp_threshold"`    // °C - temperature threshold below which battery preheating activates
 	BatteryThermalTimeConstant    float64       `json:"battery_thermal_time_constant"`     // fraction per time slot - rate at which battery temperature approaches air temperature (0-1)
+	BatteryTargetSOC              float64       `json:"battery_target_soc"`                // percentage (0-1) - desired SOC at battery_target_hour
+	BatteryTargetHour             int           `json:"battery_target_hour"`               // hour of day (0-23, local time) at which battery_target_soc should be reached
+	BatteryTargetSOCPenalty       float64       `json:"battery_target_soc_penalty"`        // EUR per kWh of deviation from battery_target_soc at battery_target_hour (0 = disabled)
+	MinActionDurationHours        float64       `json:"min_action_duration_hours"`         // hours a planned battery charge or discharge must last once started (0 = disabled)
+	ForbidGridCharge              bool          `json:"forbid_grid_charge"`                // charge the battery only from solar surplus, never from the grid
+	ImportPriceCeiling            float64       `json:"import_price_ceiling"`              // EUR/MWh - above this adjusted import price the battery never charges from the grid and miners run on solar and battery only (0 = disabled)
+	HighSOCExportThreshold        float64       `json:"high_soc_export_threshold"`         // percentage (0-1) - above this SOC the optimizer prefers export to further charging (0 = disabled)
+	HighSOCChargePenalty          float64       `json:"high_soc_charge_penalty"`           // EUR per kWh stored at battery_max_soc, rising linearly from 0 at high_soc_export_threshold
+	MaxDailyThroughputKWh         float64       `json:"max_daily_throughput_kwh"`          // kWh - battery charge plus discharge allowed per local day (0 = unlimited)
+	FlatPriceSelfConsumption      bool          `json:"flat_price_self_consumption"`       // skip arbitrage and keep maximum self-consumption when the price spread is below break-even
+	ESSSetpointStep               float64       `json:"ess_setpoint_step"`                 // kW - resolution accepted by the inverter for ESS power setpoints (0 = no rounding)
 
 	// Price adjustments
 	ImportPriceOperatorFee float64 `json:"import_price_operator_fee"` // EUR/MWh - Operator fee for import
 	ImportPriceDeliveryFee float64 `json:"import_price_delivery_fee"` // EUR/MWh - Delivery fee for import
 	ExportPriceOperatorFee float64 `json:"export_price_operator_fee"` // EUR/MWh - Operator fee for export (subtracted)
+	PriceOutlierLimit      float64 `json:"price_outlier_limit"`       // EUR/MWh - spot prices beyond ±limit are outliers (0 = disabled)
+	PriceOutlierFactor     float64 `json:"price_outlier_factor"`      // spot prices further from the daily median than factor × the median absolute price of the day are outliers (0 = disabled)
+	PriceOutlierAction     string  `json:"price_outlier_action"`      // "clamp" outliers to the exceeded bound or "skip" them by ending the MPC forecast at the first one
 }
 
+// MET Location Forecast endpoints supported for the weather forecast
+const (
+	WeatherEndpointCompact  = "compact"
+	WeatherEndpointComplete = "complete"
+)
+
+// Actions taken on implausible spot prices
+const (
+	PriceOutlierClamp = "clamp"
+	PriceOutlierSkip  = "skip"
+)
+
+// Default plant location (Riga, Latvia) used when latitude and longitude are not configured
+const (
+	DefaultLatitude  = 56.9496
+	DefaultLongitude = 24.1052
+)
+
 // DefaultConfig returns a configuration with default values
 func DefaultConfig() *Config {
 	return &Config{
@@ -97,46 +192,104 @@ func DefaultConfig() *Config {
 		MinerDiscoveryInterval:   10 * time.Minute,
 		MPCExecutionInterval:     1 * time.Minute,
 		DryRun:                   false,
+		PriceSpikeFactor:         0,
+		PriceSpikeLeadTime:       15 * time.Minute,
 		APITimeout:               30 * time.Second,
 		LogLevel:                 "info",
 		LogFormat:                "text",
 		MinerTimeout:             5 * time.Second,
+		MinerRebootAfter:         0,
+		MinerSettleTime:          10 * time.Minute,
+		MinerCommandDelay:        0,
+		MinerCooldownTime:        0,
+		MinerCooldownTemp:        75,
+		MinerControlConcurrency:  0,
 		HealthCheckPort:          0,
+		SafeModeFailureThreshold: 0,
+		TaskWatchdogMultiplier:   0,
+		HashrateDropThreshold:    0,
+		HashrateBaselineWindow:   time.Hour,
 		DeviceID:                 0,
 		PVPollInterval:           10 * time.Second,
 		PVIntegrationPeriod:      15 * time.Minute,
 		PostgresConnString:       "",
+		MetricsDownsampleAfter:   0,
+		MetricsRetention:         0,
+		MetricsRetentionInterval: 24 * time.Hour,
 		URLFormat:                "https://web-api.tp.entsoe.eu/api?documentType=A44&out_Domain=10YLV-1001A00074&in_Domain=10YLV-1001A00074&periodStart=%s&periodEnd=%s&securityToken=%s",
+		BusinessType:             "A62", // Day-ahead prices
+		PriceCacheDir:            "",
+		PriceCacheTTL:            6 * time.Hour,
 		PlantModbusAddress:       "",
-		Latitude:                 56.9496, // Riga, Latvia
-		Longitude:                24.1052, // Riga, Latvia
+		PlantModbusTimeout:       1 * time.Second,
+		Latitude:                 DefaultLatitude,
+		Longitude:                DefaultLongitude,
 		WeatherUpdateInterval:    1 * time.Hour,
 		UserAgent:                "MyApp/1.0 (username@example.com)",
+		WeatherEndpoint:          WeatherEndpointComplete,
+		WeatherMaxAge:            6 * time.Hour,
 		BatteryCapacity:          24.0,  // 24 kWh
 		BatteryMaxCharge:         12.0,  // 12 kW
 		BatteryMaxDischarge:      12.0,  // 12 kW
 		BatteryMinSOC:            0.0,   // 0%
 		BatteryMaxSOC:            1.0,   // 100%
 		BatteryEfficiency:        0.92,  // 92% round-trip
+		BatterySOHThreshold:      0.0,   // SOC window not adjusted for battery age
+		BatterySOCShrinkPerSOH:   0.01,  // 1% SOC per SOH % below the threshold
 		BatteryDegradationCost:   0.0,   // $0.00 per kWh cycled
 		MaxGridImport:            30.0,  // 30 kW
 		MaxGridExport:            30.0,  // 30 kW
+		GridSwitchPenalty:        0.0,   // No penalty for switching between import and export
+		BatteryTargetSOC:         0.0,   // No target SOC
+		BatteryTargetHour:        0,     // Midnight
+		BatteryTargetSOCPenalty:  0.0,   // Target SOC disabled
+		MinActionDurationHours:   0.0,   // Battery actions may last a single slot
+		ForbidGridCharge:         false, // Battery may charge from the grid
+		ImportPriceCeiling:       0.0,   // Grid import not limited by price
+		HighSOCExportThreshold:   0.0,   // Charging not penalized at high SOC
+		HighSOCChargePenalty:     0.05,  // 0.05 EUR/kWh at the maximum SOC
+		MaxDailyThroughputKWh:    0.0,   // Battery throughput not limited
+		FlatPriceSelfConsumption: false, // MPC plans the battery whatever the price spread
+		MPCRerunOnUpdate:         false, // MPC runs on its schedule only
+		ESSSetpointStep:          0.0,   // ESS setpoints written without rounding
 		MaxSolarPower:            30.0,  // 30 kW peak solar power
+		SolarSmoothingAlpha:      0.0,   // Solar forecast not smoothed
 		ImportPriceOperatorFee:   8.5,   // 8.5 EUR/MWh from Operator
 		ImportPriceDeliveryFee:   40.0,  // 40 EUR/MWh for delivery
 		ExportPriceOperatorFee:   17.0,  // 17 EUR/MWh from Operator
+		PriceOutlierLimit:        0.0,   // No absolute price bound
+		PriceOutlierFactor:       0.0,   // No relative price bound
+		PriceOutlierAction:       PriceOutlierClamp,
 		MinersPowerLimit:         30.0,  // 30 kW total power limit for miners
 		MinerPowerStandby:        0.05,  // 0.05 kW (50 W) in standby
 		MinerPowerEco:            0.8,   // 0.8 kW (800 W) in eco mode
 		MinerPowerStandard:          1.6,   // 1.6 kW (1600 W) in standard mode
 		MinerPowerSuper:             1.8,   // 1.8 kW (1800 W) in super mode
+		MiningRevenuePerTHDay:       0,     // Revenue model disabled
+		BTCPrice:                    0,     // BTC model disabled
+		NetworkDifficulty:           0,     // Set together with btc_price
+		BlockReward:                 3.125, // 3.125 BTC since the 2024 halving
+		MinerHashrate:               90,    // 90 TH/s in standard mode
 		UsePVPowerControl:           false, // Disabled by default
+		SolarSurplusMining:          false, // Miners follow the price only
+		GridImportFailSafeMargin:    0,     // Grid import fail-safe disabled
+		MinerBatteryMinSOC:          0,     // Miners may draw from the battery at any SOC
+		FanRTarget:                  0,     // Predictive work mode selection disabled
+		FanRModeStep:                10.0,  // 10% FanR per work mode step
+		ThunderThrottleProbability:  0,     // Thunder throttling disabled
+		ThunderStandbyProbability:   0,     // Thunder standby disabled
+		ThunderLookahead:            2 * time.Hour, // Check the next 2 hours for thunder
 		BatteryPreHeatPower:         0.7,   // 0.7 kW (700 W) battery preheating power
 		BatteryPreHeatTempThreshold: 10.0,  // 10°C - activate battery preheating below this temperature
 		BatteryThermalTimeConstant:  0.05,   // 0.05 - battery temperature moves 50% toward air temp per time slot when not charging
 	}
 }
 
+// HasDefaultLocation reports whether latitude and longitude are still the default coordinates
+func (c *Config) HasDefaultLocation() bool {
+	return c.Latitude == DefaultLatitude && c.Longitude == DefaultLongitude
+}
+
 // LoadConfig loads configuration from a JSON file
 func LoadConfig(filename string) (*Config, error) {
 	// Clean the filepath to prevent directory traversal
@@ -199,10 +352,24 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("security_token cannot be empty")
 	}
 
-	if c.Network == "" {
+	if c.Network == "" && len(c.MinerHosts) == 0 {
 		return fmt.Errorf("network cannot be empty")
 	}
 
+	for _, host := range c.MinerHosts {
+		if _, err := miners.NewHost(host); err != nil {
+			return fmt.Errorf("miner_hosts: %w", err)
+		}
+	}
+
+	if _, err := parseAddressPrefixes(c.MinerAllowlist); err != nil {
+		return fmt.Errorf("miner_allowlist: %w", err)
+	}
+
+	if _, err := parseAddressPrefixes(c.MinerDenylist); err != nil {
+		return fmt.Errorf("miner_denylist: %w", err)
+	}
+
 	if c.CheckPriceInterval <= 0 {
 		return fmt.Errorf("check_price_interval must be greater than 0, got: %s", c.CheckPriceInterval)
 	}
@@ -211,6 +378,14 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("weather_update_interval must be greater than 0, got: %s", c.WeatherUpdateInterval)
 	}
 
+	if c.PlantModbusTimeout <= 0 {
+		return fmt.Errorf("plant_modbus_timeout must be greater than 0, got: %s", c.PlantModbusTimeout)
+	}
+
+	if c.WeatherMaxAge < 0 {
+		return fmt.Errorf("weather_max_age must be non-negative, got: %v", c.WeatherMaxAge)
+	}
+
 	if c.MinersStateCheckInterval <= 0 {
 		return fmt.Errorf("miners_state_check_interval must be greater than 0, got: %s", c.MinersStateCheckInterval)
 	}
@@ -227,10 +402,42 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("url_format cannot be empty")
 	}
 
+	if c.PriceCacheTTL < 0 {
+		return fmt.Errorf("price_cache_ttl must be non-negative, got: %v", c.PriceCacheTTL)
+	}
+
 	if c.MinerTimeout <= 0 {
 		return fmt.Errorf("miner_timeout must be greater than 0, got: %s", c.MinerTimeout)
 	}
 
+	if c.MinerRebootAfter < 0 {
+		return fmt.Errorf("miner_reboot_after must be non-negative, got: %v", c.MinerRebootAfter)
+	}
+
+	if c.MinerSettleTime < 0 {
+		return fmt.Errorf("miner_settle_time must be non-negative, got: %v", c.MinerSettleTime)
+	}
+
+	if c.MinerCommandDelay < 0 {
+		return fmt.Errorf("miner_command_delay must be non-negative, got: %v", c.MinerCommandDelay)
+	}
+
+	if c.MinerCooldownTime < 0 {
+		return fmt.Errorf("miner_cooldown_time must be non-negative, got: %v", c.MinerCooldownTime)
+	}
+
+	if c.PriceSpikeFactor < 0 || (c.PriceSpikeFactor > 0 && c.PriceSpikeFactor <= 1) {
+		return fmt.Errorf("price_spike_factor must be 0 (disabled) or greater than 1, got: %f", c.PriceSpikeFactor)
+	}
+
+	if c.PriceSpikeLeadTime < 0 {
+		return fmt.Errorf("price_spike_lead_time must be non-negative, got: %v", c.PriceSpikeLeadTime)
+	}
+
+	if c.MinerControlConcurrency < 0 {
+		return fmt.Errorf("miner_control_concurrency must be non-negative, got: %d", c.MinerControlConcurrency)
+	}
+
 	if c.HealthCheckPort < 0 || c.HealthCheckPort > 65535 {
 		return fmt.Errorf("health_check_port must be between 0 and 65535, got: %d", c.HealthCheckPort)
 	}
@@ -255,6 +462,62 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid log_format: %s, must be one of: text, json", c.LogFormat)
 	}
 
+	// Validate metrics retention
+	if c.MetricsDownsampleAfter < 0 {
+		return fmt.Errorf("metrics_downsample_after must be non-negative, got: %v", c.MetricsDownsampleAfter)
+	}
+
+	if c.MetricsRetention < 0 {
+		return fmt.Errorf("metrics_retention must be non-negative, got: %v", c.MetricsRetention)
+	}
+
+	// Only whole days are downsampled, raw rows must outlive the day they are aggregated with
+	if c.MetricsDownsampleAfter > 0 && c.MetricsRetention > 0 && c.MetricsRetention < c.MetricsDownsampleAfter+24*time.Hour {
+		return fmt.Errorf("metrics_retention must be at least metrics_downsample_after plus 24h, got: %v with metrics_downsample_after %v",
+			c.MetricsRetention, c.MetricsDownsampleAfter)
+	}
+
+	if (c.MetricsDownsampleAfter > 0 || c.MetricsRetention > 0) && c.MetricsRetentionInterval <= 0 {
+		return fmt.Errorf("metrics_retention_interval must be positive when metrics retention is enabled, got: %v", c.MetricsRetentionInterval)
+	}
+
+	if c.SafeModeFailureThreshold < 0 {
+		return fmt.Errorf("safe_mode_failure_threshold must be non-negative, got: %d", c.SafeModeFailureThreshold)
+	}
+
+	if c.TaskWatchdogMultiplier < 0 || (c.TaskWatchdogMultiplier > 0 && c.TaskWatchdogMultiplier < 1) {
+		return fmt.Errorf("task_watchdog_multiplier must be 0 (disabled) or at least 1, got: %f", c.TaskWatchdogMultiplier)
+	}
+
+	if c.HashrateDropThreshold < 0 || c.HashrateDropThreshold >= 1 {
+		return fmt.Errorf("hashrate_drop_threshold must be between 0 (disabled) and 1, got: %f", c.HashrateDropThreshold)
+	}
+
+	if c.HashrateDropThreshold > 0 && c.HashrateBaselineWindow <= 0 {
+		return fmt.Errorf("hashrate_baseline_window must be positive when hashrate_drop_threshold is set, got: %v", c.HashrateBaselineWindow)
+	}
+
+	// Validate FanR thermal model
+	if c.FanRTarget < 0 || c.FanRTarget > 100 {
+		return fmt.Errorf("fanr_target must be between 0 and 100, got: %d", c.FanRTarget)
+	}
+
+	if c.FanRModeStep < 0 {
+		return fmt.Errorf("fanr_mode_step must be non-negative, got: %f", c.FanRModeStep)
+	}
+
+	if c.ThunderThrottleProbability < 0 || c.ThunderThrottleProbability > 100 {
+		return fmt.Errorf("thunder_throttle_probability must be between 0 and 100, got: %f", c.ThunderThrottleProbability)
+	}
+
+	if c.ThunderStandbyProbability < 0 || c.ThunderStandbyProbability > 100 {
+		return fmt.Errorf("thunder_standby_probability must be between 0 and 100, got: %f", c.ThunderStandbyProbability)
+	}
+
+	if (c.ThunderThrottleProbability > 0 || c.ThunderStandbyProbability > 0) && c.ThunderLookahead <= 0 {
+		return fmt.Errorf("thunder_lookahead must be positive when thunder protection is enabled, got: %v", c.ThunderLookahead)
+	}
+
 	// Validate latitude
 	if c.Latitude < -90 || c.Latitude > 90 {
 		return fmt.Errorf("latitude must be between -90 and 90, got: %f", c.Latitude)
@@ -270,6 +533,16 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("user_agent cannot be empty")
 	}
 
+	switch c.WeatherEndpoint {
+	case WeatherEndpointCompact:
+		if c.ThunderThrottleProbability > 0 || c.ThunderStandbyProbability > 0 {
+			return fmt.Errorf("thunder protection requires weather_endpoint %q, got: %q", WeatherEndpointComplete, c.WeatherEndpoint)
+		}
+	case "", WeatherEndpointComplete:
+	default:
+		return fmt.Errorf("weather_endpoint must be %q or %q, got: %q", WeatherEndpointCompact, WeatherEndpointComplete, c.WeatherEndpoint)
+	}
+
 	// Validate battery configuration
 	if c.BatteryCapacity < 0 {
 		return fmt.Errorf("battery_capacity must be non-negative, got: %f", c.BatteryCapacity)
@@ -295,6 +568,14 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("battery_min_soc (%f) cannot be greater than battery_max_soc (%f)", c.BatteryMinSOC, c.BatteryMaxSOC)
 	}
 
+	if c.BatterySOHThreshold < 0 || c.BatterySOHThreshold > 100 {
+		return fmt.Errorf("battery_soh_threshold must be between 0 and 100, got: %f", c.BatterySOHThreshold)
+	}
+
+	if c.BatterySOCShrinkPerSOH < 0 {
+		return fmt.Errorf("battery_soc_shrink_per_soh must be non-negative, got: %f", c.BatterySOCShrinkPerSOH)
+	}
+
 	if c.BatteryEfficiency < 0 || c.BatteryEfficiency > 1 {
 		return fmt.Errorf("battery_efficiency must be between 0 and 1, got: %f", c.BatteryEfficiency)
 	}
@@ -311,10 +592,58 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("max_grid_export must be non-negative, got: %f", c.MaxGridExport)
 	}
 
+	if c.GridSwitchPenalty < 0 {
+		return fmt.Errorf("grid_switch_penalty must be non-negative, got: %f", c.GridSwitchPenalty)
+	}
+
+	if c.BatteryTargetSOC < 0 || c.BatteryTargetSOC > 1 {
+		return fmt.Errorf("battery_target_soc must be between 0 and 1, got: %f", c.BatteryTargetSOC)
+	}
+
+	if c.BatteryTargetHour < 0 || c.BatteryTargetHour > 23 {
+		return fmt.Errorf("battery_target_hour must be between 0 and 23, got: %d", c.BatteryTargetHour)
+	}
+
+	if c.BatteryTargetSOCPenalty < 0 {
+		return fmt.Errorf("battery_target_soc_penalty must be non-negative, got: %f", c.BatteryTargetSOCPenalty)
+	}
+
+	if c.HighSOCExportThreshold < 0 || c.HighSOCExportThreshold > 1 {
+		return fmt.Errorf("high_soc_export_threshold must be between 0 and 1, got: %f", c.HighSOCExportThreshold)
+	}
+
+	if c.HighSOCChargePenalty < 0 {
+		return fmt.Errorf("high_soc_charge_penalty must be non-negative, got: %f", c.HighSOCChargePenalty)
+	}
+
+	if c.ImportPriceCeiling < 0 {
+		return fmt.Errorf("import_price_ceiling must be non-negative, got: %f", c.ImportPriceCeiling)
+	}
+
+	if c.MaxDailyThroughputKWh < 0 {
+		return fmt.Errorf("max_daily_throughput_kwh must be non-negative, got: %f", c.MaxDailyThroughputKWh)
+	}
+
+	if c.MinActionDurationHours < 0 {
+		return fmt.Errorf("min_action_duration_hours must be non-negative, got: %f", c.MinActionDurationHours)
+	}
+
+	if c.ESSSetpointStep < 0 {
+		return fmt.Errorf("ess_setpoint_step must be non-negative, got: %f", c.ESSSetpointStep)
+	}
+
 	if c.MaxSolarPower < 0 {
 		return fmt.Errorf("max_solar_power must be non-negative, got: %f", c.MaxSolarPower)
 	}
 
+	if c.SolarSmoothingAlpha < 0 || c.SolarSmoothingAlpha > 1 {
+		return fmt.Errorf("solar_smoothing_alpha must be between 0 and 1, got: %f", c.SolarSmoothingAlpha)
+	}
+
+	if err := validateSolarProfile(c.SolarFallbackProfile); err != nil {
+		return fmt.Errorf("solar_fallback_profile: %w", err)
+	}
+
 	// Validate price adjustments
 	if c.ImportPriceOperatorFee < 0 {
 		return fmt.Errorf("import_price_operator_fee must be non-negative, got: %f", c.ImportPriceOperatorFee)
@@ -328,11 +657,33 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("export_price_operator_fee must be non-negative, got: %f", c.ExportPriceOperatorFee)
 	}
 
+	if c.PriceOutlierLimit < 0 {
+		return fmt.Errorf("price_outlier_limit must be non-negative, got: %f", c.PriceOutlierLimit)
+	}
+
+	if c.PriceOutlierFactor < 0 {
+		return fmt.Errorf("price_outlier_factor must be non-negative, got: %f", c.PriceOutlierFactor)
+	}
+
+	switch c.PriceOutlierAction {
+	case "", PriceOutlierClamp, PriceOutlierSkip:
+	default:
+		return fmt.Errorf("price_outlier_action must be %q or %q, got: %q", PriceOutlierClamp, PriceOutlierSkip, c.PriceOutlierAction)
+	}
+
 	// Validate power settings
 	if c.MinersPowerLimit < 0 {
 		return fmt.Errorf("miners_power_limit must be non-negative, got: %f", c.MinersPowerLimit)
 	}
 
+	if c.GridImportFailSafeMargin < 0 {
+		return fmt.Errorf("grid_import_failsafe_margin must be non-negative, got: %f", c.GridImportFailSafeMargin)
+	}
+
+	if c.MinerBatteryMinSOC < 0 || c.MinerBatteryMinSOC > 1 {
+		return fmt.Errorf("miner_battery_min_soc must be between 0 and 1, got: %f", c.MinerBatteryMinSOC)
+	}
+
 	if c.MinerPowerStandby < 0 {
 		return fmt.Errorf("miner_power_standby must be non-negative, got: %f", c.MinerPowerStandby)
 	}
@@ -349,6 +700,28 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("miner_power_super must be non-negative, got: %f", c.MinerPowerSuper)
 	}
 
+	// Validate mining revenue model
+	if c.MiningRevenuePerTHDay < 0 {
+		return fmt.Errorf("mining_revenue_per_th_day must be non-negative, got: %f", c.MiningRevenuePerTHDay)
+	}
+
+	if c.BTCPrice < 0 {
+		return fmt.Errorf("btc_price must be non-negative, got: %f", c.BTCPrice)
+	}
+
+	if c.BTCPrice > 0 && c.MiningRevenuePerTHDay == 0 {
+		if c.NetworkDifficulty <= 0 {
+			return fmt.Errorf("network_difficulty must be greater than 0 when btc_price is set, got: %f", c.NetworkDifficulty)
+		}
+		if c.BlockReward <= 0 {
+			return fmt.Errorf("block_reward must be greater than 0 when btc_price is set, got: %f", c.BlockReward)
+		}
+	}
+
+	if (c.BTCPrice > 0 || c.MiningRevenuePerTHDay > 0) && c.MinerHashrate <= 0 {
+		return fmt.Errorf("miner_hashrate must be greater than 0 when the mining revenue model is enabled, got: %f", c.MinerHashrate)
+	}
+
 	// Validate PV integration settings
 	if c.PVPollInterval <= 0 {
 		return fmt.Errorf("pv_poll_interval must be greater than 0, got: %s", c.PVPollInterval)
@@ -402,6 +775,19 @@ func (c *Config) MarshalJSON() ([]byte, error) {
 		PVPollInterval           string `json:"pv_poll_interval"`
 		PVIntegrationPeriod      string `json:"pv_integration_period"`
 		WeatherUpdateInterval    string `json:"weather_update_interval"`
+		MetricsDownsampleAfter   string `json:"metrics_downsample_after"`
+		MetricsRetention         string `json:"metrics_retention"`
+		MetricsRetentionInterval string `json:"metrics_retention_interval"`
+		ThunderLookahead         string `json:"thunder_lookahead"`
+		PriceSpikeLeadTime       string `json:"price_spike_lead_time"`
+		HashrateBaselineWindow   string `json:"hashrate_baseline_window"`
+		PriceCacheTTL            string `json:"price_cache_ttl"`
+		MinerRebootAfter         string `json:"miner_reboot_after"`
+		MinerSettleTime          string `json:"miner_settle_time"`
+		MinerCommandDelay        string `json:"miner_command_delay"`
+		MinerCooldownTime        string `json:"miner_cooldown_time"`
+		WeatherMaxAge            string `json:"weather_max_age"`
+		PlantModbusTimeout       string `json:"plant_modbus_timeout"`
 	}{
 		Alias:                    (*Alias)(c),
 		CheckInterval:            c.CheckPriceInterval.String(),
@@ -413,6 +799,19 @@ func (c *Config) MarshalJSON() ([]byte, error) {
 		PVPollInterval:           c.PVPollInterval.String(),
 		PVIntegrationPeriod:      c.PVIntegrationPeriod.String(),
 		WeatherUpdateInterval:    c.WeatherUpdateInterval.String(),
+		MetricsDownsampleAfter:   c.MetricsDownsampleAfter.String(),
+		MetricsRetention:         c.MetricsRetention.String(),
+		MetricsRetentionInterval: c.MetricsRetentionInterval.String(),
+		ThunderLookahead:         c.ThunderLookahead.String(),
+		PriceSpikeLeadTime:       c.PriceSpikeLeadTime.String(),
+		HashrateBaselineWindow:   c.HashrateBaselineWindow.String(),
+		PriceCacheTTL:            c.PriceCacheTTL.String(),
+		MinerRebootAfter:         c.MinerRebootAfter.String(),
+		MinerSettleTime:          c.MinerSettleTime.String(),
+		MinerCommandDelay:        c.MinerCommandDelay.String(),
+		MinerCooldownTime:        c.MinerCooldownTime.String(),
+		WeatherMaxAge:            c.WeatherMaxAge.String(),
+		PlantModbusTimeout:       c.PlantModbusTimeout.String(),
 	})
 }
 
@@ -431,6 +830,19 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 		PVPollInterval           string `json:"pv_poll_interval"`
 		PVIntegrationPeriod      string `json:"pv_integration_period"`
 		WeatherUpdateInterval    string `json:"weather_update_interval"`
+		MetricsDownsampleAfter   string `json:"metrics_downsample_after"`
+		MetricsRetention         string `json:"metrics_retention"`
+		MetricsRetentionInterval string `json:"metrics_retention_interval"`
+		ThunderLookahead         string `json:"thunder_lookahead"`
+		PriceSpikeLeadTime       string `json:"price_spike_lead_time"`
+		HashrateBaselineWindow   string `json:"hashrate_baseline_window"`
+		PriceCacheTTL            string `json:"price_cache_ttl"`
+		MinerRebootAfter         string `json:"miner_reboot_after"`
+		MinerSettleTime          string `json:"miner_settle_time"`
+		MinerCommandDelay        string `json:"miner_command_delay"`
+		MinerCooldownTime        string `json:"miner_cooldown_time"`
+		WeatherMaxAge            string `json:"weather_max_age"`
+		PlantModbusTimeout       string `json:"plant_modbus_timeout"`
 	}{
 		Alias: (*Alias)(c),
 	}
@@ -492,6 +904,71 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 			return fmt.Errorf("invalid pv_integration_period: %w", err)
 		}
 	}
+	if aux.MetricsDownsampleAfter != "" {
+		if c.MetricsDownsampleAfter, err = time.ParseDuration(aux.MetricsDownsampleAfter); err != nil {
+			return fmt.Errorf("invalid metrics_downsample_after: %w", err)
+		}
+	}
+	if aux.MetricsRetention != "" {
+		if c.MetricsRetention, err = time.ParseDuration(aux.MetricsRetention); err != nil {
+			return fmt.Errorf("invalid metrics_retention: %w", err)
+		}
+	}
+	if aux.MetricsRetentionInterval != "" {
+		if c.MetricsRetentionInterval, err = time.ParseDuration(aux.MetricsRetentionInterval); err != nil {
+			return fmt.Errorf("invalid metrics_retention_interval: %w", err)
+		}
+	}
+	if aux.ThunderLookahead != "" {
+		if c.ThunderLookahead, err = time.ParseDuration(aux.ThunderLookahead); err != nil {
+			return fmt.Errorf("invalid thunder_lookahead: %w", err)
+		}
+	}
+	if aux.PriceSpikeLeadTime != "" {
+		if c.PriceSpikeLeadTime, err = time.ParseDuration(aux.PriceSpikeLeadTime); err != nil {
+			return fmt.Errorf("invalid price_spike_lead_time: %w", err)
+		}
+	}
+	if aux.HashrateBaselineWindow != "" {
+		if c.HashrateBaselineWindow, err = time.ParseDuration(aux.HashrateBaselineWindow); err != nil {
+			return fmt.Errorf("invalid hashrate_baseline_window: %w", err)
+		}
+	}
+	if aux.PriceCacheTTL != "" {
+		if c.PriceCacheTTL, err = time.ParseDuration(aux.PriceCacheTTL); err != nil {
+			return fmt.Errorf("invalid price_cache_ttl: %w", err)
+		}
+	}
+	if aux.MinerRebootAfter != "" {
+		if c.MinerRebootAfter, err = time.ParseDuration(aux.MinerRebootAfter); err != nil {
+			return fmt.Errorf("invalid miner_reboot_after: %w", err)
+		}
+	}
+	if aux.MinerSettleTime != "" {
+		if c.MinerSettleTime, err = time.ParseDuration(aux.MinerSettleTime); err != nil {
+			return fmt.Errorf("invalid miner_settle_time: %w", err)
+		}
+	}
+	if aux.MinerCooldownTime != "" {
+		if c.MinerCooldownTime, err = time.ParseDuration(aux.MinerCooldownTime); err != nil {
+			return fmt.Errorf("invalid miner_cooldown_time: %w", err)
+		}
+	}
+	if aux.MinerCommandDelay != "" {
+		if c.MinerCommandDelay, err = time.ParseDuration(aux.MinerCommandDelay); err != nil {
+			return fmt.Errorf("invalid miner_command_delay: %w", err)
+		}
+	}
+	if aux.WeatherMaxAge != "" {
+		if c.WeatherMaxAge, err = time.ParseDuration(aux.WeatherMaxAge); err != nil {
+			return fmt.Errorf("invalid weather_max_age: %w", err)
+		}
+	}
+	if aux.PlantModbusTimeout != "" {
+		if c.PlantModbusTimeout, err = time.ParseDuration(aux.PlantModbusTimeout); err != nil {
+			return fmt.Errorf("invalid plant_modbus_timeout: %w", err)
+		}
+	}
 	if aux.URLFormat != "" {
 		c.URLFormat = aux.URLFormat
 	}
@@ -0,0 +1,34 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/devskill-org/ems/miners"
+)
+
+// coolDownBeforeStandby returns the decision to run a hot miner in eco mode until it has cooled down for
+// miner_cooldown_time, so its fans keep running before power is cut, and standby after that. Miners below
+// miner_cooldown_temp go to standby right away, and so do miners shedding load for the power limit, which
+// must not keep drawing eco power. The cooldown ends early once a decision other than cooling is recorded
+// for the miner, see recordMinerDecision.
+func (s *MinerScheduler) coolDownBeforeStandby(m *miners.AvalonQHost, standby MinerControlDecision) MinerControlDecision {
+	if s.config.MinerCooldownTime <= 0 || standby.Reason != ReasonFanRHigh {
+		return standby
+	}
+
+	key := minerKey(m)
+	now := s.now()
+	if value, ok := s.minerCooldowns.Load(key); ok {
+		if now.Sub(value.(time.Time)) >= s.config.MinerCooldownTime {
+			return standby
+		}
+	} else {
+		if m.LastStats.HBOTemp < s.config.MinerCooldownTemp {
+			return standby
+		}
+		s.minerCooldowns.Store(key, now)
+		s.logger.Printf("Miner %s:%d is hot (HBOTemp %d°C), cooling down in eco mode for %v before standby",
+			m.Address, m.Port, m.LastStats.HBOTemp, s.config.MinerCooldownTime)
+	}
+	return MinerControlDecision{State: m.LastStats.State, WorkMode: miners.AvalonEcoMode, Reason: ReasonCooling}
+}
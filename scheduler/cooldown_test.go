@@ -0,0 +1,109 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/miners"
+)
+
+func TestControlMiner_CooldownBeforeStandby(t *testing.T) {
+	start := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	clock := &simulatedClock{now: start}
+
+	scheduler := newTestScheduler(nil)
+	scheduler.config.MinerCooldownTime = 5 * time.Minute
+	scheduler.config.MinerCooldownTemp = 75
+	scheduler.setClock(clock)
+
+	// FanR stays high, without a cooldown the miner would go to standby as soon as it reaches eco mode
+	hot := newTestMiner(90, miners.AvalonStandardMode, miners.AvalonStateMining, nil)
+	hot.LastStats.HBOTemp = 85
+	control := func() MinerControlDecision {
+		decision := scheduler.controlMiner(hot, 1.5, 10)
+		scheduler.recordMinerDecision(hot, decision)
+		hot.LastStats.WorkMode = decision.WorkMode
+		hot.LastStats.State = decision.State
+		return decision
+	}
+
+	// Standard mode is decreased to eco as before
+	if decision := control(); decision.WorkMode != miners.AvalonEcoMode || decision.Reason != ReasonFanRHigh {
+		t.Fatalf("Expected eco mode for high FanR, got %+v", decision)
+	}
+
+	// In eco mode the hot miner cools down instead of going to standby
+	for _, elapsed := range []time.Duration{0, 2 * time.Minute, 4 * time.Minute} {
+		clock.Set(start.Add(time.Minute + elapsed))
+		decision := control()
+		if decision.State != miners.AvalonStateMining || decision.WorkMode != miners.AvalonEcoMode || decision.Reason != ReasonCooling {
+			t.Fatalf("Expected the miner cooling in eco mode %v into the cooldown, got %+v", elapsed, decision)
+		}
+	}
+
+	// After the cooldown it goes to standby
+	clock.Set(start.Add(6 * time.Minute))
+	if decision := control(); decision.State != miners.AvalonStateStandBy || decision.Reason != ReasonFanRHigh {
+		t.Fatalf("Expected standby after the cooldown, got %+v", decision)
+	}
+	if _, cooling := scheduler.minerCooldowns.Load(minerKey(hot)); cooling {
+		t.Error("Expected the cooldown to be cleared after standby")
+	}
+}
+
+func TestControlMiner_CooldownSkipped(t *testing.T) {
+	tests := []struct {
+		name         string
+		cooldownTime time.Duration
+		hboTemp      int
+		fanR         int
+		totalPower   float64
+		reason       MinerControlReason
+	}{
+		{name: "cool miner", cooldownTime: 5 * time.Minute, hboTemp: 60, fanR: 90, totalPower: 1.0, reason: ReasonFanRHigh},
+		{name: "cooldown disabled", cooldownTime: 0, hboTemp: 85, fanR: 90, totalPower: 1.0, reason: ReasonFanRHigh},
+		// Load shedding must not keep drawing eco power
+		{name: "power limit", cooldownTime: 5 * time.Minute, hboTemp: 85, fanR: 60, totalPower: 11.0, reason: ReasonPowerLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := newTestScheduler(nil)
+			scheduler.config.MinerCooldownTime = tt.cooldownTime
+			scheduler.config.MinerCooldownTemp = 75
+
+			miner := newTestMiner(tt.fanR, miners.AvalonEcoMode, miners.AvalonStateMining, nil)
+			miner.LastStats.HBOTemp = tt.hboTemp
+			decision := scheduler.controlMiner(miner, tt.totalPower, 10)
+			if decision.State != miners.AvalonStateStandBy || decision.Reason != tt.reason {
+				t.Errorf("Expected standby right away, got %+v", decision)
+			}
+		})
+	}
+}
+
+func TestControlMiner_CooldownEndsWhenNoLongerNeeded(t *testing.T) {
+	start := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	clock := &simulatedClock{now: start}
+
+	scheduler := newTestScheduler(nil)
+	scheduler.config.MinerCooldownTime = 5 * time.Minute
+	scheduler.config.MinerCooldownTemp = 75
+	scheduler.setClock(clock)
+
+	miner := newTestMiner(90, miners.AvalonEcoMode, miners.AvalonStateMining, nil)
+	miner.LastStats.HBOTemp = 85
+	scheduler.recordMinerDecision(miner, scheduler.controlMiner(miner, 1.0, 10))
+
+	// FanR recovers during the cooldown, the miner keeps mining
+	clock.Set(start.Add(2 * time.Minute))
+	miner.LastStats.FanR = 60
+	scheduler.recordMinerDecision(miner, scheduler.controlMiner(miner, 1.0, 10))
+
+	// FanR rises again much later: a new cooldown starts instead of an immediate standby
+	clock.Set(start.Add(time.Hour))
+	miner.LastStats.FanR = 90
+	if decision := scheduler.controlMiner(miner, 1.0, 10); decision.Reason != ReasonCooling {
+		t.Errorf("Expected a new cooldown, got %+v", decision)
+	}
+}
@@ -1,6 +1,7 @@
 package scheduler
 
 import (
+	"context"
 	"database/sql"
 	"sync"
 	"time"
@@ -15,6 +16,7 @@ type WeatherForecastCache struct {
 	forecast      *meteo.METJSONForecast
 	fetchedAt     time.Time
 	cacheDuration time.Duration
+	clock         Clock // Time source of the expiration, the wall clock if nil
 }
 
 // Get retrieves the cached weather forecast if it's still valid.
@@ -26,7 +28,7 @@ func (w *WeatherForecastCache) Get() (*meteo.METJSONForecast, bool) {
 		return nil, false
 	}
 
-	if time.Since(w.fetchedAt) > w.cacheDuration {
+	if w.now().Sub(w.fetchedAt) > w.cacheDuration {
 		return nil, false
 	}
 
@@ -39,7 +41,22 @@ func (w *WeatherForecastCache) Set(forecast *meteo.METJSONForecast) {
 	defer w.mu.Unlock()
 
 	w.forecast = forecast
-	w.fetchedAt = time.Now()
+	w.fetchedAt = w.now()
+}
+
+// last returns the cached weather forecast even if it has expired, nil if none was fetched
+func (w *WeatherForecastCache) last() *meteo.METJSONForecast {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.forecast
+}
+
+// now returns the current time of the cache's clock
+func (w *WeatherForecastCache) now() time.Time {
+	if w.clock == nil {
+		return time.Now()
+	}
+	return w.clock.Now()
 }
 
 // DataSample represents a single measurement of power and battery data.
@@ -177,17 +194,13 @@ func (s *MinerScheduler) runDataPoll(samples *DataSamples) error {
 	if s.config.PlantModbusAddress == "" {
 		return nil
 	}
-	client, err := sigenergy.NewTCPClient(s.config.PlantModbusAddress, sigenergy.PlantAddress)
-	if err != nil {
-		s.logger.Printf("Data integration: failed to create modbus client: %v", err)
-		return err
-	}
-	defer client.Close()
-	info, err := client.ReadPlantRunningInfo()
+	info, err := s.readPlantRunningInfo(s.config)
 	if err != nil {
 		s.logger.Printf("Data integration: failed to read PlantRunningInfo: %v", err)
 		return err
 	}
+	s.alertPlantState(info)
+	s.observeThroughput(s.GetConfig(), info.ESSPower)
 	samples.AddSample(
 		info.PhotovoltaicPower,
 		info.GridSensorActivePower,
@@ -195,16 +208,16 @@ func (s *MinerScheduler) runDataPoll(samples *DataSamples) error {
 		info.DCChargerOutputPower,
 		info.ESSSOC,
 		info.ESSAvgCellTemperature,
-		time.Now(),
+		s.now(),
 	)
 	return nil
 }
 
-func (s *MinerScheduler) runDataIntegration(samples *DataSamples, pollInterval time.Duration, dataDB *sql.DB, deviceID int, dryRun bool) error {
+func (s *MinerScheduler) runDataIntegration(ctx context.Context, samples *DataSamples, pollInterval time.Duration, dataDB *sql.DB, deviceID int, dryRun bool) error {
 	// Calculate the period boundary timestamp (end of current integration period)
 	// This ensures samples are grouped by their integration period
 	config := s.GetConfig()
-	now := time.Now()
+	now := s.now()
 	periodEndTime := now.Truncate(config.PVIntegrationPeriod)
 	if periodEndTime.Before(now.Add(-config.PVIntegrationPeriod)) {
 		periodEndTime = periodEndTime.Add(config.PVIntegrationPeriod)
@@ -220,20 +233,17 @@ func (s *MinerScheduler) runDataIntegration(samples *DataSamples, pollInterval t
 
 	timestamp := data.timestamp
 
+	s.observeLoadError(data, pollInterval, config.PVIntegrationPeriod)
+
 	if dataDB == nil {
 		samples.ClearBefore(periodEndTime)
 		return nil
 	}
 
-	// Fetch weather data from meteo API
-	cloudCoverage, err := s.fetchCloudCoverage()
-	if err != nil {
-		s.logger.Printf("Data integration: failed to fetch cloud coverage: %v", err)
-	}
-
-	weatherSymbol, err := s.fetchWeatherSymbol()
+	// Fetch weather data from meteo API once for the cycle, the metrics are stored without weather when it fails
+	cloudCoverage, weatherSymbol, err := s.fetchCurrentWeather(ctx)
 	if err != nil {
-		s.logger.Printf("Data integration: failed to fetch weather symbol: %v", err)
+		s.logger.Printf("Data integration: failed to fetch weather, saving metrics without weather data: %v", err)
 	}
 
 	// Calculate costs using current energy prices
@@ -247,13 +257,10 @@ func (s *MinerScheduler) runDataIntegration(samples *DataSamples, pollInterval t
 	if marketData != nil {
 		spotPrice, found := marketData.LookupPriceByTime(timestamp)
 		if found && spotPrice > 0 {
-			// Import cost: (spot price + operator fee + delivery fee) * energy in MWh
-			importPricePerMWh := spotPrice + config.ImportPriceOperatorFee + config.ImportPriceDeliveryFee
-			gridImportCost = (importPricePerMWh / 1000.0) * data.gridImportPower
-
-			// Export revenue (negative cost): (spot price - operator fee) * energy in MWh
-			exportPricePerMWh := spotPrice - config.ExportPriceOperatorFee
-			gridExportCost = (exportPricePerMWh / 1000.0) * data.gridExportPower
+			// Import cost and export revenue: adjusted price in EUR/kWh * energy in kWh
+			importPrice, exportPrice := AdjustedPrices(spotPrice, config)
+			gridImportCost = importPrice * data.gridImportPower
+			gridExportCost = exportPrice * data.gridExportPower
 		}
 	}
 
@@ -312,104 +319,43 @@ func (s *MinerScheduler) runDataIntegration(samples *DataSamples, pollInterval t
 	return nil
 }
 
-func (s *MinerScheduler) fetchCloudCoverage() (*float64, error) {
-	// Check cache first
-	if cachedForecast, ok := s.weatherCache.Get(); ok {
-		current := cachedForecast.GetCurrentWeather()
-		if current == nil {
-			return nil, nil
-		}
-		return current.GetCloudCoverage(), nil
-	}
-
-	// Cache miss, fetch from API
-	s.logger.Printf("Data integration: fetching weather forecast from API")
-	config := s.GetConfig()
-	client := meteo.NewClient(config.UserAgent)
-
-	location := meteo.Location{
-		Latitude:  config.Latitude,
-		Longitude: config.Longitude,
-	}
-
-	params := meteo.QueryParams{Location: location}
-	forecast, err := client.GetCompact(params)
+// fetchCurrentWeather returns the current cloud coverage and weather symbol from a single weather forecast fetch.
+// Values missing from the forecast are nil.
+func (s *MinerScheduler) fetchCurrentWeather(ctx context.Context) (cloudCoverage *float64, weatherSymbol *string, err error) {
+	forecast, err := s.getOrFetchWeatherForecast(ctx, s.GetConfig())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// Store in cache
-	s.weatherCache.Set(forecast)
-
 	current := forecast.GetCurrentWeather()
 	if current == nil {
-		return nil, nil
+		return nil, nil, nil
 	}
 
-	return current.GetCloudCoverage(), nil
-}
-
-func (s *MinerScheduler) fetchWeatherSymbol() (*string, error) {
-	// Check cache first
-	if cachedForecast, ok := s.weatherCache.Get(); ok {
-		current := cachedForecast.GetCurrentWeather()
-		if current == nil {
-			return nil, nil
-		}
-		symbol := current.GetSymbolCode()
-		if symbol == nil {
-			return nil, nil
-		}
+	cloudCoverage = current.GetCloudCoverage()
+	if symbol := current.GetSymbolCode(); symbol != nil {
 		symbolStr := string(*symbol)
-		return &symbolStr, nil
-	}
-
-	// Cache miss, fetch from API
-	config := s.GetConfig()
-	client := meteo.NewClient(config.UserAgent)
-
-	location := meteo.Location{
-		Latitude:  config.Latitude,
-		Longitude: config.Longitude,
-	}
-
-	params := meteo.QueryParams{Location: location}
-	forecast, err := client.GetCompact(params)
-	if err != nil {
-		return nil, err
-	}
-
-	// Store in cache
-	s.weatherCache.Set(forecast)
-
-	current := forecast.GetCurrentWeather()
-	if current == nil {
-		return nil, nil
+		weatherSymbol = &symbolStr
 	}
-
-	symbol := current.GetSymbolCode()
-	if symbol == nil {
-		return nil, nil
-	}
-	symbolStr := string(*symbol)
-	return &symbolStr, nil
+	return cloudCoverage, weatherSymbol, nil
 }
 
 // GetPlantRunningInfo returns the current plant running information
 // If PlantModbusAddress is not configured, returns nil
 func (s *MinerScheduler) GetPlantRunningInfo() *sigenergy.PlantRunningInfo {
-	if s.config.PlantModbusAddress == "" {
-		return nil
+	if s.plantInfoFunc != nil {
+		info, err := s.plantInfoFunc(s.config)
+		if err != nil {
+			s.logger.Printf("Failed to read plant running info: %v", err)
+			return nil
+		}
+		return info
 	}
-
-	client, err := sigenergy.NewTCPClient(s.config.PlantModbusAddress, sigenergy.PlantAddress)
-	if err != nil {
-		s.logger.Printf("Failed to create modbus client for plant info: %v", err)
+	if s.config.PlantModbusAddress == "" {
 		return nil
 	}
-	defer client.Close()
 
-	info, err := client.ReadPlantRunningInfo()
+	info, err := s.readPlantRunningInfo(s.config)
 	if err != nil {
 		s.logger.Printf("Failed to read plant running info: %v", err)
 		return nil
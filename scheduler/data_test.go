@@ -1,6 +1,17 @@
 package scheduler
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -282,3 +293,261 @@ func abs(x float64) float64 {
 	}
 	return x
 }
+
+func TestWeatherForecast_UsesConfiguredEndpointAndSharedCache(t *testing.T) {
+	tests := []struct {
+		name             string
+		endpoint         string
+		expectedEndpoint string
+	}{
+		{name: "default is complete", endpoint: "", expectedEndpoint: "/complete"},
+		{name: "complete", endpoint: WeatherEndpointComplete, expectedEndpoint: "/complete"},
+		{name: "compact", endpoint: WeatherEndpointCompact, expectedEndpoint: "/compact"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var paths []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				paths = append(paths, r.URL.Path)
+				mu.Unlock()
+				now := time.Now().UTC().Truncate(time.Hour).Format(time.RFC3339)
+				fmt.Fprintf(w, `{"type":"Feature","properties":{"timeseries":[{"time":%q,"data":{
+					"instant":{"details":{"cloud_area_fraction":42.5}},
+					"next_1_hours":{"summary":{"symbol_code":"cloudy"}}}}]}}`, now)
+			}))
+			defer server.Close()
+
+			config := testConfig()
+			config.UserAgent = "test-agent"
+			config.WeatherEndpoint = tt.endpoint
+			scheduler := newTestScheduler(config)
+			scheduler.weatherBaseURL = server.URL
+
+			cloud, symbol, err := scheduler.fetchCurrentWeather(context.Background())
+			if err != nil {
+				t.Fatalf("fetchCurrentWeather failed: %v", err)
+			}
+			if cloud == nil || *cloud != 42.5 {
+				t.Errorf("Expected cloud coverage 42.5, got %v", cloud)
+			}
+			if symbol == nil || *symbol != "cloudy" {
+				t.Errorf("Expected weather symbol cloudy, got %v", symbol)
+			}
+
+			if _, err := scheduler.getOrFetchWeatherForecast(context.Background(), config); err != nil {
+				t.Fatalf("getOrFetchWeatherForecast failed: %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(paths) != 1 {
+				t.Fatalf("Expected a single API request shared through the cache, got %d: %v", len(paths), paths)
+			}
+			if paths[0] != tt.expectedEndpoint {
+				t.Errorf("Expected request to %s, got %s", tt.expectedEndpoint, paths[0])
+			}
+		})
+	}
+}
+
+func TestWeatherForecast_RevalidatesWithSharedClient(t *testing.T) {
+	const lastModified = "Mon, 10 Jun 2024 12:00:00 GMT"
+	var mu sync.Mutex
+	var conditional []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		conditional = append(conditional, r.Header.Get("If-Modified-Since"))
+		mu.Unlock()
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		// Already expired, so the next fetch has to revalidate
+		w.Header().Set("Expires", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+		w.Header().Set("Last-Modified", lastModified)
+		now := time.Now().UTC().Truncate(time.Hour).Format(time.RFC3339)
+		fmt.Fprintf(w, `{"type":"Feature","properties":{"timeseries":[{"time":%q,"data":{
+			"instant":{"details":{"cloud_area_fraction":42.5}}}}]}}`, now)
+	}))
+	defer server.Close()
+
+	config := testConfig()
+	config.UserAgent = "test-agent"
+	scheduler := newTestScheduler(config)
+	scheduler.weatherBaseURL = server.URL
+
+	for i := range 2 {
+		forecast, err := scheduler.getOrFetchWeatherForecast(context.Background(), config)
+		if err != nil {
+			t.Fatalf("Fetch %d failed: %v", i, err)
+		}
+		if cloud := forecast.Properties.Timeseries[0].GetCloudCoverage(); cloud == nil || *cloud != 42.5 {
+			t.Errorf("Fetch %d: expected cloud coverage 42.5, got %v", i, cloud)
+		}
+		// Expire the forecast cached by the scheduler
+		scheduler.weatherCache.fetchedAt = time.Time{}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(conditional) != 2 || conditional[0] != "" || conditional[1] != lastModified {
+		t.Errorf("Expected the second fetch to send If-Modified-Since %q, got %q", lastModified, conditional)
+	}
+}
+
+func TestRunDataIntegration_WeatherFetch(t *testing.T) {
+	tests := []struct {
+		name             string
+		fail             bool
+		expectedCloud    any
+		expectedSymbol   any
+		expectedErrors   int
+		expectedRequests int
+	}{
+		{name: "weather available", expectedCloud: 42.5, expectedSymbol: "cloudy", expectedRequests: 1},
+		// The temporary server error is retried by the client, 3 attempts in total
+		{name: "weather fetch fails", fail: true, expectedCloud: nil, expectedSymbol: nil, expectedErrors: 1, expectedRequests: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requests++
+				mu.Unlock()
+				if tt.fail {
+					http.Error(w, "unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				now := time.Now().UTC().Truncate(time.Hour).Format(time.RFC3339)
+				fmt.Fprintf(w, `{"type":"Feature","properties":{"timeseries":[{"time":%q,"data":{
+					"instant":{"details":{"cloud_area_fraction":42.5}},
+					"next_1_hours":{"summary":{"symbol_code":"cloudy"}}}}]}}`, now)
+			}))
+			defer server.Close()
+
+			config := testConfig()
+			config.UserAgent = "test-agent"
+			scheduler := newTestScheduler(config)
+			scheduler.weatherBaseURL = server.URL
+			var buf bytes.Buffer
+			scheduler.logger = log.New(&buf, "", 0)
+			db, recorder := newRecordingDB(t)
+
+			pollInterval := 10 * time.Second
+			periodEnd := time.Now().Truncate(config.PVIntegrationPeriod)
+			samples := &DataSamples{}
+			samples.AddSample(5.0, 1.0, 0.5, 0, 60.0, 20.0, periodEnd.Add(-2*pollInterval))
+			samples.AddSample(5.0, 1.0, 0.5, 0, 61.0, 20.0, periodEnd.Add(-pollInterval))
+
+			if err := scheduler.runDataIntegration(context.Background(), samples, pollInterval, db, 1, false); err != nil {
+				t.Fatalf("runDataIntegration failed: %v", err)
+			}
+
+			mu.Lock()
+			if requests != tt.expectedRequests {
+				t.Errorf("Expected %d weather requests per integration cycle, got %d", tt.expectedRequests, requests)
+			}
+			mu.Unlock()
+
+			if errors := strings.Count(buf.String(), "Data integration: failed to fetch weather"); errors != tt.expectedErrors {
+				t.Errorf("Expected %d weather error log lines, got %d:\n%s", tt.expectedErrors, errors, buf.String())
+			}
+
+			recorder.mu.Lock()
+			defer recorder.mu.Unlock()
+			if len(recorder.execs) != 1 {
+				t.Fatalf("Expected the metrics row to be stored, got %d statements", len(recorder.execs))
+			}
+			args := recorder.execs[0].args
+			if args[4] != tt.expectedCloud {
+				t.Errorf("Expected cloud_coverage %v, got %v", tt.expectedCloud, args[4])
+			}
+			if args[5] != tt.expectedSymbol {
+				t.Errorf("Expected weather_symbol %v, got %v", tt.expectedSymbol, args[5])
+			}
+			if !samples.IsEmpty() {
+				t.Error("Expected the integrated samples to be cleared")
+			}
+		})
+	}
+}
+
+// servePlantConnections starts a fake plant Modbus server that holds every connection for a short time
+// without answering and counts how many connections are open at the same time
+func servePlantConnections(t *testing.T) (string, *concurrencyCounter, *atomic.Int32) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	counter := &concurrencyCounter{}
+	var connections atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			connections.Add(1)
+			go func() {
+				counter.enter()
+				buf := make([]byte, 260)
+				_, _ = conn.Read(buf)
+				time.Sleep(20 * time.Millisecond)
+				// Leave before closing, the client connects again as soon as it sees the connection closed
+				counter.leave()
+				conn.Close()
+			}()
+		}
+	}()
+	return listener.Addr().String(), counter, &connections
+}
+
+func TestPlantModbus_SerializedAccess(t *testing.T) {
+	address, counter, connections := servePlantConnections(t)
+
+	config := testConfig()
+	config.PlantModbusAddress = address
+	config.PlantModbusTimeout = time.Second
+	scheduler := newTestScheduler(config)
+	scheduler.logger = log.New(io.Discard, "", 0)
+	scheduler.remoteEMSActive = true
+
+	// Readers and writers of the plant from different scheduler tasks at the same time
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if info := scheduler.GetPlantRunningInfo(); info != nil {
+				t.Errorf("Expected no plant info from the fake server, got %+v", info)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			_ = scheduler.runDataPoll(&DataSamples{})
+		}()
+		go func() {
+			defer wg.Done()
+			_ = scheduler.executeSelfConsumption(false)
+		}()
+	}
+	wg.Wait()
+
+	if got := connections.Load(); got < 12 {
+		t.Errorf("Expected every caller to connect to the plant, got %d connections", got)
+	}
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+	if counter.peak != 1 {
+		t.Errorf("Expected one plant connection at a time, got %d", counter.peak)
+	}
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/devskill-org/ems/miners"
+)
+
+// addressFilter decides which addresses are probed during miner discovery
+type addressFilter struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// parseAddressPrefixes parses IP addresses and CIDR networks, a single address becomes a host prefix
+func parseAddressPrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// newAddressFilter creates a filter from allowlist and denylist entries
+func newAddressFilter(allowlist, denylist []string) (*addressFilter, error) {
+	allow, err := parseAddressPrefixes(allowlist)
+	if err != nil {
+		return nil, fmt.Errorf("miner_allowlist: %w", err)
+	}
+	deny, err := parseAddressPrefixes(denylist)
+	if err != nil {
+		return nil, fmt.Errorf("miner_denylist: %w", err)
+	}
+	return &addressFilter{allow: allow, deny: deny}, nil
+}
+
+// allowed returns true if the address is not denylisted and, when an allowlist is set, is allowlisted
+func (f *addressFilter) allowed(addr netip.Addr) bool {
+	for _, prefix := range f.deny {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, prefix := range f.allow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterMiners returns the miners whose address passes the filter
+func (f *addressFilter) filterMiners(hosts []*miners.AvalonQHost) []*miners.AvalonQHost {
+	filtered := make([]*miners.AvalonQHost, 0, len(hosts))
+	for _, host := range hosts {
+		addr, err := netip.ParseAddr(host.Address)
+		if err != nil || !f.allowed(addr) {
+			continue
+		}
+		filtered = append(filtered, host)
+	}
+	return filtered
+}
+
+// configuredMinerHosts creates hosts for the explicitly configured miner addresses
+func configuredMinerHosts(hosts []string) ([]*miners.AvalonQHost, error) {
+	result := make([]*miners.AvalonQHost, 0, len(hosts))
+	for _, hostport := range hosts {
+		host, err := miners.NewHost(hostport)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, host)
+	}
+	return result, nil
+}
@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/devskill-org/ems/miners"
+)
+
+func TestDiscoverMiners_ExplicitHostsSkipScan(t *testing.T) {
+	config := testConfig()
+	config.MinerHosts = []string{"192.168.1.10", "192.168.1.11:4029"}
+
+	scheduler := newTestScheduler(config)
+	scheduler.minerDiscoveryFunc = func(_ context.Context, network string) []*miners.AvalonQHost {
+		t.Errorf("Expected no network scan with explicit hosts, got scan of %s", network)
+		return nil
+	}
+
+	if err := scheduler.discoverMiners(context.Background()); err != nil {
+		t.Fatalf("discoverMiners failed: %v", err)
+	}
+
+	var keys []string
+	for _, m := range scheduler.GetDiscoveredMiners() {
+		keys = append(keys, minerKey(m))
+	}
+	slices.Sort(keys)
+
+	expected := []string{"192.168.1.10:4028", "192.168.1.11:4029"}
+	if !slices.Equal(keys, expected) {
+		t.Errorf("Expected miners %v, got %v", expected, keys)
+	}
+}
+
+func TestDiscoverMiners_DenylistExcludesScannedHosts(t *testing.T) {
+	config := testConfig()
+	config.MinerDenylist = []string{"192.168.1.20", "192.168.1.128/25"}
+
+	scheduler := newTestScheduler(config)
+	scheduler.minerDiscoveryFunc = func(_ context.Context, _ string) []*miners.AvalonQHost {
+		return []*miners.AvalonQHost{
+			{Address: "192.168.1.10", Port: miners.DefaultPort},
+			{Address: "192.168.1.20", Port: miners.DefaultPort},
+			{Address: "192.168.1.30", Port: miners.DefaultPort},
+			{Address: "192.168.1.200", Port: miners.DefaultPort},
+		}
+	}
+
+	if err := scheduler.discoverMiners(context.Background()); err != nil {
+		t.Fatalf("discoverMiners failed: %v", err)
+	}
+
+	var addresses []string
+	for _, m := range scheduler.GetDiscoveredMiners() {
+		addresses = append(addresses, m.Address)
+	}
+	slices.Sort(addresses)
+
+	expected := []string{"192.168.1.10", "192.168.1.30"}
+	if !slices.Equal(addresses, expected) {
+		t.Errorf("Expected miners %v, got %v", expected, addresses)
+	}
+}
+
+func TestAddressFilter_Allowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowlist []string
+		denylist  []string
+		address   string
+		expected  bool
+	}{
+		{name: "no lists", address: "192.168.1.10", expected: true},
+		{name: "denied address", denylist: []string{"192.168.1.10"}, address: "192.168.1.10", expected: false},
+		{name: "denied network", denylist: []string{"192.168.1.0/28"}, address: "192.168.1.10", expected: false},
+		{name: "outside denied network", denylist: []string{"192.168.1.0/28"}, address: "192.168.1.20", expected: true},
+		{name: "allowed address", allowlist: []string{"192.168.1.10"}, address: "192.168.1.10", expected: true},
+		{name: "not in allowlist", allowlist: []string{"192.168.1.10"}, address: "192.168.1.11", expected: false},
+		{name: "deny wins over allow", allowlist: []string{"192.168.1.0/24"}, denylist: []string{"192.168.1.10"}, address: "192.168.1.10", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newAddressFilter(tt.allowlist, tt.denylist)
+			if err != nil {
+				t.Fatalf("newAddressFilter failed: %v", err)
+			}
+			if result := filter.allowed(netip.MustParseAddr(tt.address)); result != tt.expected {
+				t.Errorf("Expected allowed=%v for %s, got %v", tt.expected, tt.address, result)
+			}
+		})
+	}
+}
+
+func TestParseAddressPrefixes_Invalid(t *testing.T) {
+	for _, entry := range []string{"192.168.1", "192.168.1.0/33", "miner.local"} {
+		if _, err := parseAddressPrefixes([]string{entry}); err == nil {
+			t.Errorf("Expected error for %q", entry)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/devskill-org/ems/miners"
+	"github.com/devskill-org/ems/sigenergy"
+)
+
+// measuredMinerLimit returns the total miner power allowed by the measured plant state and whether a limit applies.
+// It reacts to the measurement alone, so it also holds when the forecasts behind the plan are wrong.
+// See gridImportMinerLimit, batteryFloorMinerLimit and importCeilingMinerLimit.
+func (s *MinerScheduler) measuredMinerLimit(minersList []*miners.AvalonQHost) (float64, bool) {
+	config := s.GetConfig()
+	gridFailSafe := config.GridImportFailSafeMargin > 0 && config.MaxGridImport > 0
+	if !gridFailSafe && config.MinerBatteryMinSOC <= 0 && config.ImportPriceCeiling <= 0 {
+		return 0, false
+	}
+	info := s.GetPlantRunningInfo()
+	if info == nil {
+		return 0, false
+	}
+
+	minerPower := s.calculateTotalPowerConsumption(minersList)
+	limit, limited := 0.0, false
+	if gridFailSafe {
+		if gridLimit, exceeded := s.gridImportMinerLimit(config, info, minerPower); exceeded {
+			limit, limited = gridLimit, true
+		}
+	}
+	if batteryLimit, restricted := s.batteryFloorMinerLimit(config, info, minerPower); restricted {
+		if !limited || batteryLimit < limit {
+			limit = batteryLimit
+		}
+		limited = true
+	}
+	if ceilingLimit, exceeded := s.importCeilingMinerLimit(config, info, minerPower); exceeded {
+		if !limited || ceilingLimit < limit {
+			limit = ceilingLimit
+		}
+		limited = true
+	}
+	return limit, limited
+}
+
+// gridImportMinerLimit returns the miner power limit that brings the measured grid import back to
+// max_grid_import less grid_import_failsafe_margin, and whether the import is above that threshold
+func (s *MinerScheduler) gridImportMinerLimit(config *Config, info *sigenergy.PlantRunningInfo, minerPower float64) (float64, bool) {
+	threshold := config.MaxGridImport - config.GridImportFailSafeMargin
+	gridImport := info.GridSensorActivePower // positive = import
+	if gridImport <= threshold {
+		return 0, false
+	}
+
+	limit := max(0, minerPower-(gridImport-threshold))
+	s.logger.Printf("ALERT: grid import %.2f kW above %.2f kW (max_grid_import %.2f kW), throttling miners from %.2f kW to %.2f kW",
+		gridImport, threshold, config.MaxGridImport, minerPower, limit)
+	return limit, true
+}
+
+// batteryFloorMinerLimit returns the miner power that can be supplied without the battery while its SOC is below
+// miner_battery_min_soc, and whether the SOC is below it. The battery is kept for the household: while it
+// discharges miners give up the discharged power, otherwise they may use the exported solar power and the grid
+// import left below max_grid_import on top of their current power. Should the inverter serve the new load from
+// the battery, the miners give it up at the next check.
+func (s *MinerScheduler) batteryFloorMinerLimit(config *Config, info *sigenergy.PlantRunningInfo, minerPower float64) (float64, bool) {
+	if config.MinerBatteryMinSOC <= 0 || info.ESSSOC/100 >= config.MinerBatteryMinSOC {
+		return 0, false
+	}
+
+	var limit float64
+	if discharge := -info.ESSPower; discharge > 0 {
+		limit = max(0, minerPower-discharge)
+	} else {
+		// positive = import, negative = export
+		gridHeadroom := max(0, config.MaxGridImport-max(0, info.GridSensorActivePower))
+		limit = minerPower + max(0, -info.GridSensorActivePower) + gridHeadroom
+	}
+	s.logger.Printf("Battery SOC %.1f%% below miner_battery_min_soc %.1f%%, miners limited to their power, the solar surplus and the grid headroom: %.2f kW",
+		info.ESSSOC, config.MinerBatteryMinSOC*100, limit)
+	return limit, true
+}
+
+// importCeilingMinerLimit returns the miner power that can be supplied without importing from the grid while the
+// current import price is above import_price_ceiling, and whether it is above it. Miners give up the measured
+// import and may use the exported power on top of their current power, so they only run on solar and battery.
+func (s *MinerScheduler) importCeilingMinerLimit(config *Config, info *sigenergy.PlantRunningInfo, minerPower float64) (float64, bool) {
+	importPrice, above := s.importPriceAboveCeiling(config, s.now())
+	if !above {
+		return 0, false
+	}
+
+	limit := max(0, minerPower-info.GridSensorActivePower) // positive = import, negative = export
+	s.logger.Printf("Import price %.2f EUR/MWh above import_price_ceiling %.2f EUR/MWh, miners limited to solar and battery: %.2f kW",
+		importPrice, config.ImportPriceCeiling, limit)
+	return limit, true
+}
+
+// importPriceAboveCeiling returns the adjusted import price at t in EUR/MWh and whether it is above
+// import_price_ceiling. It is false when the ceiling is disabled or no price covers t.
+func (s *MinerScheduler) importPriceAboveCeiling(config *Config, t time.Time) (float64, bool) {
+	if config.ImportPriceCeiling <= 0 {
+		return 0, false
+	}
+	doc := s.GetPricesMarketData()
+	if doc == nil {
+		return 0, false
+	}
+	spotPrice, found := doc.LookupPriceByTime(t)
+	if !found {
+		return 0, false
+	}
+	importPrice, _ := AdjustedPrices(spotPrice, config)
+	return importPrice * 1000, importPrice*1000 > config.ImportPriceCeiling
+}
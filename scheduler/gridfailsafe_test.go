@@ -0,0 +1,270 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/miners"
+	"github.com/devskill-org/ems/sigenergy"
+)
+
+// measuredLimitConfig returns a dry-run config for two miners in standard mode drawing 3.2 kW together
+func measuredLimitConfig() *Config {
+	config := testConfig()
+	config.DryRun = true
+	config.FanRHighThreshold = 80
+	config.FanRLowThreshold = 50
+	config.MinerPowerStandby = 0.05
+	config.MinerPowerEco = 0.8
+	config.MinerPowerStandard = 1.6
+	config.MinersPowerLimit = 30
+	config.MaxGridImport = 20
+	return config
+}
+
+// runMeasuredLimitStateCheck runs a state check of two miners in standard mode with FanR 71%, between the
+// thresholds so the fan alone changes nothing, with the given measured plant state. It returns the decisions.
+func runMeasuredLimitStateCheck(t *testing.T, config *Config, info *sigenergy.PlantRunningInfo) ([]MinerControlDecision, string) {
+	t.Helper()
+	response, err := os.ReadFile("../test_data/avalon_litestat.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data file: %v", err)
+	}
+	response = bytes.Replace(response, []byte("WORKMODE[0]"), []byte("WORKMODE[1]"), 1)
+	hosts, _ := serveCountingMiners(t, 2, response)
+
+	scheduler := newTestScheduler(config)
+	var buf bytes.Buffer
+	scheduler.logger = log.New(&buf, "", 0)
+	scheduler.plantInfoFunc = func(_ *Config) (*sigenergy.PlantRunningInfo, error) {
+		return info, nil
+	}
+	for _, host := range hosts {
+		scheduler.discoveredMiners.Store(minerKey(host), host)
+	}
+
+	if err := scheduler.runStateCheck(context.Background()); err != nil {
+		t.Fatalf("runStateCheck failed: %v", err)
+	}
+
+	decisions := make([]MinerControlDecision, 0, len(hosts))
+	for _, host := range hosts {
+		decision, ok := scheduler.GetMinerDecision(host)
+		if !ok {
+			t.Fatalf("Expected a decision for miner %s, got log:\n%s", minerKey(host), buf.String())
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions, buf.String()
+}
+
+func TestRunStateCheck_GridImportFailSafe(t *testing.T) {
+	tests := []struct {
+		name         string
+		margin       float64
+		gridImport   float64
+		expectedMode miners.AvalonWorkMode
+		expected     MinerControlReason
+	}{
+		// 0.5 kW too much import with 3.2 kW of miners: both step down to eco mode
+		{name: "import near the limit", margin: 2, gridImport: 18.5, expectedMode: miners.AvalonEcoMode, expected: ReasonPowerLimit},
+		{name: "import below the threshold", margin: 2, gridImport: 17.5, expectedMode: miners.AvalonStandardMode, expected: ReasonNoChange},
+		{name: "disabled", margin: 0, gridImport: 19.5, expectedMode: miners.AvalonStandardMode, expected: ReasonNoChange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := measuredLimitConfig()
+			config.GridImportFailSafeMargin = tt.margin
+			// The plan expects no import, the measurement shows otherwise
+			decisions, logs := runMeasuredLimitStateCheck(t, config, &sigenergy.PlantRunningInfo{GridSensorActivePower: tt.gridImport})
+
+			for _, decision := range decisions {
+				if decision.WorkMode != tt.expectedMode || decision.State != miners.AvalonStateMining || decision.Reason != tt.expected {
+					t.Errorf("Expected %s mode (%s), got %+v", tt.expectedMode, tt.expected, decision)
+				}
+			}
+			if alerted := strings.Contains(logs, "ALERT: grid import 18.50 kW above 18.00 kW"); alerted != (tt.expected == ReasonPowerLimit) {
+				t.Errorf("Expected the fail-safe alert %v, got log:\n%s", !alerted, logs)
+			}
+		})
+	}
+}
+
+func TestRunStateCheck_MinerBatteryMinSOC(t *testing.T) {
+	tests := []struct {
+		name          string
+		soc           float64
+		essPower      float64
+		expectedState miners.AvalonState
+		expected      MinerControlReason
+	}{
+		// 2 kW of the 3.2 kW come from the battery: eco mode would still need 0.4 kW of it
+		{name: "low SOC", soc: 15, essPower: -2, expectedState: miners.AvalonStateStandBy, expected: ReasonPowerLimit},
+		{name: "high SOC", soc: 80, essPower: -2, expectedState: miners.AvalonStateMining, expected: ReasonNoChange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := measuredLimitConfig()
+			config.MinerBatteryMinSOC = 0.3
+			info := &sigenergy.PlantRunningInfo{ESSSOC: tt.soc, ESSPower: tt.essPower, GridSensorActivePower: 1.2}
+			decisions, logs := runMeasuredLimitStateCheck(t, config, info)
+
+			for _, decision := range decisions {
+				if decision.State != tt.expectedState || decision.Reason != tt.expected {
+					t.Errorf("Expected %s (%s), got %+v", tt.expectedState, tt.expected, decision)
+				}
+			}
+			if restricted := strings.Contains(logs, "below miner_battery_min_soc"); restricted != (tt.soc < 30) {
+				t.Errorf("Expected the SOC restriction %v, got log:\n%s", tt.soc < 30, logs)
+			}
+		})
+	}
+}
+
+func TestManageMiners_MinerBatteryMinSOCGridHeadroom(t *testing.T) {
+	response, err := os.ReadFile("../test_data/avalon_litestat.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data file: %v", err)
+	}
+	response = bytes.Replace(response, []byte("STATE[1]"), []byte("STATE[2]"), 1)
+
+	tests := []struct {
+		name       string
+		gridImport float64
+		woken      int
+	}{
+		// 18.8 kW left below max_grid_import supply both miners in eco mode without the battery
+		{name: "grid headroom", gridImport: 1.2, woken: 2},
+		{name: "importing at max_grid_import", gridImport: 20, woken: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts, _ := serveCountingMiners(t, 2, response)
+			config := measuredLimitConfig()
+			config.MinerBatteryMinSOC = 0.3
+			scheduler := newTestScheduler(config)
+			var buf bytes.Buffer
+			scheduler.logger = log.New(&buf, "", 0)
+			scheduler.plantInfoFunc = func(_ *Config) (*sigenergy.PlantRunningInfo, error) {
+				return &sigenergy.PlantRunningInfo{ESSSOC: 15, GridSensorActivePower: tt.gridImport}, nil
+			}
+			for _, host := range hosts {
+				scheduler.discoveredMiners.Store(minerKey(host), host)
+			}
+
+			// The price allows mining, only the battery floor limits the miners
+			if err := scheduler.manageMiners(context.Background(), 10, false); err != nil {
+				t.Fatalf("manageMiners failed: %v", err)
+			}
+			if woken := strings.Count(buf.String(), "DRY-RUN: Would wake up miner"); woken != tt.woken {
+				t.Errorf("Expected %d miners woken at low SOC, got %d:\n%s", tt.woken, woken, buf.String())
+			}
+		})
+	}
+}
+
+func TestBatteryFloorMinerLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		info       sigenergy.PlantRunningInfo
+		expected   float64
+		restricted bool
+	}{
+		{name: "SOC above the floor", info: sigenergy.PlantRunningInfo{ESSSOC: 50, ESSPower: -3}, restricted: false},
+		{name: "battery discharging", info: sigenergy.PlantRunningInfo{ESSSOC: 20, ESSPower: -1.5}, expected: 2.5, restricted: true},
+		{name: "discharge above the miner power", info: sigenergy.PlantRunningInfo{ESSSOC: 20, ESSPower: -6}, expected: 0, restricted: true},
+		{name: "solar exported", info: sigenergy.PlantRunningInfo{ESSSOC: 20, ESSPower: 1, GridSensorActivePower: -2}, expected: 4 + 2 + 10, restricted: true},
+		{name: "importing", info: sigenergy.PlantRunningInfo{ESSSOC: 20, GridSensorActivePower: 7}, expected: 4 + 3, restricted: true},
+		{name: "importing at max_grid_import", info: sigenergy.PlantRunningInfo{ESSSOC: 20, GridSensorActivePower: 11}, expected: 4, restricted: true},
+		{name: "idle battery without export", info: sigenergy.PlantRunningInfo{ESSSOC: 20}, expected: 4 + 10, restricted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := measuredLimitConfig()
+			config.MaxGridImport = 10
+			config.MinerBatteryMinSOC = 0.3
+			scheduler := newTestScheduler(config)
+			scheduler.logger = log.New(&bytes.Buffer{}, "", 0)
+
+			limit, restricted := scheduler.batteryFloorMinerLimit(config, &tt.info, 4)
+			if restricted != tt.restricted || math.Abs(limit-tt.expected) > 1e-9 {
+				t.Errorf("Expected limit %.2f kW (restricted %v), got %.2f kW (%v)", tt.expected, tt.restricted, limit, restricted)
+			}
+		})
+	}
+}
+
+func TestImportCeilingMinerLimit(t *testing.T) {
+	// 50 EUR/MWh all day except an extreme 400 EUR/MWh at 18:00
+	marketData, location := priceDays(t, 1, func(_, hour int) float64 {
+		if hour == 18 {
+			return 400
+		}
+		return 50
+	})
+
+	tests := []struct {
+		name       string
+		ceiling    float64
+		hour       int
+		gridPower  float64
+		expected   float64
+		restricted bool
+	}{
+		{name: "disabled", ceiling: 0, hour: 18, gridPower: 3, restricted: false},
+		{name: "price below the ceiling", ceiling: 300, hour: 12, gridPower: 3, restricted: false},
+		{name: "importing above the ceiling", ceiling: 300, hour: 18, gridPower: 3, expected: 1, restricted: true},
+		{name: "import above the miner power", ceiling: 300, hour: 18, gridPower: 6, expected: 0, restricted: true},
+		{name: "exporting above the ceiling", ceiling: 300, hour: 18, gridPower: -2, expected: 6, restricted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := measuredLimitConfig()
+			config.ImportPriceCeiling = tt.ceiling
+			scheduler := newTestScheduler(config)
+			scheduler.logger = log.New(&bytes.Buffer{}, "", 0)
+			scheduler.pricesMarketData = marketData
+			scheduler.setClock(&simulatedClock{now: time.Date(2024, 6, 10, tt.hour, 30, 0, 0, location)})
+
+			info := &sigenergy.PlantRunningInfo{GridSensorActivePower: tt.gridPower}
+			limit, restricted := scheduler.importCeilingMinerLimit(config, info, 4)
+			if restricted != tt.restricted || math.Abs(limit-tt.expected) > 1e-9 {
+				t.Errorf("Expected limit %.2f kW (restricted %v), got %.2f kW (%v)", tt.expected, tt.restricted, limit, restricted)
+			}
+		})
+	}
+}
+
+func TestEstimateLoadForecast_ImportPriceCeiling(t *testing.T) {
+	config := measuredLimitConfig()
+	config.PriceLimit = 1000 // miners would run at any price
+	config.ImportPriceCeiling = 300
+	scheduler := newTestScheduler(config)
+	for i := range 4 {
+		host := &miners.AvalonQHost{Address: "192.168.1.100", Port: 4028 + i}
+		scheduler.discoveredMiners.Store(minerKey(host), host)
+	}
+
+	// Below the ceiling all miners may draw from the grid
+	if load := scheduler.estimateLoadForecast(200, config.PriceLimit/1000, 0, config); math.Abs(load-4*config.MinerPowerSuper) > 1e-9 {
+		t.Errorf("Expected all miners running below the ceiling, got %.2f kW", load)
+	}
+	// Above it they only run on the solar forecast, without solar only the standby power is imported
+	if load := scheduler.estimateLoadForecast(450, config.PriceLimit/1000, 0, config); math.Abs(load-4*config.MinerPowerStandby) > 1e-9 {
+		t.Errorf("Expected only the standby power above the ceiling without solar, got %.2f kW", load)
+	}
+	if load := scheduler.estimateLoadForecast(450, config.PriceLimit/1000, 2, config); math.Abs(load-(2*config.MinerPowerEco+2*config.MinerPowerStandby)) > 1e-9 {
+		t.Errorf("Expected the miners limited to the 2 kW solar forecast above the ceiling, got %.2f kW", load)
+	}
+}
@@ -0,0 +1,114 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/devskill-org/ems/miners"
+)
+
+// HashrateStatus reports the total fleet hashrate against its recent baseline
+type HashrateStatus struct {
+	TotalGHS    float64   `json:"total_ghs"`              // GH/s sum of GHSavg of the miners expected to hash
+	BaselineGHS float64   `json:"baseline_ghs,omitempty"` // GH/s mean total over the baseline window, 0 until known
+	Miners      int       `json:"miners"`                 // Miners expected to hash, mining or unreachable
+	Dropped     bool      `json:"dropped"`                // Total below hashrate_drop_threshold of the baseline
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// hashrateSample is the fleet hashrate seen by one state check
+type hashrateSample struct {
+	at       time.Time
+	totalGHS float64
+	miners   int
+}
+
+// hashrateMonitor keeps the recent fleet hashrate to detect unexpected drops. Only samples taken with the
+// same number of miners expected to hash form the baseline, so miners put into standby on purpose by price
+// or power control do not look like a drop. Samples taken during a drop are left out of the baseline until
+// the healthy ones age out of the window, a lasting drop then becomes the new baseline.
+type hashrateMonitor struct {
+	mu      sync.Mutex
+	samples []hashrateSample // oldest first
+	status  *HashrateStatus
+}
+
+// observe records the fleet hashrate at now and compares it with the mean of the healthy samples within window
+// taken with the same number of expected miners. It reports whether a drop started or ended with this sample.
+func (m *hashrateMonitor) observe(now time.Time, totalGHS float64, expectedMiners int, window time.Duration, threshold float64) (status HashrateStatus, started, ended bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.samples[:0]
+	baseline, n := 0.0, 0
+	for _, sample := range m.samples {
+		if !sample.at.After(now.Add(-window)) {
+			continue
+		}
+		kept = append(kept, sample)
+		if sample.miners == expectedMiners {
+			baseline += sample.totalGHS
+			n++
+		}
+	}
+	m.samples = kept
+
+	wasDropped := m.status != nil && m.status.Dropped
+	status = HashrateStatus{TotalGHS: totalGHS, Miners: expectedMiners, UpdatedAt: now}
+	if n > 0 {
+		status.BaselineGHS = baseline / float64(n)
+		status.Dropped = totalGHS < threshold*status.BaselineGHS
+	}
+	if !status.Dropped {
+		m.samples = append(m.samples, hashrateSample{at: now, totalGHS: totalGHS, miners: expectedMiners})
+	}
+	m.status = &status
+	return status, status.Dropped && !wasDropped, wasDropped && !status.Dropped && n > 0
+}
+
+// current returns the latest status, nil before the first observation
+func (m *hashrateMonitor) current() *HashrateStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.status == nil {
+		return nil
+	}
+	status := *m.status
+	return &status
+}
+
+// fleetHashrate returns the total GHSavg of the miners expected to hash and their number. Miners in standby
+// are not expected to hash, unreachable miners are and contribute nothing.
+func fleetHashrate(minersList []*miners.AvalonQHost) (totalGHS float64, expectedMiners int) {
+	for _, m := range minersList {
+		if m.LastStatsError != nil || m.LastStats == nil {
+			expectedMiners++
+			continue
+		}
+		if m.LastStats.State == miners.AvalonStateStandBy {
+			continue
+		}
+		expectedMiners++
+		totalGHS += m.LastStats.GHSavg
+	}
+	return totalGHS, expectedMiners
+}
+
+// checkFleetHashrate raises an alert when the fleet hashrate falls below hashrate_drop_threshold of its
+// recent baseline, e.g. when miners throttle or go offline
+func (s *MinerScheduler) checkFleetHashrate(minersList []*miners.AvalonQHost) {
+	config := s.GetConfig()
+	if config.HashrateDropThreshold <= 0 {
+		return
+	}
+
+	totalGHS, expectedMiners := fleetHashrate(minersList)
+	status, started, ended := s.hashrate.observe(s.now(), totalGHS, expectedMiners, config.HashrateBaselineWindow, config.HashrateDropThreshold)
+	if started {
+		s.logger.Printf("ALERT: Fleet hashrate dropped to %.2f TH/s, below %.0f%% of the %v baseline %.2f TH/s (%d miners expected to hash)",
+			status.TotalGHS/1000, config.HashrateDropThreshold*100, config.HashrateBaselineWindow, status.BaselineGHS/1000, status.Miners)
+	}
+	if ended {
+		s.logger.Printf("Fleet hashrate recovered to %.2f TH/s", status.TotalGHS/1000)
+	}
+}
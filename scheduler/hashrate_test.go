@@ -0,0 +1,147 @@
+package scheduler
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/miners"
+)
+
+// hashingMiner returns a miner whose last stats report the state and the average hashrate
+func hashingMiner(state miners.AvalonState, ghsAvg float64) *miners.AvalonQHost {
+	miner := newTestMiner(50, miners.AvalonSuperMode, state, nil)
+	miner.LastStats.GHSavg = ghsAvg
+	return miner
+}
+
+func TestFleetHashrate(t *testing.T) {
+	offline := newTestMiner(50, miners.AvalonSuperMode, miners.AvalonStateMining, nil)
+	offline.LastStats = nil
+	offline.LastStatsError = errors.New("connection refused")
+
+	total, expected := fleetHashrate([]*miners.AvalonQHost{
+		hashingMiner(miners.AvalonStateMining, 9000),
+		hashingMiner(miners.AvalonStateMining, 8500),
+		hashingMiner(miners.AvalonStateStandBy, 0),
+		offline,
+	})
+	// The standby miner is not expected to hash, the unreachable one is
+	if total != 17500 || expected != 3 {
+		t.Errorf("Expected 17500 GH/s from 3 miners, got %.0f GH/s from %d", total, expected)
+	}
+}
+
+func TestHashrateMonitor_Observe(t *testing.T) {
+	start := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	window := 10 * time.Minute
+
+	steps := []struct {
+		total           float64
+		miners          int
+		expectedDropped bool
+		expectedStarted bool
+		expectedEnded   bool
+	}{
+		{total: 30000, miners: 3},
+		{total: 29000, miners: 3},
+		{total: 31000, miners: 3},
+		{total: 12000, miners: 3, expectedDropped: true, expectedStarted: true},
+		{total: 11000, miners: 3, expectedDropped: true},
+		{total: 30000, miners: 3, expectedEnded: true},
+		// Price control put two miners into standby, the lower total starts a new baseline
+		{total: 10000, miners: 1},
+		{total: 4000, miners: 1, expectedDropped: true, expectedStarted: true},
+	}
+
+	var monitor hashrateMonitor
+	for i, step := range steps {
+		now := start.Add(time.Duration(i) * time.Minute)
+		status, started, ended := monitor.observe(now, step.total, step.miners, window, 0.5)
+		if status.Dropped != step.expectedDropped || started != step.expectedStarted || ended != step.expectedEnded {
+			t.Errorf("Step %d: expected dropped %v (started %v, ended %v), got %v (started %v, ended %v) against baseline %.0f",
+				i, step.expectedDropped, step.expectedStarted, step.expectedEnded, status.Dropped, started, ended, status.BaselineGHS)
+		}
+	}
+
+	if status := monitor.current(); status == nil || status.BaselineGHS != 10000 {
+		t.Errorf("Expected the baseline of the single remaining miner, got %+v", status)
+	}
+}
+
+func TestHashrateMonitor_BaselineExpires(t *testing.T) {
+	start := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	var monitor hashrateMonitor
+
+	monitor.observe(start, 30000, 3, time.Hour, 0.5)
+	if status, started, _ := monitor.observe(start.Add(time.Minute), 10000, 3, time.Hour, 0.5); !started || !status.Dropped {
+		t.Fatalf("Expected a drop against the baseline, got %+v", status)
+	}
+
+	// Once the healthy samples leave the window the lasting lower hashrate becomes the baseline, without a recovery
+	status, started, ended := monitor.observe(start.Add(2*time.Hour), 10000, 3, time.Hour, 0.5)
+	if status.Dropped || started || ended || status.BaselineGHS != 0 {
+		t.Errorf("Expected the baseline to restart, got %+v (started %v, ended %v)", status, started, ended)
+	}
+}
+
+func TestCheckFleetHashrate_Alert(t *testing.T) {
+	config := testConfig()
+	config.HashrateDropThreshold = 0.6
+	config.HashrateBaselineWindow = time.Hour
+	scheduler := newTestScheduler(config)
+	var buf bytes.Buffer
+	scheduler.logger = log.New(&buf, "", 0)
+	clock := &simulatedClock{now: time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)}
+	scheduler.setClock(clock)
+
+	fleet := []*miners.AvalonQHost{
+		hashingMiner(miners.AvalonStateMining, 9000),
+		hashingMiner(miners.AvalonStateMining, 9000),
+		hashingMiner(miners.AvalonStateMining, 9000),
+	}
+	for range 5 {
+		scheduler.checkFleetHashrate(fleet)
+		clock.Set(clock.Now().Add(time.Minute))
+	}
+	if strings.Contains(buf.String(), "ALERT") {
+		t.Fatalf("Expected no alert at a steady hashrate, got log:\n%s", buf.String())
+	}
+
+	// Two miners throttle hard, the fleet drops to 40% of its baseline
+	fleet[0].LastStats.GHSavg = 1800
+	fleet[1].LastStats.GHSavg = 0
+	scheduler.checkFleetHashrate(fleet)
+	if !strings.Contains(buf.String(), "ALERT: Fleet hashrate dropped to 10.80 TH/s, below 60% of the 1h0m0s baseline 27.00 TH/s") {
+		t.Errorf("Expected a hashrate drop alert, got log:\n%s", buf.String())
+	}
+	if status := scheduler.GetStatus().Hashrate; status == nil || !status.Dropped || status.TotalGHS != 10800 {
+		t.Errorf("Expected the status to report the drop, got %+v", status)
+	}
+
+	// The alert is raised once per drop
+	clock.Set(clock.Now().Add(time.Minute))
+	scheduler.checkFleetHashrate(fleet)
+	if count := strings.Count(buf.String(), "ALERT: Fleet hashrate dropped"); count != 1 {
+		t.Errorf("Expected a single alert, got %d:\n%s", count, buf.String())
+	}
+
+	fleet[0].LastStats.GHSavg = 9000
+	fleet[1].LastStats.GHSavg = 9000
+	clock.Set(clock.Now().Add(time.Minute))
+	scheduler.checkFleetHashrate(fleet)
+	if !strings.Contains(buf.String(), "Fleet hashrate recovered to 27.00 TH/s") {
+		t.Errorf("Expected the recovery to be logged, got log:\n%s", buf.String())
+	}
+}
+
+func TestCheckFleetHashrate_Disabled(t *testing.T) {
+	scheduler := newTestScheduler(testConfig())
+	scheduler.checkFleetHashrate([]*miners.AvalonQHost{hashingMiner(miners.AvalonStateMining, 9000)})
+	if status := scheduler.GetStatus().Hashrate; status != nil {
+		t.Errorf("Expected no hashrate status when disabled, got %+v", status)
+	}
+}
@@ -0,0 +1,163 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/devskill-org/ems/meteo"
+)
+
+// kpiDateLayout is the format of KPI dates, a day in the configured location
+const kpiDateLayout = "2006-01-02"
+
+// EnergyKPI is the realized self-sufficiency of the plant over one day
+type EnergyKPI struct {
+	Date                 string   `json:"date"`
+	SolarKWh             float64  `json:"solar_kwh"`
+	ConsumptionKWh       float64  `json:"consumption_kwh"` // Load including EV charging
+	GridImportKWh        float64  `json:"grid_import_kwh"`
+	GridExportKWh        float64  `json:"grid_export_kwh"`
+	SelfSufficiency      *float64 `json:"self_sufficiency"`       // % of the consumption not imported from the grid, null without consumption
+	SelfConsumptionRatio *float64 `json:"self_consumption_ratio"` // % of the solar energy not exported, null without solar
+}
+
+// energyFlowRow is the energy of one metrics row in kWh
+type energyFlowRow struct {
+	solar      float64
+	load       float64
+	evCharge   float64
+	gridImport float64
+	gridExport float64
+}
+
+// selectEnergyFlowSQL selects the energy flow of the raw and daily metrics rows within a day
+const selectEnergyFlowSQL = `
+	SELECT
+		COALESCE(pv_total_power, 0), COALESCE(load_power, 0), COALESCE(evdc_charge_power, 0),
+		COALESCE(grid_import_power, 0), COALESCE(grid_export_power, 0)
+	FROM metrics
+	WHERE metric_name IN ($1, $2) AND timestamp >= $3 AND timestamp < $4`
+
+// storeEnergyKPISQL stores the KPIs of a day, replacing the KPIs stored for it earlier
+const storeEnergyKPISQL = `
+	INSERT INTO kpi_daily (
+		date, solar_kwh, consumption_kwh, grid_import_kwh, grid_export_kwh,
+		self_sufficiency, self_consumption_ratio
+	) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (date) DO UPDATE SET
+		solar_kwh = EXCLUDED.solar_kwh,
+		consumption_kwh = EXCLUDED.consumption_kwh,
+		grid_import_kwh = EXCLUDED.grid_import_kwh,
+		grid_export_kwh = EXCLUDED.grid_export_kwh,
+		self_sufficiency = EXCLUDED.self_sufficiency,
+		self_consumption_ratio = EXCLUDED.self_consumption_ratio`
+
+// computeEnergyKPI sums the energy flow rows of a day into its KPIs. Self-sufficiency is the share of the
+// consumption not imported, the self-consumption ratio the share of solar not exported. Energy taken from
+// or put into the battery is not separated, both are clamped to 0-100%.
+func computeEnergyKPI(date string, rows []energyFlowRow) EnergyKPI {
+	kpi := EnergyKPI{Date: date}
+	for _, row := range rows {
+		kpi.SolarKWh += row.solar
+		kpi.ConsumptionKWh += row.load + row.evCharge
+		kpi.GridImportKWh += row.gridImport
+		kpi.GridExportKWh += row.gridExport
+	}
+
+	if kpi.ConsumptionKWh > 0 {
+		kpi.SelfSufficiency = meteo.Float64Ptr(100 * clamp01(1-kpi.GridImportKWh/kpi.ConsumptionKWh))
+	}
+	if kpi.SolarKWh > 0 {
+		kpi.SelfConsumptionRatio = meteo.Float64Ptr(100 * clamp01(1-kpi.GridExportKWh/kpi.SolarKWh))
+	}
+	return kpi
+}
+
+// clamp01 limits a fraction to the range 0 to 1
+func clamp01(fraction float64) float64 {
+	return min(max(fraction, 0), 1)
+}
+
+// queryEnergyKPI computes the KPIs of the day starting at dayStart from its metrics rows
+func queryEnergyKPI(ctx context.Context, db *sql.DB, dayStart time.Time) (EnergyKPI, error) {
+	rows, err := db.QueryContext(ctx, selectEnergyFlowSQL, metricEnergyFlow, metricEnergyFlowDaily,
+		dayStart, dayStart.AddDate(0, 0, 1))
+	if err != nil {
+		return EnergyKPI{}, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer rows.Close()
+
+	var flows []energyFlowRow
+	for rows.Next() {
+		var row energyFlowRow
+		if err := rows.Scan(&row.solar, &row.load, &row.evCharge, &row.gridImport, &row.gridExport); err != nil {
+			return EnergyKPI{}, fmt.Errorf("failed to scan metrics: %w", err)
+		}
+		flows = append(flows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return EnergyKPI{}, fmt.Errorf("failed to read metrics: %w", err)
+	}
+	return computeEnergyKPI(dayStart.Format(kpiDateLayout), flows), nil
+}
+
+// GetEnergyKPI returns the KPIs of a day given as YYYY-MM-DD in the configured location, computed from the
+// metrics stored so far, so the current day is included up to the last integration period
+func (s *MinerScheduler) GetEnergyKPI(ctx context.Context, date string) (EnergyKPI, error) {
+	dayStart, err := time.ParseInLocation(kpiDateLayout, date, solarProfileLocation(s.GetConfig()))
+	if err != nil {
+		return EnergyKPI{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD: %w", date, err)
+	}
+	if s.db == nil {
+		return EnergyKPI{}, fmt.Errorf("database not available")
+	}
+	return queryEnergyKPI(ctx, s.db, dayStart)
+}
+
+// storeDailyKPI computes the KPIs of the day before now in the configured location and stores them in
+// kpi_daily. Storing a day again replaces its KPIs, so the job is safe to re-run.
+func (s *MinerScheduler) storeDailyKPI(ctx context.Context, db *sql.DB, now time.Time) error {
+	local := now.In(solarProfileLocation(s.GetConfig()))
+	yesterday := time.Date(local.Year(), local.Month(), local.Day()-1, 0, 0, 0, 0, local.Location())
+
+	kpi, err := queryEnergyKPI(ctx, db, yesterday)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, storeEnergyKPISQL, kpi.Date, kpi.SolarKWh, kpi.ConsumptionKWh,
+		kpi.GridImportKWh, kpi.GridExportKWh, kpi.SelfSufficiency, kpi.SelfConsumptionRatio); err != nil {
+		return fmt.Errorf("failed to store daily KPI: %w", err)
+	}
+
+	s.logger.Printf("Daily KPI %s: solar %.2f kWh, consumption %.2f kWh, self-sufficiency %s, self-consumption %s",
+		kpi.Date, kpi.SolarKWh, kpi.ConsumptionKWh, formatPercent(kpi.SelfSufficiency), formatPercent(kpi.SelfConsumptionRatio))
+	return nil
+}
+
+// formatPercent formats an optional percentage for the log
+func formatPercent(percent *float64) string {
+	if percent == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", *percent)
+}
+
+// runDailyKPI executes the daily KPI job as a scheduled task
+func (s *MinerScheduler) runDailyKPI(ctx context.Context, db *sql.DB) error {
+	if db == nil {
+		return nil
+	}
+
+	if s.GetConfig().DryRun {
+		s.logger.Printf("Daily KPI [DRY-RUN]: would store the KPIs of yesterday")
+		return nil
+	}
+
+	if err := s.storeDailyKPI(ctx, db, s.now()); err != nil {
+		s.logger.Printf("Daily KPI: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/meteo"
+)
+
+func TestComputeEnergyKPI(t *testing.T) {
+	// A sunny day: night import, midday surplus exported, evening battery discharge
+	sunnyDay := []energyFlowRow{
+		{load: 2, gridImport: 2},
+		{solar: 1, load: 1.5, gridImport: 0.5},
+		{solar: 6, load: 2, evCharge: 1, gridExport: 2},
+		{solar: 3, load: 2, gridExport: 1},
+		{load: 1.5, gridImport: 0.5},
+	}
+
+	tests := []struct {
+		name                    string
+		rows                    []energyFlowRow
+		expectedSolar           float64
+		expectedConsumption     float64
+		expectedSelfSufficiency *float64
+		expectedSelfConsumption *float64
+	}{
+		{
+			name:                    "sunny day",
+			rows:                    sunnyDay,
+			expectedSolar:           10,
+			expectedConsumption:     10,
+			expectedSelfSufficiency: meteo.Float64Ptr(70.0), // 3 of 10 kWh imported
+			expectedSelfConsumption: meteo.Float64Ptr(70.0), // 3 of 10 kWh exported
+		},
+		{
+			name:                    "no solar",
+			rows:                    []energyFlowRow{{load: 3, gridImport: 3}, {load: 1, gridImport: 0.5}},
+			expectedConsumption:     4,
+			expectedSelfSufficiency: meteo.Float64Ptr(12.5),
+		},
+		{
+			name:                    "no load",
+			rows:                    []energyFlowRow{{solar: 4, gridExport: 4}},
+			expectedSolar:           4,
+			expectedSelfConsumption: meteo.Float64Ptr(0.0),
+		},
+		{
+			name:                    "grid charging the battery",
+			rows:                    []energyFlowRow{{load: 2, gridImport: 5}},
+			expectedConsumption:     2,
+			expectedSelfSufficiency: meteo.Float64Ptr(0.0),
+		},
+		{
+			name: "no rows",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kpi := computeEnergyKPI("2024-06-15", tt.rows)
+			if kpi.Date != "2024-06-15" {
+				t.Errorf("Expected date 2024-06-15, got %s", kpi.Date)
+			}
+			if math.Abs(kpi.SolarKWh-tt.expectedSolar) > 1e-9 {
+				t.Errorf("Expected solar %.2f kWh, got %.2f", tt.expectedSolar, kpi.SolarKWh)
+			}
+			if math.Abs(kpi.ConsumptionKWh-tt.expectedConsumption) > 1e-9 {
+				t.Errorf("Expected consumption %.2f kWh, got %.2f", tt.expectedConsumption, kpi.ConsumptionKWh)
+			}
+			checkPercent(t, "self-sufficiency", tt.expectedSelfSufficiency, kpi.SelfSufficiency)
+			checkPercent(t, "self-consumption ratio", tt.expectedSelfConsumption, kpi.SelfConsumptionRatio)
+		})
+	}
+}
+
+// checkPercent compares an optional percentage, nil means the KPI is undefined
+func checkPercent(t *testing.T, name string, expected, actual *float64) {
+	t.Helper()
+	if expected == nil || actual == nil {
+		if expected != actual {
+			t.Errorf("Expected %s %s, got %s", name, formatPercent(expected), formatPercent(actual))
+		}
+		return
+	}
+	if math.Abs(*expected-*actual) > 1e-9 {
+		t.Errorf("Expected %s %.2f%%, got %.2f%%", name, *expected, *actual)
+	}
+}
+
+func TestStoreDailyKPI(t *testing.T) {
+	db, rec := newRecordingDB(t)
+
+	config := testConfig()
+	config.Location = "Europe/Riga"
+	scheduler := NewMinerScheduler(config, log.New(os.Stdout, "TEST: ", log.LstdFlags))
+
+	// 01:30 UTC is 03:30 in Riga, the day before in Riga is stored
+	now := time.Date(2024, 3, 15, 1, 30, 0, 0, time.UTC)
+	if err := scheduler.storeDailyKPI(context.Background(), db, now); err != nil {
+		t.Fatalf("storeDailyKPI failed: %v", err)
+	}
+
+	if len(rec.execs) != 1 || rec.execs[0].query != storeEnergyKPISQL {
+		t.Fatalf("Expected the KPI to be stored, got %+v", rec.execs)
+	}
+	args := rec.execs[0].args
+	if args[0] != "2024-03-14" {
+		t.Errorf("Expected the KPI of 2024-03-14, got %v", args[0])
+	}
+	// The recording database has no metrics rows: the KPIs are undefined and stored as NULL
+	if args[5] != nil || args[6] != nil {
+		t.Errorf("Expected NULL KPIs without metrics, got %v and %v", args[5], args[6])
+	}
+}
+
+func TestRunDailyKPI_DryRun(t *testing.T) {
+	db, rec := newRecordingDB(t)
+
+	config := testConfig()
+	config.DryRun = true
+	scheduler := NewMinerScheduler(config, log.New(os.Stdout, "TEST: ", log.LstdFlags))
+
+	if err := scheduler.runDailyKPI(context.Background(), db); err != nil {
+		t.Fatalf("runDailyKPI failed: %v", err)
+	}
+	if len(rec.execs) != 0 {
+		t.Errorf("Expected no statements in dry-run mode, got %d", len(rec.execs))
+	}
+}
+
+func TestKPIHandler(t *testing.T) {
+	db, _ := newRecordingDB(t)
+
+	tests := []struct {
+		name         string
+		query        string
+		db           *sql.DB
+		expectedCode int
+	}{
+		{name: "date", query: "?date=2024-06-15", db: db, expectedCode: http.StatusOK},
+		{name: "default yesterday", db: db, expectedCode: http.StatusOK},
+		{name: "invalid date", query: "?date=15.06.2024", db: db, expectedCode: http.StatusBadRequest},
+		{name: "no database", query: "?date=2024-06-15", expectedCode: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := newTestScheduler(testConfig())
+			scheduler.db = tt.db
+			hs := &WebServer{scheduler: scheduler}
+
+			rec := httptest.NewRecorder()
+			hs.kpiHandler(rec, httptest.NewRequest(http.MethodGet, "/api/kpi"+tt.query, nil))
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedCode, rec.Code, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var kpi EnergyKPI
+			if err := json.Unmarshal(rec.Body.Bytes(), &kpi); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if tt.query != "" && kpi.Date != "2024-06-15" {
+				t.Errorf("Expected the KPI of 2024-06-15, got %s", kpi.Date)
+			}
+		})
+	}
+}
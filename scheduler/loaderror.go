@@ -0,0 +1,157 @@
+package scheduler
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/devskill-org/ems/mpc"
+)
+
+// loadErrorWindow is the number of integration periods the load error metrics are computed over
+const loadErrorWindow = 96
+
+// loadErrorMinSamples is the number of periods needed before the bias is used to correct load estimates
+const loadErrorMinSamples = 4
+
+// loadPredictionRetention is how long predictions of past slots are kept to be paired with measured load
+const loadPredictionRetention = 2 * time.Hour
+
+// LoadErrorStats summarizes the error of the load estimate against the measured load
+type LoadErrorStats struct {
+	Samples int     `json:"samples"` // Number of paired integration periods
+	Bias    float64 `json:"bias"`    // kW mean of actual - predicted, positive when the estimate is too low
+	RMSE    float64 `json:"rmse"`    // kW root mean square error
+}
+
+// loadPrediction is the uncorrected load estimate the MPC planned with for one time slot
+type loadPrediction struct {
+	start time.Time
+	end   time.Time
+	load  float64 // kW
+}
+
+// loadErrorTracker pairs the load estimated for MPC with the measured load and keeps a rolling window of the pairs
+type loadErrorTracker struct {
+	mu          sync.Mutex
+	predictions []loadPrediction
+	predicted   []float64 // kW, oldest first
+	actual      []float64 // kW, oldest first
+}
+
+// recordPlan stores the load estimates of a new MPC plan. The correction applied when the plan was built
+// is removed so the metrics describe the estimate itself. Predictions of slots before the new plan are
+// kept for a while because their load is only measured once their integration period has ended.
+func (t *loadErrorTracker) recordPlan(decisions []mpc.ControlDecision, slotDuration time.Duration, correction float64, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var planStart time.Time
+	if len(decisions) > 0 {
+		planStart = time.Unix(decisions[0].Timestamp, 0)
+	}
+	kept := t.predictions[:0]
+	for _, p := range t.predictions {
+		if p.end.After(now.Add(-loadPredictionRetention)) && (planStart.IsZero() || p.start.Before(planStart)) {
+			kept = append(kept, p)
+		}
+	}
+	t.predictions = kept
+
+	for _, dec := range decisions {
+		start := time.Unix(dec.Timestamp, 0)
+		t.predictions = append(t.predictions, loadPrediction{
+			start: start,
+			end:   start.Add(slotDuration),
+			load:  dec.LoadForecast - correction,
+		})
+	}
+}
+
+// predictedLoad returns the load estimate for the slot containing at
+func (t *loadErrorTracker) predictedLoad(at time.Time) (float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// The latest plan wins when plans overlap
+	for i := len(t.predictions) - 1; i >= 0; i-- {
+		p := t.predictions[i]
+		if !at.Before(p.start) && at.Before(p.end) {
+			return p.load, true
+		}
+	}
+	return 0, false
+}
+
+// observe adds a pair of predicted and measured average load, dropping the oldest beyond the window
+func (t *loadErrorTracker) observe(predicted, actual float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.predicted = append(t.predicted, predicted)
+	t.actual = append(t.actual, actual)
+	if len(t.predicted) > loadErrorWindow {
+		t.predicted = t.predicted[len(t.predicted)-loadErrorWindow:]
+		t.actual = t.actual[len(t.actual)-loadErrorWindow:]
+	}
+}
+
+// stats returns the error metrics over the window
+func (t *loadErrorTracker) stats() LoadErrorStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return computeLoadError(t.predicted, t.actual)
+}
+
+// computeLoadError returns bias and RMSE of paired predicted and actual load series
+func computeLoadError(predicted, actual []float64) LoadErrorStats {
+	n := min(len(predicted), len(actual))
+	if n == 0 {
+		return LoadErrorStats{}
+	}
+
+	var sum, sumSquares float64
+	for i := range n {
+		diff := actual[i] - predicted[i]
+		sum += diff
+		sumSquares += diff * diff
+	}
+	return LoadErrorStats{
+		Samples: n,
+		Bias:    sum / float64(n),
+		RMSE:    math.Sqrt(sumSquares / float64(n)),
+	}
+}
+
+// GetLoadForecastError returns the error of the MPC load estimate against the measured load
+func (s *MinerScheduler) GetLoadForecastError() LoadErrorStats {
+	return s.loadError.stats()
+}
+
+// loadForecastCorrection returns the bias added to load estimates when load_forecast_bias_correction is enabled
+func (s *MinerScheduler) loadForecastCorrection(config *Config) float64 {
+	if !config.LoadForecastBiasCorrection {
+		return 0
+	}
+	stats := s.loadError.stats()
+	if stats.Samples < loadErrorMinSamples {
+		return 0
+	}
+	return stats.Bias
+}
+
+// observeLoadError pairs the measured load of an integration period with the load the MPC planned for it
+func (s *MinerScheduler) observeLoadError(data IntegratedData, pollInterval, period time.Duration) {
+	measured := time.Duration(data.sampleCount) * pollInterval
+	if measured <= 0 {
+		return
+	}
+	predicted, ok := s.loadError.predictedLoad(data.timestamp.Add(-period / 2))
+	if !ok {
+		return
+	}
+
+	// Integrated load is energy in kWh, compare it as average power over the measured time
+	actual := data.loadPower / measured.Hours()
+	s.loadError.observe(predicted, actual)
+}
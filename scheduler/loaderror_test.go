@@ -0,0 +1,154 @@
+package scheduler
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/mpc"
+)
+
+func TestComputeLoadError(t *testing.T) {
+	tests := []struct {
+		name      string
+		predicted []float64
+		actual    []float64
+		expected  LoadErrorStats
+	}{
+		{
+			name:     "no samples",
+			expected: LoadErrorStats{},
+		},
+		{
+			name:      "perfect estimate",
+			predicted: []float64{1.0, 2.0, 3.0},
+			actual:    []float64{1.0, 2.0, 3.0},
+			expected:  LoadErrorStats{Samples: 3},
+		},
+		{
+			name:      "constant underestimate",
+			predicted: []float64{1.0, 2.0, 3.0, 4.0},
+			actual:    []float64{1.5, 2.5, 3.5, 4.5},
+			expected:  LoadErrorStats{Samples: 4, Bias: 0.5, RMSE: 0.5},
+		},
+		{
+			name:      "errors cancel in bias but not in RMSE",
+			predicted: []float64{2.0, 2.0, 2.0, 2.0},
+			actual:    []float64{1.0, 3.0, 1.0, 3.0},
+			expected:  LoadErrorStats{Samples: 4, Bias: 0, RMSE: 1.0},
+		},
+		{
+			name:      "mixed errors",
+			predicted: []float64{1.0, 1.0, 1.0},
+			actual:    []float64{2.0, 1.0, 4.0},
+			// Errors 1, 0, 3: bias 4/3, RMSE sqrt(10/3)
+			expected: LoadErrorStats{Samples: 3, Bias: 4.0 / 3.0, RMSE: math.Sqrt(10.0 / 3.0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := computeLoadError(tt.predicted, tt.actual)
+			if result.Samples != tt.expected.Samples {
+				t.Errorf("Expected %d samples, got %d", tt.expected.Samples, result.Samples)
+			}
+			if math.Abs(result.Bias-tt.expected.Bias) > 1e-9 {
+				t.Errorf("Expected bias %.4f, got %.4f", tt.expected.Bias, result.Bias)
+			}
+			if math.Abs(result.RMSE-tt.expected.RMSE) > 1e-9 {
+				t.Errorf("Expected RMSE %.4f, got %.4f", tt.expected.RMSE, result.RMSE)
+			}
+		})
+	}
+}
+
+func TestObserveLoadError_PairsPlanWithMeasuredLoad(t *testing.T) {
+	scheduler := newTestScheduler(testConfig())
+	start := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	slot := 15 * time.Minute
+
+	// The plan was built with a +0.5 kW correction, the estimate itself was 1.5 kW then 2.5 kW
+	decisions := []mpc.ControlDecision{
+		{Timestamp: start.Unix(), LoadForecast: 2.0},
+		{Timestamp: start.Add(slot).Unix(), LoadForecast: 3.0},
+	}
+	scheduler.loadError.recordPlan(decisions, slot, 0.5, start)
+
+	// A newer plan starting with the second slot keeps the prediction of the first one
+	scheduler.loadError.recordPlan(decisions[1:], slot, 0.5, start.Add(slot))
+
+	// 90 samples of 10 s cover the 15 minute period, 0.6 kWh is 2.4 kW on average
+	scheduler.observeLoadError(IntegratedData{
+		loadPower:   0.6,
+		timestamp:   start.Add(slot),
+		sampleCount: 90,
+	}, 10*time.Second, slot)
+
+	// Half the period measured, 0.5 kWh over 7.5 minutes is 4 kW on average
+	scheduler.observeLoadError(IntegratedData{
+		loadPower:   0.5,
+		timestamp:   start.Add(2 * slot),
+		sampleCount: 45,
+	}, 10*time.Second, slot)
+
+	// No prediction for this period
+	scheduler.observeLoadError(IntegratedData{
+		loadPower:   1.0,
+		timestamp:   start.Add(5 * slot),
+		sampleCount: 90,
+	}, 10*time.Second, slot)
+
+	stats := scheduler.GetLoadForecastError()
+	if stats.Samples != 2 {
+		t.Fatalf("Expected 2 paired periods, got %d", stats.Samples)
+	}
+	// Errors 2.4-1.5 = 0.9 and 4.0-2.5 = 1.5
+	if math.Abs(stats.Bias-1.2) > 1e-9 {
+		t.Errorf("Expected bias 1.2 kW, got %.4f", stats.Bias)
+	}
+	if expected := math.Sqrt((0.81 + 2.25) / 2); math.Abs(stats.RMSE-expected) > 1e-9 {
+		t.Errorf("Expected RMSE %.4f kW, got %.4f", expected, stats.RMSE)
+	}
+}
+
+func TestLoadForecastCorrection(t *testing.T) {
+	config := testConfig()
+	scheduler := newTestScheduler(config)
+
+	for range loadErrorMinSamples - 1 {
+		scheduler.loadError.observe(1.0, 1.8)
+	}
+
+	config.LoadForecastBiasCorrection = true
+	if correction := scheduler.loadForecastCorrection(config); correction != 0 {
+		t.Errorf("Expected no correction before %d samples, got %.2f", loadErrorMinSamples, correction)
+	}
+
+	scheduler.loadError.observe(1.0, 1.8)
+	if correction := scheduler.loadForecastCorrection(config); math.Abs(correction-0.8) > 1e-9 {
+		t.Errorf("Expected correction 0.8 kW, got %.4f", correction)
+	}
+
+	config.LoadForecastBiasCorrection = false
+	if correction := scheduler.loadForecastCorrection(config); correction != 0 {
+		t.Errorf("Expected no correction when disabled, got %.2f", correction)
+	}
+
+	if status := scheduler.GetStatus(); status.LoadError == nil || status.LoadError.Samples != loadErrorMinSamples {
+		t.Errorf("Expected load error in status, got %+v", status.LoadError)
+	}
+}
+
+func TestLoadErrorTracker_Window(t *testing.T) {
+	var tracker loadErrorTracker
+	for range loadErrorWindow {
+		tracker.observe(0, 10)
+	}
+	for range loadErrorWindow {
+		tracker.observe(0, 1)
+	}
+
+	if stats := tracker.stats(); stats.Samples != loadErrorWindow || stats.Bias != 1 {
+		t.Errorf("Expected only the latest %d pairs with bias 1, got %+v", loadErrorWindow, stats)
+	}
+}
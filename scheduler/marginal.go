@@ -0,0 +1,28 @@
+package scheduler
+
+import (
+	"github.com/devskill-org/ems/mpc"
+)
+
+// minerPrice returns the price compared against price_limit to decide miner operation. With marginal_price_limit
+// the marginal cost of one more kW of load in the current MPC slot replaces the spot price. The cost is converted
+// back to the spot price with the same import cost, so price_limit keeps its meaning and a slot importing from
+// the grid keeps its spot price. Without a plan covering now the spot price is used.
+func (s *MinerScheduler) minerPrice(spotPrice float64) float64 {
+	if !s.config.MarginalPriceLimit {
+		return spotPrice
+	}
+
+	now := s.now().Unix()
+	slotSeconds := int64(s.config.CheckPriceInterval.Seconds())
+	for _, decision := range s.GetMPCDecisions() {
+		if now < decision.Timestamp || now >= decision.Timestamp+slotSeconds {
+			continue
+		}
+		cost := mpc.MarginalLoadCost([]mpc.ControlDecision{decision})[0]
+		price := cost*1000 - s.config.ImportPriceOperatorFee - s.config.ImportPriceDeliveryFee
+		s.logger.Printf("Marginal load cost in the MPC plan: %.4f EUR/kWh, used as price %.2f EUR/MWh", cost, price)
+		return price
+	}
+	return spotPrice
+}
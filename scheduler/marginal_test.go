@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/mpc"
+)
+
+func TestMinerPrice(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 5, 0, 0, time.UTC)
+	slotStart := now.Truncate(15 * time.Minute).Unix()
+
+	// A spot price of 70 EUR/MWh is above the limit of 50 EUR/MWh
+	spotPrice := 70.0
+	tests := []struct {
+		name     string
+		enabled  bool
+		decision mpc.ControlDecision
+		expected float64
+	}{
+		{name: "disabled", enabled: false, decision: mpc.ControlDecision{GridExport: 3}, expected: spotPrice},
+		{name: "importing keeps the spot price", enabled: true, decision: mpc.ControlDecision{GridImport: 2}, expected: spotPrice},
+		{name: "exporting solar", enabled: true, decision: mpc.ControlDecision{GridExport: 3}, expected: 70 - 17 - 48.5},
+		{name: "curtailing solar", enabled: true, decision: mpc.ControlDecision{SolarCurtailment: 3}, expected: -48.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			config.CheckPriceInterval = 15 * time.Minute
+			config.MarginalPriceLimit = tt.enabled
+			setRevenueDefaults(config)
+			config.ExportPriceOperatorFee = 17
+			scheduler := newTestScheduler(config)
+			scheduler.logger = log.New(&bytes.Buffer{}, "", 0)
+			scheduler.setClock(&simulatedClock{now: now})
+
+			decision := tt.decision
+			decision.Timestamp = slotStart
+			decision.ImportPrice, decision.ExportPrice = AdjustedPrices(spotPrice, config)
+			scheduler.mu.Lock()
+			scheduler.mpcDecisions = []mpc.ControlDecision{
+				{Timestamp: slotStart - 900, GridExport: 3},
+				decision,
+			}
+			scheduler.mu.Unlock()
+
+			if got := scheduler.minerPrice(spotPrice); math.Abs(got-tt.expected) > 1e-9 {
+				t.Errorf("Expected price %.2f EUR/MWh, got %.2f", tt.expected, got)
+			}
+		})
+	}
+
+	// Without a decision covering now the spot price is used
+	config := testConfig()
+	config.MarginalPriceLimit = true
+	scheduler := newTestScheduler(config)
+	scheduler.setClock(&simulatedClock{now: now})
+	if got := scheduler.minerPrice(spotPrice); got != spotPrice {
+		t.Errorf("Expected the spot price without a plan, got %.2f", got)
+	}
+}
@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devskill-org/ems/miners"
+)
+
+// MinerEnergyCost is the energy drawn by one miner and its cost at the import price since accounting started
+type MinerEnergyCost struct {
+	Address   string             `json:"address"`
+	Port      int                `json:"port"`
+	EnergyKWh float64            `json:"energy_kwh"`
+	Cost      float64            `json:"cost"`       // EUR at the adjusted import price, hours without a price are not counted
+	ModeHours map[string]float64 `json:"mode_hours"` // Hours spent in each work mode or standby
+	Since     time.Time          `json:"since"`
+}
+
+// minerCostSample is the power drawn by a miner at its last state check
+type minerCostSample struct {
+	at    time.Time
+	power float64 // kW
+	mode  string
+}
+
+// minerCostAccountant attributes energy and cost to each miner from the power of its mode between state checks
+type minerCostAccountant struct {
+	mu      sync.Mutex
+	samples map[string]minerCostSample
+	costs   map[string]*MinerEnergyCost
+}
+
+// observe records the power a miner draws at now and attributes the energy since its previous check, drawn at
+// the power and price of that check. Gaps longer than maxGap, e.g. while the miner was unreachable, are not counted.
+func (a *minerCostAccountant) observe(m *miners.AvalonQHost, now time.Time, power float64, mode string,
+	importPrice func(time.Time) (float64, bool), maxGap time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.samples == nil {
+		a.samples = make(map[string]minerCostSample)
+		a.costs = make(map[string]*MinerEnergyCost)
+	}
+
+	key := minerKey(m)
+	cost, ok := a.costs[key]
+	if !ok {
+		cost = &MinerEnergyCost{Address: m.Address, Port: m.Port, ModeHours: make(map[string]float64), Since: now}
+		a.costs[key] = cost
+	}
+	if prev, ok := a.samples[key]; ok {
+		if elapsed := now.Sub(prev.at); elapsed > 0 && elapsed <= maxGap {
+			hours := elapsed.Hours()
+			energy := prev.power * hours
+			cost.EnergyKWh += energy
+			cost.ModeHours[prev.mode] += hours
+			if price, found := importPrice(prev.at); found {
+				cost.Cost += energy * price
+			}
+		}
+	}
+	a.samples[key] = minerCostSample{at: now, power: power, mode: mode}
+}
+
+// forget drops the last check of a miner, the time until it is seen again is not counted
+func (a *minerCostAccountant) forget(m *miners.AvalonQHost) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.samples, minerKey(m))
+}
+
+// snapshot returns a copy of the costs of all miners sorted by address
+func (a *minerCostAccountant) snapshot() []MinerEnergyCost {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	costs := make([]MinerEnergyCost, 0, len(a.costs))
+	for _, cost := range a.costs {
+		c := *cost
+		c.ModeHours = maps.Clone(cost.ModeHours)
+		costs = append(costs, c)
+	}
+	slices.SortFunc(costs, func(a, b MinerEnergyCost) int {
+		if c := strings.Compare(a.Address, b.Address); c != 0 {
+			return c
+		}
+		return a.Port - b.Port
+	})
+	return costs
+}
+
+// minerCostMode names the mode a miner draws power in for accounting
+func minerCostMode(stats *miners.AvalonLiteStats) string {
+	if stats.State == miners.AvalonStateStandBy {
+		return "Standby"
+	}
+	return stats.WorkMode.String()
+}
+
+// accountMinerCosts attributes the energy drawn since the previous state check to each miner when
+// miner_cost_accounting is enabled
+func (s *MinerScheduler) accountMinerCosts(minersList []*miners.AvalonQHost) {
+	config := s.GetConfig()
+	if !config.MinerCostAccounting {
+		return
+	}
+
+	doc := s.GetPricesMarketData()
+	importPrice := func(t time.Time) (float64, bool) {
+		if doc == nil {
+			return 0, false
+		}
+		spotPrice, found := doc.LookupPriceByTime(t)
+		if !found {
+			return 0, false
+		}
+		price, _ := AdjustedPrices(spotPrice, config)
+		return price, true
+	}
+
+	now := s.now()
+	maxGap := 3 * config.MinersStateCheckInterval
+	for _, m := range minersList {
+		if m.LastStatsError != nil || m.LastStats == nil {
+			s.minerCosts.forget(m)
+			continue
+		}
+		power := s.getMinerPowerConsumption(m.LastStats.State, m.LastStats.WorkMode)
+		s.minerCosts.observe(m, now, power, minerCostMode(m.LastStats), importPrice, maxGap)
+	}
+}
+
+// GetMinerEnergyCosts returns the energy and cost attributed to each miner, nil when miner_cost_accounting is disabled
+func (s *MinerScheduler) GetMinerEnergyCosts() []MinerEnergyCost {
+	if !s.GetConfig().MinerCostAccounting {
+		return nil
+	}
+	return s.minerCosts.snapshot()
+}
@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/miners"
+)
+
+func TestAccountMinerCosts(t *testing.T) {
+	marketData, dayStart := spikeDay(t, 100, 300, 12)
+
+	config := testConfig()
+	config.MinerCostAccounting = true
+	config.MinersStateCheckInterval = 30 * time.Minute
+	config.MinerPowerStandby = 0.05
+	config.MinerPowerEco = 0.8
+	config.MinerPowerStandard = 1.6
+	scheduler := newTestScheduler(config)
+	clock := &simulatedClock{now: dayStart.Add(11 * time.Hour)}
+	scheduler.setClock(clock)
+	scheduler.mu.Lock()
+	scheduler.pricesMarketData = marketData
+	scheduler.mu.Unlock()
+
+	eco := newTestMiner(50, miners.AvalonEcoMode, miners.AvalonStateMining, nil)
+	eco.Address = "192.168.1.10"
+	switching := newTestMiner(50, miners.AvalonStandardMode, miners.AvalonStateMining, nil)
+	switching.Address = "192.168.1.11"
+	fleet := []*miners.AvalonQHost{switching, eco}
+
+	steps := []func(){
+		func() {}, // 11:00
+		func() {}, // 11:30
+		func() { switching.LastStats.State = miners.AvalonStateStandBy }, // 12:00
+		func() {}, // 12:30
+		func() { switching.LastStatsError = errors.New("connection refused") }, // 13:00, the half hour before is not counted
+		func() { switching.LastStatsError = nil },                              // 13:30, back after the gap
+	}
+	for _, step := range steps {
+		step()
+		scheduler.accountMinerCosts(fleet)
+		clock.Set(clock.Now().Add(30 * time.Minute))
+	}
+
+	costs := scheduler.GetMinerEnergyCosts()
+	if len(costs) != 2 {
+		t.Fatalf("Expected costs of 2 miners, got %+v", costs)
+	}
+	expected := []struct {
+		address   string
+		energy    float64
+		cost      float64
+		modeHours map[string]float64
+	}{
+		// 0.8 kW for 1.5 hours at 0.10 EUR/kWh and an hour at 0.30 EUR/kWh
+		{address: "192.168.1.10", energy: 2, cost: 0.36, modeHours: map[string]float64{"Eco": 2.5}},
+		// 1.6 kW for an hour at 0.10 EUR/kWh and 0.05 kW for half an hour at 0.30 EUR/kWh
+		{address: "192.168.1.11", energy: 1.625, cost: 0.1675, modeHours: map[string]float64{"Standard": 1, "Standby": 0.5}},
+	}
+	for i, want := range expected {
+		got := costs[i]
+		if got.Address != want.address {
+			t.Fatalf("Expected miner %d to be %s, got %s", i, want.address, got.Address)
+		}
+		if math.Abs(got.EnergyKWh-want.energy) > 1e-9 {
+			t.Errorf("%s: expected %.3f kWh, got %.3f", want.address, want.energy, got.EnergyKWh)
+		}
+		if math.Abs(got.Cost-want.cost) > 1e-9 {
+			t.Errorf("%s: expected cost %.4f, got %.4f", want.address, want.cost, got.Cost)
+		}
+		if len(got.ModeHours) != len(want.modeHours) {
+			t.Errorf("%s: expected mode hours %v, got %v", want.address, want.modeHours, got.ModeHours)
+		}
+		for mode, hours := range want.modeHours {
+			if math.Abs(got.ModeHours[mode]-hours) > 1e-9 {
+				t.Errorf("%s: expected %.1f hours in %s, got %v", want.address, hours, mode, got.ModeHours)
+			}
+		}
+		if !got.Since.Equal(dayStart.Add(11 * time.Hour)) {
+			t.Errorf("%s: expected accounting since 11:00, got %v", want.address, got.Since)
+		}
+	}
+
+	// Disabled by default
+	scheduler.config.MinerCostAccounting = false
+	if costs := scheduler.GetMinerEnergyCosts(); costs != nil {
+		t.Errorf("Expected no costs when disabled, got %+v", costs)
+	}
+}
@@ -10,22 +10,40 @@ import (
 )
 
 // discoverMiners discovers Avalon miners on the network and stores them
+// When miner_hosts is configured the network scan is skipped and the hosts are used directly
 func (s *MinerScheduler) discoverMiners(ctx context.Context) error {
-	s.logger.Printf("Discovering miners on network: %s", s.config.Network)
+	config := s.GetConfig()
 
-	// Use injected discovery function for testing, otherwise use default
 	var newlyDiscoveredMiners []*miners.AvalonQHost
-	if s.minerDiscoveryFunc != nil {
-		newlyDiscoveredMiners = s.minerDiscoveryFunc(ctx, s.config.Network)
+	if len(config.MinerHosts) > 0 {
+		s.logger.Printf("Using %d configured miner hosts, skipping network scan", len(config.MinerHosts))
+		hosts, err := configuredMinerHosts(config.MinerHosts)
+		if err != nil {
+			return err
+		}
+		newlyDiscoveredMiners = hosts
 	} else {
-		newlyDiscoveredMiners = miners.Discover(ctx, s.config.Network)
+		s.logger.Printf("Discovering miners on network: %s", config.Network)
+
+		filter, err := newAddressFilter(config.MinerAllowlist, config.MinerDenylist)
+		if err != nil {
+			return err
+		}
+
+		// Use injected discovery function for testing, otherwise use default
+		if s.minerDiscoveryFunc != nil {
+			newlyDiscoveredMiners = s.minerDiscoveryFunc(ctx, config.Network)
+		} else {
+			newlyDiscoveredMiners = miners.DiscoverFiltered(ctx, config.Network, filter.allowed)
+		}
+		// Filter the results as well, so hosts returned by an injected discovery function honour the lists too
+		newlyDiscoveredMiners = filter.filterMiners(newlyDiscoveredMiners)
 	}
 
 	// Add only new miners that don't already exist
 	newMinersCount := 0
 	for _, newMiner := range newlyDiscoveredMiners {
-		key := fmt.Sprintf("%s:%d", newMiner.Address, newMiner.Port)
-		if _, exists := s.discoveredMiners.LoadOrStore(key, newMiner); !exists {
+		if _, exists := s.discoveredMiners.LoadOrStore(minerKey(newMiner), newMiner); !exists {
 			newMinersCount++
 			s.logger.Printf("  New miner discovered: %s:%d", newMiner.Address, newMiner.Port)
 		}
@@ -39,12 +57,16 @@ func (s *MinerScheduler) discoverMiners(ctx context.Context) error {
 	})
 	s.logger.Printf("Discovery complete: %d total miners (%d newly discovered)", totalMiners, newMinersCount)
 
+	if newMinersCount > 0 {
+		s.persistDiscoveredMiners()
+	}
+
 	return nil
 }
 
 // RunMinerDiscovery runs the miner discovery process as a scheduled task
 func (s *MinerScheduler) RunMinerDiscovery(ctx context.Context) error {
-	s.logger.Printf("Starting miner discovery task at %s", time.Now().Format(time.RFC3339))
+	s.logger.Printf("Starting miner discovery task at %s", s.now().Format(time.RFC3339))
 
 	if err := s.discoverMiners(ctx); err != nil {
 		s.logger.Printf("Error discovering miners: %v", err)
@@ -85,14 +107,42 @@ func (s *MinerScheduler) calculateTotalPowerConsumption(minersList []*miners.Ava
 	return totalPower
 }
 
+// minerLimiter bounds the number of miner operations running at the same time, nil means unlimited
+type minerLimiter chan struct{}
+
+// newMinerLimiter returns a limiter for miner_control_concurrency simultaneous operations
+func (s *MinerScheduler) newMinerLimiter() minerLimiter {
+	if limit := s.GetConfig().MinerControlConcurrency; limit > 0 {
+		return make(minerLimiter, limit)
+	}
+	return nil
+}
+
+// acquire blocks until an operation may start
+func (l minerLimiter) acquire() {
+	if l != nil {
+		l <- struct{}{}
+	}
+}
+
+// release marks an operation started with acquire as finished
+func (l minerLimiter) release() {
+	if l != nil {
+		<-l
+	}
+}
+
 // refreshMinersState refreshes the state of all discovered miners and returns miners list
 func (s *MinerScheduler) refreshMinersState(ctx context.Context) []*miners.AvalonQHost {
 	var wg sync.WaitGroup
 	minersList := s.GetDiscoveredMiners()
+	limiter := s.newMinerLimiter()
 	for _, miner := range minersList {
 		wg.Add(1)
 		go func(m *miners.AvalonQHost) {
 			defer wg.Done()
+			limiter.acquire()
+			defer limiter.release()
 
 			// Get current stats
 			m.RefreshLiteStats(ctx)
@@ -119,8 +169,16 @@ func (s *MinerScheduler) getEffecivePowerLimit() float64 {
 	return effectiveLimit
 }
 
+// priceAllowsMining reports whether price-based control runs miners at currentPrice.
+// A forecast price spike puts them into standby regardless of the price limit.
+func priceAllowsMining(currentPrice, priceLimit float64, priceSpike bool) bool {
+	return currentPrice <= priceLimit && !priceSpike
+}
+
 // manageMiners manages miner states based on current price vs price limit and power consumption
-func (s *MinerScheduler) manageMiners(ctx context.Context, currentPrice float64) error {
+// During a forecast price spike miners are put into standby whatever the price limit,
+// as they are when the mining revenue model reports a negative margin
+func (s *MinerScheduler) manageMiners(ctx context.Context, currentPrice float64, priceSpike bool) error {
 	priceLimit := s.config.PriceLimit
 	minersList := s.refreshMinersState(ctx)
 
@@ -134,6 +192,17 @@ func (s *MinerScheduler) manageMiners(ctx context.Context, currentPrice float64)
 		s.logger.Printf("DRY-RUN MODE: Actions will be simulated only")
 	}
 
+	margin, hasMargin := computeMiningMargin(s.config, currentPrice)
+	if hasMargin {
+		s.logger.Printf("Mining margin at price %.2f: revenue %.3f - energy cost %.3f = %.3f EUR/h per miner",
+			currentPrice, margin.Revenue, margin.EnergyCost, margin.Margin)
+	}
+
+	// Solar that would only be exported cheaply is mined instead of putting the miners into standby
+	if handled, err := s.manageSolarSurplus(ctx, minersList, currentPrice, priceSpike); handled {
+		return err
+	}
+
 	// Check if PV power control is enabled
 	usePowerControl := s.config.UsePVPowerControl
 	var effectiveLimit float64
@@ -145,15 +214,29 @@ func (s *MinerScheduler) manageMiners(ctx context.Context, currentPrice float64)
 		s.logger.Printf("Current total power consumption: %.2f kW, Effective limit: %.2f kW", totalPower, effectiveLimit)
 	}
 
+	// Miners are not woken beyond the limits on the measured plant state
+	if limit, limited := s.measuredMinerLimit(minersList); limited {
+		if usePowerControl {
+			effectiveLimit = min(effectiveLimit, limit)
+		} else {
+			effectiveLimit = limit
+			totalPower = s.calculateTotalPowerConsumption(minersList)
+			usePowerControl = true
+		}
+	}
+
 	// Standard price-based control
 	var wg sync.WaitGroup
 	var powerMu sync.Mutex // Mutex to protect totalPower updates
 	errChan := make(chan error, len(minersList))
+	limiter := s.newMinerLimiter()
 
 	for _, miner := range minersList {
 		wg.Add(1)
 		go func(m *miners.AvalonQHost) {
 			defer wg.Done()
+			limiter.acquire()
+			defer limiter.release()
 
 			// Get current stats
 			if m.LastStatsError != nil {
@@ -164,10 +247,25 @@ func (s *MinerScheduler) manageMiners(ctx context.Context, currentPrice float64)
 			currentState := m.LastStats.State
 			s.logger.Printf("Miner %s:%d current state: %s", m.Address, m.Port, currentState.String())
 
+			if override, ok := s.getMinerOverride(m); ok {
+				s.logger.Printf("Miner %s:%d is under manual override (%s), skipping price-based control",
+					m.Address, m.Port, override.String())
+				return
+			}
+
 			// Decision logic based on price comparison
-			if currentPrice <= priceLimit {
+			if miningAllowed(s.config, currentPrice, priceSpike) {
 				// Price is low enough - wake up miners (if power allows)
 				if currentState == miners.AvalonStateStandBy {
+					if s.IsSafeMode() {
+						s.logger.Printf("Miner %s:%d stays in standby: scheduler is in safe mode", m.Address, m.Port)
+						return
+					}
+					if protection, probability := s.thunderProtectionLevel(s.now()); protection == thunderStandby {
+						s.logger.Printf("Miner %s:%d stays in standby: %.0f%% probability of thunder forecast", m.Address, m.Port, probability)
+						return
+					}
+
 					// Check if we have power budget for waking up this miner
 					if usePowerControl {
 						additionalPower := s.config.MinerPowerEco // Wake up in Eco mode
@@ -190,6 +288,9 @@ func (s *MinerScheduler) manageMiners(ctx context.Context, currentPrice float64)
 							m.Address, m.Port, currentPrice, priceLimit)
 						return
 					}
+					if s.deferMinerCommand(m) {
+						return
+					}
 					s.logger.Printf("Price (%.2f) <= limit (%.2f), waking up miner %s:%d",
 						currentPrice, priceLimit, m.Address, m.Port)
 
@@ -198,6 +299,7 @@ func (s *MinerScheduler) manageMiners(ctx context.Context, currentPrice float64)
 						errChan <- fmt.Errorf("failed to wake up miner %s:%d: %w", m.Address, m.Port, err)
 						return
 					}
+					s.recordMinerDecision(m, MinerControlDecision{State: miners.AvalonStateMining, WorkMode: miners.AvalonEcoMode, Reason: ReasonPriceBelowLimit})
 					s.logger.Printf("WakeUp response for miner %s:%d: %s", m.Address, m.Port, response)
 					// Reserve power for this miner
 					if usePowerControl {
@@ -210,20 +312,33 @@ func (s *MinerScheduler) manageMiners(ctx context.Context, currentPrice float64)
 						m.Address, m.Port, currentState.String())
 				}
 			} else {
-				// Price is too high - put active miners into standby
+				// Price is too high or spiking, or mining runs at a loss - put active miners into standby
 				if currentState != miners.AvalonStateStandBy {
+					cause := fmt.Sprintf("price %.2f > limit %.2f", currentPrice, priceLimit)
+					reason := ReasonPriceAboveLimit
+					if priceSpike {
+						cause = fmt.Sprintf("price spike forecast, current price %.2f", currentPrice)
+						reason = ReasonPriceSpike
+					} else if currentPrice <= priceLimit {
+						cause = fmt.Sprintf("mining margin %.3f EUR/h negative at price %.2f", margin.Margin, currentPrice)
+						reason = ReasonNegativeMargin
+					}
 					if isDryRun {
-						s.logger.Printf("DRY-RUN: Would put miner %s:%d into standby (price %.2f > limit %.2f)",
-							m.Address, m.Port, currentPrice, priceLimit)
+						s.logger.Printf("DRY-RUN: Would put miner %s:%d into standby (%s)",
+							m.Address, m.Port, cause)
 					} else {
-						s.logger.Printf("Price (%.2f) > limit (%.2f), putting miner %s:%d into standby",
-							currentPrice, priceLimit, m.Address, m.Port)
+						if s.deferMinerCommand(m) {
+							return
+						}
+						s.logger.Printf("Putting miner %s:%d into standby (%s)",
+							m.Address, m.Port, cause)
 
 						response, err := m.Standby(ctx)
 						if err != nil {
 							errChan <- fmt.Errorf("failed to put miner %s:%d into standby: %w", m.Address, m.Port, err)
 							return
 						}
+						s.recordMinerDecision(m, MinerControlDecision{State: miners.AvalonStateStandBy, WorkMode: m.LastStats.WorkMode, Reason: reason})
 
 						// Update totalPower after successful standby
 						if usePowerControl {
@@ -270,36 +385,284 @@ func (s *MinerScheduler) manageMiners(ctx context.Context, currentPrice float64)
 	return nil
 }
 
-// controlMiner returns a new miner state and mode
-func (s *MinerScheduler) controlMiner(m *miners.AvalonQHost, totalPower float64, effectiveLimit float64) (miners.AvalonState, miners.AvalonWorkMode) {
+// MinerOverride pins a miner to a manually selected state and work mode
+type MinerOverride struct {
+	State    miners.AvalonState
+	WorkMode miners.AvalonWorkMode
+}
+
+// String returns the string representation of the MinerOverride
+func (o MinerOverride) String() string {
+	if o.State == miners.AvalonStateStandBy {
+		return o.State.String()
+	}
+	return o.WorkMode.String()
+}
+
+// minerKey returns the key used for a miner in discoveredMiners and minerOverrides
+func minerKey(m *miners.AvalonQHost) string {
+	return fmt.Sprintf("%s:%d", m.Address, m.Port)
+}
+
+// findMiner looks up a discovered miner by "address:port" or by address only
+func (s *MinerScheduler) findMiner(addr string) *miners.AvalonQHost {
+	if value, ok := s.discoveredMiners.Load(addr); ok {
+		if miner, ok := value.(*miners.AvalonQHost); ok {
+			return miner
+		}
+	}
+	for _, miner := range s.GetDiscoveredMiners() {
+		if miner.Address == addr {
+			return miner
+		}
+	}
+	return nil
+}
+
+// getMinerOverride returns the manual override for a miner, if any
+func (s *MinerScheduler) getMinerOverride(m *miners.AvalonQHost) (MinerOverride, bool) {
+	value, ok := s.minerOverrides.Load(minerKey(m))
+	if !ok {
+		return MinerOverride{}, false
+	}
+	override, ok := value.(MinerOverride)
+	return override, ok
+}
+
+// reserveMinerCommand records a command to the miner at the current time. If the previous command was sent
+// less than miner_command_delay ago nothing is recorded and the time left until the next command is returned.
+func (s *MinerScheduler) reserveMinerCommand(m *miners.AvalonQHost) (time.Duration, bool) {
+	key := minerKey(m)
+	now := s.now()
+	for {
+		value, loaded := s.minerLastCommand.LoadOrStore(key, now)
+		if !loaded {
+			return 0, true
+		}
+		last := value.(time.Time)
+		if wait := last.Add(s.GetConfig().MinerCommandDelay).Sub(now); wait > 0 {
+			return wait, false
+		}
+		if s.minerLastCommand.CompareAndSwap(key, last, now) {
+			return 0, true
+		}
+	}
+}
+
+// deferMinerCommand returns true if a command to the miner has to wait for miner_command_delay,
+// otherwise the command is recorded as sent now. Deferred commands are retried on the next check.
+func (s *MinerScheduler) deferMinerCommand(m *miners.AvalonQHost) bool {
+	wait, ok := s.reserveMinerCommand(m)
+	if !ok {
+		s.logger.Printf("Miner %s:%d command deferred: next command allowed in %s",
+			m.Address, m.Port, wait.Round(time.Second))
+	}
+	return !ok
+}
+
+// waitMinerCommand blocks until a command may be sent to the miner and records it as sent
+func (s *MinerScheduler) waitMinerCommand(ctx context.Context, m *miners.AvalonQHost) error {
+	for {
+		wait, ok := s.reserveMinerCommand(m)
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// SetMinerOverride pins a discovered miner to the given state and work mode until the override is cleared.
+// The override is applied to the miner immediately unless running in dry-run mode.
+func (s *MinerScheduler) SetMinerOverride(ctx context.Context, addr string, override MinerOverride) (*miners.AvalonQHost, error) {
+	m := s.findMiner(addr)
+	if m == nil {
+		return nil, fmt.Errorf("miner %s not found", addr)
+	}
+
+	s.minerOverrides.Store(minerKey(m), override)
+	s.logger.Printf("Manual override set for miner %s:%d: %s", m.Address, m.Port, override.String())
+
+	if s.GetConfig().DryRun {
+		s.logger.Printf("DRY-RUN: Would set miner %s:%d to %s", m.Address, m.Port, override.String())
+		return m, nil
+	}
+
+	if err := s.waitMinerCommand(ctx, m); err != nil {
+		return m, fmt.Errorf("failed to apply override to miner %s:%d: %w", m.Address, m.Port, err)
+	}
+
+	var response string
+	var err error
+	if override.State == miners.AvalonStateStandBy {
+		response, err = m.Standby(ctx)
+	} else {
+		if m.LastStats != nil && m.LastStats.State == miners.AvalonStateStandBy {
+			if _, err = m.WakeUp(ctx); err != nil {
+				return m, fmt.Errorf("failed to wake up miner %s:%d: %w", m.Address, m.Port, err)
+			}
+		}
+		response, err = m.SetWorkMode(ctx, override.WorkMode, true)
+	}
+	if err != nil {
+		return m, fmt.Errorf("failed to apply override to miner %s:%d: %w", m.Address, m.Port, err)
+	}
+	s.logger.Printf("Override response for miner %s:%d: %s", m.Address, m.Port, response)
+	return m, nil
+}
+
+// ClearMinerOverride removes the manual override for a miner and restores automatic control
+func (s *MinerScheduler) ClearMinerOverride(addr string) (*miners.AvalonQHost, error) {
+	m := s.findMiner(addr)
+	if m == nil {
+		return nil, fmt.Errorf("miner %s not found", addr)
+	}
+	s.minerOverrides.Delete(minerKey(m))
+	s.logger.Printf("Manual override cleared for miner %s:%d", m.Address, m.Port)
+	return m, nil
+}
+
+// MinerControlReason describes the trigger behind a miner control decision
+type MinerControlReason string
+
+// Miner control reasons
+const (
+	ReasonNoChange            MinerControlReason = "no_change"            // FanR and power within limits
+	ReasonManualOverride      MinerControlReason = "manual_override"      // Miner is under manual override
+	ReasonFanRHigh            MinerControlReason = "fanr_high"            // FanR above high threshold, work mode decreased
+	ReasonPowerLimit          MinerControlReason = "power_limit"          // Total power above limit, work mode decreased
+	ReasonFanRLow             MinerControlReason = "fanr_low"             // FanR below low threshold, work mode increased
+	ReasonInsufficientHistory MinerControlReason = "insufficient_history" // FanR low, but not enough history to increase
+	ReasonHistoryFanRHigh     MinerControlReason = "history_fanr_high"    // FanR low, but history has values above low threshold
+	ReasonMaxWorkMode         MinerControlReason = "max_work_mode"        // FanR low, but already at the highest work mode
+	ReasonFanRTargetExceeded  MinerControlReason = "fanr_target_exceeded" // FanR low, but a higher mode is predicted to exceed the FanR target
+	ReasonPowerLimitExceeded  MinerControlReason = "power_limit_exceeded" // FanR low, but a higher mode would exceed the power limit
+	ReasonPriceBelowLimit     MinerControlReason = "price_below_limit"    // Price at or below limit, miner woken up
+	ReasonPriceAboveLimit     MinerControlReason = "price_above_limit"    // Price above limit, miner put into standby
+	ReasonPriceSpike          MinerControlReason = "price_spike"          // Price spike forecast, miner put into standby
+	ReasonNegativeMargin      MinerControlReason = "negative_margin"      // Mining revenue below energy cost, miner put into standby
+	ReasonThunderThrottle     MinerControlReason = "thunder_throttle"     // Thunder forecast, work mode limited to eco
+	ReasonThunderStandby      MinerControlReason = "thunder_standby"      // Thunder forecast, miner put into standby
+	ReasonSolarSurplus        MinerControlReason = "solar_surplus"        // State and work mode selected to consume the solar surplus
+	ReasonRecentlyRestarted   MinerControlReason = "recently_restarted"   // Miner booted recently, work mode not increased until its readings stabilize
+	ReasonCooling             MinerControlReason = "cooling"              // Hot miner runs in eco mode for miner_cooldown_time before standby
+)
+
+// MinerControlDecision represents the state and work mode chosen for a miner and why
+type MinerControlDecision struct {
+	State     miners.AvalonState
+	WorkMode  miners.AvalonWorkMode
+	Reason    MinerControlReason
+	Timestamp time.Time
+}
+
+// recordMinerDecision stores the latest control decision for a miner
+func (s *MinerScheduler) recordMinerDecision(m *miners.AvalonQHost, decision MinerControlDecision) {
+	decision.Timestamp = s.now()
+	if decision.Reason != ReasonCooling {
+		s.minerCooldowns.Delete(minerKey(m))
+	}
+	s.minerDecisions.Store(minerKey(m), decision)
+}
+
+// GetMinerDecision returns the latest control decision for a miner, if any
+func (s *MinerScheduler) GetMinerDecision(m *miners.AvalonQHost) (MinerControlDecision, bool) {
+	value, ok := s.minerDecisions.Load(minerKey(m))
+	if !ok {
+		return MinerControlDecision{}, false
+	}
+	decision, ok := value.(MinerControlDecision)
+	return decision, ok
+}
+
+// controlMiner returns a new miner state and mode together with the reason for the decision
+// Miners under manual override keep their current state and mode
+// Miners booted within miner_settle_time are not stepped up until FanR settles, they are still stepped down
+// When thunder is forecast miners are limited to eco mode or put into standby, see thunderProtectionLevel
+// While miners follow the solar surplus their work mode is not increased, see manageSolarSurplus
+// Hot miners cool down in eco mode before they are put into standby for FanR, see coolDownBeforeStandby
+func (s *MinerScheduler) controlMiner(m *miners.AvalonQHost, totalPower float64, effectiveLimit float64) MinerControlDecision {
 	fanR := m.LastStats.FanR
 	currentWorkMode := miners.AvalonWorkMode(m.LastStats.WorkMode)
 	currentState := m.LastStats.State
+	keep := func(reason MinerControlReason) MinerControlDecision {
+		return MinerControlDecision{State: currentState, WorkMode: currentWorkMode, Reason: reason}
+	}
+	if _, ok := s.getMinerOverride(m); ok {
+		return keep(ReasonManualOverride)
+	}
+	protection, _ := s.thunderProtectionLevel(s.now())
+	switch protection {
+	case thunderStandby:
+		return MinerControlDecision{State: miners.AvalonStateStandBy, WorkMode: miners.AvalonEcoMode, Reason: ReasonThunderStandby}
+	case thunderThrottle:
+		if currentWorkMode > miners.AvalonEcoMode {
+			return MinerControlDecision{State: currentState, WorkMode: miners.AvalonEcoMode, Reason: ReasonThunderThrottle}
+		}
+	}
 	if fanR > s.config.FanRHighThreshold || totalPower > effectiveLimit {
+		reason := ReasonPowerLimit
+		if fanR > s.config.FanRHighThreshold {
+			reason = ReasonFanRHigh
+		}
+		standby := MinerControlDecision{State: miners.AvalonStateStandBy, WorkMode: miners.AvalonEcoMode, Reason: reason}
+
 		// Decrease work mode
 		newWorkMode := currentWorkMode - 1
+		if fanR > s.config.FanRHighThreshold && s.config.FanRTarget > 0 {
+			// Jump directly to the mode predicted to bring FanR down to the target,
+			// without a prediction reaching the target step down one mode
+			if targetMode, ok := s.targetWorkMode(m); ok {
+				newWorkMode = min(newWorkMode, targetMode)
+			}
+		}
 		newTotalPower := totalPower - s.getMinerPowerConsumption(currentState, currentWorkMode) + s.getMinerPowerConsumption(currentState, newWorkMode)
 		if newWorkMode < 0 || newTotalPower > effectiveLimit {
-			return miners.AvalonStateStandBy, miners.AvalonEcoMode
+			return s.coolDownBeforeStandby(m, standby)
 		}
-		return currentState, newWorkMode
+		return MinerControlDecision{State: currentState, WorkMode: newWorkMode, Reason: reason}
 	} else if fanR < s.config.FanRLowThreshold && totalPower <= effectiveLimit {
 		// Increase work mode only if all LiteStatsHistory fanR values match criteria
-		if len(m.LiteStatsHistory) < 5 || currentWorkMode == miners.AvalonSuperMode {
-			return currentState, currentWorkMode
+		if protection == thunderThrottle {
+			return keep(ReasonThunderThrottle)
+		}
+		// The work mode follows the solar surplus planned at the last price check
+		if s.isSolarSurplusActive() {
+			return keep(ReasonSolarSurplus)
+		}
+		if currentWorkMode == miners.AvalonSuperMode {
+			return keep(ReasonMaxWorkMode)
+		}
+		if m.LastStats.RecentlyRestarted(s.config.MinerSettleTime) {
+			return keep(ReasonRecentlyRestarted)
+		}
+		if len(m.LiteStatsHistory) < 5 {
+			return keep(ReasonInsufficientHistory)
 		}
 		for _, stat := range m.LiteStatsHistory {
 			if stat.FanR >= s.config.FanRLowThreshold {
-				return currentState, currentWorkMode
+				return keep(ReasonHistoryFanRHigh)
 			}
 		}
 		newWorkMode := currentWorkMode + 1
+		if s.config.FanRTarget > 0 {
+			// Only increase to a mode predicted to keep FanR at or below the target
+			targetMode, ok := s.targetWorkMode(m)
+			if !ok || targetMode <= currentWorkMode {
+				return keep(ReasonFanRTargetExceeded)
+			}
+			newWorkMode = targetMode
+		}
 		newTotalPower := totalPower - s.getMinerPowerConsumption(currentState, currentWorkMode) + s.getMinerPowerConsumption(currentState, newWorkMode)
 		if newTotalPower <= effectiveLimit {
-			return currentState, newWorkMode
+			return MinerControlDecision{State: currentState, WorkMode: newWorkMode, Reason: ReasonFanRLow}
 		}
+		return keep(ReasonPowerLimitExceeded)
 	}
-	return currentState, currentWorkMode
+	return keep(ReasonNoChange)
 }
 
 // runStateCheck executes the state monitoring task for miners
@@ -309,6 +672,11 @@ func (s *MinerScheduler) runStateCheck(ctx context.Context) error {
 		return nil
 	}
 
+	s.checkFleetHashrate(minersList)
+	s.alertOfflineMiners(minersList)
+	s.accountMinerCosts(minersList)
+	s.rebootStuckMiners(ctx, minersList)
+
 	isDryRun := s.config.DryRun
 
 	// Check if PV power control is enabled
@@ -322,14 +690,23 @@ func (s *MinerScheduler) runStateCheck(ctx context.Context) error {
 		s.logger.Printf("Current total power consumption: %.2f kW, Effective limit: %.2f kW", totalPower, effectiveLimit)
 	}
 
+	// Shed miner power right away when the measured plant state calls for it, whatever the plan
+	if limit, limited := s.measuredMinerLimit(minersList); limited {
+		totalPower = s.calculateTotalPowerConsumption(minersList)
+		effectiveLimit = min(effectiveLimit, limit)
+	}
+
 	var wg sync.WaitGroup
 	var powerMu sync.Mutex // Mutex to protect totalPower updates
 	errChan := make(chan error, len(minersList))
+	limiter := s.newMinerLimiter()
 
 	for _, miner := range minersList {
 		wg.Add(1)
 		go func(m *miners.AvalonQHost) {
 			defer wg.Done()
+			limiter.acquire()
+			defer limiter.release()
 
 			// Get current stats
 			if m.LastStatsError != nil {
@@ -348,6 +725,8 @@ func (s *MinerScheduler) runStateCheck(ctx context.Context) error {
 				return
 			}
 
+			s.getFanRModel(m).Observe(currentWorkMode, fanR)
+
 			s.logger.Printf("Miner %s:%d - FanR: %d%%, HBITemp:%d, HBOTemp:%d, ITemp:%d, WorkMode: %d",
 				m.Address,
 				m.Port,
@@ -358,15 +737,20 @@ func (s *MinerScheduler) runStateCheck(ctx context.Context) error {
 				currentWorkMode)
 
 			powerMu.Lock()
-			newState, newMode := s.controlMiner(m, totalPower, effectiveLimit)
+			decision := s.controlMiner(m, totalPower, effectiveLimit)
 			powerMu.Unlock()
+			s.recordMinerDecision(m, decision)
+			newState, newMode := decision.State, decision.WorkMode
 			if newState == currentState && newMode == currentWorkMode {
 				return
 			}
 			if isDryRun {
-				s.logger.Printf("DRY-RUN: Would set miner %s:%d to set %s state and %d mode (FanR %d%%)",
-					m.Address, m.Port, newState.String(), newMode, fanR)
+				s.logger.Printf("DRY-RUN: Would set miner %s:%d to set %s state and %d mode (FanR %d%%, reason: %s)",
+					m.Address, m.Port, newState.String(), newMode, fanR, decision.Reason)
 			} else {
+				if s.deferMinerCommand(m) {
+					return
+				}
 				var response string
 				var err error
 				if newState != currentState {
@@ -380,8 +764,8 @@ func (s *MinerScheduler) runStateCheck(ctx context.Context) error {
 				if newMode != currentWorkMode {
 					response, err = m.SetWorkMode(ctx, newMode, newMode > currentWorkMode)
 				}
-				s.logger.Printf("Control miner %s:%d to set %s state and %d mode (FanR %d%%)",
-					m.Address, m.Port, newState.String(), newMode, fanR)
+				s.logger.Printf("Control miner %s:%d to set %s state and %d mode (FanR %d%%, reason: %s)",
+					m.Address, m.Port, newState.String(), newMode, fanR, decision.Reason)
 				if err != nil {
 					errChan <- fmt.Errorf("failed to control miner %s:%d: %w", m.Address, m.Port, err)
 					return
@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/devskill-org/ems/miners"
+)
+
+// persistedMiner is the on-disk representation of a discovered miner
+type persistedMiner struct {
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+}
+
+// saveMinersFile writes the miner addresses to a JSON file.
+// The file is written to a temporary file first and renamed, so a crash never leaves a truncated file behind.
+func saveMinersFile(path string, hosts []*miners.AvalonQHost) error {
+	persisted := make([]persistedMiner, 0, len(hosts))
+	for _, host := range hosts {
+		persisted = append(persisted, persistedMiner{Address: host.Address, Port: host.Port})
+	}
+	// Keep the file stable between saves, discovered miners come from a map in random order
+	slices.SortFunc(persisted, func(a, b persistedMiner) int {
+		return cmp.Or(cmp.Compare(a.Address, b.Address), cmp.Compare(a.Port, b.Port))
+	})
+
+	data, err := json.MarshalIndent(persisted, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode miners: %w", err)
+	}
+
+	cleanPath := filepath.Clean(path)
+	tmpPath := cleanPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write miners file: %w", err)
+	}
+	if err := os.Rename(tmpPath, cleanPath); err != nil {
+		return fmt.Errorf("failed to replace miners file: %w", err)
+	}
+	return nil
+}
+
+// loadMinersFile reads the miner addresses from a JSON file.
+// A missing file is not an error and returns no miners.
+func loadMinersFile(path string) ([]*miners.AvalonQHost, error) {
+	// #nosec G304 -- path is cleaned and comes from trusted configuration
+	data, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read miners file: %w", err)
+	}
+
+	var persisted []persistedMiner
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		return nil, fmt.Errorf("failed to decode miners file: %w", err)
+	}
+
+	hosts := make([]*miners.AvalonQHost, 0, len(persisted))
+	for _, p := range persisted {
+		hosts = append(hosts, &miners.AvalonQHost{Address: p.Address, Port: p.Port})
+	}
+	return hosts, nil
+}
+
+// restorePersistedMiners loads the miners saved by a previous run into the discovered miners
+func (s *MinerScheduler) restorePersistedMiners() {
+	path := s.GetConfig().MinersFile
+	if path == "" {
+		return
+	}
+
+	hosts, err := loadMinersFile(path)
+	if err != nil {
+		s.logger.Printf("Warning: Failed to restore miners: %v", err)
+		return
+	}
+
+	for _, host := range hosts {
+		s.discoveredMiners.LoadOrStore(minerKey(host), host)
+	}
+	s.logger.Printf("Restored %d miners from %s", len(hosts), path)
+}
+
+// persistDiscoveredMiners saves the discovered miners so they can be restored after a restart
+func (s *MinerScheduler) persistDiscoveredMiners() {
+	path := s.GetConfig().MinersFile
+	if path == "" {
+		return
+	}
+
+	hosts := s.GetDiscoveredMiners()
+	if err := saveMinersFile(path, hosts); err != nil {
+		s.logger.Printf("Warning: Failed to persist miners: %v", err)
+		return
+	}
+	s.logger.Printf("Persisted %d miners to %s", len(hosts), path)
+}
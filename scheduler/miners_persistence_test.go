@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/devskill-org/ems/miners"
+)
+
+func TestMinersFile_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "miners.json")
+	hosts := []*miners.AvalonQHost{
+		{Address: "192.168.1.20", Port: 4028},
+		{Address: "192.168.1.10", Port: 4029},
+	}
+
+	if err := saveMinersFile(path, hosts); err != nil {
+		t.Fatalf("saveMinersFile failed: %v", err)
+	}
+
+	loaded, err := loadMinersFile(path)
+	if err != nil {
+		t.Fatalf("loadMinersFile failed: %v", err)
+	}
+
+	var keys []string
+	for _, host := range loaded {
+		keys = append(keys, minerKey(host))
+	}
+	expected := []string{"192.168.1.10:4029", "192.168.1.20:4028"}
+	if !slices.Equal(keys, expected) {
+		t.Errorf("Expected miners %v, got %v", expected, keys)
+	}
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Expected temporary file to be removed, got: %v", err)
+	}
+}
+
+func TestMinersFile_Missing(t *testing.T) {
+	loaded, err := loadMinersFile(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Expected no error for missing file, got: %v", err)
+	}
+	if len(loaded) != 0 {
+		t.Errorf("Expected no miners from missing file, got %d", len(loaded))
+	}
+}
+
+func TestMinersFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "miners.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := loadMinersFile(path); err == nil {
+		t.Error("Expected error for invalid miners file")
+	}
+}
+
+func TestPersistedMiners_MergedWithDiscovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "miners.json")
+	persisted := []*miners.AvalonQHost{
+		{Address: "192.168.1.10", Port: 4028},
+		{Address: "192.168.1.11", Port: 4028},
+	}
+	if err := saveMinersFile(path, persisted); err != nil {
+		t.Fatalf("saveMinersFile failed: %v", err)
+	}
+
+	config := testConfig()
+	config.MinersFile = path
+	scheduler := newTestScheduler(config)
+
+	// Persisted miners are available before any discovery ran
+	scheduler.restorePersistedMiners()
+	if count := len(scheduler.GetDiscoveredMiners()); count != 2 {
+		t.Fatalf("Expected 2 restored miners, got %d", count)
+	}
+
+	// Discovery finds one known and one new miner
+	scheduler.minerDiscoveryFunc = func(_ context.Context, _ string) []*miners.AvalonQHost {
+		return []*miners.AvalonQHost{
+			{Address: "192.168.1.11", Port: 4028},
+			{Address: "192.168.1.12", Port: 4028},
+		}
+	}
+	if err := scheduler.discoverMiners(context.Background()); err != nil {
+		t.Fatalf("discoverMiners failed: %v", err)
+	}
+
+	expected := []string{"192.168.1.10:4028", "192.168.1.11:4028", "192.168.1.12:4028"}
+
+	var keys []string
+	for _, m := range scheduler.GetDiscoveredMiners() {
+		keys = append(keys, minerKey(m))
+	}
+	slices.Sort(keys)
+	if !slices.Equal(keys, expected) {
+		t.Errorf("Expected merged miners %v, got %v", expected, keys)
+	}
+
+	// The merged set is written back for the next restart
+	loaded, err := loadMinersFile(path)
+	if err != nil {
+		t.Fatalf("loadMinersFile failed: %v", err)
+	}
+	keys = keys[:0]
+	for _, host := range loaded {
+		keys = append(keys, minerKey(host))
+	}
+	if !slices.Equal(keys, expected) {
+		t.Errorf("Expected persisted miners %v, got %v", expected, keys)
+	}
+}
@@ -1,10 +1,20 @@
 package scheduler
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"log"
+	"math"
+	"net"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/devskill-org/ems/meteo"
 	"github.com/devskill-org/ems/miners"
 )
 
@@ -35,7 +45,10 @@ func newTestScheduler(cfg *Config) *MinerScheduler {
 			MinersPowerLimit:   10.0,
 		}
 	}
-	return NewMinerScheduler(cfg, log.New(os.Stdout, "TEST: ", log.LstdFlags))
+	s := NewMinerScheduler(cfg, log.New(os.Stdout, "TEST: ", log.LstdFlags))
+	// Failing weather test servers are retried without the production delays
+	s.weatherRetry = &meteo.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	return s
 }
 
 // Helper function to create a test miner with specific stats
@@ -76,6 +89,7 @@ func TestControlMiner_HighFanR_DecreasesWorkMode(t *testing.T) {
 		effectiveLimit   float64
 		expectedState    miners.AvalonState
 		expectedWorkMode miners.AvalonWorkMode
+		expectedReason   MinerControlReason
 		description      string
 	}{
 		{
@@ -87,6 +101,7 @@ func TestControlMiner_HighFanR_DecreasesWorkMode(t *testing.T) {
 			effectiveLimit:   10.0,
 			expectedState:    miners.AvalonStateMining,
 			expectedWorkMode: miners.AvalonStandardMode,
+			expectedReason:   ReasonFanRHigh,
 			description:      "Should decrease from Super to Standard when FanR > high threshold",
 		},
 		{
@@ -98,6 +113,7 @@ func TestControlMiner_HighFanR_DecreasesWorkMode(t *testing.T) {
 			effectiveLimit:   10.0,
 			expectedState:    miners.AvalonStateMining,
 			expectedWorkMode: miners.AvalonEcoMode,
+			expectedReason:   ReasonFanRHigh,
 			description:      "Should decrease from Standard to Eco when FanR > high threshold",
 		},
 		{
@@ -109,6 +125,7 @@ func TestControlMiner_HighFanR_DecreasesWorkMode(t *testing.T) {
 			effectiveLimit:   10.0,
 			expectedState:    miners.AvalonStateStandBy,
 			expectedWorkMode: miners.AvalonEcoMode,
+			expectedReason:   ReasonFanRHigh,
 			description:      "Should go to Standby when FanR high and already at Eco mode",
 		},
 	}
@@ -118,13 +135,16 @@ func TestControlMiner_HighFanR_DecreasesWorkMode(t *testing.T) {
 			scheduler := newTestScheduler(nil)
 			miner := newTestMiner(tt.fanR, tt.currentWorkMode, tt.currentState, nil)
 
-			newState, newMode := scheduler.controlMiner(miner, tt.totalPower, tt.effectiveLimit)
+			decision := scheduler.controlMiner(miner, tt.totalPower, tt.effectiveLimit)
 
-			if newState != tt.expectedState {
-				t.Errorf("%s: expected state %v, got %v", tt.description, tt.expectedState, newState)
+			if decision.State != tt.expectedState {
+				t.Errorf("%s: expected state %v, got %v", tt.description, tt.expectedState, decision.State)
 			}
-			if newMode != tt.expectedWorkMode {
-				t.Errorf("%s: expected work mode %v, got %v", tt.description, tt.expectedWorkMode, newMode)
+			if decision.WorkMode != tt.expectedWorkMode {
+				t.Errorf("%s: expected work mode %v, got %v", tt.description, tt.expectedWorkMode, decision.WorkMode)
+			}
+			if decision.Reason != tt.expectedReason {
+				t.Errorf("%s: expected reason %v, got %v", tt.description, tt.expectedReason, decision.Reason)
 			}
 		})
 	}
@@ -140,6 +160,7 @@ func TestControlMiner_PowerLimitExceeded_DecreasesWorkMode(t *testing.T) {
 		effectiveLimit   float64
 		expectedState    miners.AvalonState
 		expectedWorkMode miners.AvalonWorkMode
+		expectedReason   MinerControlReason
 	}{
 		{
 			name:             "Super mode exceeds power limit",
@@ -150,6 +171,7 @@ func TestControlMiner_PowerLimitExceeded_DecreasesWorkMode(t *testing.T) {
 			effectiveLimit:   10.0,
 			expectedState:    miners.AvalonStateMining,
 			expectedWorkMode: miners.AvalonStandardMode,
+			expectedReason:   ReasonPowerLimit,
 		},
 		{
 			name:             "Standard mode exceeds power limit significantly",
@@ -160,6 +182,7 @@ func TestControlMiner_PowerLimitExceeded_DecreasesWorkMode(t *testing.T) {
 			effectiveLimit:   10.0,
 			expectedState:    miners.AvalonStateStandBy,
 			expectedWorkMode: miners.AvalonEcoMode,
+			expectedReason:   ReasonPowerLimit,
 		},
 		{
 			name:             "Eco mode exceeds power limit",
@@ -170,6 +193,7 @@ func TestControlMiner_PowerLimitExceeded_DecreasesWorkMode(t *testing.T) {
 			effectiveLimit:   10.0,
 			expectedState:    miners.AvalonStateStandBy,
 			expectedWorkMode: miners.AvalonEcoMode,
+			expectedReason:   ReasonPowerLimit,
 		},
 	}
 
@@ -178,13 +202,16 @@ func TestControlMiner_PowerLimitExceeded_DecreasesWorkMode(t *testing.T) {
 			scheduler := newTestScheduler(nil)
 			miner := newTestMiner(tt.fanR, tt.currentWorkMode, tt.currentState, nil)
 
-			newState, newMode := scheduler.controlMiner(miner, tt.totalPower, tt.effectiveLimit)
+			decision := scheduler.controlMiner(miner, tt.totalPower, tt.effectiveLimit)
 
-			if newState != tt.expectedState {
-				t.Errorf("expected state %v, got %v", tt.expectedState, newState)
+			if decision.State != tt.expectedState {
+				t.Errorf("expected state %v, got %v", tt.expectedState, decision.State)
+			}
+			if decision.WorkMode != tt.expectedWorkMode {
+				t.Errorf("expected work mode %v, got %v", tt.expectedWorkMode, decision.WorkMode)
 			}
-			if newMode != tt.expectedWorkMode {
-				t.Errorf("expected work mode %v, got %v", tt.expectedWorkMode, newMode)
+			if decision.Reason != tt.expectedReason {
+				t.Errorf("expected reason %v, got %v", tt.expectedReason, decision.Reason)
 			}
 		})
 	}
@@ -201,6 +228,7 @@ func TestControlMiner_LowFanR_IncreasesWorkMode(t *testing.T) {
 		effectiveLimit    float64
 		expectedState     miners.AvalonState
 		expectedWorkMode  miners.AvalonWorkMode
+		expectedReason    MinerControlReason
 		description       string
 	}{
 		{
@@ -213,6 +241,7 @@ func TestControlMiner_LowFanR_IncreasesWorkMode(t *testing.T) {
 			effectiveLimit:    10.0,
 			expectedState:     miners.AvalonStateMining,
 			expectedWorkMode:  miners.AvalonStandardMode,
+			expectedReason:    ReasonFanRLow,
 			description:       "Should increase from Eco to Standard when FanR < low threshold with history",
 		},
 		{
@@ -225,6 +254,7 @@ func TestControlMiner_LowFanR_IncreasesWorkMode(t *testing.T) {
 			effectiveLimit:    10.0,
 			expectedState:     miners.AvalonStateMining,
 			expectedWorkMode:  miners.AvalonSuperMode,
+			expectedReason:    ReasonFanRLow,
 			description:       "Should increase from Standard to Super when FanR < low threshold with history",
 		},
 		{
@@ -237,6 +267,7 @@ func TestControlMiner_LowFanR_IncreasesWorkMode(t *testing.T) {
 			effectiveLimit:    10.0,
 			expectedState:     miners.AvalonStateMining,
 			expectedWorkMode:  miners.AvalonSuperMode,
+			expectedReason:    ReasonMaxWorkMode,
 			description:       "Should not change when already at Super mode",
 		},
 	}
@@ -246,13 +277,16 @@ func TestControlMiner_LowFanR_IncreasesWorkMode(t *testing.T) {
 			scheduler := newTestScheduler(nil)
 			miner := newTestMiner(tt.fanR, tt.currentWorkMode, tt.currentState, tt.historyFanRValues)
 
-			newState, newMode := scheduler.controlMiner(miner, tt.totalPower, tt.effectiveLimit)
+			decision := scheduler.controlMiner(miner, tt.totalPower, tt.effectiveLimit)
 
-			if newState != tt.expectedState {
-				t.Errorf("%s: expected state %v, got %v", tt.description, tt.expectedState, newState)
+			if decision.State != tt.expectedState {
+				t.Errorf("%s: expected state %v, got %v", tt.description, tt.expectedState, decision.State)
+			}
+			if decision.WorkMode != tt.expectedWorkMode {
+				t.Errorf("%s: expected work mode %v, got %v", tt.description, tt.expectedWorkMode, decision.WorkMode)
 			}
-			if newMode != tt.expectedWorkMode {
-				t.Errorf("%s: expected work mode %v, got %v", tt.description, tt.expectedWorkMode, newMode)
+			if decision.Reason != tt.expectedReason {
+				t.Errorf("%s: expected reason %v, got %v", tt.description, tt.expectedReason, decision.Reason)
 			}
 		})
 	}
@@ -284,13 +318,16 @@ func TestControlMiner_LowFanR_NoIncreaseWithInsufficientHistory(t *testing.T) {
 			scheduler := newTestScheduler(nil)
 			miner := newTestMiner(40, tt.currentWorkMode, miners.AvalonStateMining, tt.historyFanRValues)
 
-			newState, newMode := scheduler.controlMiner(miner, 5.0, 10.0)
+			decision := scheduler.controlMiner(miner, 5.0, 10.0)
 
-			if newState != miners.AvalonStateMining {
-				t.Errorf("%s: expected state %v, got %v", tt.description, miners.AvalonStateMining, newState)
+			if decision.State != miners.AvalonStateMining {
+				t.Errorf("%s: expected state %v, got %v", tt.description, miners.AvalonStateMining, decision.State)
 			}
-			if newMode != tt.currentWorkMode {
-				t.Errorf("%s: expected work mode to remain %v, got %v", tt.description, tt.currentWorkMode, newMode)
+			if decision.WorkMode != tt.currentWorkMode {
+				t.Errorf("%s: expected work mode to remain %v, got %v", tt.description, tt.currentWorkMode, decision.WorkMode)
+			}
+			if decision.Reason != ReasonInsufficientHistory {
+				t.Errorf("expected reason %v, got %v", ReasonInsufficientHistory, decision.Reason)
 			}
 		})
 	}
@@ -302,14 +339,79 @@ func TestControlMiner_LowFanR_NoIncreaseWhenHistoryHasHighValues(t *testing.T) {
 	// Current FanR is low, but history contains a high value
 	miner := newTestMiner(40, miners.AvalonEcoMode, miners.AvalonStateMining, []int{40, 42, 60, 45, 43})
 
-	newState, newMode := scheduler.controlMiner(miner, 5.0, 10.0)
+	decision := scheduler.controlMiner(miner, 5.0, 10.0)
 
 	// Should not increase because one history value (60) is >= FanRLowThreshold (50)
-	if newState != miners.AvalonStateMining {
-		t.Errorf("expected state %v, got %v", miners.AvalonStateMining, newState)
+	if decision.State != miners.AvalonStateMining {
+		t.Errorf("expected state %v, got %v", miners.AvalonStateMining, decision.State)
+	}
+	if decision.WorkMode != miners.AvalonEcoMode {
+		t.Errorf("expected work mode to remain %v, got %v", miners.AvalonEcoMode, decision.WorkMode)
+	}
+	if decision.Reason != ReasonHistoryFanRHigh {
+		t.Errorf("expected reason %v, got %v", ReasonHistoryFanRHigh, decision.Reason)
+	}
+}
+
+func TestControlMiner_RecentlyRestarted_NoIncrease(t *testing.T) {
+	config := &Config{
+		FanRHighThreshold:  80,
+		FanRLowThreshold:   50,
+		MinerPowerStandby:  0.1,
+		MinerPowerEco:      1.0,
+		MinerPowerStandard: 1.5,
+		MinerPowerSuper:    2.0,
+		MinersPowerLimit:   10.0,
+		MinerSettleTime:    10 * time.Minute,
+	}
+	scheduler := newTestScheduler(config)
+
+	// FanR reads low right after the boot
+	miner := newTestMiner(40, miners.AvalonStandardMode, miners.AvalonStateMining, []int{40, 42, 38, 45, 43})
+	miner.LastStats.Elapsed = 120
+	miner.LastStats.BootBy = "0x01.00000000"
+
+	decision := scheduler.controlMiner(miner, 5.0, 10.0)
+	if decision.WorkMode != miners.AvalonStandardMode || decision.Reason != ReasonRecentlyRestarted {
+		t.Errorf("expected work mode %v kept for %v, got %v for %v", miners.AvalonStandardMode, ReasonRecentlyRestarted, decision.WorkMode, decision.Reason)
+	}
+
+	// Once settled the low FanR is acted on
+	miner.LastStats.Elapsed = 900
+	decision = scheduler.controlMiner(miner, 5.0, 10.0)
+	if decision.WorkMode != miners.AvalonSuperMode || decision.Reason != ReasonFanRLow {
+		t.Errorf("expected work mode %v for %v, got %v for %v", miners.AvalonSuperMode, ReasonFanRLow, decision.WorkMode, decision.Reason)
+	}
+}
+
+func TestControlMiner_RecentlyRestarted_StillDecreases(t *testing.T) {
+	config := &Config{
+		FanRHighThreshold:  80,
+		FanRLowThreshold:   50,
+		MinerPowerStandby:  0.1,
+		MinerPowerEco:      1.0,
+		MinerPowerStandard: 1.5,
+		MinerPowerSuper:    2.0,
+		MinersPowerLimit:   10.0,
+		MinerSettleTime:    10 * time.Minute,
+	}
+	scheduler := newTestScheduler(config)
+
+	miner := newTestMiner(90, miners.AvalonStandardMode, miners.AvalonStateMining, nil)
+	miner.LastStats.Elapsed = 120
+	miner.LastStats.BootBy = "0x01.00000000"
+
+	// Overheating right after the boot
+	decision := scheduler.controlMiner(miner, 5.0, 10.0)
+	if decision.WorkMode != miners.AvalonEcoMode || decision.Reason != ReasonFanRHigh {
+		t.Errorf("expected work mode %v for %v, got %v for %v", miners.AvalonEcoMode, ReasonFanRHigh, decision.WorkMode, decision.Reason)
 	}
-	if newMode != miners.AvalonEcoMode {
-		t.Errorf("expected work mode to remain %v, got %v", miners.AvalonEcoMode, newMode)
+
+	// Over the power limit right after the boot
+	miner.LastStats.FanR = 60
+	decision = scheduler.controlMiner(miner, 10.3, 10.0)
+	if decision.WorkMode != miners.AvalonEcoMode || decision.Reason != ReasonPowerLimit {
+		t.Errorf("expected work mode %v for %v, got %v for %v", miners.AvalonEcoMode, ReasonPowerLimit, decision.WorkMode, decision.Reason)
 	}
 }
 
@@ -319,14 +421,17 @@ func TestControlMiner_LowFanR_NoIncreaseWhenPowerLimitWouldExceed(t *testing.T)
 	// Current FanR is low with good history, but increasing would exceed power limit
 	miner := newTestMiner(40, miners.AvalonEcoMode, miners.AvalonStateMining, []int{40, 42, 38, 45, 43})
 
-	newState, newMode := scheduler.controlMiner(miner, 9.6, 10.0)
+	decision := scheduler.controlMiner(miner, 9.6, 10.0)
 
 	// Should not increase because new power (9.6 - 1.0 + 1.5 = 10.1) would be > effectiveLimit
-	if newState != miners.AvalonStateMining {
-		t.Errorf("expected state %v, got %v", miners.AvalonStateMining, newState)
+	if decision.State != miners.AvalonStateMining {
+		t.Errorf("expected state %v, got %v", miners.AvalonStateMining, decision.State)
+	}
+	if decision.WorkMode != miners.AvalonEcoMode {
+		t.Errorf("expected work mode to remain %v, got %v", miners.AvalonEcoMode, decision.WorkMode)
 	}
-	if newMode != miners.AvalonEcoMode {
-		t.Errorf("expected work mode to remain %v, got %v", miners.AvalonEcoMode, newMode)
+	if decision.Reason != ReasonPowerLimitExceeded {
+		t.Errorf("expected reason %v, got %v", ReasonPowerLimitExceeded, decision.Reason)
 	}
 }
 
@@ -366,13 +471,16 @@ func TestControlMiner_NormalRange_NoChange(t *testing.T) {
 			scheduler := newTestScheduler(nil)
 			miner := newTestMiner(tt.fanR, tt.currentWorkMode, miners.AvalonStateMining, nil)
 
-			newState, newMode := scheduler.controlMiner(miner, tt.totalPower, tt.effectiveLimit)
+			decision := scheduler.controlMiner(miner, tt.totalPower, tt.effectiveLimit)
 
-			if newState != miners.AvalonStateMining {
-				t.Errorf("expected state to remain %v, got %v", miners.AvalonStateMining, newState)
+			if decision.State != miners.AvalonStateMining {
+				t.Errorf("expected state to remain %v, got %v", miners.AvalonStateMining, decision.State)
 			}
-			if newMode != tt.currentWorkMode {
-				t.Errorf("expected work mode to remain %v, got %v", tt.currentWorkMode, newMode)
+			if decision.WorkMode != tt.currentWorkMode {
+				t.Errorf("expected work mode to remain %v, got %v", tt.currentWorkMode, decision.WorkMode)
+			}
+			if decision.Reason != ReasonNoChange {
+				t.Errorf("expected reason %v, got %v", ReasonNoChange, decision.Reason)
 			}
 		})
 	}
@@ -386,14 +494,14 @@ func TestControlMiner_DecreaseWhenNewPowerWouldStillExceedLimit(t *testing.T) {
 	// New total would be: 10.5 - 1.0 + 0.1 = 9.6 (still would need to check, but logic goes to standby)
 	miner := newTestMiner(85, miners.AvalonEcoMode, miners.AvalonStateMining, nil)
 
-	newState, newMode := scheduler.controlMiner(miner, 10.5, 10.0)
+	decision := scheduler.controlMiner(miner, 10.5, 10.0)
 
 	// When at Eco and FanR is high or power exceeded, should go to Standby
-	if newState != miners.AvalonStateStandBy {
-		t.Errorf("expected state %v, got %v", miners.AvalonStateStandBy, newState)
+	if decision.State != miners.AvalonStateStandBy {
+		t.Errorf("expected state %v, got %v", miners.AvalonStateStandBy, decision.State)
 	}
-	if newMode != miners.AvalonEcoMode {
-		t.Errorf("expected work mode %v, got %v", miners.AvalonEcoMode, newMode)
+	if decision.WorkMode != miners.AvalonEcoMode {
+		t.Errorf("expected work mode %v, got %v", miners.AvalonEcoMode, decision.WorkMode)
 	}
 }
 
@@ -412,28 +520,28 @@ func TestControlMiner_CustomThresholds(t *testing.T) {
 
 	t.Run("Decrease at custom high threshold", func(t *testing.T) {
 		miner := newTestMiner(71, miners.AvalonSuperMode, miners.AvalonStateMining, nil)
-		_, newMode := scheduler.controlMiner(miner, 8.0, 10.0)
+		decision := scheduler.controlMiner(miner, 8.0, 10.0)
 
-		if newMode != miners.AvalonStandardMode {
-			t.Errorf("expected work mode %v, got %v", miners.AvalonStandardMode, newMode)
+		if decision.WorkMode != miners.AvalonStandardMode {
+			t.Errorf("expected work mode %v, got %v", miners.AvalonStandardMode, decision.WorkMode)
 		}
 	})
 
 	t.Run("Increase at custom low threshold", func(t *testing.T) {
 		miner := newTestMiner(39, miners.AvalonEcoMode, miners.AvalonStateMining, []int{35, 36, 37, 38, 39})
-		_, newMode := scheduler.controlMiner(miner, 5.0, 10.0)
+		decision := scheduler.controlMiner(miner, 5.0, 10.0)
 
-		if newMode != miners.AvalonStandardMode {
-			t.Errorf("expected work mode %v, got %v", miners.AvalonStandardMode, newMode)
+		if decision.WorkMode != miners.AvalonStandardMode {
+			t.Errorf("expected work mode %v, got %v", miners.AvalonStandardMode, decision.WorkMode)
 		}
 	})
 
 	t.Run("No change between thresholds", func(t *testing.T) {
 		miner := newTestMiner(55, miners.AvalonStandardMode, miners.AvalonStateMining, nil)
-		newState, newMode := scheduler.controlMiner(miner, 6.0, 10.0)
+		decision := scheduler.controlMiner(miner, 6.0, 10.0)
 
-		if newState != miners.AvalonStateMining || newMode != miners.AvalonStandardMode {
-			t.Errorf("expected no change, got state %v mode %v", newState, newMode)
+		if decision.State != miners.AvalonStateMining || decision.WorkMode != miners.AvalonStandardMode {
+			t.Errorf("expected no change, got state %v mode %v", decision.State, decision.WorkMode)
 		}
 	})
 }
@@ -443,41 +551,41 @@ func TestControlMiner_EdgeCases(t *testing.T) {
 
 	t.Run("Zero total power", func(t *testing.T) {
 		miner := newTestMiner(40, miners.AvalonEcoMode, miners.AvalonStateMining, []int{40, 41, 42, 43, 44})
-		_, newMode := scheduler.controlMiner(miner, 0.0, 10.0)
+		decision := scheduler.controlMiner(miner, 0.0, 10.0)
 
 		// Should increase because power allows and FanR is low
-		if newMode != miners.AvalonStandardMode {
-			t.Errorf("expected work mode %v, got %v", miners.AvalonStandardMode, newMode)
+		if decision.WorkMode != miners.AvalonStandardMode {
+			t.Errorf("expected work mode %v, got %v", miners.AvalonStandardMode, decision.WorkMode)
 		}
 	})
 
 	t.Run("Power exactly at limit", func(t *testing.T) {
 		miner := newTestMiner(60, miners.AvalonStandardMode, miners.AvalonStateMining, nil)
-		newState, newMode := scheduler.controlMiner(miner, 10.0, 10.0)
+		decision := scheduler.controlMiner(miner, 10.0, 10.0)
 
 		// Should remain the same (FanR in normal range, power at limit but not over)
-		if newState != miners.AvalonStateMining || newMode != miners.AvalonStandardMode {
-			t.Errorf("expected no change, got state %v mode %v", newState, newMode)
+		if decision.State != miners.AvalonStateMining || decision.WorkMode != miners.AvalonStandardMode {
+			t.Errorf("expected no change, got state %v mode %v", decision.State, decision.WorkMode)
 		}
 	})
 
 	t.Run("Very high FanR", func(t *testing.T) {
 		miner := newTestMiner(99, miners.AvalonSuperMode, miners.AvalonStateMining, nil)
-		_, newMode := scheduler.controlMiner(miner, 5.0, 10.0)
+		decision := scheduler.controlMiner(miner, 5.0, 10.0)
 
 		// Should decrease
-		if newMode != miners.AvalonStandardMode {
-			t.Errorf("expected work mode %v, got %v", miners.AvalonStandardMode, newMode)
+		if decision.WorkMode != miners.AvalonStandardMode {
+			t.Errorf("expected work mode %v, got %v", miners.AvalonStandardMode, decision.WorkMode)
 		}
 	})
 
 	t.Run("Very low FanR", func(t *testing.T) {
 		miner := newTestMiner(10, miners.AvalonEcoMode, miners.AvalonStateMining, []int{10, 11, 12, 13, 14})
-		_, newMode := scheduler.controlMiner(miner, 3.0, 10.0)
+		decision := scheduler.controlMiner(miner, 3.0, 10.0)
 
 		// Should increase
-		if newMode != miners.AvalonStandardMode {
-			t.Errorf("expected work mode %v, got %v", miners.AvalonStandardMode, newMode)
+		if decision.WorkMode != miners.AvalonStandardMode {
+			t.Errorf("expected work mode %v, got %v", miners.AvalonStandardMode, decision.WorkMode)
 		}
 	})
 }
@@ -487,12 +595,12 @@ func TestControlMiner_StandbyState(t *testing.T) {
 
 	t.Run("Standby state with high FanR", func(t *testing.T) {
 		miner := newTestMiner(85, miners.AvalonEcoMode, miners.AvalonStateStandBy, nil)
-		newState, _ := scheduler.controlMiner(miner, 5.0, 10.0)
+		decision := scheduler.controlMiner(miner, 5.0, 10.0)
 
 		// When in standby, high FanR should still potentially trigger state change
 		// But the function logic primarily applies to mining state
-		if newState != miners.AvalonStateStandBy {
-			t.Errorf("expected state %v, got %v", miners.AvalonStateStandBy, newState)
+		if decision.State != miners.AvalonStateStandBy {
+			t.Errorf("expected state %v, got %v", miners.AvalonStateStandBy, decision.State)
 		}
 	})
 }
@@ -513,10 +621,10 @@ func TestControlMiner_PowerCalculation(t *testing.T) {
 		// Current: Super (2.5kW), Total: 9.0kW
 		// After decrease to Standard: 9.0 - 2.5 + 1.8 = 8.3kW (should fit)
 		miner := newTestMiner(85, miners.AvalonSuperMode, miners.AvalonStateMining, nil)
-		_, newMode := scheduler.controlMiner(miner, 9.0, 10.0)
+		decision := scheduler.controlMiner(miner, 9.0, 10.0)
 
-		if newMode != miners.AvalonStandardMode {
-			t.Errorf("expected work mode %v, got %v", miners.AvalonStandardMode, newMode)
+		if decision.WorkMode != miners.AvalonStandardMode {
+			t.Errorf("expected work mode %v, got %v", miners.AvalonStandardMode, decision.WorkMode)
 		}
 	})
 
@@ -524,10 +632,455 @@ func TestControlMiner_PowerCalculation(t *testing.T) {
 		// Current: Eco (1.2kW), Total: 7.0kW
 		// After increase to Standard: 7.0 - 1.2 + 1.8 = 7.6kW (should fit)
 		miner := newTestMiner(40, miners.AvalonEcoMode, miners.AvalonStateMining, []int{40, 41, 42, 43, 44})
-		_, newMode := scheduler.controlMiner(miner, 7.0, 10.0)
+		decision := scheduler.controlMiner(miner, 7.0, 10.0)
+
+		if decision.WorkMode != miners.AvalonStandardMode {
+			t.Errorf("expected work mode %v, got %v", miners.AvalonStandardMode, decision.WorkMode)
+		}
+	})
+}
+
+func TestControlMiner_ManualOverride(t *testing.T) {
+	scheduler := newTestScheduler(&Config{
+		FanRHighThreshold:  80,
+		FanRLowThreshold:   50,
+		MinerPowerStandby:  0.1,
+		MinerPowerEco:      1.0,
+		MinerPowerStandard: 1.5,
+		MinerPowerSuper:    2.0,
+		MinersPowerLimit:   10.0,
+		DryRun:             true,
+	})
+	miner := newTestMiner(95, miners.AvalonSuperMode, miners.AvalonStateMining, nil)
+	scheduler.discoveredMiners.Store(minerKey(miner), miner)
+
+	override := MinerOverride{State: miners.AvalonStateMining, WorkMode: miners.AvalonSuperMode}
+	if _, err := scheduler.SetMinerOverride(context.Background(), miner.Address, override); err != nil {
+		t.Fatalf("SetMinerOverride failed: %v", err)
+	}
+
+	// High FanR and power over the limit would normally decrease the work mode
+	decision := scheduler.controlMiner(miner, 12.0, 10.0)
+	if decision.State != miners.AvalonStateMining || decision.WorkMode != miners.AvalonSuperMode {
+		t.Errorf("Overridden miner should not be auto-adjusted, got state %v and mode %v", decision.State, decision.WorkMode)
+	}
+	if decision.Reason != ReasonManualOverride {
+		t.Errorf("expected reason %v, got %v", ReasonManualOverride, decision.Reason)
+	}
+
+	if _, err := scheduler.ClearMinerOverride(minerKey(miner)); err != nil {
+		t.Fatalf("ClearMinerOverride failed: %v", err)
+	}
+
+	decision = scheduler.controlMiner(miner, 12.0, 10.0)
+	if decision.State != miners.AvalonStateStandBy || decision.WorkMode != miners.AvalonEcoMode {
+		t.Errorf("Clearing override should restore automatic control, got state %v and mode %v", decision.State, decision.WorkMode)
+	}
+}
+
+func TestSetMinerOverride_UnknownMiner(t *testing.T) {
+	scheduler := newTestScheduler(nil)
+
+	override := MinerOverride{State: miners.AvalonStateStandBy, WorkMode: miners.AvalonEcoMode}
+	if _, err := scheduler.SetMinerOverride(context.Background(), "192.168.1.200", override); err == nil {
+		t.Error("Expected error for unknown miner")
+	}
+	if _, err := scheduler.ClearMinerOverride("192.168.1.200"); err == nil {
+		t.Error("Expected error for unknown miner")
+	}
+}
+
+func TestFanRModel_Predict(t *testing.T) {
+	tests := []struct {
+		name        string
+		samples     map[miners.AvalonWorkMode][]int
+		currentMode miners.AvalonWorkMode
+		currentFanR int
+		targetMode  miners.AvalonWorkMode
+		expected    float64
+	}{
+		{
+			name:        "No history uses default step",
+			samples:     nil,
+			currentMode: miners.AvalonSuperMode,
+			currentFanR: 85,
+			targetMode:  miners.AvalonEcoMode,
+			expected:    65,
+		},
+		{
+			name: "Observed modes use learned difference",
+			samples: map[miners.AvalonWorkMode][]int{
+				miners.AvalonEcoMode:   {40, 40, 40},
+				miners.AvalonSuperMode: {90, 90, 90},
+			},
+			currentMode: miners.AvalonSuperMode,
+			currentFanR: 88,
+			targetMode:  miners.AvalonEcoMode,
+			expected:    38,
+		},
+		{
+			name: "Unobserved target mode uses learned slope",
+			samples: map[miners.AvalonWorkMode][]int{
+				miners.AvalonEcoMode:   {40, 40},
+				miners.AvalonSuperMode: {80, 80},
+			},
+			currentMode: miners.AvalonSuperMode,
+			currentFanR: 80,
+			targetMode:  miners.AvalonStandardMode,
+			expected:    60,
+		},
+		{
+			name:        "Same mode keeps current FanR",
+			samples:     nil,
+			currentMode: miners.AvalonStandardMode,
+			currentFanR: 55,
+			targetMode:  miners.AvalonStandardMode,
+			expected:    55,
+		},
+		{
+			name:        "Prediction is clamped to 100",
+			samples:     nil,
+			currentMode: miners.AvalonEcoMode,
+			currentFanR: 95,
+			targetMode:  miners.AvalonSuperMode,
+			expected:    100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := NewFanRModel(10.0)
+			for mode, values := range tt.samples {
+				for _, fanR := range values {
+					model.Observe(mode, fanR)
+				}
+			}
+
+			predicted := model.Predict(tt.currentMode, tt.currentFanR, tt.targetMode)
+			if math.Abs(predicted-tt.expected) > 0.001 {
+				t.Errorf("expected predicted FanR %.2f, got %.2f", tt.expected, predicted)
+			}
+		})
+	}
+}
+
+func TestControlMiner_FanRTarget_UsesLearnedModel(t *testing.T) {
+	cfg := &Config{
+		FanRHighThreshold:  80,
+		FanRLowThreshold:   50,
+		FanRTarget:         60,
+		FanRModeStep:       10.0,
+		MinerPowerStandby:  0.1,
+		MinerPowerEco:      1.0,
+		MinerPowerStandard: 1.5,
+		MinerPowerSuper:    2.0,
+		MinersPowerLimit:   10.0,
+	}
+
+	t.Run("High FanR jumps to mode predicted to meet target", func(t *testing.T) {
+		scheduler := newTestScheduler(cfg)
+		miner := newTestMiner(90, miners.AvalonSuperMode, miners.AvalonStateMining, nil)
+
+		// History shows Standard runs at 75% and Eco at 50%, so only Eco meets the 60% target
+		model := scheduler.getFanRModel(miner)
+		for range 3 {
+			model.Observe(miners.AvalonEcoMode, 50)
+			model.Observe(miners.AvalonStandardMode, 75)
+			model.Observe(miners.AvalonSuperMode, 90)
+		}
+
+		decision := scheduler.controlMiner(miner, 5.0, 10.0)
+		if decision.State != miners.AvalonStateMining || decision.WorkMode != miners.AvalonEcoMode {
+			t.Errorf("expected Mining/Eco, got %v/%v", decision.State, decision.WorkMode)
+		}
+	})
+
+	t.Run("High FanR steps down one mode when no mode is predicted to meet target", func(t *testing.T) {
+		scheduler := newTestScheduler(cfg)
+		miner := newTestMiner(95, miners.AvalonSuperMode, miners.AvalonStateMining, nil)
+
+		// History shows even Eco runs at 85%, above the 60% target
+		model := scheduler.getFanRModel(miner)
+		for range 3 {
+			model.Observe(miners.AvalonEcoMode, 85)
+			model.Observe(miners.AvalonStandardMode, 90)
+			model.Observe(miners.AvalonSuperMode, 95)
+		}
+
+		decision := scheduler.controlMiner(miner, 5.0, 10.0)
+		if decision.State != miners.AvalonStateMining || decision.WorkMode != miners.AvalonStandardMode || decision.Reason != ReasonFanRHigh {
+			t.Errorf("expected Mining/Standard for %s, got %v/%v for %s", ReasonFanRHigh, decision.State, decision.WorkMode, decision.Reason)
+		}
+	})
+
+	t.Run("Low FanR does not increase when model predicts target exceeded", func(t *testing.T) {
+		scheduler := newTestScheduler(cfg)
+		miner := newTestMiner(45, miners.AvalonEcoMode, miners.AvalonStateMining, []int{45, 45, 45, 45, 45})
+
+		// History shows Standard runs 25% hotter than Eco
+		model := scheduler.getFanRModel(miner)
+		for range 3 {
+			model.Observe(miners.AvalonEcoMode, 45)
+			model.Observe(miners.AvalonStandardMode, 70)
+		}
+
+		decision := scheduler.controlMiner(miner, 5.0, 10.0)
+		if decision.State != miners.AvalonStateMining || decision.WorkMode != miners.AvalonEcoMode {
+			t.Errorf("expected Mining/Eco, got %v/%v", decision.State, decision.WorkMode)
+		}
+	})
+
+	t.Run("Low FanR increases to highest mode predicted to meet target", func(t *testing.T) {
+		scheduler := newTestScheduler(cfg)
+		miner := newTestMiner(30, miners.AvalonEcoMode, miners.AvalonStateMining, []int{30, 30, 30, 30, 30})
+
+		// History shows each mode step adds about 12% FanR
+		model := scheduler.getFanRModel(miner)
+		for range 3 {
+			model.Observe(miners.AvalonEcoMode, 30)
+			model.Observe(miners.AvalonStandardMode, 42)
+			model.Observe(miners.AvalonSuperMode, 54)
+		}
 
-		if newMode != miners.AvalonStandardMode {
-			t.Errorf("expected work mode %v, got %v", miners.AvalonStandardMode, newMode)
+		decision := scheduler.controlMiner(miner, 5.0, 10.0)
+		if decision.State != miners.AvalonStateMining || decision.WorkMode != miners.AvalonSuperMode {
+			t.Errorf("expected Mining/Super, got %v/%v", decision.State, decision.WorkMode)
 		}
 	})
 }
+
+// concurrencyCounter records the highest number of miner connections open at the same time
+type concurrencyCounter struct {
+	mu      sync.Mutex
+	current int
+	peak    int
+}
+
+func (c *concurrencyCounter) enter() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current++
+	c.peak = max(c.peak, c.current)
+}
+
+func (c *concurrencyCounter) leave() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current--
+}
+
+// serveCountingMiners starts n fake miners answering every command with response after a short delay
+// and counts how many of them are talking to the scheduler at the same time
+func serveCountingMiners(t *testing.T, n int, response []byte) ([]*miners.AvalonQHost, *concurrencyCounter) {
+	t.Helper()
+	counter := &concurrencyCounter{}
+	hosts := make([]*miners.AvalonQHost, 0, n)
+	for range n {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		t.Cleanup(func() { listener.Close() })
+
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					defer conn.Close()
+					counter.enter()
+					var cmd miners.AvalonQCommand
+					_ = json.NewDecoder(conn).Decode(&cmd)
+					time.Sleep(20 * time.Millisecond)
+					// Leave before answering, the scheduler may start the next operation as soon as it has the response
+					counter.leave()
+					_, _ = conn.Write(response)
+				}()
+			}
+		}()
+
+		addr := listener.Addr().(*net.TCPAddr)
+		hosts = append(hosts, &miners.AvalonQHost{Address: addr.IP.String(), Port: addr.Port})
+	}
+	return hosts, counter
+}
+
+func TestRunStateCheck_MinerControlConcurrency(t *testing.T) {
+	response, err := os.ReadFile("../test_data/avalon_litestat.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data file: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		concurrency int
+		maxPeak     int
+	}{
+		{name: "limited to two", concurrency: 2, maxPeak: 2},
+		{name: "limited to one", concurrency: 1, maxPeak: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts, counter := serveCountingMiners(t, 8, response)
+
+			config := testConfig()
+			config.DryRun = true
+			config.MinerControlConcurrency = tt.concurrency
+			scheduler := newTestScheduler(config)
+			for _, host := range hosts {
+				scheduler.discoveredMiners.Store(minerKey(host), host)
+			}
+
+			if err := scheduler.runStateCheck(context.Background()); err != nil {
+				t.Fatalf("runStateCheck failed: %v", err)
+			}
+
+			for _, host := range hosts {
+				if host.LastStatsError != nil {
+					t.Fatalf("Miner %s was not refreshed: %v", minerKey(host), host.LastStatsError)
+				}
+			}
+			counter.mu.Lock()
+			defer counter.mu.Unlock()
+			if counter.peak == 0 {
+				t.Fatal("Expected the fake miners to be contacted")
+			}
+			if counter.peak > tt.maxPeak {
+				t.Errorf("Expected at most %d concurrent miner operations, got %d", tt.maxPeak, counter.peak)
+			}
+		})
+	}
+}
+
+func TestMinerLimiter_Unlimited(t *testing.T) {
+	scheduler := newTestScheduler(testConfig())
+	limiter := scheduler.newMinerLimiter()
+	if limiter != nil {
+		t.Fatalf("Expected no limiter without miner_control_concurrency, got capacity %d", cap(limiter))
+	}
+	// A nil limiter never blocks
+	for range 100 {
+		limiter.acquire()
+	}
+	limiter.release()
+}
+
+// serveRecordingMiner starts a mock miner answering litestats with the response and recording the ascset commands
+func serveRecordingMiner(t *testing.T, response []byte) (*miners.AvalonQHost, func() []string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var mu sync.Mutex
+	var commands []string
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 256)
+			n, _ := conn.Read(buf)
+			if cmd := string(buf[:n]); strings.HasPrefix(cmd, "ascset") {
+				mu.Lock()
+				commands = append(commands, cmd)
+				mu.Unlock()
+				_, _ = conn.Write([]byte("STATUS=I,Msg=ASC 0 set OK|"))
+			} else {
+				_, _ = conn.Write(response)
+			}
+			conn.Close()
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return &miners.AvalonQHost{Address: addr.IP.String(), Port: addr.Port}, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(commands)
+	}
+}
+
+func TestDeferMinerCommand(t *testing.T) {
+	config := testConfig()
+	config.MinerCommandDelay = 10 * time.Second
+	scheduler := newTestScheduler(config)
+	var buf bytes.Buffer
+	scheduler.logger = log.New(&buf, "", 0)
+	clock := &simulatedClock{now: time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)}
+	scheduler.setClock(clock)
+	miner := newTestMiner(50, miners.AvalonEcoMode, miners.AvalonStateMining, nil)
+	other := newTestMiner(50, miners.AvalonEcoMode, miners.AvalonStateMining, nil)
+	other.Address = "192.168.1.101"
+
+	if scheduler.deferMinerCommand(miner) {
+		t.Fatal("Expected the first command to be sent")
+	}
+	clock.Set(clock.Now().Add(4 * time.Second))
+	if !scheduler.deferMinerCommand(miner) {
+		t.Fatal("Expected a second command within the delay to be deferred")
+	}
+	if !strings.Contains(buf.String(), "command deferred: next command allowed in 6s") {
+		t.Errorf("Expected the deferral to be logged, got:\n%s", buf.String())
+	}
+	if scheduler.deferMinerCommand(other) {
+		t.Error("Expected a command to another miner to be sent")
+	}
+
+	// The deferred command did not restart the delay
+	clock.Set(clock.Now().Add(6 * time.Second))
+	if scheduler.deferMinerCommand(miner) {
+		t.Error("Expected a command after the delay to be sent")
+	}
+
+	scheduler.config.MinerCommandDelay = 0
+	if scheduler.deferMinerCommand(miner) {
+		t.Error("Expected no deferral when disabled")
+	}
+}
+
+func TestManageMiners_SecondCommandWithinDelayDeferred(t *testing.T) {
+	response, err := os.ReadFile("../test_data/avalon_litestat.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data file: %v", err)
+	}
+	host, commands := serveRecordingMiner(t, response)
+
+	config := testConfig()
+	config.MinerCommandDelay = 10 * time.Second
+	scheduler := newTestScheduler(config)
+	clock := &simulatedClock{now: time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)}
+	scheduler.setClock(clock)
+	scheduler.discoveredMiners.Store(minerKey(host), host)
+
+	// The mock miner keeps reporting the mining state, so every cycle above the limit sends it into standby
+	if err := scheduler.manageMiners(context.Background(), 100, false); err != nil {
+		t.Fatalf("manageMiners failed: %v", err)
+	}
+	sent := len(commands())
+	if sent == 0 {
+		t.Fatal("Expected the standby commands to be sent")
+	}
+
+	clock.Set(clock.Now().Add(5 * time.Second))
+	if err := scheduler.manageMiners(context.Background(), 100, false); err != nil {
+		t.Fatalf("manageMiners failed: %v", err)
+	}
+	if got := len(commands()); got != sent {
+		t.Errorf("Expected no commands within the delay, got %v", commands()[sent:])
+	}
+
+	clock.Set(clock.Now().Add(5 * time.Second))
+	if err := scheduler.manageMiners(context.Background(), 100, false); err != nil {
+		t.Fatalf("manageMiners failed: %v", err)
+	}
+	if got := len(commands()); got != 2*sent {
+		t.Errorf("Expected the standby commands to be sent again after the delay, got %d commands", got)
+	}
+}
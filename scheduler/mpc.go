@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"time"
@@ -14,18 +15,21 @@ import (
 
 // RunMPCOptimize executes the MPC optimization task
 func (s *MinerScheduler) RunMPCOptimize(ctx context.Context) error {
-	s.logger.Printf("Starting MPC optimization task at %s", time.Now().Format(time.RFC3339))
+	s.logger.Printf("Starting MPC optimization task at %s", s.now().Format(time.RFC3339))
 
 	config := s.GetConfig()
 
 	// Check if Plant Modbus Address is configured
-	if config.PlantModbusAddress == "" {
+	readPlantInfo := s.readPlantRunningInfo
+	if s.plantInfoFunc != nil {
+		readPlantInfo = s.plantInfoFunc
+	} else if config.PlantModbusAddress == "" {
 		s.logger.Printf("MPC optimization skipped: PlantModbusAddress not configured")
 		return nil
 	}
 
 	// Step 1: Read plant running info from inverter
-	plantInfo, err := s.readPlantRunningInfo(config)
+	plantInfo, err := readPlantInfo(config)
 	if err != nil {
 		s.logger.Printf("Error reading plant running info from inverter: %v", err)
 		return err
@@ -35,8 +39,33 @@ func (s *MinerScheduler) RunMPCOptimize(ctx context.Context) error {
 	initialSOC := plantInfo.ESSSOC / 100.0 // Convert from percentage (0-100) to fraction (0-1)
 	s.logger.Printf("Initial battery SOC: %.1f%%", plantInfo.ESSSOC)
 
+	// The battery thermal model starts from the average cell temperature measured by the inverter
+	initialBatteryTemp := plantInfo.ESSAvgCellTemperature
+	s.logger.Printf("Initial battery temperature: %.1f °C", initialBatteryTemp)
+
+	// Narrow the usable SOC window for an aged battery
+	minSOC, maxSOC := effectiveSOCWindow(config, plantInfo.ESSSOH)
+	if minSOC != config.BatteryMinSOC || maxSOC != config.BatteryMaxSOC {
+		s.logger.Printf("Battery SOH %.1f%% below %.1f%%: SOC window narrowed to %.1f%%-%.1f%%",
+			plantInfo.ESSSOH, config.BatterySOHThreshold, minSOC*100, maxSOC*100)
+	}
+	// Never plan beyond the cutoffs the hardware enforces itself
+	if hwMinSOC, hwMaxSOC := hardwareSOCWindow(minSOC, maxSOC, plantInfo); hwMinSOC != minSOC || hwMaxSOC != maxSOC {
+		s.logger.Printf("ESS discharge-off SOC %.1f%% / charge-off SOC %.1f%%: SOC window narrowed to %.1f%%-%.1f%%",
+			plantInfo.ESSDischargeOffSOC, plantInfo.ESSChargeOffSOC, hwMinSOC*100, hwMaxSOC*100)
+		minSOC, maxSOC = hwMinSOC, hwMaxSOC
+	}
+	// The optimizer can only start from a SOC inside its window
+	initialSOC = max(minSOC, min(maxSOC, initialSOC))
+
+	// Correct the load estimate by its measured bias when enabled
+	loadCorrection := s.loadForecastCorrection(config)
+	if loadCorrection != 0 {
+		s.logger.Printf("Correcting load forecast by %.2f kW measured bias", loadCorrection)
+	}
+
 	// Step 2: Get forecast data (prices, solar, load)
-	forecast, err := s.buildMPCForecast(ctx, config, plantInfo)
+	forecast, err := s.buildMPCForecast(ctx, config, plantInfo, loadCorrection)
 	if err != nil {
 		s.logger.Printf("Error building MPC forecast: %v", err)
 		return err
@@ -54,20 +83,65 @@ func (s *MinerScheduler) RunMPCOptimize(ctx context.Context) error {
 		BatteryCapacity:             config.BatteryCapacity,
 		BatteryMaxCharge:            config.BatteryMaxCharge,
 		BatteryMaxDischarge:         config.BatteryMaxDischarge,
-		BatteryMinSOC:               config.BatteryMinSOC,
-		BatteryMaxSOC:               config.BatteryMaxSOC,
+		BatteryMinSOC:               minSOC,
+		BatteryMaxSOC:               maxSOC,
 		BatteryEfficiency:           config.BatteryEfficiency,
 		BatteryDegradationCost:      config.BatteryDegradationCost,
 		MaxGridImport:               config.MaxGridImport,
 		MaxGridExport:               config.MaxGridExport,
+		GridSwitchPenalty:           config.GridSwitchPenalty,
 		BatteryPreHeatPower:         config.BatteryPreHeatPower,
 		BatteryPreHeatTempThreshold: config.BatteryPreHeatTempThreshold,
 		BatteryThermalTimeConstant:  config.BatteryThermalTimeConstant,
+		TargetSOC:                   config.BatteryTargetSOC,
+		TargetHour:                  config.BatteryTargetHour,
+		TargetSOCPenalty:            config.BatteryTargetSOCPenalty,
+		MinActionDurationHours:      config.MinActionDurationHours,
+		ForbidGridCharge:            config.ForbidGridCharge,
+		ImportPriceCeiling:          config.ImportPriceCeiling / 1000, // EUR/MWh to EUR/kWh
+		HighSOCExportThreshold:      config.HighSOCExportThreshold,
+		HighSOCChargePenalty:        config.HighSOCChargePenalty,
+		Location:                    solarProfileLocation(config),
+	}
+
+	breakEvenSpread := mpc.BreakEvenSpread(systemConfig)
+	s.logger.Printf("Battery arbitrage break-even spread: %.2f EUR/MWh above import price / efficiency³",
+		breakEvenSpread*1000)
+
+	// Step 3.1: On flat prices arbitrage only wears the battery, leave it to maximum self-consumption
+	if config.FlatPriceSelfConsumption {
+		if spread := arbitrageSpread(forecast, config.BatteryEfficiency); spread < breakEvenSpread {
+			s.logger.Printf("Price spread %.2f EUR/MWh over the horizon below break-even %.2f EUR/MWh: skipping battery arbitrage",
+				spread*1000, breakEvenSpread*1000)
+			// Drop the previous plan so MPC execution does not re-apply it
+			s.mu.Lock()
+			s.mpcDecisions = nil
+			s.lastExecutedDecision = nil
+			s.selfConsumption = true
+			s.mu.Unlock()
+			if err := s.executeSelfConsumption(config.DryRun); err != nil {
+				s.logger.Printf("Error setting maximum self-consumption: %v", err)
+				return err
+			}
+			return nil
+		}
+	}
+
+	// Step 3.2: Keep the plan within what is left of the daily battery throughput limit
+	if budget, today, midnight := s.throughputBudget(config, forecast); budget > 0 {
+		systemConfig.MaxThroughputKWh = budget
+		if !midnight.IsZero() {
+			// Tomorrow's share may only be used after midnight
+			systemConfig.ThroughputSplit = midnight.Unix()
+			systemConfig.MaxThroughputBeforeSplitKWh = today
+		}
+		s.logger.Printf("Battery throughput budget over the horizon: %.1f kWh (%.1f kWh used today)",
+			budget, s.GetDailyThroughput())
 	}
 
 	horizon := len(forecast)
 	controller := mpc.NewController(systemConfig, horizon, initialSOC)
-	controller.CurrentBatteryTemp = plantInfo.ESSAvgCellTemperature
+	controller.CurrentBatteryTemp = initialBatteryTemp
 
 	// Step 4: Run optimization
 	decisions := controller.Optimize(forecast)
@@ -76,10 +150,22 @@ func (s *MinerScheduler) RunMPCOptimize(ctx context.Context) error {
 		return nil
 	}
 
+	// Step 4.1: Self-check the plan, an inconsistent decision indicates a solver bug and must not be executed
+	if violations := controller.CheckDecisions(decisions, mpcCheckTolerance); len(violations) > 0 {
+		for _, v := range violations {
+			s.logger.Printf("MPC self-check violation: %s", v)
+		}
+		return fmt.Errorf("MPC plan failed self-check with %d violations", len(violations))
+	}
+
+	// Step 4.2: Remember the planned load to measure the error of the load estimate
+	s.loadError.recordPlan(decisions, config.CheckPriceInterval, loadCorrection, s.now())
+
 	// Step 5: Save optimization results to memory
 	s.mu.Lock()
 	s.mpcDecisions = decisions
 	s.lastExecutedDecision = nil // Clear last executed decision for new optimization
+	s.selfConsumption = false
 	s.mu.Unlock()
 
 	// Step 5.1: Persist decisions to database (only when not in dry run mode)
@@ -125,28 +211,78 @@ func (s *MinerScheduler) RunMPCOptimize(ctx context.Context) error {
 	return nil
 }
 
-// readPlantRunningInfo reads the plant running information from the inverter
-func (s *MinerScheduler) readPlantRunningInfo(config *Config) (*sigenergy.PlantRunningInfo, error) {
-	// Connect to Plant Modbus server
-	client, err := sigenergy.NewTCPClient(config.PlantModbusAddress, sigenergy.PlantAddress)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Plant Modbus: %w", err)
+// mpcCheckTolerance is the power (kW) and SOC (fraction) tolerance of the MPC plan self-check
+const mpcCheckTolerance = 0.01
+
+// minSOCWindow is the narrowest SOC window the degradation adjustment may produce
+const minSOCWindow = 0.1
+
+// effectiveSOCWindow returns the SOC window used by the MPC for the reported battery state of health.
+// Below battery_soh_threshold the window shrinks linearly from both ends by battery_soc_shrink_per_soh
+// per SOH percent, around its center and never below minSOCWindow (or the configured window if narrower).
+// An unreported SOH (0) leaves the configured window unchanged.
+func effectiveSOCWindow(config *Config, soh float64) (minSOC, maxSOC float64) {
+	minSOC, maxSOC = config.BatteryMinSOC, config.BatteryMaxSOC
+	if config.BatterySOHThreshold <= 0 || soh <= 0 || soh >= config.BatterySOHThreshold {
+		return minSOC, maxSOC
 	}
-	defer client.Close()
 
-	// Read plant running info
-	plantInfo, err := client.ReadPlantRunningInfo()
+	shrink := (config.BatterySOHThreshold - soh) * config.BatterySOCShrinkPerSOH
+	maxShrink := max(0, (maxSOC-minSOC-minSOCWindow)/2)
+	shrink = min(shrink, maxShrink)
+	return minSOC + shrink, maxSOC - shrink
+}
+
+// hardwareSOCWindow narrows the SOC window to the ESS discharge-off and charge-off SOC reported by the plant,
+// so the plan stays executable. Cutoffs that do not overlap the window are ignored as implausible.
+func hardwareSOCWindow(minSOC, maxSOC float64, plantInfo *sigenergy.PlantRunningInfo) (float64, float64) {
+	hwMinSOC, hwMaxSOC := plantInfo.ESSSOCCutoffs()
+	clampedMin, clampedMax := max(minSOC, hwMinSOC), min(maxSOC, hwMaxSOC)
+	if clampedMin > clampedMax {
+		return minSOC, maxSOC
+	}
+	return clampedMin, clampedMax
+}
+
+// withPlantClient connects to the plant and runs fn with the Modbus client. Only one connection to the plant
+// is open at a time, the inverter accepts few connections, so concurrent callers wait for the current one to close.
+func (s *MinerScheduler) withPlantClient(config *Config, fn func(client *sigenergy.SigenModbusClient) error) error {
+	s.plantMu.Lock()
+	defer s.plantMu.Unlock()
+
+	client, err := sigenergy.NewTCPClientWithTimeout(config.PlantModbusAddress, sigenergy.PlantAddress, config.PlantModbusTimeout)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read plant info: %w", err)
+		return fmt.Errorf("failed to connect to Plant Modbus: %w", err)
 	}
+	defer client.Close()
 
-	return plantInfo, nil
+	return fn(client)
+}
+
+// readPlantRunningInfo reads the plant running information from the inverter. Partial info is used with a warning.
+func (s *MinerScheduler) readPlantRunningInfo(config *Config) (*sigenergy.PlantRunningInfo, error) {
+	var plantInfo *sigenergy.PlantRunningInfo
+	err := s.withPlantClient(config, func(client *sigenergy.SigenModbusClient) error {
+		var err error
+		plantInfo, err = client.ReadPlantRunningInfo()
+		var partialErr *sigenergy.PartialReadError
+		if errors.As(err, &partialErr) {
+			s.logger.Printf("Warning: %v", err)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read plant info: %w", err)
+		}
+		return nil
+	})
+	return plantInfo, err
 }
 
 // buildMPCForecast builds the forecast data needed for MPC optimization
 // buildMPCForecast builds a forecast for MPC optimization combining prices, solar, and load
-func (s *MinerScheduler) buildMPCForecast(ctx context.Context, config *Config, plantInfo *sigenergy.PlantRunningInfo) ([]mpc.TimeSlot, error) {
-	now := time.Now()
+// loadCorrection (kW) is added to every load estimate
+func (s *MinerScheduler) buildMPCForecast(ctx context.Context, config *Config, plantInfo *sigenergy.PlantRunningInfo, loadCorrection float64) ([]mpc.TimeSlot, error) {
+	now := s.now()
 
 	// Get the market data for price lookups
 	marketData, err := s.GetMarketData(ctx)
@@ -158,27 +294,14 @@ func (s *MinerScheduler) buildMPCForecast(ctx context.Context, config *Config, p
 	}
 
 	// Get weather forecast for weather data
-	weatherForecast, err := s.getOrFetchWeatherForecast(config)
+	weatherForecast, err := s.getOrFetchWeatherForecast(ctx, config)
 	if err != nil {
 		s.logger.Printf("Warning: failed to get weather forecast: %v", err)
 		weatherForecast = nil
 	}
 
-	// Pre-compute hourly solar and weather forecasts (cache for efficiency)
-	// Solar forecasts are typically hourly, so we compute them once and reuse for all 15-min slots in each hour
-	var solarForecasts map[int]float64
-	var weatherData map[int]WeatherData
-	if weatherForecast != nil {
-		solarForecasts, weatherData, err = s.getSolarForecast(config, now, weatherForecast, plantInfo)
-		if err != nil {
-			s.logger.Printf("Warning: failed to get solar forecast: %v, using zero solar", err)
-			solarForecasts = make(map[int]float64)
-			weatherData = make(map[int]WeatherData)
-		}
-	} else {
-		solarForecasts = make(map[int]float64)
-		weatherData = make(map[int]WeatherData)
-	}
+	// This run uses the latest prices and weather, an update fetched above needs no extra run
+	s.clearMPCRerun()
 
 	// Determine time slot duration based on CheckPriceInterval
 	// Default to 15 minutes if not configured
@@ -192,32 +315,73 @@ func (s *MinerScheduler) buildMPCForecast(ctx context.Context, config *Config, p
 	forecastDuration := 36 * time.Hour
 	numSlots := int(forecastDuration / slotDuration)
 
+	// Pre-compute the solar and weather forecasts for every time slot
+	var solarForecasts map[int]float64
+	var weatherData map[int]WeatherData
+	if weatherForecast != nil {
+		solarForecasts, weatherData, err = s.getSolarForecast(config, now, weatherForecast, plantInfo, slotDuration, forecastDuration)
+		if err != nil {
+			s.logger.Printf("Warning: failed to get solar forecast: %v", err)
+		}
+	}
+	if solarForecasts == nil {
+		// Without live weather MPC would otherwise assume no PV and over-import
+		solarForecasts = s.fallbackSolarForecast(config, now, plantInfo, slotDuration, forecastDuration)
+		weatherData = make(map[int]WeatherData)
+	}
+
+	// A hole in the published prices shortens the horizon, the plan would otherwise jump over it
+	horizonEnd := now.Add(forecastDuration)
+	firstHour := now.Truncate(time.Hour)
+	if start := marketData.PeriodTimeInterval.Start; firstHour.Before(start) {
+		firstHour = start
+	}
+	if published := int(marketData.PeriodTimeInterval.End.Sub(firstHour) / time.Hour); published > 0 {
+		if missing := marketData.MissingHours(firstHour, published); len(missing) > 0 {
+			s.logger.Printf("Warning: %d hour(s) without price in the price document, MPC horizon ends at %s",
+				len(missing), missing[0].Format(time.RFC3339))
+			horizonEnd = missing[0]
+		}
+	}
+
 	// Build time slots at the configured interval
 	var timeSlots []mpc.TimeSlot
+	validator := newPriceValidator(marketData, config)
 	for i := range numSlots {
 		futureTime := now.Add(time.Duration(i) * slotDuration)
+		if !futureTime.Before(horizonEnd) {
+			break
+		}
 
 		// Get exact price for this time slot using LookupPriceByTime
 		// This will return the price for the specific 15-minute interval
 		var importPrice, exportPrice float64
 		if spotPrice, found := marketData.LookupPriceByTime(futureTime); found {
-			// Apply price adjustments from configuration (all values in EUR/MWh)
-			importPrice = (spotPrice + config.ImportPriceOperatorFee + config.ImportPriceDeliveryFee) / 1000.0 // Convert to EUR/kWh
-			exportPrice = (spotPrice - config.ExportPriceOperatorFee) / 1000.0                                 // Convert to EUR/kWh
+			// Guard against glitches in the price document, they would drive the plan to extreme actions
+			validated, outlier, reliable := validator.validate(futureTime, spotPrice)
+			// Like a hole in the prices, an unreliable one ends the horizon so the plan does not jump over it
+			if !reliable {
+				s.logger.Printf("Warning: implausible spot price %.2f EUR/MWh at %s, MPC horizon ends there", spotPrice, futureTime.Format(time.RFC3339))
+				break
+			}
+			if outlier {
+				s.logger.Printf("Warning: implausible spot price %.2f EUR/MWh at %s, clamped to %.2f EUR/MWh",
+					spotPrice, futureTime.Format(time.RFC3339), validated)
+			}
+			// Apply price adjustments from configuration and convert to EUR/kWh
+			importPrice, exportPrice = AdjustedPrices(validated, config)
 		} else {
 			// No price available for this time slot, skip it
 			continue
 		}
 
 		// Get solar forecast for this time period
-		// Since solar forecasts are typically hourly, we use the forecast for the containing hour
-		// All 15-minute slots within the same hour will use the same solar/weather forecast
-		hourIndex := int(futureTime.Sub(now).Hours())
-		solar := solarForecasts[hourIndex]
-		weather := weatherData[hourIndex]
+		solar := solarForecasts[i]
+		weather := weatherData[i]
 
 		// Estimate load forecast (miners only, based on price and solar availability)
 		loadForecast := s.estimateLoadForecast(importPrice*1000.0, config.PriceLimit/1000, solar, config)
+		loadForecast = max(0, loadForecast+loadCorrection)
 
 		timeSlots = append(timeSlots, mpc.TimeSlot{
 			Hour:           i, // Now represents time slot index, not hour
@@ -242,8 +406,10 @@ type WeatherData struct {
 	AirTemperature float64 // °C air temperature
 }
 
-// getSolarForecast gets solar power forecast from weather data
-func (s *MinerScheduler) getSolarForecast(config *Config, now time.Time, weatherForecast *meteo.METJSONForecast, plantInfo *sigenergy.PlantRunningInfo) (map[int]float64, map[int]WeatherData, error) {
+// getSolarForecast gets the solar power forecast from weather data for the slots of the given resolution
+// over the horizon from now, keyed by slot index. Each slot is the average power over the slot.
+func (s *MinerScheduler) getSolarForecast(config *Config, now time.Time, weatherForecast *meteo.METJSONForecast, plantInfo *sigenergy.PlantRunningInfo,
+	resolution, horizon time.Duration) (map[int]float64, map[int]WeatherData, error) {
 	if weatherForecast == nil || weatherForecast.Properties == nil {
 		return nil, nil, fmt.Errorf("invalid weather forecast data")
 	}
@@ -254,13 +420,31 @@ func (s *MinerScheduler) getSolarForecast(config *Config, now time.Time, weather
 		currentPVPower = plantInfo.PhotovoltaicPower
 	}
 
+	// A stale forecast no longer describes the sky, solar estimated from it is unreliable
+	stale := false
+	if age := weatherForecast.AgeAt(now); config.WeatherMaxAge > 0 && age > config.WeatherMaxAge {
+		fallback := "solar forecast limited to the current PV power"
+		if len(config.SolarFallbackProfile) > 0 {
+			fallback = "using the fallback solar profile"
+		}
+		s.logger.Printf("Warning: weather forecast is %s old (max %s), %s",
+			age.Round(time.Minute), config.WeatherMaxAge, fallback)
+		stale = true
+	}
+	location := solarProfileLocation(config)
+
 	// Convert weather to solar forecast
 	solarForecast := make(map[int]float64)
 	weatherData := make(map[int]WeatherData)
 
-	for i := range 36 {
-		futureTime := now.Add(time.Duration(i) * time.Hour)
-		solarPower, cloudCoverage, weatherSymbol, airTemp := s.estimateSolarPowerFromWeather(weatherForecast, futureTime, config.MaxSolarPower, currentPVPower)
+	slots := int(horizon / resolution)
+	for i := range slots {
+		futureTime := now.Add(time.Duration(i) * resolution)
+		_, cloudCoverage, weatherSymbol, airTemp := s.estimateSolarPowerFromWeather(weatherForecast, futureTime, config.MaxSolarPower, currentPVPower)
+		solarPower := s.estimateAverageSolarPower(weatherForecast, futureTime, resolution, config.MaxSolarPower, currentPVPower)
+		if stale {
+			solarPower = fallbackSolarPower(config, location, futureTime)
+		}
 		solarForecast[i] = solarPower
 		weatherData[i] = WeatherData{
 			CloudCoverage:  cloudCoverage,
@@ -270,35 +454,112 @@ func (s *MinerScheduler) getSolarForecast(config *Config, now time.Time, weather
 	}
 	solarForecast[0] = currentPVPower
 
+	if alpha := config.SolarSmoothingAlpha; alpha > 0 && alpha < 1 {
+		// The weight is given per hour, slots of another length get the weight with the same decay over an hour
+		alpha = 1 - math.Pow(1-alpha, resolution.Hours())
+		series := make([]float64, slots)
+		for i := range series {
+			series[i] = solarForecast[i]
+		}
+		for i, power := range smoothSolarForecast(series, alpha) {
+			solarForecast[i] = power
+		}
+	}
+
 	return solarForecast, weatherData, nil
 }
 
+// smoothSolarForecast applies an exponential moving average with weight alpha to a solar series,
+// damping the jumps caused by snapping each slot to the nearest weather time step.
+// Slots without solar power stay at zero so the average never carries PV into the night.
+func smoothSolarForecast(series []float64, alpha float64) []float64 {
+	smoothed := make([]float64, len(series))
+	average := 0.0
+	for i, power := range series {
+		if i == 0 {
+			average = power
+		} else {
+			average = alpha*power + (1-alpha)*average
+		}
+		if power > 0 {
+			smoothed[i] = average
+		}
+	}
+	return smoothed
+}
+
 // getOrFetchWeatherForecast gets weather forecast from cache or fetches new one
-func (s *MinerScheduler) getOrFetchWeatherForecast(config *Config) (*meteo.METJSONForecast, error) {
+// All weather consumers share this forecast, so the API is called for a single endpoint and cached once.
+// A fetch is cancelled when ctx is done.
+func (s *MinerScheduler) getOrFetchWeatherForecast(ctx context.Context, config *Config) (*meteo.METJSONForecast, error) {
 	// Try cache first
 	if forecast, ok := s.weatherCache.Get(); ok {
 		return forecast, nil
 	}
 
 	// Fetch new forecast
-	client := meteo.NewClient(config.UserAgent)
+	previous := s.weatherCache.last()
+	client := s.getWeatherClient(config)
 
-	forecast, err := client.GetComplete(meteo.QueryParams{
+	params := meteo.QueryParams{
 		Location: meteo.Location{
 			Latitude:  config.Latitude,
 			Longitude: config.Longitude,
 		},
-	})
+	}
+
+	var forecast *meteo.METJSONForecast
+	var err error
+	if config.WeatherEndpoint == WeatherEndpointCompact {
+		forecast, err = client.GetCompactWithContext(ctx, params)
+	} else {
+		// Complete is the default, it includes cloud layers and thunder probability
+		forecast, err = client.GetCompleteWithContext(ctx, params)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch weather forecast: %w", err)
 	}
 
 	// Cache it
 	s.weatherCache.Set(forecast)
+	if weatherForecastUpdated(previous, forecast) {
+		s.requestMPCRerun(fmt.Sprintf("Weather forecast updated at %s", forecast.Properties.Meta.UpdatedAt.Format(time.RFC3339)))
+	}
 
 	return forecast, nil
 }
 
+// getWeatherClient returns the MET API client for the configured user agent. The client is kept across
+// fetches so its cached responses are reused until they expire and then revalidated, as the MET terms of
+// service require. It is only rebuilt when the user agent or the base URL changes. Rate limiting and
+// temporary server errors are retried with the default policy of the client.
+func (s *MinerScheduler) getWeatherClient(config *Config) *meteo.Client {
+	s.weatherClientMu.Lock()
+	defer s.weatherClientMu.Unlock()
+
+	key := config.UserAgent + " " + s.weatherBaseURL
+	if s.weatherClient != nil && s.weatherClientKey == key {
+		return s.weatherClient
+	}
+	client := s.newWeatherClient(config)
+	s.weatherClient = client
+	s.weatherClientKey = key
+	return client
+}
+
+// newWeatherClient returns a new MET API client with an empty response cache
+func (s *MinerScheduler) newWeatherClient(config *Config) *meteo.Client {
+	var opts []meteo.ClientOption
+	if s.weatherRetry != nil {
+		opts = append(opts, meteo.WithRetryPolicy(*s.weatherRetry))
+	}
+	client := meteo.NewClient(config.UserAgent, opts...)
+	if s.weatherBaseURL != "" {
+		client.SetBaseURL(s.weatherBaseURL)
+	}
+	return client
+}
+
 // estimateSolarPowerFromWeather estimates solar power output from weather data
 func (s *MinerScheduler) estimateSolarPowerFromWeather(forecast *meteo.METJSONForecast, targetTime time.Time, peakPower float64, currentPVPower float64) (float64, float64, string, float64) {
 	cloudCoverage := 0.0
@@ -350,19 +611,34 @@ func (s *MinerScheduler) estimateSolarPowerFromWeather(forecast *meteo.METJSONFo
 	lat := config.Latitude
 	lon := config.Longitude
 
+	// suncalc does not validate its inputs, NaN coordinates or times would leak into the forecast
+	if !isFinite(lat) || !isFinite(lon) || lat < -90 || lat > 90 || targetTime.IsZero() {
+		s.logger.Printf("Warning: cannot compute sun position for %s at %.4f, %.4f, setting solar power to zero",
+			targetTime.Format(time.RFC3339), lat, lon)
+		return 0, cloudCoverage, weatherSymbol, airTemperature
+	}
+
 	// Get sun times for the target date
+	// During polar day and night the sun does not rise or set and suncalc returns invalid times,
+	// the solar altitude alone then decides whether there is sun
 	sunTimes := suncalc.GetTimes(targetTime, lat, lon)
 	sunrise := sunTimes["sunrise"].Value
 	sunset := sunTimes["sunset"].Value
 
 	// Check if we're between sunrise and sunset
-	if targetTime.Before(sunrise) || targetTime.After(sunset) {
+	if validSunTime(sunrise, targetTime) && validSunTime(sunset, targetTime) &&
+		(targetTime.Before(sunrise) || targetTime.After(sunset)) {
 		return 0, cloudCoverage, weatherSymbol, airTemperature // No sun available
 	}
 
 	// Get solar position to calculate altitude angle
 	pos := suncalc.GetPosition(targetTime, lat, lon)
 	altitude := pos.Altitude // in radians
+	if !isFinite(altitude) {
+		s.logger.Printf("Warning: invalid solar altitude for %s at %.4f, %.4f, setting solar power to zero",
+			targetTime.Format(time.RFC3339), lat, lon)
+		return 0, cloudCoverage, weatherSymbol, airTemperature
+	}
 
 	// Solar altitude factor (0-1)
 	// Altitude ranges from 0 (horizon) to π/2 (zenith)
@@ -383,7 +659,7 @@ func (s *MinerScheduler) estimateSolarPowerFromWeather(forecast *meteo.METJSONFo
 	// Check if panels are already covered by snow:
 	// If current PV power is zero but we expect power based on sun angle, panels might be covered
 	expectedPower := peakPower * solarAngleFactor * 0.5 // Rough estimate with some clouds
-	if currentPVPower < 0.1 && expectedPower > 1.0 && time.Until(targetTime).Hours() < 1 {
+	if currentPVPower < 0.1 && expectedPower > 1.0 && targetTime.Sub(s.now()).Hours() < 1 {
 		// Current power is essentially zero but we expect power - likely snow covered
 		s.logger.Printf("Current PV power is zero (%.2f kW) but forecast expects %.2f kW - panels may be snow covered", currentPVPower, expectedPower)
 		return 0, cloudCoverage, weatherSymbol, airTemperature
@@ -398,10 +674,55 @@ func (s *MinerScheduler) estimateSolarPowerFromWeather(forecast *meteo.METJSONFo
 
 	// Estimate solar power
 	solarPower := peakPower * solarAngleFactor * cloudFactor
+	if !isFinite(solarPower) {
+		s.logger.Printf("Warning: invalid solar power estimate for %s, setting solar power to zero", targetTime.Format(time.RFC3339))
+		return 0, cloudCoverage, weatherSymbol, airTemperature
+	}
 
 	return solarPower, cloudCoverage, weatherSymbol, airTemperature
 }
 
+// solarSamplesPerHour is the number of intervals an hour is split into to estimate its solar energy
+const solarSamplesPerHour = 4
+
+// estimateHourlySolarEnergy estimates the solar energy in kWh produced during the hour from hourStart.
+// The instantaneous model is averaged over the hour with the trapezoidal rule, so the hours around
+// sunrise and sunset are not represented by the power at their start alone.
+func (s *MinerScheduler) estimateHourlySolarEnergy(forecast *meteo.METJSONForecast, hourStart time.Time, peakPower float64, currentPVPower float64) float64 {
+	// The average power over one hour equals the energy in kWh
+	return s.estimateAverageSolarPower(forecast, hourStart, time.Hour, peakPower, currentPVPower)
+}
+
+// estimateAverageSolarPower estimates the average solar power in kW during the period from start.
+// The instantaneous model is sampled solarSamplesPerHour times an hour, at least at both ends of the period,
+// and averaged with the trapezoidal rule. Consecutive periods share their boundary sample, so a series of
+// short periods has no steps at the hour boundaries and averages to the estimate of the whole hour.
+func (s *MinerScheduler) estimateAverageSolarPower(forecast *meteo.METJSONForecast, start time.Time, duration time.Duration, peakPower float64, currentPVPower float64) float64 {
+	samples := max(1, int(math.Ceil(float64(duration)/float64(time.Hour/solarSamplesPerHour))))
+	sum := 0.0
+	for i := 0; i <= samples; i++ {
+		sampleTime := start.Add(time.Duration(i) * duration / time.Duration(samples))
+		power, _, _, _ := s.estimateSolarPowerFromWeather(forecast, sampleTime, peakPower, currentPVPower)
+		if i == 0 || i == samples {
+			power /= 2
+		}
+		sum += power
+	}
+	return sum / float64(samples)
+}
+
+// validSunTime reports whether a sunrise or sunset computed by suncalc belongs to the day of targetTime.
+// Times suncalc derives from NaN, when the sun does not rise or set, end up centuries away.
+func validSunTime(t, targetTime time.Time) bool {
+	diff := t.Sub(targetTime)
+	return diff > -48*time.Hour && diff < 48*time.Hour
+}
+
+// isFinite reports whether v is neither NaN nor infinite
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
 // estimateLoadForecast estimates power load based on price and available power
 // Follows the same logic as manageMiners: miners wake up in Eco mode when price <= limit,
 // but only if there's enough power budget (when PV power control is enabled)
@@ -423,8 +744,8 @@ func (s *MinerScheduler) estimateLoadForecast(hourlyPrice float64, priceLimit fl
 		return float64(len(minersList)) * config.MinerPowerStandby
 	}
 
-	// Check if PV power control is enabled
-	usePowerControl := config.UsePVPowerControl
+	// Check if PV power control is enabled, above the import price ceiling miners only run on solar power
+	usePowerControl := config.UsePVPowerControl || (config.ImportPriceCeiling > 0 && hourlyPrice > config.ImportPriceCeiling)
 	if !usePowerControl {
 		// Without power control, all miners can run in Super mode
 		totalMinerPower := float64(len(minersList)) * config.MinerPowerSuper
@@ -456,26 +777,39 @@ func (s *MinerScheduler) estimateLoadForecast(hourlyPrice float64, priceLimit fl
 
 // executeMPCDecision executes the first MPC control decision
 func (s *MinerScheduler) executeMPCDecision(decision *mpc.ControlDecision, dryRun bool) error {
+	config := s.GetConfig()
+
+	// Once the daily throughput limit is reached the battery stays idle until midnight, whatever the plan
+	if used, reached := s.dailyThroughputReached(config); reached &&
+		(decision.BatteryChargeFromPV > 0.01 || decision.BatteryChargeFromGrid > 0.01 || decision.BatteryDischarge > 0.01) {
+		s.logger.Printf("Warning: Battery throughput %.1f kWh today reached max_daily_throughput_kwh %.1f kWh, keeping the battery idle",
+			used, config.MaxDailyThroughputKWh)
+		idle := *decision
+		idle.BatteryCharge, idle.BatteryChargeFromPV, idle.BatteryChargeFromGrid, idle.BatteryDischarge = 0, 0, 0, 0
+		decision = &idle
+	}
+
 	if dryRun {
-		s.logger.Printf("DRY-RUN: Would execute MPC decision - ChargeFromPV: %.1f kW, ChargeFromGrid: %.1f kW, Discharge: %.1f kW, Import: %.1f kW, Export: %.1f kW",
-			decision.BatteryChargeFromPV, decision.BatteryChargeFromGrid, decision.BatteryDischarge, decision.GridImport, decision.GridExport)
+		s.logger.Printf("DRY-RUN: Would execute MPC decision - ChargeFromPV: %.1f kW, ChargeFromGrid: %.1f kW, Discharge: %.1f kW, Import: %.1f kW, Export: %.1f kW, Curtailment: %.1f kW",
+			decision.BatteryChargeFromPV, decision.BatteryChargeFromGrid, decision.BatteryDischarge, decision.GridImport, decision.GridExport, decision.SolarCurtailment)
 		return nil
 	}
 
-	config := s.GetConfig()
-
-	// Connect to Plant Modbus server
-	client, err := sigenergy.NewTCPClient(config.PlantModbusAddress, sigenergy.PlantAddress)
-	if err != nil {
-		return fmt.Errorf("failed to connect to Plant Modbus: %w", err)
-	}
-	defer client.Close()
+	return s.withPlantClient(config, func(client *sigenergy.SigenModbusClient) error {
+		return s.applyMPCDecision(client, config, decision)
+	})
+}
 
+// applyMPCDecision writes the remote EMS mode, ESS limits and PV power limit of an MPC decision to the plant
+func (s *MinerScheduler) applyMPCDecision(client *sigenergy.SigenModbusClient, config *Config, decision *mpc.ControlDecision) error {
 	// Enable Remote EMS control
 	if err := client.EnableRemoteEMS(true); err != nil {
 		return fmt.Errorf("failed to enable remote EMS: %w", err)
 	}
 	s.logger.Printf("Enabled Remote EMS control")
+	s.mu.Lock()
+	s.remoteEMSActive = true
+	s.mu.Unlock()
 
 	// Determine control mode based on decision
 	var mode uint16
@@ -483,7 +817,7 @@ func (s *MinerScheduler) executeMPCDecision(decision *mpc.ControlDecision, dryRu
 	if decision.BatteryChargeFromPV > 0.01 || decision.BatteryChargeFromGrid > 0.01 {
 		// Battery should charge
 		// Use BatteryChargeFromPV as the charge limit
-		chargeLimit := decision.BatteryChargeFromPV
+		chargeLimit := roundESSSetpoint(decision.BatteryChargeFromPV, config.ESSSetpointStep, config.BatteryMaxCharge)
 
 		// Decide mode based on whether grid charging is needed
 		if decision.BatteryChargeFromGrid > 0.01 {
@@ -512,7 +846,7 @@ func (s *MinerScheduler) executeMPCDecision(decision *mpc.ControlDecision, dryRu
 		// Battery should discharge
 		// Mode 5: Command discharging (PV first) - discharge from PV first
 		mode = 5
-		dischargeLimit := decision.BatteryDischarge
+		dischargeLimit := roundESSSetpoint(decision.BatteryDischarge, config.ESSSetpointStep, config.BatteryMaxDischarge)
 		s.logger.Printf("Setting battery to DISCHARGE mode: %.1f kW", dischargeLimit)
 
 		// Set Remote EMS control mode
@@ -548,12 +882,132 @@ func (s *MinerScheduler) executeMPCDecision(decision *mpc.ControlDecision, dryRu
 		}
 	}
 
+	// Curtail PV as planned instead of exporting at a loss, the limit is lifted again once the plan stops curtailing
+	if limit, ok := pvPowerLimit(decision, config); ok {
+		if err := client.SetPVMaxPowerLimit(limit); err != nil {
+			return fmt.Errorf("failed to set PV power limit: %w", err)
+		}
+		if decision.SolarCurtailment > 0.01 {
+			s.logger.Printf("Curtailing PV by %.1f kW: PV power limited to %.1f kW", decision.SolarCurtailment, limit)
+		}
+	}
+
 	s.logger.Printf("Successfully executed MPC decision - Mode: %d, SOC: %.1f%%, ChargeFromPV: %.1f kW, ChargeFromGrid: %.1f kW, Discharge: %.1f kW, GridImport: %.1f kW, GridExport: %.1f kW",
 		mode, decision.BatterySOC*100, decision.BatteryChargeFromPV, decision.BatteryChargeFromGrid, decision.BatteryDischarge, decision.GridImport, decision.GridExport)
 
 	return nil
 }
 
+// pvPowerLimit returns the PV max power limit executing the solar curtailment of an MPC decision and whether it is
+// set. While curtailing, PV is limited to the planned production, the forecast less the curtailment. Otherwise the
+// limit is lifted to max_solar_power. Without max_solar_power no limit can be lifted, so none is set.
+func pvPowerLimit(decision *mpc.ControlDecision, config *Config) (float64, bool) {
+	if config.MaxSolarPower <= 0 {
+		return 0, false
+	}
+	if decision.SolarCurtailment > 0.01 {
+		return max(0, decision.SolarForecast-decision.SolarCurtailment), true
+	}
+	return config.MaxSolarPower, true
+}
+
+// arbitrageSpread returns the largest spread ($/kWh) battery arbitrage could exploit over the forecast horizon:
+// the highest price a discharged kWh earns or saves, whether exported or covering the load instead of importing,
+// less the lowest effective charge cost importPrice/eff³, comparable with mpc.BreakEvenSpread.
+func arbitrageSpread(forecast []mpc.TimeSlot, efficiency float64) float64 {
+	if len(forecast) == 0 || efficiency <= 0 {
+		return 0
+	}
+	highest, lowestImport := math.Inf(-1), math.Inf(1)
+	for _, slot := range forecast {
+		highest = max(highest, slot.ImportPrice, slot.ExportPrice)
+		lowestImport = min(lowestImport, slot.ImportPrice)
+	}
+	return highest - lowestImport/(efficiency*efficiency*efficiency)
+}
+
+// executeSelfConsumption sets the plant to maximum self-consumption with the full battery charge and discharge
+// power and PV power available, the plant then charges from PV surplus and discharges to cover the load on its own
+func (s *MinerScheduler) executeSelfConsumption(dryRun bool) error {
+	if dryRun {
+		s.logger.Printf("DRY-RUN: Would set battery to maximum self-consumption mode")
+		return nil
+	}
+
+	config := s.GetConfig()
+	return s.withPlantClient(config, func(client *sigenergy.SigenModbusClient) error {
+		if err := client.EnableRemoteEMS(true); err != nil {
+			return fmt.Errorf("failed to enable remote EMS: %w", err)
+		}
+		s.mu.Lock()
+		s.remoteEMSActive = true
+		s.mu.Unlock()
+
+		// Mode 2: Maximum self-consumption, limits of an earlier idle decision are lifted
+		if err := client.SetRemoteEMSMode(2); err != nil {
+			return fmt.Errorf("failed to set remote EMS mode: %w", err)
+		}
+		if err := client.SetESSMaxChargingLimit(config.BatteryMaxCharge); err != nil {
+			return fmt.Errorf("failed to set ESS charging limit: %w", err)
+		}
+		if err := client.SetESSMaxDischargingLimit(config.BatteryMaxDischarge); err != nil {
+			return fmt.Errorf("failed to set ESS discharging limit: %w", err)
+		}
+		if config.MaxSolarPower > 0 {
+			if err := client.SetPVMaxPowerLimit(config.MaxSolarPower); err != nil {
+				return fmt.Errorf("failed to set PV power limit: %w", err)
+			}
+		}
+
+		s.logger.Printf("Set battery to maximum self-consumption mode")
+		return nil
+	})
+}
+
+// releaseRemoteEMS hands battery control back to the inverter's native EMS if MPC decisions took it over,
+// lifting a PV power limit left by a curtailing decision
+func (s *MinerScheduler) releaseRemoteEMS() error {
+	s.mu.RLock()
+	active := s.remoteEMSActive
+	s.mu.RUnlock()
+	config := s.GetConfig()
+	if !active || config.PlantModbusAddress == "" {
+		return nil
+	}
+
+	err := s.withPlantClient(config, func(client *sigenergy.SigenModbusClient) error {
+		// PV curtailed by the last decision would otherwise stay limited
+		if config.MaxSolarPower > 0 {
+			if err := client.SetPVMaxPowerLimit(config.MaxSolarPower); err != nil {
+				return fmt.Errorf("failed to lift PV power limit: %w", err)
+			}
+		}
+		return client.ReleaseRemoteEMS()
+	})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.remoteEMSActive = false
+	s.mu.Unlock()
+	s.logger.Printf("Released Remote EMS control to the inverter")
+	return nil
+}
+
+// roundESSSetpoint rounds an ESS power setpoint to the inverter's resolution and clamps it to [0, limit].
+// A limit that is not a multiple of the step is rounded down so the clamped setpoint stays writable.
+// A zero step leaves the setpoint unrounded, a zero limit leaves it unclamped from above.
+func roundESSSetpoint(powerKW, step, limit float64) float64 {
+	if step > 0 {
+		powerKW = math.Round(powerKW/step) * step
+		limit = math.Floor(limit/step+1e-9) * step
+	}
+	if limit > 0 {
+		powerKW = min(powerKW, limit)
+	}
+	return max(0, powerKW)
+}
+
 // runMPCExecution re-executes the current MPC decision only if previous execution failed
 // This ensures the decision is applied even if previous execution failed
 func (s *MinerScheduler) runMPCExecution() error {
@@ -567,7 +1021,7 @@ func (s *MinerScheduler) runMPCExecution() error {
 		return nil
 	}
 
-	now := time.Now().Unix()
+	now := s.now().Unix()
 	var currentDecision *mpc.ControlDecision
 
 	// Find the decision that matches the current hour
@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"time"
 
 	"github.com/devskill-org/ems/mpc"
 )
@@ -60,8 +59,9 @@ func (s *MinerScheduler) saveMPCDecisions(ctx context.Context, decisions []mpc.C
 			weather_symbol,
 			battery_avg_cell_temp,
 			air_temperature,
-			battery_preheat_active
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+			battery_preheat_active,
+			solar_curtailment
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
 		ON CONFLICT (timestamp) DO UPDATE SET
 			hour = EXCLUDED.hour,
 			battery_charge = EXCLUDED.battery_charge,
@@ -80,7 +80,8 @@ func (s *MinerScheduler) saveMPCDecisions(ctx context.Context, decisions []mpc.C
 			weather_symbol = EXCLUDED.weather_symbol,
 			battery_avg_cell_temp = EXCLUDED.battery_avg_cell_temp,
 			air_temperature = EXCLUDED.air_temperature,
-			battery_preheat_active = EXCLUDED.battery_preheat_active
+			battery_preheat_active = EXCLUDED.battery_preheat_active,
+			solar_curtailment = EXCLUDED.solar_curtailment
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
@@ -109,6 +110,7 @@ func (s *MinerScheduler) saveMPCDecisions(ctx context.Context, decisions []mpc.C
 			decision.BatteryAvgCellTemp,
 			decision.AirTemperature,
 			decision.BatteryPreHeatActive,
+			decision.SolarCurtailment,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to insert decision for hour %d: %w", decision.Hour, err)
@@ -167,7 +169,8 @@ func (s *MinerScheduler) loadLatestMPCDecisions(ctx context.Context) ([]mpc.Cont
 			weather_symbol,
 			battery_avg_cell_temp,
 			air_temperature,
-			battery_preheat_active
+			battery_preheat_active,
+			solar_curtailment
 		FROM mpc_decisions
 		WHERE timestamp >= $1
 		ORDER BY timestamp ASC
@@ -185,6 +188,7 @@ func (s *MinerScheduler) loadLatestMPCDecisions(ctx context.Context) ([]mpc.Cont
 		var batteryAvgCellTemp sql.NullFloat64
 		var airTemperature sql.NullFloat64
 		var batteryPreHeatActive sql.NullBool
+		var solarCurtailment sql.NullFloat64
 
 		err := rows.Scan(
 			&decision.Timestamp,
@@ -206,6 +210,7 @@ func (s *MinerScheduler) loadLatestMPCDecisions(ctx context.Context) ([]mpc.Cont
 			&batteryAvgCellTemp,
 			&airTemperature,
 			&batteryPreHeatActive,
+			&solarCurtailment,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan decision: %w", err)
@@ -226,6 +231,9 @@ func (s *MinerScheduler) loadLatestMPCDecisions(ctx context.Context) ([]mpc.Cont
 		if batteryPreHeatActive.Valid {
 			decision.BatteryPreHeatActive = batteryPreHeatActive.Bool
 		}
+		if solarCurtailment.Valid {
+			decision.SolarCurtailment = solarCurtailment.Float64
+		}
 
 		decisions = append(decisions, decision)
 	}
@@ -246,5 +254,5 @@ func (s *MinerScheduler) loadLatestMPCDecisions(ctx context.Context) ([]mpc.Cont
 
 // getCurrentTimestamp returns the current Unix timestamp
 func (s *MinerScheduler) getCurrentTimestamp() int64 {
-	return time.Now().Unix()
+	return s.now().Unix()
 }
@@ -0,0 +1,607 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/meteo"
+	"github.com/devskill-org/ems/mpc"
+	"github.com/devskill-org/ems/sigenergy"
+)
+
+func TestEffectiveSOCWindow(t *testing.T) {
+	tests := []struct {
+		name        string
+		minSOC      float64
+		maxSOC      float64
+		threshold   float64
+		shrink      float64
+		soh         float64
+		expectedMin float64
+		expectedMax float64
+	}{
+		{name: "disabled", minSOC: 0.1, maxSOC: 0.9, threshold: 0, shrink: 0.01, soh: 70, expectedMin: 0.1, expectedMax: 0.9},
+		{name: "SOH not reported", minSOC: 0.1, maxSOC: 0.9, threshold: 90, shrink: 0.01, soh: 0, expectedMin: 0.1, expectedMax: 0.9},
+		{name: "healthy battery", minSOC: 0.1, maxSOC: 0.9, threshold: 90, shrink: 0.01, soh: 98, expectedMin: 0.1, expectedMax: 0.9},
+		{name: "at threshold", minSOC: 0.1, maxSOC: 0.9, threshold: 90, shrink: 0.01, soh: 90, expectedMin: 0.1, expectedMax: 0.9},
+		{name: "slightly aged", minSOC: 0.1, maxSOC: 0.9, threshold: 90, shrink: 0.01, soh: 85, expectedMin: 0.15, expectedMax: 0.85},
+		{name: "aged", minSOC: 0.0, maxSOC: 1.0, threshold: 90, shrink: 0.01, soh: 70, expectedMin: 0.2, expectedMax: 0.8},
+		{name: "shrink limited to minimum window", minSOC: 0.1, maxSOC: 0.9, threshold: 90, shrink: 0.05, soh: 60, expectedMin: 0.45, expectedMax: 0.55},
+		{name: "configured window already narrow", minSOC: 0.45, maxSOC: 0.5, threshold: 90, shrink: 0.01, soh: 60, expectedMin: 0.45, expectedMax: 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				BatteryMinSOC:          tt.minSOC,
+				BatteryMaxSOC:          tt.maxSOC,
+				BatterySOHThreshold:    tt.threshold,
+				BatterySOCShrinkPerSOH: tt.shrink,
+			}
+			minSOC, maxSOC := effectiveSOCWindow(config, tt.soh)
+			if math.Abs(minSOC-tt.expectedMin) > 1e-9 || math.Abs(maxSOC-tt.expectedMax) > 1e-9 {
+				t.Errorf("Expected SOC window %.2f-%.2f, got %.2f-%.2f", tt.expectedMin, tt.expectedMax, minSOC, maxSOC)
+			}
+		})
+	}
+}
+
+func TestHardwareSOCWindow(t *testing.T) {
+	tests := []struct {
+		name         string
+		minSOC       float64
+		maxSOC       float64
+		dischargeOff float64 // %
+		chargeOff    float64 // %
+		expectedMin  float64
+		expectedMax  float64
+	}{
+		{name: "cutoffs not reported", minSOC: 0.1, maxSOC: 0.9, expectedMin: 0.1, expectedMax: 0.9},
+		{name: "cutoffs tighten window", minSOC: 0.0, maxSOC: 1.0, dischargeOff: 15, chargeOff: 95, expectedMin: 0.15, expectedMax: 0.95},
+		{name: "only charge-off reported", minSOC: 0.1, maxSOC: 1.0, chargeOff: 90, expectedMin: 0.1, expectedMax: 0.9},
+		{name: "configured window already narrower", minSOC: 0.2, maxSOC: 0.8, dischargeOff: 10, chargeOff: 100, expectedMin: 0.2, expectedMax: 0.8},
+		{name: "cutoffs outside window ignored", minSOC: 0.1, maxSOC: 0.3, dischargeOff: 50, chargeOff: 100, expectedMin: 0.1, expectedMax: 0.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plantInfo := &sigenergy.PlantRunningInfo{ESSDischargeOffSOC: tt.dischargeOff, ESSChargeOffSOC: tt.chargeOff}
+			minSOC, maxSOC := hardwareSOCWindow(tt.minSOC, tt.maxSOC, plantInfo)
+			if math.Abs(minSOC-tt.expectedMin) > 1e-9 || math.Abs(maxSOC-tt.expectedMax) > 1e-9 {
+				t.Errorf("Expected SOC window %.2f-%.2f, got %.2f-%.2f", tt.expectedMin, tt.expectedMax, minSOC, maxSOC)
+			}
+		})
+	}
+}
+
+func TestHardwareSOCWindow_PlanStaysWithinCutoffs(t *testing.T) {
+	plantInfo := &sigenergy.PlantRunningInfo{ESSSOC: 50, ESSDischargeOffSOC: 20, ESSChargeOffSOC: 80}
+	minSOC, maxSOC := hardwareSOCWindow(0, 1, plantInfo)
+
+	// Cheap hours followed by expensive ones make the optimizer fill and then empty the battery
+	start := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	forecast := make([]mpc.TimeSlot, 12)
+	for i := range forecast {
+		forecast[i] = mpc.TimeSlot{
+			Hour:         i,
+			Timestamp:    start.Add(time.Duration(i) * time.Hour).Unix(),
+			ImportPrice:  0.02,
+			ExportPrice:  0.01,
+			LoadForecast: 1.0,
+		}
+		if i >= 6 {
+			forecast[i].ImportPrice = 0.50
+			forecast[i].ExportPrice = 0.40
+		}
+	}
+
+	controller := mpc.NewController(mpc.SystemConfig{
+		BatteryCapacity:     10,
+		BatteryMaxCharge:    5,
+		BatteryMaxDischarge: 5,
+		BatteryMinSOC:       minSOC,
+		BatteryMaxSOC:       maxSOC,
+		BatteryEfficiency:   0.95,
+		MaxGridImport:       20,
+		MaxGridExport:       20,
+	}, len(forecast), plantInfo.ESSSOC/100)
+	decisions := controller.Optimize(forecast)
+	if len(decisions) == 0 {
+		t.Fatal("Expected decisions")
+	}
+
+	lowest, highest := 1.0, 0.0
+	for _, dec := range decisions {
+		lowest, highest = min(lowest, dec.BatterySOC), max(highest, dec.BatterySOC)
+	}
+	if lowest < 0.2-mpcCheckTolerance || highest > 0.8+mpcCheckTolerance {
+		t.Errorf("Expected plan within the hardware cutoffs 20%%-80%%, got SOC %.1f%%-%.1f%%", lowest*100, highest*100)
+	}
+	if highest < 0.8-0.05 || lowest > 0.2+0.05 {
+		t.Errorf("Expected plan to use the SOC window up to the cutoffs, got SOC %.1f%%-%.1f%%", lowest*100, highest*100)
+	}
+}
+
+func TestArbitrageSpread(t *testing.T) {
+	tests := []struct {
+		name     string
+		prices   [][2]float64 // import, export price per slot
+		expected float64
+	}{
+		{name: "flat prices", prices: [][2]float64{{0.1, 0.05}, {0.1, 0.05}}, expected: 0.1 - 0.1/0.125},
+		{name: "import price peak", prices: [][2]float64{{0.1, 0.05}, {0.5, 0.05}}, expected: 0.5 - 0.1/0.125},
+		{name: "export price peak", prices: [][2]float64{{0.1, 0.05}, {0.3, 0.9}}, expected: 0.9 - 0.1/0.125},
+		{name: "no slots", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forecast := make([]mpc.TimeSlot, len(tt.prices))
+			for i, p := range tt.prices {
+				forecast[i] = mpc.TimeSlot{ImportPrice: p[0], ExportPrice: p[1]}
+			}
+			if spread := arbitrageSpread(forecast, 0.5); math.Abs(spread-tt.expected) > 1e-9 {
+				t.Errorf("Expected spread %.4f, got %.4f", tt.expected, spread)
+			}
+		})
+	}
+}
+
+func TestRunMPCOptimize_FlatPriceSelfConsumption(t *testing.T) {
+	tests := []struct {
+		name         string
+		spike        float64
+		expectedSkip bool
+	}{
+		{name: "flat price day", spike: 50, expectedSkip: true},
+		{name: "evening price peak", spike: 400, expectedSkip: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marketData, dayStart := spikeDay(t, 50, tt.spike, 18)
+
+			config := testConfig()
+			config.DryRun = true
+			config.CheckPriceInterval = time.Hour
+			config.BatteryCapacity = 10
+			config.BatteryMaxCharge = 5
+			config.BatteryMaxDischarge = 5
+			config.BatteryMaxSOC = 1
+			config.BatteryEfficiency = 0.92
+			config.BatteryDegradationCost = 0.05
+			config.MaxGridImport = 20
+			config.MaxGridExport = 20
+			config.ImportPriceOperatorFee = 8.5
+			config.ImportPriceDeliveryFee = 40
+			config.ExportPriceOperatorFee = 17
+			config.FlatPriceSelfConsumption = true
+
+			scheduler := newTestScheduler(config)
+			var buf bytes.Buffer
+			scheduler.logger = log.New(&buf, "", 0)
+			scheduler.setClock(&simulatedClock{now: dayStart.Add(time.Hour)})
+			scheduler.plantInfoFunc = func(_ *Config) (*sigenergy.PlantRunningInfo, error) {
+				return &sigenergy.PlantRunningInfo{ESSSOC: 50, ESSAvgCellTemperature: 20}, nil
+			}
+			scheduler.weatherCache.Set(&meteo.METJSONForecast{})
+			scheduler.mu.Lock()
+			scheduler.pricesMarketData = marketData
+			scheduler.pricesMarketDataExpiry = dayStart.AddDate(0, 0, 1)
+			// A plan from an earlier run must not be re-executed when arbitrage is skipped
+			scheduler.mpcDecisions = []mpc.ControlDecision{{Timestamp: dayStart.Unix(), BatteryDischarge: 5}}
+			scheduler.mu.Unlock()
+
+			if err := scheduler.RunMPCOptimize(context.Background()); err != nil {
+				t.Fatalf("RunMPCOptimize failed: %v", err)
+			}
+
+			decisions := scheduler.GetMPCDecisions()
+			skipped := strings.Contains(buf.String(), "skipping battery arbitrage")
+			if skipped != tt.expectedSkip {
+				t.Fatalf("Expected arbitrage skipped %v, got log:\n%s", tt.expectedSkip, buf.String())
+			}
+			if tt.expectedSkip {
+				if len(decisions) != 0 {
+					t.Errorf("Expected no battery plan on a flat price day, got %d decisions", len(decisions))
+				}
+				if !strings.Contains(buf.String(), "Would set battery to maximum self-consumption mode") {
+					t.Errorf("Expected maximum self-consumption to be commanded, got log:\n%s", buf.String())
+				}
+				if status := scheduler.GetStatus(); status.ActiveStrategy != StrategySelfConsumption {
+					t.Errorf("Expected active strategy %s, got %s", StrategySelfConsumption, status.ActiveStrategy)
+				}
+				return
+			}
+
+			// The price peak is worth a cycle: the battery discharges into it
+			discharged := false
+			for _, dec := range decisions {
+				if time.Unix(dec.Timestamp, 0).Equal(dayStart.Add(18*time.Hour)) && dec.BatteryDischarge > 0 {
+					discharged = true
+				}
+			}
+			if !discharged {
+				t.Errorf("Expected the battery to discharge into the 18:00 price peak, got %+v", decisions)
+			}
+		})
+	}
+}
+
+func TestRunMPCOptimize_InitialBatteryTemperature(t *testing.T) {
+	marketData, dayStart := spikeDay(t, 50, 400, 18)
+
+	config := testConfig()
+	config.DryRun = true
+	config.CheckPriceInterval = time.Hour
+	config.BatteryCapacity = 10
+	config.BatteryMaxCharge = 5
+	config.BatteryMaxDischarge = 5
+	config.BatteryMaxSOC = 1
+	config.BatteryEfficiency = 0.92
+	config.MaxGridImport = 20
+	config.MaxGridExport = 20
+
+	scheduler := newTestScheduler(config)
+	var buf bytes.Buffer
+	scheduler.logger = log.New(&buf, "", 0)
+	scheduler.setClock(&simulatedClock{now: dayStart.Add(time.Hour)})
+	scheduler.plantInfoFunc = func(_ *Config) (*sigenergy.PlantRunningInfo, error) {
+		return &sigenergy.PlantRunningInfo{ESSSOC: 50, ESSAvgCellTemperature: -7.5}, nil
+	}
+	scheduler.weatherCache.Set(&meteo.METJSONForecast{})
+	scheduler.mu.Lock()
+	scheduler.pricesMarketData = marketData
+	scheduler.pricesMarketDataExpiry = dayStart.AddDate(0, 0, 1)
+	scheduler.mu.Unlock()
+
+	if err := scheduler.RunMPCOptimize(context.Background()); err != nil {
+		t.Fatalf("RunMPCOptimize failed: %v", err)
+	}
+
+	// The first slot starts from the cell temperature reported by the inverter, not the room temperature default
+	decisions := scheduler.GetMPCDecisions()
+	if len(decisions) == 0 {
+		t.Fatalf("Expected MPC decisions, got log:\n%s", buf.String())
+	}
+	if decisions[0].BatteryAvgCellTemp != -7.5 {
+		t.Errorf("Expected the first slot to start at -7.5 °C, got %.1f °C", decisions[0].BatteryAvgCellTemp)
+	}
+	if !strings.Contains(buf.String(), "Initial battery temperature: -7.5 °C") {
+		t.Errorf("Expected the initial battery temperature to be logged, got:\n%s", buf.String())
+	}
+}
+
+func TestRoundESSSetpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		power    float64
+		step     float64
+		limit    float64
+		expected float64
+	}{
+		{name: "no step", power: 3.14159, step: 0, limit: 12, expected: 3.14159},
+		{name: "rounds down", power: 3.14159, step: 0.1, limit: 12, expected: 3.1},
+		{name: "rounds up", power: 2.96, step: 0.1, limit: 12, expected: 3.0},
+		{name: "half kW step", power: 1.8, step: 0.5, limit: 12, expected: 2.0},
+		{name: "tiny setpoint rounds to zero", power: 0.04, step: 0.1, limit: 12, expected: 0},
+		{name: "clamped to limit", power: 12.34, step: 0.1, limit: 12, expected: 12},
+		{name: "limit off the step grid", power: 11.9, step: 0.5, limit: 11.8, expected: 11.5},
+		{name: "negative clamped to zero", power: -0.3, step: 0.1, limit: 12, expected: 0},
+		{name: "no limit", power: 15.26, step: 0.1, limit: 0, expected: 15.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := roundESSSetpoint(tt.power, tt.step, tt.limit); math.Abs(result-tt.expected) > 1e-9 {
+				t.Errorf("Expected setpoint %.5f, got %.5f", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestPVPowerLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxSolarPower float64
+		decision      mpc.ControlDecision
+		expected      float64
+		set           bool
+	}{
+		{name: "curtailing", maxSolarPower: 30, decision: mpc.ControlDecision{SolarForecast: 12, SolarCurtailment: 4.5}, expected: 7.5, set: true},
+		{name: "curtailing all solar", maxSolarPower: 30, decision: mpc.ControlDecision{SolarForecast: 3, SolarCurtailment: 3}, expected: 0, set: true},
+		{name: "not curtailing lifts the limit", maxSolarPower: 30, decision: mpc.ControlDecision{SolarForecast: 12}, expected: 30, set: true},
+		{name: "without max_solar_power", maxSolarPower: 0, decision: mpc.ControlDecision{SolarForecast: 12, SolarCurtailment: 4.5}, set: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			config.MaxSolarPower = tt.maxSolarPower
+			limit, set := pvPowerLimit(&tt.decision, config)
+			if set != tt.set || math.Abs(limit-tt.expected) > 1e-9 {
+				t.Errorf("Expected PV power limit %.2f kW (set %v), got %.2f kW (%v)", tt.expected, tt.set, limit, set)
+			}
+		})
+	}
+}
+
+func TestSmoothSolarForecast(t *testing.T) {
+	// Jumpy daytime estimates between two nights
+	raw := []float64{0, 0, 2, 9, 4, 12, 6, 14, 5, 11, 3, 0, 0}
+
+	variance := func(series []float64) float64 {
+		mean := 0.0
+		for _, v := range series {
+			mean += v
+		}
+		mean /= float64(len(series))
+		sum := 0.0
+		for _, v := range series {
+			sum += (v - mean) * (v - mean)
+		}
+		return sum / float64(len(series))
+	}
+	// Variance of the hour-to-hour changes measures how jumpy the series is
+	stepVariance := func(series []float64) float64 {
+		steps := make([]float64, len(series)-1)
+		for i := range steps {
+			steps[i] = series[i+1] - series[i]
+		}
+		return variance(steps)
+	}
+
+	smoothed := smoothSolarForecast(raw, 0.3)
+	if len(smoothed) != len(raw) {
+		t.Fatalf("Expected %d values, got %d", len(raw), len(smoothed))
+	}
+	if stepVariance(smoothed) >= stepVariance(raw) {
+		t.Errorf("Expected smoothed step variance %.2f below raw %.2f", stepVariance(smoothed), stepVariance(raw))
+	}
+	if variance(smoothed) >= variance(raw) {
+		t.Errorf("Expected smoothed variance %.2f below raw %.2f", variance(smoothed), variance(raw))
+	}
+
+	for i, power := range raw {
+		if power == 0 && smoothed[i] != 0 {
+			t.Errorf("Hour %d: expected no solar power without sun, got %.2f", i, smoothed[i])
+		}
+	}
+
+	// Alpha 1 keeps the raw series
+	for i, power := range smoothSolarForecast(raw, 1) {
+		if math.Abs(power-raw[i]) > 1e-9 {
+			t.Errorf("Hour %d: expected raw value %.2f with alpha 1, got %.2f", i, raw[i], power)
+		}
+	}
+}
+
+// clearSkyForecast returns a forecast with one cloudless step per hour starting at start
+func clearSkyForecast(start time.Time, hours int) *meteo.METJSONForecast {
+	timeseries := make([]meteo.ForecastTimeStep, hours)
+	for i := range timeseries {
+		timeseries[i] = meteo.ForecastTimeStep{
+			Time: start.Add(time.Duration(i) * time.Hour),
+			Data: &meteo.ForecastTimeStepData{
+				Instant: &meteo.ForecastInstantData{
+					Details: &meteo.ForecastTimeInstant{
+						CloudAreaFraction: meteo.Float64Ptr(0),
+						AirTemperature:    meteo.Float64Ptr(15),
+					},
+				},
+			},
+		}
+	}
+	return &meteo.METJSONForecast{Properties: &meteo.Forecast{Timeseries: timeseries}}
+}
+
+func TestEstimateSolarPowerFromWeather_ExtremeInputs(t *testing.T) {
+	midsummer := time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC)
+	midwinter := time.Date(2024, 12, 21, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		latitude  float64
+		longitude float64
+		start     time.Time
+		expectSun bool // some hour of the day is expected to produce solar power
+	}{
+		{name: "regular location", latitude: 56.9496, longitude: 24.1052, start: midsummer, expectSun: true},
+		{name: "polar day", latitude: 89.9, longitude: 0, start: midsummer, expectSun: true},
+		{name: "polar night", latitude: 89.9, longitude: 0, start: midwinter},
+		{name: "south pole", latitude: -90, longitude: 180, start: midsummer},
+		{name: "NaN latitude", latitude: math.NaN(), longitude: 24.1052, start: midsummer},
+		{name: "infinite longitude", latitude: 56.9496, longitude: math.Inf(1), start: midsummer},
+		{name: "latitude out of range", latitude: 123, longitude: 24.1052, start: midsummer},
+		{name: "zero time", latitude: 56.9496, longitude: 24.1052, start: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			config.Latitude = tt.latitude
+			config.Longitude = tt.longitude
+			scheduler := newTestScheduler(config)
+			forecast := clearSkyForecast(tt.start, 24)
+
+			sun := false
+			for hour := range 24 {
+				target := tt.start.Add(time.Duration(hour) * time.Hour)
+				if tt.start.IsZero() {
+					target = time.Time{}
+				}
+				solar, _, _, _ := scheduler.estimateSolarPowerFromWeather(forecast, target, 10.0, 5.0)
+				if math.IsNaN(solar) || math.IsInf(solar, 0) || solar < 0 || solar > 10.0 {
+					t.Fatalf("Hour %d: expected solar power within 0-10 kW, got %v", hour, solar)
+				}
+				if solar > 0 {
+					sun = true
+				}
+			}
+			if sun != tt.expectSun {
+				t.Errorf("Expected solar power during the day %v, got %v", tt.expectSun, sun)
+			}
+		})
+	}
+}
+
+func TestEstimateHourlySolarEnergy(t *testing.T) {
+	// Riga on midsummer, sunrise is around 01:30 UTC and sunset around 19:20 UTC
+	day := time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC)
+	config := testConfig()
+	config.Latitude = 56.9496
+	config.Longitude = 24.1052
+	scheduler := newTestScheduler(config)
+	forecast := clearSkyForecast(day, 24)
+
+	instant := func(at time.Time) float64 {
+		power, _, _, _ := scheduler.estimateSolarPowerFromWeather(forecast, at, 10.0, 5.0)
+		return power
+	}
+
+	tests := []struct {
+		name   string
+		hour   int
+		rising bool
+	}{
+		{name: "hour of sunrise", hour: 1, rising: true},
+		{name: "hour after sunrise", hour: 3, rising: true},
+		{name: "hour before sunset", hour: 18, rising: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := day.Add(time.Duration(tt.hour) * time.Hour)
+			energy := scheduler.estimateHourlySolarEnergy(forecast, start, 10.0, 5.0)
+			startPower, endPower := instant(start), instant(start.Add(time.Hour))
+			if tt.rising && energy <= startPower {
+				t.Errorf("Expected the hourly energy %.3f kWh above the start-of-hour power %.3f kW while the sun rises", energy, startPower)
+			}
+			if !tt.rising && energy >= startPower {
+				t.Errorf("Expected the hourly energy %.3f kWh below the start-of-hour power %.3f kW while the sun sets", energy, startPower)
+			}
+			if energy < math.Min(startPower, endPower) || energy > math.Max(startPower, endPower) {
+				t.Errorf("Expected the hourly energy %.3f kWh between %.3f and %.3f kW", energy, startPower, endPower)
+			}
+		})
+	}
+
+	// No energy during the night
+	if energy := scheduler.estimateHourlySolarEnergy(forecast, day.Add(22*time.Hour), 10.0, 5.0); energy != 0 {
+		t.Errorf("Expected no solar energy at night, got %.3f kWh", energy)
+	}
+}
+
+func TestGetSolarForecast_StaleForecast(t *testing.T) {
+	// Early morning in Riga, the forecast covers the whole sunny day
+	now := time.Date(2024, 6, 21, 3, 0, 0, 0, time.UTC)
+	plantInfo := &sigenergy.PlantRunningInfo{PhotovoltaicPower: 0.5}
+
+	tests := []struct {
+		name        string
+		age         time.Duration
+		maxAge      time.Duration
+		expectSolar bool
+	}{
+		{name: "fresh forecast", age: time.Hour, maxAge: 6 * time.Hour, expectSolar: true},
+		{name: "stale forecast", age: 12 * time.Hour, maxAge: 6 * time.Hour, expectSolar: false},
+		{name: "stale forecast without limit", age: 12 * time.Hour, maxAge: 0, expectSolar: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			config.MaxSolarPower = 10
+			config.WeatherMaxAge = tt.maxAge
+			scheduler := newTestScheduler(config)
+			var buf bytes.Buffer
+			scheduler.logger = log.New(&buf, "", 0)
+			scheduler.setClock(&simulatedClock{now: now})
+			forecast := clearSkyForecast(now, 36)
+			forecast.Properties.Meta.UpdatedAt = now.Add(-tt.age)
+
+			solar, weather, err := scheduler.getSolarForecast(config, now, forecast, plantInfo, time.Hour, 36*time.Hour)
+			if err != nil {
+				t.Fatalf("getSolarForecast failed: %v", err)
+			}
+			if solar[0] != 0.5 {
+				t.Errorf("Expected the current PV power in the first hour, got %.2f", solar[0])
+			}
+			total := 0.0
+			for i := 1; i < 36; i++ {
+				total += solar[i]
+			}
+			if (total > 0) != tt.expectSolar {
+				t.Errorf("Expected solar beyond the current hour %v, got %.2f kWh", tt.expectSolar, total)
+			}
+			if stale := strings.Contains(buf.String(), "weather forecast is 12h0m0s old"); stale == tt.expectSolar {
+				t.Errorf("Expected stale warning %v, got log:\n%s", !tt.expectSolar, buf.String())
+			}
+			if weather[5].AirTemperature != 15 {
+				t.Errorf("Expected the weather data to be kept, got %+v", weather[5])
+			}
+		})
+	}
+}
+
+func TestGetSolarForecast_QuarterHourResolution(t *testing.T) {
+	// Early morning in Riga, the forecast covers the whole sunny day
+	now := time.Date(2024, 6, 21, 3, 0, 0, 0, time.UTC)
+	plantInfo := &sigenergy.PlantRunningInfo{PhotovoltaicPower: 0.5}
+	config := testConfig()
+	config.MaxSolarPower = 10
+	scheduler := newTestScheduler(config)
+	forecast := clearSkyForecast(now, 36)
+
+	hourly, _, err := scheduler.getSolarForecast(config, now, forecast, plantInfo, time.Hour, 36*time.Hour)
+	if err != nil {
+		t.Fatalf("getSolarForecast failed: %v", err)
+	}
+	quarters, weather, err := scheduler.getSolarForecast(config, now, forecast, plantInfo, 15*time.Minute, 36*time.Hour)
+	if err != nil {
+		t.Fatalf("getSolarForecast failed: %v", err)
+	}
+	if len(quarters) != 144 || len(weather) != 144 {
+		t.Fatalf("Expected 144 quarter-hour slots, got %d solar and %d weather", len(quarters), len(weather))
+	}
+	if quarters[0] != 0.5 {
+		t.Errorf("Expected the current PV power in the first slot, got %.2f", quarters[0])
+	}
+
+	// The quarters of an hour average to the hourly estimate
+	for hour := 1; hour < 36; hour++ {
+		average := (quarters[4*hour] + quarters[4*hour+1] + quarters[4*hour+2] + quarters[4*hour+3]) / 4
+		if math.Abs(average-hourly[hour]) > 1e-9 {
+			t.Errorf("Hour %d: expected the quarters to average to %.3f kW, got %.3f", hour, hourly[hour], average)
+		}
+	}
+
+	// No step at the hour boundaries: the change into a new hour is no larger than the changes within hours
+	maxWithin, maxBoundary := 0.0, 0.0
+	for i := 2; i < len(quarters); i++ {
+		step := math.Abs(quarters[i] - quarters[i-1])
+		if i%4 == 0 {
+			maxBoundary = max(maxBoundary, step)
+		} else {
+			maxWithin = max(maxWithin, step)
+		}
+	}
+	if maxWithin == 0 || maxBoundary > maxWithin*1.05 {
+		t.Errorf("Expected continuity across hour boundaries, got steps of up to %.3f kW there and %.3f kW within hours", maxBoundary, maxWithin)
+	}
+}
+
+func TestValidSunTime(t *testing.T) {
+	target := time.Date(2024, 6, 21, 12, 0, 0, 0, time.UTC)
+	if !validSunTime(target.Add(-8*time.Hour), target) {
+		t.Error("Expected sunrise on the same day to be valid")
+	}
+	// suncalc times derived from NaN end up far from the target
+	if validSunTime(time.Unix(0, math.MinInt64), target) {
+		t.Error("Expected a time centuries away to be invalid")
+	}
+}
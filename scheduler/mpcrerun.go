@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"github.com/devskill-org/ems/entsoe"
+	"github.com/devskill-org/ems/meteo"
+)
+
+// requestMPCRerun asks the MPC task for an out-of-schedule run when mpc_rerun_on_update is enabled.
+// Further requests while one is pending are merged into it.
+func (s *MinerScheduler) requestMPCRerun(reason string) {
+	if !s.GetConfig().MPCRerunOnUpdate {
+		return
+	}
+	select {
+	case s.mpcRerun <- struct{}{}:
+		s.logger.Printf("%s: re-running MPC optimization", reason)
+	default:
+	}
+}
+
+// clearMPCRerun drops a pending re-run request
+func (s *MinerScheduler) clearMPCRerun() {
+	select {
+	case <-s.mpcRerun:
+	default:
+	}
+}
+
+// priceDocumentUpdated reports whether a downloaded price document revises the previous one or extends it,
+// e.g. with the next day prices. The first document is no update, MPC has not planned with other prices yet.
+func priceDocumentUpdated(previous, current *entsoe.PublicationMarketData) bool {
+	if previous == nil || current == nil {
+		return false
+	}
+	return current.RevisionNumber != previous.RevisionNumber ||
+		!current.PeriodTimeInterval.End.Equal(previous.PeriodTimeInterval.End)
+}
+
+// weatherForecastUpdated reports whether a fetched weather forecast was updated since the previous one
+func weatherForecastUpdated(previous, current *meteo.METJSONForecast) bool {
+	if previous == nil || previous.Properties == nil || current == nil || current.Properties == nil {
+		return false
+	}
+	return !current.Properties.Meta.UpdatedAt.Equal(previous.Properties.Meta.UpdatedAt)
+}
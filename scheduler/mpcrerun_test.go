@@ -0,0 +1,97 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMPCRerun_PriceRevisionTriggersExtraRun(t *testing.T) {
+	xmlData, err := os.ReadFile("../test_data/Energy_Prices_202509052100-202509062100.xml")
+	if err != nil {
+		t.Fatalf("Failed to read test data file: %v", err)
+	}
+	var revision atomic.Value
+	revision.Store("1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		doc := strings.Replace(string(xmlData), "<revisionNumber>1</revisionNumber>",
+			"<revisionNumber>"+revision.Load().(string)+"</revisionNumber>", 1)
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(doc))
+	}))
+	defer server.Close()
+
+	config := testConfigWithServer(server)
+	config.MPCRerunOnUpdate = true
+	scheduler := newTestScheduler(config)
+	var buf bytes.Buffer
+	scheduler.logger = log.New(&buf, "", 0)
+
+	// The MPC task loop with a schedule too long to run again during the test
+	runs := make(chan struct{}, 10)
+	task := PeriodicTask{
+		name:     "MPC",
+		interval: time.Hour,
+		trigger:  scheduler.mpcRerun,
+		state:    &taskState{},
+		runFunc: func(context.Context) error {
+			runs <- struct{}{}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go task.run(ctx, make(chan struct{}), log.New(&bytes.Buffer{}, "", 0))
+
+	waitRun := func(expected bool, step string) {
+		t.Helper()
+		select {
+		case <-runs:
+			if !expected {
+				t.Fatalf("%s: expected no extra MPC run, log:\n%s", step, buf.String())
+			}
+		case <-time.After(200 * time.Millisecond):
+			if expected {
+				t.Fatalf("%s: expected an extra MPC run, log:\n%s", step, buf.String())
+			}
+		}
+	}
+	download := func() {
+		t.Helper()
+		scheduler.mu.Lock()
+		scheduler.pricesMarketDataExpiry = time.Time{}
+		scheduler.mu.Unlock()
+		if _, err := scheduler.GetMarketData(context.Background()); err != nil {
+			t.Fatalf("GetMarketData failed: %v", err)
+		}
+	}
+
+	waitRun(true, "scheduled run")
+
+	// The first document and an unchanged one are no reason to plan again
+	download()
+	waitRun(false, "first document")
+	download()
+	waitRun(false, "same revision")
+
+	// A revised document is planned with right away
+	revision.Store("2")
+	download()
+	waitRun(true, "revision 2")
+	if !strings.Contains(buf.String(), "Price document revision 2") {
+		t.Errorf("Expected the re-run to be logged, got:\n%s", buf.String())
+	}
+
+	// Disabled by default
+	scheduler.config.MPCRerunOnUpdate = false
+	revision.Store("3")
+	download()
+	waitRun(false, "disabled")
+}
@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"slices"
+	"time"
+
+	"github.com/devskill-org/ems/entsoe"
+)
+
+// priceSpikeSampleInterval is the step at which prices are sampled, it weights every period of the day
+// equally whatever the resolution of the price document
+const priceSpikeSampleInterval = 15 * time.Minute
+
+// priceSpike describes a forecast price far above the median price of its day
+type priceSpike struct {
+	at     time.Time // sampled time with the spike price
+	price  float64   // EUR/MWh
+	median float64   // EUR/MWh median price of the day
+}
+
+// dailyPrices returns the prices of the day containing t in t's location, sampled every priceSpikeSampleInterval
+func dailyPrices(marketData *entsoe.PublicationMarketData, t time.Time) []float64 {
+	dayStart := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	var prices []float64
+	for sample := dayStart; sample.Before(dayEnd); sample = sample.Add(priceSpikeSampleInterval) {
+		if price, found := marketData.LookupPriceByTime(sample); found {
+			prices = append(prices, price)
+		}
+	}
+	return prices
+}
+
+// median returns the median of values, sorting them in place. values must not be empty.
+func median(values []float64) float64 {
+	slices.Sort(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// dailyMedianPrice returns the median price of the day containing t in t's location
+func dailyMedianPrice(marketData *entsoe.PublicationMarketData, t time.Time) (float64, bool) {
+	prices := dailyPrices(marketData, t)
+	if len(prices) == 0 {
+		return 0, false
+	}
+	return median(prices), true
+}
+
+// findPriceSpike returns the first price from now until lead ahead that exceeds the median price of its day
+// times factor. Days with a median price at or below zero have no spikes.
+func findPriceSpike(marketData *entsoe.PublicationMarketData, now time.Time, lead time.Duration, factor float64) (priceSpike, bool) {
+	if marketData == nil || factor <= 0 {
+		return priceSpike{}, false
+	}
+
+	for offset := time.Duration(0); offset <= lead; offset += priceSpikeSampleInterval {
+		at := now.Add(offset)
+		price, found := marketData.LookupPriceByTime(at)
+		if !found {
+			continue
+		}
+		median, ok := dailyMedianPrice(marketData, at)
+		if !ok || median <= 0 {
+			continue
+		}
+		if price > median*factor {
+			return priceSpike{at: at, price: price, median: median}, true
+		}
+	}
+	return priceSpike{}, false
+}
+
+// detectPriceSpike reports whether a price spike is forecast from now until price_spike_lead_time ahead,
+// raising an alert for it. Miners go to standby for a spike regardless of the price limit.
+func (s *MinerScheduler) detectPriceSpike(now time.Time) bool {
+	if s.config.PriceSpikeFactor <= 0 {
+		return false
+	}
+	location, err := time.LoadLocation(s.config.Location)
+	if err != nil {
+		return false
+	}
+
+	spike, found := findPriceSpike(s.GetPricesMarketData(), now.In(location), s.config.PriceSpikeLeadTime, s.config.PriceSpikeFactor)
+	if !found {
+		return false
+	}
+	s.logger.Printf("ALERT: Price spike: %.2f EUR/MWh at %s is above %.1f x the daily median of %.2f EUR/MWh, putting miners into standby",
+		spike.price, spike.at.Format(time.RFC3339), s.config.PriceSpikeFactor, spike.median)
+	return true
+}
@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/entsoe"
+)
+
+// spikeDay returns hourly prices for one local day with every hour at base except spikeHour at spike
+func spikeDay(t *testing.T, base, spike float64, spikeHour int) (*entsoe.PublicationMarketData, time.Time) {
+	t.Helper()
+	location, err := time.LoadLocation("Europe/Riga")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+	dayStart := time.Date(2024, 6, 15, 0, 0, 0, 0, location)
+
+	points := make([]entsoe.Point, 24)
+	for i := range points {
+		points[i] = entsoe.Point{Position: i + 1, PriceAmount: base}
+	}
+	points[spikeHour].PriceAmount = spike
+
+	interval := entsoe.TimeInterval{Start: dayStart.UTC(), End: dayStart.AddDate(0, 0, 1).UTC()}
+	return &entsoe.PublicationMarketData{
+		PeriodTimeInterval: interval,
+		TimeSeries: []entsoe.TimeSeries{
+			{
+				Period: entsoe.Period{
+					TimeInterval: interval,
+					Resolution:   time.Hour,
+					Points:       points,
+				},
+			},
+		},
+	}, dayStart
+}
+
+func TestDailyMedianPrice(t *testing.T) {
+	marketData, dayStart := spikeDay(t, 50, 300, 18)
+
+	median, ok := dailyMedianPrice(marketData, dayStart.Add(18*time.Hour))
+	if !ok || median != 50 {
+		t.Errorf("Expected median 50, got %.2f (found %v)", median, ok)
+	}
+
+	if _, ok := dailyMedianPrice(marketData, dayStart.AddDate(0, 0, 1)); ok {
+		t.Error("Expected no median for a day without prices")
+	}
+}
+
+func TestFindPriceSpike(t *testing.T) {
+	marketData, dayStart := spikeDay(t, 50, 300, 18)
+	negativeDay, _ := spikeDay(t, -5, 300, 18)
+
+	tests := []struct {
+		name       string
+		marketData *entsoe.PublicationMarketData
+		now        time.Time
+		lead       time.Duration
+		factor     float64
+		expected   bool
+	}{
+		{name: "spike hour", marketData: marketData, now: dayStart.Add(18 * time.Hour), factor: 3, expected: true},
+		{name: "end of spike hour", marketData: marketData, now: dayStart.Add(18*time.Hour + 59*time.Minute), factor: 3, expected: true},
+		{name: "before spike without lead time", marketData: marketData, now: dayStart.Add(17*time.Hour + 45*time.Minute), factor: 3, expected: false},
+		{name: "before spike within lead time", marketData: marketData, now: dayStart.Add(17*time.Hour + 45*time.Minute), lead: 15 * time.Minute, factor: 3, expected: true},
+		{name: "before spike beyond lead time", marketData: marketData, now: dayStart.Add(17 * time.Hour), lead: 30 * time.Minute, factor: 3, expected: false},
+		{name: "after spike", marketData: marketData, now: dayStart.Add(19 * time.Hour), lead: time.Hour, factor: 3, expected: false},
+		{name: "spike below factor", marketData: marketData, now: dayStart.Add(18 * time.Hour), factor: 10, expected: false},
+		{name: "disabled", marketData: marketData, now: dayStart.Add(18 * time.Hour), factor: 0, expected: false},
+		{name: "no market data", now: dayStart.Add(18 * time.Hour), factor: 3, expected: false},
+		{name: "negative median", marketData: negativeDay, now: dayStart.Add(18 * time.Hour), factor: 3, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spike, found := findPriceSpike(tt.marketData, tt.now, tt.lead, tt.factor)
+			if found != tt.expected {
+				t.Fatalf("Expected spike found %v, got %v (%+v)", tt.expected, found, spike)
+			}
+			if found && (spike.price != 300 || spike.median != 50) {
+				t.Errorf("Expected spike of 300 over median 50, got %+v", spike)
+			}
+		})
+	}
+}
+
+func TestDetectPriceSpike_StandbyMiners(t *testing.T) {
+	response, err := os.ReadFile("../test_data/avalon_litestat.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data file: %v", err)
+	}
+	marketData, dayStart := spikeDay(t, 50, 300, 18)
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected bool
+	}{
+		{name: "ordinary hour", now: dayStart.Add(12 * time.Hour), expected: false},
+		{name: "ahead of the spike hour", now: dayStart.Add(17*time.Hour + 50*time.Minute), expected: true},
+		{name: "spike hour", now: dayStart.Add(18*time.Hour + 30*time.Minute), expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts, _ := serveCountingMiners(t, 2, response)
+
+			config := testConfig()
+			config.DryRun = true
+			config.PriceLimit = 100
+			config.PriceSpikeFactor = 3
+			config.PriceSpikeLeadTime = 15 * time.Minute
+			scheduler := newTestScheduler(config)
+			var buf bytes.Buffer
+			scheduler.logger = log.New(&buf, "", 0)
+			scheduler.mu.Lock()
+			scheduler.pricesMarketData = marketData
+			scheduler.mu.Unlock()
+			for _, host := range hosts {
+				scheduler.discoveredMiners.Store(minerKey(host), host)
+			}
+
+			priceSpike := scheduler.detectPriceSpike(tt.now)
+			if priceSpike != tt.expected {
+				t.Fatalf("Expected price spike %v, got %v", tt.expected, priceSpike)
+			}
+			if got := strings.Contains(buf.String(), "ALERT: Price spike"); got != tt.expected {
+				t.Errorf("Expected price spike alert %v, got log:\n%s", tt.expected, buf.String())
+			}
+
+			// The current price is below the price limit, only the spike puts the mining miners into standby
+			if err := scheduler.manageMiners(context.Background(), 50, priceSpike); err != nil {
+				t.Fatalf("manageMiners failed: %v", err)
+			}
+			standby := strings.Count(buf.String(), "into standby (price spike")
+			if tt.expected && standby != len(hosts) {
+				t.Errorf("Expected %d miners put into standby for the spike, got %d:\n%s", len(hosts), standby, buf.String())
+			}
+			if !tt.expected && strings.Contains(buf.String(), "into standby") {
+				t.Errorf("Expected miners to keep mining, got log:\n%s", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package scheduler
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devskill-org/ems/entsoe"
+)
+
+// priceStatsWindowDays is the number of most recent days kept in the rolling price statistics
+const priceStatsWindowDays = 7
+
+// DailyPriceStats summarizes the spot prices of one day in the configured location, in EUR/MWh
+type DailyPriceStats struct {
+	Date   string  `json:"date"` // YYYY-MM-DD
+	Min    float64 `json:"min"`
+	P10    float64 `json:"p10"`
+	P25    float64 `json:"p25"`
+	Median float64 `json:"median"`
+	P75    float64 `json:"p75"`
+	P90    float64 `json:"p90"`
+	Max    float64 `json:"max"`
+}
+
+// priceStatsCache keeps the statistics of the most recent days of prices. The samples of all days in the
+// window are kept sorted so a price is ranked with two binary searches instead of scanning the documents.
+type priceStatsCache struct {
+	mu      sync.RWMutex
+	days    map[string][]float64 // sorted samples by date
+	stats   map[string]DailyPriceStats
+	samples []float64 // sorted samples of all days in the window
+}
+
+// percentile returns the p-th percentile (0-100) of sorted values with linear interpolation between
+// the closest ranks. values must not be empty.
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// dailyPriceStats computes the statistics of a day from its sorted samples
+func dailyPriceStats(date string, sorted []float64) DailyPriceStats {
+	return DailyPriceStats{
+		Date:   date,
+		Min:    sorted[0],
+		P10:    percentile(sorted, 10),
+		P25:    percentile(sorted, 25),
+		Median: percentile(sorted, 50),
+		P75:    percentile(sorted, 75),
+		P90:    percentile(sorted, 90),
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// update adds the days covered by a new price document and drops the days older than the window. A day covered
+// again replaces the stored one unless it has fewer prices, e.g. a document ending mid-day. Days are taken in
+// location and sampled like the price spike detection.
+func (c *priceStatsCache) update(marketData *entsoe.PublicationMarketData, location *time.Location) {
+	if marketData == nil {
+		return
+	}
+
+	days := make(map[string][]float64)
+	start := marketData.PeriodTimeInterval.Start.In(location)
+	end := marketData.PeriodTimeInterval.End.In(location)
+	for day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, location); day.Before(end); day = day.AddDate(0, 0, 1) {
+		prices := dailyPrices(marketData, day)
+		if len(prices) == 0 {
+			continue
+		}
+		slices.Sort(prices)
+		days[day.Format(time.DateOnly)] = prices
+	}
+	if len(days) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.days == nil {
+		c.days = make(map[string][]float64)
+	}
+	for date, prices := range days {
+		if len(prices) >= len(c.days[date]) {
+			c.days[date] = prices
+		}
+	}
+
+	dates := slices.Sorted(maps.Keys(c.days))
+	if len(dates) > priceStatsWindowDays {
+		for _, date := range dates[:len(dates)-priceStatsWindowDays] {
+			delete(c.days, date)
+		}
+	}
+
+	c.stats = make(map[string]DailyPriceStats, len(c.days))
+	c.samples = c.samples[:0]
+	for date, prices := range c.days {
+		c.stats[date] = dailyPriceStats(date, prices)
+		c.samples = append(c.samples, prices...)
+	}
+	slices.Sort(c.samples)
+}
+
+// snapshot returns the statistics of the days in the window sorted by date
+func (c *priceStatsCache) snapshot() []DailyPriceStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	stats := slices.Collect(maps.Values(c.stats))
+	slices.SortFunc(stats, func(a, b DailyPriceStats) int { return strings.Compare(a.Date, b.Date) })
+	return stats
+}
+
+// percentileRank returns the share of the samples in the window below price in %, samples equal to price
+// count half. It is false without samples.
+func (c *priceStatsCache) percentileRank(price float64) (float64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	n := len(c.samples)
+	if n == 0 {
+		return 0, false
+	}
+	below := sort.SearchFloat64s(c.samples, price)
+	atOrBelow := sort.Search(n, func(i int) bool { return c.samples[i] > price })
+	return (float64(below) + float64(atOrBelow-below)/2) / float64(n) * 100, true
+}
+
+// GetPriceStats returns the spot price statistics of the most recent days, oldest first
+func (s *MinerScheduler) GetPriceStats() []DailyPriceStats {
+	return s.priceStats.snapshot()
+}
+
+// PricePercentile ranks a spot price in EUR/MWh against the prices of the most recent days: 0 is below every
+// recent price, 100 above every one. It is false before any prices were downloaded.
+func (s *MinerScheduler) PricePercentile(price float64) (float64, bool) {
+	return s.priceStats.percentileRank(price)
+}
@@ -0,0 +1,163 @@
+package scheduler
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/entsoe"
+)
+
+// priceDays returns a document with hourly prices for consecutive local days from 2024-06-10 in Europe/Riga,
+// day d priced at prices(d, hour)
+func priceDays(t *testing.T, days int, prices func(day, hour int) float64) (*entsoe.PublicationMarketData, *time.Location) {
+	t.Helper()
+	location, err := time.LoadLocation("Europe/Riga")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+	start := time.Date(2024, 6, 10, 0, 0, 0, 0, location)
+
+	points := make([]entsoe.Point, 0, days*24)
+	for day := range days {
+		for hour := range 24 {
+			points = append(points, entsoe.Point{Position: len(points) + 1, PriceAmount: prices(day, hour)})
+		}
+	}
+
+	interval := entsoe.TimeInterval{Start: start.UTC(), End: start.AddDate(0, 0, days).UTC()}
+	return &entsoe.PublicationMarketData{
+		PeriodTimeInterval: interval,
+		TimeSeries: []entsoe.TimeSeries{
+			{
+				Period: entsoe.Period{
+					TimeInterval: interval,
+					Resolution:   time.Hour,
+					Points:       points,
+				},
+			},
+		},
+	}, location
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{10, 20, 30, 40, 50}
+	tests := []struct {
+		p        float64
+		expected float64
+	}{
+		{p: 0, expected: 10},
+		{p: 25, expected: 20},
+		{p: 50, expected: 30},
+		{p: 90, expected: 46},
+		{p: 100, expected: 50},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); math.Abs(got-tt.expected) > 1e-9 {
+			t.Errorf("percentile(%v) = %.2f, expected %.2f", tt.p, got, tt.expected)
+		}
+	}
+}
+
+func TestPriceStatsCache_Update(t *testing.T) {
+	// Day d has prices 10*d + hour, so every day spans 23 EUR/MWh from its own base
+	marketData, location := priceDays(t, 3, func(day, hour int) float64 { return float64(10*day + hour) })
+
+	var cache priceStatsCache
+	cache.update(marketData, location)
+
+	stats := cache.snapshot()
+	if len(stats) != 3 {
+		t.Fatalf("Expected statistics of 3 days, got %+v", stats)
+	}
+	for i, date := range []string{"2024-06-10", "2024-06-11", "2024-06-12"} {
+		day := stats[i]
+		base := float64(10 * i)
+		if day.Date != date {
+			t.Errorf("Expected day %d to be %s, got %s", i, date, day.Date)
+		}
+		if day.Min != base || day.Max != base+23 {
+			t.Errorf("Expected %s to range from %.0f to %.0f, got %.2f to %.2f", date, base, base+23, day.Min, day.Max)
+		}
+		// Each hour is sampled 4 times, so the median lies between hours 11 and 12
+		if day.Median != base+11.5 {
+			t.Errorf("Expected %s median %.1f, got %.2f", date, base+11.5, day.Median)
+		}
+		if day.P10 >= day.P25 || day.P25 >= day.Median || day.Median >= day.P75 || day.P75 >= day.P90 {
+			t.Errorf("Expected increasing percentiles for %s, got %+v", date, day)
+		}
+	}
+}
+
+func TestPriceStatsCache_Window(t *testing.T) {
+	marketData, location := priceDays(t, priceStatsWindowDays+2, func(day, _ int) float64 { return float64(day) })
+
+	var cache priceStatsCache
+	cache.update(marketData, location)
+
+	stats := cache.snapshot()
+	if len(stats) != priceStatsWindowDays {
+		t.Fatalf("Expected %d days, got %d", priceStatsWindowDays, len(stats))
+	}
+	if stats[0].Date != "2024-06-12" || stats[0].Min != 2 {
+		t.Errorf("Expected the window to start with the third day, got %+v", stats[0])
+	}
+
+	// A document covering fewer prices of a day does not replace it
+	partial, _ := priceDays(t, 1, func(_, _ int) float64 { return 1000 })
+	partial.PeriodTimeInterval.End = partial.PeriodTimeInterval.Start.Add(6 * time.Hour)
+	partial.TimeSeries[0].Period.Points = partial.TimeSeries[0].Period.Points[:6]
+	cache.update(partial, location)
+	if stats := cache.snapshot(); stats[0].Date != "2024-06-12" {
+		t.Errorf("Expected an older partial day to be dropped, got %+v", stats[0])
+	}
+}
+
+func TestPriceStatsCache_PercentileRank(t *testing.T) {
+	var cache priceStatsCache
+	if _, ok := cache.percentileRank(50); ok {
+		t.Error("Expected no rank without prices")
+	}
+
+	// Three days with the same prices 0..23 EUR/MWh
+	marketData, location := priceDays(t, 3, func(_, hour int) float64 { return float64(hour) })
+	cache.update(marketData, location)
+
+	tests := []struct {
+		name     string
+		price    float64
+		expected float64
+	}{
+		{name: "below every price", price: -10, expected: 0},
+		{name: "above every price", price: 100, expected: 100},
+		{name: "cheapest hour", price: 0, expected: 100.0 / 48},
+		{name: "between hours", price: 5.5, expected: 25},
+		{name: "middle", price: 11.5, expected: 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rank, ok := cache.percentileRank(tt.price)
+			if !ok || math.Abs(rank-tt.expected) > 1e-9 {
+				t.Errorf("Expected rank %.2f, got %.2f (found %v)", tt.expected, rank, ok)
+			}
+		})
+	}
+}
+
+func TestPricePercentile_Overview(t *testing.T) {
+	marketData, location := priceDays(t, 3, func(day, hour int) float64 { return float64(10*day + hour) })
+	scheduler := newTestScheduler(nil)
+	scheduler.config.Location = location.String()
+	scheduler.pricesMarketData = marketData
+	scheduler.priceStats.update(marketData, location)
+	// The last hour of the last day is the highest price of the window
+	scheduler.setClock(&simulatedClock{now: time.Date(2024, 6, 12, 23, 30, 0, 0, location)})
+
+	overview := (&WebServer{scheduler: scheduler}).buildOverview()
+	if overview.Price.Current == nil || *overview.Price.Current != 43 {
+		t.Fatalf("Expected current price 43, got %v", overview.Price.Current)
+	}
+	if overview.Price.Percentile == nil || *overview.Price.Percentile <= 99 {
+		t.Errorf("Expected the highest recent price to rank above 99, got %v", overview.Price.Percentile)
+	}
+}
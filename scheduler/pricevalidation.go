@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"math"
+	"time"
+
+	"github.com/devskill-org/ems/entsoe"
+)
+
+// priceBounds is the plausible spot price range in EUR/MWh
+type priceBounds struct {
+	low  float64
+	high float64
+}
+
+// priceValidator flags spot prices outside the plausible range configured by price_outlier_limit and
+// price_outlier_factor, protecting MPC from data glitches in the price document
+type priceValidator struct {
+	marketData *entsoe.PublicationMarketData
+	limit      float64
+	factor     float64
+	action     string
+	days       map[time.Time]priceBounds // bounds keyed by the start of the local day
+}
+
+// newPriceValidator returns a validator of the prices in marketData using the outlier settings of config
+func newPriceValidator(marketData *entsoe.PublicationMarketData, config *Config) *priceValidator {
+	return &priceValidator{
+		marketData: marketData,
+		limit:      config.PriceOutlierLimit,
+		factor:     config.PriceOutlierFactor,
+		action:     config.PriceOutlierAction,
+		days:       make(map[time.Time]priceBounds),
+	}
+}
+
+// bounds returns the plausible price range for the day containing t. The absolute bound is ±price_outlier_limit,
+// the relative bound is the daily median ± price_outlier_factor times the median absolute price of the day.
+func (v *priceValidator) bounds(t time.Time) priceBounds {
+	dayStart := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if b, ok := v.days[dayStart]; ok {
+		return b
+	}
+
+	b := priceBounds{low: math.Inf(-1), high: math.Inf(1)}
+	if v.limit > 0 {
+		b.low, b.high = -v.limit, v.limit
+	}
+	if v.factor > 0 && v.marketData != nil {
+		if prices := dailyPrices(v.marketData, dayStart); len(prices) > 0 {
+			absPrices := make([]float64, len(prices))
+			for i, p := range prices {
+				absPrices[i] = math.Abs(p)
+			}
+			// A day of zero prices has no scale to judge deviations by
+			if scale := median(absPrices); scale > 0 {
+				center := median(prices)
+				b.low = max(b.low, center-v.factor*scale)
+				b.high = min(b.high, center+v.factor*scale)
+			}
+		}
+	}
+	v.days[dayStart] = b
+	return b
+}
+
+// validate checks the spot price at t. An outlier is clamped to the bound it exceeds, or reported as
+// unreliable when price_outlier_action is "skip". Prices within the bounds are returned unchanged.
+func (v *priceValidator) validate(t time.Time, price float64) (validated float64, outlier, reliable bool) {
+	if v.limit <= 0 && v.factor <= 0 {
+		return price, false, true
+	}
+
+	b := v.bounds(t)
+	if price >= b.low && price <= b.high {
+		return price, false, true
+	}
+	if v.action == PriceOutlierSkip {
+		return price, true, false
+	}
+	return max(b.low, min(b.high, price)), true, true
+}
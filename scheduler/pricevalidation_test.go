@@ -0,0 +1,131 @@
+package scheduler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/entsoe"
+)
+
+func TestPriceValidator(t *testing.T) {
+	marketData, dayStart := spikeDay(t, 50, 3000, 18)
+	zeroDay, _ := spikeDay(t, 0, 3000, 18)
+	outlierHour := dayStart.Add(18*time.Hour + 30*time.Minute)
+	ordinaryHour := dayStart.Add(12 * time.Hour)
+
+	tests := []struct {
+		name             string
+		marketData       *entsoe.PublicationMarketData
+		limit            float64
+		factor           float64
+		action           string
+		at               time.Time
+		price            float64
+		expectedPrice    float64
+		expectedOutlier  bool
+		expectedReliable bool
+	}{
+		{name: "disabled", marketData: marketData, at: outlierHour, price: 3000, expectedPrice: 3000, expectedReliable: true},
+		{name: "within absolute limit", marketData: marketData, limit: 1000, at: ordinaryHour, price: 50, expectedPrice: 50, expectedReliable: true},
+		{name: "clamped to absolute limit", marketData: marketData, limit: 1000, at: outlierHour, price: 3000, expectedPrice: 1000, expectedOutlier: true, expectedReliable: true},
+		{name: "negative clamped to absolute limit", marketData: marketData, limit: 1000, at: ordinaryHour, price: -1500, expectedPrice: -1000, expectedOutlier: true, expectedReliable: true},
+		{name: "clamped to relative bound", marketData: marketData, factor: 10, at: outlierHour, price: 3000, expectedPrice: 550, expectedOutlier: true, expectedReliable: true},
+		{name: "tighter of both bounds", marketData: marketData, limit: 400, factor: 10, at: outlierHour, price: 3000, expectedPrice: 400, expectedOutlier: true, expectedReliable: true},
+		{name: "within relative bound", marketData: marketData, factor: 10, at: ordinaryHour, price: 50, expectedPrice: 50, expectedReliable: true},
+		{name: "skipped as unreliable", marketData: marketData, limit: 1000, action: PriceOutlierSkip, at: outlierHour, price: 3000, expectedPrice: 3000, expectedOutlier: true},
+		{name: "relative bound without price scale", marketData: zeroDay, factor: 10, at: outlierHour, price: 3000, expectedPrice: 3000, expectedReliable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{PriceOutlierLimit: tt.limit, PriceOutlierFactor: tt.factor, PriceOutlierAction: tt.action}
+			validator := newPriceValidator(tt.marketData, config)
+
+			price, outlier, reliable := validator.validate(tt.at, tt.price)
+			if price != tt.expectedPrice || outlier != tt.expectedOutlier || reliable != tt.expectedReliable {
+				t.Errorf("Expected price %.2f (outlier %v, reliable %v), got %.2f (outlier %v, reliable %v)",
+					tt.expectedPrice, tt.expectedOutlier, tt.expectedReliable, price, outlier, reliable)
+			}
+		})
+	}
+}
+
+func TestBuildMPCForecast_PriceOutlier(t *testing.T) {
+	// Hourly prices of 50 EUR/MWh around now with a glitch of 5000 EUR/MWh three hours ahead
+	start := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour)
+	outlierStart := start.Add(4 * time.Hour)
+	points := make([]entsoe.Point, 40)
+	for i := range points {
+		points[i] = entsoe.Point{Position: i + 1, PriceAmount: 50}
+	}
+	points[4].PriceAmount = 5000
+	interval := entsoe.TimeInterval{Start: start, End: start.Add(time.Duration(len(points)) * time.Hour)}
+	marketData := &entsoe.PublicationMarketData{
+		PeriodTimeInterval: interval,
+		TimeSeries: []entsoe.TimeSeries{
+			{Period: entsoe.Period{TimeInterval: interval, Resolution: time.Hour, Points: points}},
+		},
+	}
+
+	weatherServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer weatherServer.Close()
+
+	tests := []struct {
+		name          string
+		action        string
+		expectedSlots int
+		expectedEnd   bool
+	}{
+		{name: "clamp", action: PriceOutlierClamp, expectedSlots: 4},
+		{name: "skip", action: PriceOutlierSkip, expectedSlots: 0, expectedEnd: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			config.CheckPriceInterval = 15 * time.Minute
+			config.UserAgent = "test-agent"
+			config.PriceOutlierLimit = 1000
+			config.PriceOutlierAction = tt.action
+			scheduler := newTestScheduler(config)
+			scheduler.weatherBaseURL = weatherServer.URL
+			scheduler.mu.Lock()
+			scheduler.pricesMarketData = marketData
+			scheduler.pricesMarketDataExpiry = time.Now().Add(time.Hour)
+			scheduler.mu.Unlock()
+
+			forecast, err := scheduler.buildMPCForecast(context.Background(), config, nil, 0)
+			if err != nil {
+				t.Fatalf("buildMPCForecast failed: %v", err)
+			}
+			if len(forecast) == 0 {
+				t.Fatal("Expected forecast slots")
+			}
+
+			clampedImport, _ := AdjustedPrices(1000, config)
+			outlierSlots := 0
+			for _, slot := range forecast {
+				slotTime := time.Unix(slot.Timestamp, 0)
+				if slotTime.Before(outlierStart) || !slotTime.Before(outlierStart.Add(time.Hour)) {
+					continue
+				}
+				outlierSlots++
+				if slot.ImportPrice != clampedImport {
+					t.Errorf("Expected import price clamped to %.4f EUR/kWh at %s, got %.4f", clampedImport, slotTime, slot.ImportPrice)
+				}
+			}
+			if outlierSlots != tt.expectedSlots {
+				t.Errorf("Expected %d slots in the outlier hour, got %d", tt.expectedSlots, outlierSlots)
+			}
+			// A skipped outlier ends the horizon instead of leaving a hole in it
+			if last := time.Unix(forecast[len(forecast)-1].Timestamp, 0); last.Before(outlierStart) != tt.expectedEnd {
+				t.Errorf("Expected the horizon to end before the outlier hour: %v, last slot at %s", tt.expectedEnd, last)
+			}
+		})
+	}
+}
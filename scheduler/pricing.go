@@ -2,12 +2,22 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/devskill-org/ems/entsoe"
 )
 
+// AdjustedPrices converts a spot price in EUR/MWh into the import and export prices in EUR/kWh.
+// Import pays the spot price plus operator and delivery fees, export receives the spot price minus the operator fee.
+func AdjustedPrices(spotMWh float64, cfg *Config) (importKWh, exportKWh float64) {
+	importKWh = (spotMWh + cfg.ImportPriceOperatorFee + cfg.ImportPriceDeliveryFee) / 1000.0
+	exportKWh = (spotMWh - cfg.ExportPriceOperatorFee) / 1000.0
+	return importKWh, exportKWh
+}
+
 // GetPricesMarketData returns the cached PublicationMarketData without downloading
 func (s *MinerScheduler) GetPricesMarketData() *entsoe.PublicationMarketData {
 	s.mu.RLock()
@@ -15,7 +25,10 @@ func (s *MinerScheduler) GetPricesMarketData() *entsoe.PublicationMarketData {
 	return s.pricesMarketData
 }
 
-// GetMarketData returns the latest PublicationMarketData, downloading new data if needed
+// GetMarketData returns the latest PublicationMarketData, downloading new data if needed.
+// A stored document is never modified: an update builds the complete new document without holding mu and
+// only swaps the pointer under it, so readers never see a partially updated document and may keep using
+// the one they got after releasing the lock.
 func (s *MinerScheduler) GetMarketData(ctx context.Context) (*entsoe.PublicationMarketData, error) {
 
 	location, err := time.LoadLocation(s.config.Location)
@@ -23,7 +36,7 @@ func (s *MinerScheduler) GetMarketData(ctx context.Context) (*entsoe.Publication
 		return nil, err
 	}
 
-	now := time.Now().In(location)
+	now := s.now().In(location)
 
 	s.mu.RLock()
 	marketData := s.pricesMarketData
@@ -35,6 +48,19 @@ func (s *MinerScheduler) GetMarketData(ctx context.Context) (*entsoe.Publication
 		return marketData, nil
 	}
 
+	// Readers keep using the current document while a single caller downloads the new one
+	s.pricesUpdateMu.Lock()
+	defer s.pricesUpdateMu.Unlock()
+
+	// Another caller may have downloaded new data while this one waited
+	s.mu.RLock()
+	marketData = s.pricesMarketData
+	expiry = s.pricesMarketDataExpiry
+	s.mu.RUnlock()
+	if marketData != nil && now.Before(expiry) {
+		return marketData, nil
+	}
+
 	// Cache expired or no cached document, download new data
 	if marketData != nil {
 		s.logger.Printf("Cached pricing data expired at %s, downloading new PublicationMarketData...", expiry.Format(time.RFC3339))
@@ -42,13 +68,22 @@ func (s *MinerScheduler) GetMarketData(ctx context.Context) (*entsoe.Publication
 		s.logger.Printf("No cached pricing data available, downloading new PublicationMarketData...")
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	var cache *entsoe.DiskCache
+	if s.config.PriceCacheDir != "" {
+		cache = entsoe.NewDiskCache(s.config.PriceCacheDir, s.config.PriceCacheTTL)
+	}
 
-	newDoc, err := entsoe.DownloadPublicationMarketData(ctx, s.config.SecurityToken, s.config.URLFormat, location)
+	newDoc, err := entsoe.DownloadPublicationMarketDataWithCache(ctx, s.config.SecurityToken, s.config.URLFormat, location, cache)
 	if err != nil {
+		s.logAPIError(err)
 		return nil, fmt.Errorf("failed to download PublicationMarketData: %w", err)
 	}
+	if businessType := s.config.BusinessType; businessType != "" {
+		newDoc = newDoc.FilterByBusinessType(businessType)
+		if len(newDoc.TimeSeries) == 0 {
+			s.logger.Printf("Warning: downloaded PublicationMarketData has no TimeSeries of business type %s", businessType)
+		}
+	}
 
 	// Calculate next expiry time at 14:00
 	nextExpiry := time.Date(now.Year(), now.Month(), now.Day(), 14, 0, 0, 0, location)
@@ -58,17 +93,41 @@ func (s *MinerScheduler) GetMarketData(ctx context.Context) (*entsoe.Publication
 		nextExpiry = nextExpiry.Add(24 * time.Hour)
 	}
 
-	// Store as latest with expiry time
+	// Swap in the complete document with its expiry time
+	s.mu.Lock()
 	s.pricesMarketData = newDoc
 	s.pricesMarketDataExpiry = nextExpiry
+	s.mu.Unlock()
+	s.priceStats.update(newDoc, location)
 
 	s.logger.Printf("Successfully downloaded new PublicationMarketData, cache expires at %s", nextExpiry.Format(time.RFC3339))
+	if priceDocumentUpdated(marketData, newDoc) {
+		s.requestMPCRerun(fmt.Sprintf("Price document revision %d until %s downloaded",
+			newDoc.RevisionNumber, newDoc.PeriodTimeInterval.End.Format(time.RFC3339)))
+	}
 	return newDoc, nil
 }
 
+// logAPIError explains an ENTSO-E API error response: a rejected request needs a configuration change,
+// a temporary failure is retried at the next price check
+func (s *MinerScheduler) logAPIError(err error) {
+	var apiErr *entsoe.APIError
+	if !errors.As(err, &apiErr) {
+		return
+	}
+	switch {
+	case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+		s.logger.Printf("ALERT: ENTSO-E API rejected the security token (status %d), check security_token", apiErr.StatusCode)
+	case apiErr.Retryable():
+		s.logger.Printf("Warning: ENTSO-E API temporarily unavailable (status %d), retrying at the next price check", apiErr.StatusCode)
+	default:
+		s.logger.Printf("ALERT: ENTSO-E API rejected the request (status %d), check url_format", apiErr.StatusCode)
+	}
+}
+
 // runPriceCheck executes the main scheduler task
 func (s *MinerScheduler) runPriceCheck(ctx context.Context) error {
-	s.logger.Printf("Starting price check task at %s", time.Now().Format(time.RFC3339))
+	s.logger.Printf("Starting price check task at %s", s.now().Format(time.RFC3339))
 
 	// Step 1: Get current electricity price
 	currentPrice, err := s.getCurrentPrice(ctx)
@@ -80,8 +139,11 @@ func (s *MinerScheduler) runPriceCheck(ctx context.Context) error {
 	s.logger.Printf("Current electricity price: %.2f EUR/MWh", currentPrice)
 	s.logger.Printf("Price limit: %.2f EUR/MWh", s.config.PriceLimit)
 
-	// Step 2: Manage miners based on price
-	if err := s.manageMiners(ctx, currentPrice); err != nil {
+	// Step 2: Look ahead for a price spike
+	priceSpike := s.detectPriceSpike(s.now())
+
+	// Step 3: Manage miners based on price
+	if err := s.manageMiners(ctx, s.minerPrice(currentPrice), priceSpike); err != nil {
 		s.logger.Printf("Error managing miners: %v", err)
 		return err
 	}
@@ -97,7 +159,7 @@ func (s *MinerScheduler) getCurrentPrice(ctx context.Context) (float64, error) {
 		return 0, fmt.Errorf("failed to load location: %w", err)
 	}
 
-	now := time.Now().In(location)
+	now := s.now().In(location)
 
 	marketData, err := s.GetMarketData(ctx)
 	if err != nil {
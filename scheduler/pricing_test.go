@@ -1,13 +1,20 @@
 package scheduler
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"log"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/devskill-org/ems/entsoe"
 )
 
 // TestGetCurrentPrice_UsesConfiguredTimezone validates that getCurrentPrice uses the configured timezone
@@ -129,6 +136,143 @@ func TestGetCurrentPrice_LocationLoading(t *testing.T) {
 	t.Log("getCurrentPrice successfully loads timezone configuration")
 }
 
+func TestGetMarketData_APIErrorLogged(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		expected string
+	}{
+		{name: "bad token", status: http.StatusUnauthorized, expected: "ALERT: ENTSO-E API rejected the security token (status 401)"},
+		{name: "unavailable", status: http.StatusServiceUnavailable, expected: "Warning: ENTSO-E API temporarily unavailable (status 503)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			scheduler := newTestScheduler(testConfigWithServer(server))
+			var buf bytes.Buffer
+			scheduler.logger = log.New(&buf, "", 0)
+
+			_, err := scheduler.GetMarketData(context.Background())
+			var apiErr *entsoe.APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Fatalf("Expected an API error with status %d, got %v", tt.status, err)
+			}
+			if !strings.Contains(buf.String(), tt.expected) {
+				t.Errorf("Expected log %q, got:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestGetMarketData_ConcurrentReadsDuringUpdate(t *testing.T) {
+	xmlData, err := os.ReadFile("../test_data/Energy_Prices_202509052100-202509062100.xml")
+	if err != nil {
+		t.Fatalf("Failed to read test data file: %v", err)
+	}
+
+	release := make(chan struct{})
+	var requestsMu sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestsMu.Lock()
+		requests[r.URL.RawQuery]++
+		requestsMu.Unlock()
+		<-release
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write(xmlData)
+	}))
+	defer server.Close()
+
+	scheduler := newTestScheduler(testConfigWithServer(server))
+	scheduler.logger = log.New(&bytes.Buffer{}, "", 0)
+	// An expired document is replaced by the download
+	oldDoc, _ := spikeDay(t, 50, 300, 18)
+	scheduler.mu.Lock()
+	scheduler.pricesMarketData = oldDoc
+	scheduler.pricesMarketDataExpiry = time.Now().Add(-time.Minute)
+	scheduler.mu.Unlock()
+
+	const updaters = 5
+	var wg sync.WaitGroup
+	results := make(chan *entsoe.PublicationMarketData, updaters)
+	for range updaters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			doc, err := scheduler.GetMarketData(context.Background())
+			if err != nil {
+				t.Errorf("GetMarketData failed: %v", err)
+				return
+			}
+			results <- doc
+		}()
+	}
+
+	// Readers are not blocked by the download in progress and see the complete old document
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				doc := scheduler.GetPricesMarketData()
+				if doc == nil || len(doc.TimeSeries) == 0 {
+					t.Error("Expected a complete document during the update")
+					return
+				}
+				doc.LookupPriceByTime(time.Now())
+				scheduler.GetStatus()
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	current := make(chan *entsoe.PublicationMarketData, 1)
+	go func() {
+		current <- scheduler.GetPricesMarketData()
+	}()
+	select {
+	case doc := <-current:
+		if doc != oldDoc {
+			t.Error("Expected the old document while the download is in progress")
+		}
+	case <-time.After(time.Second):
+		t.Error("GetPricesMarketData blocked by the download in progress")
+	}
+	close(release)
+	wg.Wait()
+	close(stop)
+	readers.Wait()
+	close(results)
+
+	newDoc := scheduler.GetPricesMarketData()
+	if newDoc == oldDoc {
+		t.Fatal("Expected the downloaded document after the update")
+	}
+	for doc := range results {
+		if doc != newDoc {
+			t.Error("Expected every caller to get the downloaded document")
+		}
+	}
+	// Callers waiting for the download reuse it instead of downloading again
+	for query, count := range requests {
+		if count != 1 {
+			t.Errorf("Expected a single request for %s, got %d", query, count)
+		}
+	}
+}
+
 // TestGetCurrentPrice_InvalidLocation validates error handling for invalid timezone
 func TestGetCurrentPrice_InvalidLocation(t *testing.T) {
 	// Load test data
@@ -174,3 +318,56 @@ func TestGetCurrentPrice_InvalidLocation(t *testing.T) {
 
 	t.Logf("Correctly handled invalid timezone with error: %v", err)
 }
+
+func TestAdjustedPrices(t *testing.T) {
+	defaults := DefaultConfig()
+
+	tests := []struct {
+		name           string
+		config         *Config
+		spotMWh        float64
+		expectedImport float64
+		expectedExport float64
+	}{
+		{
+			name:           "default fees",
+			config:         defaults,
+			spotMWh:        100.0,
+			expectedImport: 0.1485, // (100 + 8.5 + 40) / 1000
+			expectedExport: 0.083,  // (100 - 17) / 1000
+		},
+		{
+			name:           "zero spot price with default fees",
+			config:         defaults,
+			spotMWh:        0.0,
+			expectedImport: 0.0485,
+			expectedExport: -0.017,
+		},
+		{
+			name:           "negative spot price with default fees",
+			config:         defaults,
+			spotMWh:        -50.0,
+			expectedImport: -0.0015,
+			expectedExport: -0.067,
+		},
+		{
+			name:           "no fees is a plain unit conversion",
+			config:         &Config{},
+			spotMWh:        123.4,
+			expectedImport: 0.1234,
+			expectedExport: 0.1234,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			importKWh, exportKWh := AdjustedPrices(tt.spotMWh, tt.config)
+			if math.Abs(importKWh-tt.expectedImport) > 1e-9 {
+				t.Errorf("Expected import price %.6f EUR/kWh, got %.6f", tt.expectedImport, importKWh)
+			}
+			if math.Abs(exportKWh-tt.expectedExport) > 1e-9 {
+				t.Errorf("Expected export price %.6f EUR/kWh, got %.6f", tt.expectedExport, exportKWh)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/devskill-org/ems/miners"
+)
+
+// rebootStuckMiners reboots miners whose stats have kept failing for miner_reboot_after. The reboot command
+// only reaches a miner that still accepts connections, an unreachable miner is left alone. A rebooted miner
+// gets another full period to recover before it is rebooted again. Miners under manual override are skipped.
+func (s *MinerScheduler) rebootStuckMiners(ctx context.Context, minersList []*miners.AvalonQHost) {
+	rebootAfter := s.config.MinerRebootAfter
+	if rebootAfter <= 0 {
+		return
+	}
+
+	now := s.now()
+	for _, m := range minersList {
+		key := minerKey(m)
+		if m.LastStatsError == nil {
+			s.minerErrorSince.Delete(key)
+			continue
+		}
+
+		value, _ := s.minerErrorSince.LoadOrStore(key, now)
+		since := value.(time.Time)
+		if now.Sub(since) < rebootAfter {
+			continue
+		}
+		if _, ok := s.getMinerOverride(m); ok {
+			continue
+		}
+		if !s.config.DryRun && s.deferMinerCommand(m) {
+			continue
+		}
+		s.minerErrorSince.Store(key, now)
+
+		if s.config.DryRun {
+			s.logger.Printf("DRY-RUN: Would reboot miner %s:%d, stats failing since %s: %v",
+				m.Address, m.Port, since.Format(time.RFC3339), m.LastStatsError)
+			continue
+		}
+		s.logger.Printf("Rebooting miner %s:%d, stats failing since %s: %v",
+			m.Address, m.Port, since.Format(time.RFC3339), m.LastStatsError)
+
+		response, err := m.Reboot(ctx)
+		if err != nil {
+			s.logger.Printf("Warning: failed to reboot miner %s:%d: %v", m.Address, m.Port, err)
+			continue
+		}
+		s.logger.Printf("Reboot response for miner %s:%d: %s", m.Address, m.Port, response)
+	}
+}
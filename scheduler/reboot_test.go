@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/miners"
+)
+
+func TestRunStateCheck_RebootsStuckMiner(t *testing.T) {
+	host, commands := serveRecordingMiner(t, []byte(`{"STATUS":[],"STATS":[]}`))
+	reboots := func() int { return len(commands()) }
+
+	config := testConfig()
+	config.MinerRebootAfter = 10 * time.Minute
+	scheduler := newTestScheduler(config)
+	var buf bytes.Buffer
+	scheduler.logger = log.New(&buf, "", 0)
+	clock := &simulatedClock{now: time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)}
+	scheduler.setClock(clock)
+	scheduler.discoveredMiners.Store(minerKey(host), host)
+
+	steps := []struct {
+		advance  time.Duration
+		expected int
+	}{
+		{advance: 0, expected: 0},                // Stats start failing
+		{advance: 9 * time.Minute, expected: 0},  // Not failing long enough
+		{advance: time.Minute, expected: 1},      // Rebooted after 10 minutes
+		{advance: 5 * time.Minute, expected: 1},  // Time to recover after the reboot
+		{advance: 5 * time.Minute, expected: 2},  // Still failing another 10 minutes later
+		{advance: 30 * time.Minute, expected: 3}, // One reboot per check at most
+	}
+	for i, step := range steps {
+		clock.Set(clock.Now().Add(step.advance))
+		_ = scheduler.runStateCheck(context.Background())
+		if got := reboots(); got != step.expected {
+			t.Fatalf("Step %d: expected %d reboots, got %d:\n%s", i, step.expected, got, buf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), "Rebooting miner "+host.Address) {
+		t.Errorf("Expected the reboot to be logged, got:\n%s", buf.String())
+	}
+}
+
+func TestRebootStuckMiners_RecoveryResetsTimer(t *testing.T) {
+	config := testConfig()
+	config.DryRun = true
+	config.MinerRebootAfter = 10 * time.Minute
+	scheduler := newTestScheduler(config)
+	var buf bytes.Buffer
+	scheduler.logger = log.New(&buf, "", 0)
+	clock := &simulatedClock{now: time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)}
+	scheduler.setClock(clock)
+
+	stuck := newTestMiner(50, miners.AvalonEcoMode, miners.AvalonStateMining, nil)
+	stuck.Address = "192.168.1.10"
+	stuck.LastStatsError = errors.New("invalid stats response")
+	scheduler.rebootStuckMiners(context.Background(), []*miners.AvalonQHost{stuck})
+
+	// The miner recovers for a check, the failure starts over
+	clock.Set(clock.Now().Add(8 * time.Minute))
+	stuck.LastStatsError = nil
+	scheduler.rebootStuckMiners(context.Background(), []*miners.AvalonQHost{stuck})
+	clock.Set(clock.Now().Add(time.Minute))
+	stuck.LastStatsError = errors.New("invalid stats response")
+	scheduler.rebootStuckMiners(context.Background(), []*miners.AvalonQHost{stuck})
+	clock.Set(clock.Now().Add(5 * time.Minute))
+	scheduler.rebootStuckMiners(context.Background(), []*miners.AvalonQHost{stuck})
+	if strings.Contains(buf.String(), "reboot") {
+		t.Fatalf("Expected no reboot within 10 minutes of the last failure start, got:\n%s", buf.String())
+	}
+
+	// In dry-run mode the reboot is only logged
+	clock.Set(clock.Now().Add(5 * time.Minute))
+	scheduler.rebootStuckMiners(context.Background(), []*miners.AvalonQHost{stuck})
+	if !strings.Contains(buf.String(), "DRY-RUN: Would reboot miner 192.168.1.10") {
+		t.Errorf("Expected a dry-run reboot, got:\n%s", buf.String())
+	}
+
+	// Disabled by default
+	scheduler.config.MinerRebootAfter = 0
+	buf.Reset()
+	clock.Set(clock.Now().Add(time.Hour))
+	scheduler.rebootStuckMiners(context.Background(), []*miners.AvalonQHost{stuck})
+	if buf.Len() != 0 {
+		t.Errorf("Expected no reboot when disabled, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Metric names used by the metrics retention job
+const (
+	metricEnergyFlow      = "energy_flow"
+	metricEnergyFlowDaily = "energy_flow_daily"
+)
+
+// downsampleMetricsSQL aggregates energy flow rows older than the cutoff into one row per day and device.
+// Energy and cost columns are summed, cloud coverage is averaged, snapshots are taken at the end of the day.
+// Merging into an existing daily row keeps the job safe to re-run for a day that was already downsampled.
+const downsampleMetricsSQL = `
+	INSERT INTO metrics (
+		timestamp, device_id, metric_name,
+		pv_total_power, cloud_coverage, weather_symbol,
+		grid_export_power, grid_import_power,
+		battery_charge_power, battery_discharge_power, battery_soc,
+		evdc_charge_power, load_power,
+		grid_export_cost, grid_import_cost,
+		battery_avg_cell_temperature
+	)
+	SELECT
+		date_trunc('day', timestamp) AS day, device_id, $2,
+		SUM(pv_total_power), AVG(cloud_coverage), mode() WITHIN GROUP (ORDER BY weather_symbol),
+		SUM(grid_export_power), SUM(grid_import_power),
+		SUM(battery_charge_power), SUM(battery_discharge_power), (array_agg(battery_soc ORDER BY timestamp DESC))[1],
+		SUM(evdc_charge_power), SUM(load_power),
+		SUM(grid_export_cost), SUM(grid_import_cost),
+		(array_agg(battery_avg_cell_temperature ORDER BY timestamp DESC))[1]
+	FROM metrics
+	WHERE metric_name = $1 AND timestamp < $3
+	GROUP BY day, device_id
+	ON CONFLICT (timestamp, device_id, metric_name) DO UPDATE SET
+		pv_total_power = COALESCE(metrics.pv_total_power, 0) + COALESCE(EXCLUDED.pv_total_power, 0),
+		cloud_coverage = COALESCE(EXCLUDED.cloud_coverage, metrics.cloud_coverage),
+		weather_symbol = COALESCE(EXCLUDED.weather_symbol, metrics.weather_symbol),
+		grid_export_power = COALESCE(metrics.grid_export_power, 0) + COALESCE(EXCLUDED.grid_export_power, 0),
+		grid_import_power = COALESCE(metrics.grid_import_power, 0) + COALESCE(EXCLUDED.grid_import_power, 0),
+		battery_charge_power = COALESCE(metrics.battery_charge_power, 0) + COALESCE(EXCLUDED.battery_charge_power, 0),
+		battery_discharge_power = COALESCE(metrics.battery_discharge_power, 0) + COALESCE(EXCLUDED.battery_discharge_power, 0),
+		battery_soc = COALESCE(EXCLUDED.battery_soc, metrics.battery_soc),
+		evdc_charge_power = COALESCE(metrics.evdc_charge_power, 0) + COALESCE(EXCLUDED.evdc_charge_power, 0),
+		load_power = COALESCE(metrics.load_power, 0) + COALESCE(EXCLUDED.load_power, 0),
+		grid_export_cost = COALESCE(metrics.grid_export_cost, 0) + COALESCE(EXCLUDED.grid_export_cost, 0),
+		grid_import_cost = COALESCE(metrics.grid_import_cost, 0) + COALESCE(EXCLUDED.grid_import_cost, 0),
+		battery_avg_cell_temperature = COALESCE(EXCLUDED.battery_avg_cell_temperature, metrics.battery_avg_cell_temperature)`
+
+// deleteDownsampledMetricsSQL deletes the energy flow rows that were aggregated into daily rows
+const deleteDownsampledMetricsSQL = `DELETE FROM metrics WHERE metric_name = $1 AND timestamp < $2`
+
+// deleteExpiredMetricsSQL deletes raw metrics rows past the retention window, daily aggregates are kept
+const deleteExpiredMetricsSQL = `DELETE FROM metrics WHERE metric_name <> $1 AND timestamp < $2`
+
+// downsampleCutoff returns the start of the day before which rows are downsampled.
+// Only whole days are aggregated so a day is never split between raw and daily rows.
+func downsampleCutoff(now time.Time, age time.Duration) time.Time {
+	cutoff := now.Add(-age)
+	return time.Date(cutoff.Year(), cutoff.Month(), cutoff.Day(), 0, 0, 0, 0, cutoff.Location())
+}
+
+// applyMetricsRetention downsamples old energy flow rows into daily aggregates and deletes raw rows
+// past the retention window. Aggregation and deletion of the aggregated rows run in one transaction,
+// so running the job repeatedly never counts a row twice.
+func (s *MinerScheduler) applyMetricsRetention(ctx context.Context, db *sql.DB, now time.Time) error {
+	config := s.GetConfig()
+
+	if config.MetricsDownsampleAfter > 0 {
+		cutoff := downsampleCutoff(now, config.MetricsDownsampleAfter)
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		result, err := tx.ExecContext(ctx, downsampleMetricsSQL, metricEnergyFlow, metricEnergyFlowDaily, cutoff)
+		if err != nil {
+			return fmt.Errorf("failed to downsample metrics: %w", err)
+		}
+		aggregated, _ := result.RowsAffected()
+
+		result, err = tx.ExecContext(ctx, deleteDownsampledMetricsSQL, metricEnergyFlow, cutoff)
+		if err != nil {
+			return fmt.Errorf("failed to delete downsampled metrics: %w", err)
+		}
+		deleted, _ := result.RowsAffected()
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+
+		s.logger.Printf("Metrics retention: downsampled %d rows into %d daily rows before %s",
+			deleted, aggregated, cutoff.Format(time.RFC3339))
+	}
+
+	if config.MetricsRetention > 0 {
+		cutoff := now.Add(-config.MetricsRetention)
+		result, err := db.ExecContext(ctx, deleteExpiredMetricsSQL, metricEnergyFlowDaily, cutoff)
+		if err != nil {
+			return fmt.Errorf("failed to delete expired metrics: %w", err)
+		}
+		deleted, _ := result.RowsAffected()
+		s.logger.Printf("Metrics retention: deleted %d raw rows before %s", deleted, cutoff.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// runMetricsRetention executes the metrics retention job as a scheduled task
+func (s *MinerScheduler) runMetricsRetention(ctx context.Context, db *sql.DB) error {
+	if db == nil {
+		return nil
+	}
+
+	if s.GetConfig().DryRun {
+		s.logger.Printf("Metrics retention [DRY-RUN]: would downsample and delete old metrics")
+		return nil
+	}
+
+	if err := s.applyMetricsRetention(ctx, db, s.now()); err != nil {
+		s.logger.Printf("Metrics retention: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingDriver is a minimal database/sql driver that records executed statements and queries
+type recordingDriver struct {
+	mu         sync.Mutex
+	execs      []recordedExec
+	queries    []recordedExec
+	queryRows  [][]driver.Value // rows returned by every query, none by default
+	commits    int
+	rollbacks  int
+	rowsResult int64
+}
+
+type recordedExec struct {
+	query string
+	args  []driver.Value
+}
+
+func (d *recordingDriver) Open(_ string) (driver.Conn, error) {
+	return &recordingConn{driver: d}, nil
+}
+
+type recordingConn struct {
+	driver *recordingDriver
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{driver: c.driver, query: query}, nil
+}
+
+func (c *recordingConn) Close() error { return nil }
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return &recordingTx{driver: c.driver}, nil
+}
+
+type recordingTx struct {
+	driver *recordingDriver
+}
+
+func (tx *recordingTx) Commit() error {
+	tx.driver.mu.Lock()
+	defer tx.driver.mu.Unlock()
+	tx.driver.commits++
+	return nil
+}
+
+func (tx *recordingTx) Rollback() error {
+	tx.driver.mu.Lock()
+	defer tx.driver.mu.Unlock()
+	tx.driver.rollbacks++
+	return nil
+}
+
+type recordingStmt struct {
+	driver *recordingDriver
+	query  string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+	s.driver.execs = append(s.driver.execs, recordedExec{query: s.query, args: args})
+	return driver.RowsAffected(s.driver.rowsResult), nil
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+	s.driver.queries = append(s.driver.queries, recordedExec{query: s.query, args: args})
+	if len(s.driver.queryRows) == 0 {
+		return emptyRows{}, nil
+	}
+	return &valueRows{rows: s.driver.queryRows}, nil
+}
+
+// valueRows returns fixed rows to a query
+type valueRows struct {
+	rows [][]driver.Value
+}
+
+func (r *valueRows) Columns() []string { return make([]string, len(r.rows[0])) }
+func (r *valueRows) Close() error      { return nil }
+
+func (r *valueRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string           { return nil }
+func (emptyRows) Close() error                { return nil }
+func (emptyRows) Next(_ []driver.Value) error { return io.EOF }
+
+var registerRecordingDriver sync.Once
+var activeRecordingDriver = &recordingDriver{}
+
+// newRecordingDB returns a database handle whose statements are recorded by a fresh driver state
+func newRecordingDB(t *testing.T) (*sql.DB, *recordingDriver) {
+	t.Helper()
+	registerRecordingDriver.Do(func() {
+		sql.Register("recording", &recordingDriverProxy{})
+	})
+	activeRecordingDriver = &recordingDriver{rowsResult: 1}
+	db, err := sql.Open("recording", "")
+	if err != nil {
+		t.Fatalf("Failed to open recording database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, activeRecordingDriver
+}
+
+// recordingDriverProxy forwards to the currently active recording driver
+type recordingDriverProxy struct{}
+
+func (recordingDriverProxy) Open(name string) (driver.Conn, error) {
+	return activeRecordingDriver.Open(name)
+}
+
+func TestApplyMetricsRetention(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		downsampleAfter time.Duration
+		retention       time.Duration
+		expectedQueries []string
+		expectedArgs    [][]driver.Value
+		expectedCommits int
+	}{
+		{
+			name:            "disabled",
+			expectedQueries: nil,
+		},
+		{
+			name:            "downsample only",
+			downsampleAfter: 7 * 24 * time.Hour,
+			expectedQueries: []string{downsampleMetricsSQL, deleteDownsampledMetricsSQL},
+			expectedArgs: [][]driver.Value{
+				{"energy_flow", "energy_flow_daily", time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)},
+				{"energy_flow", time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)},
+			},
+			expectedCommits: 1,
+		},
+		{
+			name:            "retention only",
+			retention:       30 * 24 * time.Hour,
+			expectedQueries: []string{deleteExpiredMetricsSQL},
+			expectedArgs: [][]driver.Value{
+				{"energy_flow_daily", time.Date(2024, 2, 14, 10, 30, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:            "downsample and retention",
+			downsampleAfter: 48 * time.Hour,
+			retention:       90 * 24 * time.Hour,
+			expectedQueries: []string{downsampleMetricsSQL, deleteDownsampledMetricsSQL, deleteExpiredMetricsSQL},
+			expectedArgs: [][]driver.Value{
+				{"energy_flow", "energy_flow_daily", time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC)},
+				{"energy_flow", time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC)},
+				{"energy_flow_daily", time.Date(2023, 12, 16, 10, 30, 0, 0, time.UTC)},
+			},
+			expectedCommits: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, rec := newRecordingDB(t)
+
+			config := testConfig()
+			config.MetricsDownsampleAfter = tt.downsampleAfter
+			config.MetricsRetention = tt.retention
+			scheduler := NewMinerScheduler(config, log.New(os.Stdout, "TEST: ", log.LstdFlags))
+
+			if err := scheduler.applyMetricsRetention(context.Background(), db, now); err != nil {
+				t.Fatalf("applyMetricsRetention failed: %v", err)
+			}
+
+			if len(rec.execs) != len(tt.expectedQueries) {
+				t.Fatalf("Expected %d statements, got %d", len(tt.expectedQueries), len(rec.execs))
+			}
+			for i, exec := range rec.execs {
+				if exec.query != tt.expectedQueries[i] {
+					t.Errorf("Statement %d: expected query %q, got %q", i, tt.expectedQueries[i], exec.query)
+				}
+				if len(exec.args) != len(tt.expectedArgs[i]) {
+					t.Fatalf("Statement %d: expected %d args, got %d", i, len(tt.expectedArgs[i]), len(exec.args))
+				}
+				for j, arg := range exec.args {
+					expected := tt.expectedArgs[i][j]
+					if expectedTime, ok := expected.(time.Time); ok {
+						if actualTime, ok := arg.(time.Time); !ok || !actualTime.Equal(expectedTime) {
+							t.Errorf("Statement %d arg %d: expected %v, got %v", i, j, expectedTime, arg)
+						}
+						continue
+					}
+					if arg != expected {
+						t.Errorf("Statement %d arg %d: expected %v, got %v", i, j, expected, arg)
+					}
+				}
+			}
+			if rec.commits != tt.expectedCommits {
+				t.Errorf("Expected %d commits, got %d", tt.expectedCommits, rec.commits)
+			}
+		})
+	}
+}
+
+func TestConfigValidate_MetricsRetentionAfterDownsample(t *testing.T) {
+	tests := []struct {
+		name            string
+		downsampleAfter time.Duration
+		retention       time.Duration
+		wantErr         bool
+	}{
+		{name: "retention only", retention: 24 * time.Hour},
+		{name: "downsampled a day before deletion", downsampleAfter: 7 * 24 * time.Hour, retention: 8 * 24 * time.Hour},
+		{name: "deleted before downsampling", downsampleAfter: 7 * 24 * time.Hour, retention: 3 * 24 * time.Hour, wantErr: true},
+		// Rows of the day being aggregated would be deleted first
+		{name: "equal ages", downsampleAfter: 7 * 24 * time.Hour, retention: 7 * 24 * time.Hour, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.SecurityToken = "test-token"
+			config.MetricsDownsampleAfter = tt.downsampleAfter
+			config.MetricsRetention = tt.retention
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDownsampleMetricsSQL(t *testing.T) {
+	// The aggregation must group whole days per device and merge into existing daily rows
+	for _, fragment := range []string{
+		"date_trunc('day', timestamp)",
+		"GROUP BY day, device_id",
+		"SUM(pv_total_power)",
+		"SUM(grid_import_cost)",
+		"ON CONFLICT (timestamp, device_id, metric_name) DO UPDATE",
+	} {
+		if !strings.Contains(downsampleMetricsSQL, fragment) {
+			t.Errorf("Expected downsample SQL to contain %q", fragment)
+		}
+	}
+}
+
+func TestRunMetricsRetention_DryRun(t *testing.T) {
+	db, rec := newRecordingDB(t)
+
+	config := testConfig()
+	config.DryRun = true
+	config.MetricsDownsampleAfter = 24 * time.Hour
+	config.MetricsRetention = 30 * 24 * time.Hour
+	scheduler := NewMinerScheduler(config, log.New(os.Stdout, "TEST: ", log.LstdFlags))
+
+	if err := scheduler.runMetricsRetention(context.Background(), db); err != nil {
+		t.Fatalf("runMetricsRetention failed: %v", err)
+	}
+	if len(rec.execs) != 0 {
+		t.Errorf("Expected no statements in dry-run mode, got %d", len(rec.execs))
+	}
+}
@@ -0,0 +1,115 @@
+package scheduler
+
+import (
+	"math"
+	"time"
+
+	"github.com/devskill-org/ems/entsoe"
+)
+
+// MiningMargin is the revenue and energy cost of one miner in standard mode for one hour, in EUR
+type MiningMargin struct {
+	Time       time.Time `json:"time"`
+	SpotPrice  float64   `json:"spot_price"`  // Spot price in EUR/MWh
+	Revenue    float64   `json:"revenue"`     // Mining revenue in EUR/h
+	EnergyCost float64   `json:"energy_cost"` // Energy cost at the import price in EUR/h
+	Margin     float64   `json:"margin"`      // Revenue minus energy cost in EUR/h
+}
+
+// miningRevenuePerTHDay returns the mining revenue per TH/s per day in EUR.
+// The fixed mining_revenue_per_th_day input takes precedence, otherwise the expected share of the block rewards
+// is derived from the BTC price and network difficulty. It returns false when the revenue model is disabled.
+func miningRevenuePerTHDay(config *Config) (float64, bool) {
+	if config.MiningRevenuePerTHDay > 0 {
+		return config.MiningRevenuePerTHDay, true
+	}
+	if config.BTCPrice <= 0 || config.NetworkDifficulty <= 0 {
+		return 0, false
+	}
+	// A hash solves a block with probability 1/(difficulty*2^32)
+	blocksPerTHDay := 1e12 * 86400 / (config.NetworkDifficulty * math.Exp2(32))
+	return blocksPerTHDay * config.BlockReward * config.BTCPrice, true
+}
+
+// computeMiningMargin returns the hourly margin of one miner in standard mode at a spot price in EUR/MWh.
+// It returns false when the revenue model is disabled.
+func computeMiningMargin(config *Config, spotPrice float64) (MiningMargin, bool) {
+	perTHDay, ok := miningRevenuePerTHDay(config)
+	if !ok {
+		return MiningMargin{}, false
+	}
+	importPrice, _ := AdjustedPrices(spotPrice, config)
+	margin := MiningMargin{
+		SpotPrice:  spotPrice,
+		Revenue:    config.MinerHashrate * perTHDay / 24,
+		EnergyCost: config.MinerPowerStandard * importPrice,
+	}
+	margin.Margin = margin.Revenue - margin.EnergyCost
+	return margin, true
+}
+
+// miningAllowed reports whether miners run at currentPrice: the price-based control allows it and,
+// when the revenue model is enabled, the mining margin is not negative
+func miningAllowed(config *Config, currentPrice float64, priceSpike bool) bool {
+	if !priceAllowsMining(currentPrice, config.PriceLimit, priceSpike) {
+		return false
+	}
+	margin, ok := computeMiningMargin(config, currentPrice)
+	return !ok || margin.Margin >= 0
+}
+
+// GetMiningMargins returns the hourly mining margins from the current hour until the end of the cached prices.
+// It returns nil when the revenue model is disabled or no prices are cached.
+func (s *MinerScheduler) GetMiningMargins() []MiningMargin {
+	doc := s.GetPricesMarketData()
+	if doc == nil {
+		return nil
+	}
+	config := s.GetConfig()
+
+	var margins []MiningMargin
+	for hour := s.now().Truncate(time.Hour); ; hour = hour.Add(time.Hour) {
+		price, found := doc.LookupPriceByTime(hour)
+		if !found {
+			break
+		}
+		margin, ok := computeMiningMargin(config, price)
+		if !ok {
+			return nil
+		}
+		margin.Time = hour
+		margins = append(margins, margin)
+	}
+	return margins
+}
+
+// MinerScheduleSlot is a period of a planned miner schedule with the power drawn by the running miners
+type MinerScheduleSlot struct {
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"` // 0 = one hour
+	Load     float64       `json:"load"`     // kW drawn by the miners
+}
+
+// EstimateMinerEnergyCost returns the expected grid cost in EUR of running miners on a schedule, summing the
+// energy of each slot at the import price adjusted with the fees of cfg. The spot price at the start of a slot
+// applies to the whole slot, so slots should not span several price intervals. Slots without a price are not
+// counted.
+func EstimateMinerEnergyCost(prices *entsoe.PublicationMarketData, schedule []MinerScheduleSlot, cfg *Config) float64 {
+	if prices == nil {
+		return 0
+	}
+	cost := 0.0
+	for _, slot := range schedule {
+		spotPrice, found := prices.LookupPriceByTime(slot.Start)
+		if !found {
+			continue
+		}
+		duration := slot.Duration
+		if duration <= 0 {
+			duration = time.Hour
+		}
+		importPrice, _ := AdjustedPrices(spotPrice, cfg)
+		cost += slot.Load * duration.Hours() * importPrice
+	}
+	return cost
+}
@@ -0,0 +1,196 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setRevenueDefaults sets the miner hashrate, power and import fees of DefaultConfig:
+// 90 TH/s at 1.6 kW with import fees of 48.5 EUR/MWh
+func setRevenueDefaults(config *Config) {
+	defaults := DefaultConfig()
+	config.MinerHashrate = defaults.MinerHashrate
+	config.MinerPowerStandard = defaults.MinerPowerStandard
+	config.ImportPriceOperatorFee = defaults.ImportPriceOperatorFee
+	config.ImportPriceDeliveryFee = defaults.ImportPriceDeliveryFee
+}
+
+func TestComputeMiningMargin(t *testing.T) {
+	tests := []struct {
+		name            string
+		revenuePerTHDay float64
+		btcPrice        float64
+		difficulty      float64
+		spotPrice       float64
+		expectedEnabled bool
+		expectedRevenue float64
+		expectedMargin  float64
+	}{
+		{name: "disabled", spotPrice: 50, expectedEnabled: false},
+		{name: "per TH input, cheap hour", revenuePerTHDay: 0.05, spotPrice: 0, expectedEnabled: true, expectedRevenue: 0.1875, expectedMargin: 0.1099},
+		{name: "per TH input, average hour", revenuePerTHDay: 0.05, spotPrice: 50, expectedEnabled: true, expectedRevenue: 0.1875, expectedMargin: 0.0299},
+		{name: "per TH input, expensive hour", revenuePerTHDay: 0.05, spotPrice: 100, expectedEnabled: true, expectedRevenue: 0.1875, expectedMargin: -0.0501},
+		{name: "per TH input, negative price", revenuePerTHDay: 0.05, spotPrice: -60, expectedEnabled: true, expectedRevenue: 0.1875, expectedMargin: 0.2059},
+		{name: "BTC model, cheap hour", btcPrice: 60000, difficulty: 1e14, spotPrice: 0, expectedEnabled: true, expectedRevenue: 0.1414, expectedMargin: 0.0638},
+		{name: "BTC model, average hour", btcPrice: 60000, difficulty: 1e14, spotPrice: 50, expectedEnabled: true, expectedRevenue: 0.1414, expectedMargin: -0.0162},
+		{name: "per TH input overrides the BTC model", revenuePerTHDay: 0.05, btcPrice: 60000, difficulty: 1e14, spotPrice: 50, expectedEnabled: true, expectedRevenue: 0.1875, expectedMargin: 0.0299},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 90 TH/s at 1.6 kW, import fees of 48.5 EUR/MWh
+			config := DefaultConfig()
+			config.MiningRevenuePerTHDay = tt.revenuePerTHDay
+			config.BTCPrice = tt.btcPrice
+			config.NetworkDifficulty = tt.difficulty
+
+			margin, enabled := computeMiningMargin(config, tt.spotPrice)
+			if enabled != tt.expectedEnabled {
+				t.Fatalf("Expected model enabled %v, got %v", tt.expectedEnabled, enabled)
+			}
+			if !enabled {
+				return
+			}
+			if math.Abs(margin.Revenue-tt.expectedRevenue) > 1e-4 {
+				t.Errorf("Expected revenue %.4f EUR/h, got %.4f", tt.expectedRevenue, margin.Revenue)
+			}
+			if math.Abs(margin.Margin-tt.expectedMargin) > 1e-4 {
+				t.Errorf("Expected margin %.4f EUR/h, got %.4f", tt.expectedMargin, margin.Margin)
+			}
+			if math.Abs(margin.Revenue-margin.EnergyCost-margin.Margin) > 1e-9 {
+				t.Errorf("Expected margin to be revenue minus energy cost, got %+v", margin)
+			}
+		})
+	}
+}
+
+func TestMiningAllowed(t *testing.T) {
+	config := DefaultConfig()
+	config.PriceLimit = 80
+	config.MiningRevenuePerTHDay = 0.05
+
+	tests := []struct {
+		name     string
+		price    float64
+		spike    bool
+		expected bool
+	}{
+		{name: "positive margin below the limit", price: 50, expected: true},
+		{name: "negative margin below the limit", price: 70, expected: false},
+		{name: "above the limit", price: 90, expected: false},
+		{name: "price spike", price: 0, spike: true, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := miningAllowed(config, tt.price, tt.spike); got != tt.expected {
+				t.Errorf("Expected mining allowed %v at price %.2f, got %v", tt.expected, tt.price, got)
+			}
+		})
+	}
+
+	// Without a revenue model only the price limit applies
+	config.MiningRevenuePerTHDay = 0
+	if !miningAllowed(config, 70, false) {
+		t.Errorf("Expected mining allowed below the limit without a revenue model")
+	}
+}
+
+func TestGetMiningMargins(t *testing.T) {
+	marketData, dayStart := spikeDay(t, 50, 300, 18)
+
+	config := testConfig()
+	config.MiningRevenuePerTHDay = 0.05
+	setRevenueDefaults(config)
+	scheduler := newTestScheduler(config)
+	scheduler.setClock(&simulatedClock{now: dayStart.Add(16*time.Hour + 20*time.Minute)})
+	scheduler.mu.Lock()
+	scheduler.pricesMarketData = marketData
+	scheduler.mu.Unlock()
+
+	// Hourly margins from 16:00 until the end of the day
+	margins := scheduler.GetMiningMargins()
+	if len(margins) != 8 {
+		t.Fatalf("Expected 8 hourly margins, got %d", len(margins))
+	}
+	if !margins[0].Time.Equal(dayStart.Add(16 * time.Hour)) {
+		t.Errorf("Expected the first margin at 16:00, got %s", margins[0].Time)
+	}
+	for _, margin := range margins {
+		expectedPositive := margin.SpotPrice == 50
+		if (margin.Margin > 0) != expectedPositive {
+			t.Errorf("Expected margin positive %v at price %.2f, got %.4f", expectedPositive, margin.SpotPrice, margin.Margin)
+		}
+	}
+	if margins[2].SpotPrice != 300 {
+		t.Errorf("Expected the spike price at 18:00, got %.2f", margins[2].SpotPrice)
+	}
+
+	scheduler.config.MiningRevenuePerTHDay = 0
+	if margins := scheduler.GetMiningMargins(); margins != nil {
+		t.Errorf("Expected no margins without a revenue model, got %d", len(margins))
+	}
+}
+
+func TestManageMiners_NegativeMarginStandby(t *testing.T) {
+	response, err := os.ReadFile("../test_data/avalon_litestat.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data file: %v", err)
+	}
+	hosts, _ := serveCountingMiners(t, 2, response)
+
+	config := testConfig()
+	config.DryRun = true
+	config.PriceLimit = 100
+	config.MiningRevenuePerTHDay = 0.05
+	setRevenueDefaults(config)
+	scheduler := newTestScheduler(config)
+	var buf bytes.Buffer
+	scheduler.logger = log.New(&buf, "", 0)
+	for _, host := range hosts {
+		scheduler.discoveredMiners.Store(minerKey(host), host)
+	}
+
+	// The price is below the limit, but the energy cost exceeds the mining revenue
+	if err := scheduler.manageMiners(context.Background(), 70, false); err != nil {
+		t.Fatalf("manageMiners failed: %v", err)
+	}
+	if standby := strings.Count(buf.String(), "into standby (mining margin -0.002 EUR/h negative at price 70.00)"); standby != len(hosts) {
+		t.Errorf("Expected %d miners put into standby for the negative margin, got %d:\n%s", len(hosts), standby, buf.String())
+	}
+
+	buf.Reset()
+	if err := scheduler.manageMiners(context.Background(), 50, false); err != nil {
+		t.Fatalf("manageMiners failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "into standby") {
+		t.Errorf("Expected miners to keep mining with a positive margin, got log:\n%s", buf.String())
+	}
+}
+
+func TestEstimateMinerEnergyCost(t *testing.T) {
+	// 50 EUR/MWh all day with a spike of 300 EUR/MWh at 18:00
+	marketData, dayStart := spikeDay(t, 50, 300, 18)
+	config := DefaultConfig() // Import fees of 48.5 EUR/MWh
+
+	schedule := []MinerScheduleSlot{
+		{Start: dayStart.Add(2 * time.Hour), Load: 3.2},                             // 3.2 kWh at 98.5 EUR/MWh
+		{Start: dayStart.Add(3 * time.Hour), Duration: 30 * time.Minute, Load: 1.6}, // 0.8 kWh at 98.5 EUR/MWh
+		{Start: dayStart.Add(18 * time.Hour), Load: 1.6},                            // 1.6 kWh at 348.5 EUR/MWh
+		{Start: dayStart.Add(19 * time.Hour), Load: 0},                              // Miners in standby
+		{Start: dayStart.Add(30 * time.Hour), Load: 3.2},                            // No price, not counted
+	}
+
+	expected := 3.2*0.0985 + 0.8*0.0985 + 1.6*0.3485
+	if cost := EstimateMinerEnergyCost(marketData, schedule, config); math.Abs(cost-expected) > 1e-9 {
+		t.Errorf("Expected energy cost %.4f EUR, got %.4f", expected, cost)
+	}
+	if cost := EstimateMinerEnergyCost(nil, schedule, config); cost != 0 {
+		t.Errorf("Expected no cost without prices, got %.4f", cost)
+	}
+}
@@ -0,0 +1,114 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// IsSafeMode returns true if the scheduler is in safe mode
+func (s *MinerScheduler) IsSafeMode() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.safeModeActive
+}
+
+// checkDataSources probes the price source, weather forecast and plant.
+// Returns nil if at least one source is reachable, otherwise an error describing all failures.
+func (s *MinerScheduler) checkDataSources(ctx context.Context) error {
+	config := s.GetConfig()
+
+	_, priceErr := s.getCurrentPrice(ctx)
+	if priceErr == nil {
+		return nil
+	}
+
+	_, weatherErr := s.getOrFetchWeatherForecast(ctx, config)
+	if weatherErr == nil {
+		return nil
+	}
+
+	plantErr := fmt.Errorf("plant_modbus_address not configured")
+	if config.PlantModbusAddress != "" {
+		if _, plantErr = s.readPlantRunningInfo(config); plantErr == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("price: %v; weather: %v; plant: %v", priceErr, weatherErr, plantErr)
+}
+
+// updateSafeMode records the result of a full data cycle.
+// After SafeModeFailureThreshold consecutive failed cycles the scheduler enters safe mode
+// and puts all miners into standby. The first successful cycle exits safe mode.
+// Returns true if the safe mode state changed.
+func (s *MinerScheduler) updateSafeMode(ctx context.Context, cycleFailed bool) bool {
+	threshold := s.GetConfig().SafeModeFailureThreshold
+
+	s.mu.Lock()
+	if !cycleFailed {
+		s.consecutiveCycleFailures = 0
+		wasActive := s.safeModeActive
+		s.safeModeActive = false
+		s.mu.Unlock()
+
+		if wasActive {
+			s.logger.Printf("SAFE MODE EXITED: data sources are reachable again, resuming automatic control")
+		}
+		return wasActive
+	}
+
+	s.consecutiveCycleFailures++
+	failures := s.consecutiveCycleFailures
+	enter := !s.safeModeActive && threshold > 0 && failures >= threshold
+	if enter {
+		s.safeModeActive = true
+	}
+	s.mu.Unlock()
+
+	if !enter {
+		s.logger.Printf("Safe mode check: %d/%d consecutive full-cycle failures", failures, threshold)
+		return false
+	}
+
+	s.logger.Printf("SAFE MODE ENTERED: %d consecutive full-cycle failures, putting all miners into standby", failures)
+	s.sendAlert(AlertSafeMode, fmt.Sprintf("Safe mode entered after %d consecutive full-cycle failures, all miners put into standby", failures),
+		map[string]any{"failures": failures})
+	s.standbyAllMiners(ctx)
+	return true
+}
+
+// standbyAllMiners puts every discovered miner into standby, except miners under manual override
+func (s *MinerScheduler) standbyAllMiners(ctx context.Context) {
+	isDryRun := s.GetConfig().DryRun
+	for _, m := range s.GetDiscoveredMiners() {
+		if override, ok := s.getMinerOverride(m); ok {
+			s.logger.Printf("SAFE MODE: Miner %s:%d is under manual override (%s), leaving as is",
+				m.Address, m.Port, override.String())
+			continue
+		}
+		if isDryRun {
+			s.logger.Printf("DRY-RUN: Would put miner %s:%d into standby (safe mode)", m.Address, m.Port)
+			continue
+		}
+		// Safe mode does not wait for miner_command_delay, but delays the next command
+		s.minerLastCommand.Store(minerKey(m), s.now())
+		if _, err := m.Standby(ctx); err != nil {
+			s.logger.Printf("SAFE MODE: Failed to put miner %s:%d into standby: %v", m.Address, m.Port, err)
+			continue
+		}
+		s.logger.Printf("SAFE MODE: Miner %s:%d put into standby", m.Address, m.Port)
+	}
+}
+
+// runSafeModeCheck executes the safe mode check as a scheduled task
+func (s *MinerScheduler) runSafeModeCheck(ctx context.Context) error {
+	s.logger.Printf("Starting safe mode check at %s", s.now().Format(time.RFC3339))
+
+	err := s.checkDataSources(ctx)
+	if err != nil {
+		s.logger.Printf("Safe mode check: all data sources failed: %v", err)
+	}
+	s.updateSafeMode(ctx, err != nil)
+	return nil
+}
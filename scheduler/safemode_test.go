@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/devskill-org/ems/miners"
+)
+
+func TestUpdateSafeMode_TriggersAndClears(t *testing.T) {
+	config := testConfig()
+	config.DryRun = true
+	config.SafeModeFailureThreshold = 3
+
+	var buf bytes.Buffer
+	scheduler := NewMinerScheduler(config, log.New(&buf, "", 0))
+	miner := newTestMiner(60, miners.AvalonStandardMode, miners.AvalonStateMining, nil)
+	scheduler.discoveredMiners.Store(minerKey(miner), miner)
+	ctx := context.Background()
+
+	// Failures below the threshold do not enter safe mode
+	for i := 1; i < config.SafeModeFailureThreshold; i++ {
+		if changed := scheduler.updateSafeMode(ctx, true); changed {
+			t.Fatalf("failure %d: expected no safe mode change", i)
+		}
+		if scheduler.IsSafeMode() {
+			t.Fatalf("failure %d: expected safe mode to be inactive", i)
+		}
+	}
+
+	// Reaching the threshold enters safe mode and puts miners into standby
+	if changed := scheduler.updateSafeMode(ctx, true); !changed {
+		t.Fatal("expected safe mode to be entered at threshold")
+	}
+	if !scheduler.IsSafeMode() {
+		t.Fatal("expected safe mode to be active")
+	}
+	if !scheduler.GetStatus().SafeMode {
+		t.Error("expected status to report safe mode")
+	}
+	if !strings.Contains(buf.String(), "Would put miner 192.168.1.100:4028 into standby") {
+		t.Errorf("expected miners to be put into standby, got log output: %q", buf.String())
+	}
+
+	// Further failures keep safe mode without re-entering it
+	if changed := scheduler.updateSafeMode(ctx, true); changed {
+		t.Error("expected no safe mode change on further failures")
+	}
+
+	// A successful cycle exits safe mode
+	if changed := scheduler.updateSafeMode(ctx, false); !changed {
+		t.Fatal("expected safe mode to be exited on success")
+	}
+	if scheduler.IsSafeMode() {
+		t.Fatal("expected safe mode to be inactive after success")
+	}
+
+	// The failure count restarts after a successful cycle
+	scheduler.updateSafeMode(ctx, true)
+	if scheduler.IsSafeMode() {
+		t.Error("expected failure count to be reset after success")
+	}
+}
+
+func TestUpdateSafeMode_Disabled(t *testing.T) {
+	config := testConfig()
+	config.DryRun = true
+	config.SafeModeFailureThreshold = 0
+
+	scheduler := newTestScheduler(config)
+	for range 10 {
+		scheduler.updateSafeMode(context.Background(), true)
+	}
+	if scheduler.IsSafeMode() {
+		t.Error("expected safe mode to stay inactive when disabled")
+	}
+}
+
+func TestUpdateSafeMode_RespectsManualOverride(t *testing.T) {
+	config := testConfig()
+	config.DryRun = true
+	config.SafeModeFailureThreshold = 1
+
+	var buf bytes.Buffer
+	scheduler := NewMinerScheduler(config, log.New(&buf, "", 0))
+	miner := newTestMiner(60, miners.AvalonStandardMode, miners.AvalonStateMining, nil)
+	scheduler.discoveredMiners.Store(minerKey(miner), miner)
+	scheduler.minerOverrides.Store(minerKey(miner), MinerOverride{State: miners.AvalonStateMining, WorkMode: miners.AvalonStandardMode})
+
+	scheduler.updateSafeMode(context.Background(), true)
+
+	if strings.Contains(buf.String(), "Would put miner") {
+		t.Errorf("expected overridden miner to be left as is, got log output: %q", buf.String())
+	}
+}
@@ -9,8 +9,10 @@ import (
 	"time"
 
 	"github.com/devskill-org/ems/entsoe"
+	"github.com/devskill-org/ems/meteo"
 	"github.com/devskill-org/ems/miners"
 	"github.com/devskill-org/ems/mpc"
+	"github.com/devskill-org/ems/sigenergy"
 	_ "github.com/lib/pq" // PostgreSQL driver
 )
 
@@ -19,9 +21,11 @@ type PeriodicTask struct {
 	name          string
 	initialDelay  time.Duration
 	interval      time.Duration
-	runFunc       func() error
+	runFunc       func(ctx context.Context) error // ctx is cancelled when the watchdog finds the run hung
 	retryInterval *time.Duration
+	trigger       <-chan struct{} // Runs the task out of schedule when signalled, nil for none
 	err           error
+	state         *taskState
 }
 
 // run executes the periodic task in a loop, respecting the initial delay and context cancellation
@@ -33,7 +37,7 @@ func (pt *PeriodicTask) run(ctx context.Context, stopChan <-chan struct{}, logge
 		case <-time.After(pt.initialDelay):
 			// Initial delay passed, run the task
 			logger.Printf("[%s] Initial delay passed, running first iteration", pt.name)
-			pt.err = pt.runFunc()
+			pt.err = pt.execute(ctx)
 		case <-ctx.Done():
 			logger.Printf("[%s] Stopped during initial delay due to context cancellation", pt.name)
 			return
@@ -44,7 +48,7 @@ func (pt *PeriodicTask) run(ctx context.Context, stopChan <-chan struct{}, logge
 	} else {
 		// No initial delay, run immediately
 		logger.Printf("[%s] Running immediately (no initial delay)", pt.name)
-		pt.err = pt.runFunc()
+		pt.err = pt.execute(ctx)
 	}
 
 	// Create ticker for periodic execution
@@ -64,11 +68,14 @@ func (pt *PeriodicTask) run(ctx context.Context, stopChan <-chan struct{}, logge
 	for {
 		select {
 		case <-ticker.C:
-			pt.err = pt.runFunc()
+			pt.err = pt.execute(ctx)
 		case <-retryTicker.C:
 			if pt.retryInterval != nil && pt.err != nil {
-				pt.err = pt.runFunc()
+				pt.err = pt.execute(ctx)
 			}
+		case <-pt.trigger:
+			logger.Printf("[%s] Triggered out of schedule", pt.name)
+			pt.err = pt.execute(ctx)
 		case <-ctx.Done():
 			logger.Printf("[%s] Stopped due to context cancellation", pt.name)
 			return
@@ -79,6 +86,16 @@ func (pt *PeriodicTask) run(ctx context.Context, stopChan <-chan struct{}, logge
 	}
 }
 
+// execute runs the task once and records its start and end for the watchdog, which may cancel the run
+func (pt *PeriodicTask) execute(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pt.state.begin(time.Now(), cancel)
+	err := pt.runFunc(runCtx)
+	pt.state.finish(time.Now(), err)
+	return err
+}
+
 // MinerScheduler manages energy system optimization, miner control, and scheduling tasks.
 type MinerScheduler struct {
 	// Configuration
@@ -86,18 +103,65 @@ type MinerScheduler struct {
 
 	// State
 	discoveredMiners       sync.Map // map[string]*miners.AvalonQHost
+	minerOverrides         sync.Map // map[string]MinerOverride - manual overrides keyed like discoveredMiners
+	fanRModels             sync.Map // map[string]*FanRModel - learned FanR per work mode keyed like discoveredMiners
+	minerDecisions         sync.Map // map[string]MinerControlDecision - latest control decision keyed like discoveredMiners
+	minerCooldowns         sync.Map // map[string]time.Time - start of the cooldown before standby keyed like discoveredMiners
+	minerErrorSince        sync.Map // map[string]time.Time - since when stats fail, keyed like discoveredMiners
+	minerLastCommand       sync.Map // map[string]time.Time - when the last command was sent, keyed like discoveredMiners
 	pricesMarketData       *entsoe.PublicationMarketData
 	pricesMarketDataExpiry time.Time
+	pricesUpdateMu         sync.Mutex // Serializes price downloads, held without mu while downloading
 	isRunning              bool
 	stopChan               chan struct{}
 	mu                     sync.RWMutex
 
+	// Periodic task run times keyed by task name, set once by Start
+	taskStates map[string]*taskState
+
+	// Safe mode state
+	safeModeActive           bool
+	consecutiveCycleFailures int
+
+	// Miners follow the solar surplus until the next price check, see solar_surplus_mining
+	solarSurplusActive bool
+
 	// Weather forecast cache
 	weatherCache WeatherForecastCache
 
+	// MET API client kept across fetches for its response cache, see getWeatherClient
+	weatherClientMu  sync.Mutex
+	weatherClient    *meteo.Client
+	weatherClientKey string // user agent and base URL the client was built for
+
+	// Error of the MPC load estimate against the measured load
+	loadError loadErrorTracker
+
+	// Recent fleet hashrate to detect unexpected drops
+	hashrate hashrateMonitor
+
+	// Energy and cost attributed to each miner, see miner_cost_accounting
+	minerCosts minerCostAccountant
+
+	// Statistics of the spot prices of the most recent days
+	priceStats priceStatsCache
+
+	// Battery charge plus discharge energy of the current day, see max_daily_throughput_kwh
+	throughput throughputTracker
+
+	// Last state of the sources of critical events posted to alert_webhook_url
+	alerts          alertState
+	alertRetryDelay time.Duration // Delay before the first retry of a failed webhook delivery
+
 	// MPC optimization results
 	mpcDecisions         []mpc.ControlDecision
 	lastExecutedDecision *mpc.ControlDecision // Tracks the last successfully executed decision
+	remoteEMSActive      bool                 // Remote EMS was enabled to execute MPC decisions
+	selfConsumption      bool                 // Maximum self-consumption was set instead of a plan on flat prices
+	mpcRerun             chan struct{}        // Pending out-of-schedule MPC run, see mpc_rerun_on_update
+
+	// Only one Modbus connection to the plant is open at a time, see withPlantClient
+	plantMu sync.Mutex
 
 	// Web server
 	webServer *WebServer
@@ -108,8 +172,14 @@ type MinerScheduler struct {
 	// Logging
 	logger *log.Logger
 
-	// Test hooks for dependency injection
+	// Time source, replaced by a simulated clock when replaying recorded data
+	clock Clock
+
+	// Dependency injection hooks for tests and simulations
 	minerDiscoveryFunc func(ctx context.Context, network string) []*miners.AvalonQHost
+	weatherBaseURL     string                                                    // Overrides the MET API base URL
+	weatherRetry       *meteo.RetryPolicy                                        // Overrides the retry policy of MET API requests
+	plantInfoFunc      func(config *Config) (*sigenergy.PlantRunningInfo, error) // Overrides reading plant running info over Modbus
 }
 
 // NewMinerScheduler creates a new scheduler instance
@@ -121,10 +191,15 @@ func NewMinerScheduler(config *Config, logger *log.Logger) *MinerScheduler {
 	scheduler := &MinerScheduler{
 		config:   config,
 		stopChan: make(chan struct{}),
+		mpcRerun: make(chan struct{}, 1),
 		logger:   logger,
-		weatherCache: WeatherForecastCache{
-			cacheDuration: 2 * time.Hour,
-		},
+		clock:    realClock{},
+
+		alertRetryDelay: 2 * time.Second,
+	}
+	scheduler.weatherCache = WeatherForecastCache{
+		cacheDuration: 2 * time.Hour,
+		clock:         scheduler.clock,
 	}
 
 	return scheduler
@@ -205,6 +280,12 @@ func (s *MinerScheduler) Start(ctx context.Context, serverOnly bool) error {
 
 	config := s.GetConfig()
 
+	s.warnIfDefaultLocation()
+	s.warnIfOutsideForecastDomain()
+
+	// Restore miners from the previous run, discovery refreshes them in the background
+	s.restorePersistedMiners()
+
 	// Data integration state
 	dataSamples := &DataSamples{}
 	var dataDB *sql.DB
@@ -226,11 +307,15 @@ func (s *MinerScheduler) Start(ctx context.Context, serverOnly bool) error {
 				s.mu.Unlock()
 				s.logger.Printf("Loaded %d MPC decisions from database on startup", len(decisions))
 			}
+
+			if err := s.restoreDailyThroughput(ctx, dataDB); err != nil {
+				s.logger.Printf("Warning: Failed to restore the daily battery throughput: %v", err)
+			}
 		}
 	}
 
 	// Calculate initial delays
-	now := time.Now()
+	now := s.now()
 	minersControlInitialDelay := s.getInitialDelay(now, config.CheckPriceInterval) + time.Second
 	pvDataInitialDelay := s.getInitialDelay(now, config.PVIntegrationPeriod)
 	stateCheckInitialDelay := s.getInitialDelay(now, config.MinersStateCheckInterval)
@@ -244,7 +329,7 @@ func (s *MinerScheduler) Start(ctx context.Context, serverOnly bool) error {
 			name:         "MinerDiscovery",
 			initialDelay: 0, // Run immediately
 			interval:     config.MinerDiscoveryInterval,
-			runFunc: func() error {
+			runFunc: func(ctx context.Context) error {
 				return s.RunMinerDiscovery(ctx)
 			},
 		},
@@ -253,7 +338,7 @@ func (s *MinerScheduler) Start(ctx context.Context, serverOnly bool) error {
 			initialDelay:  minersControlInitialDelay,
 			interval:      config.CheckPriceInterval,
 			retryInterval: &taskRetryInterval,
-			runFunc: func() error {
+			runFunc: func(ctx context.Context) error {
 				return s.runPriceCheck(ctx)
 			},
 		},
@@ -262,7 +347,8 @@ func (s *MinerScheduler) Start(ctx context.Context, serverOnly bool) error {
 			initialDelay:  minersControlInitialDelay,
 			interval:      config.CheckPriceInterval,
 			retryInterval: &taskRetryInterval,
-			runFunc: func() error {
+			trigger:       s.mpcRerun,
+			runFunc: func(ctx context.Context) error {
 				return s.RunMPCOptimize(ctx)
 			},
 		},
@@ -270,7 +356,7 @@ func (s *MinerScheduler) Start(ctx context.Context, serverOnly bool) error {
 			name:         "StateCheck",
 			initialDelay: stateCheckInitialDelay,
 			interval:     config.MinersStateCheckInterval,
-			runFunc: func() error {
+			runFunc: func(ctx context.Context) error {
 				return s.runStateCheck(ctx)
 			},
 		},
@@ -278,7 +364,7 @@ func (s *MinerScheduler) Start(ctx context.Context, serverOnly bool) error {
 			name:         "DataPoll",
 			initialDelay: 0,
 			interval:     config.PVPollInterval,
-			runFunc: func() error {
+			runFunc: func(context.Context) error {
 				return s.runDataPoll(dataSamples)
 			},
 		},
@@ -287,39 +373,122 @@ func (s *MinerScheduler) Start(ctx context.Context, serverOnly bool) error {
 			initialDelay:  pvDataInitialDelay,
 			interval:      config.PVIntegrationPeriod,
 			retryInterval: &taskRetryInterval,
-			runFunc: func() error {
-				return s.runDataIntegration(dataSamples, config.PVPollInterval, dataDB, config.DeviceID, config.DryRun)
+			runFunc: func(ctx context.Context) error {
+				return s.runDataIntegration(ctx, dataSamples, config.PVPollInterval, dataDB, config.DeviceID, config.DryRun)
 			},
 		},
 		{
 			name:         "MPCExecution",
 			initialDelay: mpcExecutionInitialDelay,
 			interval:     config.MPCExecutionInterval,
-			runFunc: func() error {
+			runFunc: func(context.Context) error {
 				return s.runMPCExecution()
 			},
 		},
 	}
 
+	if dataDB != nil && (config.MetricsDownsampleAfter > 0 || config.MetricsRetention > 0) {
+		tasks = append(tasks, PeriodicTask{
+			name:          "MetricsRetention",
+			initialDelay:  pvDataInitialDelay + time.Minute,
+			interval:      config.MetricsRetentionInterval,
+			retryInterval: &taskRetryInterval,
+			runFunc: func(ctx context.Context) error {
+				return s.runMetricsRetention(ctx, dataDB)
+			},
+		})
+	}
+
+	if dataDB != nil && config.StoreDailyKPI {
+		tasks = append(tasks, PeriodicTask{
+			name:         "DailyKPI",
+			initialDelay: pvDataInitialDelay + 2*time.Minute,
+			interval:     24 * time.Hour,
+			runFunc: func(ctx context.Context) error {
+				return s.runDailyKPI(ctx, dataDB)
+			},
+		})
+	}
+
+	if config.SafeModeFailureThreshold > 0 {
+		tasks = append(tasks, PeriodicTask{
+			name:         "SafeModeCheck",
+			initialDelay: minersControlInitialDelay,
+			interval:     config.CheckPriceInterval,
+			runFunc: func(ctx context.Context) error {
+				return s.runSafeModeCheck(ctx)
+			},
+		})
+	}
+
+	// Track run times of every task for the watchdog and the status endpoint
+	taskStates := make(map[string]*taskState, len(tasks))
+	for i := range tasks {
+		tasks[i].state = &taskState{}
+		taskStates[tasks[i].name] = tasks[i].state
+	}
+	s.mu.Lock()
+	s.taskStates = taskStates
+	s.mu.Unlock()
+
 	// Start each periodic task in its own goroutine
 	var wg sync.WaitGroup
 	for _, task := range tasks {
 		wg.Add(1)
-		task := task // capture loop variable
 		go func() {
 			defer wg.Done()
 			task.run(ctx, s.stopChan, s.logger)
 		}()
 	}
 
+	// Alert on tasks whose run hangs, e.g. on a Modbus read without timeout
+	if config.TaskWatchdogMultiplier > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runWatchdog(ctx, s.stopChan, tasks, config.TaskWatchdogMultiplier)
+		}()
+	}
+
 	// Wait for all tasks to complete
 	wg.Wait()
 
 	s.logger.Printf("All periodic tasks stopped")
+
+	// Hand battery control back to the inverter, MPC decisions are no longer executed
+	if err := s.releaseRemoteEMS(); err != nil {
+		s.logger.Printf("Error releasing remote EMS control: %v", err)
+	}
 	s.stop()
 	return nil
 }
 
+// warnIfDefaultLocation logs a warning when the plant location is left at the default coordinates.
+// Solar estimation and sun position depend on the location, and the inverter does not expose
+// its geolocation over Modbus, so the configured coordinates cannot be cross-checked.
+// Returns true if the warning was logged.
+func (s *MinerScheduler) warnIfDefaultLocation() bool {
+	config := s.GetConfig()
+	if !config.HasDefaultLocation() {
+		return false
+	}
+	s.logger.Printf("Warning: latitude/longitude are the default coordinates (%.4f, %.4f); set them to the plant location for accurate solar forecasts",
+		config.Latitude, config.Longitude)
+	return true
+}
+
+// warnIfOutsideForecastDomain logs a warning when the plant location is outside of MET's high-resolution domain,
+// the weather based solar forecast is less accurate there. Returns true if the warning was logged.
+func (s *MinerScheduler) warnIfOutsideForecastDomain() bool {
+	config := s.GetConfig()
+	warning := meteo.LocationCoverageWarning(meteo.Location{Latitude: config.Latitude, Longitude: config.Longitude})
+	if warning == "" {
+		return false
+	}
+	s.logger.Printf("Warning: %s", warning)
+	return true
+}
+
 // Stop gracefully stops the scheduler
 func (s *MinerScheduler) Stop() {
 	s.stop()
@@ -372,10 +541,23 @@ func (s *MinerScheduler) GetStatus() Status {
 		return true
 	})
 
+	var loadError *LoadErrorStats
+	if stats := s.loadError.stats(); stats.Samples > 0 {
+		loadError = &stats
+	}
+
+	strategy, reason := s.activeStrategy()
+
 	return Status{
-		IsRunning:     s.isRunning,
-		MinersCount:   minersCount,
-		HasMarketData: s.pricesMarketData != nil,
+		IsRunning:      s.isRunning,
+		MinersCount:    minersCount,
+		HasMarketData:  s.pricesMarketData != nil,
+		SafeMode:       s.safeModeActive,
+		ActiveStrategy: strategy,
+		StrategyReason: reason,
+		Tasks:          s.getTaskStatuses(),
+		LoadError:      loadError,
+		Hashrate:       s.hashrate.current(),
 	}
 }
 
@@ -396,7 +578,13 @@ func (s *MinerScheduler) GetMPCDecisions() []mpc.ControlDecision {
 
 // Status represents the current status of the scheduler
 type Status struct {
-	IsRunning     bool `json:"is_running"`
-	MinersCount   int  `json:"miners_count"`
-	HasMarketData bool `json:"has_latest_document"`
+	IsRunning      bool                  `json:"is_running"`
+	MinersCount    int                   `json:"miners_count"`
+	HasMarketData  bool                  `json:"has_latest_document"`
+	SafeMode       bool                  `json:"safe_mode"`
+	ActiveStrategy string                `json:"active_strategy"` // Control strategy in effect, see the Strategy constants
+	StrategyReason string                `json:"strategy_reason"` // Why the strategy is in effect
+	Tasks          map[string]TaskStatus `json:"tasks,omitempty"`
+	LoadError      *LoadErrorStats       `json:"load_forecast_error,omitempty"`
+	Hashrate       *HashrateStatus       `json:"hashrate,omitempty"`
 }
@@ -1,12 +1,14 @@
 package scheduler
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -724,3 +726,52 @@ func TestMockMinerDiscovery(t *testing.T) {
 		t.Errorf("Expected 2 miners, got %d", len(miners))
 	}
 }
+
+func TestWarnIfDefaultLocation(t *testing.T) {
+	tests := []struct {
+		name      string
+		latitude  float64
+		longitude float64
+		wantWarn  bool
+	}{
+		{
+			name:      "default coordinates",
+			latitude:  DefaultLatitude,
+			longitude: DefaultLongitude,
+			wantWarn:  true,
+		},
+		{
+			name:      "custom coordinates",
+			latitude:  59.4370,
+			longitude: 24.7536,
+			wantWarn:  false,
+		},
+		{
+			name:      "only latitude customized",
+			latitude:  59.4370,
+			longitude: DefaultLongitude,
+			wantWarn:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			config.Latitude = tt.latitude
+			config.Longitude = tt.longitude
+
+			var buf bytes.Buffer
+			scheduler := NewMinerScheduler(config, log.New(&buf, "", 0))
+
+			warned := scheduler.warnIfDefaultLocation()
+			if warned != tt.wantWarn {
+				t.Errorf("warnIfDefaultLocation() = %v, want %v", warned, tt.wantWarn)
+			}
+
+			logged := strings.Contains(buf.String(), "default coordinates")
+			if logged != tt.wantWarn {
+				t.Errorf("Expected warning logged = %v, got log output: %q", tt.wantWarn, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package scheduler
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/devskill-org/ems/miners"
+)
+
+// SelfConsumptionPlan is the miner load selected to absorb a solar surplus instead of exporting it
+type SelfConsumptionPlan struct {
+	Surplus   float64                         // kW solar left after the base load and the battery charge headroom
+	Target    float64                         // kW miner load aimed for, the surplus bounded by miners_power_limit
+	Load      float64                         // kW total power of the selected miner states and modes
+	Decisions map[string]MinerControlDecision // selected state and work mode keyed by miner address:port
+}
+
+// selfConsumptionCandidate is a miner whose state and work mode can be chosen freely within its thermal headroom
+type selfConsumptionCandidate struct {
+	miner   *miners.AvalonQHost
+	mode    int // work mode selected so far, -1 for standby
+	maxMode int // highest work mode allowed, -1 when the miner must stay in standby
+}
+
+// planSelfConsumption selects miner states and work modes whose total power tracks the solar surplus
+// solar - baseLoad - batteryChargeHeadroom (all kW), bounded by miners_power_limit and each miner's thermal headroom.
+// Miners are woken in eco mode first and then stepped up one work mode at a time, so the load is spread
+// over as many miners as possible. Miners already mining are served first to avoid shuffling which miners run.
+// Miners under manual override keep their state and mode, their power counts toward the surplus.
+func (s *MinerScheduler) planSelfConsumption(minersList []*miners.AvalonQHost, solar, baseLoad, batteryChargeHeadroom float64, now time.Time) SelfConsumptionPlan {
+	plan := SelfConsumptionPlan{
+		Surplus:   max(0, solar-baseLoad-batteryChargeHeadroom),
+		Decisions: make(map[string]MinerControlDecision),
+	}
+	plan.Target = plan.Surplus
+	if s.config.MinersPowerLimit > 0 {
+		plan.Target = min(plan.Target, s.config.MinersPowerLimit)
+	}
+
+	protection, _ := s.thunderProtectionLevel(now)
+	var candidates []*selfConsumptionCandidate
+	for _, m := range minersList {
+		if m.LastStatsError != nil || m.LastStats == nil {
+			continue
+		}
+		if _, ok := s.getMinerOverride(m); ok {
+			plan.Load += s.getMinerPowerConsumption(m.LastStats.State, m.LastStats.WorkMode)
+			plan.Decisions[minerKey(m)] = MinerControlDecision{State: m.LastStats.State, WorkMode: m.LastStats.WorkMode, Reason: ReasonManualOverride}
+			continue
+		}
+		plan.Load += s.config.MinerPowerStandby
+		candidates = append(candidates, &selfConsumptionCandidate{
+			miner:   m,
+			mode:    -1,
+			maxMode: s.selfConsumptionMaxMode(m, protection),
+		})
+	}
+
+	slices.SortStableFunc(candidates, func(a, b *selfConsumptionCandidate) int {
+		aStandby := a.miner.LastStats.State == miners.AvalonStateStandBy
+		bStandby := b.miner.LastStats.State == miners.AvalonStateStandBy
+		if aStandby != bStandby {
+			if aStandby {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Compare(minerKey(a.miner), minerKey(b.miner))
+	})
+
+	for mode := miners.AvalonEcoMode; mode <= miners.AvalonSuperMode; mode++ {
+		for _, c := range candidates {
+			if c.mode != int(mode)-1 || c.maxMode < int(mode) {
+				continue
+			}
+			additional := s.selfConsumptionPower(int(mode)) - s.selfConsumptionPower(c.mode)
+			// Allow for rounding of the summed power values
+			if plan.Load+additional > plan.Target+1e-9 {
+				continue
+			}
+			plan.Load += additional
+			c.mode = int(mode)
+		}
+	}
+
+	for _, c := range candidates {
+		decision := MinerControlDecision{State: miners.AvalonStateStandBy, WorkMode: miners.AvalonEcoMode, Reason: ReasonSolarSurplus}
+		if c.mode >= 0 {
+			decision.State = miners.AvalonStateMining
+			decision.WorkMode = miners.AvalonWorkMode(c.mode)
+		}
+		plan.Decisions[minerKey(c.miner)] = decision
+	}
+	return plan
+}
+
+// manageSolarSurplus sets the miners to the plan of planSelfConsumption for the measured solar surplus when
+// solar_surplus_mining is enabled, the price keeps miners in standby and the export price is at or below
+// price_limit, so the surplus is worth less exported than mined. The base load is the measured site load less
+// the miners, the battery keeps the charge power it can take. It reports whether the miners were handled,
+// without prices or a plant measurement the price-based control applies.
+func (s *MinerScheduler) manageSolarSurplus(ctx context.Context, minersList []*miners.AvalonQHost, currentPrice float64, priceSpike bool) (bool, error) {
+	config := s.GetConfig()
+	active := false
+	defer func() { s.setSolarSurplusActive(active) }()
+
+	if !config.SolarSurplusMining || miningAllowed(config, currentPrice, priceSpike) || s.IsSafeMode() {
+		return false, nil
+	}
+	exportPrice, low := s.exportPriceAtMost(config, s.now(), config.PriceLimit)
+	if !low {
+		return false, nil
+	}
+	info := s.GetPlantRunningInfo()
+	if info == nil {
+		return false, nil
+	}
+	active = true
+
+	// Positive grid power is import, positive ESS power is charging
+	minerPower := s.calculateTotalPowerConsumption(minersList)
+	baseLoad := max(0, info.PhotovoltaicPower+info.GridSensorActivePower-info.ESSPower-minerPower)
+	plan := s.planSelfConsumption(minersList, info.PhotovoltaicPower, baseLoad, info.ESSAvailableMaxChargingPower, s.now())
+	s.logger.Printf("Export price %.2f EUR/MWh <= limit %.2f, miners follow the solar surplus of %.2f kW: %.2f kW planned",
+		exportPrice, config.PriceLimit, plan.Surplus, plan.Load)
+
+	var wg sync.WaitGroup
+	errChan := make(chan error, len(minersList))
+	limiter := s.newMinerLimiter()
+	for _, miner := range minersList {
+		decision, ok := plan.Decisions[minerKey(miner)]
+		if !ok || decision.Reason != ReasonSolarSurplus {
+			continue
+		}
+		wg.Add(1)
+		go func(m *miners.AvalonQHost) {
+			defer wg.Done()
+			limiter.acquire()
+			defer limiter.release()
+			if err := s.applySolarSurplusDecision(ctx, m, decision); err != nil {
+				errChan <- err
+			}
+		}(miner)
+	}
+	wg.Wait()
+	close(errChan)
+
+	var errors []error
+	for err := range errChan {
+		errors = append(errors, err)
+	}
+	if len(errors) > 0 {
+		s.logger.Printf("Encountered %d errors while following the solar surplus:", len(errors))
+		for _, err := range errors {
+			s.logger.Printf("  - %v", err)
+		}
+		return true, fmt.Errorf("encountered %d errors while managing miners", len(errors))
+	}
+	return true, nil
+}
+
+// applySolarSurplusDecision sends the commands moving a miner to the state and work mode of decision
+func (s *MinerScheduler) applySolarSurplusDecision(ctx context.Context, m *miners.AvalonQHost, decision MinerControlDecision) error {
+	currentState := m.LastStats.State
+	currentWorkMode := miners.AvalonWorkMode(m.LastStats.WorkMode)
+	s.recordMinerDecision(m, decision)
+	if decision.State == currentState && (decision.State == miners.AvalonStateStandBy || decision.WorkMode == currentWorkMode) {
+		return nil
+	}
+	if s.config.DryRun {
+		s.logger.Printf("DRY-RUN: Would set miner %s:%d to %s state and %d mode for the solar surplus",
+			m.Address, m.Port, decision.State.String(), decision.WorkMode)
+		return nil
+	}
+	if s.deferMinerCommand(m) {
+		return nil
+	}
+
+	var response string
+	var err error
+	switch {
+	case decision.State == miners.AvalonStateStandBy:
+		response, err = m.Standby(ctx)
+	case currentState == miners.AvalonStateStandBy:
+		// Woken miners start in eco mode, the only mode planned for them
+		response, err = m.WakeUp(ctx)
+	default:
+		response, err = m.SetWorkMode(ctx, decision.WorkMode, decision.WorkMode > currentWorkMode)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to control miner %s:%d: %w", m.Address, m.Port, err)
+	}
+	s.logger.Printf("Control miner %s:%d to set %s state and %d mode (reason: %s): %s",
+		m.Address, m.Port, decision.State.String(), decision.WorkMode, decision.Reason, response)
+	return nil
+}
+
+// setSolarSurplusActive records whether miners follow the solar surplus until the next price check
+func (s *MinerScheduler) setSolarSurplusActive(active bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.solarSurplusActive = active
+}
+
+// isSolarSurplusActive reports whether miners follow the solar surplus planned at the last price check
+func (s *MinerScheduler) isSolarSurplusActive() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.solarSurplusActive
+}
+
+// exportPriceAtMost returns the adjusted export price at t in EUR/MWh and whether it is at or below limit.
+// It is false when no price covers t.
+func (s *MinerScheduler) exportPriceAtMost(config *Config, t time.Time, limit float64) (float64, bool) {
+	doc := s.GetPricesMarketData()
+	if doc == nil {
+		return 0, false
+	}
+	spotPrice, found := doc.LookupPriceByTime(t)
+	if !found {
+		return 0, false
+	}
+	_, exportPrice := AdjustedPrices(spotPrice, config)
+	return exportPrice * 1000, exportPrice*1000 <= limit
+}
+
+// selfConsumptionMaxMode returns the highest work mode a miner may be set to, -1 when it must stay in standby.
+// Standby miners are woken in eco mode, mining miners move at most one work mode up when FanR is below
+// the low threshold and must step down when FanR is above the high threshold, like in controlMiner.
+func (s *MinerScheduler) selfConsumptionMaxMode(m *miners.AvalonQHost, protection thunderProtection) int {
+	if protection == thunderStandby {
+		return -1
+	}
+
+	maxMode := int(miners.AvalonEcoMode)
+	if m.LastStats.State != miners.AvalonStateStandBy {
+		mode := int(m.LastStats.WorkMode)
+		switch {
+		case m.LastStats.FanR > s.config.FanRHighThreshold:
+			maxMode = mode - 1
+		case m.LastStats.FanR < s.config.FanRLowThreshold:
+			maxMode = min(mode+1, int(miners.AvalonSuperMode))
+		default:
+			maxMode = mode
+		}
+	}
+	if protection == thunderThrottle {
+		maxMode = min(maxMode, int(miners.AvalonEcoMode))
+	}
+	return maxMode
+}
+
+// selfConsumptionPower returns the power of a miner in the given work mode, -1 meaning standby
+func (s *MinerScheduler) selfConsumptionPower(mode int) float64 {
+	if mode < 0 {
+		return s.config.MinerPowerStandby
+	}
+	return s.getMinerPowerConsumption(miners.AvalonStateMining, miners.AvalonWorkMode(mode))
+}
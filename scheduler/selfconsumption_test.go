@@ -0,0 +1,224 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/miners"
+	"github.com/devskill-org/ems/sigenergy"
+)
+
+// newSelfConsumptionMiners creates n mining miners in eco mode with room to step up one work mode
+func newSelfConsumptionMiners(n int) []*miners.AvalonQHost {
+	list := make([]*miners.AvalonQHost, 0, n)
+	for i := range n {
+		m := newTestMiner(40, miners.AvalonEcoMode, miners.AvalonStateMining, nil)
+		m.Port = 4028 + i
+		list = append(list, m)
+	}
+	return list
+}
+
+func TestPlanSelfConsumption_LoadTracksSurplus(t *testing.T) {
+	tests := []struct {
+		name         string
+		solar        float64
+		baseLoad     float64
+		headroom     float64
+		expectedLoad float64
+		mining       int
+	}{
+		{name: "no surplus", solar: 3.0, baseLoad: 2.0, headroom: 1.0, expectedLoad: 0.4, mining: 0},
+		{name: "solar below base load", solar: 1.0, baseLoad: 2.0, expectedLoad: 0.4, mining: 0},
+		{name: "surplus for two eco miners", solar: 6.0, baseLoad: 2.0, headroom: 1.5, expectedLoad: 2.2, mining: 2},
+		{name: "surplus for all miners", solar: 8.0, baseLoad: 2.0, headroom: 1.0, expectedLoad: 5.0, mining: 4},
+		{name: "surplus beyond thermal headroom", solar: 30.0, baseLoad: 2.0, expectedLoad: 6.0, mining: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := newTestScheduler(nil)
+			plan := scheduler.planSelfConsumption(newSelfConsumptionMiners(4), tt.solar, tt.baseLoad, tt.headroom, time.Now())
+
+			if expected := max(0, tt.solar-tt.baseLoad-tt.headroom); math.Abs(plan.Surplus-expected) > 1e-9 {
+				t.Errorf("Expected surplus %.2f kW, got %.2f kW", expected, plan.Surplus)
+			}
+			if math.Abs(plan.Load-tt.expectedLoad) > 1e-9 {
+				t.Errorf("Expected miner load %.2f kW, got %.2f kW", tt.expectedLoad, plan.Load)
+			}
+			if plan.Surplus > 0 && plan.Load > plan.Target+1e-9 {
+				t.Errorf("Expected miner load %.2f kW within the target %.2f kW", plan.Load, plan.Target)
+			}
+
+			mining := 0
+			for _, decision := range plan.Decisions {
+				if decision.Reason != ReasonSolarSurplus {
+					t.Errorf("Expected reason %s, got %s", ReasonSolarSurplus, decision.Reason)
+				}
+				if decision.State == miners.AvalonStateMining {
+					mining++
+				}
+			}
+			if mining != tt.mining {
+				t.Errorf("Expected %d mining miners, got %d", tt.mining, mining)
+			}
+		})
+	}
+}
+
+func TestPlanSelfConsumption_IncreasingSurplus(t *testing.T) {
+	scheduler := newTestScheduler(nil)
+	minersList := newSelfConsumptionMiners(4)
+
+	// Up to the thermal headroom of 6 kW the load stays within one wake-up step below the surplus
+	previous := 0.0
+	for solar := 2.0; solar <= 8.0; solar += 0.25 {
+		plan := scheduler.planSelfConsumption(minersList, solar, 2.0, 0, time.Now())
+		if plan.Load < previous {
+			t.Errorf("Solar %.2f kW: miner load dropped from %.2f to %.2f kW", solar, previous, plan.Load)
+		}
+		if plan.Surplus > 0.4 && plan.Target-plan.Load >= 0.9 {
+			t.Errorf("Solar %.2f kW: miner load %.2f kW leaves %.2f kW of the surplus unused", solar, plan.Load, plan.Target-plan.Load)
+		}
+		previous = plan.Load
+	}
+}
+
+func TestPlanSelfConsumption_Constraints(t *testing.T) {
+	config := testConfig()
+	config.FanRHighThreshold = 80
+	config.FanRLowThreshold = 50
+	config.MinerPowerStandby = 0.1
+	config.MinerPowerEco = 1.0
+	config.MinerPowerStandard = 1.5
+	config.MinerPowerSuper = 2.0
+	config.MinersPowerLimit = 5.0
+	scheduler := newTestScheduler(config)
+
+	minersList := newSelfConsumptionMiners(4)
+	// Too hot in standard mode, may only step down
+	minersList[0].LastStats.FanR = 90
+	minersList[0].LastStats.WorkMode = miners.AvalonStandardMode
+	// Standby miners are only woken in eco mode
+	minersList[1].LastStats.State = miners.AvalonStateStandBy
+	// A manual override keeps its state and counts toward the load
+	minersList[2].LastStats.WorkMode = miners.AvalonSuperMode
+	scheduler.minerOverrides.Store(minerKey(minersList[2]), MinerOverride{State: miners.AvalonStateMining, WorkMode: miners.AvalonSuperMode})
+
+	plan := scheduler.planSelfConsumption(minersList, 20.0, 2.0, 0, time.Now())
+
+	if plan.Target != 5.0 {
+		t.Errorf("Expected target bounded by miners_power_limit to 5.0 kW, got %.2f kW", plan.Target)
+	}
+	// Override 2.0 kW plus three miners in eco mode, stepping one up to standard would exceed the limit
+	if math.Abs(plan.Load-5.0) > 1e-9 {
+		t.Errorf("Expected miner load 5.0 kW, got %.2f kW", plan.Load)
+	}
+	if decision := plan.Decisions[minerKey(minersList[0])]; decision.WorkMode > miners.AvalonEcoMode {
+		t.Errorf("Expected hot miner at most in eco mode, got %d", decision.WorkMode)
+	}
+	if decision := plan.Decisions[minerKey(minersList[1])]; decision.State != miners.AvalonStateMining || decision.WorkMode != miners.AvalonEcoMode {
+		t.Errorf("Expected standby miner to be woken in eco mode, got %+v", decision)
+	}
+	if decision := plan.Decisions[minerKey(minersList[2])]; decision.Reason != ReasonManualOverride || decision.WorkMode != miners.AvalonSuperMode {
+		t.Errorf("Expected overridden miner to keep super mode, got %+v", decision)
+	}
+}
+
+func TestManageMiners_SolarSurplus(t *testing.T) {
+	response, err := os.ReadFile("../test_data/avalon_litestat.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data file: %v", err)
+	}
+	// Miners in standard mode with FanR 71%, between the thresholds so they may not step up
+	response = bytes.Replace(response, []byte("WORKMODE[0]"), []byte("WORKMODE[1]"), 1)
+	// 60 EUR/MWh all day, above the price limit, with 300 EUR/MWh at 18:00
+	marketData, dayStart := spikeDay(t, 60, 300, 18)
+
+	tests := []struct {
+		name         string
+		hour         int
+		solar        float64
+		gridPower    float64
+		headroom     float64
+		expectedMode miners.AvalonWorkMode
+		surplus      bool
+	}{
+		// 3.2 kW of miners and a base load of 1 kW leave 2 kW of solar for two miners in eco mode
+		{name: "small surplus", hour: 12, solar: 3, gridPower: 1.2, expectedMode: miners.AvalonEcoMode, surplus: true},
+		{name: "large surplus", hour: 12, solar: 6, gridPower: -1.8, expectedMode: miners.AvalonStandardMode, surplus: true},
+		{name: "surplus charging the battery", hour: 12, solar: 6, gridPower: -1.8, headroom: 3, expectedMode: miners.AvalonEcoMode, surplus: true},
+		// Exporting at 280 EUR/MWh pays more than mining
+		{name: "high export price", hour: 18, solar: 6, gridPower: -1.8, surplus: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts, _ := serveCountingMiners(t, 2, response)
+
+			config := measuredLimitConfig()
+			config.PriceLimit = 50
+			config.ExportPriceOperatorFee = 20
+			config.SolarSurplusMining = true
+			scheduler := newTestScheduler(config)
+			var buf bytes.Buffer
+			scheduler.logger = log.New(&buf, "", 0)
+			scheduler.pricesMarketData = marketData
+			scheduler.setClock(&simulatedClock{now: dayStart.Add(time.Duration(tt.hour)*time.Hour + 30*time.Minute)})
+			scheduler.plantInfoFunc = func(_ *Config) (*sigenergy.PlantRunningInfo, error) {
+				return &sigenergy.PlantRunningInfo{
+					PhotovoltaicPower:            tt.solar,
+					GridSensorActivePower:        tt.gridPower,
+					ESSAvailableMaxChargingPower: tt.headroom,
+				}, nil
+			}
+			for _, host := range hosts {
+				scheduler.discoveredMiners.Store(minerKey(host), host)
+			}
+
+			spotPrice, _ := marketData.LookupPriceByTime(scheduler.now())
+			if err := scheduler.manageMiners(context.Background(), spotPrice, false); err != nil {
+				t.Fatalf("manageMiners failed: %v", err)
+			}
+			if scheduler.isSolarSurplusActive() != tt.surplus {
+				t.Errorf("Expected miners following the solar surplus %v, got log:\n%s", tt.surplus, buf.String())
+			}
+
+			for _, host := range hosts {
+				decision, ok := scheduler.GetMinerDecision(host)
+				if !tt.surplus {
+					if ok {
+						t.Errorf("Expected no solar surplus decision for miner %s, got %+v", minerKey(host), decision)
+					}
+					continue
+				}
+				if !ok || decision.Reason != ReasonSolarSurplus || decision.State != miners.AvalonStateMining || decision.WorkMode != tt.expectedMode {
+					t.Errorf("Expected miner %s mining in mode %d for the solar surplus, got %+v", minerKey(host), tt.expectedMode, decision)
+				}
+			}
+			if standby := strings.Count(buf.String(), "into standby (price 300.00 > limit 50.00)"); !tt.surplus && standby != len(hosts) {
+				t.Errorf("Expected %d miners put into standby for the price, got %d:\n%s", len(hosts), standby, buf.String())
+			}
+		})
+	}
+}
+
+func TestControlMiner_SolarSurplusHoldsWorkMode(t *testing.T) {
+	scheduler := newTestScheduler(nil)
+	miner := newTestMiner(40, miners.AvalonEcoMode, miners.AvalonStateMining, []int{40, 40, 40, 40, 40})
+
+	// A cool miner steps up on its own, but not beyond the solar surplus planned at the price check
+	if decision := scheduler.controlMiner(miner, 1.0, 30); decision.Reason != ReasonFanRLow {
+		t.Errorf("Expected reason %s without the solar surplus, got %s", ReasonFanRLow, decision.Reason)
+	}
+	scheduler.setSolarSurplusActive(true)
+	if decision := scheduler.controlMiner(miner, 1.0, 30); decision.Reason != ReasonSolarSurplus || decision.WorkMode != miners.AvalonEcoMode {
+		t.Errorf("Expected eco mode kept for the solar surplus, got %+v", decision)
+	}
+}
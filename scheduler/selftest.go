@@ -0,0 +1,110 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/devskill-org/ems/entsoe"
+	"github.com/devskill-org/ems/meteo"
+)
+
+// Self-test results of an integration
+const (
+	SelfTestOK      = "ok"
+	SelfTestFailed  = "failed"
+	SelfTestSkipped = "skipped" // The integration is not configured
+)
+
+// IntegrationCheck is the self-test result of one integration
+type IntegrationCheck struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// SelfTest attempts one lightweight operation against each configured integration: a price download from
+// ENTSO-E, a compact forecast from MET, a plant running info read over Modbus and a database ping. Nothing
+// is cached or written, so it can run before Start. Integrations that are not configured are skipped.
+func (s *MinerScheduler) SelfTest(ctx context.Context) []IntegrationCheck {
+	config := s.GetConfig()
+	checks := []struct {
+		name       string
+		configured bool
+		run        func(ctx context.Context) error
+	}{
+		{name: "prices", configured: config.SecurityToken != "", run: s.selfTestPrices},
+		{name: "weather", configured: true, run: s.selfTestWeather},
+		{name: "plant", configured: s.plantInfoFunc != nil || config.PlantModbusAddress != "", run: s.selfTestPlant},
+		{name: "database", configured: s.db != nil || config.PostgresConnString != "", run: s.selfTestDatabase},
+	}
+
+	results := make([]IntegrationCheck, 0, len(checks))
+	for _, check := range checks {
+		result := IntegrationCheck{Name: check.name, Status: SelfTestSkipped}
+		if check.configured {
+			start := time.Now()
+			err := check.run(ctx)
+			result.Duration = time.Since(start)
+			result.Status = SelfTestOK
+			if err != nil {
+				result.Status = SelfTestFailed
+				result.Error = err.Error()
+			}
+		}
+		s.logger.Printf("Self-test %s: %s %s", result.Name, result.Status, result.Error)
+		results = append(results, result)
+	}
+	return results
+}
+
+// selfTestPrices downloads the day-ahead prices without the price cache
+func (s *MinerScheduler) selfTestPrices(ctx context.Context) error {
+	config := s.GetConfig()
+	location, err := time.LoadLocation(config.Location)
+	if err != nil {
+		return fmt.Errorf("failed to load location: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, config.APITimeout)
+	defer cancel()
+	_, err = entsoe.DownloadPublicationMarketData(ctx, config.SecurityToken, config.URLFormat, location)
+	return err
+}
+
+// selfTestWeather fetches the compact forecast for the configured location with a new client, so the request
+// reaches MET instead of being answered from the response cache of the scheduler's client, which is left as it is
+func (s *MinerScheduler) selfTestWeather(ctx context.Context) error {
+	config := s.GetConfig()
+	_, err := s.newWeatherClient(config).GetCompactWithContext(ctx, meteo.QueryParams{
+		Location: meteo.Location{Latitude: config.Latitude, Longitude: config.Longitude},
+	})
+	return err
+}
+
+// selfTestPlant reads the plant running info
+func (s *MinerScheduler) selfTestPlant(_ context.Context) error {
+	config := s.GetConfig()
+	if s.plantInfoFunc != nil {
+		_, err := s.plantInfoFunc(config)
+		return err
+	}
+	_, err := s.readPlantRunningInfo(config)
+	return err
+}
+
+// selfTestDatabase pings the database, opening a connection when the scheduler has none yet
+func (s *MinerScheduler) selfTestDatabase(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.GetConfig().APITimeout)
+	defer cancel()
+	if s.db != nil {
+		return s.db.PingContext(ctx)
+	}
+	db, err := sql.Open("postgres", s.GetConfig().PostgresConnString)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.PingContext(ctx)
+}
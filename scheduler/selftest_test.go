@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devskill-org/ems/sigenergy"
+)
+
+func TestSelfTest_MixedResults(t *testing.T) {
+	pricesServer := mockEnergyPricesServer()
+	defer pricesServer.Close()
+	weatherServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer weatherServer.Close()
+	db, _ := newRecordingDB(t)
+
+	config := testConfigWithServer(pricesServer)
+	config.UserAgent = "test-agent"
+	scheduler := newTestScheduler(config)
+	scheduler.weatherBaseURL = weatherServer.URL
+	scheduler.plantInfoFunc = func(_ *Config) (*sigenergy.PlantRunningInfo, error) {
+		return nil, errors.New("modbus: connection refused")
+	}
+	scheduler.db = db
+
+	results := scheduler.SelfTest(t.Context())
+
+	expected := map[string]string{
+		"prices":   SelfTestOK,
+		"weather":  SelfTestFailed,
+		"plant":    SelfTestFailed,
+		"database": SelfTestOK,
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %+v", len(expected), results)
+	}
+	for _, result := range results {
+		if result.Status != expected[result.Name] {
+			t.Errorf("Expected %s %s, got %s (%s)", result.Name, expected[result.Name], result.Status, result.Error)
+		}
+		if (result.Status == SelfTestFailed) != (result.Error != "") {
+			t.Errorf("Expected an error only for a failed %s check, got %q", result.Name, result.Error)
+		}
+	}
+
+	// The self-test leaves the price and weather caches alone
+	if scheduler.GetPricesMarketData() != nil {
+		t.Error("Expected the downloaded prices not to be cached")
+	}
+	if _, ok := scheduler.weatherCache.Get(); ok {
+		t.Error("Expected no weather forecast to be cached")
+	}
+	if scheduler.weatherClient != nil {
+		t.Error("Expected the self-test not to keep a MET API client with cached responses")
+	}
+}
+
+func TestSelfTest_NotConfigured(t *testing.T) {
+	weatherServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"type": "Feature", "properties": {"timeseries": []}}`))
+	}))
+	defer weatherServer.Close()
+
+	config := testConfig()
+	config.UserAgent = "test-agent"
+	config.SecurityToken = ""
+	scheduler := newTestScheduler(config)
+	scheduler.weatherBaseURL = weatherServer.URL
+
+	for _, result := range scheduler.SelfTest(t.Context()) {
+		expected := SelfTestSkipped
+		if result.Name == "weather" {
+			expected = SelfTestOK
+		}
+		if result.Status != expected {
+			t.Errorf("Expected %s %s, got %s (%s)", result.Name, expected, result.Status, result.Error)
+		}
+	}
+}
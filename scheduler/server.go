@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/devskill-org/ems/miners"
 	"github.com/gorilla/websocket"
 	"github.com/sixdouglas/suncalc"
 )
@@ -38,15 +40,21 @@ type StatusResponse struct {
 
 // Health represents scheduler-specific health information
 type Health struct {
-	IsRunning          bool              `json:"is_running"`
-	MinersCount        int               `json:"miners_count"`
-	LastCheck          *time.Time        `json:"last_check,omitempty"`
-	HasMarketData      bool              `json:"has_market_data"`
-	LastDocumentTime   *time.Time        `json:"last_document_time,omitempty"`
-	PriceLimit         float64           `json:"price_limit"`
-	Network            string            `json:"network"`
-	CheckPriceInterval string            `json:"check_price_interval"`
-	MPCDecisions       []MPCDecisionInfo `json:"mpc_decisions,omitempty"`
+	IsRunning          bool                  `json:"is_running"`
+	MinersCount        int                   `json:"miners_count"`
+	LastCheck          *time.Time            `json:"last_check,omitempty"`
+	HasMarketData      bool                  `json:"has_market_data"`
+	SafeMode           bool                  `json:"safe_mode"`
+	ActiveStrategy     string                `json:"active_strategy"`
+	StrategyReason     string                `json:"strategy_reason,omitempty"`
+	LastDocumentTime   *time.Time            `json:"last_document_time,omitempty"`
+	PriceLimit         float64               `json:"price_limit"`
+	Network            string                `json:"network"`
+	CheckPriceInterval string                `json:"check_price_interval"`
+	MPCDecisions       []MPCDecisionInfo     `json:"mpc_decisions,omitempty"`
+	Tasks              map[string]TaskStatus `json:"tasks,omitempty"`
+	LoadForecastError  *LoadErrorStats       `json:"load_forecast_error,omitempty"`
+	Hashrate           *HashrateStatus       `json:"hashrate,omitempty"`
 }
 
 // MPCDecisionInfo represents MPC optimization decision information for API
@@ -57,6 +65,7 @@ type MPCDecisionInfo struct {
 	BatteryDischarge float64 `json:"battery_discharge"`
 	GridImport       float64 `json:"grid_import"`
 	GridExport           float64 `json:"grid_export"`
+	SolarCurtailment     float64 `json:"solar_curtailment"`
 	BatterySOC           float64 `json:"battery_soc"`
 	Profit               float64 `json:"profit"`
 	BatteryPreHeatActive bool    `json:"battery_preheat_active"`
@@ -107,6 +116,76 @@ type MetricsSummary struct {
 	EndTime         string  `json:"end_time"`
 }
 
+// MinerModeRequest represents a manual work mode request for a miner
+type MinerModeRequest struct {
+	Mode string `json:"mode"` // standby, eco, standard, super or auto to clear the override
+}
+
+// MinerModeResponse represents the result of a manual work mode request
+type MinerModeResponse struct {
+	Miner    string `json:"miner"`
+	Mode     string `json:"mode"`
+	Override bool   `json:"override"`
+}
+
+// Overview combines the current price, weather, plant and miners state for dashboards
+type Overview struct {
+	Timestamp     string             `json:"timestamp"`
+	Price         OverviewPrice      `json:"price"`
+	Weather       *OverviewWeather   `json:"weather,omitempty"`
+	Plant         *OverviewPlant     `json:"plant,omitempty"`
+	Miners        OverviewMiners     `json:"miners"`
+	NextMPCAction *OverviewMPCAction `json:"next_mpc_action,omitempty"`
+}
+
+// OverviewPrice represents the current spot price and the next hour cheap enough for mining
+type OverviewPrice struct {
+	Current        *float64 `json:"current,omitempty"`          // EUR/MWh
+	Limit          float64  `json:"limit"`                      // EUR/MWh
+	NextCheapHour  string   `json:"next_cheap_hour,omitempty"`  // start of the next hour priced at or below the limit
+	NextCheapPrice *float64 `json:"next_cheap_price,omitempty"` // EUR/MWh
+	Percentile     *float64 `json:"percentile,omitempty"`       // rank of the current price among the prices of the last 7 days, 0-100
+}
+
+// OverviewWeather represents the cached forecast step closest to now
+type OverviewWeather struct {
+	Time             string   `json:"time"`
+	AirTemperature   *float64 `json:"air_temperature,omitempty"` // °C
+	CloudCoverage    *float64 `json:"cloud_coverage,omitempty"`  // %
+	WindSpeed        *float64 `json:"wind_speed,omitempty"`      // m/s
+	Symbol           string   `json:"symbol,omitempty"`
+	Precipitation    *float64 `json:"precipitation,omitempty"`     // mm expected over the forecast period
+	PrecipitationMin *float64 `json:"precipitation_min,omitempty"` // mm, only when the forecast has the uncertainty range
+	PrecipitationMax *float64 `json:"precipitation_max,omitempty"` // mm, only when the forecast has the uncertainty range
+}
+
+// OverviewPlant represents the plant battery and power flows
+type OverviewPlant struct {
+	ESSSOC           float64 `json:"ess_soc"`            // %
+	PVPower          float64 `json:"pv_power"`           // kW
+	ESSPower         float64 `json:"ess_power"`          // kW, positive = charging
+	GridPower        float64 `json:"grid_power"`         // kW, positive = import
+	PlantActivePower float64 `json:"plant_active_power"` // kW
+	RunningState     string  `json:"running_state"`      // e.g. "Running", "Standby" or "Fault"
+	Fault            bool    `json:"fault"`              // the plant reports a fault
+}
+
+// OverviewMiners represents the discovered miners counted by mode (standby, eco, standard, super or unknown)
+type OverviewMiners struct {
+	Total  int            `json:"total"`
+	ByMode map[string]int `json:"by_mode"`
+}
+
+// OverviewMPCAction represents the MPC decision in effect now or next
+type OverviewMPCAction struct {
+	Timestamp    string  `json:"timestamp"`
+	Action       string  `json:"action"`        // charge, discharge or idle
+	BatteryPower float64 `json:"battery_power"` // kW
+	GridImport   float64 `json:"grid_import"`   // kW
+	GridExport   float64 `json:"grid_export"`   // kW
+	BatterySOC   float64 `json:"battery_soc"`   // percentage (0-1) at the end of the slot
+}
+
 // NewWebServer creates a new web server with health endpoints and static file serving
 func NewWebServer(scheduler *MinerScheduler, port int) *WebServer {
 	if port <= 0 {
@@ -117,7 +196,7 @@ func NewWebServer(scheduler *MinerScheduler, port int) *WebServer {
 	hs := &WebServer{
 		scheduler: scheduler,
 		port:      port,
-		startTime: time.Now(),
+		startTime: scheduler.now(),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
@@ -141,6 +220,11 @@ func NewWebServer(scheduler *MinerScheduler, port int) *WebServer {
 	mux.HandleFunc("/api/ready", hs.readinessHandler)
 	mux.HandleFunc("/api/ws", hs.wsHandler)
 	mux.HandleFunc("/api/metrics/summary", hs.metricsSummaryHandler)
+	mux.HandleFunc("/api/miners/{addr}/mode", hs.minerModeHandler)
+	mux.HandleFunc("/api/miners/costs", hs.minerCostsHandler)
+	mux.HandleFunc("/api/overview", hs.overviewHandler)
+	mux.HandleFunc("/api/kpi", hs.kpiHandler)
+	mux.HandleFunc("/api/prices/stats", hs.priceStatsHandler)
 
 	// Serve static files from web folder
 	fs := http.FileServer(http.Dir("./web/dist"))
@@ -211,6 +295,7 @@ func (hs *WebServer) healthHandler(w http.ResponseWriter, r *http.Request) {
 			BatteryDischarge:   dec.BatteryDischarge,
 			GridImport:         dec.GridImport,
 			GridExport:           dec.GridExport,
+			SolarCurtailment:     dec.SolarCurtailment,
 			BatterySOC:           dec.BatterySOC,
 			Profit:               dec.Profit,
 			BatteryPreHeatActive: dec.BatteryPreHeatActive,
@@ -227,18 +312,24 @@ func (hs *WebServer) healthHandler(w http.ResponseWriter, r *http.Request) {
 
 	response := StatusResponse{
 		Status:    "healthy",
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Timestamp: hs.scheduler.now().UTC().Format(time.RFC3339),
 		Version:   "1.0.0",
 		Scheduler: Health{
-			IsRunning:     status.IsRunning,
-			MinersCount:   status.MinersCount,
-			HasMarketData: status.HasMarketData,
-			PriceLimit:    hs.scheduler.GetConfig().PriceLimit,
-			Network:       hs.scheduler.GetConfig().Network,
-			MPCDecisions:  mpcDecisionsInfo,
+			IsRunning:         status.IsRunning,
+			MinersCount:       status.MinersCount,
+			HasMarketData:     status.HasMarketData,
+			SafeMode:          status.SafeMode,
+			ActiveStrategy:    status.ActiveStrategy,
+			StrategyReason:    status.StrategyReason,
+			PriceLimit:        hs.scheduler.GetConfig().PriceLimit,
+			Network:           hs.scheduler.GetConfig().Network,
+			MPCDecisions:      mpcDecisionsInfo,
+			Tasks:             status.Tasks,
+			LoadForecastError: status.LoadError,
+			Hashrate:          status.Hashrate,
 		},
 		System: SystemHealth{
-			Uptime:     formatUptime(time.Since(hs.startTime)),
+			Uptime:     formatUptime(hs.scheduler.now().Sub(hs.startTime)),
 			Goroutines: 0, // Placeholder - would need runtime.NumGoroutine()
 		},
 	}
@@ -266,7 +357,7 @@ func (hs *WebServer) readinessHandler(w http.ResponseWriter, r *http.Request) {
 
 	ready := map[string]any{
 		"ready":     status.IsRunning,
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"timestamp": hs.scheduler.now().UTC().Format(time.RFC3339),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -308,7 +399,7 @@ func (hs *WebServer) metricsSummaryHandler(w http.ResponseWriter, r *http.Reques
 		}
 	} else {
 		// Default to yesterday (calendar past date - midnight to midnight)
-		now := time.Now()
+		now := hs.scheduler.now()
 		yesterday := now.AddDate(0, 0, -1)
 		startTime = time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 0, 0, 0, 0, yesterday.Location())
 		endTime = time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 23, 59, 59, 999999999, yesterday.Location())
@@ -332,8 +423,8 @@ func (hs *WebServer) metricsSummaryHandler(w http.ResponseWriter, r *http.Reques
 			COALESCE(SUM(grid_export_power), 0) as total_export_kwh
 		FROM metrics
 		WHERE timestamp >= $1 AND timestamp <= $2
-		AND metric_name = 'energy_flow'
-	`, startTime, endTime).Scan(
+		AND metric_name IN ($3, $4)
+	`, startTime, endTime, metricEnergyFlow, metricEnergyFlowDaily).Scan(
 		&summary.TotalImportCost,
 		&summary.TotalExportCost,
 		&summary.TotalImportKWh,
@@ -355,6 +446,252 @@ func (hs *WebServer) metricsSummaryHandler(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// minerModeHandler handles the /api/miners/{addr}/mode endpoint
+func (hs *WebServer) minerModeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MinerModeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	addr := r.PathValue("addr")
+	mode := strings.ToLower(req.Mode)
+
+	var m *miners.AvalonQHost
+	var err error
+	if mode == "auto" {
+		m, err = hs.scheduler.ClearMinerOverride(addr)
+	} else {
+		override, ok := parseMinerOverride(mode)
+		if !ok {
+			http.Error(w, "Invalid mode. Use standby, eco, standard, super or auto", http.StatusBadRequest)
+			return
+		}
+		m, err = hs.scheduler.SetMinerOverride(r.Context(), addr, override)
+	}
+	if m == nil {
+		http.Error(w, "Miner not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	response := MinerModeResponse{
+		Miner:    minerKey(m),
+		Mode:     mode,
+		Override: mode != "auto",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// parseMinerOverride converts a mode name to a MinerOverride
+func parseMinerOverride(mode string) (MinerOverride, bool) {
+	switch mode {
+	case "standby":
+		return MinerOverride{State: miners.AvalonStateStandBy, WorkMode: miners.AvalonEcoMode}, true
+	case "eco":
+		return MinerOverride{State: miners.AvalonStateMining, WorkMode: miners.AvalonEcoMode}, true
+	case "standard":
+		return MinerOverride{State: miners.AvalonStateMining, WorkMode: miners.AvalonStandardMode}, true
+	case "super":
+		return MinerOverride{State: miners.AvalonStateMining, WorkMode: miners.AvalonSuperMode}, true
+	default:
+		return MinerOverride{}, false
+	}
+}
+
+// minerCostsHandler handles the /api/miners/costs endpoint
+func (hs *WebServer) minerCostsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	costs := hs.scheduler.GetMinerEnergyCosts()
+	if costs == nil {
+		http.Error(w, "Miner cost accounting is disabled", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(costs); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// priceStatsHandler handles the /api/prices/stats endpoint
+func (hs *WebServer) priceStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(hs.scheduler.GetPriceStats()); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// kpiHandler handles the /api/kpi endpoint. The date query parameter selects the day as YYYY-MM-DD
+// in the configured location, yesterday by default.
+func (hs *WebServer) kpiHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = hs.scheduler.now().In(solarProfileLocation(hs.scheduler.GetConfig())).AddDate(0, 0, -1).Format(kpiDateLayout)
+	}
+	if _, err := time.Parse(kpiDateLayout, date); err != nil {
+		http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if hs.scheduler.db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	kpi, err := hs.scheduler.GetEnergyKPI(r.Context(), date)
+	if err != nil {
+		http.Error(w, "Failed to compute KPI", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(kpi); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// overviewHandler handles the /api/overview endpoint
+func (hs *WebServer) overviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(hs.buildOverview()); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// buildOverview assembles the overview from the scheduler state, nothing is fetched from the
+// price or weather APIs
+func (hs *WebServer) buildOverview() Overview {
+	config := hs.scheduler.GetConfig()
+	now := hs.scheduler.now()
+
+	overview := Overview{
+		Timestamp: now.UTC().Format(time.RFC3339),
+		Price:     OverviewPrice{Limit: config.PriceLimit},
+		Miners:    OverviewMiners{ByMode: make(map[string]int)},
+	}
+
+	if doc := hs.scheduler.GetPricesMarketData(); doc != nil {
+		if price, found := doc.LookupPriceByTime(now); found {
+			overview.Price.Current = &price
+			if rank, ok := hs.scheduler.PricePercentile(price); ok {
+				overview.Price.Percentile = &rank
+			}
+		}
+		// Hours are checked until the end of the price document
+		for hour := now.Truncate(time.Hour).Add(time.Hour); ; hour = hour.Add(time.Hour) {
+			price, found := doc.LookupPriceByTime(hour)
+			if !found {
+				break
+			}
+			if miningAllowed(config, price, false) {
+				overview.Price.NextCheapHour = hour.UTC().Format(time.RFC3339)
+				overview.Price.NextCheapPrice = &price
+				break
+			}
+		}
+	}
+
+	if forecast, ok := hs.scheduler.weatherCache.Get(); ok {
+		if step := forecast.GetWeatherAtTime(now); step != nil {
+			weather := &OverviewWeather{
+				Time:           step.Time.UTC().Format(time.RFC3339),
+				AirTemperature: step.GetTemperature(),
+				CloudCoverage:  step.GetCloudCoverage(),
+				WindSpeed:      step.GetWindSpeed(),
+			}
+			if symbol := step.GetSymbolCode(); symbol != nil {
+				weather.Symbol = string(*symbol)
+			}
+			weather.PrecipitationMin, weather.PrecipitationMax, weather.Precipitation = step.GetPrecipitationRange()
+			overview.Weather = weather
+		}
+	}
+
+	if info := hs.scheduler.GetPlantRunningInfo(); info != nil {
+		overview.Plant = &OverviewPlant{
+			ESSSOC:           info.ESSSOC,
+			PVPower:          info.PhotovoltaicPower,
+			ESSPower:         info.ESSPower,
+			GridPower:        info.GridSensorActivePower,
+			PlantActivePower: info.PlantActivePower,
+			RunningState:     info.PlantRunningState.String(),
+			Fault:            info.PlantRunningState.IsFault(),
+		}
+	}
+
+	for _, miner := range hs.scheduler.GetDiscoveredMiners() {
+		overview.Miners.Total++
+		overview.Miners.ByMode[overviewMinerMode(miner)]++
+	}
+
+	slotDuration := int64(config.CheckPriceInterval.Seconds())
+	for _, dec := range hs.scheduler.GetMPCDecisions() {
+		if dec.Timestamp+slotDuration <= now.Unix() {
+			continue
+		}
+		action := &OverviewMPCAction{
+			Timestamp:  time.Unix(dec.Timestamp, 0).UTC().Format(time.RFC3339),
+			Action:     "idle",
+			GridImport: dec.GridImport,
+			GridExport: dec.GridExport,
+			BatterySOC: dec.BatterySOC,
+		}
+		if dec.BatteryCharge > 0 {
+			action.Action = "charge"
+			action.BatteryPower = dec.BatteryCharge
+		} else if dec.BatteryDischarge > 0 {
+			action.Action = "discharge"
+			action.BatteryPower = dec.BatteryDischarge
+		}
+		overview.NextMPCAction = action
+		break
+	}
+
+	return overview
+}
+
+// overviewMinerMode returns the mode a miner is counted under in the overview
+func overviewMinerMode(miner *miners.AvalonQHost) string {
+	if miner.LastStats == nil {
+		return "unknown"
+	}
+	if miner.LastStats.State == miners.AvalonStateStandBy {
+		return "standby"
+	}
+	return strings.ToLower(miner.LastStats.WorkMode.String())
+}
+
 // wsHandler handles WebSocket connections
 func (hs *WebServer) wsHandler(w http.ResponseWriter, r *http.Request) {
 	conn, err := hs.upgrader.Upgrade(w, r, nil)
@@ -485,17 +822,28 @@ func (hs *WebServer) buildStatusData() map[string]any {
 			minersHealthy = false
 		}
 
-		minersList = append(minersList, map[string]any{
+		minerInfo := map[string]any{
 			"ip":     miner.Address,
 			"status": minerStatus,
-		})
+		}
+		if !miner.LastSeen.IsZero() {
+			minerInfo["last_seen"] = miner.LastSeen.UTC().Format(time.RFC3339)
+		}
+		if miner.LastStats != nil {
+			minerInfo["network_health"] = miner.LastStats.NetworkHealth(hs.scheduler.now()).String()
+		}
+		if decision, ok := hs.scheduler.GetMinerDecision(miner); ok {
+			minerInfo["reason"] = decision.Reason
+			minerInfo["decided_at"] = decision.Timestamp.UTC().Format(time.RFC3339)
+		}
+		minersList = append(minersList, minerInfo)
 	}
 
 	// Determine overall health status
 	overallStatus := "healthy"
 	if !status.IsRunning {
 		overallStatus = "unhealthy"
-	} else if len(miners) > 0 && !minersHealthy {
+	} else if status.SafeMode || (len(miners) > 0 && !minersHealthy) {
 		overallStatus = "degraded"
 	}
 
@@ -510,6 +858,7 @@ func (hs *WebServer) buildStatusData() map[string]any {
 			BatteryDischarge:   dec.BatteryDischarge,
 			GridImport:         dec.GridImport,
 			GridExport:           dec.GridExport,
+			SolarCurtailment:     dec.SolarCurtailment,
 			BatterySOC:           dec.BatterySOC,
 			Profit:               dec.Profit,
 			BatteryPreHeatActive: dec.BatteryPreHeatActive,
@@ -526,18 +875,21 @@ func (hs *WebServer) buildStatusData() map[string]any {
 
 	health := StatusResponse{
 		Status:    overallStatus,
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Timestamp: hs.scheduler.now().UTC().Format(time.RFC3339),
 		Version:   "1.0.0",
 		Scheduler: Health{
-			IsRunning:     status.IsRunning,
-			MinersCount:   status.MinersCount,
-			HasMarketData: status.HasMarketData,
-			PriceLimit:    hs.scheduler.GetConfig().PriceLimit,
-			Network:       hs.scheduler.GetConfig().Network,
-			MPCDecisions:  mpcDecisionsInfo,
+			IsRunning:      status.IsRunning,
+			MinersCount:    status.MinersCount,
+			HasMarketData:  status.HasMarketData,
+			SafeMode:       status.SafeMode,
+			ActiveStrategy: status.ActiveStrategy,
+			StrategyReason: status.StrategyReason,
+			PriceLimit:     hs.scheduler.GetConfig().PriceLimit,
+			Network:        hs.scheduler.GetConfig().Network,
+			MPCDecisions:   mpcDecisionsInfo,
 		},
 		System: SystemHealth{
-			Uptime:     formatUptime(time.Since(hs.startTime)),
+			Uptime:     formatUptime(hs.scheduler.now().Sub(hs.startTime)),
 			Goroutines: 0,
 		},
 	}
@@ -558,7 +910,7 @@ func (hs *WebServer) buildStatusData() map[string]any {
 
 	// Calculate sun information
 	config := hs.scheduler.GetConfig()
-	now := time.Now()
+	now := hs.scheduler.now()
 	sunTimes := suncalc.GetTimes(now, config.Latitude, config.Longitude)
 	sunPos := suncalc.GetPosition(now, config.Latitude, config.Longitude)
 
@@ -576,7 +928,7 @@ func (hs *WebServer) buildStatusData() map[string]any {
 		priceData["document_id"] = doc.MRID
 		priceData["created_at"] = doc.CreatedDateTime
 
-		if price, found := doc.LookupPriceByTime(time.Now()); found {
+		if price, found := doc.LookupPriceByTime(now); found {
 			priceData["current_price"] = price
 			priceData["current"] = price
 			priceData["limit"] = hs.scheduler.GetConfig().PriceLimit
@@ -593,7 +945,7 @@ func (hs *WebServer) buildStatusData() map[string]any {
 				"list":  minersList,
 			},
 			"price_data": priceData,
-			"timestamp":  time.Now().UTC().Format(time.RFC3339),
+			"timestamp":  hs.scheduler.now().UTC().Format(time.RFC3339),
 		},
 	}
 }
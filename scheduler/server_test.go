@@ -0,0 +1,209 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/entsoe"
+	"github.com/devskill-org/ems/meteo"
+	"github.com/devskill-org/ems/miners"
+	"github.com/devskill-org/ems/mpc"
+	"github.com/devskill-org/ems/sigenergy"
+)
+
+// newOverviewTestServer returns a web server whose scheduler holds prices, weather, plant info, miners and
+// an MPC plan at 11:10 UTC on 2025-11-30
+func newOverviewTestServer(t *testing.T) *WebServer {
+	t.Helper()
+	now := time.Date(2025, 11, 30, 11, 10, 0, 0, time.UTC)
+	dayStart := time.Date(2025, 11, 30, 0, 0, 0, 0, time.UTC)
+
+	config := testConfig()
+	config.PriceLimit = 100
+	config.CheckPriceInterval = 15 * time.Minute
+	scheduler := newTestScheduler(config)
+	scheduler.logger = log.New(&bytes.Buffer{}, "", 0)
+	scheduler.setClock(&simulatedClock{now: now})
+
+	// Hourly prices above the limit except at 14:00
+	points := make([]entsoe.Point, 24)
+	for i := range points {
+		points[i] = entsoe.Point{Position: i + 1, PriceAmount: 150}
+	}
+	points[14].PriceAmount = 80
+	interval := entsoe.TimeInterval{Start: dayStart, End: dayStart.AddDate(0, 0, 1)}
+	scheduler.mu.Lock()
+	scheduler.pricesMarketData = &entsoe.PublicationMarketData{
+		PeriodTimeInterval: interval,
+		TimeSeries: []entsoe.TimeSeries{
+			{Period: entsoe.Period{TimeInterval: interval, Resolution: time.Hour, Points: points}},
+		},
+	}
+	scheduler.mpcDecisions = []mpc.ControlDecision{
+		{Timestamp: now.Add(-time.Hour).Unix(), BatteryDischarge: 4, BatterySOC: 0.6},
+		{Timestamp: now.Add(-10 * time.Minute).Unix(), BatteryCharge: 2.5, GridImport: 3, BatterySOC: 0.65},
+		{Timestamp: now.Add(5 * time.Minute).Unix(), BatterySOC: 0.65},
+	}
+	scheduler.mu.Unlock()
+
+	weatherJSON, err := os.ReadFile("../test_data/locationforecast/example.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data file: %v", err)
+	}
+	var forecast meteo.METJSONForecast
+	if err := json.Unmarshal(weatherJSON, &forecast); err != nil {
+		t.Fatalf("Failed to decode weather forecast: %v", err)
+	}
+	scheduler.weatherCache.Set(&forecast)
+
+	scheduler.plantInfoFunc = func(_ *Config) (*sigenergy.PlantRunningInfo, error) {
+		return &sigenergy.PlantRunningInfo{ESSSOC: 55, PhotovoltaicPower: 3.2, ESSPower: 2.5, GridSensorActivePower: 0.4,
+			PlantRunningState: sigenergy.RunningStateRunning}, nil
+	}
+
+	for i, miner := range []*miners.AvalonQHost{
+		newTestMiner(50, miners.AvalonEcoMode, miners.AvalonStateStandBy, nil),
+		newTestMiner(50, miners.AvalonEcoMode, miners.AvalonStateMining, nil),
+		newTestMiner(50, miners.AvalonSuperMode, miners.AvalonStateMining, nil),
+		newTestMiner(50, miners.AvalonSuperMode, miners.AvalonStateMining, nil),
+		{Port: 4028},
+	} {
+		miner.Address = fmt.Sprintf("192.168.1.%d", i+1)
+		scheduler.discoveredMiners.Store(minerKey(miner), miner)
+	}
+
+	return &WebServer{scheduler: scheduler, startTime: now}
+}
+
+func TestHealthHandler_ActiveStrategy(t *testing.T) {
+	hs := newOverviewTestServer(t)
+
+	rec := httptest.NewRecorder()
+	hs.healthHandler(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	var response StatusResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Scheduler.ActiveStrategy != StrategyMPC {
+		t.Errorf("Expected active strategy %s, got %q", StrategyMPC, response.Scheduler.ActiveStrategy)
+	}
+	if !strings.Contains(response.Scheduler.StrategyReason, "MPC plan") {
+		t.Errorf("Expected the strategy reason to mention the MPC plan, got %q", response.Scheduler.StrategyReason)
+	}
+}
+
+func TestOverviewHandler(t *testing.T) {
+	hs := newOverviewTestServer(t)
+
+	rec := httptest.NewRecorder()
+	hs.overviewHandler(rec, httptest.NewRequest(http.MethodGet, "/api/overview", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", contentType)
+	}
+
+	// Every section is present in the combined document
+	var shape map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &shape); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	for _, key := range []string{"timestamp", "price", "weather", "plant", "miners", "next_mpc_action"} {
+		if _, ok := shape[key]; !ok {
+			t.Errorf("Expected %q in the overview, got %s", key, rec.Body.String())
+		}
+	}
+
+	var overview Overview
+	if err := json.Unmarshal(rec.Body.Bytes(), &overview); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if overview.Timestamp != "2025-11-30T11:10:00Z" {
+		t.Errorf("Expected timestamp of the scheduler clock, got %s", overview.Timestamp)
+	}
+
+	price := overview.Price
+	if price.Current == nil || *price.Current != 150 || price.Limit != 100 {
+		t.Errorf("Expected current price 150 with limit 100, got %+v", price)
+	}
+	if price.NextCheapHour != "2025-11-30T14:00:00Z" || price.NextCheapPrice == nil || *price.NextCheapPrice != 80 {
+		t.Errorf("Expected next cheap hour at 14:00 for 80, got %+v", price)
+	}
+
+	weather := overview.Weather
+	if weather == nil || weather.Time != "2025-11-30T11:00:00Z" {
+		t.Fatalf("Expected the weather of 11:00, got %+v", weather)
+	}
+	if weather.AirTemperature == nil || *weather.AirTemperature != 1.5 || weather.CloudCoverage == nil || *weather.CloudCoverage != 100 {
+		t.Errorf("Expected 1.5 °C with 100%% cloud coverage, got %+v", weather)
+	}
+	// The forecast has only the expected amount, without the uncertainty range
+	if weather.Precipitation == nil || *weather.Precipitation != 0 || weather.PrecipitationMin != nil || weather.PrecipitationMax != nil {
+		t.Errorf("Expected 0 mm precipitation without a range, got %+v", weather)
+	}
+
+	if overview.Plant == nil || overview.Plant.ESSSOC != 55 || overview.Plant.PVPower != 3.2 || overview.Plant.GridPower != 0.4 {
+		t.Errorf("Expected plant SOC 55%% with 3.2 kW PV and 0.4 kW import, got %+v", overview.Plant)
+	}
+	if overview.Plant != nil && (overview.Plant.RunningState != "Running" || overview.Plant.Fault) {
+		t.Errorf("Expected the plant running without a fault, got %+v", overview.Plant)
+	}
+
+	expectedModes := map[string]int{"standby": 1, "eco": 1, "super": 2, "unknown": 1}
+	if overview.Miners.Total != 5 || len(overview.Miners.ByMode) != len(expectedModes) {
+		t.Errorf("Expected 5 miners by mode %v, got %+v", expectedModes, overview.Miners)
+	}
+	for mode, count := range expectedModes {
+		if overview.Miners.ByMode[mode] != count {
+			t.Errorf("Expected %d miners in %s, got %d", count, mode, overview.Miners.ByMode[mode])
+		}
+	}
+
+	// The decision of the current slot, the past one is skipped
+	action := overview.NextMPCAction
+	if action == nil || action.Timestamp != "2025-11-30T11:00:00Z" || action.Action != "charge" || action.BatteryPower != 2.5 {
+		t.Errorf("Expected charging at 2.5 kW from 11:00, got %+v", action)
+	}
+}
+
+func TestOverviewHandler_Empty(t *testing.T) {
+	hs := &WebServer{scheduler: newTestScheduler(testConfig())}
+
+	rec := httptest.NewRecorder()
+	hs.overviewHandler(rec, httptest.NewRequest(http.MethodGet, "/api/overview", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var shape map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &shape); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	// Sections without data are left out, the price limit and miner counts are always present
+	for _, key := range []string{"weather", "plant", "next_mpc_action"} {
+		if _, ok := shape[key]; ok {
+			t.Errorf("Expected no %q without data, got %s", key, rec.Body.String())
+		}
+	}
+	for _, key := range []string{"price", "miners"} {
+		if _, ok := shape[key]; !ok {
+			t.Errorf("Expected %q in the overview, got %s", key, rec.Body.String())
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	hs.overviewHandler(rec, httptest.NewRequest(http.MethodPost, "/api/overview", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for POST, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,183 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/devskill-org/ems/entsoe"
+	"github.com/devskill-org/ems/meteo"
+	"github.com/devskill-org/ems/mpc"
+	"github.com/devskill-org/ems/sigenergy"
+)
+
+// PlantSnapshot is a plant running info reading recorded at a point in time
+type PlantSnapshot struct {
+	Time time.Time                  `json:"time"`
+	Info sigenergy.PlantRunningInfo `json:"info"`
+}
+
+// SimulationData holds the recorded data a simulation replays instead of the live sources
+type SimulationData struct {
+	Prices  *entsoe.PublicationMarketData // Day-ahead price document
+	Weather *meteo.METJSONForecast        // Weather forecast, nil runs without solar forecast
+	Plant   []PlantSnapshot               // Plant readings, the latest one at or before the simulated time is used
+}
+
+// SimulationStep is what the scheduler decided at one simulated time
+type SimulationStep struct {
+	Time         time.Time
+	SpotPrice    float64              // EUR/MWh, 0 without a price
+	HasPrice     bool                 // A price was available at Time
+	PriceSpike   bool                 // A price spike was forecast within price_spike_lead_time
+	MinersMining bool                 // Price-based control would keep miners mining
+	MPCDecision  *mpc.ControlDecision // First decision of the MPC plan, the one executed, nil without a plan
+	MPCError     error                // Error of the MPC optimization, if any
+}
+
+// simulatedClock is a Clock set explicitly by the simulation
+type simulatedClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// Now returns the simulated time
+func (c *simulatedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the simulated time to now
+func (c *simulatedClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Simulate replays recorded data through the scheduler from start until end every step, as fast as the
+// optimizations run, and returns the decisions taken at each simulated time. Nothing is written to the
+// plant, the miners or the database: the scheduler runs in dry-run mode on a copy of config.
+func Simulate(ctx context.Context, config *Config, data SimulationData, start, end time.Time, step time.Duration, logger *log.Logger) ([]SimulationStep, error) {
+	if step <= 0 {
+		return nil, fmt.Errorf("simulation step must be positive, got: %v", step)
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("simulation end %s must be after start %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+	if data.Prices == nil {
+		return nil, fmt.Errorf("simulation requires a price document")
+	}
+	if len(data.Plant) == 0 {
+		return nil, fmt.Errorf("simulation requires at least one plant snapshot")
+	}
+
+	simConfig := *config
+	simConfig.DryRun = true
+
+	clock := &simulatedClock{now: start}
+	s := NewMinerScheduler(&simConfig, logger)
+	s.setClock(clock)
+
+	// The recorded prices never expire during the simulation
+	s.pricesMarketData = data.Prices
+	s.pricesMarketDataExpiry = end.Add(step)
+	if location, err := time.LoadLocation(simConfig.Location); err == nil {
+		s.priceStats.update(data.Prices, location)
+	}
+
+	// The recorded forecast never expires either, an empty forecast yields no solar instead of a live fetch
+	weather := data.Weather
+	if weather == nil {
+		weather = &meteo.METJSONForecast{}
+	}
+	s.weatherCache.cacheDuration = math.MaxInt64
+	s.weatherCache.Set(weather)
+
+	snapshots := slices.Clone(data.Plant)
+	slices.SortStableFunc(snapshots, func(a, b PlantSnapshot) int { return a.Time.Compare(b.Time) })
+	s.plantInfoFunc = func(_ *Config) (*sigenergy.PlantRunningInfo, error) {
+		info := plantSnapshotAt(snapshots, clock.Now())
+		return &info, nil
+	}
+
+	var steps []SimulationStep
+	for t := start; t.Before(end); t = t.Add(step) {
+		if err := ctx.Err(); err != nil {
+			return steps, err
+		}
+		clock.Set(t)
+
+		result := SimulationStep{Time: t}
+		if price, found := data.Prices.LookupPriceByTime(t); found {
+			result.SpotPrice = price
+			result.HasPrice = true
+			result.PriceSpike = s.detectPriceSpike(t)
+			result.MinersMining = miningAllowed(&simConfig, price, result.PriceSpike)
+		}
+
+		if err := s.RunMPCOptimize(ctx); err != nil {
+			result.MPCError = err
+		} else if decisions := s.GetMPCDecisions(); len(decisions) > 0 && decisions[0].Timestamp == t.Unix() {
+			// A plan from an earlier step is kept when no new one was made
+			decision := decisions[0]
+			result.MPCDecision = &decision
+		}
+		steps = append(steps, result)
+	}
+	return steps, nil
+}
+
+// plantSnapshotAt returns the latest snapshot at or before t, the first one before any snapshot.
+// snapshots must be sorted by time and not empty.
+func plantSnapshotAt(snapshots []PlantSnapshot, t time.Time) sigenergy.PlantRunningInfo {
+	info := snapshots[0].Info
+	for _, snapshot := range snapshots[1:] {
+		if snapshot.Time.After(t) {
+			break
+		}
+		info = snapshot.Info
+	}
+	return info
+}
+
+// LoadSimulationData reads recorded data for Simulate: an ENTSO-E price XML document, a MET weather
+// forecast JSON (optional, empty path for none) and a JSON array of plant snapshots
+func LoadSimulationData(pricesPath, weatherPath, plantPath string) (SimulationData, error) {
+	var data SimulationData
+
+	pricesFile, err := os.Open(pricesPath)
+	if err != nil {
+		return data, fmt.Errorf("failed to open prices: %w", err)
+	}
+	defer pricesFile.Close()
+	if data.Prices, err = entsoe.DecodeEnergyPricesXML(pricesFile); err != nil {
+		return data, fmt.Errorf("failed to decode prices: %w", err)
+	}
+
+	if weatherPath != "" {
+		weatherJSON, err := os.ReadFile(weatherPath)
+		if err != nil {
+			return data, fmt.Errorf("failed to read weather forecast: %w", err)
+		}
+		data.Weather = &meteo.METJSONForecast{}
+		if err := json.Unmarshal(weatherJSON, data.Weather); err != nil {
+			return data, fmt.Errorf("failed to decode weather forecast: %w", err)
+		}
+	}
+
+	plantJSON, err := os.ReadFile(plantPath)
+	if err != nil {
+		return data, fmt.Errorf("failed to read plant snapshots: %w", err)
+	}
+	if err := json.Unmarshal(plantJSON, &data.Plant); err != nil {
+		return data, fmt.Errorf("failed to decode plant snapshots: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/sigenergy"
+)
+
+func TestSimulate_RecordedScenario(t *testing.T) {
+	data, err := LoadSimulationData(
+		"../test_data/Energy_Prices_202601042300-202601052300.xml",
+		"../test_data/locationforecast/example.json",
+		"../test_data/plant_snapshots.json",
+	)
+	if err != nil {
+		t.Fatalf("LoadSimulationData failed: %v", err)
+	}
+
+	config := testConfig()
+	config.PriceLimit = 105
+	// Hourly slots keep the optimization of each step short
+	config.CheckPriceInterval = time.Hour
+	config.BatteryCapacity = 10
+	config.BatteryMaxCharge = 5
+	config.BatteryMaxDischarge = 5
+	config.BatteryMinSOC = 0.1
+	config.BatteryMaxSOC = 0.9
+	config.BatteryEfficiency = 0.95
+	config.MaxGridImport = 20
+	config.MaxGridExport = 20
+	config.ImportPriceDeliveryFee = 40
+	config.ExportPriceOperatorFee = 17
+	var buf bytes.Buffer
+
+	start := time.Date(2026, 1, 5, 1, 0, 0, 0, time.UTC)
+	steps, err := Simulate(context.Background(), config, data, start, start.Add(7*time.Hour), time.Hour, log.New(&buf, "", 0))
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+
+	expected := []struct {
+		price  float64
+		mining bool
+		soc    float64 // % of the plant snapshot the plan starts from
+	}{
+		{price: 101.21, mining: true, soc: 40},
+		{price: 99.93, mining: true, soc: 40},
+		{price: 86.25, mining: true, soc: 40},
+		{price: 110.07, mining: false, soc: 85},
+		{price: 110.02, mining: false, soc: 85},
+		{price: 114.46, mining: false, soc: 85},
+		{price: 145.35, mining: false, soc: 85},
+	}
+	if len(steps) != len(expected) {
+		t.Fatalf("Expected %d steps, got %d", len(expected), len(steps))
+	}
+
+	for i, step := range steps {
+		want := expected[i]
+		if wantTime := start.Add(time.Duration(i) * time.Hour); !step.Time.Equal(wantTime) {
+			t.Errorf("Step %d: expected time %s, got %s", i, wantTime, step.Time)
+		}
+		if !step.HasPrice || step.SpotPrice != want.price {
+			t.Errorf("Step %d: expected spot price %.2f, got %.2f (found %v)", i, want.price, step.SpotPrice, step.HasPrice)
+		}
+		if step.MinersMining != want.mining {
+			t.Errorf("Step %d: expected miners mining %v at %.2f EUR/MWh, got %v", i, want.mining, step.SpotPrice, step.MinersMining)
+		}
+		if step.MPCError != nil {
+			t.Fatalf("Step %d: MPC failed: %v", i, step.MPCError)
+		}
+		if step.MPCDecision == nil {
+			t.Fatalf("Step %d: expected an MPC decision", i)
+		}
+		if step.MPCDecision.Timestamp != step.Time.Unix() {
+			t.Errorf("Step %d: expected a plan starting at the simulated time, got %s", i, time.Unix(step.MPCDecision.Timestamp, 0).UTC())
+		}
+		importPrice, _ := AdjustedPrices(want.price, config)
+		if math.Abs(step.MPCDecision.ImportPrice-importPrice) > 1e-9 {
+			t.Errorf("Step %d: expected planned import price %.4f EUR/kWh, got %.4f", i, importPrice, step.MPCDecision.ImportPrice)
+		}
+		// The executed decision moves the SOC at most one slot of charge or discharge away from the recorded SOC
+		maxStep := 5.0 * config.CheckPriceInterval.Hours() / config.BatteryCapacity
+		if soc := step.MPCDecision.BatterySOC; math.Abs(soc-want.soc/100) > maxStep+1e-9 {
+			t.Errorf("Step %d: expected SOC near the recorded %.0f%%, got %.1f%%", i, want.soc, soc*100)
+		}
+	}
+
+	if !bytes.Contains(buf.Bytes(), []byte("DRY-RUN: Would execute MPC decision")) {
+		t.Error("Expected MPC decisions to be simulated in dry-run mode")
+	}
+}
+
+func TestPlantSnapshotAt(t *testing.T) {
+	base := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	snapshots := []PlantSnapshot{
+		{Time: base, Info: sigenergy.PlantRunningInfo{ESSSOC: 40}},
+		{Time: base.Add(time.Hour), Info: sigenergy.PlantRunningInfo{ESSSOC: 50}},
+	}
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected float64
+	}{
+		{name: "before the first snapshot", at: base.Add(-time.Hour), expected: 40},
+		{name: "at the first snapshot", at: base, expected: 40},
+		{name: "between snapshots", at: base.Add(30 * time.Minute), expected: 40},
+		{name: "at the last snapshot", at: base.Add(time.Hour), expected: 50},
+		{name: "after the last snapshot", at: base.Add(5 * time.Hour), expected: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if info := plantSnapshotAt(snapshots, tt.at); info.ESSSOC != tt.expected {
+				t.Errorf("Expected SOC %.0f, got %.0f", tt.expected, info.ESSSOC)
+			}
+		})
+	}
+}
+
+func TestSimulate_InvalidArguments(t *testing.T) {
+	start := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	data := SimulationData{Plant: []PlantSnapshot{{Time: start}}}
+
+	if _, err := Simulate(context.Background(), testConfig(), data, start, start.Add(time.Hour), time.Hour, nil); err == nil {
+		t.Error("Expected an error without a price document")
+	}
+}
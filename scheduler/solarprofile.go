@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/devskill-org/ems/sigenergy"
+)
+
+// validateSolarProfile checks a solar profile has 12 monthly rows of 24 hourly fractions between 0 and 1,
+// an empty profile disables it
+func validateSolarProfile(profile [][]float64) error {
+	if len(profile) == 0 {
+		return nil
+	}
+	if len(profile) != 12 {
+		return fmt.Errorf("expected 12 months, got: %d", len(profile))
+	}
+	for month, hours := range profile {
+		if len(hours) != 24 {
+			return fmt.Errorf("expected 24 hours in month %d, got: %d", month+1, len(hours))
+		}
+		for hour, fraction := range hours {
+			if fraction < 0 || fraction > 1 {
+				return fmt.Errorf("fraction for month %d hour %d must be between 0 and 1, got: %f", month+1, hour, fraction)
+			}
+		}
+	}
+	return nil
+}
+
+// fallbackSolarPower returns the typical solar power at t from the fallback profile, 0 without a profile.
+// The profile is indexed by the month and hour in the configured location.
+func fallbackSolarPower(config *Config, location *time.Location, t time.Time) float64 {
+	if len(config.SolarFallbackProfile) != 12 {
+		return 0
+	}
+	local := t.In(location)
+	hours := config.SolarFallbackProfile[local.Month()-1]
+	if len(hours) != 24 {
+		return 0
+	}
+	return hours[local.Hour()] * config.MaxSolarPower
+}
+
+// solarProfileLocation returns the configured location used to look up the fallback profile, UTC if it is invalid
+func solarProfileLocation(config *Config) *time.Location {
+	location, err := time.LoadLocation(config.Location)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// fallbackSolarForecast builds the solar forecast used without live weather for the slots of the given resolution
+// over the horizon from now. With a fallback profile the first slot is the current PV power and later slots follow
+// the profile, without one all slots are zero.
+func (s *MinerScheduler) fallbackSolarForecast(config *Config, now time.Time, plantInfo *sigenergy.PlantRunningInfo,
+	resolution, horizon time.Duration) map[int]float64 {
+	solarForecast := make(map[int]float64)
+	if len(config.SolarFallbackProfile) == 0 {
+		s.logger.Printf("Warning: no live weather, using zero solar")
+		return solarForecast
+	}
+
+	s.logger.Printf("Warning: no live weather, using the fallback solar profile")
+	location := solarProfileLocation(config)
+	for i := range int(horizon / resolution) {
+		solarForecast[i] = fallbackSolarPower(config, location, now.Add(time.Duration(i)*resolution))
+	}
+	if plantInfo != nil {
+		solarForecast[0] = plantInfo.PhotovoltaicPower
+	}
+	return solarForecast
+}
@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/sigenergy"
+)
+
+// daytimeProfile returns a fallback profile with the given fraction from 08:00 to 17:59 in every month
+func daytimeProfile(fraction float64) [][]float64 {
+	profile := make([][]float64, 12)
+	for month := range profile {
+		profile[month] = make([]float64, 24)
+		for hour := 8; hour < 18; hour++ {
+			profile[month][hour] = fraction
+		}
+	}
+	return profile
+}
+
+func TestValidateSolarProfile(t *testing.T) {
+	invalidFraction := daytimeProfile(0.5)
+	invalidFraction[5][12] = 1.5
+	missingHour := daytimeProfile(0.5)
+	missingHour[0] = missingHour[0][:23]
+
+	tests := []struct {
+		name    string
+		profile [][]float64
+		wantErr bool
+	}{
+		{name: "empty", profile: nil},
+		{name: "valid", profile: daytimeProfile(0.5)},
+		{name: "missing month", profile: daytimeProfile(0.5)[:11], wantErr: true},
+		{name: "missing hour", profile: missingHour, wantErr: true},
+		{name: "fraction above 1", profile: invalidFraction, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSolarProfile(tt.profile); (err != nil) != tt.wantErr {
+				t.Errorf("validateSolarProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildMPCForecast_WeatherUnavailableUsesFallbackProfile(t *testing.T) {
+	marketData, dayStart := spikeDay(t, 50, 300, 23)
+	now := dayStart.Add(6 * time.Hour)
+
+	weatherServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer weatherServer.Close()
+
+	tests := []struct {
+		name          string
+		profile       [][]float64
+		expectedSolar float64
+		expectedFirst float64
+		expectedLog   string
+	}{
+		{name: "fallback profile", profile: daytimeProfile(0.5), expectedSolar: 5, expectedFirst: 1.2, expectedLog: "using the fallback solar profile"},
+		{name: "no profile", profile: nil, expectedSolar: 0, expectedFirst: 0, expectedLog: "using zero solar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			config.CheckPriceInterval = time.Hour
+			config.UserAgent = "test-agent"
+			config.MaxSolarPower = 10
+			config.SolarFallbackProfile = tt.profile
+			scheduler := newTestScheduler(config)
+			var buf bytes.Buffer
+			scheduler.logger = log.New(&buf, "", 0)
+			scheduler.setClock(&simulatedClock{now: now})
+			scheduler.weatherBaseURL = weatherServer.URL
+			scheduler.mu.Lock()
+			scheduler.pricesMarketData = marketData
+			scheduler.pricesMarketDataExpiry = dayStart.AddDate(0, 0, 1)
+			scheduler.mu.Unlock()
+
+			plantInfo := &sigenergy.PlantRunningInfo{PhotovoltaicPower: 1.2}
+			forecast, err := scheduler.buildMPCForecast(context.Background(), config, plantInfo, 0)
+			if err != nil {
+				t.Fatalf("buildMPCForecast failed: %v", err)
+			}
+			if !strings.Contains(buf.String(), tt.expectedLog) {
+				t.Errorf("Expected %q in the log, got:\n%s", tt.expectedLog, buf.String())
+			}
+			if len(forecast) == 0 || forecast[0].SolarForecast != tt.expectedFirst {
+				t.Fatalf("Expected solar %.2f kW in the first slot, got %+v", tt.expectedFirst, forecast)
+			}
+
+			// Daytime hours follow the profile in local time, the night stays dark
+			for _, slot := range forecast[1:] {
+				hour := time.Unix(slot.Timestamp, 0).In(dayStart.Location()).Hour()
+				expected := 0.0
+				if hour >= 8 && hour < 18 {
+					expected = tt.expectedSolar
+				}
+				if slot.SolarForecast != expected {
+					t.Errorf("Expected solar %.2f kW at %02d:00, got %.2f", expected, hour, slot.SolarForecast)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/devskill-org/ems/meteo"
+)
+
+// thunderProtection is the protective action taken for miners when thunder is forecast
+type thunderProtection int
+
+const (
+	thunderNone     thunderProtection = iota // No thunder expected
+	thunderThrottle                          // Miners are limited to eco mode
+	thunderStandby                           // Miners are put into standby
+)
+
+// maxThunderProbability returns the highest probability of thunder in % forecast for the period
+// from now until now+lookahead. A time step describes the hour after its time, so the step
+// that started within the last hour is included.
+func maxThunderProbability(forecast *meteo.METJSONForecast, now time.Time, lookahead time.Duration) float64 {
+	if forecast == nil || forecast.Properties == nil {
+		return 0
+	}
+
+	start := now.Add(-time.Hour)
+	end := now.Add(lookahead)
+	maxProbability := 0.0
+	for i := range forecast.Properties.Timeseries {
+		step := &forecast.Properties.Timeseries[i]
+		if !step.Time.After(start) || !step.Time.Before(end) {
+			continue
+		}
+		if probability := step.GetProbabilityOfThunder(); probability != nil && *probability > maxProbability {
+			maxProbability = *probability
+		}
+	}
+	return maxProbability
+}
+
+// thunderProtectionLevel returns the protective action for the probability of thunder in the cached
+// weather forecast together with that probability. The forecast is never fetched from here, so the
+// miner control loop does not block on the weather API; without a cached forecast no action is taken.
+func (s *MinerScheduler) thunderProtectionLevel(now time.Time) (thunderProtection, float64) {
+	config := s.GetConfig()
+	if config.ThunderThrottleProbability <= 0 && config.ThunderStandbyProbability <= 0 {
+		return thunderNone, 0
+	}
+
+	forecast, ok := s.weatherCache.Get()
+	if !ok {
+		return thunderNone, 0
+	}
+
+	probability := maxThunderProbability(forecast, now, config.ThunderLookahead)
+	if config.ThunderStandbyProbability > 0 && probability >= config.ThunderStandbyProbability {
+		return thunderStandby, probability
+	}
+	if config.ThunderThrottleProbability > 0 && probability >= config.ThunderThrottleProbability {
+		return thunderThrottle, probability
+	}
+	return thunderNone, probability
+}
@@ -0,0 +1,180 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/meteo"
+	"github.com/devskill-org/ems/miners"
+)
+
+// thunderForecast builds an hourly forecast starting at start with the given probabilities of thunder
+func thunderForecast(start time.Time, probabilities ...float64) *meteo.METJSONForecast {
+	timeseries := make([]meteo.ForecastTimeStep, len(probabilities))
+	for i, probability := range probabilities {
+		timeseries[i] = meteo.ForecastTimeStep{
+			Time: start.Add(time.Duration(i) * time.Hour),
+			Data: &meteo.ForecastTimeStepData{
+				Next1Hours: &meteo.ForecastPeriodData{
+					Details: &meteo.ForecastTimePeriod{ProbabilityOfThunder: meteo.Float64Ptr(probability)},
+				},
+			},
+		}
+	}
+	return &meteo.METJSONForecast{Properties: &meteo.Forecast{Timeseries: timeseries}}
+}
+
+func TestMaxThunderProbability(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 30, 0, 0, time.UTC)
+	forecast := thunderForecast(time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC), 90, 5, 20, 60, 10, 95)
+
+	tests := []struct {
+		name      string
+		forecast  *meteo.METJSONForecast
+		lookahead time.Duration
+		expected  float64
+	}{
+		{
+			name:      "nil forecast",
+			forecast:  nil,
+			lookahead: 2 * time.Hour,
+			expected:  0,
+		},
+		{
+			name:      "current hour only, past steps are ignored",
+			forecast:  forecast,
+			lookahead: 30 * time.Minute,
+			expected:  20,
+		},
+		{
+			name:      "storm within lookahead",
+			forecast:  forecast,
+			lookahead: 2 * time.Hour,
+			expected:  60,
+		},
+		{
+			name:      "longer lookahead reaches later storm",
+			forecast:  forecast,
+			lookahead: 3 * time.Hour,
+			expected:  95,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := maxThunderProbability(tt.forecast, now, tt.lookahead); result != tt.expected {
+				t.Errorf("Expected probability %.0f, got %.0f", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestControlMiner_ThunderProtection(t *testing.T) {
+	tests := []struct {
+		name              string
+		probability       float64
+		fanR              int
+		workMode          miners.AvalonWorkMode
+		history           []int
+		expectedState     miners.AvalonState
+		expectedWorkMode  miners.AvalonWorkMode
+		expectedReason    MinerControlReason
+		disableProtection bool
+	}{
+		{
+			name:             "low probability keeps normal control",
+			probability:      10,
+			fanR:             60,
+			workMode:         miners.AvalonStandardMode,
+			expectedState:    miners.AvalonStateMining,
+			expectedWorkMode: miners.AvalonStandardMode,
+			expectedReason:   ReasonNoChange,
+		},
+		{
+			name:             "throttle probability limits to eco mode",
+			probability:      50,
+			fanR:             60,
+			workMode:         miners.AvalonSuperMode,
+			expectedState:    miners.AvalonStateMining,
+			expectedWorkMode: miners.AvalonEcoMode,
+			expectedReason:   ReasonThunderThrottle,
+		},
+		{
+			name:             "throttle probability blocks work mode increase",
+			probability:      50,
+			fanR:             30,
+			workMode:         miners.AvalonEcoMode,
+			history:          []int{30, 30, 30, 30, 30},
+			expectedState:    miners.AvalonStateMining,
+			expectedWorkMode: miners.AvalonEcoMode,
+			expectedReason:   ReasonThunderThrottle,
+		},
+		{
+			name:             "standby probability puts miner into standby",
+			probability:      90,
+			fanR:             60,
+			workMode:         miners.AvalonStandardMode,
+			expectedState:    miners.AvalonStateStandBy,
+			expectedWorkMode: miners.AvalonEcoMode,
+			expectedReason:   ReasonThunderStandby,
+		},
+		{
+			name:              "disabled protection ignores thunder",
+			probability:       90,
+			fanR:              60,
+			workMode:          miners.AvalonStandardMode,
+			expectedState:     miners.AvalonStateMining,
+			expectedWorkMode:  miners.AvalonStandardMode,
+			expectedReason:    ReasonNoChange,
+			disableProtection: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				FanRHighThreshold:          80,
+				FanRLowThreshold:           50,
+				MinerPowerStandby:          0.1,
+				MinerPowerEco:              1.0,
+				MinerPowerStandard:         1.5,
+				MinerPowerSuper:            2.0,
+				MinersPowerLimit:           10.0,
+				ThunderThrottleProbability: 40,
+				ThunderStandbyProbability:  80,
+				ThunderLookahead:           2 * time.Hour,
+			}
+			if tt.disableProtection {
+				config.ThunderThrottleProbability = 0
+				config.ThunderStandbyProbability = 0
+			}
+			scheduler := newTestScheduler(config)
+			scheduler.weatherCache.Set(thunderForecast(time.Now().Truncate(time.Hour), 0, tt.probability))
+
+			miner := newTestMiner(tt.fanR, tt.workMode, miners.AvalonStateMining, tt.history)
+			decision := scheduler.controlMiner(miner, 3.0, 10.0)
+
+			if decision.State != tt.expectedState {
+				t.Errorf("Expected state %s, got %s", tt.expectedState.String(), decision.State.String())
+			}
+			if decision.WorkMode != tt.expectedWorkMode {
+				t.Errorf("Expected work mode %d, got %d", tt.expectedWorkMode, decision.WorkMode)
+			}
+			if decision.Reason != tt.expectedReason {
+				t.Errorf("Expected reason %s, got %s", tt.expectedReason, decision.Reason)
+			}
+		})
+	}
+}
+
+func TestThunderProtectionLevel_NoCachedForecast(t *testing.T) {
+	config := testConfig()
+	config.ThunderThrottleProbability = 40
+	config.ThunderStandbyProbability = 80
+	config.ThunderLookahead = 2 * time.Hour
+	scheduler := newTestScheduler(config)
+
+	if protection, _ := scheduler.thunderProtectionLevel(time.Now()); protection != thunderNone {
+		t.Errorf("Expected no protection without a cached forecast, got %d", protection)
+	}
+}
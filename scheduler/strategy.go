@@ -0,0 +1,34 @@
+package scheduler
+
+import "fmt"
+
+// Control strategies reported as the active strategy in the status
+const (
+	StrategyMPC             = "mpc"              // The battery follows the MPC plan, miners the price limit
+	StrategySelfConsumption = "self_consumption" // Arbitrage skipped on flat prices, the plant keeps maximum self-consumption
+	StrategyPriceRule       = "price_rule"       // No plant connection, miners follow the price limit alone
+	StrategySafeMode        = "safe_mode"        // Data sources unreachable, miners are kept in standby
+)
+
+// activeStrategy returns the control strategy in effect and why. s.mu must be held.
+func (s *MinerScheduler) activeStrategy() (string, string) {
+	strategy, reason := s.currentStrategy()
+	if s.config.DryRun {
+		reason += " (dry run, actions are only logged)"
+	}
+	return strategy, reason
+}
+
+// currentStrategy returns the control strategy in effect and why, regardless of dry run. s.mu must be held.
+func (s *MinerScheduler) currentStrategy() (string, string) {
+	if s.safeModeActive {
+		return StrategySafeMode, fmt.Sprintf("%d consecutive full-cycle failures of the data sources", s.consecutiveCycleFailures)
+	}
+	if s.plantInfoFunc == nil && s.config.PlantModbusAddress == "" {
+		return StrategyPriceRule, "plant_modbus_address not configured, MPC is disabled"
+	}
+	if s.selfConsumption {
+		return StrategySelfConsumption, "price spread below the arbitrage break-even spread"
+	}
+	return StrategyMPC, "battery and grid follow the MPC plan"
+}
@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/devskill-org/ems/sigenergy"
+)
+
+func TestGetStatus_ActiveStrategy(t *testing.T) {
+	withPlant := func(s *MinerScheduler) {
+		s.plantInfoFunc = func(_ *Config) (*sigenergy.PlantRunningInfo, error) {
+			return &sigenergy.PlantRunningInfo{ESSSOC: 50}, nil
+		}
+	}
+
+	tests := []struct {
+		name             string
+		dryRun           bool
+		setup            func(s *MinerScheduler)
+		expectedStrategy string
+		expectedReason   string
+	}{
+		{
+			name:             "MPC disabled without a plant",
+			setup:            func(s *MinerScheduler) {},
+			expectedStrategy: StrategyPriceRule,
+			expectedReason:   "MPC is disabled",
+		},
+		{
+			name:             "MPC with a plant",
+			setup:            withPlant,
+			expectedStrategy: StrategyMPC,
+			expectedReason:   "MPC plan",
+		},
+		{
+			name: "safe mode engaged",
+			setup: func(s *MinerScheduler) {
+				withPlant(s)
+				s.updateSafeMode(context.Background(), true)
+			},
+			expectedStrategy: StrategySafeMode,
+			expectedReason:   "1 consecutive full-cycle failures",
+		},
+		{
+			name: "safe mode exited",
+			setup: func(s *MinerScheduler) {
+				withPlant(s)
+				s.updateSafeMode(context.Background(), true)
+				s.updateSafeMode(context.Background(), false)
+			},
+			expectedStrategy: StrategyMPC,
+			expectedReason:   "MPC plan",
+		},
+		{
+			name:             "dry run",
+			dryRun:           true,
+			setup:            withPlant,
+			expectedStrategy: StrategyMPC,
+			expectedReason:   "dry run",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			config.DryRun = tt.dryRun
+			config.SafeModeFailureThreshold = 1
+			scheduler := newTestScheduler(config)
+			tt.setup(scheduler)
+
+			status := scheduler.GetStatus()
+			if status.ActiveStrategy != tt.expectedStrategy {
+				t.Errorf("Expected active strategy %s, got %s (%s)", tt.expectedStrategy, status.ActiveStrategy, status.StrategyReason)
+			}
+			if !strings.Contains(status.StrategyReason, tt.expectedReason) {
+				t.Errorf("Expected the reason to mention %q, got %q", tt.expectedReason, status.StrategyReason)
+			}
+			if tt.dryRun != strings.Contains(status.StrategyReason, "dry run") {
+				t.Errorf("Expected dry run in the reason %v, got %q", tt.dryRun, status.StrategyReason)
+			}
+		})
+	}
+}
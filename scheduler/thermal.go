@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"sync"
+
+	"github.com/devskill-org/ems/miners"
+)
+
+// fanRModelSmoothing is the weight of a new FanR sample in the per-mode moving average
+const fanRModelSmoothing = 0.3
+
+// FanRModel predicts a miner's fan speed (FanR) for each work mode.
+// It keeps an exponential moving average of observed FanR per work mode,
+// learned from the samples collected during state checks.
+type FanRModel struct {
+	mu          sync.Mutex
+	avgFanR     [miners.AvalonSuperMode + 1]float64
+	samples     [miners.AvalonSuperMode + 1]int
+	defaultStep float64 // FanR % change per work mode step used until the model has learned a slope
+}
+
+// NewFanRModel creates a new FanR model with the given default FanR change per work mode step
+func NewFanRModel(defaultStep float64) *FanRModel {
+	return &FanRModel{defaultStep: defaultStep}
+}
+
+// Observe records a FanR sample for a work mode
+func (fm *FanRModel) Observe(mode miners.AvalonWorkMode, fanR int) {
+	if !validWorkMode(mode) {
+		return
+	}
+
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	if fm.samples[mode] == 0 {
+		fm.avgFanR[mode] = float64(fanR)
+	} else {
+		fm.avgFanR[mode] += fanRModelSmoothing * (float64(fanR) - fm.avgFanR[mode])
+	}
+	fm.samples[mode]++
+}
+
+// Predict estimates the FanR after switching from currentMode at currentFanR to targetMode.
+// When both modes have been observed, the learned difference between them is used.
+// Otherwise the slope learned from any two observed modes is used, falling back to the default step.
+func (fm *FanRModel) Predict(currentMode miners.AvalonWorkMode, currentFanR int, targetMode miners.AvalonWorkMode) float64 {
+	if !validWorkMode(currentMode) || !validWorkMode(targetMode) || currentMode == targetMode {
+		return float64(currentFanR)
+	}
+
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	if fm.samples[currentMode] > 0 && fm.samples[targetMode] > 0 {
+		return clampFanR(float64(currentFanR) + fm.avgFanR[targetMode] - fm.avgFanR[currentMode])
+	}
+
+	return clampFanR(float64(currentFanR) + fm.slope()*float64(targetMode-currentMode))
+}
+
+// slope returns the learned FanR change per work mode step
+func (fm *FanRModel) slope() float64 {
+	lowest, highest := -1, -1
+	for mode := range fm.samples {
+		if fm.samples[mode] == 0 {
+			continue
+		}
+		if lowest < 0 {
+			lowest = mode
+		}
+		highest = mode
+	}
+	if lowest < 0 || lowest == highest {
+		return fm.defaultStep
+	}
+	return (fm.avgFanR[highest] - fm.avgFanR[lowest]) / float64(highest-lowest)
+}
+
+// validWorkMode returns true if the mode can be used as an index into the model
+func validWorkMode(mode miners.AvalonWorkMode) bool {
+	return mode >= miners.AvalonEcoMode && mode <= miners.AvalonSuperMode
+}
+
+// clampFanR limits a FanR value to the 0-100% range
+func clampFanR(fanR float64) float64 {
+	if fanR < 0 {
+		return 0
+	}
+	if fanR > 100 {
+		return 100
+	}
+	return fanR
+}
+
+// getFanRModel returns the FanR model for a miner, creating it on first use
+func (s *MinerScheduler) getFanRModel(m *miners.AvalonQHost) *FanRModel {
+	value, _ := s.fanRModels.LoadOrStore(minerKey(m), NewFanRModel(s.config.FanRModeStep))
+	return value.(*FanRModel)
+}
+
+// targetWorkMode returns the highest work mode predicted to keep FanR at or below the configured target.
+// Returns false if even Eco mode is predicted to exceed the target.
+func (s *MinerScheduler) targetWorkMode(m *miners.AvalonQHost) (miners.AvalonWorkMode, bool) {
+	model := s.getFanRModel(m)
+	currentMode := miners.AvalonWorkMode(m.LastStats.WorkMode)
+	for mode := miners.AvalonSuperMode; mode >= miners.AvalonEcoMode; mode-- {
+		if model.Predict(currentMode, m.LastStats.FanR, mode) <= float64(s.config.FanRTarget) {
+			return mode, true
+		}
+	}
+	return miners.AvalonEcoMode, false
+}
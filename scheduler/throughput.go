@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/devskill-org/ems/mpc"
+)
+
+// throughputTracker integrates the battery charge plus discharge energy of the current local day from the ESS
+// power polled from the plant, to enforce max_daily_throughput_kwh
+type throughputTracker struct {
+	mu        sync.Mutex
+	day       time.Time // local midnight starting the day used is counted for
+	used      float64   // kWh charged plus discharged since day
+	lastAt    time.Time
+	lastPower float64 // kW, absolute ESS power at lastAt
+}
+
+// localDay returns the local midnight starting the day of t
+func localDay(t time.Time, location *time.Location) time.Time {
+	t = t.In(location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+}
+
+// observe records the ESS power at now and adds the energy since the previous sample, drawn at the power of that
+// sample. The count restarts at local midnight. Gaps longer than maxGap, e.g. while the plant was unreachable,
+// are not counted.
+func (t *throughputTracker) observe(now time.Time, essPower float64, location *time.Location, maxGap time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	day := localDay(now, location)
+	if !day.Equal(t.day) {
+		t.day = day
+		t.used = 0
+	}
+	if elapsed := now.Sub(t.lastAt); !t.lastAt.IsZero() && elapsed > 0 && elapsed <= maxGap {
+		// Only the part of the interval after midnight belongs to a new day
+		if t.lastAt.Before(day) {
+			elapsed = now.Sub(day)
+		}
+		t.used += t.lastPower * elapsed.Hours()
+	}
+	t.lastAt = now
+	t.lastPower = math.Abs(essPower)
+}
+
+// restore seeds the throughput counted on the local day of now, e.g. from the stored metrics after a restart.
+// A higher count already observed for the day is kept.
+func (t *throughputTracker) restore(now time.Time, used float64, location *time.Location) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	day := localDay(now, location)
+	if !day.Equal(t.day) {
+		t.day = day
+		t.used = 0
+	}
+	t.used = max(t.used, used)
+}
+
+// usedOn returns the throughput counted on the local day of now in kWh, 0 before the first sample of the day
+func (t *throughputTracker) usedOn(now time.Time, location *time.Location) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !localDay(now, location).Equal(t.day) {
+		return 0
+	}
+	return t.used
+}
+
+// throughputLocation returns the location whose days max_daily_throughput_kwh applies to
+func throughputLocation(config *Config) *time.Location {
+	location, err := time.LoadLocation(config.Location)
+	if err != nil {
+		return time.Local
+	}
+	return location
+}
+
+// observeThroughput counts the battery throughput from the ESS power of a plant poll when
+// max_daily_throughput_kwh is set
+func (s *MinerScheduler) observeThroughput(config *Config, essPower float64) {
+	if config.MaxDailyThroughputKWh <= 0 {
+		return
+	}
+	s.throughput.observe(s.now(), essPower, throughputLocation(config), 3*config.PVPollInterval)
+}
+
+// GetDailyThroughput returns the battery charge plus discharge energy of the current local day in kWh
+func (s *MinerScheduler) GetDailyThroughput() float64 {
+	config := s.GetConfig()
+	return s.throughput.usedOn(s.now(), throughputLocation(config))
+}
+
+// throughputBudget returns the battery throughput the MPC plan may use over the forecast horizon in kWh: what is
+// left of today's max_daily_throughput_kwh plus a pro-rata share of the limit for the hours after midnight.
+// For a horizon spanning midnight it also returns what is left of today and the midnight, the slots before
+// it must stay within that, so tomorrow's share is not spent today. The budget is 0 (unlimited) when the
+// limit is not set.
+func (s *MinerScheduler) throughputBudget(config *Config, forecast []mpc.TimeSlot) (budget, today float64, midnight time.Time) {
+	limit := config.MaxDailyThroughputKWh
+	if limit <= 0 || len(forecast) == 0 {
+		return 0, 0, time.Time{}
+	}
+	location := throughputLocation(config)
+	now := s.now()
+	left := max(0, limit-s.throughput.usedOn(now, location))
+	budget = left
+
+	// The horizon ends one slot after the last forecast slot
+	slotSeconds := int64(3600)
+	if len(forecast) > 1 && forecast[1].Timestamp > forecast[0].Timestamp {
+		slotSeconds = forecast[1].Timestamp - forecast[0].Timestamp
+	}
+	end := time.Unix(forecast[len(forecast)-1].Timestamp+slotSeconds, 0)
+	nextDay := localDay(now, location).AddDate(0, 0, 1)
+	if after := end.Sub(nextDay); after > 0 {
+		budget += limit * after.Hours() / 24
+		today, midnight = left, nextDay
+	}
+	// A budget of exactly 0 would mean unlimited to the optimizer, keep it just above
+	return max(budget, 1e-6), today, midnight
+}
+
+// selectBatteryThroughputSQL sums the battery charge and discharge energy of the energy flow rows of the
+// integration periods ending within a time range
+const selectBatteryThroughputSQL = `
+	SELECT COALESCE(SUM(COALESCE(battery_charge_power, 0) + COALESCE(battery_discharge_power, 0)), 0)
+	FROM metrics
+	WHERE metric_name = $1 AND timestamp > $2 AND timestamp <= $3`
+
+// restoreDailyThroughput seeds today's battery throughput from the energy flow metrics stored before a restart,
+// so a restart does not grant a second max_daily_throughput_kwh. The part of the day not integrated yet when
+// the scheduler stopped is not counted.
+func (s *MinerScheduler) restoreDailyThroughput(ctx context.Context, db *sql.DB) error {
+	config := s.GetConfig()
+	if config.MaxDailyThroughputKWh <= 0 {
+		return nil
+	}
+	location := throughputLocation(config)
+	now := s.now()
+
+	var used float64
+	if err := db.QueryRowContext(ctx, selectBatteryThroughputSQL, metricEnergyFlow, localDay(now, location), now).Scan(&used); err != nil {
+		return fmt.Errorf("failed to query battery throughput: %w", err)
+	}
+	s.throughput.restore(now, used, location)
+	s.logger.Printf("Restored battery throughput of %.1f kWh today from the stored metrics", used)
+	return nil
+}
+
+// dailyThroughputReached returns the throughput of the current local day and whether it reached
+// max_daily_throughput_kwh
+func (s *MinerScheduler) dailyThroughputReached(config *Config) (float64, bool) {
+	if config.MaxDailyThroughputKWh <= 0 {
+		return 0, false
+	}
+	used := s.throughput.usedOn(s.now(), throughputLocation(config))
+	return used, used >= config.MaxDailyThroughputKWh
+}
@@ -0,0 +1,171 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"log"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devskill-org/ems/mpc"
+)
+
+func TestThroughputTracker_Observe(t *testing.T) {
+	location := time.UTC
+	start := time.Date(2024, 6, 10, 22, 0, 0, 0, location)
+	var tracker throughputTracker
+
+	// Charging at 4 kW, then discharging at 2 kW, for an hour each
+	tracker.observe(start, 4, location, 2*time.Hour)
+	tracker.observe(start.Add(time.Hour), -2, location, 2*time.Hour)
+	tracker.observe(start.Add(90*time.Minute), -2, location, 2*time.Hour)
+	if used := tracker.usedOn(start, location); math.Abs(used-5) > 1e-9 {
+		t.Errorf("Expected 5 kWh, got %.2f kWh", used)
+	}
+
+	// Only the half hour after midnight counts for the new day
+	tracker.observe(start.Add(150*time.Minute), -2, location, 2*time.Hour)
+	midnight := start.Add(2 * time.Hour)
+	if used := tracker.usedOn(midnight, location); math.Abs(used-1) > 1e-9 {
+		t.Errorf("Expected 1 kWh after midnight, got %.2f kWh", used)
+	}
+	if used := tracker.usedOn(midnight.AddDate(0, 0, 1), location); used != 0 {
+		t.Errorf("Expected nothing counted for the next day yet, got %.2f kWh", used)
+	}
+
+	// A gap longer than maxGap is not counted
+	tracker.observe(start.Add(10*time.Hour), 0, location, 2*time.Hour)
+	if used := tracker.usedOn(midnight, location); math.Abs(used-1) > 1e-9 {
+		t.Errorf("Expected the gap not to be counted, got %.2f kWh", used)
+	}
+}
+
+// throughputForecast returns hourly forecast slots starting at start
+func throughputForecast(start time.Time, hours int) []mpc.TimeSlot {
+	forecast := make([]mpc.TimeSlot, hours)
+	for i := range forecast {
+		forecast[i] = mpc.TimeSlot{Hour: i, Timestamp: start.Add(time.Duration(i) * time.Hour).Unix()}
+	}
+	return forecast
+}
+
+func TestThroughputBudget(t *testing.T) {
+	config := testConfig()
+	config.Location = "UTC"
+	now := time.Date(2024, 6, 10, 18, 0, 0, 0, time.UTC)
+	scheduler := newTestScheduler(config)
+	scheduler.setClock(&simulatedClock{now: now})
+
+	if budget, _, _ := scheduler.throughputBudget(config, throughputForecast(now, 24)); budget != 0 {
+		t.Errorf("Expected no budget without a limit, got %.2f kWh", budget)
+	}
+
+	config.MaxDailyThroughputKWh = 20
+	scheduler.throughput.observe(now.Add(-2*time.Hour), 4, time.UTC, time.Hour)
+	scheduler.throughput.observe(now.Add(-time.Hour), 4, time.UTC, time.Hour)
+	scheduler.throughput.observe(now, 0, time.UTC, time.Hour)
+
+	midnight := time.Date(2024, 6, 11, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name             string
+		hours            int
+		expected         float64
+		expectedToday    float64
+		expectedMidnight time.Time
+	}{
+		// 8 kWh used today leaves 12 kWh until midnight
+		{name: "ends today", hours: 6, expected: 12},
+		// 18 hours of tomorrow add 18/24 of the limit, the slots before midnight keep within the 12 kWh left
+		{name: "spans midnight", hours: 24, expected: 12 + 15, expectedToday: 12, expectedMidnight: midnight},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget, today, split := scheduler.throughputBudget(config, throughputForecast(now, tt.hours))
+			if math.Abs(budget-tt.expected) > 1e-9 {
+				t.Errorf("Expected %.2f kWh, got %.2f kWh", tt.expected, budget)
+			}
+			if math.Abs(today-tt.expectedToday) > 1e-9 || !split.Equal(tt.expectedMidnight) {
+				t.Errorf("Expected %.2f kWh before %v, got %.2f kWh before %v", tt.expectedToday, tt.expectedMidnight, today, split)
+			}
+		})
+	}
+}
+
+func TestRestoreDailyThroughput(t *testing.T) {
+	db, rec := newRecordingDB(t)
+	rec.queryRows = [][]driver.Value{{9.5}}
+
+	config := testConfig()
+	config.Location = "UTC"
+	now := time.Date(2024, 6, 10, 18, 0, 0, 0, time.UTC)
+	scheduler := newTestScheduler(config)
+	scheduler.setClock(&simulatedClock{now: now})
+
+	// Nothing is queried without a limit
+	if err := scheduler.restoreDailyThroughput(context.Background(), db); err != nil {
+		t.Fatalf("restoreDailyThroughput failed: %v", err)
+	}
+	if len(rec.queries) != 0 {
+		t.Fatalf("Expected no query without a limit, got %+v", rec.queries)
+	}
+
+	config.MaxDailyThroughputKWh = 20
+	if err := scheduler.restoreDailyThroughput(context.Background(), db); err != nil {
+		t.Fatalf("restoreDailyThroughput failed: %v", err)
+	}
+	if len(rec.queries) != 1 || rec.queries[0].query != selectBatteryThroughputSQL {
+		t.Fatalf("Expected the throughput to be queried, got %+v", rec.queries)
+	}
+	if from := rec.queries[0].args[1]; from != time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("Expected the metrics since midnight, got %v", from)
+	}
+	if used := scheduler.GetDailyThroughput(); used != 9.5 {
+		t.Errorf("Expected 9.5 kWh restored, got %.2f kWh", used)
+	}
+
+	// Counting continues from the restored throughput
+	scheduler.throughput.observe(now, 2, time.UTC, time.Hour)
+	scheduler.throughput.observe(now.Add(time.Hour), 0, time.UTC, time.Hour)
+	if used := scheduler.throughput.usedOn(now, time.UTC); math.Abs(used-11.5) > 1e-9 {
+		t.Errorf("Expected 11.5 kWh, got %.2f kWh", used)
+	}
+}
+
+func TestExecuteMPCDecision_ThroughputLimit(t *testing.T) {
+	config := testConfig()
+	config.Location = "UTC"
+	config.MaxDailyThroughputKWh = 10
+	now := time.Date(2024, 6, 10, 18, 0, 0, 0, time.UTC)
+	scheduler := newTestScheduler(config)
+	scheduler.setClock(&simulatedClock{now: now})
+	var buf bytes.Buffer
+	scheduler.logger = log.New(&buf, "", 0)
+
+	decision := &mpc.ControlDecision{BatteryDischarge: 5, BatteryCharge: 0, GridExport: 5}
+	if err := scheduler.executeMPCDecision(decision, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Discharge: 5.0 kW") || strings.Contains(buf.String(), "Warning") {
+		t.Errorf("Expected the discharge to execute below the limit, got %q", buf.String())
+	}
+
+	// 12 kWh discharged today
+	scheduler.throughput.observe(now.Add(-3*time.Hour), -4, time.UTC, 2*time.Hour)
+	scheduler.throughput.observe(now.Add(-2*time.Hour), -4, time.UTC, 2*time.Hour)
+	scheduler.throughput.observe(now.Add(-time.Hour), -4, time.UTC, 2*time.Hour)
+	scheduler.throughput.observe(now, 0, time.UTC, 2*time.Hour)
+
+	buf.Reset()
+	if err := scheduler.executeMPCDecision(decision, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Warning: Battery throughput 12.0 kWh") || !strings.Contains(buf.String(), "Discharge: 0.0 kW") {
+		t.Errorf("Expected the battery kept idle at the limit, got %q", buf.String())
+	}
+	if decision.BatteryDischarge != 5 {
+		t.Errorf("Expected the planned decision left unchanged, got %.1f kW discharge", decision.BatteryDischarge)
+	}
+}
@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// watchdogCheckInterval is how often the watchdog looks for stuck tasks
+const watchdogCheckInterval = 30 * time.Second
+
+// taskState tracks the runs of a periodic task for the watchdog and the status endpoint
+type taskState struct {
+	mu          sync.Mutex
+	runStarted  time.Time          // start of the run in progress, zero when the task is idle
+	cancelRun   context.CancelFunc // cancels the context of the run in progress, nil when the task is idle
+	lastRun     time.Time          // end of the last completed run
+	lastSuccess time.Time          // end of the last run that returned no error
+	stuck       bool               // the run in progress exceeded the watchdog timeout
+}
+
+// TaskStatus represents the run times of a periodic task
+type TaskStatus struct {
+	LastRun     *time.Time `json:"last_run,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	Running     bool       `json:"running"`
+	Stuck       bool       `json:"stuck"`
+}
+
+// begin records the start of a run and the function cancelling its context
+func (ts *taskState) begin(now time.Time, cancel context.CancelFunc) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.runStarted = now
+	ts.cancelRun = cancel
+}
+
+// finish records the end of a run, a stuck task is healthy again once its run returns
+func (ts *taskState) finish(now time.Time, err error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.runStarted = time.Time{}
+	ts.cancelRun = nil
+	ts.stuck = false
+	ts.lastRun = now
+	if err == nil {
+		ts.lastSuccess = now
+	}
+}
+
+// markIfStuck marks the task stuck and cancels the context of the run in progress when it has been running for
+// longer than timeout. It returns how long the run has been running and whether it has just been marked, so a hung
+// run is reported once.
+func (ts *taskState) markIfStuck(now time.Time, timeout time.Duration) (time.Duration, bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.runStarted.IsZero() || ts.stuck {
+		return 0, false
+	}
+	running := now.Sub(ts.runStarted)
+	if running <= timeout {
+		return running, false
+	}
+	ts.stuck = true
+	if ts.cancelRun != nil {
+		ts.cancelRun()
+	}
+	return running, true
+}
+
+// status returns a snapshot of the task's run times
+func (ts *taskState) status() TaskStatus {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	status := TaskStatus{
+		Running: !ts.runStarted.IsZero(),
+		Stuck:   ts.stuck,
+	}
+	if !ts.lastRun.IsZero() {
+		lastRun := ts.lastRun
+		status.LastRun = &lastRun
+	}
+	if !ts.lastSuccess.IsZero() {
+		lastSuccess := ts.lastSuccess
+		status.LastSuccess = &lastSuccess
+	}
+	return status
+}
+
+// checkStuckTasks alerts on every task whose run has not completed within multiplier times its interval,
+// cancels the context of the run and marks the task stuck until the run returns. A run blocked on a call
+// without context, e.g. a Modbus read, returns only once the call does. A second run next to it would share
+// the plant and miner state, so the task is not restarted: its ticker holds a missed tick and the next run
+// starts as soon as the hung one returns. Returns the names of the tasks found stuck by this check.
+func (s *MinerScheduler) checkStuckTasks(now time.Time, tasks []PeriodicTask, multiplier float64) []string {
+	var stuck []string
+	for _, task := range tasks {
+		timeout := time.Duration(multiplier * float64(task.interval))
+		running, marked := task.state.markIfStuck(now, timeout)
+		if !marked {
+			continue
+		}
+		s.logger.Printf("ALERT: Watchdog: task %s has been running for %v, longer than %v, it looks hung, cancelling it",
+			task.name, running.Round(time.Second), timeout)
+		stuck = append(stuck, task.name)
+	}
+	return stuck
+}
+
+// runWatchdog periodically checks the tasks for stuck runs until the context is cancelled or the scheduler stops
+func (s *MinerScheduler) runWatchdog(ctx context.Context, stopChan <-chan struct{}, tasks []PeriodicTask, multiplier float64) {
+	ticker := time.NewTicker(watchdogCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			s.checkStuckTasks(now, tasks, multiplier)
+		case <-ctx.Done():
+			return
+		case <-stopChan:
+			return
+		}
+	}
+}
+
+// getTaskStatuses returns the run times of all periodic tasks keyed by task name
+func (s *MinerScheduler) getTaskStatuses() map[string]TaskStatus {
+	if len(s.taskStates) == 0 {
+		return nil
+	}
+	statuses := make(map[string]TaskStatus, len(s.taskStates))
+	for name, state := range s.taskStates {
+		statuses[name] = state.status()
+	}
+	return statuses
+}
@@ -0,0 +1,136 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatchdog_ReportsHungTask(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.New(&buf, "", 0)
+	scheduler := NewMinerScheduler(testConfig(), logger)
+
+	var calls atomic.Int32
+	task := PeriodicTask{
+		name:     "Hung",
+		interval: time.Minute,
+		state:    &taskState{},
+		runFunc: func(ctx context.Context) error {
+			calls.Add(1)
+			// Simulate a request that only returns when its context is cancelled
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopChan := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		task.run(ctx, stopChan, logger)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for !task.state.status().Running {
+		if time.Now().After(deadline) {
+			t.Fatal("Task did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	tasks := []PeriodicTask{task}
+
+	// Within the allowed multiple of the interval the task is left alone
+	if stuck := scheduler.checkStuckTasks(time.Now().Add(2*time.Minute), tasks, 3); len(stuck) != 0 {
+		t.Fatalf("Expected no stuck task within 3 intervals, got %v", stuck)
+	}
+
+	// Past it the task is reported once, marked stuck and its run cancelled
+	if stuck := scheduler.checkStuckTasks(time.Now().Add(4*time.Minute), tasks, 3); !slices.Equal(stuck, []string{"Hung"}) {
+		t.Fatalf("Expected Hung to be stuck, got %v", stuck)
+	}
+	if stuck := scheduler.checkStuckTasks(time.Now().Add(5*time.Minute), tasks, 3); len(stuck) != 0 {
+		t.Errorf("Expected the hung run reported once, got %v", stuck)
+	}
+
+	// The cancelled run returns and the task is healthy again, the next run keeps to the schedule
+	deadline = time.Now().Add(time.Second)
+	for {
+		status := task.state.status()
+		if status.LastRun != nil && !status.Running {
+			if status.Stuck || status.LastSuccess != nil {
+				t.Errorf("Expected the cancelled run to fail and the task no longer stuck, got %+v", status)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the hung run to complete, got %+v", status)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	close(stopChan)
+	cancel()
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected no second run next to the hung one, got %d runs", got)
+	}
+	if output := buf.String(); !strings.Contains(output, "ALERT: Watchdog: task Hung") {
+		t.Errorf("Expected watchdog alert in log, got: %s", output)
+	}
+}
+
+func TestTaskState_IdleTaskIsNotStuck(t *testing.T) {
+	state := &taskState{}
+	now := time.Now()
+
+	if _, stuck := state.markIfStuck(now.Add(time.Hour), time.Minute); stuck {
+		t.Error("Expected a task that never started not to be stuck")
+	}
+
+	state.begin(now, nil)
+	state.finish(now.Add(time.Second), nil)
+	if _, stuck := state.markIfStuck(now.Add(time.Hour), time.Minute); stuck {
+		t.Error("Expected a completed task not to be stuck")
+	}
+
+	status := state.status()
+	if status.Running || status.LastRun == nil || status.LastSuccess == nil {
+		t.Errorf("Expected completed successful run in status, got %+v", status)
+	}
+}
+
+func TestGetStatus_TaskStatuses(t *testing.T) {
+	scheduler := NewMinerScheduler(testConfig(), nil)
+	if tasks := scheduler.GetStatus().Tasks; tasks != nil {
+		t.Errorf("Expected no task statuses before start, got %v", tasks)
+	}
+
+	now := time.Now()
+	state := &taskState{}
+	state.begin(now, nil)
+	state.finish(now, errors.New("price API unavailable"))
+	scheduler.taskStates = map[string]*taskState{"PriceCheck": state}
+
+	status, ok := scheduler.GetStatus().Tasks["PriceCheck"]
+	if !ok {
+		t.Fatal("Expected PriceCheck task status")
+	}
+	if status.LastRun == nil || !status.LastRun.Equal(now) {
+		t.Errorf("Expected last run %v, got %v", now, status.LastRun)
+	}
+	if status.LastSuccess != nil {
+		t.Errorf("Expected no successful run, got %v", status.LastSuccess)
+	}
+}
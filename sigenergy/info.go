@@ -2,6 +2,7 @@
 package sigenergy
 
 import (
+	"errors"
 	"fmt"
 	"time"
 )
@@ -19,9 +20,12 @@ func ShowPlantInfo(plantModbusAddress string) error {
 	}
 	defer client.Close()
 
-	// Read plant running info
+	// Read plant running info, a missing secondary block leaves its fields at 0
 	info, err := client.ReadPlantRunningInfo()
-	if err != nil {
+	var partialErr *PartialReadError
+	if errors.As(err, &partialErr) {
+		fmt.Printf("Warning: %v\n", partialErr)
+	} else if err != nil {
 		return fmt.Errorf("error reading plant information: %w", err)
 	}
 
@@ -38,7 +42,7 @@ func ShowPlantInfo(plantModbusAddress string) error {
 	fmt.Printf("  System Timezone:                %d minutes\n", info.SystemTimeZone)
 	fmt.Printf("  EMS Work Mode:                  %s\n", getEMSWorkMode(info.EMSWorkMode))
 	fmt.Printf("  On/Off Grid Status:             %s\n", getOnOffGridStatus(info.OnOffGridStatus))
-	fmt.Printf("  Plant Running State:            %d\n", info.PlantRunningState)
+	fmt.Printf("  Plant Running State:            %s\n", info.PlantRunningState)
 	fmt.Println()
 
 	// Grid Sensor Information
@@ -46,9 +46,18 @@ func NewRTUClient(device string, baudRate int, slaveID byte) (*SigenModbusClient
 
 // NewTCPClient creates a new Sigenergy Modbus TCP client
 func NewTCPClient(address string, slaveID byte) (*SigenModbusClient, error) {
+	return NewTCPClientWithTimeout(address, slaveID, 0)
+}
+
+// NewTCPClientWithTimeout creates a new Sigenergy Modbus TCP client with the given connect and
+// response timeout, 0 uses the default of 1 second
+func NewTCPClientWithTimeout(address string, slaveID byte, timeout time.Duration) (*SigenModbusClient, error) {
+	if timeout <= 0 {
+		timeout = 1 * time.Second
+	}
 	handler := modbus.NewTCPClientHandler(address)
 	handler.SlaveId = slaveID
-	handler.Timeout = 1 * time.Second
+	handler.Timeout = timeout
 
 	err := handler.Connect()
 	if err != nil {
@@ -82,6 +91,35 @@ func (c *SigenModbusClient) SetSlaveID(slaveID byte) {
 	}
 }
 
+// readInputRegisters reads quantity input registers starting at address and checks the response holds exactly
+// those registers, so a short read is an error instead of a slice out of range
+func (c *SigenModbusClient) readInputRegisters(address, quantity uint16) ([]byte, error) {
+	data, err := c.client.ReadInputRegisters(address, quantity)
+	if err != nil {
+		return nil, err
+	}
+	if expected := int(quantity) * 2; len(data) != expected {
+		return nil, fmt.Errorf("read of input registers %d-%d returned %d bytes, expected %d",
+			address, address+quantity-1, len(data), expected)
+	}
+	return data, nil
+}
+
+// PartialReadError is returned with a valid PlantRunningInfo when a secondary register block could not be read,
+// the fields of that block are left at 0
+type PartialReadError struct {
+	Block string // registers of the block, e.g. "30083-30087"
+	Err   error
+}
+
+func (e *PartialReadError) Error() string {
+	return fmt.Sprintf("partial plant running info, registers %s not read: %v", e.Block, e.Err)
+}
+
+func (e *PartialReadError) Unwrap() error {
+	return e.Err
+}
+
 // Helper functions for data conversion
 func bytesToU16(data []byte) uint16 {
 	return binary.BigEndian.Uint16(data)
@@ -148,7 +186,7 @@ type PlantRunningInfo struct {
 	AvailableMinReactivePower       float64 // kVar
 	ESSAvailableMaxChargingPower    float64 // kW
 	ESSAvailableMaxDischargingPower float64 // kW
-	PlantRunningState               uint16
+	PlantRunningState               RunningState
 	ESSRatedEnergyCapacity          float64 // kWh
 	ESSChargeOffSOC                 float64 // %
 	ESSDischargeOffSOC              float64 // %
@@ -158,12 +196,27 @@ type PlantRunningInfo struct {
 	DCChargerVehicleSOC             float64 // %
 }
 
-// ReadPlantRunningInfo reads plant running information (slave address 247)
+// ESSSOCCutoffs returns the discharge-off and charge-off SOC as fractions (0-1): the ESS stops discharging
+// at minSOC and stops charging at maxSOC. Cutoffs the plant did not report (0) are returned as 0 and 1.
+func (info *PlantRunningInfo) ESSSOCCutoffs() (minSOC, maxSOC float64) {
+	minSOC, maxSOC = 0, 1
+	if info.ESSDischargeOffSOC > 0 {
+		minSOC = info.ESSDischargeOffSOC / 100.0
+	}
+	if info.ESSChargeOffSOC > 0 {
+		maxSOC = info.ESSChargeOffSOC / 100.0
+	}
+	return minSOC, maxSOC
+}
+
+// ReadPlantRunningInfo reads plant running information (slave address 247). When the additional ESS block cannot
+// be read the info is returned together with a *PartialReadError. The DC charger and the inverter cell temperature
+// are optional hardware, their fields are left at 0 when they are missing.
 func (c *SigenModbusClient) ReadPlantRunningInfo() (*PlantRunningInfo, error) {
 	c.SetSlaveID(PlantAddress)
 
 	// Read main block (30000-30051, 52 registers)
-	data, err := c.client.ReadInputRegisters(30000, 52)
+	data, err := c.readInputRegisters(30000, 52)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read plant running info: %v", err)
 	}
@@ -199,20 +252,23 @@ func (c *SigenModbusClient) ReadPlantRunningInfo() (*PlantRunningInfo, error) {
 		AvailableMinReactivePower:       float64(bytesToU32(data[90:94])) / 1000.0,
 		ESSAvailableMaxChargingPower:    float64(bytesToU32(data[94:98])) / 1000.0,
 		ESSAvailableMaxDischargingPower: float64(bytesToU32(data[98:102])) / 1000.0,
-		PlantRunningState:               bytesToU16(data[102:104]),
+		PlantRunningState:               RunningState(bytesToU16(data[102:104])),
 	}
 
 	// Read additional ESS data (30083-30087)
-	data2, err := c.client.ReadInputRegisters(30083, 5)
+	var partialErr error
+	data2, err := c.readInputRegisters(30083, 5)
 	if err == nil {
 		info.ESSRatedEnergyCapacity = float64(bytesToU32(data2[0:4])) / 100.0
 		info.ESSChargeOffSOC = float64(bytesToU16(data2[4:6])) / 10.0
 		info.ESSDischargeOffSOC = float64(bytesToU16(data2[6:8])) / 10.0
 		info.ESSSOH = float64(bytesToU16(data2[8:10])) / 10.0
+	} else {
+		partialErr = &PartialReadError{Block: "30083-30087", Err: err}
 	}
 
 	// Read DC Charger data (31502-31504)
-	data3, err := c.client.ReadInputRegisters(31502, 3)
+	data3, err := c.readInputRegisters(31502, 3)
 	if err == nil {
 		info.DCChargerOutputPower = float64(bytesToS32(data3[0:4])) / 1000.0
 		info.DCChargerVehicleSOC = float64(bytesToU16(data3[4:6])) / 10.0
@@ -221,14 +277,14 @@ func (c *SigenModbusClient) ReadPlantRunningInfo() (*PlantRunningInfo, error) {
 	// Read ESS Average Cell Temperature from first inverter (slave address 1, register 30603)
 	// Note: This assumes at least one hybrid inverter is present with slave ID 1
 	c.SetSlaveID(1)
-	data4, err := c.client.ReadInputRegisters(30603, 1)
+	data4, err := c.readInputRegisters(30603, 1)
 	if err == nil {
 		info.ESSAvgCellTemperature = float64(bytesToS16(data4[0:2])) / 10.0
 	}
 	// Reset to plant address
 	c.SetSlaveID(PlantAddress)
 
-	return info, nil
+	return info, partialErr
 }
 
 // PlantParameters represents the plant parameter settings (Section 5.2)
@@ -308,6 +364,36 @@ func (c *SigenModbusClient) EnableRemoteEMS(enable bool) error {
 	return err
 }
 
+// EnableIndependentPhaseControl enables or disables independent phase power control.
+// Valid only when the output type is L1/L2/L3/N.
+func (c *SigenModbusClient) EnableIndependentPhaseControl(enable bool) error {
+	c.SetSlaveID(PlantAddress)
+	var value uint16
+	if enable {
+		value = 1
+	}
+	_, err := c.client.WriteSingleRegister(40030, value)
+	return err
+}
+
+// SetPhasePowerLimits sets fixed active power targets (kW) for phases A, B and C, so an imbalanced load
+// cannot overload a single phase. Independent phase power control is enabled first, the targets are
+// ignored by the plant without it. Valid only when the output type is L1/L2/L3/N.
+func (c *SigenModbusClient) SetPhasePowerLimits(phaseA, phaseB, phaseC float64) error {
+	if err := c.EnableIndependentPhaseControl(true); err != nil {
+		return fmt.Errorf("failed to enable independent phase control: %w", err)
+	}
+	c.SetSlaveID(PlantAddress)
+	data := make([]byte, 0, 12)
+	for _, powerKW := range []float64{phaseA, phaseB, phaseC} {
+		data = append(data, s32ToBytes(int32(powerKW*1000))...)
+	}
+	if _, err := c.client.WriteMultipleRegisters(40008, 6, data); err != nil {
+		return fmt.Errorf("failed to set phase power targets: %w", err)
+	}
+	return nil
+}
+
 // SetRemoteEMSMode sets the remote EMS control mode
 // 0: PCS remote control, 1: Standby, 2: Maximum self-consumption
 // 3: Command charging (grid first), 4: Command charging (PV first)
@@ -318,6 +404,19 @@ func (c *SigenModbusClient) SetRemoteEMSMode(mode uint16) error {
 	return err
 }
 
+// ReleaseRemoteEMS hands control back to the plant's native EMS. The remote EMS control mode is set to
+// maximum self-consumption first, so the plant is left in a safe mode even if remote EMS is enabled again
+// later, then remote EMS is disabled.
+func (c *SigenModbusClient) ReleaseRemoteEMS() error {
+	if err := c.SetRemoteEMSMode(2); err != nil {
+		return fmt.Errorf("failed to set remote EMS mode: %w", err)
+	}
+	if err := c.EnableRemoteEMS(false); err != nil {
+		return fmt.Errorf("failed to disable remote EMS: %w", err)
+	}
+	return nil
+}
+
 // SetESSMaxChargingLimit sets ESS max charging limit (kW)
 func (c *SigenModbusClient) SetESSMaxChargingLimit(powerKW float64) error {
 	c.SetSlaveID(PlantAddress)
@@ -354,7 +453,7 @@ type HybridInverterInfo struct {
 	RatedBatteryCapacity      float64 // kWh
 	ESSRatedChargePower       float64 // kW
 	ESSRatedDischargePower    float64 // kW
-	RunningState              uint16
+	RunningState              RunningState
 	ActivePower               float64 // kW
 	ReactivePower             float64 // kVar
 	ESSChargeOrDischargePower float64 // kW
@@ -391,7 +490,7 @@ func (c *SigenModbusClient) ReadHybridInverterInfo(slaveID byte) (*HybridInverte
 	c.SetSlaveID(slaveID)
 
 	// Read device info (30540-30552)
-	data, err := c.client.ReadInputRegisters(30540, 13)
+	data, err := c.readInputRegisters(30540, 13)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read inverter info: %v", err)
 	}
@@ -407,12 +506,12 @@ func (c *SigenModbusClient) ReadHybridInverterInfo(slaveID byte) (*HybridInverte
 	}
 
 	// Read running state and power (30578-30609)
-	data2, err := c.client.ReadInputRegisters(30578, 32)
+	data2, err := c.readInputRegisters(30578, 32)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read running state: %v", err)
 	}
 
-	info.RunningState = bytesToU16(data2[0:2])
+	info.RunningState = RunningState(bytesToU16(data2[0:2]))
 	info.ActivePower = float64(bytesToS32(data2[18:22])) / 1000.0
 	info.ReactivePower = float64(bytesToS32(data2[22:26])) / 1000.0
 	info.ESSChargeOrDischargePower = float64(bytesToS32(data2[42:46])) / 1000.0
@@ -426,8 +525,8 @@ func (c *SigenModbusClient) ReadHybridInverterInfo(slaveID byte) (*HybridInverte
 	info.Alarm4 = bytesToU16(data2[60:62])
 	info.Alarm5 = bytesToU16(data2[62:64])
 
-	// Read grid and phase info (31000-31035)
-	data3, err := c.client.ReadInputRegisters(31000, 36)
+	// Read grid and phase info (31000-31037)
+	data3, err := c.readInputRegisters(31000, 38)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read grid info: %v", err)
 	}
@@ -472,13 +571,13 @@ func (c *SigenModbusClient) StopInverter(slaveID byte) error {
 
 // ACChargerInfo represents the AC-Charger information (Section 5.5)
 type ACChargerInfo struct {
-	SystemState              uint16  // System state according to IEC61851-1
-	TotalEnergyConsumed      float64 // kWh
-	ChargingPower            float64 // kW
-	RatedPower               float64 // kW
-	RatedCurrent             float64 // A
-	RatedVoltage             float64 // V
-	InputBreakerRatedCurrent float64 // A
+	SystemState              ACChargerState // System state according to IEC61851-1
+	TotalEnergyConsumed      float64        // kWh
+	ChargingPower            float64        // kW
+	RatedPower               float64        // kW
+	RatedCurrent             float64        // A
+	RatedVoltage             float64        // V
+	InputBreakerRatedCurrent float64        // A
 	Alarm1                   uint16
 	Alarm2                   uint16
 	Alarm3                   uint16
@@ -491,13 +590,13 @@ func (c *SigenModbusClient) ReadACChargerInfo(slaveID byte) (*ACChargerInfo, err
 	}
 	c.SetSlaveID(slaveID)
 
-	data, err := c.client.ReadInputRegisters(32000, 15)
+	data, err := c.readInputRegisters(32000, 15)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read AC charger info: %v", err)
 	}
 
 	info := &ACChargerInfo{
-		SystemState:              bytesToU16(data[0:2]),
+		SystemState:              ACChargerState(bytesToU16(data[0:2])),
 		TotalEnergyConsumed:      float64(bytesToU32(data[2:6])) / 100.0,
 		ChargingPower:            float64(bytesToS32(data[6:10])) / 1000.0,
 		RatedPower:               float64(bytesToU32(data[10:14])) / 1000.0,
@@ -0,0 +1,171 @@
+package sigenergy
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/goburrow/modbus"
+)
+
+// registerWrite is a single register write seen by mockModbusClient
+type registerWrite struct {
+	address uint16
+	value   uint16
+}
+
+// multipleRegisterWrite is a multiple register write seen by mockModbusClient
+type multipleRegisterWrite struct {
+	address  uint16
+	quantity uint16
+	value    []byte
+}
+
+// mockModbusClient records register writes and fails the write to failAddress.
+// Input register reads answer from registers by start address, other reads fail.
+type mockModbusClient struct {
+	modbus.Client
+	writes         []registerWrite
+	multipleWrites []multipleRegisterWrite
+	failAddress    uint16
+	registers      map[uint16][]byte
+}
+
+func (m *mockModbusClient) ReadInputRegisters(address, quantity uint16) ([]byte, error) {
+	data, ok := m.registers[address]
+	if !ok {
+		return nil, errors.New("illegal data address")
+	}
+	return data, nil
+}
+
+func (m *mockModbusClient) WriteSingleRegister(address, value uint16) ([]byte, error) {
+	if m.failAddress != 0 && address == m.failAddress {
+		return nil, errors.New("write failed")
+	}
+	m.writes = append(m.writes, registerWrite{address: address, value: value})
+	return nil, nil
+}
+
+func (m *mockModbusClient) WriteMultipleRegisters(address, quantity uint16, value []byte) ([]byte, error) {
+	if m.failAddress != 0 && address == m.failAddress {
+		return nil, errors.New("write failed")
+	}
+	m.multipleWrites = append(m.multipleWrites, multipleRegisterWrite{address: address, quantity: quantity, value: value})
+	return nil, nil
+}
+
+func TestReleaseRemoteEMS(t *testing.T) {
+	mock := &mockModbusClient{}
+	client := &SigenModbusClient{client: mock}
+
+	if err := client.ReleaseRemoteEMS(); err != nil {
+		t.Fatalf("ReleaseRemoteEMS failed: %v", err)
+	}
+
+	// Maximum self-consumption mode first, then remote EMS disabled
+	expected := []registerWrite{{address: 40031, value: 2}, {address: 40029, value: 0}}
+	if len(mock.writes) != len(expected) {
+		t.Fatalf("Expected writes %v, got %v", expected, mock.writes)
+	}
+	for i, w := range expected {
+		if mock.writes[i] != w {
+			t.Errorf("Write %d: expected %+v, got %+v", i, w, mock.writes[i])
+		}
+	}
+}
+
+func TestReleaseRemoteEMS_ModeWriteFails(t *testing.T) {
+	mock := &mockModbusClient{failAddress: 40031}
+	client := &SigenModbusClient{client: mock}
+
+	if err := client.ReleaseRemoteEMS(); err == nil {
+		t.Fatal("Expected an error when the mode cannot be set")
+	}
+	// The sequence stops at the first failed write
+	if len(mock.writes) != 0 {
+		t.Errorf("Expected no further writes, got %v", mock.writes)
+	}
+}
+
+func TestSetPhasePowerLimits(t *testing.T) {
+	mock := &mockModbusClient{}
+	client := &SigenModbusClient{client: mock}
+
+	if err := client.SetPhasePowerLimits(3.5, -2, 0.25); err != nil {
+		t.Fatalf("SetPhasePowerLimits failed: %v", err)
+	}
+
+	// Independent phase control is enabled before the targets are written
+	if len(mock.writes) != 1 || mock.writes[0] != (registerWrite{address: 40030, value: 1}) {
+		t.Errorf("Expected independent phase control enabled, got %v", mock.writes)
+	}
+	if len(mock.multipleWrites) != 1 {
+		t.Fatalf("Expected a single write of the phase targets, got %v", mock.multipleWrites)
+	}
+	write := mock.multipleWrites[0]
+	if write.address != 40008 || write.quantity != 6 {
+		t.Errorf("Expected 6 registers written at 40008, got %d at %d", write.quantity, write.address)
+	}
+	expected := slices.Concat(s32ToBytes(3500), s32ToBytes(-2000), s32ToBytes(250))
+	if !bytes.Equal(write.value, expected) {
+		t.Errorf("Expected phase targets %v, got %v", expected, write.value)
+	}
+}
+
+func TestSetPhasePowerLimits_EnableFails(t *testing.T) {
+	mock := &mockModbusClient{failAddress: 40030}
+	client := &SigenModbusClient{client: mock}
+
+	if err := client.SetPhasePowerLimits(3, 3, 3); err == nil {
+		t.Fatal("Expected an error when independent phase control cannot be enabled")
+	}
+	// Targets are not written without independent phase control
+	if len(mock.multipleWrites) != 0 {
+		t.Errorf("Expected no phase targets written, got %v", mock.multipleWrites)
+	}
+}
+
+func TestReadPlantRunningInfo(t *testing.T) {
+	mainBlock := make([]byte, 104)
+	copy(mainBlock[28:30], []byte{0x02, 0x6C}) // ESS SOC 62.0%
+	copy(mainBlock[70:74], s32ToBytes(4500))   // PV power 4.5 kW
+	// 10 kWh rated capacity, charge off at 90%, discharge off at 10%, 98% SOH
+	ess := []byte{0x00, 0x00, 0x03, 0xE8, 0x03, 0x84, 0x00, 0x64, 0x03, 0xD4}
+
+	t.Run("complete", func(t *testing.T) {
+		client := &SigenModbusClient{client: &mockModbusClient{registers: map[uint16][]byte{30000: mainBlock, 30083: ess}}}
+		info, err := client.ReadPlantRunningInfo()
+		if err != nil {
+			t.Fatalf("ReadPlantRunningInfo() unexpected error = %v", err)
+		}
+		if info.ESSSOC != 62 || info.PhotovoltaicPower != 4.5 || info.ESSRatedEnergyCapacity != 10 || info.ESSSOH != 98 {
+			t.Errorf("Unexpected plant running info %+v", info)
+		}
+	})
+
+	t.Run("short main block", func(t *testing.T) {
+		client := &SigenModbusClient{client: &mockModbusClient{registers: map[uint16][]byte{30000: mainBlock[:60], 30083: ess}}}
+		info, err := client.ReadPlantRunningInfo()
+		if err == nil || info != nil {
+			t.Fatalf("Expected an error without info for a short read, got %+v (err %v)", info, err)
+		}
+		if !strings.Contains(err.Error(), "returned 60 bytes, expected 104") {
+			t.Errorf("Expected the short read in the error, got %v", err)
+		}
+	})
+
+	t.Run("ESS block missing", func(t *testing.T) {
+		client := &SigenModbusClient{client: &mockModbusClient{registers: map[uint16][]byte{30000: mainBlock, 30083: ess[:6]}}}
+		info, err := client.ReadPlantRunningInfo()
+		var partialErr *PartialReadError
+		if !errors.As(err, &partialErr) || partialErr.Block != "30083-30087" {
+			t.Fatalf("Expected a partial read of the ESS block, got %v", err)
+		}
+		if info == nil || info.ESSSOC != 62 || info.ESSRatedEnergyCapacity != 0 {
+			t.Errorf("Expected the main block without ESS data, got %+v", info)
+		}
+	})
+}
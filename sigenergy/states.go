@@ -0,0 +1,79 @@
+package sigenergy
+
+import "fmt"
+
+// RunningState is the running state of the plant or of a hybrid inverter (Appendix 1)
+type RunningState uint16
+
+// Running state constants
+const (
+	RunningStateStandby  RunningState = 0 // Standby
+	RunningStateRunning  RunningState = 1 // Running
+	RunningStateFault    RunningState = 2 // Fault
+	RunningStateShutdown RunningState = 3 // Shutdown
+)
+
+// String returns the name of the running state
+func (s RunningState) String() string {
+	switch s {
+	case RunningStateStandby:
+		return "Standby"
+	case RunningStateRunning:
+		return "Running"
+	case RunningStateFault:
+		return "Fault"
+	case RunningStateShutdown:
+		return "Shutdown"
+	default:
+		return fmt.Sprintf("Unknown (%d)", uint16(s))
+	}
+}
+
+// IsFault returns true if the plant or inverter reports a fault
+func (s RunningState) IsFault() bool {
+	return s == RunningStateFault
+}
+
+// ACChargerState is the system state of the AC charger according to IEC 61851-1 (Appendix 7)
+type ACChargerState uint16
+
+// AC charger system state constants
+const (
+	ACChargerStateInit          ACChargerState = 0 // System initialization
+	ACChargerStateNotConnected  ACChargerState = 1 // A1/A2: no vehicle connected
+	ACChargerStateConnected     ACChargerState = 2 // B1: vehicle connected, charger not ready
+	ACChargerStateReady         ACChargerState = 3 // B2: vehicle connected, charger ready
+	ACChargerStateChargeRequest ACChargerState = 4 // C1: vehicle requests charging, charger not ready
+	ACChargerStateCharging      ACChargerState = 5 // C2: charging
+	ACChargerStateNotAvailable  ACChargerState = 6 // F: charger not available
+	ACChargerStateError         ACChargerState = 7 // E: error, e.g. a short circuit of the control pilot
+)
+
+// String returns the name of the AC charger state with its IEC 61851-1 state letter
+func (s ACChargerState) String() string {
+	switch s {
+	case ACChargerStateInit:
+		return "Initializing"
+	case ACChargerStateNotConnected:
+		return "A1/A2 Not Connected"
+	case ACChargerStateConnected:
+		return "B1 Connected"
+	case ACChargerStateReady:
+		return "B2 Ready"
+	case ACChargerStateChargeRequest:
+		return "C1 Charge Requested"
+	case ACChargerStateCharging:
+		return "C2 Charging"
+	case ACChargerStateNotAvailable:
+		return "F Not Available"
+	case ACChargerStateError:
+		return "E Error"
+	default:
+		return fmt.Sprintf("Unknown (%d)", uint16(s))
+	}
+}
+
+// IsFault returns true in the IEC 61851-1 fault states E and F, in which the charger cannot charge
+func (s ACChargerState) IsFault() bool {
+	return s == ACChargerStateNotAvailable || s == ACChargerStateError
+}
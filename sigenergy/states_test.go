@@ -0,0 +1,51 @@
+package sigenergy
+
+import "testing"
+
+func TestRunningState(t *testing.T) {
+	tests := []struct {
+		state RunningState
+		name  string
+		fault bool
+	}{
+		{state: 0, name: "Standby"},
+		{state: 1, name: "Running"},
+		{state: 2, name: "Fault", fault: true},
+		{state: 3, name: "Shutdown"},
+		{state: 9, name: "Unknown (9)"},
+	}
+	for _, tt := range tests {
+		if name := tt.state.String(); name != tt.name {
+			t.Errorf("RunningState(%d).String() = %q, expected %q", uint16(tt.state), name, tt.name)
+		}
+		if fault := tt.state.IsFault(); fault != tt.fault {
+			t.Errorf("RunningState(%d).IsFault() = %v, expected %v", uint16(tt.state), fault, tt.fault)
+		}
+	}
+}
+
+func TestACChargerState(t *testing.T) {
+	tests := []struct {
+		state ACChargerState
+		name  string
+		fault bool
+	}{
+		{state: 0, name: "Initializing"},
+		{state: 1, name: "A1/A2 Not Connected"},
+		{state: 2, name: "B1 Connected"},
+		{state: 3, name: "B2 Ready"},
+		{state: 4, name: "C1 Charge Requested"},
+		{state: 5, name: "C2 Charging"},
+		{state: 6, name: "F Not Available", fault: true},
+		{state: 7, name: "E Error", fault: true},
+		{state: 42, name: "Unknown (42)"},
+	}
+	for _, tt := range tests {
+		if name := tt.state.String(); name != tt.name {
+			t.Errorf("ACChargerState(%d).String() = %q, expected %q", uint16(tt.state), name, tt.name)
+		}
+		if fault := tt.state.IsFault(); fault != tt.fault {
+			t.Errorf("ACChargerState(%d).IsFault() = %v, expected %v", uint16(tt.state), fault, tt.fault)
+		}
+	}
+}
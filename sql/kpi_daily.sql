@@ -0,0 +1,20 @@
+CREATE TABLE kpi_daily (
+    date DATE PRIMARY KEY,
+    solar_kwh NUMERIC,
+    consumption_kwh NUMERIC,
+    grid_import_kwh NUMERIC,
+    grid_export_kwh NUMERIC,
+    self_sufficiency NUMERIC,
+    self_consumption_ratio NUMERIC
+);
+
+-- Written once a day by the daily KPI job when store_daily_kpi is enabled, one row per day
+-- in the configured location computed from the 'energy_flow' and 'energy_flow_daily' metrics rows.
+
+-- Column descriptions:
+-- solar_kwh: Solar energy produced in kWh
+-- consumption_kwh: Energy consumed by the load including EV DC charging in kWh
+-- grid_import_kwh: Energy imported from the grid in kWh
+-- grid_export_kwh: Energy exported to the grid in kWh
+-- self_sufficiency: Share of the consumption not imported from the grid in %, NULL without consumption
+-- self_consumption_ratio: Share of the solar energy not exported in %, NULL without solar
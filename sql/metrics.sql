@@ -24,6 +24,7 @@ CREATE INDEX idx_metrics_time ON metrics USING BRIN (timestamp);
 -- Supported metric_name values:
 -- - 'pv_total_power': Total PV power in kWh for the integration period
 -- - 'energy_flow': Combined energy flow metrics for the integration period
+-- - 'energy_flow_daily': Daily aggregate of 'energy_flow' rows written by the metrics retention job
 
 -- Column descriptions:
 -- pv_total_power: Total PV power in kWh
@@ -0,0 +1,10 @@
+-- Migration: Add solar curtailment column to mpc_decisions table
+-- Description: Adds solar_curtailment column so plans that curtail PV (e.g. full battery
+--              and negative export prices) keep the curtailed power when reloaded
+
+-- Add solar_curtailment column (curtailed solar power)
+ALTER TABLE mpc_decisions 
+ADD COLUMN IF NOT EXISTS solar_curtailment NUMERIC;
+
+-- Add comment to document the new column
+COMMENT ON COLUMN mpc_decisions.solar_curtailment IS 'Solar power in kW the plan curtails instead of storing or exporting it';
@@ -17,7 +17,8 @@ CREATE TABLE mpc_decisions (
     weather_symbol VARCHAR(100),
     battery_avg_cell_temp NUMERIC,
     air_temperature NUMERIC,
-    battery_preheat_active BOOLEAN
+    battery_preheat_active BOOLEAN,
+    solar_curtailment NUMERIC
 );
 
 -- Column descriptions:
@@ -40,3 +41,4 @@ CREATE TABLE mpc_decisions (
 -- battery_avg_cell_temp: Forecasted battery average cell temperature in °C for this time slot
 -- air_temperature: Forecasted air temperature in °C for this time slot
 -- battery_preheat_active: Whether battery preheating is active during this time slot (true when charging below threshold temperature)
+-- solar_curtailment: Solar power in kW the plan curtails instead of storing or exporting it
@@ -147,12 +147,31 @@ function App() {
             <div className="miners-list">
               {status.miners.list.map((miner, index) => (
                 <div key={index} className="miner-item">
-                  <div className="miner-ip">{miner.ip}</div>
+                  <div
+                    className="miner-ip"
+                    title={
+                      miner.last_seen
+                        ? `Last seen ${new Date(miner.last_seen).toLocaleString()}`
+                        : "Never seen"
+                    }
+                  >
+                    {miner.ip}
+                  </div>
                   <div
                     className={`miner-status status-${miner.status?.toLowerCase()}`}
                   >
                     {miner.status || "Unknown"}
                   </div>
+                  {miner.network_health && miner.network_health !== "Good" && (
+                    <div className="miner-reason">
+                      network {miner.network_health.toLowerCase()}
+                    </div>
+                  )}
+                  {miner.reason && (
+                    <div className="miner-reason" title={miner.decided_at}>
+                      {miner.reason.replace(/_/g, " ")}
+                    </div>
+                  )}
                 </div>
               ))}
             </div>
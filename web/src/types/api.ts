@@ -5,6 +5,7 @@ export interface MPCDecisionInfo {
   battery_discharge: number;
   grid_import: number;
   grid_export: number;
+  solar_curtailment: number;
   battery_soc: number;
   profit: number;
   // Forecast data used for this decision
@@ -18,13 +19,29 @@ export interface MPCDecisionInfo {
   air_temperature: number;
 }
 
+export interface TaskStatus {
+  last_run?: string;
+  last_success?: string;
+  running: boolean;
+  stuck: boolean;
+}
+
+export interface LoadErrorStats {
+  samples: number;
+  bias: number;
+  rmse: number;
+}
+
 export interface SchedulerStatus {
   is_running: boolean;
   miners_count: number;
   has_market_data: boolean;
+  safe_mode: boolean;
   price_limit: number;
   network: string;
   mpc_decisions?: MPCDecisionInfo[];
+  tasks?: Record<string, TaskStatus>;
+  load_forecast_error?: LoadErrorStats;
 }
 
 export interface HealthResponse {
@@ -58,12 +75,19 @@ export interface StatusResponse {
     is_running: boolean;
     miners_count: number;
     has_market_data: boolean;
+    safe_mode: boolean;
+    tasks?: Record<string, TaskStatus>;
+    load_forecast_error?: LoadErrorStats;
   };
   miners: {
     count: number;
     list: Array<{
       ip: string;
       status: string;
+      reason?: string;
+      decided_at?: string;
+      last_seen?: string;
+      network_health?: string;
     }>;
   };
   price_data: {
@@ -243,6 +243,7 @@ function generateMPCDecisions(): MPCDecisionInfo[] {
       battery_discharge: batteryDischarge,
       grid_import: gridImport,
       grid_export: gridExport,
+      solar_curtailment: 0,
       battery_soc: Math.max(0, Math.min(1, currentSOC)),
       profit,
       import_price: importPrice,